@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+)
+
+const chatHelp = `Commands:
+  /system [text]  Show or set the system prompt
+  /model [name]   Show or switch the model
+  /save <file>    Save the conversation to a file
+  /load <file>    Load a conversation from a file
+  /clear          Start a new conversation
+  /help           Show this help
+  /exit           Leave the chat`
+
+// chatMessage is a single turn of the conversation.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatTranscript is the on-disk format used by /save, /load and the history file.
+type chatTranscript struct {
+	System   string        `json:"system,omitempty"`
+	Model    string        `json:"model,omitempty"`
+	Messages []chatMessage `json:"messages"`
+}
+
+// chatSession holds the state of an interactive chat.
+type chatSession struct {
+	client      gollm.LLM
+	llmFlags    *configFlags
+	transcript  chatTranscript
+	historyPath string
+	stream      bool
+	out         io.Writer
+}
+
+// setupChat registers the chat flags and returns the interactive chat REPL.
+func setupChat(fs *flag.FlagSet) func() error {
+	llmFlags := registerConfigFlags(fs)
+	system := fs.String("system", "", "System prompt for the conversation")
+	historyPath := fs.String("history", defaultHistoryPath(), "File the conversation is persisted to (empty disables persistence)")
+	fresh := fs.Bool("new", false, "Start a new conversation instead of resuming the history file")
+	noStream := fs.Bool("no-stream", false, "Print responses only once they are complete")
+
+	return func() error {
+		return runChat(llmFlags, *system, *historyPath, *fresh, *noStream)
+	}
+}
+
+// runChat starts the interactive chat REPL.
+func runChat(llmFlags *configFlags, system, historyPath string, fresh, noStream bool) error {
+	session := &chatSession{
+		llmFlags:    llmFlags,
+		historyPath: historyPath,
+		stream:      !noStream,
+		out:         os.Stdout,
+	}
+	if session.historyPath != "" && !fresh {
+		if err := session.load(session.historyPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load history: %w", err)
+		}
+	}
+	if system != "" {
+		session.transcript.System = system
+	}
+	if *llmFlags.model != "" {
+		session.transcript.Model = *llmFlags.model
+	}
+	if err := session.connect(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(session.out, "Chatting with %s/%s. Type /help for commands.\n", session.client.GetProvider(), session.client.GetModel())
+	if n := len(session.transcript.Messages); n > 0 {
+		fmt.Fprintf(session.out, "Resumed %d messages from %s.\n", n, session.historyPath)
+	}
+
+	return session.loop(os.Stdin)
+}
+
+// connect (re)creates the LLM client for the current model.
+func (s *chatSession) connect() error {
+	var opts []gollm.ConfigOption
+	if s.transcript.Model != "" {
+		opts = append(opts, gollm.SetModel(s.transcript.Model))
+	}
+	client, err := s.llmFlags.newClient(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	s.client = client
+	s.transcript.Model = client.GetModel()
+	return nil
+}
+
+// loop reads lines from in until EOF or /exit.
+func (s *chatSession) loop(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			done, err := s.command(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			if done {
+				return nil
+			}
+			continue
+		}
+
+		if err := s.send(line); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating response: %v\n", err)
+		}
+	}
+}
+
+// command executes a slash command. It reports whether the chat should end.
+func (s *chatSession) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Fprintln(s.out, chatHelp)
+	case "/system":
+		if arg == "" {
+			fmt.Fprintf(s.out, "System prompt: %q\n", s.transcript.System)
+			return false, nil
+		}
+		s.transcript.System = arg
+		return false, s.persist()
+	case "/model":
+		if arg == "" {
+			fmt.Fprintf(s.out, "Model: %s\n", s.client.GetModel())
+			return false, nil
+		}
+		previous := s.transcript.Model
+		s.transcript.Model = arg
+		if err := s.connect(); err != nil {
+			s.transcript.Model = previous
+			return false, err
+		}
+		fmt.Fprintf(s.out, "Switched to %s.\n", arg)
+		return false, s.persist()
+	case "/save":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /save <file>")
+		}
+		if err := s.save(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(s.out, "Saved %d messages to %s.\n", len(s.transcript.Messages), arg)
+	case "/load":
+		if arg == "" {
+			return false, fmt.Errorf("usage: /load <file>")
+		}
+		if err := s.load(arg); err != nil {
+			return false, err
+		}
+		if err := s.connect(); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(s.out, "Loaded %d messages from %s.\n", len(s.transcript.Messages), arg)
+		return false, s.persist()
+	case "/clear":
+		s.transcript.Messages = nil
+		return false, s.persist()
+	default:
+		return false, fmt.Errorf("unknown command %s (type /help)", name)
+	}
+	return false, nil
+}
+
+// send adds a user message, prints the assistant reply and records it.
+func (s *chatSession) send(input string) error {
+	s.transcript.Messages = append(s.transcript.Messages, chatMessage{Role: "user", Content: input})
+
+	// Interrupting a reply cancels it without leaving the chat
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	prompt := s.prompt(input)
+
+	var reply string
+	var err error
+	if s.stream && s.client.SupportsStreaming() {
+		reply, err = s.streamReply(ctx, prompt)
+	} else {
+		reply, err = s.client.Generate(ctx, prompt)
+		if err == nil {
+			fmt.Fprintln(s.out, reply)
+		}
+	}
+	if err != nil {
+		// Drop the unanswered message so the conversation stays consistent
+		s.transcript.Messages = s.transcript.Messages[:len(s.transcript.Messages)-1]
+		return err
+	}
+
+	s.transcript.Messages = append(s.transcript.Messages, chatMessage{Role: "assistant", Content: reply})
+	return s.persist()
+}
+
+// streamReply prints tokens as they arrive and returns the full reply.
+func (s *chatSession) streamReply(ctx context.Context, prompt *gollm.Prompt) (string, error) {
+	stream, err := s.client.Stream(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(s.out)
+			return "", err
+		}
+		reply.WriteString(token.Text)
+		fmt.Fprint(s.out, token.Text)
+	}
+	fmt.Fprintln(s.out)
+	return reply.String(), nil
+}
+
+// prompt builds the request for the latest user input. Providers with native
+// message support receive the transcript as messages; others receive it
+// flattened into the prompt text.
+func (s *chatSession) prompt(input string) *gollm.Prompt {
+	var opts []gollm.PromptOption
+	if s.transcript.System != "" {
+		opts = append(opts, gollm.WithSystemPrompt(s.transcript.System, ""))
+	}
+	if !s.client.SupportsMessages() {
+		return gollm.NewPrompt(s.conversation(), opts...)
+	}
+	messages := make([]gollm.PromptMessage, len(s.transcript.Messages))
+	for i, msg := range s.transcript.Messages {
+		messages[i] = gollm.PromptMessage{Role: msg.Role, Content: msg.Content}
+	}
+	return gollm.NewPrompt(input, append(opts, gollm.WithMessages(messages))...)
+}
+
+// conversation renders the history in the same "role: content" form used by
+// the library's conversation memory, for providers without message support.
+func (s *chatSession) conversation() string {
+	var b strings.Builder
+	for _, msg := range s.transcript.Messages {
+		fmt.Fprintf(&b, "%s: %s\n", msg.Role, msg.Content)
+	}
+	return b.String()
+}
+
+func (s *chatSession) persist() error {
+	if s.historyPath == "" {
+		return nil
+	}
+	return s.save(s.historyPath)
+}
+
+func (s *chatSession) save(path string) error {
+	data, err := json.MarshalIndent(s.transcript, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (s *chatSession) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var transcript chatTranscript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return fmt.Errorf("invalid conversation file %s: %w", path, err)
+	}
+	s.transcript = transcript
+	return nil
+}
+
+// defaultHistoryPath returns the default chat history location, or an empty
+// string (no persistence) when the user config directory is unknown.
+func defaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gollm", "chat_history.json")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// setupCompletion defines the completion command, which prints a completion
+// script for bash, zsh or fish built from the command table and its flags.
+func setupCompletion(fs *flag.FlagSet) func() error {
+	return func() error {
+		if fs.NArg() != 1 {
+			return usageError("a shell (bash, zsh or fish) is required")
+		}
+		switch shell := fs.Arg(0); shell {
+		case "bash":
+			writeBashCompletion(os.Stdout, false)
+		case "zsh":
+			writeBashCompletion(os.Stdout, true)
+		case "fish":
+			writeFishCompletion(os.Stdout)
+		default:
+			return usageError(fmt.Sprintf("unsupported shell %q", shell))
+		}
+		return nil
+	}
+}
+
+// commandFlags returns the flag names of a command by running its setup on a
+// throwaway flag set.
+func commandFlags(cmd command) []*flag.Flag {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.setup(fs)
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	return flags
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer, zsh bool) {
+	if zsh {
+		fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+	}
+	fmt.Fprintln(w, `_gollm() {
+    local cur cmd flags
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    cmd="generate"
+    if [[ ${COMP_CWORD} -gt 1 ]]; then
+        cmd="${COMP_WORDS[1]}"
+    fi
+    case "${cmd}" in`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        %s) flags=%q ;;\n", cmd.name, flagList(commandFlags(cmd)))
+	}
+	fmt.Fprintf(w, `        *) flags=%q ;;
+    esac
+    if [[ ${COMP_CWORD} -eq 1 && "${cur}" != -* ]]; then
+        COMPREPLY=( $(compgen -W %q -- "${cur}") )
+    elif [[ "${cur}" == -* ]]; then
+        COMPREPLY=( $(compgen -W "${flags}" -- "${cur}") )
+    fi
+}
+complete -o default -F _gollm gollm
+`, flagList(commandFlags(*findCommand("generate"))), strings.Join(commandNames(), " "))
+}
+
+func flagList(flags []*flag.Flag) string {
+	names := make([]string, 0, len(flags))
+	for _, f := range flags {
+		names = append(names, "-"+f.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "complete -c gollm -f")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c gollm -n '__fish_use_subcommand' -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+		for _, f := range commandFlags(cmd) {
+			fmt.Fprintf(w, "complete -c gollm -n '__fish_seen_subcommand_from %s' -o %s -d %s\n", cmd.name, f.Name, fishQuote(f.Usage))
+		}
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
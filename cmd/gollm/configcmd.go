@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/teilomillet/gollm"
+)
+
+// setupConfig defines the config command, which shows and edits the CLI
+// configuration file. Keys use the file's field names; profile settings are
+// addressed as profiles.<name>.<key>.
+func setupConfig(fs *flag.FlagSet) func() error {
+	configPath := fs.String("config", defaultConfigPath(), "CLI configuration file (YAML, TOML or JSON)")
+
+	return func() error {
+		args := fs.Args()
+		if len(args) == 0 {
+			return usageError("a config action is required")
+		}
+		if *configPath == "" {
+			return fmt.Errorf("no configuration file path; use -config")
+		}
+
+		switch action, args := args[0], args[1:]; {
+		case action == "path" && len(args) == 0:
+			fmt.Println(*configPath)
+			return nil
+		case action == "list" && len(args) == 0:
+			settings, err := readSettings(*configPath)
+			if err != nil {
+				return err
+			}
+			for _, line := range flattenSettings("", settings) {
+				fmt.Println(line)
+			}
+			return nil
+		case action == "get" && len(args) == 1:
+			settings, err := readSettings(*configPath)
+			if err != nil {
+				return err
+			}
+			value, ok := lookupSetting(settings, strings.Split(args[0], "."))
+			if !ok {
+				return fmt.Errorf("%s is not set", args[0])
+			}
+			fmt.Println(formatSetting(value))
+			return nil
+		case action == "set" && len(args) == 2:
+			return updateSettings(*configPath, func(settings map[string]interface{}) error {
+				var value interface{}
+				if err := yaml.Unmarshal([]byte(args[1]), &value); err != nil {
+					return fmt.Errorf("invalid value %q: %w", args[1], err)
+				}
+				return setSetting(settings, strings.Split(args[0], "."), value)
+			})
+		case action == "unset" && len(args) == 1:
+			return updateSettings(*configPath, func(settings map[string]interface{}) error {
+				if !deleteSetting(settings, strings.Split(args[0], ".")) {
+					return fmt.Errorf("%s is not set", args[0])
+				}
+				return nil
+			})
+		default:
+			return usageError(fmt.Sprintf("invalid config action %q", strings.Join(fs.Args(), " ")))
+		}
+	}
+}
+
+// readSettings decodes the configuration file, returning an empty map when
+// the file does not exist yet.
+func readSettings(path string) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".json":
+		err = json.Unmarshal(data, &settings)
+	default:
+		return nil, fmt.Errorf("the config command only edits YAML and JSON files, got %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// updateSettings applies change to the configuration file and writes it back
+// atomically, refusing changes that would make the file invalid.
+func updateSettings(path string, change func(map[string]interface{}) error) error {
+	settings, err := readSettings(path)
+	if err != nil {
+		return err
+	}
+	if err := change(settings); err != nil {
+		return err
+	}
+
+	var data []byte
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		data, err = json.MarshalIndent(settings, "", "  ")
+	} else {
+		data, err = yaml.Marshal(settings)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Load every profile so a bad value is reported before it is saved
+	if _, err := gollm.LoadFileProfile(tmp.Name(), ""); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if profiles, ok := settings["profiles"].(map[string]interface{}); ok {
+		for name := range profiles {
+			if _, err := gollm.LoadFileProfile(tmp.Name(), name); err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
+		}
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func lookupSetting(settings map[string]interface{}, keys []string) (interface{}, bool) {
+	value, ok := settings[keys[0]]
+	if !ok || len(keys) == 1 {
+		return value, ok
+	}
+	nested, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, false
+	}
+	return lookupSetting(nested, keys[1:])
+}
+
+func setSetting(settings map[string]interface{}, keys []string, value interface{}) error {
+	if len(keys) == 1 {
+		settings[keys[0]] = value
+		return nil
+	}
+	nested, ok := settings[keys[0]].(map[string]interface{})
+	if !ok {
+		if _, exists := settings[keys[0]]; exists {
+			return fmt.Errorf("%s is not a section", keys[0])
+		}
+		nested = make(map[string]interface{})
+		settings[keys[0]] = nested
+	}
+	return setSetting(nested, keys[1:], value)
+}
+
+func deleteSetting(settings map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		_, ok := settings[keys[0]]
+		delete(settings, keys[0])
+		return ok
+	}
+	nested, ok := settings[keys[0]].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	deleted := deleteSetting(nested, keys[1:])
+	if deleted && len(nested) == 0 {
+		delete(settings, keys[0])
+	}
+	return deleted
+}
+
+// flattenSettings renders the settings as sorted key=value lines with dotted keys.
+func flattenSettings(prefix string, settings map[string]interface{}) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		if nested, ok := settings[key].(map[string]interface{}); ok {
+			lines = append(lines, flattenSettings(prefix+key+".", nested)...)
+			continue
+		}
+		lines = append(lines, prefix+key+"="+formatSetting(settings[key]))
+	}
+	return lines
+}
+
+func formatSetting(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
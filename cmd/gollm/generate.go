@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/optimizer"
+	"github.com/teilomillet/gollm/presets"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// setupGenerate defines the generate command. defaultType selects the prompt
+// type used when -type is not given, which lets optimize share this command.
+func setupGenerate(defaultType string) func(fs *flag.FlagSet) func() error {
+	return func(fs *flag.FlagSet) func() error {
+		promptType := fs.String("type", defaultType, "Prompt type (raw, qa, cot, summarize, optimize)")
+		verbose := fs.Bool("verbose", false, "Display verbose output including full prompt")
+		llmFlags := registerConfigFlags(fs)
+		outputFormat := fs.String("output-format", "", "Output format for structured responses (json)")
+		schemaPath := fs.String("schema", "", "JSON schema file the response must conform to (implies -output-format json)")
+		schemaRetries := fs.Int("schema-retries", 2, "Number of times to re-prompt when the response does not match -schema")
+
+		input := registerInputFlags(fs)
+		meter := registerUsageFlags(fs)
+
+		// Flags for prompt optimization
+		optimizeGoal := fs.String("optimize-goal", "Improve the prompt's clarity and effectiveness", "Optimization goal")
+		optimizeIterations := fs.Int("optimize-iterations", 5, "Number of optimization iterations")
+		optimizeMemory := fs.Int("optimize-memory", 2, "Number of previous iterations to remember")
+
+		return func() error {
+			// Create LLM client with the specified options
+			llmClient, err := llmFlags.newClient(meter.options()...)
+			if err != nil {
+				return fmt.Errorf("failed to create LLM client: %w", err)
+			}
+
+			rawPrompt, contextTexts, err := input.readInputs(fs.Args())
+			if err != nil {
+				return fmt.Errorf("failed to read input: %w", err)
+			}
+			if strings.TrimSpace(rawPrompt) == "" {
+				return usageError("a prompt is required as arguments, with -f, or on stdin")
+			}
+			ctx := meter.start(context.Background(), llmClient)
+			defer meter.report(os.Stderr)
+
+			attachments, err := input.attachments()
+			if err != nil {
+				return fmt.Errorf("invalid attachment: %w", err)
+			}
+			if len(attachments) > 0 && *promptType != "raw" {
+				return usageError("-image and -file can only be used with -type raw")
+			}
+			if len(contextTexts) > 0 && *promptType == "optimize" {
+				return usageError("-context-file and piped context cannot be used with -type optimize")
+			}
+			var contextOpts []gollm.PromptOption
+			if len(contextTexts) > 0 {
+				contextOpts = append(contextOpts, gollm.WithContext(joinNonEmpty(contextTexts...)))
+			}
+
+			if *schemaPath != "" {
+				if *promptType != "raw" {
+					return usageError("-schema can only be used with -type raw")
+				}
+				schema, err := readSchema(*schemaPath)
+				if err != nil {
+					return err
+				}
+				prompt := gollm.NewPrompt(rawPrompt, attachments...)
+				prompt.Apply(contextOpts...)
+				response, err := generateStructured(ctx, llmClient, prompt, schema, *schemaRetries)
+				if err != nil {
+					return err
+				}
+				printResponse(*verbose, *promptType, prompt.String(), rawPrompt, response, "json")
+				return nil
+			}
+
+			var response string
+			var fullPrompt string
+
+			switch *promptType {
+			case "qa":
+				response, err = presets.QuestionAnswer(ctx, llmClient, rawPrompt, contextOpts...)
+			case "cot":
+				response, err = presets.ChainOfThought(ctx, llmClient, rawPrompt, contextOpts...)
+			case "summarize":
+				response, err = summarizeChunked(ctx, llmClient, joinNonEmpty(append([]string{rawPrompt}, contextTexts...)...), *input.chunkSize, *verbose)
+			case "optimize":
+				promptOptimizer := optimizer.NewPromptOptimizer(
+					llmClient,
+					utils.NewDebugManager(
+						llmClient.GetLogger(),
+						utils.DebugOptions{LogPrompts: true, LogResponses: true}),
+					llmClient.NewPrompt(rawPrompt),
+					*optimizeGoal,
+					optimizer.WithIterations(*optimizeIterations),
+					optimizer.WithMemorySize(*optimizeMemory),
+				)
+				var optimizedPrompt *gollm.Prompt
+				optimizedPrompt, err = promptOptimizer.OptimizePrompt(ctx)
+				if err == nil {
+					response = optimizedPrompt.Input
+					fullPrompt = fmt.Sprintf("Initial Prompt: %s\nOptimization Goal: %s\nMemory Size: %d", rawPrompt, *optimizeGoal, *optimizeMemory)
+				}
+			default:
+				prompt := gollm.NewPrompt(rawPrompt, attachments...)
+				prompt.Apply(contextOpts...)
+				if *outputFormat == "json" {
+					prompt.Apply(gollm.WithOutput("Please provide your response in JSON format."))
+				}
+				response, err = llmClient.Generate(ctx, prompt, gollm.WithJSONSchemaValidation())
+				fullPrompt = prompt.String()
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to generate response: %w", err)
+			}
+
+			printResponse(*verbose, *promptType, fullPrompt, rawPrompt, response, *outputFormat)
+			return nil
+		}
+	}
+}
+
+func printResponse(verbose bool, promptType, fullPrompt, rawPrompt, response, outputFormat string) {
+	if verbose {
+		if fullPrompt == "" {
+			fullPrompt = rawPrompt // For qa, cot, and summarize, we don't have access to the full prompt
+		}
+		fmt.Printf("Prompt Type: %s\nFull Prompt:\n%s\n\nResponse:\n---------\n", promptType, fullPrompt)
+	}
+
+	if outputFormat == "json" {
+		var jsonResponse interface{}
+		err := json.Unmarshal([]byte(response), &jsonResponse)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing JSON response: %v\n", err)
+			fmt.Println(response) // Print raw response if JSON parsing fails
+		} else {
+			jsonPretty, _ := json.MarshalIndent(jsonResponse, "", "  ")
+			fmt.Println(string(jsonPretty))
+		}
+	} else {
+		fmt.Println(response)
+	}
+}
+
+// isPromptType reports whether name is a prompt type usable as a leading word.
+func isPromptType(name string) bool {
+	switch name {
+	case "qa", "cot", "summarize":
+		return true
+	}
+	return false
+}
+
+// readSchema loads a JSON schema file.
+func readSchema(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	if _, ok := schema["type"].(string); !ok {
+		return nil, fmt.Errorf("invalid schema %s: missing \"type\"", path)
+	}
+	return schema, nil
+}
+
+// generateStructured generates a response matching schema with
+// GenerateWithSchema. Rejected responses are reported on stderr and sent back
+// to the model with the validation error up to retries times.
+func generateStructured(ctx context.Context, client gollm.LLM, prompt *gollm.Prompt, schema map[string]interface{}, retries int) (string, error) {
+	request := prompt
+	for attempt := 0; ; attempt++ {
+		response, err := client.GenerateWithSchema(ctx, request, schema)
+		if err == nil {
+			return response, nil
+		}
+		var mismatch *gollm.SchemaMismatchError
+		if !errors.As(err, &mismatch) {
+			return "", fmt.Errorf("failed to generate response: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Response %d/%d failed schema validation: %v\n", attempt+1, retries+1, mismatch.Err)
+		if attempt >= retries {
+			fmt.Fprintf(os.Stderr, "Last response:\n%s\n", mismatch.Response)
+			return "", fmt.Errorf("response does not match schema after %d attempts", attempt+1)
+		}
+		retry := *prompt
+		retry.Input = fmt.Sprintf("%s\n\nA previous response to this request was rejected.\n<rejected_response>\n%s\n</rejected_response>\nIt did not match the schema: %v\nRespond again, fixing it.",
+			prompt.Input, mismatch.Response, mismatch.Err)
+		retry.Messages = []gollm.PromptMessage{{Role: "user", Content: retry.Input}}
+		request = &retry
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/presets"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// inputFlags holds the flags controlling where the prompt and context come from.
+type inputFlags struct {
+	promptFile   *string
+	contextFiles stringList
+	images       stringList
+	files        stringList
+	maxInput     *int64
+	chunkSize    *int
+}
+
+// registerInputFlags defines the input flags on fs.
+func registerInputFlags(fs *flag.FlagSet) *inputFlags {
+	f := &inputFlags{
+		promptFile: fs.String("f", "", "Read the prompt from a file (use - for stdin)"),
+		maxInput:   fs.Int64("max-input", 5<<20, "Maximum size in bytes of any prompt or context input"),
+		chunkSize:  fs.Int("chunk-size", 16000, "Summarize inputs larger than this many characters in chunks"),
+	}
+	fs.Var(&f.contextFiles, "context-file", "File added to the prompt as context (repeatable)")
+	fs.Var(&f.images, "image", "Image path or URL sent to vision models (repeatable)")
+	fs.Var(&f.files, "file", "Document, such as a PDF, sent to models that accept files (repeatable)")
+	return f
+}
+
+// attachments converts -image and -file into prompt options. Images given
+// as http(s) or data: URLs are sent by URL; anything else is a local file.
+func (f *inputFlags) attachments() ([]gollm.PromptOption, error) {
+	var opts []gollm.PromptOption
+	for _, image := range f.images {
+		if isURL(image) {
+			opts = append(opts, gollm.WithImageURL(image))
+			continue
+		}
+		if err := checkAttachment(image, *f.maxInput); err != nil {
+			return nil, err
+		}
+		opts = append(opts, gollm.WithImageFile(image))
+	}
+	for _, file := range f.files {
+		if err := checkAttachment(file, *f.maxInput); err != nil {
+			return nil, err
+		}
+		opts = append(opts, gollm.WithFile(file))
+	}
+	return opts, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "data:")
+}
+
+// checkAttachment fails early for missing or oversized attachment files.
+func checkAttachment(path string, max int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > max {
+		return fmt.Errorf("%s exceeds the maximum input size of %d bytes (see -max-input)", path, max)
+	}
+	return nil
+}
+
+// readInputs assembles the prompt text and context from the positional
+// arguments, -f, --context-file and piped stdin. Piped stdin is the prompt
+// when no other prompt is given, and context otherwise.
+func (f *inputFlags) readInputs(args []string) (prompt string, context []string, err error) {
+	prompt = strings.Join(args, " ")
+
+	if *f.promptFile != "" {
+		text, err := readLimited(*f.promptFile, *f.maxInput)
+		if err != nil {
+			return "", nil, err
+		}
+		prompt = joinNonEmpty(prompt, text)
+	}
+
+	for _, path := range f.contextFiles {
+		text, err := readLimited(path, *f.maxInput)
+		if err != nil {
+			return "", nil, err
+		}
+		context = append(context, text)
+	}
+
+	if *f.promptFile != "-" && stdinIsPiped() {
+		text, err := readLimited("-", *f.maxInput)
+		if err != nil {
+			return "", nil, err
+		}
+		if prompt == "" {
+			prompt = text
+		} else if strings.TrimSpace(text) != "" {
+			context = append(context, text)
+		}
+	}
+
+	return prompt, context, nil
+}
+
+// readLimited reads a file, or stdin for "-", failing if it exceeds max bytes.
+func readLimited(path string, max int64) (string, error) {
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		r, name = file, path
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if int64(len(data)) > max {
+		return "", fmt.Errorf("%s exceeds the maximum input size of %d bytes (see -max-input)", name, max)
+	}
+	return string(data), nil
+}
+
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+func joinNonEmpty(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// summarizeChunked summarizes text directly when it fits in one chunk, and
+// otherwise summarizes each chunk and then the combined chunk summaries.
+func summarizeChunked(ctx context.Context, l gollm.LLM, text string, chunkSize int, verbose bool) (string, error) {
+	chunks := chunkText(text, chunkSize)
+	if len(chunks) <= 1 {
+		return presets.Summarize(ctx, l, text)
+	}
+
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Summarizing chunk %d/%d (%d characters)\n", i+1, len(chunks), len(chunk))
+		}
+		summary, err := presets.Summarize(ctx, l, chunk)
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	combined := strings.Join(summaries, "\n\n")
+	if len(combined) >= len(text) {
+		// The summaries did not shrink the input; avoid recursing forever
+		return presets.Summarize(ctx, l, combined)
+	}
+	return summarizeChunked(ctx, l, combined, chunkSize, verbose)
+}
+
+// chunkText splits text into chunks of at most size characters, preferring
+// paragraph, then line, then word boundaries.
+func chunkText(text string, size int) []string {
+	if size <= 0 || len(text) <= size {
+		return []string{text}
+	}
+
+	var chunks []string
+	for len(text) > size {
+		cut := size
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			if i := strings.LastIndex(text[:size], sep); i > size/2 {
+				cut = i + len(sep)
+				break
+			}
+		}
+		for cut > 1 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = text[cut:]
+	}
+	if strings.TrimSpace(text) != "" {
+		chunks = append(chunks, strings.TrimSpace(text))
+	}
+	return chunks
+}
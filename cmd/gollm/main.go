@@ -1,153 +1,218 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/teilomillet/gollm"
-	"github.com/teilomillet/gollm/optimizer"
-	"github.com/teilomillet/gollm/presets"
-	"github.com/teilomillet/gollm/utils"
 )
 
+// command is a CLI subcommand. setup registers the command's flags on fs and
+// returns the function that runs it once the flags are parsed.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	setup   func(fs *flag.FlagSet) func() error
+}
+
+// commands lists the subcommands in the order they are shown in the help.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"generate", "generate [flags] <prompt>", "Generate a response for a prompt (default)", setupGenerate("raw")},
+		{"chat", "chat [flags]", "Start an interactive chat", setupChat},
+		{"optimize", "optimize [flags] <prompt>", "Optimize a prompt", setupGenerate("optimize")},
+		{"models", "models [flags]", "List the models available from a provider", setupModels},
+		{"providers", "providers", "List the supported providers", setupProviders},
+		{"config", "config <path|list|get|set|unset> [key] [value]", "Show or edit the CLI configuration file", setupConfig},
+		{"completion", "completion <bash|zsh|fish>", "Print a shell completion script", setupCompletion},
+	}
+}
+
 func main() {
-	// Existing flags
-	promptType := flag.String("type", "raw", "Prompt type (raw, qa, cot, summarize, optimize)")
-	verbose := flag.Bool("verbose", false, "Display verbose output including full prompt")
-	provider := flag.String("provider", "", "LLM provider (anthropic, openai, groq, mistral, ollama, cohere)")
-	model := flag.String("model", "", "LLM model")
-	temperature := flag.Float64("temperature", -1, "LLM temperature")
-	maxTokens := flag.Int("max-tokens", 0, "LLM max tokens")
-	timeout := flag.Duration("timeout", 0, "LLM timeout")
-	apiKey := flag.String("api-key", "", "API key for the specified provider")
-	maxRetries := flag.Int("max-retries", 3, "Maximum number of retries for API calls")
-	retryDelay := flag.Duration("retry-delay", time.Second*2, "Delay between retries")
-	debugLevel := flag.String("debug-level", "warn", "Debug level (debug, info, warn, error)")
-	outputFormat := flag.String("output-format", "", "Output format for structured responses (json)")
-
-	// New flags for prompt optimization
-	optimizeGoal := flag.String("optimize-goal", "Improve the prompt's clarity and effectiveness", "Optimization goal")
-	optimizeIterations := flag.Int("optimize-iterations", 5, "Number of optimization iterations")
-	optimizeMemory := flag.Int("optimize-memory", 2, "Number of previous iterations to remember")
-
-	flag.Parse()
-
-	// Prepare configuration options
-	configOpts := prepareConfigOptions(provider, model, temperature, maxTokens, timeout, apiKey, maxRetries, retryDelay, debugLevel)
-
-	// Create LLM client with the specified options
-	llmClient, err := gollm.NewLLM(configOpts...)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating LLM client: %v\n", err)
-		os.Exit(1)
+	args := os.Args[1:]
+	cmd := findCommand("generate")
+
+	switch {
+	case len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help"):
+		printUsage()
+		return
+	case len(args) > 0 && findCommand(args[0]) != nil:
+		cmd, args = findCommand(args[0]), args[1:]
+	case len(args) > 0 && isPromptType(args[0]):
+		// Allow the prompt type as a leading word, e.g. `cat doc.md | gollm summarize`
+		args = append([]string{"-type", args[0]}, args[1:]...)
 	}
 
-	if len(flag.Args()) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <prompt>\n", os.Args[0])
-		flag.PrintDefaults()
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\n%s\n\nFlags:\n", os.Args[0], cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	run := cmd.setup(fs)
+	_ = fs.Parse(args)
+
+	if err := run(); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+			fs.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	rawPrompt := strings.Join(flag.Args(), " ")
-	ctx := context.Background()
-
-	var response string
-	var fullPrompt string
-
-	switch *promptType {
-	case "qa":
-		response, err = presets.QuestionAnswer(ctx, llmClient, rawPrompt)
-	case "cot":
-		response, err = presets.ChainOfThought(ctx, llmClient, rawPrompt)
-	case "summarize":
-		response, err = presets.Summarize(ctx, llmClient, rawPrompt)
-	case "optimize":
-		optimizer := optimizer.NewPromptOptimizer(
-			llmClient,
-			utils.NewDebugManager(
-				llmClient.GetLogger(),
-				utils.DebugOptions{LogPrompts: true, LogResponses: true}),
-			llmClient.NewPrompt(rawPrompt),
-			*optimizeGoal,
-			optimizer.WithIterations(*optimizeIterations),
-			optimizer.WithMemorySize(*optimizeMemory),
-		)
-		optimizedPrompt, err := optimizer.OptimizePrompt(ctx)
-		if err == nil {
-			response = optimizedPrompt.Input
-			fullPrompt = fmt.Sprintf("Initial Prompt: %s\nOptimization Goal: %s\nMemory Size: %d", rawPrompt, *optimizeGoal, *optimizeMemory)
-		}
-	default:
-		prompt := gollm.NewPrompt(rawPrompt)
-		if *outputFormat == "json" {
-			prompt.Apply(gollm.WithOutput("Please provide your response in JSON format."))
+// usageError reports invalid arguments; the command usage is printed after it.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
 		}
-		response, err = llmClient.Generate(ctx, prompt, gollm.WithJSONSchemaValidation())
-		fullPrompt = prompt.String()
 	}
+	return nil
+}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating response: %v\n", err)
-		os.Exit(1)
+func printUsage() {
+	fmt.Printf("Usage: %s <command> [flags] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Printf("  %-12s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Printf("\nWithout a command, arguments are passed to generate.\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
 
-	printResponse(*verbose, *promptType, fullPrompt, rawPrompt, response, *outputFormat)
+// configFlags holds the flags shared by all commands that configure the LLM client.
+type configFlags struct {
+	fs          *flag.FlagSet
+	configPath  *string
+	profile     *string
+	provider    *string
+	model       *string
+	temperature *float64
+	maxTokens   *int
+	timeout     *time.Duration
+	apiKey      *string
+	maxRetries  *int
+	retryDelay  *time.Duration
+	debugLevel  *string
 }
 
-func prepareConfigOptions(provider, model *string, temperature *float64, maxTokens *int, timeout *time.Duration, apiKey *string, maxRetries *int, retryDelay *time.Duration, debugLevel *string) []gollm.ConfigOption {
+// registerConfigFlags defines the LLM configuration flags on fs.
+func registerConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		fs:          fs,
+		configPath:  fs.String("config", defaultConfigPath(), "CLI configuration file (YAML, TOML or JSON)"),
+		profile:     fs.String("profile", "", "Profile to use from the configuration file"),
+		provider:    fs.String("provider", "", "LLM provider (anthropic, openai, groq, mistral, ollama, cohere)"),
+		model:       fs.String("model", "", "LLM model"),
+		temperature: fs.Float64("temperature", -1, "LLM temperature"),
+		maxTokens:   fs.Int("max-tokens", 0, "LLM max tokens"),
+		timeout:     fs.Duration("timeout", 0, "LLM timeout"),
+		apiKey:      fs.String("api-key", "", "API key for the specified provider"),
+		maxRetries:  fs.Int("max-retries", 3, "Maximum number of retries for API calls"),
+		retryDelay:  fs.Duration("retry-delay", time.Second*2, "Delay between retries"),
+		debugLevel:  fs.String("debug-level", "warn", "Debug level (debug, info, warn, error)"),
+	}
+}
+
+// options converts the flags into configuration options. Flags with a
+// non-empty default only apply when set explicitly, so they do not override
+// values from the configuration file.
+func (f *configFlags) options() []gollm.ConfigOption {
+	set := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
 	var configOpts []gollm.ConfigOption
 
-	if *provider != "" {
-		configOpts = append(configOpts, gollm.SetProvider(*provider))
+	if *f.provider != "" {
+		configOpts = append(configOpts, gollm.SetProvider(*f.provider))
+	}
+	if *f.model != "" {
+		configOpts = append(configOpts, gollm.SetModel(*f.model))
+	}
+	if *f.temperature != -1 {
+		configOpts = append(configOpts, gollm.SetTemperature(*f.temperature))
+	}
+	if *f.maxTokens != 0 {
+		configOpts = append(configOpts, gollm.SetMaxTokens(*f.maxTokens))
 	}
-	if *model != "" {
-		configOpts = append(configOpts, gollm.SetModel(*model))
+	if *f.timeout != 0 {
+		configOpts = append(configOpts, gollm.SetTimeout(*f.timeout))
 	}
-	if *temperature != -1 {
-		configOpts = append(configOpts, gollm.SetTemperature(*temperature))
+	if *f.apiKey != "" {
+		configOpts = append(configOpts, gollm.SetAPIKey(*f.apiKey))
 	}
-	if *maxTokens != 0 {
-		configOpts = append(configOpts, gollm.SetMaxTokens(*maxTokens))
+	if set["max-retries"] {
+		configOpts = append(configOpts, gollm.SetMaxRetries(*f.maxRetries))
 	}
-	if *timeout != 0 {
-		configOpts = append(configOpts, gollm.SetTimeout(*timeout))
+	if set["retry-delay"] {
+		configOpts = append(configOpts, gollm.SetRetryDelay(*f.retryDelay))
 	}
-	if *apiKey != "" {
-		configOpts = append(configOpts, gollm.SetAPIKey(*apiKey))
+	if set["debug-level"] {
+		configOpts = append(configOpts, gollm.SetLogLevel(gollm.LogLevel(getLogLevel(*f.debugLevel))))
 	}
-	configOpts = append(configOpts, gollm.SetMaxRetries(*maxRetries))
-	configOpts = append(configOpts, gollm.SetRetryDelay(*retryDelay))
-	configOpts = append(configOpts, gollm.SetLogLevel(gollm.LogLevel(getLogLevel(*debugLevel))))
 
 	return configOpts
 }
 
-func printResponse(verbose bool, promptType, fullPrompt, rawPrompt, response, outputFormat string) {
-	if verbose {
-		if fullPrompt == "" {
-			fullPrompt = rawPrompt // For qa, cot, and summarize, we don't have access to the full prompt
-		}
-		fmt.Printf("Prompt Type: %s\nFull Prompt:\n%s\n\nResponse:\n---------\n", promptType, fullPrompt)
-	}
-
-	if outputFormat == "json" {
-		var jsonResponse interface{}
-		err := json.Unmarshal([]byte(response), &jsonResponse)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing JSON response: %v\n", err)
-			fmt.Println(response) // Print raw response if JSON parsing fails
-		} else {
-			jsonPretty, _ := json.MarshalIndent(jsonResponse, "", "  ")
-			fmt.Println(string(jsonPretty))
+// hasConfigFile reports whether the configuration file exists.
+func (f *configFlags) hasConfigFile() bool {
+	if *f.configPath == "" {
+		return false
+	}
+	_, err := os.Stat(*f.configPath)
+	return err == nil
+}
+
+// loadConfig returns the configuration from the file when it exists, or from
+// the environment otherwise, with the flags applied on top. The -profile flag
+// takes precedence over GOLLM_PROFILE.
+func (f *configFlags) loadConfig(extra ...gollm.ConfigOption) (*gollm.Config, error) {
+	opts := append(f.options(), extra...)
+	if f.hasConfigFile() {
+		if *f.profile == "" {
+			// LoadFile selects the profile from GOLLM_PROFILE
+			return gollm.LoadFile(*f.configPath, opts...)
 		}
-	} else {
-		fmt.Println(response)
+		return gollm.LoadFileProfile(*f.configPath, *f.profile, opts...)
+	}
+	cfg, err := gollm.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	gollm.ApplyOptions(cfg, opts...)
+	return cfg, nil
+}
+
+// newClient creates an LLM client from the configuration file, if any, and the flags.
+func (f *configFlags) newClient(extra ...gollm.ConfigOption) (gollm.LLM, error) {
+	cfg, err := f.loadConfig(extra...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return gollm.NewLLMFromConfig(cfg)
+}
+
+// defaultConfigPath returns ~/.config/gollm/config.yaml, or an empty string
+// when the user configuration directory is unknown.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
 	}
+	return filepath.Join(dir, "gollm", "config.yaml")
 }
 
 func getLogLevel(level string) gollm.LogLevel {
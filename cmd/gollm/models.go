@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/providers"
+)
+
+// modelsEndpoint describes how to list the models of a provider.
+type modelsEndpoint struct {
+	baseURL string // API base URL, replaced by the one set with SetBaseURL
+	path    string // Path of the models list below the base URL
+	// versions are the API version suffixes, such as "/v1", removed from a
+	// configured base URL before the path is added, as the providers do.
+	versions []string
+	headers  func(apiKey string) map[string]string
+	// names extracts the model identifiers from the response body.
+	names func(body []byte) ([]string, error)
+}
+
+func bearerAuth(apiKey string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
+
+// dataIDs reads the OpenAI-style {"data": [{"id": ...}]} list.
+func dataIDs(body []byte) ([]string, error) {
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		names = append(names, m.ID)
+	}
+	return names, nil
+}
+
+// modelNames reads the {"models": [{"name": ...}]} list used by Cohere and Ollama.
+func modelNames(body []byte) ([]string, error) {
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// url returns the models list URL, below the configured base URL if any.
+func (e modelsEndpoint) url(configured string) string {
+	baseURL := e.baseURL
+	if configured != "" {
+		baseURL = strings.TrimRight(configured, "/")
+		for _, version := range e.versions {
+			baseURL = strings.TrimSuffix(baseURL, version)
+		}
+	}
+	return baseURL + e.path
+}
+
+var v1 = []string{"/v1"}
+
+var modelsEndpoints = map[string]modelsEndpoint{
+	"openai":   {baseURL: "https://api.openai.com", path: "/v1/models", versions: v1, headers: bearerAuth, names: dataIDs},
+	"groq":     {baseURL: "https://api.groq.com/openai", path: "/v1/models", versions: v1, headers: bearerAuth, names: dataIDs},
+	"mistral":  {baseURL: "https://api.mistral.ai", path: "/v1/models", versions: v1, headers: bearerAuth, names: dataIDs},
+	"cohere":   {baseURL: "https://api.cohere.com", path: "/v1/models", versions: []string{"/v1", "/v2"}, headers: bearerAuth, names: modelNames},
+	"deepseek": {baseURL: "https://api.deepseek.com", path: "/models", headers: bearerAuth, names: dataIDs},
+	"anthropic": {
+		baseURL:  "https://api.anthropic.com",
+		path:     "/v1/models",
+		versions: v1,
+		headers: func(apiKey string) map[string]string {
+			return map[string]string{"x-api-key": apiKey, "anthropic-version": "2023-06-01"}
+		},
+		names: dataIDs,
+	},
+}
+
+// setupModels defines the models command, which lists the models available
+// from the configured provider. It uses the configured base URL, HTTP client
+// and proxy, like generation requests do.
+func setupModels(fs *flag.FlagSet) func() error {
+	llmFlags := registerConfigFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the models as a JSON array")
+
+	return func() error {
+		cfg, err := llmFlags.loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		endpoint, ok := modelsEndpoints[cfg.Provider]
+		url := endpoint.url(cfg.Endpoints[cfg.Provider])
+		if cfg.Provider == "ollama" {
+			url = strings.TrimRight(cfg.OllamaEndpoint, "/") + "/api/tags"
+			endpoint = modelsEndpoint{
+				headers: func(string) map[string]string { return nil },
+				names:   modelNames,
+			}
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("listing models is not supported for provider %s", cfg.Provider)
+		}
+
+		apiKey := cfg.APIKeys[cfg.Provider]
+		if apiKey == "" && cfg.Provider != "ollama" {
+			return fmt.Errorf("no API key configured for provider %s", cfg.Provider)
+		}
+
+		client, err := gollm.NewHTTPClient(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP client configuration: %w", err)
+		}
+		names, err := listModels(client, url, endpoint, apiKey)
+		if err != nil {
+			return fmt.Errorf("failed to list %s models: %w", cfg.Provider, err)
+		}
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(names)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+}
+
+func listModels(client *http.Client, url string, endpoint modelsEndpoint, apiKey string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range endpoint.headers(apiKey) {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+
+	names, err := endpoint.names(body)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// setupProviders defines the providers command, which lists the registered providers.
+func setupProviders(fs *flag.FlagSet) func() error {
+	return func() error {
+		for _, name := range providers.NewProviderRegistry().Names() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+}
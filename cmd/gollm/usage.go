@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/teilomillet/gollm"
+)
+
+// errMaxCost aborts the invocation when the next request could exceed -max-cost.
+var errMaxCost = errors.New("maximum cost reached")
+
+// defaultCompletionEstimate bounds the completion of requests without max_tokens.
+const defaultCompletionEstimate = 4096
+
+// usageMeter totals token usage and cost across every request of one
+// invocation, including the several requests made by chunked summaries,
+// prompt optimization and schema retries.
+type usageMeter struct {
+	showUsage *bool
+	maxCost   *float64
+
+	mu       sync.Mutex
+	provider string
+	model    string
+	cancel   context.CancelCauseFunc
+	total    gollm.Usage
+	cost     float64
+	unpriced bool
+	requests int
+}
+
+// registerUsageFlags defines -show-usage and -max-cost on fs.
+func registerUsageFlags(fs *flag.FlagSet) *usageMeter {
+	return &usageMeter{
+		showUsage: fs.Bool("show-usage", false, "Print token usage and estimated cost to stderr after generating"),
+		maxCost:   fs.Float64("max-cost", 0, "Abort before a request could push the estimated cost of this invocation above this many US dollars"),
+	}
+}
+
+// options returns the hooks that meter requests, or nothing when neither flag is set.
+func (m *usageMeter) options() []gollm.ConfigOption {
+	if !*m.showUsage && *m.maxCost <= 0 {
+		return nil
+	}
+	return []gollm.ConfigOption{
+		gollm.SetBeforeRequestHook(m.beforeRequest),
+		gollm.SetAfterResponseHook(m.afterResponse),
+	}
+}
+
+// start records the client in use and returns a context that is cancelled
+// once the cost cap is reached, which stops further retries.
+func (m *usageMeter) start(ctx context.Context, client gollm.LLM) context.Context {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.provider, m.model = client.GetProvider(), client.GetModel()
+	ctx, m.cancel = context.WithCancelCause(ctx)
+	return ctx
+}
+
+// pricing returns the price of model; local Ollama models are free.
+func (m *usageMeter) pricing(model string) (gollm.ModelPricing, bool) {
+	if m.provider == "ollama" {
+		return gollm.ModelPricing{}, true
+	}
+	return gollm.LookupPricing(model)
+}
+
+// beforeRequest refuses requests whose worst-case cost, estimated from the
+// request size and its max_tokens, would exceed -max-cost.
+func (m *usageMeter) beforeRequest(req *http.Request, body []byte) ([]byte, error) {
+	if *m.maxCost <= 0 {
+		return body, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	model, maxTokens := m.requestModel(body)
+	pricing, ok := m.pricing(model)
+	if !ok {
+		return nil, fmt.Errorf("no pricing known for model %s, so -max-cost cannot be enforced", model)
+	}
+
+	// Roughly four bytes per token; the JSON envelope makes this an overestimate
+	estimate := pricing.Cost(gollm.Usage{PromptTokens: len(body) / 4, CompletionTokens: maxTokens})
+	if m.cost+estimate > *m.maxCost {
+		err := fmt.Errorf("%w: the next request could cost up to $%.4f, which would exceed -max-cost $%.4f ($%.4f spent)", errMaxCost, estimate, *m.maxCost, m.cost)
+		if m.cancel != nil {
+			m.cancel(err)
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// afterResponse adds the usage reported in the response to the totals.
+func (m *usageMeter) afterResponse(resp *http.Response, body []byte) ([]byte, error) {
+	if body == nil || resp.StatusCode != http.StatusOK {
+		return body, nil
+	}
+	usage, ok := gollm.ParseUsage(body)
+	if !ok {
+		return body, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	m.total.Add(usage)
+	if pricing, ok := m.pricing(m.responseModel(body)); ok {
+		m.cost += pricing.Cost(usage)
+	} else {
+		m.unpriced = true
+	}
+	return body, nil
+}
+
+// requestModel reads the model and completion limit from a request body.
+func (m *usageMeter) requestModel(body []byte) (string, int) {
+	var request struct {
+		Model               string `json:"model"`
+		MaxTokens           int    `json:"max_tokens"`
+		MaxCompletionTokens int    `json:"max_completion_tokens"`
+	}
+	_ = json.Unmarshal(body, &request)
+
+	model, maxTokens := request.Model, request.MaxTokens
+	if model == "" {
+		model = m.model
+	}
+	if request.MaxCompletionTokens > 0 {
+		maxTokens = request.MaxCompletionTokens
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultCompletionEstimate
+	}
+	return model, maxTokens
+}
+
+// responseModel reads the model from a response body, falling back to the
+// configured model.
+func (m *usageMeter) responseModel(body []byte) string {
+	var response struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &response) != nil || response.Model == "" {
+		return m.model
+	}
+	return response.Model
+}
+
+// report prints the totals when -show-usage is set.
+func (m *usageMeter) report(w io.Writer) {
+	if !*m.showUsage {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "Usage: %d prompt tokens (%d cached), %d completion tokens, %d total over %d request(s)\n",
+		m.total.PromptTokens, m.total.CachedTokens, m.total.CompletionTokens, m.total.TotalTokens, m.requests)
+	if m.unpriced {
+		fmt.Fprintf(w, "Estimated cost: unknown (no pricing for %s/%s)\n", m.provider, m.model)
+		return
+	}
+	fmt.Fprintf(w, "Estimated cost: $%.6f\n", m.cost)
+}
@@ -0,0 +1,76 @@
+package gollm
+
+import (
+	"context"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// ComparisonResult is the response, latency and usage of one LLM in a comparison.
+	ComparisonResult = llm.ComparisonResult
+
+	// CompareOption configures a comparison.
+	CompareOption = llm.CompareOption
+)
+
+var (
+	// WithJudge ranks compared responses with a judge LLM.
+	WithJudge = llm.WithJudge
+
+	// WithCompareGenerateOptions passes options to every Generate call of a comparison.
+	WithCompareGenerateOptions = llm.WithCompareGenerateOptions
+
+	// WithCompareConcurrency limits the number of LLMs called at the same time.
+	WithCompareConcurrency = llm.WithCompareConcurrency
+)
+
+// Compare sends the prompt to every LLM concurrently and returns their
+// responses, latencies and token usage, optionally ranked by a judge. See
+// llm.Compare.
+func Compare(ctx context.Context, llms []LLM, prompt *Prompt, opts ...CompareOption) ([]ComparisonResult, error) {
+	base := make([]llm.LLM, len(llms))
+	for i, l := range llms {
+		base[i] = l
+	}
+	return llm.Compare(ctx, base, prompt, opts...)
+}
+
+type (
+	// Ensemble queries several LLMs concurrently and combines their responses.
+	Ensemble = llm.Ensemble
+
+	// EnsembleStrategy selects how an ensemble combines responses.
+	EnsembleStrategy = llm.EnsembleStrategy
+
+	// EnsembleOption configures an ensemble.
+	EnsembleOption = llm.EnsembleOption
+
+	// EnsembleResult is the combined response of an ensemble.
+	EnsembleResult = llm.EnsembleResult
+)
+
+// Strategies of an ensemble.
+const (
+	EnsembleVote  = llm.EnsembleVote
+	EnsembleJudge = llm.EnsembleJudge
+	EnsembleMerge = llm.EnsembleMerge
+)
+
+var (
+	// WithEnsembleJudge sets the judge of the judge strategy.
+	WithEnsembleJudge = llm.WithEnsembleJudge
+
+	// WithMinAgreement sets the share of members that must agree on a result.
+	WithMinAgreement = llm.WithMinAgreement
+)
+
+// NewEnsemble creates an ensemble that combines the responses of its members
+// by voting, judge selection or field-wise merge. See llm.NewEnsemble.
+func NewEnsemble(members []LLM, strategy EnsembleStrategy, opts ...EnsembleOption) (*Ensemble, error) {
+	base := make([]llm.LLM, len(members))
+	for i, l := range members {
+		base[i] = l
+	}
+	return llm.NewEnsemble(base, strategy, opts...)
+}
@@ -0,0 +1,10 @@
+// Package gollm provides raw text completion for Language Learning Models.
+// This file contains re-exports for continuing text without a chat template.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Complete continues text using the provider's raw completion endpoint, without chat formatting.
+var Complete = llm.Complete
@@ -0,0 +1,24 @@
+// Package gollm provides prompt context compression for Language Learning Models.
+// This file contains re-exports for shrinking long context to a token budget.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Compressor shrinks text to about a token budget, keeping what matters for a query.
+type Compressor = llm.Compressor
+
+var (
+	// WithContextCompression shrinks the prompt's context and documents to about a token budget.
+	WithContextCompression = llm.WithContextCompression
+
+	// WithContextCompressor shrinks the prompt's context and documents with a given Compressor.
+	WithContextCompressor = llm.WithContextCompressor
+
+	// HeuristicCompressor keeps the most informative sentences within the budget.
+	HeuristicCompressor = llm.HeuristicCompressor
+
+	// ModelCompressor asks a small model to rewrite the text within the budget.
+	ModelCompressor = llm.ModelCompressor
+)
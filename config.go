@@ -5,6 +5,7 @@ package gollm
 
 import (
 	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/llm"
 	"github.com/teilomillet/gollm/utils"
 )
 
@@ -43,6 +44,81 @@ type (
 	//   cfg := NewConfig()
 	//   cfg = ApplyOptions(cfg, SetMemory(MemoryOption{MaxHistory: 10}))
 	MemoryOption = config.MemoryOption
+
+	// CircuitBreakerConfig configures the per-endpoint circuit breaker.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, CoolDown: time.Minute}))
+	CircuitBreakerConfig = config.CircuitBreakerConfig
+
+	// APIKeyPoolConfig configures a pool of API keys used in rotation.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetAPIKeyPool(APIKeyPoolConfig{
+	//       Strategy: KeyPoolLeastLoaded,
+	//       Keys:     []PooledKey{{APIKey: key1, RequestsPerSecond: 5}, {APIKey: key2, RequestsPerSecond: 5}},
+	//   }))
+	APIKeyPoolConfig = config.APIKeyPoolConfig
+
+	// ObservabilityConfig exports a trace of every call to Langfuse or LangSmith.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetObservability(ObservabilityConfig{Backend: ObservabilityLangfuse}))
+	ObservabilityConfig = config.ObservabilityConfig
+
+	// ObservabilityHeaders holds the tracing, caching and retry settings sent to Portkey or Helicone as headers.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetObservabilityHeaders(GatewayHelicone, ObservabilityHeaders{Cache: true, SessionID: id}))
+	ObservabilityHeaders = config.ObservabilityHeaders
+
+	// ObservabilityGateway names the header conventions of an observability gateway.
+	ObservabilityGateway = config.ObservabilityGateway
+
+	// KeyProvider supplies API keys at runtime, e.g. from a secrets manager.
+	// See the secrets package for AWS, GCP and Vault implementations.
+	KeyProvider = config.KeyProvider
+
+	// KeyProviderFunc adapts a function to the KeyProvider interface.
+	KeyProviderFunc = config.KeyProviderFunc
+
+	// ProviderEnvVars names the environment variables read for a provider.
+	//
+	// Example usage:
+	//   RegisterProviderEnvVars("gemini", ProviderEnvVars{APIKey: "GOOGLE_API_KEY"})
+	ProviderEnvVars = config.ProviderEnvVars
+
+	// SamplingProfile is a named set of sampling parameters for SetSamplingProfile.
+	SamplingProfile = config.SamplingProfile
+
+	// PooledKey is a single credential within an APIKeyPoolConfig.
+	PooledKey = config.PooledKey
+
+	// BeforeRequestHook inspects or mutates a provider request before it is sent.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetBeforeRequestHook(func(req *http.Request, body []byte) ([]byte, error) {
+	//       req.Header.Set("Authorization", "Bearer "+token())
+	//       return body, nil
+	//   }))
+	BeforeRequestHook = config.BeforeRequestHook
+
+	// AfterResponseHook inspects or mutates a provider response before it is parsed.
+	AfterResponseHook = config.AfterResponseHook
+
+	// SafetySetting is the blocking threshold of a Gemini harm category.
+	//
+	// Example usage:
+	//   llm, err := NewLLM(SetProvider("gemini"), SetSafetySettings(
+	//       SafetySetting{Category: HarmCategoryDangerousContent, Threshold: BlockOnlyHigh},
+	//   ))
+	SafetySetting = config.SafetySetting
+
+	// HarmCategory is a category of harmful content rated by Gemini's safety filters.
+	HarmCategory = config.HarmCategory
+
+	// HarmBlockThreshold is the probability of harm from which Gemini blocks content.
+	HarmBlockThreshold = config.HarmBlockThreshold
 )
 
 // Re-export core configuration functions
@@ -65,6 +141,29 @@ var (
 	//   cfg := NewConfig()
 	//   cfg = ApplyOptions(cfg, SetProvider("openai"), SetModel("gpt-3.5-turbo"))
 	ApplyOptions = config.ApplyOptions
+
+	// LoadFile loads configuration from a YAML, TOML or JSON file, applying the
+	// profile selected by GOLLM_PROFILE (or the file's default_profile) and then
+	// the given options.
+	//
+	// Example usage:
+	//   cfg, err := LoadFile("gollm.yaml", SetMaxTokens(500))
+	LoadFile = config.LoadFile
+
+	// LoadFileProfile is like LoadFile but selects the profile explicitly.
+	//
+	// Example usage:
+	//   cfg, err := LoadFileProfile("gollm.toml", "prod")
+	LoadFileProfile = config.LoadFileProfile
+
+	// RegisterProviderEnvVars sets the environment variables read for a provider.
+	RegisterProviderEnvVars = config.RegisterProviderEnvVars
+
+	// RegisterSamplingProfile adds a named sampling profile for SetSamplingProfile.
+	//
+	// Example usage:
+	//   RegisterSamplingProfile("focused", SamplingProfile{Temperature: 0.2, TopK: 20})
+	RegisterSamplingProfile = config.RegisterSamplingProfile
 )
 
 // Re-export ConfigOption functions for configuration modification.
@@ -75,15 +174,31 @@ var (
 	SetProvider       = config.SetProvider       // Sets the LLM provider (e.g., "openai", "anthropic")
 	SetModel          = config.SetModel          // Sets the model name for the selected provider
 	SetOllamaEndpoint = config.SetOllamaEndpoint // Sets the endpoint URL for Ollama local deployment
+	SetBaseURL        = config.SetBaseURL        // Points a provider at a gateway, proxy or self-hosted endpoint
 	SetAPIKey         = config.SetAPIKey         // Sets the API key for the current provider
+	SetEnvPrefix      = config.SetEnvPrefix      // Reloads settings from environment variables with a prefix
+	SetKeyProvider    = config.SetKeyProvider    // Fetches and rotates the API key through a KeyProvider
 
 	// Generation parameters
 	SetTemperature      = config.SetTemperature      // Controls randomness in generation (0.0-1.0)
 	SetMaxTokens        = config.SetMaxTokens        // Sets maximum tokens to generate
 	SetTopP             = config.SetTopP             // Controls nucleus sampling
+	SetTopK             = config.SetTopK             // Limits sampling to the k most likely tokens
 	SetFrequencyPenalty = config.SetFrequencyPenalty // Penalizes frequent token usage
 	SetPresencePenalty  = config.SetPresencePenalty  // Penalizes repeated tokens
 	SetSeed             = config.SetSeed             // Sets random seed for reproducible generation
+	SetServiceTier      = config.SetServiceTier      // Selects the provider's processing tier
+	SetStrictOptions    = config.SetStrictOptions    // Fails instead of warning on sampling options the provider ignores
+	SetSamplingProfile  = config.SetSamplingProfile  // Applies a named set of sampling parameters
+	SetReproducible     = config.SetReproducible     // Forces temperature 0 and a fixed seed for repeatable outputs
+	SetSafetySettings   = config.SetSafetySettings   // Sets the blocking thresholds of Gemini's safety filters
+
+	// SetContextFallbackModel switches prompts too long for the model to a long-context model
+	SetContextFallbackModel = config.SetContextFallbackModel
+
+	// OpenAI billing attribution
+	SetOpenAIOrganization = config.SetOpenAIOrganization // Sends the OpenAI-Organization header
+	SetOpenAIProject      = config.SetOpenAIProject      // Sends the OpenAI-Project header
 
 	// Advanced generation parameters
 	SetMinP          = config.SetMinP          // Sets minimum probability threshold
@@ -95,20 +210,105 @@ var (
 	SetTfsZ          = config.SetTfsZ          // Sets tail-free sampling parameter
 
 	// Runtime configuration
-	SetTimeout      = config.SetTimeout      // Sets request timeout duration
-	SetMaxRetries   = config.SetMaxRetries   // Sets maximum retry attempts
-	SetRetryDelay   = config.SetRetryDelay   // Sets delay between retries
-	SetLogLevel     = config.SetLogLevel     // Sets logging verbosity
-	SetExtraHeaders = config.SetExtraHeaders // Sets additional HTTP headers
+	SetTimeout        = config.SetTimeout        // Sets the overall deadline including retries
+	SetAttemptTimeout = config.SetAttemptTimeout // Sets the timeout for each individual attempt
+	SetMaxRetries     = config.SetMaxRetries     // Sets maximum retry attempts
+	SetRetryDelay     = config.SetRetryDelay     // Sets delay between retries
+	SetLogLevel       = config.SetLogLevel       // Sets logging verbosity
+	SetExtraHeaders   = config.SetExtraHeaders   // Sets additional HTTP headers
+	SetHTTPClient     = config.SetHTTPClient     // Sets a custom HTTP client for all provider calls
+	SetProxy          = config.SetProxy          // Routes provider calls through a proxy
+	NewHTTPClient     = llm.NewHTTPClient        // Builds the HTTP client configured for provider calls
+
+	// Request/response hooks
+	SetBeforeRequestHook = config.SetBeforeRequestHook // Runs a hook before every provider request
+	SetAfterResponseHook = config.SetAfterResponseHook // Runs a hook after every provider response
+
+	// Resilience and throughput
+	SetCircuitBreaker = config.SetCircuitBreaker // Enables a per-endpoint circuit breaker
+	SetAPIKeyPool     = config.SetAPIKeyPool     // Spreads requests across a pool of API keys
+
+	// Response cleaning
+	SetCleanStrategy = config.SetCleanStrategy // Sets how generated text is cleaned
+
+	// System prompt
+	SetDefaultSystemPrompt = config.SetDefaultSystemPrompt // Sets the system prompt of every request
+	SetSystemPromptPolicy  = config.SetSystemPromptPolicy  // Sets how a Prompt's system prompt combines with the default
+
+	// Observability
+	SetObservability        = config.SetObservability        // Exports a trace of every call to Langfuse or LangSmith
+	SetObservabilityHeaders = config.SetObservabilityHeaders // Sends Portkey or Helicone headers with every request
 
 	// Feature toggles
-	SetEnableCaching = config.SetEnableCaching // Enables/disables response caching
-	SetMemory        = config.SetMemory        // Configures conversation memory
+	SetEnableCaching         = config.SetEnableCaching         // Enables/disables response caching
+	SetMemory                = config.SetMemory                // Configures conversation memory
+	SetPersistPartialStreams = config.SetPersistPartialStreams // Keeps partial replies of cancelled streams in memory
 
 	// Configuration creation
 	NewConfig = config.NewConfig // Creates a new Config with default values
 )
 
+// ErrUnsupportedOption is wrapped by the error NewLLM returns when
+// SetStrictOptions is enabled and the provider ignores a sampling option.
+var ErrUnsupportedOption = llm.ErrUnsupportedOption
+
+// Key pool strategies for APIKeyPoolConfig
+const (
+	KeyPoolRoundRobin  = config.KeyPoolRoundRobin  // Cycles through keys in order
+	KeyPoolLeastLoaded = config.KeyPoolLeastLoaded // Picks the key with the fewest in-flight requests
+)
+
+// Response cleaning strategies for SetCleanStrategy
+const (
+	CleanNone       = config.CleanNone       // Returns the generated text unchanged
+	CleanMinimal    = config.CleanMinimal    // Trims whitespace and a code fence around the text
+	CleanAggressive = config.CleanAggressive // Also extracts the JSON object, like CleanResponse
+)
+
+// System prompt merge policies for SetSystemPromptPolicy
+const (
+	SystemPromptReplace = config.SystemPromptReplace // Uses the Prompt's system prompt instead of the default
+	SystemPromptPrepend = config.SystemPromptPrepend // Puts the Prompt's system prompt before the default
+	SystemPromptAppend  = config.SystemPromptAppend  // Puts the Prompt's system prompt after the default
+)
+
+// Sampling profiles for SetSamplingProfile
+const (
+	SamplingDeterministic = config.SamplingDeterministic // Temperature 0 and, where supported, top-k 1
+	SamplingBalanced      = config.SamplingBalanced      // Temperature 0.7 and top-p 0.9
+	SamplingCreative      = config.SamplingCreative      // Temperature 1 and top-p 0.95
+)
+
+// Observability backends for ObservabilityConfig
+const (
+	ObservabilityLangfuse  = config.ObservabilityLangfuse  // Exports traces to Langfuse
+	ObservabilityLangSmith = config.ObservabilityLangSmith // Exports traces to LangSmith
+)
+
+// Header conventions for SetObservabilityHeaders
+const (
+	GatewayPortkey  = config.GatewayPortkey  // Emits x-portkey-* headers
+	GatewayHelicone = config.GatewayHelicone // Emits Helicone-* headers
+)
+
+// Gemini harm categories for SafetySetting
+const (
+	HarmCategoryHarassment       = config.HarmCategoryHarassment       // Negative or harmful comments targeting identity
+	HarmCategoryHateSpeech       = config.HarmCategoryHateSpeech       // Rude, disrespectful or profane content
+	HarmCategorySexuallyExplicit = config.HarmCategorySexuallyExplicit // References to sexual acts or lewd content
+	HarmCategoryDangerousContent = config.HarmCategoryDangerousContent // Promotes or facilitates harmful acts
+	HarmCategoryCivicIntegrity   = config.HarmCategoryCivicIntegrity   // Election-related queries
+)
+
+// Gemini blocking thresholds for SafetySetting
+const (
+	BlockLowAndAbove    = config.BlockLowAndAbove    // Blocks a low or higher probability of harm
+	BlockMediumAndAbove = config.BlockMediumAndAbove // Blocks a medium or high probability of harm
+	BlockOnlyHigh       = config.BlockOnlyHigh       // Blocks a high probability of harm
+	BlockNone           = config.BlockNone           // Blocks nothing but still rates content
+	BlockOff            = config.BlockOff            // Turns the filter off
+)
+
 // LogLevel constants define available logging verbosity levels
 const (
 	LogLevelOff   = utils.LogLevelOff   // Disables all logging
@@ -4,6 +4,8 @@
 package config
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -18,12 +20,153 @@ type MemoryOption struct {
 	// MaxTokens specifies the maximum number of tokens to retain in memory
 	// for context in subsequent interactions.
 	MaxTokens int
+
+	// PersistPartialStreams stores the partial assistant reply of a stream
+	// that is closed before it completes.
+	PersistPartialStreams bool
+}
+
+// CircuitBreakerConfig controls the per-endpoint circuit breaker. After
+// FailureThreshold consecutive failures the circuit opens and calls fail fast
+// for CoolDown, after which a single trial request decides whether it closes again.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit.
+	FailureThreshold int
+
+	// CoolDown is how long the circuit stays open before allowing a trial request.
+	CoolDown time.Duration
+}
+
+// BeforeRequestHook is called before every provider request is sent. It may
+// modify the method, URL and headers of req and returns the request body to
+// send, which allows adding idempotency keys, custom authentication or request
+// signatures. Returning an error aborts the attempt.
+type BeforeRequestHook func(req *http.Request, body []byte) ([]byte, error)
+
+// AfterResponseHook is called after every provider response is received, before
+// it is parsed. It returns the body to parse, which allows auditing or rewriting
+// raw payloads. For streaming responses body is nil and the return value is
+// ignored. Returning an error fails the attempt.
+type AfterResponseHook func(resp *http.Response, body []byte) ([]byte, error)
+
+// KeyProvider supplies API keys at runtime, typically from a secrets manager.
+// GetAPIKey is called when the LLM is created and again whenever the cached key
+// expires or is rejected by the provider, so rotated keys are picked up without
+// a restart. Implementations must be safe for concurrent use.
+type KeyProvider interface {
+	GetAPIKey(ctx context.Context, provider string) (string, error)
+}
+
+// KeyProviderFunc adapts an ordinary function to the KeyProvider interface.
+type KeyProviderFunc func(ctx context.Context, provider string) (string, error)
+
+// GetAPIKey calls f(ctx, provider).
+func (f KeyProviderFunc) GetAPIKey(ctx context.Context, provider string) (string, error) {
+	return f(ctx, provider)
+}
+
+// Key pool selection strategies for APIKeyPoolConfig.Strategy.
+const (
+	// KeyPoolRoundRobin cycles through the pooled keys in order.
+	KeyPoolRoundRobin = "round_robin"
+
+	// KeyPoolLeastLoaded picks the key with the fewest requests in flight.
+	KeyPoolLeastLoaded = "least_loaded"
+)
+
+// PooledKey is a single credential in an API key pool. Each key may carry its
+// own extra headers (e.g., an account or organization identifier) and its own
+// rate limit, so a pool can also represent several accounts or deployments.
+type PooledKey struct {
+	// APIKey is the credential used for requests routed to this entry.
+	APIKey string
+
+	// ExtraHeaders are added to requests routed to this entry.
+	ExtraHeaders map[string]string
+
+	// RequestsPerSecond limits the request rate for this key. Zero means unlimited.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests allowed above the steady rate (default: 1).
+	Burst int
+}
+
+// APIKeyPoolConfig spreads requests across several API keys for workloads that
+// exceed the quota of a single key.
+type APIKeyPoolConfig struct {
+	// Keys are the pooled credentials. At least one is required.
+	Keys []PooledKey
+
+	// Strategy selects the next key: KeyPoolRoundRobin (default) or KeyPoolLeastLoaded.
+	Strategy string
+}
+
+// Response cleaning strategies for SetCleanStrategy.
+const (
+	// CleanNone returns the generated text unchanged.
+	CleanNone = "none"
+
+	// CleanMinimal trims whitespace and a markdown code fence around the
+	// whole text.
+	CleanMinimal = "minimal"
+
+	// CleanAggressive also strips the text around the JSON object of the
+	// response, like gollm.CleanResponse.
+	CleanAggressive = "aggressive"
+)
+
+// Merge policies for SetSystemPromptPolicy, which combine the default system
+// prompt with the system prompt of a Prompt.
+const (
+	// SystemPromptReplace uses the Prompt's system prompt instead of the
+	// default.
+	SystemPromptReplace = "replace"
+
+	// SystemPromptPrepend puts the Prompt's system prompt before the default.
+	SystemPromptPrepend = "prepend"
+
+	// SystemPromptAppend puts the Prompt's system prompt after the default.
+	SystemPromptAppend = "append"
+)
+
+// Observability backends for ObservabilityConfig.Backend.
+const (
+	// ObservabilityLangfuse exports traces to Langfuse.
+	ObservabilityLangfuse = "langfuse"
+
+	// ObservabilityLangSmith exports traces to LangSmith.
+	ObservabilityLangSmith = "langsmith"
+)
+
+// ObservabilityConfig exports a trace of every call, with its prompt,
+// response, token usage, cost and scores, to an observability backend.
+// Credentials left empty are read from the backend's usual environment
+// variables: LANGFUSE_PUBLIC_KEY, LANGFUSE_SECRET_KEY and LANGFUSE_HOST for
+// Langfuse, and LANGSMITH_API_KEY, LANGSMITH_ENDPOINT and LANGSMITH_PROJECT
+// for LangSmith.
+type ObservabilityConfig struct {
+	// Backend is ObservabilityLangfuse or ObservabilityLangSmith.
+	Backend string
+
+	// Host is the URL of the backend's API, for self-hosted instances.
+	Host string
+
+	// PublicKey and SecretKey are the Langfuse project keys. LangSmith
+	// uses SecretKey as its API key.
+	PublicKey string
+	SecretKey string
+
+	// Project is the LangSmith project receiving the runs.
+	Project string
 }
 
 // Config represents the complete configuration for LLM interactions.
 // It supports configuration through environment variables, with sensible defaults
 // for most settings. API keys are automatically loaded from environment variables
 // matching the pattern *_API_KEY (e.g., OPENAI_API_KEY, ANTHROPIC_API_KEY).
+// Provider-specific variables for API keys, endpoints and organization IDs
+// (e.g., OPENAI_BASE_URL, OPENAI_ORG_ID) are read according to the mapping
+// managed by RegisterProviderEnvVars. Use SetEnvPrefix to namespace variables.
 //
 // Environment Variables:
 //   - LLM_PROVIDER: LLM provider name (default: "anthropic")
@@ -32,15 +175,27 @@ type MemoryOption struct {
 //   - LLM_TEMPERATURE: Generation temperature (default: 0.7)
 //   - LLM_MAX_TOKENS: Maximum tokens to generate (default: 100)
 //   - LLM_TOP_P: Top-p sampling parameter (default: 0.9)
+//   - LLM_TOP_K: Top-k sampling parameter
 //   - LLM_FREQUENCY_PENALTY: Token frequency penalty (default: 0.0)
 //   - LLM_PRESENCE_PENALTY: Token presence penalty (default: 0.0)
-//   - LLM_TIMEOUT: Request timeout duration (default: 30s)
+//   - LLM_TIMEOUT: Overall deadline for a call, including retries (default: 30s)
+//   - LLM_ATTEMPT_TIMEOUT: Timeout for each individual attempt (default: none)
 //   - LLM_MAX_RETRIES: Maximum retry attempts (default: 3)
 //   - LLM_RETRY_DELAY: Delay between retries (default: 2s)
 //   - LLM_LOG_LEVEL: Logging verbosity (default: "WARN")
 //   - LLM_SEED: Random seed for reproducible generation
+//   - LLM_SERVICE_TIER: Processing tier for providers that offer several (e.g., Groq's "flex")
 //   - LLM_ENABLE_CACHING: Enable response caching (default: false)
 //   - LLM_ENABLE_STREAMING: Enable streaming responses (default: false)
+//   - LLM_PROXY_URL: Proxy URL applied to all provider calls
+//   - LLM_KEY_REFRESH_INTERVAL: How long keys from a KeyProvider are cached (default: until rejected)
+//   - LLM_OBSERVABILITY: Observability backend receiving traces ("langfuse" or "langsmith")
+//   - LLM_CLEAN_STRATEGY: Cleaning applied to generated text ("none", "minimal" or "aggressive"; default: "none")
+//   - LLM_SYSTEM_PROMPT_POLICY: How a Prompt's system prompt combines with the default ("replace", "prepend" or "append"; default: "replace")
+//   - LLM_SAMPLING_PROFILE: Named sampling parameters ("deterministic", "balanced" or "creative"), applied over LLM_TEMPERATURE and LLM_TOP_P
+//   - LLM_STRICT_OPTIONS: Fail instead of warning when the provider ignores the seed, top_p or top_k (default: false)
+//   - LLM_REPRODUCIBLE: Force temperature 0 and a fixed seed for repeatable outputs (default: false)
+//   - LLM_CONTEXT_FALLBACK_MODEL: Long-context model used for prompts that overflow the model's context window
 //
 // Advanced Parameters:
 //   - LLM_MIN_P: Minimum token probability threshold
@@ -57,14 +212,29 @@ type Config struct {
 	Temperature           float64           `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
 	MaxTokens             int               `env:"LLM_MAX_TOKENS" envDefault:"100"`
 	TopP                  float64           `env:"LLM_TOP_P" envDefault:"0.9" validate:"gte=0,lte=1"`
+	TopK                  *int              `env:"LLM_TOP_K"`
 	FrequencyPenalty      float64           `env:"LLM_FREQUENCY_PENALTY" envDefault:"0.0"`
 	PresencePenalty       float64           `env:"LLM_PRESENCE_PENALTY" envDefault:"0.0"`
 	Timeout               time.Duration     `env:"LLM_TIMEOUT" envDefault:"30s"`
+	AttemptTimeout        time.Duration     `env:"LLM_ATTEMPT_TIMEOUT"`
 	MaxRetries            int               `env:"LLM_MAX_RETRIES" envDefault:"3"`
 	RetryDelay            time.Duration     `env:"LLM_RETRY_DELAY" envDefault:"2s"`
 	APIKeys               map[string]string `validate:"required,apikey"`
+	Endpoints             map[string]string // Custom endpoints per provider
+	Organizations         map[string]string // Organization or project IDs per provider
+	Projects              map[string]string // Project IDs within the organization per provider
+	KeyProvider           KeyProvider       `env:"-"`
+	KeyRefreshInterval    time.Duration     `env:"LLM_KEY_REFRESH_INTERVAL"`
 	LogLevel              utils.LogLevel    `env:"LLM_LOG_LEVEL" envDefault:"WARN"`
 	Seed                  *int              `env:"LLM_SEED"`
+	ServiceTier           string            `env:"LLM_SERVICE_TIER"`
+	CleanStrategy         string            `env:"LLM_CLEAN_STRATEGY"`
+	SystemPromptPolicy    string            `env:"LLM_SYSTEM_PROMPT_POLICY"`
+	StrictOptions         bool              `env:"LLM_STRICT_OPTIONS"`
+	Reproducible          bool              `env:"LLM_REPRODUCIBLE"`
+	SafetySettings        []SafetySetting   `env:"-"`
+	ContextFallbackModel  string            `env:"LLM_CONTEXT_FALLBACK_MODEL"`
+	SamplingProfile       string            `env:"-"`
 	MinP                  *float64          `env:"LLM_MIN_P" envDefault:"0.05"`
 	RepeatPenalty         *float64          `env:"LLM_REPEAT_PENALTY" envDefault:"1.1"`
 	RepeatLastN           *int              `env:"LLM_REPEAT_LAST_N" envDefault:"64"`
@@ -78,6 +248,13 @@ type Config struct {
 	EnableCaching         bool `env:"LLM_ENABLE_CACHING" envDefault:"false"`
 	EnableStreaming       bool `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
 	MemoryOption          *MemoryOption
+	CircuitBreaker        *CircuitBreakerConfig
+	APIKeyPool            *APIKeyPoolConfig
+	Observability         *ObservabilityConfig
+	BeforeRequestHooks    []BeforeRequestHook `env:"-"`
+	AfterResponseHooks    []AfterResponseHook `env:"-"`
+	HTTPClient            *http.Client        `env:"-"`
+	ProxyURL              string              `env:"LLM_PROXY_URL"`
 }
 
 // LoadConfig creates a new Config instance, loading values from environment
@@ -103,12 +280,19 @@ func LoadConfig() (*Config, error) {
 	}
 
 	loadAPIKeys(cfg)
+	if profile := os.Getenv("LLM_SAMPLING_PROFILE"); profile != "" {
+		SetSamplingProfile(profile)(cfg)
+	}
+	if backend := os.Getenv("LLM_OBSERVABILITY"); backend != "" {
+		SetObservability(ObservabilityConfig{Backend: backend})(cfg)
+	}
 	return cfg, nil
 }
 
 // loadAPIKeys automatically detects and loads API keys from environment variables
-// matching the pattern *_API_KEY. It ensures the default provider has an API key
-// available.
+// matching the pattern *_API_KEY, followed by the provider-specific variables
+// registered with RegisterProviderEnvVars. It ensures the default provider has
+// an API key available.
 func loadAPIKeys(cfg *Config) {
 	for _, envVar := range os.Environ() {
 		key, value, found := strings.Cut(envVar, "=")
@@ -118,6 +302,8 @@ func loadAPIKeys(cfg *Config) {
 		}
 	}
 
+	loadProviderEnv(cfg, "")
+
 	// Ensure the default provider has an API key
 	if apiKey, exists := cfg.APIKeys[strings.ToUpper(cfg.Provider)]; exists {
 		cfg.APIKeys[cfg.Provider] = apiKey
@@ -185,6 +371,30 @@ func SetOllamaEndpoint(endpoint string) ConfigOption {
 	}
 }
 
+// SetBaseURL points a provider at another server than its API, such as an
+// OpenAI-compatible gateway (LiteLLM, Portkey), a corporate proxy or a
+// self-hosted Anthropic-compatible endpoint. It applies to the built-in
+// providers and overrides the provider's base URL environment variable,
+// such as OPENAI_BASE_URL. A trailing API version, such as /v1, is accepted.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.SetBaseURL("openai", "https://litellm.internal:4000/v1"),
+//	)
+func SetBaseURL(provider, url string) ConfigOption {
+	return func(c *Config) {
+		if c.Endpoints == nil {
+			c.Endpoints = make(map[string]string)
+		}
+		c.Endpoints[provider] = url
+		if provider == "ollama" {
+			c.OllamaEndpoint = url
+		}
+	}
+}
+
 // SetTemperature sets the generation temperature.
 func SetTemperature(temperature float64) ConfigOption {
 	return func(c *Config) {
@@ -202,13 +412,21 @@ func SetMaxTokens(maxTokens int) ConfigOption {
 	}
 }
 
-// SetTimeout sets the request timeout duration.
+// SetTimeout sets the overall deadline for a call, covering all retry attempts.
 func SetTimeout(timeout time.Duration) ConfigOption {
 	return func(c *Config) {
 		c.Timeout = timeout
 	}
 }
 
+// SetAttemptTimeout sets the timeout for each individual attempt. Every retry
+// gets a fresh budget, while SetTimeout still bounds the whole operation.
+func SetAttemptTimeout(timeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.AttemptTimeout = timeout
+	}
+}
+
 // SetAPIKey sets the API key for the specified provider.
 func SetAPIKey(apiKey string) ConfigOption {
 	return func(c *Config) {
@@ -219,6 +437,142 @@ func SetAPIKey(apiKey string) ConfigOption {
 	}
 }
 
+// SetKeyProvider fetches the API key from kp instead of static configuration.
+// The key is cached for refreshInterval (zero caches it until the provider
+// rejects it with 401 Unauthorized), after which it is fetched again.
+//
+// Example:
+//
+//	vault := secrets.NewVault(secrets.VaultConfig{
+//		Address: "https://vault.internal:8200",
+//		Names:   map[string]string{"openai": "gollm/openai"},
+//	})
+//	llm, err := gollm.NewLLM(gollm.SetProvider("openai"), gollm.SetKeyProvider(vault, 15*time.Minute))
+func SetKeyProvider(kp KeyProvider, refreshInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.KeyProvider = kp
+		c.KeyRefreshInterval = refreshInterval
+	}
+}
+
+// SetHTTPClient sets the HTTP client used for all provider calls.
+// This allows custom CA bundles, mTLS, connection-pool tuning or any other
+// transport customization. The client is never modified by gollm; when a proxy
+// is also configured, a copy of the client's transport is used instead.
+func SetHTTPClient(client *http.Client) ConfigOption {
+	return func(c *Config) {
+		c.HTTPClient = client
+	}
+}
+
+// SetProxy routes all provider calls through the given proxy URL
+// (e.g., "http://proxy.internal:3128"). The URL is validated when the LLM is created.
+func SetProxy(proxyURL string) ConfigOption {
+	return func(c *Config) {
+		c.ProxyURL = proxyURL
+	}
+}
+
+// SetBeforeRequestHook registers a hook that runs before every provider request.
+// Hooks run in registration order, each receiving the body returned by the previous one.
+//
+// Example:
+//
+//	config.SetBeforeRequestHook(func(req *http.Request, body []byte) ([]byte, error) {
+//		req.Header.Set("Idempotency-Key", uuid.NewString())
+//		return body, nil
+//	})
+func SetBeforeRequestHook(hook BeforeRequestHook) ConfigOption {
+	return func(c *Config) {
+		c.BeforeRequestHooks = append(c.BeforeRequestHooks, hook)
+	}
+}
+
+// SetAfterResponseHook registers a hook that runs after every provider response.
+// Hooks run in registration order, each receiving the body returned by the previous one.
+func SetAfterResponseHook(hook AfterResponseHook) ConfigOption {
+	return func(c *Config) {
+		c.AfterResponseHooks = append(c.AfterResponseHooks, hook)
+	}
+}
+
+// SetCircuitBreaker enables a circuit breaker for the provider endpoint.
+// Non-positive values fall back to 5 failures and a 30 second cool-down.
+// While the circuit is open calls fail fast with llm.ErrCircuitOpen; diverting
+// them to another provider is left to the caller.
+func SetCircuitBreaker(cb CircuitBreakerConfig) ConfigOption {
+	return func(c *Config) {
+		if cb.FailureThreshold <= 0 {
+			cb.FailureThreshold = 5
+		}
+		if cb.CoolDown <= 0 {
+			cb.CoolDown = 30 * time.Second
+		}
+		c.CircuitBreaker = &cb
+	}
+}
+
+// SetObservability exports a trace of every Generate call to Langfuse or
+// LangSmith. Calls made with a trace of their own are not exported; export
+// such traces with the exporters of the llm package instead. Credentials
+// left empty are read from the environment.
+//
+// Example:
+//
+//	config.SetObservability(config.ObservabilityConfig{Backend: config.ObservabilityLangfuse})
+func SetObservability(o ObservabilityConfig) ConfigOption {
+	return func(c *Config) {
+		o.Backend = strings.ToLower(o.Backend)
+		fill := func(field *string, envVar string) {
+			if *field == "" {
+				*field = os.Getenv(envVar)
+			}
+		}
+		switch o.Backend {
+		case ObservabilityLangfuse:
+			fill(&o.Host, "LANGFUSE_HOST")
+			fill(&o.PublicKey, "LANGFUSE_PUBLIC_KEY")
+			fill(&o.SecretKey, "LANGFUSE_SECRET_KEY")
+		case ObservabilityLangSmith:
+			fill(&o.Host, "LANGSMITH_ENDPOINT")
+			fill(&o.SecretKey, "LANGSMITH_API_KEY")
+			fill(&o.Project, "LANGSMITH_PROJECT")
+		}
+		c.Observability = &o
+	}
+}
+
+// SetAPIKeyPool distributes requests across a pool of API keys for the
+// configured provider. The first key also becomes the provider's primary API
+// key when none has been set. An empty strategy selects round-robin.
+//
+// Example:
+//
+//	config.SetAPIKeyPool(config.APIKeyPoolConfig{
+//		Strategy: config.KeyPoolLeastLoaded,
+//		Keys: []config.PooledKey{
+//			{APIKey: key1, RequestsPerSecond: 5},
+//			{APIKey: key2, RequestsPerSecond: 5},
+//		},
+//	})
+func SetAPIKeyPool(pool APIKeyPoolConfig) ConfigOption {
+	return func(c *Config) {
+		if pool.Strategy == "" {
+			pool.Strategy = KeyPoolRoundRobin
+		}
+		c.APIKeyPool = &pool
+		if len(pool.Keys) == 0 {
+			return
+		}
+		if c.APIKeys == nil {
+			c.APIKeys = make(map[string]string)
+		}
+		if c.APIKeys[c.Provider] == "" {
+			c.APIKeys[c.Provider] = pool.Keys[0].APIKey
+		}
+	}
+}
+
 // SetMaxRetries sets the maximum number of retry attempts.
 func SetMaxRetries(maxRetries int) ConfigOption {
 	return func(c *Config) {
@@ -240,15 +594,33 @@ func SetLogLevel(level utils.LogLevel) ConfigOption {
 	}
 }
 
+// DefaultMemoryMaxTokens is the memory size used when memory is enabled
+// implicitly, for example by SetPersistPartialStreams.
+const DefaultMemoryMaxTokens = 4000
+
 // SetMemory sets the conversation memory settings.
 func SetMemory(maxTokens int) ConfigOption {
 	return func(c *Config) {
+		persistPartial := c.MemoryOption != nil && c.MemoryOption.PersistPartialStreams
 		c.MemoryOption = &MemoryOption{
-			MaxTokens: maxTokens,
+			MaxTokens:             maxTokens,
+			PersistPartialStreams: persistPartial,
 		}
 	}
 }
 
+// SetPersistPartialStreams controls whether conversation memory keeps the
+// partial reply of a stream closed before it completes. It enables memory
+// with the default size if SetMemory has not been applied.
+func SetPersistPartialStreams(enabled bool) ConfigOption {
+	return func(c *Config) {
+		if c.MemoryOption == nil {
+			c.MemoryOption = &MemoryOption{MaxTokens: DefaultMemoryMaxTokens}
+		}
+		c.MemoryOption.PersistPartialStreams = enabled
+	}
+}
+
 // SetExtraHeaders sets additional HTTP headers.
 func SetExtraHeaders(headers map[string]string) ConfigOption {
 	return func(c *Config) {
@@ -275,6 +647,58 @@ func SetTopP(topP float64) ConfigOption {
 	}
 }
 
+// defaultTopP is the top-p loaded when LLM_TOP_P is not set.
+const defaultTopP = 0.9
+
+// TopPSet reports whether TopP was set to a value other than its default.
+// Providers leave top-p to the API's own default unless it was.
+func (c *Config) TopPSet() bool {
+	return c.TopP != 0 && c.TopP != defaultTopP
+}
+
+// SetTopK sets the top-k sampling parameter, for providers that support it.
+func SetTopK(topK int) ConfigOption {
+	return func(c *Config) {
+		c.TopK = &topK
+	}
+}
+
+// SetStrictOptions makes NewLLM fail with an ErrUnsupportedOption error when
+// the seed, top-p or top-k is set but the provider ignores it. By default a
+// warning is logged and the option is dropped.
+func SetStrictOptions(strict bool) ConfigOption {
+	return func(c *Config) {
+		c.StrictOptions = strict
+	}
+}
+
+// ReproducibleSeed is the seed SetReproducible sends when no seed is set.
+const ReproducibleSeed = 42
+
+// SetReproducible makes generation as repeatable as the provider allows,
+// for regression-sensitive pipelines: NewLLM forces a temperature of 0 and,
+// unless SetSeed was used, sends ReproducibleSeed to providers that support
+// a seed. Providers that ignore the seed cannot guarantee determinism, which
+// NewLLM warns about. The system fingerprint of each response, which
+// identifies the backend that produced it, is recorded in
+// Response.SystemFingerprint, and a change of fingerprint is logged since
+// the same seed may then produce different outputs.
+func SetReproducible(reproducible bool) ConfigOption {
+	return func(c *Config) {
+		c.Reproducible = reproducible
+	}
+}
+
+// SetContextFallbackModel sets a model of the same provider with a longer
+// context window, such as "gpt-4.1" for "gpt-4o-mini", that serves the
+// requests too long for the configured model's context window instead of
+// letting them fail. See llm.WithContextFallback.
+func SetContextFallbackModel(model string) ConfigOption {
+	return func(c *Config) {
+		c.ContextFallbackModel = model
+	}
+}
+
 // SetFrequencyPenalty sets the token frequency penalty.
 func SetFrequencyPenalty(penalty float64) ConfigOption {
 	return func(c *Config) {
@@ -296,6 +720,64 @@ func SetSeed(seed int) ConfigOption {
 	}
 }
 
+// SetCleanStrategy sets how generated text is cleaned: CleanNone (the
+// default), CleanMinimal or CleanAggressive. Individual calls can override
+// it with llm.WithCleanStrategy.
+func SetCleanStrategy(strategy string) ConfigOption {
+	return func(c *Config) {
+		c.CleanStrategy = strings.ToLower(strategy)
+	}
+}
+
+// SetDefaultSystemPrompt sets the system prompt of every request, so that
+// apps with a static system prompt need not add it to each Prompt. A Prompt
+// with its own system prompt is combined with it according to
+// SetSystemPromptPolicy.
+func SetDefaultSystemPrompt(prompt string) ConfigOption {
+	return func(c *Config) {
+		c.SystemPrompt = prompt
+	}
+}
+
+// SetSystemPromptPolicy sets how the system prompt of a Prompt is combined
+// with the default system prompt: SystemPromptReplace (the default),
+// SystemPromptPrepend or SystemPromptAppend.
+func SetSystemPromptPolicy(policy string) ConfigOption {
+	return func(c *Config) {
+		c.SystemPromptPolicy = strings.ToLower(policy)
+	}
+}
+
+// SetServiceTier selects the processing tier for providers that offer
+// several, such as Groq ("on_demand", "flex" or "auto") and OpenAI.
+func SetServiceTier(tier string) ConfigOption {
+	return func(c *Config) {
+		c.ServiceTier = tier
+	}
+}
+
+// SetOpenAIOrganization sets the OpenAI organization requests are billed to,
+// sent as the OpenAI-Organization header. It overrides OPENAI_ORG_ID.
+func SetOpenAIOrganization(organization string) ConfigOption {
+	return func(c *Config) {
+		if c.Organizations == nil {
+			c.Organizations = make(map[string]string)
+		}
+		c.Organizations["openai"] = organization
+	}
+}
+
+// SetOpenAIProject sets the OpenAI project requests are attributed to, sent
+// as the OpenAI-Project header. It overrides OPENAI_PROJECT_ID.
+func SetOpenAIProject(project string) ConfigOption {
+	return func(c *Config) {
+		if c.Projects == nil {
+			c.Projects = make(map[string]string)
+		}
+		c.Projects["openai"] = project
+	}
+}
+
 // SetMinP sets the minimum token probability threshold.
 func SetMinP(minP float64) ConfigOption {
 	return func(c *Config) {
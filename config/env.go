@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// ProviderEnvVars names the environment variables read for a single provider.
+// Empty fields are not looked up.
+type ProviderEnvVars struct {
+	// APIKey holds the provider API key (e.g., "OPENAI_API_KEY").
+	APIKey string
+
+	// Endpoint holds a custom API endpoint or base URL (e.g., "OPENAI_BASE_URL").
+	Endpoint string
+
+	// Organization holds an organization or project identifier (e.g., "OPENAI_ORG_ID").
+	Organization string
+
+	// Project holds a project identifier within the organization (e.g., "OPENAI_PROJECT_ID").
+	Project string
+}
+
+var (
+	providerEnvVarsMu sync.RWMutex
+	providerEnvVars   = map[string]ProviderEnvVars{
+		"openai":     {APIKey: "OPENAI_API_KEY", Endpoint: "OPENAI_BASE_URL", Organization: "OPENAI_ORG_ID", Project: "OPENAI_PROJECT_ID"},
+		"anthropic":  {APIKey: "ANTHROPIC_API_KEY", Endpoint: "ANTHROPIC_BASE_URL"},
+		"groq":       {APIKey: "GROQ_API_KEY", Endpoint: "GROQ_BASE_URL"},
+		"mistral":    {APIKey: "MISTRAL_API_KEY", Endpoint: "MISTRAL_BASE_URL"},
+		"cohere":     {APIKey: "COHERE_API_KEY", Endpoint: "COHERE_BASE_URL"},
+		"gemini":     {APIKey: "GEMINI_API_KEY", Endpoint: "GEMINI_BASE_URL"},
+		"openrouter": {APIKey: "OPENROUTER_API_KEY", Endpoint: "OPENROUTER_BASE_URL"},
+		"deepseek":   {APIKey: "DEEPSEEK_API_KEY", Endpoint: "DEEPSEEK_BASE_URL"},
+		"ollama":     {Endpoint: "OLLAMA_ENDPOINT"},
+		"vllm":       {APIKey: "VLLM_API_KEY", Endpoint: "VLLM_ENDPOINT"},
+		"llamacpp":   {APIKey: "LLAMACPP_API_KEY", Endpoint: "LLAMACPP_ENDPOINT"},
+		"gateway":    {APIKey: "GATEWAY_API_KEY", Endpoint: "GATEWAY_BASE_URL"},
+	}
+)
+
+// RegisterProviderEnvVars sets the environment variables read for a provider,
+// replacing any existing mapping. Use it to support custom providers or
+// non-standard variable names such as GOOGLE_API_KEY for Gemini.
+func RegisterProviderEnvVars(provider string, vars ProviderEnvVars) {
+	providerEnvVarsMu.Lock()
+	defer providerEnvVarsMu.Unlock()
+	providerEnvVars[provider] = vars
+}
+
+// ProviderEnvVarsFor returns the environment variables read for a provider.
+func ProviderEnvVarsFor(provider string) (ProviderEnvVars, bool) {
+	providerEnvVarsMu.RLock()
+	defer providerEnvVarsMu.RUnlock()
+	vars, ok := providerEnvVars[provider]
+	return vars, ok
+}
+
+// KnownProviderEnvVars returns the names of all providers with an environment
+// variable mapping, sorted alphabetically.
+func KnownProviderEnvVars() []string {
+	providerEnvVarsMu.RLock()
+	defer providerEnvVarsMu.RUnlock()
+	names := make([]string, 0, len(providerEnvVars))
+	for name := range providerEnvVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadProviderEnv reads API keys, endpoints, organization and project IDs for every
+// mapped provider, looking each variable up with the given prefix.
+func loadProviderEnv(cfg *Config, prefix string) {
+	providerEnvVarsMu.RLock()
+	defer providerEnvVarsMu.RUnlock()
+
+	for provider, vars := range providerEnvVars {
+		if value, ok := lookupEnv(prefix, vars.APIKey); ok {
+			if cfg.APIKeys == nil {
+				cfg.APIKeys = make(map[string]string)
+			}
+			cfg.APIKeys[provider] = value
+		}
+		if value, ok := lookupEnv(prefix, vars.Endpoint); ok {
+			if cfg.Endpoints == nil {
+				cfg.Endpoints = make(map[string]string)
+			}
+			cfg.Endpoints[provider] = value
+			if provider == "ollama" {
+				cfg.OllamaEndpoint = value
+			}
+		}
+		if value, ok := lookupEnv(prefix, vars.Organization); ok {
+			if cfg.Organizations == nil {
+				cfg.Organizations = make(map[string]string)
+			}
+			cfg.Organizations[provider] = value
+		}
+		if value, ok := lookupEnv(prefix, vars.Project); ok {
+			if cfg.Projects == nil {
+				cfg.Projects = make(map[string]string)
+			}
+			cfg.Projects[provider] = value
+		}
+	}
+}
+
+func lookupEnv(prefix, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	value, ok := os.LookupEnv(prefix + name)
+	return value, ok && value != ""
+}
+
+// SetEnvPrefix reloads configuration from environment variables namespaced
+// with prefix, so several applications can share one environment. With prefix
+// "MYAPP_", MYAPP_LLM_MODEL sets the model and MYAPP_OPENAI_API_KEY the OpenAI
+// key. Only variables that are actually set are applied, so apply this option
+// before any options it should not override.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(gollm.SetEnvPrefix("MYAPP_"), gollm.SetMaxTokens(500))
+func SetEnvPrefix(prefix string) ConfigOption {
+	return func(c *Config) {
+		parsed := &Config{}
+		if err := env.ParseWithOptions(parsed, env.Options{Prefix: prefix}); err == nil {
+			copySetEnvFields(c, parsed, prefix)
+		}
+
+		for _, envVar := range os.Environ() {
+			key, value, found := strings.Cut(envVar, "=")
+			if !found || !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			key = strings.ToUpper(strings.TrimPrefix(key, prefix))
+			if strings.HasSuffix(key, "_API_KEY") {
+				if c.APIKeys == nil {
+					c.APIKeys = make(map[string]string)
+				}
+				c.APIKeys[strings.ToLower(strings.TrimSuffix(key, "_API_KEY"))] = value
+			}
+		}
+		loadProviderEnv(c, prefix)
+	}
+}
+
+// copySetEnvFields copies the fields of src whose prefixed environment
+// variable is set into dst, leaving every other field of dst untouched.
+func copySetEnvFields(dst, src *Config, prefix string) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		name := st.Field(i).Tag.Get("env")
+		if name == "" || name == "-" {
+			continue
+		}
+		if _, ok := os.LookupEnv(prefix + name); ok {
+			dv.Field(i).Set(sv.Field(i))
+		}
+	}
+}
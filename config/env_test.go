@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigProviderEnvVars(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("OPENAI_BASE_URL", "https://proxy.example.com/v1")
+	t.Setenv("OPENAI_ORG_ID", "org-123")
+	t.Setenv("OPENAI_PROJECT_ID", "proj-456")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-openai", cfg.APIKeys["openai"])
+	assert.Equal(t, "https://proxy.example.com/v1", cfg.Endpoints["openai"])
+	assert.Equal(t, "org-123", cfg.Organizations["openai"])
+	assert.Equal(t, "proj-456", cfg.Projects["openai"])
+
+	ApplyOptions(cfg, SetOpenAIOrganization("org-789"), SetOpenAIProject("proj-000"))
+	assert.Equal(t, "org-789", cfg.Organizations["openai"])
+	assert.Equal(t, "proj-000", cfg.Projects["openai"])
+}
+
+func TestSetEnvPrefix(t *testing.T) {
+	t.Setenv("MYAPP_LLM_MODEL", "gpt-4o")
+	t.Setenv("MYAPP_GROQ_API_KEY", "gsk-myapp")
+	t.Setenv("MYAPP_CUSTOM_API_KEY", "custom-key")
+
+	cfg := NewConfig()
+	ApplyOptions(cfg, SetMaxTokens(42), SetEnvPrefix("MYAPP_"))
+
+	assert.Equal(t, "gpt-4o", cfg.Model)
+	assert.Equal(t, 42, cfg.MaxTokens, "unset prefixed variables must not reset fields")
+	assert.Equal(t, "gsk-myapp", cfg.APIKeys["groq"])
+	assert.Equal(t, "custom-key", cfg.APIKeys["custom"])
+}
+
+func TestSetObservabilityHeaders(t *testing.T) {
+	settings := ObservabilityHeaders{
+		APIKey:     "pk-123",
+		Cache:      true,
+		CacheTTL:   time.Hour,
+		Retries:    3,
+		User:       "user-42",
+		SessionID:  "session-7",
+		Properties: map[string]string{"feature": "search"},
+	}
+
+	cfg := NewConfig()
+	ApplyOptions(cfg, SetObservabilityHeaders(GatewayHelicone, settings))
+	assert.Equal(t, map[string]string{
+		"Helicone-Auth":             "Bearer pk-123",
+		"Helicone-Cache-Enabled":    "true",
+		"Cache-Control":             "max-age=3600",
+		"Helicone-Retry-Enabled":    "true",
+		"Helicone-Retry-Num":        "3",
+		"Helicone-User-Id":          "user-42",
+		"Helicone-Session-Id":       "session-7",
+		"Helicone-Property-feature": "search",
+	}, cfg.ExtraHeaders)
+
+	headers := settings.Headers(GatewayPortkey)
+	assert.Equal(t, "pk-123", headers["x-portkey-api-key"])
+	assert.Equal(t, "session-7", headers["x-portkey-trace-id"])
+	assert.JSONEq(t, `{"cache": {"mode": "simple", "max_age": 3600}, "retry": {"attempts": 3}}`, headers["x-portkey-config"])
+	assert.JSONEq(t, `{"_user": "user-42", "feature": "search"}`, headers["x-portkey-metadata"])
+	assert.Nil(t, settings.Headers("unknown"))
+}
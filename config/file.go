@@ -0,0 +1,328 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// ProfileEnvVar names the environment variable that selects the profile used by
+// LoadFile when none is given explicitly.
+const ProfileEnvVar = "GOLLM_PROFILE"
+
+// fileSettings mirrors the keys accepted in a configuration file. Every field
+// is optional; unset fields keep their current value.
+type fileSettings struct {
+	Provider         *string           `json:"provider"`
+	Model            *string           `json:"model"`
+	APIKey           *string           `json:"api_key"`
+	APIKeys          map[string]string `json:"api_keys"`
+	BaseURLs         map[string]string `json:"base_urls"`
+	OllamaEndpoint   *string           `json:"ollama_endpoint"`
+	Temperature      *float64          `json:"temperature"`
+	MaxTokens        *int              `json:"max_tokens"`
+	TopP             *float64          `json:"top_p"`
+	TopK             *int              `json:"top_k"`
+	FrequencyPenalty *float64          `json:"frequency_penalty"`
+	PresencePenalty  *float64          `json:"presence_penalty"`
+	Seed             *int              `json:"seed"`
+	ServiceTier      *string           `json:"service_tier"`
+	Timeout          *string           `json:"timeout"`
+	AttemptTimeout   *string           `json:"attempt_timeout"`
+	MaxRetries       *int              `json:"max_retries"`
+	RetryDelay       *string           `json:"retry_delay"`
+	LogLevel         *utils.LogLevel   `json:"log_level"`
+	SystemPrompt     *string           `json:"system_prompt"`
+	ExtraHeaders     map[string]string `json:"extra_headers"`
+	EnableCaching    *bool             `json:"enable_caching"`
+	ProxyURL         *string           `json:"proxy_url"`
+	MemoryMaxTokens  *int              `json:"memory_max_tokens"`
+	Observability    *string           `json:"observability"`
+	CleanStrategy    *string           `json:"clean_strategy"`
+	SystemPolicy     *string           `json:"system_prompt_policy"`
+	StrictOptions    *bool             `json:"strict_options"`
+	Reproducible     *bool             `json:"reproducible"`
+	ContextFallback  *string           `json:"context_fallback_model"`
+	SamplingProfile  *string           `json:"sampling_profile"`
+}
+
+// configFile is the top-level layout of a configuration file: base settings,
+// named profiles layered on top of them, and an optional default profile.
+type configFile struct {
+	fileSettings
+	DefaultProfile string                  `json:"default_profile"`
+	Profiles       map[string]fileSettings `json:"profiles"`
+}
+
+// LoadFile reads a YAML, TOML or JSON configuration file and returns the
+// resulting Config. The format is chosen from the file extension.
+//
+// Base settings are applied on top of NewConfig defaults, followed by the
+// selected profile and finally the given options, so code can still override
+// anything the file sets. The profile is taken from GOLLM_PROFILE, falling
+// back to the file's default_profile. References such as ${OPENAI_API_KEY} or
+// ${MODEL:-gpt-4o-mini} in string values are expanded from the environment
+// after parsing, so their values cannot change the structure of the file; a
+// value that is a single reference, such as "${MAX_TOKENS:-500}", takes the
+// number or boolean it expands to. Other $ signs are kept as written. API keys
+// found in *_API_KEY variables are loaded as with LoadConfig.
+//
+// Example file (gollm.yaml):
+//
+//	provider: openai
+//	model: gpt-4o-mini
+//	api_key: ${OPENAI_API_KEY}
+//	timeout: 30s
+//	default_profile: dev
+//	profiles:
+//	  dev:
+//	    log_level: debug
+//	  prod:
+//	    model: gpt-4o
+//	    max_tokens: 1000
+func LoadFile(path string, opts ...ConfigOption) (*Config, error) {
+	return LoadFileProfile(path, os.Getenv(ProfileEnvVar), opts...)
+}
+
+// LoadFileProfile is like LoadFile but selects the named profile explicitly.
+// An empty profile falls back to the file's default_profile.
+func LoadFileProfile(path, profile string, opts ...ConfigOption) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file, err := parseConfigFile(filepath.Ext(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	cfg := NewConfig()
+	loadAPIKeys(cfg)
+
+	if err := file.fileSettings.apply(cfg); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+	if profile != "" {
+		settings, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in %s", profile, path)
+		}
+		if err := settings.apply(cfg); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+	}
+
+	ApplyOptions(cfg, opts...)
+	return cfg, nil
+}
+
+// parseConfigFile decodes the file into a generic map using the format given
+// by ext, then maps it onto configFile through JSON so all formats share the
+// same field names and validation.
+func parseConfigFile(ext string, data []byte) (*configFile, error) {
+	var raw map[string]interface{}
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .yml, .toml or .json)", ext)
+	}
+
+	normalized, err := json.Marshal(expandEnvValues(raw))
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(normalized)))
+	decoder.DisallowUnknownFields()
+
+	var file configFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// apply converts the settings into ConfigOptions and applies them to cfg.
+func (s fileSettings) apply(cfg *Config) error {
+	var opts []ConfigOption
+
+	if s.Provider != nil {
+		opts = append(opts, SetProvider(*s.Provider))
+	}
+	if s.Model != nil {
+		opts = append(opts, SetModel(*s.Model))
+	}
+	for provider, key := range s.APIKeys {
+		provider, key := provider, key
+		opts = append(opts, func(c *Config) { c.APIKeys[provider] = key })
+	}
+	if s.APIKey != nil {
+		opts = append(opts, SetAPIKey(*s.APIKey))
+	}
+	if s.OllamaEndpoint != nil {
+		opts = append(opts, SetOllamaEndpoint(*s.OllamaEndpoint))
+	}
+	for provider, url := range s.BaseURLs {
+		opts = append(opts, SetBaseURL(provider, url))
+	}
+	if s.SamplingProfile != nil {
+		opts = append(opts, SetSamplingProfile(*s.SamplingProfile))
+	}
+	if s.Temperature != nil {
+		opts = append(opts, SetTemperature(*s.Temperature))
+	}
+	if s.MaxTokens != nil {
+		opts = append(opts, SetMaxTokens(*s.MaxTokens))
+	}
+	if s.TopP != nil {
+		opts = append(opts, SetTopP(*s.TopP))
+	}
+	if s.TopK != nil {
+		opts = append(opts, SetTopK(*s.TopK))
+	}
+	if s.FrequencyPenalty != nil {
+		opts = append(opts, SetFrequencyPenalty(*s.FrequencyPenalty))
+	}
+	if s.PresencePenalty != nil {
+		opts = append(opts, SetPresencePenalty(*s.PresencePenalty))
+	}
+	if s.Seed != nil {
+		opts = append(opts, SetSeed(*s.Seed))
+	}
+	if s.ServiceTier != nil {
+		opts = append(opts, SetServiceTier(*s.ServiceTier))
+	}
+	if s.MaxRetries != nil {
+		opts = append(opts, SetMaxRetries(*s.MaxRetries))
+	}
+	if s.LogLevel != nil {
+		opts = append(opts, SetLogLevel(*s.LogLevel))
+	}
+	if s.SystemPrompt != nil {
+		opts = append(opts, SetDefaultSystemPrompt(*s.SystemPrompt))
+	}
+	if s.SystemPolicy != nil {
+		opts = append(opts, SetSystemPromptPolicy(*s.SystemPolicy))
+	}
+	if s.StrictOptions != nil {
+		opts = append(opts, SetStrictOptions(*s.StrictOptions))
+	}
+	if s.Reproducible != nil {
+		opts = append(opts, SetReproducible(*s.Reproducible))
+	}
+	if s.ContextFallback != nil {
+		opts = append(opts, SetContextFallbackModel(*s.ContextFallback))
+	}
+	if s.ExtraHeaders != nil {
+		opts = append(opts, SetExtraHeaders(s.ExtraHeaders))
+	}
+	if s.EnableCaching != nil {
+		opts = append(opts, SetEnableCaching(*s.EnableCaching))
+	}
+	if s.ProxyURL != nil {
+		opts = append(opts, SetProxy(*s.ProxyURL))
+	}
+	if s.MemoryMaxTokens != nil {
+		opts = append(opts, SetMemory(*s.MemoryMaxTokens))
+	}
+	if s.CleanStrategy != nil {
+		opts = append(opts, SetCleanStrategy(*s.CleanStrategy))
+	}
+	if s.Observability != nil {
+		opts = append(opts, SetObservability(ObservabilityConfig{Backend: *s.Observability}))
+	}
+
+	durations := []struct {
+		name  string
+		value *string
+		set   func(time.Duration) ConfigOption
+	}{
+		{"timeout", s.Timeout, SetTimeout},
+		{"attempt_timeout", s.AttemptTimeout, SetAttemptTimeout},
+		{"retry_delay", s.RetryDelay, SetRetryDelay},
+	}
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		value, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+		opts = append(opts, d.set(value))
+	}
+
+	ApplyOptions(cfg, opts...)
+	return nil
+}
+
+// envReference matches the ${VAR} and ${VAR:-default} references expanded
+// by expandEnv.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} references with values from
+// the environment. The default applies when VAR is unset or empty.
+func expandEnv(s string) string {
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		match := envReference.FindStringSubmatch(ref)
+		if value := os.Getenv(match[1]); value != "" || !strings.Contains(ref, ":-") {
+			return value
+		}
+		return match[2]
+	})
+}
+
+// expandEnvValues expands the references in the string values of a parsed
+// file. A value that is a single reference takes the number or boolean it
+// expands to, so that numeric and boolean settings can be set from the
+// environment.
+func expandEnvValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = expandEnvValues(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandEnvValues(item)
+		}
+	case string:
+		expanded := expandEnv(v)
+		if expanded == v || envReference.FindString(v) != v {
+			return expanded
+		}
+		var scalar interface{}
+		if err := yaml.Unmarshal([]byte(expanded), &scalar); err == nil {
+			switch scalar.(type) {
+			case int, float64, bool:
+				return scalar
+			}
+		}
+		return expanded
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadFileFormatsAndProfiles(t *testing.T) {
+	t.Setenv("GOLLM_TEST_KEY", "sk-test")
+	t.Setenv(ProfileEnvVar, "")
+
+	files := map[string]string{
+		"gollm.yaml": `
+provider: openai
+model: ${GOLLM_TEST_MODEL:-gpt-4o-mini}
+api_key: ${GOLLM_TEST_KEY}
+timeout: 10s
+default_profile: dev
+profiles:
+  dev:
+    max_tokens: 50
+  prod:
+    model: gpt-4o
+    max_tokens: 1000
+`,
+		"gollm.toml": `
+provider = "openai"
+model = "${GOLLM_TEST_MODEL:-gpt-4o-mini}" # defaults to mini
+api_key = "${GOLLM_TEST_KEY}"
+timeout = "10s"
+default_profile = "dev"
+
+[profiles.dev]
+max_tokens = 50
+
+[profiles.prod]
+model = "gpt-4o"
+max_tokens = 1_000
+`,
+		"gollm.json": `{
+  "provider": "openai",
+  "model": "${GOLLM_TEST_MODEL:-gpt-4o-mini}",
+  "api_key": "${GOLLM_TEST_KEY}",
+  "timeout": "10s",
+  "default_profile": "dev",
+  "profiles": {"dev": {"max_tokens": 50}, "prod": {"model": "gpt-4o", "max_tokens": 1000}}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, name, content)
+
+			cfg, err := LoadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "openai", cfg.Provider)
+			assert.Equal(t, "gpt-4o-mini", cfg.Model)
+			assert.Equal(t, "sk-test", cfg.APIKeys["openai"])
+			assert.Equal(t, 10*time.Second, cfg.Timeout)
+			assert.Equal(t, 50, cfg.MaxTokens)
+
+			cfg, err = LoadFileProfile(path, "prod", SetTemperature(0.1))
+			require.NoError(t, err)
+			assert.Equal(t, "gpt-4o", cfg.Model)
+			assert.Equal(t, 1000, cfg.MaxTokens)
+			assert.Equal(t, 0.1, cfg.Temperature)
+		})
+	}
+}
+
+func TestLoadFileRejectsUnknownKeysAndProfiles(t *testing.T) {
+	path := writeConfigFile(t, "gollm.yaml", "modle: gpt-4o\n")
+	_, err := LoadFile(path)
+	assert.ErrorContains(t, err, "modle")
+
+	path = writeConfigFile(t, "gollm.yaml", "model: gpt-4o\n")
+	_, err = LoadFileProfile(path, "staging")
+	assert.ErrorContains(t, err, "staging")
+}
+
+func TestLoadFileExpandsEnvInStringValues(t *testing.T) {
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv("GOLLM_TEST_PROMPT", "Say \"hi\"\nmodel: injected")
+	t.Setenv("GOLLM_TEST_MAX_TOKENS", "300")
+	path := writeConfigFile(t, "gollm.yaml", `
+model: gpt-4o
+system_prompt: "Costs $5, see $HOME and $1. ${GOLLM_TEST_PROMPT}"
+max_tokens: ${GOLLM_TEST_MAX_TOKENS:-100}
+enable_caching: "${GOLLM_TEST_UNSET:-true}"
+`)
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", cfg.Model)
+	assert.Equal(t, "Costs $5, see $HOME and $1. Say \"hi\"\nmodel: injected", cfg.SystemPrompt)
+	assert.Equal(t, 300, cfg.MaxTokens)
+	assert.True(t, cfg.EnableCaching)
+}
+
+func TestLoadFileTOMLSyntax(t *testing.T) {
+	t.Setenv(ProfileEnvVar, "")
+	path := writeConfigFile(t, "gollm.toml", `
+"provider" = 'openai'
+system_prompt = """
+Line one
+Line "two\""""
+extra_headers = { "X-Team" = "search", X-Env = "prod" }
+profiles.dev.max_tokens = 50
+
+[profiles.prod]
+model = "gpt-4o"
+`)
+	cfg, err := LoadFileProfile(path, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", cfg.Provider)
+	assert.Equal(t, "Line one\nLine \"two\"", cfg.SystemPrompt)
+	assert.Equal(t, map[string]string{"X-Team": "search", "X-Env": "prod"}, cfg.ExtraHeaders)
+	assert.Equal(t, 50, cfg.MaxTokens)
+
+	path = writeConfigFile(t, "gollm.toml", "model = \"gpt-4o\"\nmodel = \"gpt-4o-mini\"\n")
+	_, err = LoadFile(path)
+	assert.Error(t, err, "duplicate keys are rejected")
+}
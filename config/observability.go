@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// ObservabilityGateway names the header conventions of an LLM observability
+// gateway.
+type ObservabilityGateway string
+
+const (
+	// GatewayPortkey emits Portkey's x-portkey-* headers.
+	GatewayPortkey ObservabilityGateway = "portkey"
+	// GatewayHelicone emits Helicone's Helicone-* headers.
+	GatewayHelicone ObservabilityGateway = "helicone"
+)
+
+// ObservabilityHeaders describes the tracing, caching and retry settings a
+// gateway such as Portkey or Helicone reads from request headers. Zero
+// fields emit no header.
+type ObservabilityHeaders struct {
+	// APIKey is the gateway's own API key, sent alongside the provider key.
+	APIKey string
+	// Cache enables the gateway's response cache.
+	Cache bool
+	// CacheTTL is how long cached responses are served. Zero keeps the
+	// gateway's default.
+	CacheTTL time.Duration
+	// Retries is the number of times the gateway retries a failed request.
+	Retries int
+	// User identifies the end user of the requests.
+	User string
+	// SessionID groups the requests of a session or trace.
+	SessionID string
+	// SessionName is a readable name of the session, for Helicone.
+	SessionName string
+	// Properties are custom key-value properties to filter requests by.
+	Properties map[string]string
+}
+
+// Headers returns the HTTP headers of the settings in the conventions of
+// gateway. It returns nil for an unknown gateway.
+func (h ObservabilityHeaders) Headers(gateway ObservabilityGateway) map[string]string {
+	switch gateway {
+	case GatewayPortkey:
+		return h.portkeyHeaders()
+	case GatewayHelicone:
+		return h.heliconeHeaders()
+	}
+	return nil
+}
+
+// portkeyHeaders carries cache and retry settings in the x-portkey-config
+// header and the user and properties in x-portkey-metadata, where Portkey
+// reserves the _user key for the end user.
+func (h ObservabilityHeaders) portkeyHeaders() map[string]string {
+	headers := make(map[string]string)
+	if h.APIKey != "" {
+		headers["x-portkey-api-key"] = h.APIKey
+	}
+	if h.SessionID != "" {
+		headers["x-portkey-trace-id"] = h.SessionID
+	}
+
+	gatewayConfig := make(map[string]interface{})
+	if h.Cache {
+		cache := map[string]interface{}{"mode": "simple"}
+		if h.CacheTTL > 0 {
+			cache["max_age"] = int(h.CacheTTL.Seconds())
+		}
+		gatewayConfig["cache"] = cache
+	}
+	if h.Retries > 0 {
+		gatewayConfig["retry"] = map[string]interface{}{"attempts": h.Retries}
+	}
+	if len(gatewayConfig) > 0 {
+		headers["x-portkey-config"] = marshalHeader(gatewayConfig)
+	}
+
+	metadata := make(map[string]string, len(h.Properties)+1)
+	for k, v := range h.Properties {
+		metadata[k] = v
+	}
+	if h.User != "" {
+		metadata["_user"] = h.User
+	}
+	if len(metadata) > 0 {
+		headers["x-portkey-metadata"] = marshalHeader(metadata)
+	}
+	return headers
+}
+
+// heliconeHeaders emits a Helicone-* header per setting, with each property
+// in its own Helicone-Property-* header.
+func (h ObservabilityHeaders) heliconeHeaders() map[string]string {
+	headers := make(map[string]string)
+	if h.APIKey != "" {
+		headers["Helicone-Auth"] = "Bearer " + h.APIKey
+	}
+	if h.Cache {
+		headers["Helicone-Cache-Enabled"] = "true"
+		if h.CacheTTL > 0 {
+			headers["Cache-Control"] = "max-age=" + strconv.Itoa(int(h.CacheTTL.Seconds()))
+		}
+	}
+	if h.Retries > 0 {
+		headers["Helicone-Retry-Enabled"] = "true"
+		headers["Helicone-Retry-Num"] = strconv.Itoa(h.Retries)
+	}
+	if h.User != "" {
+		headers["Helicone-User-Id"] = h.User
+	}
+	if h.SessionID != "" {
+		headers["Helicone-Session-Id"] = h.SessionID
+	}
+	if h.SessionName != "" {
+		headers["Helicone-Session-Name"] = h.SessionName
+	}
+	for k, v := range h.Properties {
+		headers["Helicone-Property-"+k] = v
+	}
+	return headers
+}
+
+// marshalHeader encodes a header value as JSON. The values are maps of
+// strings and numbers, which always encode.
+func marshalHeader(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// SetObservabilityHeaders adds the headers of an observability gateway such
+// as Portkey or Helicone to every request, so that requests are traced,
+// cached and retried by the gateway without setting its headers by hand
+// with SetExtraHeaders. Point the provider at the gateway with SetBaseURL.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.SetBaseURL("openai", "https://oai.helicone.ai/v1"),
+//	    gollm.SetObservabilityHeaders(gollm.GatewayHelicone, gollm.ObservabilityHeaders{
+//	        APIKey:    os.Getenv("HELICONE_API_KEY"),
+//	        Cache:     true,
+//	        User:      userID,
+//	        SessionID: sessionID,
+//	    }),
+//	)
+func SetObservabilityHeaders(gateway ObservabilityGateway, headers ObservabilityHeaders) ConfigOption {
+	return SetExtraHeaders(headers.Headers(gateway))
+}
@@ -0,0 +1,55 @@
+package config
+
+// HarmCategory is a category of harmful content rated by Gemini's safety
+// filters.
+type HarmCategory string
+
+const (
+	HarmCategoryHarassment       HarmCategory = "HARM_CATEGORY_HARASSMENT"
+	HarmCategoryHateSpeech       HarmCategory = "HARM_CATEGORY_HATE_SPEECH"
+	HarmCategorySexuallyExplicit HarmCategory = "HARM_CATEGORY_SEXUALLY_EXPLICIT"
+	HarmCategoryDangerousContent HarmCategory = "HARM_CATEGORY_DANGEROUS_CONTENT"
+	HarmCategoryCivicIntegrity   HarmCategory = "HARM_CATEGORY_CIVIC_INTEGRITY"
+)
+
+// HarmBlockThreshold is the probability of harm from which Gemini blocks
+// content of a category.
+type HarmBlockThreshold string
+
+const (
+	// BlockLowAndAbove blocks content with a low or higher probability of
+	// harm.
+	BlockLowAndAbove HarmBlockThreshold = "BLOCK_LOW_AND_ABOVE"
+	// BlockMediumAndAbove blocks content with a medium or high probability
+	// of harm.
+	BlockMediumAndAbove HarmBlockThreshold = "BLOCK_MEDIUM_AND_ABOVE"
+	// BlockOnlyHigh blocks content with a high probability of harm.
+	BlockOnlyHigh HarmBlockThreshold = "BLOCK_ONLY_HIGH"
+	// BlockNone blocks nothing but still rates the content.
+	BlockNone HarmBlockThreshold = "BLOCK_NONE"
+	// BlockOff turns the filter of the category off, without ratings.
+	BlockOff HarmBlockThreshold = "OFF"
+)
+
+// SafetySetting is the blocking threshold of a Gemini harm category.
+type SafetySetting struct {
+	Category  HarmCategory       `json:"category"`
+	Threshold HarmBlockThreshold `json:"threshold"`
+}
+
+// SetSafetySettings sets the blocking thresholds of Gemini's safety filters,
+// replacing the API's defaults for the listed categories. Individual calls
+// can override them per category with llm.WithSafetySettings. Other
+// providers ignore them.
+//
+// Example:
+//
+//	config.SetSafetySettings(
+//	    config.SafetySetting{Category: config.HarmCategoryDangerousContent, Threshold: config.BlockOnlyHigh},
+//	    config.SafetySetting{Category: config.HarmCategoryHarassment, Threshold: config.BlockNone},
+//	)
+func SetSafetySettings(settings ...SafetySetting) ConfigOption {
+	return func(c *Config) {
+		c.SafetySettings = settings
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import "strings"
+
+// Sampling profiles for SetSamplingProfile.
+const (
+	SamplingDeterministic = "deterministic"
+	SamplingBalanced      = "balanced"
+	SamplingCreative      = "creative"
+)
+
+// SamplingProfile is a named set of sampling parameters. A zero TopP or
+// TopK leaves the corresponding setting unchanged.
+type SamplingProfile struct {
+	Temperature float64
+	TopP        float64
+
+	// TopK is only sent to providers that support top-k sampling, so a
+	// profile does not trigger unsupported option warnings.
+	TopK int
+}
+
+var samplingProfiles = map[string]SamplingProfile{
+	SamplingDeterministic: {TopK: 1},
+	SamplingBalanced:      {Temperature: 0.7, TopP: defaultTopP},
+	SamplingCreative:      {Temperature: 1, TopP: 0.95},
+}
+
+// LookupSamplingProfile returns the sampling profile registered under name.
+func LookupSamplingProfile(name string) (SamplingProfile, bool) {
+	profile, ok := samplingProfiles[strings.ToLower(name)]
+	return profile, ok
+}
+
+// RegisterSamplingProfile adds or replaces a named sampling profile. It is
+// not safe to call concurrently with SetSamplingProfile.
+func RegisterSamplingProfile(name string, profile SamplingProfile) {
+	samplingProfiles[strings.ToLower(name)] = profile
+}
+
+// SetSamplingProfile selects a named set of sampling parameters instead of
+// setting them one by one: SamplingDeterministic, SamplingBalanced,
+// SamplingCreative, or a profile added with RegisterSamplingProfile. The
+// temperature and top-p are applied immediately, so options given after it
+// override them; the top-k is applied by NewLLM when the provider supports
+// it and SetTopK was not used. Unknown profiles make NewLLM fail.
+//
+// Example:
+//
+//	cfg := config.NewConfig()
+//	config.ApplyOptions(cfg, config.SetSamplingProfile(config.SamplingDeterministic))
+func SetSamplingProfile(name string) ConfigOption {
+	return func(c *Config) {
+		c.SamplingProfile = strings.ToLower(name)
+		profile, ok := samplingProfiles[c.SamplingProfile]
+		if !ok {
+			return
+		}
+		c.Temperature = profile.Temperature
+		if profile.TopP != 0 {
+			c.TopP = profile.TopP
+		}
+	}
+}
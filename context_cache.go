@@ -0,0 +1,17 @@
+// Package gollm provides server-side context caching for Language Learning Models.
+// This file contains re-exports for caching large static content and referencing it in later requests.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/providers"
+)
+
+// CachedContext is content cached server-side by a provider, referenced by name until it expires.
+type CachedContext = providers.CachedContext
+
+// CreateCachedContext caches a prompt server-side for a TTL using the provider's context caching API.
+var CreateCachedContext = llm.CreateCachedContext
+
+// UseCachedContext references content cached with CreateCachedContext for a single Generate call.
+var UseCachedContext = llm.UseCachedContext
@@ -0,0 +1,29 @@
+// Package gollm provides model token limits for Language Learning Models.
+// This file contains re-exports for looking up context windows and sizing replies to them.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// ModelLimits holds the context window and maximum output of a model.
+	ModelLimits = llm.ModelLimits
+
+	// ModelSubstitution records that a request was served by another model than the configured one.
+	ModelSubstitution = llm.ModelSubstitution
+)
+
+var (
+	// RegisterModelLimits sets the token limits of a model or model name prefix.
+	RegisterModelLimits = llm.RegisterModelLimits
+
+	// LookupModelLimits returns the token limits of a model from the limits table.
+	LookupModelLimits = llm.LookupModelLimits
+
+	// WithAutoMaxTokens sets max_tokens to the room left in the model's context window after the prompt.
+	WithAutoMaxTokens = llm.WithAutoMaxTokens
+
+	// WithContextFallback sends prompts too long for the model's context window to a long-context model.
+	WithContextFallback = llm.WithContextFallback
+)
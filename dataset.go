@@ -0,0 +1,32 @@
+// Package gollm provides fine-tuning dataset building for Language Learning Models.
+// This file contains re-exports for converting conversation transcripts into training files.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// DatasetFormat is the fine-tuning file format of a DatasetBuilder.
+	DatasetFormat = llm.DatasetFormat
+
+	// DatasetExample is a conversation to train on, with its score.
+	DatasetExample = llm.DatasetExample
+
+	// DatasetStats reports what a DatasetBuilder wrote and left out.
+	DatasetStats = llm.DatasetStats
+
+	// DatasetBuilder writes conversations as a filtered fine-tuning dataset in JSON Lines.
+	DatasetBuilder = llm.DatasetBuilder
+)
+
+const (
+	// DatasetFormatOpenAI is the chat format of OpenAI fine-tuning.
+	DatasetFormatOpenAI = llm.DatasetFormatOpenAI
+
+	// DatasetFormatAnthropic is the format of Claude fine-tuning.
+	DatasetFormatAnthropic = llm.DatasetFormatAnthropic
+)
+
+// ScrubPII masks email addresses, card numbers, social security numbers, IP addresses and phone numbers.
+var ScrubPII = llm.ScrubPII
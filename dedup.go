@@ -0,0 +1,38 @@
+// Package gollm provides deduplication for Language Learning Models.
+// This file contains re-exports for finding near-duplicate texts.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// DedupMethod is how FindDuplicates compared texts.
+	DedupMethod = llm.DedupMethod
+	// DuplicateGroup is a set of texts that are near-duplicates of the first.
+	DuplicateGroup = llm.DuplicateGroup
+	// DedupResult is the near-duplicates found in a corpus.
+	DedupResult = llm.DedupResult
+	// DedupOption configures FindDuplicates.
+	DedupOption = llm.DedupOption
+)
+
+const (
+	// DedupEmbedding compares texts by the cosine similarity of their embeddings.
+	DedupEmbedding = llm.DedupEmbedding
+	// DedupMinHash compares texts by the estimated Jaccard similarity of their word shingles.
+	DedupMinHash = llm.DedupMinHash
+)
+
+var (
+	// FindDuplicates groups the near-duplicates of texts by embeddings, or MinHash without embeddings.
+	FindDuplicates = llm.FindDuplicates
+	// Deduplicate returns texts without their near-duplicates.
+	Deduplicate = llm.Deduplicate
+	// WithDedupThreshold sets the cosine similarity from which texts are near-duplicates.
+	WithDedupThreshold = llm.WithDedupThreshold
+	// WithMinHashThreshold sets the Jaccard similarity from which texts are near-duplicates with MinHash.
+	WithMinHashThreshold = llm.WithMinHashThreshold
+	// WithMinHash sets the shingle size and number of hashes of the MinHash comparison.
+	WithMinHash = llm.WithMinHash
+)
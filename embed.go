@@ -0,0 +1,13 @@
+// Package gollm provides embeddings for Language Learning Models.
+// This file contains re-exports for computing embeddings with a provider.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Unwrapper is implemented by LLMs that wrap another LLM.
+type Unwrapper = llm.Unwrapper
+
+// Embed returns one embedding vector per input using the provider's embeddings endpoint.
+var Embed = llm.Embed
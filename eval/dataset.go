@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadFile reads a dataset from a JSONL or CSV file, chosen by the file
+// extension (.jsonl or .csv).
+func LoadFile(path string) (Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".jsonl":
+		return LoadJSONL(f)
+	case ".csv":
+		return LoadCSV(f)
+	default:
+		return nil, fmt.Errorf("eval: unsupported dataset format %q", ext)
+	}
+}
+
+// LoadJSONL reads a dataset with one JSON object per line, with "input",
+// "expected" and optionally "id" fields. Other fields are kept as metadata.
+// Empty lines are skipped.
+//
+// Example line:
+//
+//	{"id": "capital-fr", "input": "Capital of France?", "expected": "Paris"}
+func LoadJSONL(r io.Reader) (Dataset, error) {
+	var dataset Dataset
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 10<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			return nil, fmt.Errorf("eval: line %d: %w", line, err)
+		}
+		record := make(map[string]string, len(fields))
+		for k, v := range fields {
+			if s, ok := v.(string); ok {
+				record[k] = s
+				continue
+			}
+			encoded, _ := json.Marshal(v)
+			record[k] = string(encoded)
+		}
+		example, err := newExample(record, len(dataset)+1)
+		if err != nil {
+			return nil, fmt.Errorf("eval: line %d: %w", line, err)
+		}
+		dataset = append(dataset, example)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("eval: %w", err)
+	}
+	return dataset, nil
+}
+
+// LoadCSV reads a dataset from CSV with a header row naming the "input",
+// "expected" and optionally "id" columns. Other columns are kept as
+// metadata.
+func LoadCSV(r io.Reader) (Dataset, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("eval: reading CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var dataset Dataset
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("eval: %w", err)
+		}
+		record := make(map[string]string, len(header))
+		for i, value := range row {
+			record[header[i]] = value
+		}
+		example, err := newExample(record, len(dataset)+1)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("eval: line %d: %w", line, err)
+		}
+		dataset = append(dataset, example)
+	}
+	return dataset, nil
+}
+
+// newExample builds an example from the fields of a record, numbered n.
+func newExample(record map[string]string, n int) (Example, error) {
+	input, ok := record["input"]
+	if !ok {
+		return Example{}, fmt.Errorf("missing input")
+	}
+	example := Example{ID: record["id"], Input: input, Expected: record["expected"]}
+	if example.ID == "" {
+		example.ID = strconv.Itoa(n)
+	}
+	for k, v := range record {
+		if k == "id" || k == "input" || k == "expected" {
+			continue
+		}
+		if example.Metadata == nil {
+			example.Metadata = make(map[string]string)
+		}
+		example.Metadata[k] = v
+	}
+	return example, nil
+}
@@ -0,0 +1,245 @@
+// Package eval runs an LLM task over a dataset and scores the outputs with
+// metrics, such as exact match, BLEU or an LLM judge, to measure the quality
+// of a prompt or model. Unlike the assess package, which checks behavior
+// inside Go tests, eval produces aggregate scores and the list of failing
+// examples, for offline evaluation.
+//
+// Example:
+//
+//	dataset, err := eval.LoadFile("testdata/qa.jsonl")
+//	report, err := eval.Run(ctx, dataset, eval.GenerateTask(client),
+//	    []eval.Metric{eval.ExactMatch(), eval.Judge(judge, "factual accuracy")},
+//	    eval.WithConcurrency(4))
+//	fmt.Println(report)
+//	for _, failure := range report.Failures() {
+//	    fmt.Println(failure.Example.ID, failure.Output, failure.Err)
+//	}
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Example is one input of a dataset, with the output expected for it.
+type Example struct {
+	// ID identifies the example in reports. Loaders default it to the
+	// example's position, starting at 1.
+	ID string `json:"id"`
+
+	// Input is passed to the task.
+	Input string `json:"input"`
+
+	// Expected is the reference output metrics compare against.
+	Expected string `json:"expected"`
+
+	// Metadata holds any other fields of the example.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Dataset is a list of examples.
+type Dataset []Example
+
+// Task produces the output of an example, typically by calling an LLM.
+type Task func(ctx context.Context, example Example) (string, error)
+
+// GenerateTask returns a task that generates the output of an example from
+// a prompt with its input and the given prompt options, such as a system
+// prompt or directives.
+func GenerateTask(l llm.LLM, opts ...llm.PromptOption) Task {
+	return func(ctx context.Context, example Example) (string, error) {
+		return l.Generate(ctx, llm.NewPrompt(example.Input, opts...))
+	}
+}
+
+// Option configures Run.
+type Option func(*runConfig)
+
+type runConfig struct {
+	concurrency   int
+	passThreshold float64
+	timeout       time.Duration
+}
+
+// WithConcurrency sets the number of examples evaluated at the same time,
+// 1 by default.
+func WithConcurrency(n int) Option {
+	return func(c *runConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithPassThreshold sets the score every metric must reach for an example
+// to pass, 0.5 by default.
+func WithPassThreshold(threshold float64) Option {
+	return func(c *runConfig) {
+		c.passThreshold = threshold
+	}
+}
+
+// WithExampleTimeout limits the time spent on each example, including its
+// metrics.
+func WithExampleTimeout(timeout time.Duration) Option {
+	return func(c *runConfig) {
+		c.timeout = timeout
+	}
+}
+
+// Result is the evaluation of one example.
+type Result struct {
+	Example Example
+	Output  string
+
+	// Scores holds the score of each metric, by name.
+	Scores map[string]float64
+
+	// Err is the error of the task or of a metric.
+	Err error
+
+	// Latency is the duration of the task.
+	Latency time.Duration
+
+	// Passed is true when the task succeeded and every score reached the
+	// pass threshold.
+	Passed bool
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	// Results holds the result of every example, in dataset order.
+	Results []Result
+
+	// Scores holds the mean score of each metric over the examples it
+	// scored.
+	Scores map[string]float64
+
+	// Passed and Failed count the examples.
+	Passed int
+	Failed int
+
+	// Duration is the duration of the run.
+	Duration time.Duration
+}
+
+// Failures returns the results of the examples that did not pass.
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if !result.Passed {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// PassRate returns the fraction of examples that passed.
+func (r *Report) PassRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(len(r.Results))
+}
+
+// String summarizes the report: the pass rate and the mean of each metric.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d/%d examples passed (%.1f%%) in %s\n", r.Passed, len(r.Results), 100*r.PassRate(), r.Duration.Round(time.Millisecond))
+	names := make([]string, 0, len(r.Scores))
+	for name := range r.Scores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %.3f\n", name, r.Scores[name])
+	}
+	return b.String()
+}
+
+// Run evaluates the task on every example of the dataset and scores its
+// outputs with the metrics. Task and metric failures are reported in the
+// results; Run only fails on invalid arguments or when ctx is done.
+func Run(ctx context.Context, dataset Dataset, task Task, metrics []Metric, opts ...Option) (*Report, error) {
+	if task == nil {
+		return nil, errors.New("eval: task is required")
+	}
+	cfg := &runConfig{concurrency: 1, passThreshold: 0.5}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+
+	start := time.Now()
+	results := make([]Result, len(dataset))
+	slots := make(chan struct{}, cfg.concurrency)
+	var wg sync.WaitGroup
+	for i, example := range dataset {
+		wg.Add(1)
+		go func(i int, example Example) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = evaluate(ctx, cfg, example, task, metrics)
+		}(i, example)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Results: results, Scores: make(map[string]float64), Duration: time.Since(start)}
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		for name, score := range result.Scores {
+			report.Scores[name] += score
+			counts[name]++
+		}
+	}
+	for name, count := range counts {
+		report.Scores[name] /= float64(count)
+	}
+	return report, nil
+}
+
+// evaluate runs the task on an example and scores its output.
+func evaluate(ctx context.Context, cfg *runConfig, example Example, task Task, metrics []Metric) Result {
+	result := Result{Example: example, Scores: make(map[string]float64, len(metrics))}
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result.Output, result.Err = task(ctx, example)
+	result.Latency = time.Since(start)
+	if result.Err != nil {
+		return result
+	}
+
+	passed := true
+	for _, metric := range metrics {
+		score, err := metric.Score(ctx, example, result.Output)
+		if err != nil {
+			result.Err = fmt.Errorf("metric %s: %w", metric.Name(), err)
+			return result
+		}
+		result.Scores[metric.Name()] = score
+		passed = passed && score >= cfg.passThreshold
+	}
+	result.Passed = passed
+	return result
+}
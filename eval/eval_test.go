@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatasets(t *testing.T) {
+	jsonl, err := LoadJSONL(strings.NewReader(`{"id": "fr", "input": "Capital of France?", "expected": "Paris"}
+
+{"input": "2+2?", "expected": "4", "difficulty": 1}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Dataset{
+		{ID: "fr", Input: "Capital of France?", Expected: "Paris"},
+		{ID: "2", Input: "2+2?", Expected: "4", Metadata: map[string]string{"difficulty": "1"}},
+	}, jsonl)
+
+	csv, err := LoadCSV(strings.NewReader("Input,Expected,topic\n\"Capital of France?\",Paris,geography\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Dataset{
+		{ID: "1", Input: "Capital of France?", Expected: "Paris", Metadata: map[string]string{"topic": "geography"}},
+	}, csv)
+
+	_, err = LoadJSONL(strings.NewReader(`{"expected": "x"}`))
+	assert.Error(t, err)
+}
+
+func TestBLEU(t *testing.T) {
+	assert.InDelta(t, 1, bleu(strings.Fields("the cat sat on the mat"), strings.Fields("the cat sat on the mat"), 4), 1e-9)
+	assert.Zero(t, bleu(strings.Fields("the cat sat on the mat"), strings.Fields("dogs bark"), 4))
+	partial := bleu(strings.Fields("the cat sat on the mat"), strings.Fields("the cat sat on a mat"), 4)
+	assert.Greater(t, partial, 0.3)
+	assert.Less(t, partial, 1.0)
+}
+
+func TestRun(t *testing.T) {
+	dataset := Dataset{
+		{ID: "1", Input: "Capital of France?", Expected: "Paris"},
+		{ID: "2", Input: "Capital of Italy?", Expected: "Rome"},
+		{ID: "3", Input: "Capital of Spain?", Expected: "Madrid"},
+	}
+	answers := map[string]string{"1": "paris", "2": "Milan"}
+	task := func(_ context.Context, example Example) (string, error) {
+		if answer, ok := answers[example.ID]; ok {
+			return answer, nil
+		}
+		return "", errors.New("no answer")
+	}
+
+	report, err := Run(context.Background(), dataset, task, []Metric{ExactMatch(), BLEU()}, WithConcurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.InDelta(t, 0.5, report.Scores["exact_match"], 1e-9)
+	failures := report.Failures()
+	require.Len(t, failures, 2)
+	assert.Equal(t, "2", failures[0].Example.ID)
+	assert.Equal(t, "Milan", failures[0].Output)
+	assert.Error(t, failures[1].Err)
+	assert.Contains(t, report.String(), "1/3 examples passed")
+}
@@ -0,0 +1,149 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Metric scores the output of an example, from 0 (worst) to 1 (best).
+type Metric interface {
+	Name() string
+	Score(ctx context.Context, example Example, output string) (float64, error)
+}
+
+type metricFunc struct {
+	name  string
+	score func(ctx context.Context, example Example, output string) (float64, error)
+}
+
+func (m metricFunc) Name() string { return m.name }
+
+func (m metricFunc) Score(ctx context.Context, example Example, output string) (float64, error) {
+	return m.score(ctx, example, output)
+}
+
+// MetricFunc creates a metric from a function.
+func MetricFunc(name string, score func(ctx context.Context, example Example, output string) (float64, error)) Metric {
+	return metricFunc{name: name, score: score}
+}
+
+// normalize lowercases s and collapses its whitespace.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// ExactMatch scores 1 when the output equals the expected output, ignoring
+// case and whitespace differences, and 0 otherwise.
+func ExactMatch() Metric {
+	return MetricFunc("exact_match", func(_ context.Context, example Example, output string) (float64, error) {
+		if normalize(output) == normalize(example.Expected) {
+			return 1, nil
+		}
+		return 0, nil
+	})
+}
+
+// Contains scores 1 when the output contains the expected output, ignoring
+// case and whitespace differences, and 0 otherwise.
+func Contains() Metric {
+	return MetricFunc("contains", func(_ context.Context, example Example, output string) (float64, error) {
+		if strings.Contains(normalize(output), normalize(example.Expected)) {
+			return 1, nil
+		}
+		return 0, nil
+	})
+}
+
+// BLEU scores the output against the expected output with sentence-level
+// BLEU-4 on lowercased whitespace tokens, with add-one smoothing of the
+// higher-order precisions so short outputs do not score 0.
+func BLEU() Metric {
+	return MetricFunc("bleu", func(_ context.Context, example Example, output string) (float64, error) {
+		return bleu(strings.Fields(strings.ToLower(example.Expected)), strings.Fields(strings.ToLower(output)), 4), nil
+	})
+}
+
+// bleu computes the BLEU score of candidate against reference with n-grams
+// up to maxN.
+func bleu(reference, candidate []string, maxN int) float64 {
+	if len(candidate) == 0 || len(reference) == 0 {
+		return 0
+	}
+	logPrecision := 0.0
+	for n := 1; n <= maxN; n++ {
+		counts := ngrams(reference, n)
+		matches, total := 0, 0
+		for gram, count := range ngrams(candidate, n) {
+			matches += min(count, counts[gram])
+			total += count
+		}
+		if n == 1 && matches == 0 {
+			return 0
+		}
+		if n > 1 {
+			matches, total = matches+1, total+1
+		}
+		logPrecision += math.Log(float64(matches)/float64(total)) / float64(maxN)
+	}
+	brevity := 1.0
+	if len(candidate) < len(reference) {
+		brevity = math.Exp(1 - float64(len(reference))/float64(len(candidate)))
+	}
+	return brevity * math.Exp(logPrecision)
+}
+
+// ngrams counts the n-grams of tokens.
+func ngrams(tokens []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(tokens); i++ {
+		counts[strings.Join(tokens[i:i+n], "\x00")]++
+	}
+	return counts
+}
+
+var judgeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"score":     map[string]interface{}{"type": "number", "minimum": 0, "maximum": 10},
+		"rationale": map[string]interface{}{"type": "string"},
+	},
+	"required": []string{"score", "rationale"},
+}
+
+// Judge scores the output with a judge LLM, which rates it from 0 to 10 on
+// the criteria, such as "factual accuracy", given the input and the
+// expected output. The rating is divided by 10. An empty criteria asks for
+// overall correctness.
+func Judge(judge llm.LLM, criteria string) Metric {
+	if criteria == "" {
+		criteria = "correctness and completeness compared to the reference answer"
+	}
+	return MetricFunc("judge", func(ctx context.Context, example Example, output string) (float64, error) {
+		text := fmt.Sprintf("Task:\n<task>\n%s\n</task>\n\nReference answer:\n<reference>\n%s\n</reference>\n\nResponse to evaluate:\n<response>\n%s\n</response>",
+			example.Input, example.Expected, output)
+		prompt := llm.NewPrompt(text,
+			llm.WithSystemPrompt("You are an impartial judge evaluating a response against a reference answer.", ""),
+			llm.WithDirectives(
+				"Score the response from 0 to 10 on "+criteria,
+				"Judge the content, not the length or style of the response",
+				"Give a one-sentence rationale for the score",
+			),
+		)
+		result, err := judge.GenerateWithSchema(ctx, prompt, judgeSchema)
+		if err != nil {
+			return 0, err
+		}
+		var rating struct {
+			Score float64 `json:"score"`
+		}
+		if err := json.Unmarshal([]byte(result), &rating); err != nil {
+			return 0, fmt.Errorf("parsing judge rating: %w", err)
+		}
+		return math.Max(0, math.Min(rating.Score, 10)) / 10, nil
+	})
+}
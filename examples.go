@@ -0,0 +1,26 @@
+// Package gollm provides few-shot example selection for Language Learning Models.
+// This file contains re-exports for picking the examples most relevant to a prompt.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// ExampleSelector picks the few-shot examples most relevant to a prompt from a pool.
+	ExampleSelector = llm.ExampleSelector
+
+	// ExampleSelectorOption configures an ExampleSelector.
+	ExampleSelectorOption = llm.ExampleSelectorOption
+)
+
+var (
+	// NewExampleSelector creates a selector that compares examples by their embeddings.
+	NewExampleSelector = llm.NewExampleSelector
+
+	// WithMMR selects diverse examples by maximal marginal relevance.
+	WithMMR = llm.WithMMR
+
+	// WithExampleSelector adds the examples most relevant to the prompt's input for a single Generate call.
+	WithExampleSelector = llm.WithExampleSelector
+)
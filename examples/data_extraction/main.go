@@ -7,7 +7,6 @@ import (
 	"os"
 	"reflect"
 	"strings"
-	"sync"
 
 	"github.com/teilomillet/gollm"
 	"github.com/teilomillet/gollm/presets"
@@ -75,79 +74,39 @@ func main() {
 	         It seamlessly blends elements of science fiction, action, and psychological drama. The movie explores the concept 
 	         of dream infiltration and leaves you questioning reality long after the credits roll.`
 
-	ctx := context.Background()
-	var wg sync.WaitGroup
-
-	// Channels to collect results
-	reviewChan := make(chan *MovieReview)
-	reviewValidatedChan := make(chan *MovieReviewValidated)
-	errorChan := make(chan error, 2) // Buffer for potential errors from both goroutines
+	// Run both extractions concurrently; an error in one does not cancel the other
+	g, _ := gollm.NewGroup(context.Background(), gollm.WithGroupFatal(func(error) bool { return false }))
+	var review *MovieReview
+	var reviewValidated *MovieReviewValidated
 
 	// Extract without validation
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		review, err := extractReview[MovieReview](ctx, llm, text, false)
-		if err != nil {
-			errorChan <- err
-			return
-		}
-		reviewChan <- review
-	}()
+	g.Go(func(ctx context.Context) (err error) {
+		review, err = extractReview[MovieReview](ctx, llm, text, false)
+		return err
+	})
 
 	// Extract with validation
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		reviewValidated, err := extractReview[MovieReviewValidated](ctx, llm, text, true)
-		if err != nil {
-			errorChan <- err
-			return
-		}
-		reviewValidatedChan <- reviewValidated
-	}()
-
-	// Wait for both goroutines to complete
-	go func() {
-		wg.Wait()
-		close(reviewChan)
-		close(reviewValidatedChan)
-		close(errorChan)
-	}()
-
-	// Collect and print results
-	for {
-		select {
-		case review, ok := <-reviewChan:
-			if !ok {
-				reviewChan = nil
-			} else {
-				fmt.Printf("\n%s\n", strings.Repeat("=", 50))
-				fmt.Println("\nExtracted Movie Review (without validation):")
-				printReview(review)
-				fmt.Printf("\n%s\n", strings.Repeat("=", 50))
-			}
-		case reviewValidated, ok := <-reviewValidatedChan:
-			if !ok {
-				reviewValidatedChan = nil
-			} else {
-				fmt.Printf("\n%s\n", strings.Repeat("=", 50))
-				fmt.Println("\nExtracted Movie Review (with validation):")
-				printReview(reviewValidated)
-				fmt.Printf("\n%s\n", strings.Repeat("=", 50))
-			}
-		case err, ok := <-errorChan:
-			if !ok {
-				errorChan = nil
-			} else {
-				fmt.Printf("Error occurred: %v\n", err)
-			}
-		}
+	g.Go(func(ctx context.Context) (err error) {
+		reviewValidated, err = extractReview[MovieReviewValidated](ctx, llm, text, true)
+		return err
+	})
 
-		if reviewChan == nil && reviewValidatedChan == nil && errorChan == nil {
-			break
-		}
+	if err := g.Wait(); err != nil {
+		fmt.Printf("Error occurred: %v\n", err)
+	}
+	if review != nil {
+		fmt.Printf("\n%s\n", strings.Repeat("=", 50))
+		fmt.Println("\nExtracted Movie Review (without validation):")
+		printReview(review)
+		fmt.Printf("\n%s\n", strings.Repeat("=", 50))
+	}
+	if reviewValidated != nil {
+		fmt.Printf("\n%s\n", strings.Repeat("=", 50))
+		fmt.Println("\nExtracted Movie Review (with validation):")
+		printReview(reviewValidated)
+		fmt.Printf("\n%s\n", strings.Repeat("=", 50))
 	}
+	fmt.Printf("Tokens used: %d\n", g.Usage().Usage.TotalTokens)
 
 	fmt.Println("Application completed.")
 }
@@ -0,0 +1,10 @@
+// Package gollm provides fill-in-the-middle completion for Language Learning Models.
+// This file contains re-exports for code completion with a provider.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// CompleteFIM returns the text between a prefix and a suffix using the provider's fill-in-the-middle endpoint.
+var CompleteFIM = llm.CompleteFIM
@@ -3,12 +3,14 @@ module github.com/teilomillet/gollm
 go 1.22.5
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/caarlos0/env/v11 v11.3.0
 	github.com/go-playground/validator/v10 v10.23.0
 	github.com/invopop/jsonschema v0.12.0
 	github.com/pkoukk/tiktoken-go v0.1.7
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/time v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -29,5 +31,4 @@ require (
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
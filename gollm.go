@@ -34,8 +34,12 @@ type LLM interface {
 	// SetOllamaEndpoint configures a custom endpoint for Ollama provider.
 	// Returns an error if the current provider doesn't support endpoint configuration.
 	SetOllamaEndpoint(endpoint string) error
-	// SetSystemPrompt updates the system prompt with caching configuration.
-	// The cacheType parameter determines how the prompt should be cached.
+	// SupportsMessages reports whether the provider receives a prompt's
+	// conversation history as separate messages rather than as prompt text.
+	SupportsMessages() bool
+	// SetSystemPrompt updates the default system prompt.
+	// The cacheType parameter is ignored and only kept for compatibility; see
+	// the method's documentation.
 	SetSystemPrompt(prompt string, cacheType CacheType)
 }
 
@@ -50,10 +54,16 @@ type llmImpl struct {
 	config   *config.Config
 }
 
-// SetSystemPrompt sets the system prompt for the LLM.
+// SetSystemPrompt sets the default system prompt for the LLM.
+// A system prompt set on an individual Prompt replaces it for that request, or
+// is combined with it according to the configured SystemPromptPolicy.
+//
+// The cacheType parameter is ignored and only kept for compatibility.
+// Providers with prompt caching, such as Anthropic, place the cache
+// breakpoints of the system prompt themselves; enable them with
+// SetEnableCaching or the "enable_caching" option.
 func (l *llmImpl) SetSystemPrompt(prompt string, cacheType CacheType) {
-	newPrompt := NewPrompt(prompt, WithSystemPrompt(prompt, cacheType))
-	l.SetOption("system_prompt", newPrompt)
+	l.SetOption("system_prompt", prompt)
 }
 
 // GetProvider returns the provider of the LLM.
@@ -91,6 +101,13 @@ func (l *llmImpl) SetOllamaEndpoint(endpoint string) error {
 	return fmt.Errorf("current provider does not support setting custom endpoint")
 }
 
+// SupportsMessages reports whether the provider accepts conversation
+// histories natively.
+func (l *llmImpl) SupportsMessages() bool {
+	mp, ok := l.provider.(providers.MessageProvider)
+	return ok && mp.SupportsMessages()
+}
+
 // GetPromptJSONSchema generates and returns the JSON schema for the Prompt.
 func (l *llmImpl) GetPromptJSONSchema(opts ...SchemaOption) ([]byte, error) {
 	p := &Prompt{}
@@ -106,6 +123,12 @@ func (l *llmImpl) UpdateLogLevel(level LogLevel) {
 	}
 }
 
+// Unwrap returns the underlying llm.LLM, so that helpers such as llm.Embed
+// can reach the provider client.
+func (l *llmImpl) Unwrap() llm.LLM {
+	return l.LLM
+}
+
 // Implement the base Generate method (if not already provided by embedded llm.LLM)
 func (l *llmImpl) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
 	l.logger.Debug("Starting Generate method", "prompt_length", len(prompt.String()), "context", ctx)
@@ -155,6 +178,41 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 		opt(cfg)
 	}
 
+	return newLLMFromConfig(cfg)
+}
+
+// NewLLMFromConfigFile creates a new LLM instance from a YAML, TOML or JSON
+// configuration file. The profile is selected through the GOLLM_PROFILE
+// environment variable or the file's default_profile, and the given options
+// are applied last so they override anything set in the file.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLMFromConfigFile("gollm.yaml", gollm.SetLogLevel(gollm.LogLevelDebug))
+func NewLLMFromConfigFile(path string, opts ...ConfigOption) (LLM, error) {
+	cfg, err := config.LoadFile(path, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return newLLMFromConfig(cfg)
+}
+
+// NewLLMFromConfig creates a new LLM instance from a configuration loaded
+// with LoadConfig, LoadFile or LoadFileProfile.
+//
+// Example:
+//
+//	cfg, err := gollm.LoadFileProfile("gollm.yaml", "work")
+//	if err != nil {
+//		return err
+//	}
+//	llm, err := gollm.NewLLMFromConfig(cfg)
+func NewLLMFromConfig(cfg *Config) (LLM, error) {
+	return newLLMFromConfig(cfg)
+}
+
+// newLLMFromConfig validates cfg and builds the LLM instance around it.
+func newLLMFromConfig(cfg *Config) (LLM, error) {
 	// Validate config
 	if err := llm.Validate(cfg); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -194,6 +252,7 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 			logger.Error("Failed to create LLM with memory", "error", err)
 			return nil, fmt.Errorf("failed to create LLM with memory: %w", err)
 		}
+		llmWithMemory.SetPersistPartialStreams(cfg.MemoryOption.PersistPartialStreams)
 		llmInstance.LLM = llmWithMemory
 	}
 
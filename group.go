@@ -0,0 +1,29 @@
+// Package gollm provides concurrent LLM calls for Language Learning Models.
+// This file contains re-exports for running groups of calls with shared limits.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// Group runs related LLM calls concurrently with shared limits, cancellation and usage.
+	Group = llm.Group
+
+	// GroupOption configures a Group.
+	GroupOption = llm.GroupOption
+)
+
+var (
+	// NewGroup creates a Group and the context its calls run with.
+	NewGroup = llm.NewGroup
+
+	// WithGroupLimit runs at most n calls of the group at once.
+	WithGroupLimit = llm.WithGroupLimit
+
+	// WithGroupRateLimit starts at most a number of calls of the group per second.
+	WithGroupRateLimit = llm.WithGroupRateLimit
+
+	// WithGroupFatal sets which errors cancel the group.
+	WithGroupFatal = llm.WithGroupFatal
+)
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestPromptAttachments(t *testing.T) {
+	image := t.TempDir() + "/pixel.png"
+	require.NoError(t, os.WriteFile(image, []byte("\x89PNG\r\n\x1a\nfake"), 0o600))
+
+	prompt := NewPrompt("Describe these", WithImageFile(image), WithImageURL("https://example.com/cat.jpg"))
+	l := &LLMImpl{Options: map[string]interface{}{}}
+
+	openai := providers.NewOpenAIProvider("sk-test", "gpt-4o", nil)
+	body, err := openai.PrepareRequest(prompt.String(), l.requestOptions(prompt, nil))
+	require.NoError(t, err)
+
+	var request struct {
+		Messages []struct {
+			Content []struct {
+				Type     string `json:"type"`
+				ImageURL struct {
+					URL string `json:"url"`
+				} `json:"image_url"`
+			} `json:"content"`
+		} `json:"messages"`
+		Attachments interface{} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal(body, &request))
+	require.Len(t, request.Messages, 1)
+	content := request.Messages[0].Content
+	require.Len(t, content, 3)
+	assert.Equal(t, "text", content[0].Type)
+	assert.True(t, strings.HasPrefix(content[1].ImageURL.URL, "data:image/png;base64,"))
+	assert.Equal(t, "https://example.com/cat.jpg", content[2].ImageURL.URL)
+	assert.Nil(t, request.Attachments)
+
+	_, err = providers.NewOllamaProvider("", "llava", nil).PrepareRequest(prompt.String(), l.requestOptions(prompt, nil))
+	assert.ErrorContains(t, err, "does not support image attachments by URL")
+}
+
+func TestMessageAttachments(t *testing.T) {
+	first := utils.Attachment{Type: utils.AttachmentImage, URL: "https://example.com/first.png"}
+	second := utils.Attachment{Type: utils.AttachmentImage, Data: []byte("\x89PNG\r\n\x1a\n"), MediaType: "image/png"}
+	prompt := NewPrompt("Now compare it with this second image.",
+		WithMessages(nil),
+		WithMessage("user", "Describe this image.", ""),
+		WithMessageAttachments(first),
+		WithMessage("assistant", "A cat.", ""),
+		WithMessage("user", "Now compare it with this second image.", ""),
+		WithMessageAttachments(second),
+	)
+	l := &LLMImpl{Options: map[string]interface{}{}}
+	prepare := func(provider providers.Provider, prompt *Prompt) []interface{} {
+		options := l.requestOptions(prompt, nil)
+		body, err := provider.PrepareRequest(promptText(provider, prompt, options), options)
+		require.NoError(t, err)
+		var request struct {
+			Messages []interface{} `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal(body, &request))
+		return request.Messages
+	}
+
+	messages := prepare(providers.NewOpenAIProvider("key", "gpt-4o", nil), prompt)
+	require.Len(t, messages, 3)
+	parts := messages[0].(map[string]interface{})["content"].([]interface{})
+	assert.Equal(t, "https://example.com/first.png", parts[1].(map[string]interface{})["image_url"].(map[string]interface{})["url"])
+	assert.Equal(t, "A cat.", messages[1].(map[string]interface{})["content"])
+	parts = messages[2].(map[string]interface{})["content"].([]interface{})
+	assert.True(t, strings.HasPrefix(parts[1].(map[string]interface{})["image_url"].(map[string]interface{})["url"].(string), "data:image/png;base64,"))
+
+	messages = prepare(providers.NewAnthropicProvider("key", "claude-3-5-sonnet-latest", nil), prompt)
+	require.Len(t, messages, 3)
+	blocks := messages[2].(map[string]interface{})["content"].([]interface{})
+	assert.Equal(t, "image", blocks[0].(map[string]interface{})["type"])
+	assert.Equal(t, "Now compare it with this second image.", blocks[1].(map[string]interface{})["text"])
+
+	// Providers without native messages receive every attachment with the prompt
+	options := l.requestOptions(prompt, nil)
+	promptText(&echoProvider{}, prompt, options)
+	assert.Equal(t, []utils.Attachment{first, second}, options["attachments"])
+}
+
+func TestImageLimits(t *testing.T) {
+	limits := providers.ImageLimits{MaxImages: 2, MaxImageBytes: 1 << 20}
+	small := utils.Attachment{Type: utils.AttachmentImage, Data: make([]byte, 1024), Filename: "small.png"}
+
+	prompt := NewPrompt("describe", WithImageURL("https://example.com/a.png"))
+	prompt.Attachments = append(prompt.Attachments, small)
+	assert.NoError(t, prompt.CheckImageLimits(limits))
+
+	prompt.Attachments = append(prompt.Attachments, small)
+	var llmErr *LLMError
+	require.ErrorAs(t, prompt.CheckImageLimits(limits), &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	assert.Contains(t, llmErr.Message, "3 images, over the limit of 2")
+
+	// Documents do not count, and images in messages do
+	large := utils.Attachment{Type: utils.AttachmentImage, Data: make([]byte, 1<<20+100<<10), Filename: "large.png"}
+	prompt = NewPrompt("describe", WithMessage("user", "first", ""), WithMessageAttachments(large))
+	prompt.Attachments = append(prompt.Attachments, utils.Attachment{Type: utils.AttachmentFile, Data: make([]byte, 2<<20)})
+	require.ErrorAs(t, prompt.CheckImageLimits(limits), &llmErr)
+	assert.Equal(t, "image 1 (large.png) is 1.1 MB, over the limit of 1 MB per image", llmErr.Message)
+
+	prompt = NewPrompt("describe", WithImageURL("https://example.com/a.png"))
+	require.ErrorAs(t, prompt.CheckImageLimits(providers.ImageLimits{InlineOnly: true}), &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+	data := NewPrompt("describe", WithImageURL("data:image/png;base64,"+base64.StdEncoding.EncodeToString(make([]byte, 2048))))
+	assert.NoError(t, data.CheckImageLimits(providers.ImageLimits{InlineOnly: true, MaxImageBytes: 2048}))
+	require.ErrorAs(t, data.CheckImageLimits(providers.ImageLimits{MaxImageBytes: 100}), &llmErr)
+	assert.Equal(t, "image 1 (attachment) is 2 KB, over the limit of 100 bytes per image", llmErr.Message)
+
+	// Generate rejects images over the provider's limits before sending them
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}]}`)
+	}))
+	defer server.Close()
+	l := newTestLLM(t, "", config.SetProvider("anthropic"), config.SetModel("claude-sonnet-4-20250514"), config.SetAPIKey("key"), config.SetBaseURL("anthropic", server.URL))
+	prompt = NewPrompt("describe")
+	prompt.Attachments = []utils.Attachment{{Type: utils.AttachmentImage, Data: make([]byte, 4<<20), Filename: "photo.jpg"}}
+	_, err := l.Generate(context.Background(), prompt)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	assert.Contains(t, llmErr.Message, "photo.jpg")
+	assert.Equal(t, 0, calls)
+}
@@ -0,0 +1,150 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+// ErrCircuitOpen is returned when a request is rejected because the circuit
+// breaker for the provider endpoint is open. gollm does not divert requests
+// itself; callers detect it with errors.Is to fall back to another LLM.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState describes the current state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects requests until the cool-down elapses.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a single trial request through to probe recovery.
+	CircuitHalfOpen
+)
+
+// String returns a human-readable name for the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker tracks consecutive failures for a provider endpoint and
+// short-circuits requests while the endpoint is considered unhealthy.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	cfg      config.CircuitBreakerConfig
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool             // A half-open trial request is in flight
+	now      func() time.Time // Clock, replaceable in tests
+}
+
+// NewCircuitBreaker creates a closed circuit breaker with the given thresholds.
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{cfg: cfg, now: time.Now}
+}
+
+// Allow reports whether a request may proceed. It returns ErrCircuitOpen while
+// the circuit is open, or while a half-open trial request is still in flight.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cfg.CoolDown {
+			return ErrCircuitOpen
+		}
+		cb.state = CircuitHalfOpen
+		cb.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess closes the circuit and resets the failure count.
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// RecordFailure counts a failure, opening the circuit once the threshold is
+// reached. A failed half-open trial reopens the circuit immediately.
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.cfg.FailureThreshold {
+		cb.state = CircuitOpen
+		cb.openedAt = cb.now()
+	}
+	cb.probing = false
+}
+
+// release abandons an in-flight half-open trial without recording an outcome,
+// e.g. when the caller cancelled the request.
+func (cb *CircuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probing = false
+}
+
+// State returns the current state of the circuit.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.cfg.CoolDown {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// circuitBreakerKey identifies a shared breaker. LLM instances only share a
+// breaker when they talk to the same endpoint with the same thresholds.
+type circuitBreakerKey struct {
+	endpoint string
+	cfg      config.CircuitBreakerConfig
+}
+
+var (
+	circuitBreakers   = make(map[circuitBreakerKey]*CircuitBreaker)
+	circuitBreakersMu sync.Mutex
+)
+
+// circuitBreakerFor returns the breaker shared by all LLM instances that talk
+// to the given endpoint with the same configuration, creating it on first use.
+func circuitBreakerFor(endpoint string, cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+	key := circuitBreakerKey{endpoint: endpoint, cfg: cfg}
+	if cb, ok := circuitBreakers[key]; ok {
+		return cb
+	}
+	cb := NewCircuitBreaker(cfg)
+	circuitBreakers[key] = cb
+	return cb
+}
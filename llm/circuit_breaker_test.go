@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	cb := NewCircuitBreaker(config.CircuitBreakerConfig{FailureThreshold: 2, CoolDown: time.Minute})
+	cb.now = func() time.Time { return now }
+
+	assert.NoError(t, cb.Allow())
+	cb.RecordFailure()
+	assert.Equal(t, CircuitClosed, cb.State())
+	cb.RecordFailure()
+	assert.Equal(t, CircuitOpen, cb.State())
+	assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+
+	// After the cool-down a single trial request is allowed.
+	now = now.Add(time.Minute)
+	assert.NoError(t, cb.Allow())
+	assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+
+	// A failed trial reopens the circuit immediately.
+	cb.RecordFailure()
+	assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, cb.Allow())
+	cb.RecordSuccess()
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.NoError(t, cb.Allow())
+}
+
+func TestCircuitBreakerForSharesByEndpointAndConfig(t *testing.T) {
+	cfg := config.CircuitBreakerConfig{FailureThreshold: 2, CoolDown: time.Minute}
+	shared := circuitBreakerFor("https://breaker.test/v1", cfg)
+	assert.Same(t, shared, circuitBreakerFor("https://breaker.test/v1", cfg))
+	assert.NotSame(t, shared, circuitBreakerFor("https://other.test/v1", cfg))
+
+	stricter := circuitBreakerFor("https://breaker.test/v1", config.CircuitBreakerConfig{FailureThreshold: 1, CoolDown: time.Minute})
+	assert.NotSame(t, shared, stricter)
+	stricter.RecordFailure()
+	assert.Equal(t, CircuitOpen, stricter.State())
+	assert.Equal(t, CircuitClosed, shared.State(), "breakers with other thresholds are not shared")
+}
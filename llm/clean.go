@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+// WithCleanStrategy sets how the text generated by the Generate call is
+// cleaned, overriding the configured strategy: config.CleanNone,
+// config.CleanMinimal or config.CleanAggressive. Cleaning runs before the
+// post-processors, and Response.Raw keeps the text as generated.
+func WithCleanStrategy(strategy string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.CleanStrategy = strings.ToLower(strategy)
+	}
+}
+
+// CleanText cleans text with a strategy. An empty strategy is
+// config.CleanNone.
+func CleanText(text, strategy string) (string, error) {
+	switch strategy {
+	case "", config.CleanNone:
+		return text, nil
+	case config.CleanMinimal:
+		return stripFence(strings.TrimSpace(text)), nil
+	case config.CleanAggressive:
+		text = stripFence(strings.TrimSpace(text))
+		start := strings.Index(text, "{")
+		end := strings.LastIndex(text, "}")
+		if start != -1 && end > start {
+			text = text[start : end+1]
+		}
+		return strings.TrimSpace(text), nil
+	default:
+		return "", fmt.Errorf("unknown clean strategy %q", strategy)
+	}
+}
+
+// stripFence removes a markdown code fence, such as ```json, around the
+// whole of text.
+func stripFence(text string) string {
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	inner := strings.TrimSuffix(text[3:], "```")
+	// The rest of the opening line names the language
+	if newline := strings.IndexByte(inner, '\n'); newline != -1 && !strings.ContainsAny(inner[:newline], " {[") {
+		inner = inner[newline+1:]
+	}
+	return strings.TrimSpace(inner)
+}
+
+// cleanStrategy returns the clean strategy of a call, validated.
+func (l *LLMImpl) cleanStrategy(cfg *GenerateConfig) (string, error) {
+	strategy := cfg.CleanStrategy
+	if strategy == "" && l.config != nil {
+		strategy = l.config.CleanStrategy
+	}
+	if _, err := CleanText("", strategy); err != nil {
+		return "", NewLLMError(ErrorTypeInvalidInput, "invalid clean strategy", err)
+	}
+	return strategy, nil
+}
@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestCleanStrategy(t *testing.T) {
+	fenced := "```json\n{\"a\": 1}\n```"
+	minimal, err := CleanText("  "+fenced+"\n", config.CleanMinimal)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1}`, minimal)
+	aggressive, err := CleanText(`Result: {"a": 1} hope it helps`, config.CleanAggressive)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1}`, aggressive)
+	_, err = CleanText("x", "heavy")
+	assert.Error(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "Sure:\n" + fenced})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL, config.SetCleanStrategy(config.CleanAggressive))
+
+	var response Response
+	result, err := l.Generate(context.Background(), NewPrompt("json"), WithResponse(&response))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1}`, result)
+	assert.Equal(t, "Sure:\n"+fenced, response.Raw)
+
+	result, err = l.Generate(context.Background(), NewPrompt("json"), WithCleanStrategy(config.CleanNone))
+	require.NoError(t, err)
+	assert.Equal(t, "Sure:\n"+fenced, result)
+
+	_, err = l.Generate(context.Background(), NewPrompt("json"), WithCleanStrategy("heavy"))
+	assert.Error(t, err)
+}
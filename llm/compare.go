@@ -0,0 +1,225 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ComparisonResult is the outcome of one LLM in a Compare run.
+type ComparisonResult struct {
+	// Provider and Model identify the LLM, when it reported them.
+	Provider string
+	Model    string
+
+	// Response holds the generated text and token usage. It is nil when the
+	// call failed.
+	Response *Response
+
+	// Latency is the duration of the call, including retries.
+	Latency time.Duration
+
+	// Err is the error of the call, if any.
+	Err error
+
+	// Score is the judge's score from 0 to 10, and Rank the position in the
+	// judge's ranking, starting at 1. Both are zero without a judge.
+	Score float64
+	Rank  int
+
+	// Rationale is the judge's explanation of the score.
+	Rationale string
+}
+
+// CompareOption configures a Compare run.
+type CompareOption func(*compareConfig)
+
+type compareConfig struct {
+	judge         LLM
+	criteria      string
+	generateOpts  []GenerateOption
+	maxConcurrent int
+}
+
+// WithJudge ranks the responses with a judge LLM, which scores each one
+// against the criteria, such as "accuracy and concision". An empty criteria
+// asks for overall quality.
+func WithJudge(judge LLM, criteria string) CompareOption {
+	return func(c *compareConfig) {
+		c.judge = judge
+		c.criteria = criteria
+	}
+}
+
+// WithCompareGenerateOptions passes options to every Generate call of the
+// comparison.
+func WithCompareGenerateOptions(opts ...GenerateOption) CompareOption {
+	return func(c *compareConfig) {
+		c.generateOpts = append(c.generateOpts, opts...)
+	}
+}
+
+// WithCompareConcurrency limits the number of LLMs called at the same time.
+// By default all are called concurrently.
+func WithCompareConcurrency(n int) CompareOption {
+	return func(c *compareConfig) {
+		c.maxConcurrent = n
+	}
+}
+
+// Compare sends the prompt to every LLM concurrently and returns their
+// responses, latencies and token usage, in the order of llms. Failed calls
+// are reported in the result's Err rather than failing the comparison. With
+// WithJudge, the successful responses are also scored and ranked; an error
+// is returned if judging fails, along with the unranked results.
+//
+// Example:
+//
+//	results, err := llm.Compare(ctx, []llm.LLM{gpt, claude}, prompt,
+//	    llm.WithJudge(judge, "factual accuracy"))
+//	for _, r := range results {
+//	    fmt.Printf("#%d %s/%s (%s, %d tokens): %s\n", r.Rank, r.Provider, r.Model,
+//	        r.Latency, r.Response.Usage.TotalTokens, r.Response.Content)
+//	}
+func Compare(ctx context.Context, llms []LLM, prompt *Prompt, opts ...CompareOption) ([]ComparisonResult, error) {
+	if len(llms) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no LLMs to compare", nil)
+	}
+	cfg := &compareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	results := fanOut(ctx, llms, cfg.maxConcurrent, func(ctx context.Context, l LLM) (*Response, error) {
+		return GenerateResponse(ctx, l, prompt, cfg.generateOpts...)
+	})
+
+	if cfg.judge != nil {
+		if err := judgeResults(ctx, cfg.judge, cfg.criteria, prompt, results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// fanOut calls every LLM concurrently, at most limit at a time when limit
+// is positive, and returns the results in the order of llms.
+func fanOut(ctx context.Context, llms []LLM, limit int, call func(context.Context, LLM) (*Response, error)) []ComparisonResult {
+	results := make([]ComparisonResult, len(llms))
+	if limit <= 0 || limit > len(llms) {
+		limit = len(llms)
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, l := range llms {
+		wg.Add(1)
+		go func(i int, l LLM) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := &results[i]
+			if named, ok := l.(interface {
+				GetProvider() string
+				GetModel() string
+			}); ok {
+				result.Provider, result.Model = named.GetProvider(), named.GetModel()
+			}
+
+			start := time.Now()
+			result.Response, result.Err = call(ctx, l)
+			result.Latency = time.Since(start)
+			if result.Response != nil && result.Response.Provider != "" {
+				result.Provider, result.Model = result.Response.Provider, result.Response.Model
+			}
+		}(i, l)
+	}
+	wg.Wait()
+	return results
+}
+
+// judgeScores is the response format of the judge.
+type judgeScores struct {
+	Scores []struct {
+		Candidate int     `json:"candidate"`
+		Score     float64 `json:"score"`
+		Rationale string  `json:"rationale"`
+	} `json:"scores"`
+}
+
+var judgeScoresSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"scores": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"candidate": map[string]interface{}{"type": "integer"},
+					"score":     map[string]interface{}{"type": "number", "minimum": 0, "maximum": 10},
+					"rationale": map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"candidate", "score", "rationale"},
+			},
+		},
+	},
+	"required": []string{"scores"},
+}
+
+// judgeResults asks the judge to score the successful results and ranks
+// them by score.
+func judgeResults(ctx context.Context, judge LLM, criteria string, prompt *Prompt, results []ComparisonResult) error {
+	if criteria == "" {
+		criteria = "overall quality, correctness and helpfulness"
+	}
+
+	var candidates strings.Builder
+	var judged []int
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		judged = append(judged, i)
+		fmt.Fprintf(&candidates, "<candidate id=\"%d\">\n%s\n</candidate>\n", len(judged), r.Response.Content)
+	}
+	if len(judged) == 0 {
+		return nil
+	}
+
+	judgePrompt := NewPrompt(fmt.Sprintf("Task given to each candidate:\n<task>\n%s\n</task>\n\nCandidate responses:\n%s", prompt.String(), candidates.String()),
+		WithSystemPrompt("You are an impartial judge comparing responses to the same task.", ""),
+		WithDirectives(
+			"Score every candidate from 0 to 10 on "+criteria,
+			"Judge the content, not the length or style of the response",
+			"Give a one-sentence rationale for each score",
+		),
+	)
+	output, err := judge.GenerateWithSchema(ctx, judgePrompt, judgeScoresSchema)
+	if err != nil {
+		return NewLLMError(ErrorTypeResponse, "failed to judge responses", err)
+	}
+	var scores judgeScores
+	if err := json.Unmarshal([]byte(output), &scores); err != nil {
+		return NewLLMError(ErrorTypeResponse, "failed to parse judge scores", err)
+	}
+	for _, s := range scores.Scores {
+		if s.Candidate < 1 || s.Candidate > len(judged) {
+			continue
+		}
+		r := &results[judged[s.Candidate-1]]
+		r.Score, r.Rationale = s.Score, s.Rationale
+	}
+
+	ranked := append([]int(nil), judged...)
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return results[ranked[a]].Score > results[ranked[b]].Score
+	})
+	for rank, i := range ranked {
+		results[i].Rank = rank + 1
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	reply := func(content string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]string{"content": content})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	short := newTestLLM(t, reply("Paris").URL)
+	long := newTestLLM(t, reply("Paris is the capital of France.").URL)
+	judge := newTestLLM(t, reply(`{"scores": [{"candidate": 1, "score": 6, "rationale": "Terse"}, {"candidate": 2, "score": 9, "rationale": "Complete"}]}`).URL)
+
+	results, err := Compare(context.Background(), []LLM{short, long}, NewPrompt("What is the capital of France?"), WithJudge(judge, ""))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Paris", results[0].Response.Content)
+	assert.Equal(t, "echo", results[0].Provider)
+	assert.Positive(t, results[0].Latency)
+	assert.Equal(t, []int{2, 1}, []int{results[0].Rank, results[1].Rank})
+	assert.Equal(t, 9.0, results[1].Score)
+	assert.Equal(t, "Complete", results[1].Rationale)
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Complete continues text with the raw completion endpoint of the LLM's
+// provider, without a chat template or system prompt: the legacy completions
+// API for OpenAI, DeepSeek and OpenAI-compatible servers such as vLLM,
+// llama.cpp and OpenRouter, and a raw generate request for Ollama. It suits
+// base models and tasks where chat formatting hurts, such as scoring or
+// completing documents. Options set with WithOption, such as "max_tokens",
+// "stop" or "logprobs", are passed to the provider. It returns
+// ErrorTypeUnsupported when the provider has no completion endpoint.
+//
+// Example:
+//
+//	rest, err := llm.Complete(ctx, client, "The quick brown fox", llm.WithOption("max_tokens", 16))
+func Complete(ctx context.Context, l LLM, text string, opts ...GenerateOption) (string, error) {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return "", NewLLMError(ErrorTypeUnsupported, "raw completion requires a gollm client", nil)
+	}
+	if _, ok := impl.Provider.(providers.CompletionProvider); !ok {
+		return "", NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("raw completion not supported by provider %s", impl.Provider.Name()), nil)
+	}
+	if text == "" {
+		return "", NewLLMError(ErrorTypeInvalidInput, "empty text", nil)
+	}
+
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var completion string
+	err := impl.callEndpoint(ctx, "failed to complete", func(provider providers.Provider) (string, []byte, error) {
+		completer, ok := provider.(providers.CompletionProvider)
+		if !ok {
+			return "", nil, NewLLMError(ErrorTypeUnsupported, "raw completion not supported by provider "+provider.Name(), nil)
+		}
+		reqBody, err := completer.PrepareCompletionRequest(text, cfg.Options)
+		if err != nil {
+			return "", nil, NewLLMError(ErrorTypeRequest, "failed to prepare completion request", err)
+		}
+		return completer.CompletionEndpoint(), reqBody, nil
+	}, func(provider providers.Provider, body []byte) error {
+		var err error
+		if completion, err = provider.(providers.CompletionProvider).ParseCompletionResponse(body); err != nil {
+			return NewLLMError(ErrorTypeResponse, "failed to parse completion response", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return completion, nil
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestComplete(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/completions", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"choices":[{"text":" jumps over the lazy dog"}]}`)
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("vllm"), config.SetModel("base"), config.SetAPIKey("test-key"), config.SetDefaultSystemPrompt("Be brief."))
+	cfg.Endpoints = map[string]string{"vllm": server.URL}
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	rest, err := Complete(context.Background(), l, "The quick brown fox", WithOption("max_tokens", 16))
+	require.NoError(t, err)
+	assert.Equal(t, " jumps over the lazy dog", rest)
+	assert.Equal(t, "The quick brown fox", request["prompt"])
+	assert.Equal(t, float64(16), request["max_tokens"])
+	assert.NotContains(t, request, "messages")
+	assert.NotContains(t, request, "system_prompt")
+
+	_, err = Complete(context.Background(), newTestLLM(t, server.URL), "text")
+	assert.Error(t, err)
+}
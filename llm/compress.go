@@ -0,0 +1,237 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Compressor shrinks text to about budget tokens, as estimated by
+// EstimateTokens, keeping the content that matters most for query. Any
+// func(ctx context.Context, text, query string, budget int) (string, error)
+// can be used as a Compressor.
+type Compressor func(ctx context.Context, text, query string, budget int) (string, error)
+
+// contextCompression is the context compression requested for a call.
+type contextCompression struct {
+	budget   int
+	compress Compressor
+}
+
+// WithContextCompression shrinks the context and documents of the prompt to
+// about budget tokens before sending it, with HeuristicCompressor. Prompts
+// whose context already fits are sent unchanged.
+//
+// Example:
+//
+//	prompt := llm.NewPrompt("When was the warranty extended?", llm.WithContext(manual))
+//	answer, err := client.Generate(ctx, prompt, llm.WithContextCompression(2000))
+func WithContextCompression(budget int) GenerateOption {
+	return WithContextCompressor(budget, HeuristicCompressor())
+}
+
+// WithContextCompressor is like WithContextCompression but shrinks the
+// context with compress, such as a ModelCompressor.
+func WithContextCompressor(budget int, compress Compressor) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.compression = &contextCompression{budget: budget, compress: compress}
+	}
+}
+
+// apply returns a copy of prompt whose context and documents fit the budget.
+// Each block gets a share of the budget proportional to its size.
+func (c *contextCompression) apply(ctx context.Context, prompt *Prompt) (*Prompt, error) {
+	if c.budget <= 0 || c.compress == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "context compression requires a positive budget and a compressor", nil)
+	}
+	total := EstimateTokens(prompt.Context)
+	for _, doc := range prompt.Documents {
+		total += EstimateTokens(doc.Text)
+	}
+	if total <= c.budget {
+		return prompt, nil
+	}
+
+	compress := func(text string) (string, error) {
+		tokens := EstimateTokens(text)
+		if tokens == 0 {
+			return text, nil
+		}
+		share := max(1, c.budget*tokens/total)
+		compressed, err := c.compress(ctx, text, prompt.Input, share)
+		if err != nil {
+			return "", NewLLMError(ErrorTypeRequest, "failed to compress context", err)
+		}
+		return compressed, nil
+	}
+	compressed := *prompt
+	var err error
+	if compressed.Context, err = compress(prompt.Context); err != nil {
+		return nil, err
+	}
+	if len(prompt.Documents) > 0 {
+		compressed.Documents = append(compressed.Documents[:0:0], prompt.Documents...)
+		for i := range compressed.Documents {
+			if compressed.Documents[i].Text, err = compress(prompt.Documents[i].Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &compressed, nil
+}
+
+// HeuristicCompressor keeps the most informative sentences of the text, in
+// their original order, until the budget is filled. Sentences score higher
+// for words that are rare in the text and for words of the query, much like
+// the token-level pruning of LLMLingua applied to whole sentences. It needs
+// no model call, so it adds no latency or cost.
+func HeuristicCompressor() Compressor {
+	return func(ctx context.Context, text, query string, budget int) (string, error) {
+		if EstimateTokens(text) <= budget {
+			return text, nil
+		}
+		sentences := splitSentences(text)
+
+		words := make([][]string, len(sentences))
+		frequency := make(map[string]int)
+		for i, sentence := range sentences {
+			words[i] = contentWords(sentence)
+			seen := make(map[string]bool)
+			for _, word := range words[i] {
+				if !seen[word] {
+					seen[word] = true
+					frequency[word]++
+				}
+			}
+		}
+		queryWords := make(map[string]bool)
+		for _, word := range contentWords(query) {
+			queryWords[word] = true
+		}
+
+		scores := make([]float64, len(sentences))
+		for i := range sentences {
+			for _, word := range words[i] {
+				scores[i] += math.Log(1 + float64(len(sentences))/float64(frequency[word]))
+				if queryWords[word] {
+					scores[i] += 2
+				}
+			}
+			// Dividing by the square root favours dense sentences without
+			// discarding every long one
+			if len(words[i]) > 0 {
+				scores[i] /= math.Sqrt(float64(len(words[i])))
+			}
+		}
+
+		ranked := make([]int, len(sentences))
+		for i := range ranked {
+			ranked[i] = i
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i]] > scores[ranked[j]] })
+		kept := make([]bool, len(sentences))
+		used := 0
+		for _, i := range ranked {
+			if tokens := EstimateTokens(sentences[i]); used+tokens <= budget {
+				kept[i] = true
+				used += tokens
+			}
+		}
+		var builder strings.Builder
+		for i, sentence := range sentences {
+			if kept[i] {
+				if builder.Len() > 0 {
+					builder.WriteString(" ")
+				}
+				builder.WriteString(sentence)
+			}
+		}
+		if builder.Len() == 0 {
+			// Not even the best sentence fits: keep its beginning
+			return truncateWords(sentences[ranked[0]], budget*4), nil
+		}
+		return builder.String(), nil
+	}
+}
+
+// ModelCompressor asks l, typically a small and cheap model, to rewrite the
+// text within the budget while keeping the facts relevant to the query.
+func ModelCompressor(l LLM) Compressor {
+	return func(ctx context.Context, text, query string, budget int) (string, error) {
+		prompt := NewPrompt(
+			fmt.Sprintf("Compress the following text to at most %d tokens, about %d words.\n\nText:\n%s", budget, budget*3/4, text),
+			WithDirectives(
+				"Keep names, numbers, dates and every fact relevant to the question",
+				"Drop repetitions, filler and formatting",
+				"Reply with the compressed text only",
+			),
+		)
+		if query != "" {
+			prompt.Input += "\n\nQuestion:\n" + query
+		}
+		compressed, err := l.Generate(ctx, prompt, WithOption("max_tokens", budget))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(compressed), nil
+	}
+}
+
+// splitSentences splits text into trimmed sentences.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range sentenceEnd.FindAllStringIndex(text, -1) {
+		if sentence := strings.TrimSpace(text[start:end[1]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end[1]
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// contentWords returns the lowercase words of text, without the stop words
+// of the languages DetectLanguage knows and words of one or two letters.
+func contentWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if utf8.RuneCountInString(word) > 2 && !allStopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// allStopWords holds the stop words of every language of stopWords.
+var allStopWords = func() map[string]bool {
+	set := make(map[string]bool)
+	for _, words := range stopWords {
+		for _, word := range words {
+			set[word] = true
+		}
+	}
+	return set
+}()
+
+// truncateWords cuts text to at most limit bytes at a word boundary.
+func truncateWords(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.LastIndexFunc(text[:limit+1], unicode.IsSpace)
+	if cut <= 0 {
+		// Back up to a rune boundary
+		for cut = limit; cut > 0 && !utf8.RuneStart(text[cut]); cut-- {
+		}
+	}
+	return strings.TrimSpace(text[:cut])
+}
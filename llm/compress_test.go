@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestContextCompression(t *testing.T) {
+	manual := "The device ships in a blue box. " +
+		"Cleaning requires a dry cloth and no solvents. " +
+		"The warranty was extended to three years in 2021. " +
+		"Our offices are closed on public holidays. " +
+		"Batteries should be stored below thirty degrees."
+	l := newTestLLM(t, newEchoServer(t).URL)
+
+	prompt := NewPrompt("When was the warranty extended?", WithContext(manual))
+	sent, err := l.Generate(context.Background(), prompt, WithContextCompression(15))
+	require.NoError(t, err)
+	assert.Contains(t, sent, "The warranty was extended to three years in 2021.")
+	assert.NotContains(t, sent, "blue box")
+	assert.Equal(t, manual, prompt.Context, "the caller's prompt is not modified")
+
+	sent, err = l.Generate(context.Background(), prompt, WithContextCompression(1000))
+	require.NoError(t, err)
+	assert.Contains(t, sent, "blue box", "context within the budget is sent unchanged")
+
+	var budgets []int
+	compressor := func(ctx context.Context, text, query string, budget int) (string, error) {
+		budgets = append(budgets, budget)
+		return "summary", nil
+	}
+	prompt = NewPrompt("warranty?", WithContext(manual), WithDocuments(utils.Document{Text: manual}))
+	sent, err = l.Generate(context.Background(), prompt, WithContextCompressor(20, compressor))
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 10}, budgets, "the budget is shared between blocks")
+	assert.NotContains(t, sent, "blue box")
+
+	_, err = l.Generate(context.Background(), prompt, WithContextCompression(0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrContentFiltered is wrapped by the errors of requests whose prompt or
+// response a provider's safety system refused or filtered. Such requests
+// are not retried. Use errors.As with a *ContentFilterError for the details.
+var ErrContentFiltered = errors.New("content filtered")
+
+// ContentFilterCategory is the verdict of a provider's content filter for
+// one category, such as hate or violence.
+type ContentFilterCategory struct {
+	// Category is the provider's name of the category, such as "hate" or
+	// Gemini's "HARM_CATEGORY_HARASSMENT".
+	Category string `json:"category"`
+	// Severity is the provider's rating, such as Azure's "high" or Gemini's
+	// probability "MEDIUM". Empty when not reported.
+	Severity string `json:"severity,omitempty"`
+	// Filtered is true for the categories that caused the block.
+	Filtered bool `json:"filtered"`
+}
+
+// ContentFilterError describes a prompt or response a provider refused or
+// filtered, such as an Azure content filter block, an Anthropic refusal or a
+// Gemini safety block. It matches ErrContentFiltered with errors.Is.
+type ContentFilterError struct {
+	// Provider is the name of the provider that blocked the request.
+	Provider string `json:"provider"`
+	// Reason is why the request was blocked: "content_filter", "refusal",
+	// or Gemini's block or finish reason, such as "SAFETY".
+	Reason string `json:"reason"`
+	// Target is "prompt" when the input was blocked, or "completion" when
+	// the response was.
+	Target string `json:"target"`
+	// Categories holds the filter's verdict per category, when reported.
+	Categories []ContentFilterCategory `json:"categories,omitempty"`
+	// Message is the refusal the model wrote or the provider's explanation,
+	// if any.
+	Message string `json:"message,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *ContentFilterError) Error() string {
+	msg := fmt.Sprintf("%s blocked by %s (%s)", e.Target, e.Provider, e.Reason)
+	var filtered []string
+	for _, c := range e.Categories {
+		if c.Filtered {
+			filtered = append(filtered, strings.TrimSpace(c.Category+" "+c.Severity))
+		}
+	}
+	if len(filtered) > 0 {
+		msg += ": " + strings.Join(filtered, ", ")
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is reports whether target is ErrContentFiltered.
+func (e *ContentFilterError) Is(target error) bool {
+	return target == ErrContentFiltered
+}
+
+// contentFilterError returns an ErrorTypeContentFiltered error when body,
+// returned by provider, reports a refused or filtered prompt or response,
+// and nil otherwise.
+func contentFilterError(provider string, body []byte) error {
+	filtered := parseContentFilter(body)
+	if filtered == nil {
+		return nil
+	}
+	filtered.Provider = provider
+	return NewLLMError(ErrorTypeContentFiltered, "content filtered by "+provider, filtered)
+}
+
+// azureFilterResults are the per-category results of Azure's content
+// filter, such as {"hate": {"filtered": true, "severity": "high"}}.
+type azureFilterResults map[string]struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+}
+
+func (r azureFilterResults) categories() []ContentFilterCategory {
+	var categories []ContentFilterCategory
+	for name, result := range r {
+		categories = append(categories, ContentFilterCategory{Category: name, Severity: result.Severity, Filtered: result.Filtered})
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Category < categories[j].Category })
+	return categories
+}
+
+// geminiSafetyRatings are the safety ratings of the native Gemini API.
+type geminiSafetyRatings []struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+func (r geminiSafetyRatings) categories() []ContentFilterCategory {
+	categories := make([]ContentFilterCategory, len(r))
+	for i, rating := range r {
+		categories[i] = ContentFilterCategory{Category: rating.Category, Severity: rating.Probability, Filtered: rating.Blocked}
+	}
+	return categories
+}
+
+// geminiBlockReasons are the finish reasons of Gemini candidates withheld
+// by its safety system.
+var geminiBlockReasons = map[string]bool{
+	"SAFETY":             true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// parseContentFilter returns the content filter outcome of a response body,
+// or nil when nothing was blocked. It understands the OpenAI and Azure
+// finish reasons, refusals and filter results, Azure's content filter
+// errors, Anthropic's refusal stop reason and the native Gemini block
+// reasons.
+func parseContentFilter(body []byte) *ContentFilterError {
+	var response struct {
+		// Azure content filter errors
+		Error *struct {
+			Code       string `json:"code"`
+			Message    string `json:"message"`
+			InnerError struct {
+				ContentFilterResult azureFilterResults `json:"content_filter_result"`
+			} `json:"innererror"`
+		} `json:"error"`
+		// OpenAI and Azure
+		Choices []struct {
+			FinishReason string `json:"finish_reason"`
+			Message      struct {
+				Refusal string `json:"refusal"`
+			} `json:"message"`
+			ContentFilterResults azureFilterResults `json:"content_filter_results"`
+		} `json:"choices"`
+		// Anthropic
+		StopReason string `json:"stop_reason"`
+		Content    []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		// Gemini
+		PromptFeedback struct {
+			BlockReason   string              `json:"blockReason"`
+			SafetyRatings geminiSafetyRatings `json:"safetyRatings"`
+		} `json:"promptFeedback"`
+		Candidates []struct {
+			FinishReason  string              `json:"finishReason"`
+			SafetyRatings geminiSafetyRatings `json:"safetyRatings"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+
+	switch {
+	case response.Error != nil && response.Error.Code == "content_filter":
+		return &ContentFilterError{Reason: "content_filter", Target: "prompt", Categories: response.Error.InnerError.ContentFilterResult.categories(), Message: response.Error.Message}
+	case len(response.Choices) > 0 && response.Choices[0].FinishReason == "content_filter":
+		return &ContentFilterError{Reason: "content_filter", Target: "completion", Categories: response.Choices[0].ContentFilterResults.categories()}
+	case len(response.Choices) > 0 && response.Choices[0].Message.Refusal != "":
+		return &ContentFilterError{Reason: "refusal", Target: "completion", Message: response.Choices[0].Message.Refusal}
+	case response.StopReason == "refusal":
+		var text []string
+		for _, block := range response.Content {
+			if block.Type == "text" && block.Text != "" {
+				text = append(text, block.Text)
+			}
+		}
+		return &ContentFilterError{Reason: "refusal", Target: "completion", Message: strings.Join(text, " ")}
+	case response.PromptFeedback.BlockReason != "":
+		return &ContentFilterError{Reason: response.PromptFeedback.BlockReason, Target: "prompt", Categories: response.PromptFeedback.SafetyRatings.categories()}
+	case len(response.Candidates) > 0 && geminiBlockReasons[response.Candidates[0].FinishReason]:
+		return &ContentFilterError{Reason: response.Candidates[0].FinishReason, Target: "completion", Categories: response.Candidates[0].SafetyRatings.categories()}
+	}
+	return nil
+}
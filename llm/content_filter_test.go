@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestContentFiltered(t *testing.T) {
+	var calls int
+	var status int
+	var reply string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		calls++
+		w.WriteHeader(status)
+		fmt.Fprint(w, reply)
+	}))
+	defer server.Close()
+	generate := func(provider, model string) error {
+		l := newTestLLM(t, "", config.SetProvider(provider), config.SetModel(model), config.SetAPIKey("key"), config.SetBaseURL(provider, server.URL), config.SetMaxRetries(2))
+		_, err := l.Generate(context.Background(), NewPrompt("hi"))
+		return err
+	}
+
+	status = http.StatusBadRequest
+	reply = `{"error":{"code":"content_filter","message":"The prompt was filtered.","innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{"violence":{"filtered":true,"severity":"high"},"hate":{"filtered":false,"severity":"safe"}}}}}`
+	err := generate("openai", "gpt-4o")
+	require.ErrorIs(t, err, ErrContentFiltered)
+	assert.Equal(t, 1, calls, "filtered requests are not retried")
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeContentFiltered, llmErr.Type)
+	var filtered *ContentFilterError
+	require.ErrorAs(t, err, &filtered)
+	assert.Equal(t, "openai", filtered.Provider)
+	assert.Equal(t, "prompt", filtered.Target)
+	assert.Equal(t, []ContentFilterCategory{
+		{Category: "hate", Severity: "safe"},
+		{Category: "violence", Severity: "high", Filtered: true},
+	}, filtered.Categories)
+
+	status = http.StatusOK
+	reply = `{"choices":[{"finish_reason":"content_filter","message":{"content":""},"content_filter_results":{"sexual":{"filtered":true,"severity":"medium"}}}]}`
+	require.ErrorAs(t, generate("openai", "gpt-4o"), &filtered)
+	assert.Equal(t, "completion", filtered.Target)
+	assert.Contains(t, filtered.Error(), "sexual medium")
+
+	reply = `{"type":"message","content":[{"type":"text","text":"I can't help with that."}],"stop_reason":"refusal"}`
+	require.ErrorAs(t, generate("anthropic", "claude-sonnet-4-20250514"), &filtered)
+	assert.Equal(t, "refusal", filtered.Reason)
+	assert.Equal(t, "I can't help with that.", filtered.Message)
+
+	filtered = parseContentFilter([]byte(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}}`))
+	require.NotNil(t, filtered)
+	assert.Equal(t, "SAFETY", filtered.Reason)
+	assert.Equal(t, []ContentFilterCategory{{Category: "HARM_CATEGORY_HARASSMENT", Severity: "HIGH", Filtered: true}}, filtered.Categories)
+	assert.Nil(t, parseContentFilter([]byte(`{"choices":[{"finish_reason":"stop","message":{"content":"ok"}}]}`)))
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// contextCacheTarget sends a provider's requests to its context cache
+// endpoint, with the headers of that endpoint.
+type contextCacheTarget struct {
+	providers.Provider
+	cache providers.ContextCacheProvider
+}
+
+func (t contextCacheTarget) Endpoint() string           { return t.cache.ContextCacheEndpoint() }
+func (t contextCacheTarget) Headers() map[string]string { return t.cache.ContextCacheHeaders() }
+
+// CreateCachedContext caches the prompt server-side for ttl, using the
+// context caching API of the LLM's provider, such as Gemini's. It suits
+// large static corpora, such as manuals or codebases, set as the prompt's
+// context or documents and queried many times: requests made with
+// UseCachedContext reference the cache instead of resending it, and its
+// tokens are billed at the provider's cached input price, reported as
+// Usage.CachedTokens. The prompt's system prompt, or the default one, is
+// cached with it. A zero ttl uses the provider's default, one hour for
+// Gemini. Providers require a minimum size, such as 1024 tokens for Gemini
+// Flash models. It returns ErrorTypeUnsupported when the provider has no
+// context caching API.
+//
+// Example:
+//
+//	cached, err := llm.CreateCachedContext(ctx, client, llm.NewPrompt("", llm.WithContext(manual)), time.Hour)
+//	answer, err := client.Generate(ctx, llm.NewPrompt("How do I reset the device?"), llm.UseCachedContext(cached))
+func CreateCachedContext(ctx context.Context, l LLM, prompt *Prompt, ttl time.Duration) (providers.CachedContext, error) {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return providers.CachedContext{}, NewLLMError(ErrorTypeUnsupported, "context caching requires a gollm client", nil)
+	}
+	if _, ok := impl.Provider.(providers.ContextCacheProvider); !ok {
+		return providers.CachedContext{}, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("context caching not supported by provider %s", impl.Provider.Name()), nil)
+	}
+	systemPrompt, _ := impl.requestOptions(prompt, nil)["system_prompt"].(string)
+	content := *prompt
+	content.SystemPrompt, content.SystemCacheType = "", ""
+	text := content.String()
+	if text == "" {
+		return providers.CachedContext{}, NewLLMError(ErrorTypeInvalidInput, "empty content to cache", nil)
+	}
+
+	var cached providers.CachedContext
+	_, err := impl.withRetries(ctx, "failed to create cached context", func(ctx context.Context, attempt int) (string, error) {
+		provider, release, err := impl.acquireProvider(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		cache, ok := provider.(providers.ContextCacheProvider)
+		if !ok {
+			return "", NewLLMError(ErrorTypeUnsupported, "context caching not supported by provider "+provider.Name(), nil)
+		}
+		reqBody, err := cache.PrepareContextCacheRequest(text, systemPrompt, ttl)
+		if err != nil {
+			return "", NewLLMError(ErrorTypeRequest, "failed to prepare context cache request", err)
+		}
+		body, err := impl.sendRequest(ctx, contextCacheTarget{provider, cache}, reqBody)
+		if err != nil {
+			return "", err
+		}
+		if cached, err = cache.ParseContextCacheResponse(body); err != nil {
+			return "", NewLLMError(ErrorTypeResponse, "failed to parse context cache response", err)
+		}
+		return "", nil
+	})
+	if err != nil {
+		return providers.CachedContext{}, err
+	}
+	return cached, nil
+}
+
+// UseCachedContext references content cached with CreateCachedContext for a
+// single Generate call. The cache holds the system prompt, so the call's
+// own system prompt is not sent.
+func UseCachedContext(cached providers.CachedContext) GenerateOption {
+	return WithOption("cached_content", cached.Name)
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetEnableCaching(true))
+	anthropic := providers.NewAnthropicProvider("key", "claude-3-5-sonnet-latest", nil)
+	anthropic.SetDefaultOptions(cfg)
+	l := &LLMImpl{Options: map[string]interface{}{}}
+
+	prompt := &Prompt{
+		Input:        "What changed in version 2?",
+		SystemPrompt: "You answer questions about the manual.",
+		Context:      "The manual text.",
+		Messages: []PromptMessage{
+			{Role: "user", Content: "Hello", CacheType: CacheTypeEphemeral},
+			{Role: "assistant", Content: "Hi"},
+			{Role: "user", Content: "What is version 1?", CacheType: CacheTypeEphemeral},
+			{Role: "assistant", Content: "The first release."},
+		},
+	}
+	options := l.requestOptions(prompt, nil)
+	body, err := anthropic.PrepareRequest(promptText(anthropic, prompt, options), options)
+	require.NoError(t, err)
+
+	type block struct {
+		Text         string      `json:"text"`
+		CacheControl interface{} `json:"cache_control"`
+	}
+	var request struct {
+		System   []block `json:"system"`
+		Messages []struct {
+			Role    string  `json:"role"`
+			Content []block `json:"content"`
+		} `json:"messages"`
+		CachePrefix interface{} `json:"cache_prefix"`
+	}
+	require.NoError(t, json.Unmarshal(body, &request))
+	assert.Nil(t, request.CachePrefix)
+	require.Len(t, request.System, 1)
+	assert.NotNil(t, request.System[0].CacheControl, "the system prompt is cached")
+
+	require.Len(t, request.Messages, 5)
+	assert.Nil(t, request.Messages[0].Content[0].CacheControl, "the earliest breakpoint is dropped beyond four")
+	assert.NotNil(t, request.Messages[2].Content[0].CacheControl, "explicit breakpoints are kept")
+	assert.NotNil(t, request.Messages[3].Content[0].CacheControl, "the history is cached before the new turn")
+	last := request.Messages[4].Content
+	require.Len(t, last, 2)
+	assert.Contains(t, last[0].Text, "The manual text.")
+	assert.Equal(t, "What changed in version 2?", last[1].Text)
+	assert.NotNil(t, last[0].CacheControl, "the context is cached apart from the input")
+	assert.Nil(t, last[1].CacheControl)
+
+	var breakpoints int
+	for _, b := range request.System {
+		if b.CacheControl != nil {
+			breakpoints++
+		}
+	}
+	for _, m := range request.Messages {
+		for _, b := range m.Content {
+			if b.CacheControl != nil {
+				breakpoints++
+			}
+		}
+	}
+	assert.Equal(t, 4, breakpoints)
+}
+
+func TestGeminiCachedContext(t *testing.T) {
+	var cacheRequest, chatRequest map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cachedContents":
+			assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&cacheRequest))
+			_, _ = io.WriteString(w, `{"name": "cachedContents/abc", "model": "models/gemini-2.5-flash",
+				"expireTime": "2025-01-01T01:00:00Z", "usageMetadata": {"totalTokenCount": 4096}}`)
+		case "/openai/chat/completions":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&chatRequest))
+			_, _ = io.WriteString(w, `{"choices": [{"message": {"content": "Hold the button."}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("gemini"),
+		config.SetModel("gemini-2.5-flash"),
+		config.SetAPIKey("test-key"),
+		config.SetMaxRetries(0),
+	)
+	cfg.Endpoints = map[string]string{"gemini": server.URL}
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cached, err := CreateCachedContext(ctx, l, NewPrompt("", WithContext("The manual."), WithSystemPrompt("Answer from the manual.", "")), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "cachedContents/abc", cached.Name)
+	assert.Equal(t, "gemini-2.5-flash", cached.Model)
+	assert.Equal(t, 4096, cached.Tokens)
+	assert.Equal(t, "models/gemini-2.5-flash", cacheRequest["model"])
+	assert.Equal(t, "3600s", cacheRequest["ttl"])
+	assert.Contains(t, fmt.Sprint(cacheRequest["contents"]), "The manual.")
+	assert.Contains(t, fmt.Sprint(cacheRequest["systemInstruction"]), "Answer from the manual.")
+
+	l.SetOption("system_prompt", "Be brief.")
+	answer, err := l.Generate(ctx, NewPrompt("How do I reset it?"), UseCachedContext(cached))
+	require.NoError(t, err)
+	assert.Equal(t, "Hold the button.", answer)
+	assert.Equal(t, map[string]interface{}{"google": map[string]interface{}{"cached_content": "cachedContents/abc"}}, chatRequest["extra_body"])
+	assert.NotContains(t, fmt.Sprint(chatRequest["messages"]), "Be brief.", "the cache holds the system prompt")
+	assert.NotContains(t, chatRequest, "cached_content")
+}
@@ -0,0 +1,211 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// ModelLimits holds the token limits of a model.
+type ModelLimits struct {
+	// ContextWindow is the number of tokens of the prompt and the reply
+	// together.
+	ContextWindow int `json:"context_window"`
+	// MaxOutputTokens is the number of tokens the model generates at most.
+	// Zero means the reply is only bounded by the context window.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+}
+
+var (
+	limitsMu sync.RWMutex
+	// limits is keyed by model name prefix, like pricing.
+	limits = map[string]ModelLimits{
+		"gpt-4o":                  {ContextWindow: 128000, MaxOutputTokens: 16384},
+		"gpt-4o-mini":             {ContextWindow: 128000, MaxOutputTokens: 16384},
+		"gpt-4.1":                 {ContextWindow: 1047576, MaxOutputTokens: 32768},
+		"gpt-4-turbo":             {ContextWindow: 128000, MaxOutputTokens: 4096},
+		"gpt-3.5-turbo":           {ContextWindow: 16385, MaxOutputTokens: 4096},
+		"o1":                      {ContextWindow: 200000, MaxOutputTokens: 100000},
+		"o1-mini":                 {ContextWindow: 128000, MaxOutputTokens: 65536},
+		"o3-mini":                 {ContextWindow: 200000, MaxOutputTokens: 100000},
+		"o4-mini":                 {ContextWindow: 200000, MaxOutputTokens: 100000},
+		"claude-3-5-sonnet":       {ContextWindow: 200000, MaxOutputTokens: 8192},
+		"claude-3-7-sonnet":       {ContextWindow: 200000, MaxOutputTokens: 64000},
+		"claude-sonnet-4":         {ContextWindow: 200000, MaxOutputTokens: 64000},
+		"claude-3-5-haiku":        {ContextWindow: 200000, MaxOutputTokens: 8192},
+		"claude-3-haiku":          {ContextWindow: 200000, MaxOutputTokens: 4096},
+		"claude-3-opus":           {ContextWindow: 200000, MaxOutputTokens: 4096},
+		"claude-opus-4":           {ContextWindow: 200000, MaxOutputTokens: 32000},
+		"llama-3.1-8b-instant":    {ContextWindow: 131072, MaxOutputTokens: 131072},
+		"llama-3.3-70b-versatile": {ContextWindow: 131072, MaxOutputTokens: 32768},
+		"mistral-large":           {ContextWindow: 131072},
+		"mistral-small":           {ContextWindow: 131072},
+		"open-mistral-nemo":       {ContextWindow: 131072},
+		"command-r-plus":          {ContextWindow: 128000, MaxOutputTokens: 4000},
+		"command-r":               {ContextWindow: 128000, MaxOutputTokens: 4000},
+		"command-a":               {ContextWindow: 256000, MaxOutputTokens: 8000},
+		"gemini-2.5-pro":          {ContextWindow: 1048576, MaxOutputTokens: 65536},
+		"gemini-2.5-flash":        {ContextWindow: 1048576, MaxOutputTokens: 65536},
+		"gemini-2.0-flash":        {ContextWindow: 1048576, MaxOutputTokens: 8192},
+		"gemini-1.5-pro":          {ContextWindow: 2097152, MaxOutputTokens: 8192},
+		"gemini-1.5-flash":        {ContextWindow: 1048576, MaxOutputTokens: 8192},
+		"deepseek-chat":           {ContextWindow: 64000, MaxOutputTokens: 8192},
+		"deepseek-reasoner":       {ContextWindow: 64000, MaxOutputTokens: 8192},
+	}
+)
+
+// RegisterModelLimits sets the token limits of a model, or of every model
+// whose name starts with model, such as a local or fine-tuned model. It
+// overrides the built-in limits, if any.
+func RegisterModelLimits(model string, l ModelLimits) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	limits[model] = l
+}
+
+// LookupModelLimits returns the token limits of a model, matching the
+// longest registered name prefix.
+func LookupModelLimits(model string) (ModelLimits, bool) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+
+	var best string
+	for name := range limits {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelLimits{}, false
+	}
+	return limits[best], true
+}
+
+// autoMaxTokens is the max_tokens sizing requested for a call.
+type autoMaxTokens struct {
+	margin int
+}
+
+// WithAutoMaxTokens sets max_tokens to the room left in the model's context
+// window once the prompt is in, minus a safety margin of margin tokens, and
+// capped at the model's maximum output. Long prompts then get a reply that
+// fits instead of a "context length exceeded" error, and short ones are not
+// cut short by a low default. A margin of zero keeps 5% of the context
+// window, to absorb the error of EstimateTokens. The limits come from
+// LookupModelLimits; for models without known limits, max_tokens is left
+// as configured. A prompt that leaves no room fails with
+// ErrorTypeInvalidInput before it is sent.
+//
+// Example:
+//
+//	summary, err := client.Generate(ctx, llm.NewPrompt(longReport), llm.WithAutoMaxTokens(0))
+func WithAutoMaxTokens(margin int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.autoMaxTokens = &autoMaxTokens{margin: margin}
+	}
+}
+
+// apply sets the max_tokens option of a request to model whose prompt text
+// is text.
+func (a *autoMaxTokens) apply(model, text string, options map[string]interface{}) error {
+	limits, ok := LookupModelLimits(model)
+	if !ok || limits.ContextWindow <= 0 {
+		return nil
+	}
+	margin := a.margin
+	if margin <= 0 {
+		margin = limits.ContextWindow / 20
+	}
+	promptTokens := estimateRequestTokens(text, options)
+	room := limits.ContextWindow - promptTokens - margin
+	if room <= 0 {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("prompt of about %d tokens leaves no room for a reply in the %d-token context window of %s", promptTokens, limits.ContextWindow, model), nil)
+	}
+	if limits.MaxOutputTokens > 0 && room > limits.MaxOutputTokens {
+		room = limits.MaxOutputTokens
+	}
+	options["max_tokens"] = room
+	return nil
+}
+
+// estimateRequestTokens estimates the input tokens of a request: the prompt
+// text, the system prompt, the conversation history and the tool
+// definitions.
+func estimateRequestTokens(text string, options map[string]interface{}) int {
+	tokens := EstimateTokens(text)
+	if system, ok := options["system_prompt"].(string); ok {
+		tokens += EstimateTokens(system)
+	}
+	if messages, ok := options["messages"].([]providers.Message); ok {
+		for _, m := range messages {
+			tokens += EstimateTokens(m.Content)
+		}
+	}
+	if tools, ok := options["tools"]; ok {
+		tokens += EstimateTokens(renderJSON(tools))
+	}
+	return tokens
+}
+
+// ModelSubstitution records that a request was sent to another model than
+// the configured one. Generate stores it in the Response metadata under the
+// "model_substitution" key.
+type ModelSubstitution struct {
+	// From is the configured model and To the model that served the request.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Reason is why the model was substituted, such as "context_overflow".
+	Reason string `json:"reason"`
+	// PromptTokens is the estimated size of the request, in tokens.
+	PromptTokens int `json:"prompt_tokens"`
+}
+
+// WithContextFallback sends the Generate call to model, a model of the same
+// provider with a longer context window, when the prompt and the requested
+// max_tokens would not fit in the configured model's context window. It
+// overrides the fallback set with config.SetContextFallbackModel. The check
+// uses LookupModelLimits and EstimateTokens before the request is sent;
+// models without known limits are never substituted. The Response passed to
+// WithResponse names the model that served the request, and holds a
+// ModelSubstitution under the "model_substitution" metadata key.
+//
+// Example:
+//
+//	var resp llm.Response
+//	answer, err := client.Generate(ctx, prompt, llm.WithContextFallback("gpt-4.1"), llm.WithResponse(&resp))
+func WithContextFallback(model string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.contextFallback = model
+	}
+}
+
+// substituteModel returns the substitution of model by the context fallback
+// of the call, when the request whose prompt text is text overflows the
+// context window of model, and sets the model option of the request to the
+// fallback. It returns nil when the request fits or there is no fallback.
+func (l *LLMImpl) substituteModel(cfg *GenerateConfig, model, text string, options map[string]interface{}) *ModelSubstitution {
+	fallback := ""
+	if l.config != nil {
+		fallback = l.config.ContextFallbackModel
+	}
+	if cfg != nil && cfg.contextFallback != "" {
+		fallback = cfg.contextFallback
+	}
+	if fallback == "" || fallback == model {
+		return nil
+	}
+	limits, ok := LookupModelLimits(model)
+	if !ok || limits.ContextWindow <= 0 {
+		return nil
+	}
+	tokens := estimateRequestTokens(text, options)
+	reserved, _ := options["max_tokens"].(int)
+	if tokens+reserved <= limits.ContextWindow {
+		return nil
+	}
+	l.logger.Warn("Prompt overflows the context window, switching model", "model", model, "fallback", fallback, "tokens", tokens, "context_window", limits.ContextWindow)
+	options["model"] = fallback
+	return &ModelSubstitution{From: model, To: fallback, Reason: "context_overflow", PromptTokens: tokens}
+}
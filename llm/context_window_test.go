@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestAutoMaxTokens(t *testing.T) {
+	RegisterModelLimits("test-small-model", ModelLimits{ContextWindow: 1000, MaxOutputTokens: 600})
+	server := newEchoServer(t)
+	ctx := context.Background()
+	maxTokens := func(l *LLMImpl, input string, opts ...GenerateOption) (float64, error) {
+		response, err := l.Generate(ctx, &Prompt{Input: input}, append(opts, WithAutoMaxTokens(100))...)
+		if err != nil {
+			return 0, err
+		}
+		var request map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(response), &request))
+		value, _ := request["max_tokens"].(float64)
+		return value, nil
+	}
+
+	l := newTestLLM(t, server.URL, config.SetModel("test-small-model"))
+	tokens, err := maxTokens(l, strings.Repeat("word ", 80))
+	require.NoError(t, err)
+	assert.Equal(t, 600.0, tokens, "short prompts get the model's maximum output")
+
+	tokens, err = maxTokens(l, strings.Repeat("word ", 560))
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, tokens, "long prompts get the room left in the context window")
+
+	_, err = maxTokens(l, strings.Repeat("word ", 800))
+	require.Error(t, err)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+
+	unknown := newTestLLM(t, server.URL, config.SetModel("unknown-model"))
+	tokens, err = maxTokens(unknown, "Hello", WithOption("max_tokens", 42))
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, tokens, "models without known limits keep the configured value")
+}
+
+func TestContextFallback(t *testing.T) {
+	RegisterModelLimits("short-context-model", ModelLimits{ContextWindow: 50})
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL, config.SetModel("short-context-model"), config.SetContextFallbackModel("long-context-model"))
+	ctx := context.Background()
+
+	var resp Response
+	_, err := l.Generate(ctx, &Prompt{Input: "Hello"}, WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, "short-context-model", resp.Model)
+	assert.NotContains(t, resp.Content, "long-context-model")
+	assert.Nil(t, resp.Metadata["model_substitution"])
+
+	long := &Prompt{Input: strings.Repeat("lorem ipsum dolor sit amet ", 40)}
+	_, err = l.Generate(ctx, long, WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, "long-context-model", resp.Model)
+	assert.Contains(t, resp.Content, `"model":"long-context-model"`)
+	substitution, ok := resp.Metadata["model_substitution"].(ModelSubstitution)
+	require.True(t, ok)
+	assert.Equal(t, "short-context-model", substitution.From)
+	assert.Equal(t, "context_overflow", substitution.Reason)
+	assert.Greater(t, substitution.PromptTokens, 50)
+
+	// The fallback of a call overrides the configured one
+	_, err = l.Generate(ctx, long, WithContextFallback("other-model"), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, "other-model", resp.Model)
+}
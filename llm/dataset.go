@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DatasetFormat is the fine-tuning file format written by a DatasetBuilder.
+type DatasetFormat string
+
+const (
+	// DatasetFormatOpenAI is the chat format of OpenAI fine-tuning, one
+	// {"messages": [...]} object per line, the system prompt included as a
+	// system message.
+	DatasetFormatOpenAI DatasetFormat = "openai"
+	// DatasetFormatAnthropic is the format of Claude fine-tuning, one
+	// {"system": ..., "messages": [...]} object per line, with messages
+	// alternating between user and assistant.
+	DatasetFormatAnthropic DatasetFormat = "anthropic"
+)
+
+// DatasetExample is a conversation to train on, such as a transcript kept by
+// a Memory.
+type DatasetExample struct {
+	// System is the system prompt of the conversation, if any. System
+	// messages of Messages are added to it.
+	System string `json:"system,omitempty"`
+	// Messages is the conversation, which must end with an assistant reply:
+	// the reply the model learns to give.
+	Messages []MemoryMessage `json:"messages"`
+	// Score rates the conversation, such as a judge's or an assessment's
+	// grade, for DatasetBuilder.MinScore. Unrated conversations score zero.
+	Score float64 `json:"score,omitempty"`
+}
+
+// DatasetStats reports what a DatasetBuilder wrote and left out.
+type DatasetStats struct {
+	Written    int `json:"written"`    // Examples written
+	LowScore   int `json:"low_score"`  // Examples scoring below MinScore
+	Duplicates int `json:"duplicates"` // Examples identical to a written one
+	Invalid    int `json:"invalid"`    // Examples without a final assistant reply
+}
+
+// DatasetBuilder collects conversations and writes them as a fine-tuning
+// dataset in JSON Lines. Set its fields before adding examples; it is safe
+// for concurrent use afterwards.
+//
+// Example:
+//
+//	builder := &llm.DatasetBuilder{Format: llm.DatasetFormatOpenAI, MinScore: 0.8, Deduplicate: true, Scrub: llm.ScrubPII}
+//	builder.AddMemory(chat.GetMemory(), "You are a support agent.", 0.9)
+//	stats, err := builder.WriteJSONL(file)
+type DatasetBuilder struct {
+	// Format is the file format; it defaults to DatasetFormatOpenAI.
+	Format DatasetFormat
+	// MinScore leaves out the examples scoring less.
+	MinScore float64
+	// Deduplicate leaves out the examples identical to an earlier one, once
+	// scrubbed.
+	Deduplicate bool
+	// Scrub, if set, rewrites the text of every message and system prompt,
+	// such as ScrubPII to mask personal data.
+	Scrub func(string) string
+
+	mu       sync.Mutex
+	examples []DatasetExample
+}
+
+// Add adds conversations to the dataset.
+func (b *DatasetBuilder) Add(examples ...DatasetExample) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.examples = append(b.examples, examples...)
+}
+
+// AddMemory adds a transcript, such as the one returned by
+// LLMWithMemory.GetMemory, with the system prompt it was held with and its
+// score.
+func (b *DatasetBuilder) AddMemory(messages []MemoryMessage, system string, score float64) {
+	b.Add(DatasetExample{System: system, Messages: append([]MemoryMessage(nil), messages...), Score: score})
+}
+
+// Len returns the number of conversations added, before filtering.
+func (b *DatasetBuilder) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.examples)
+}
+
+// datasetMessage is a message of a dataset line.
+type datasetMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// datasetLine is a line of a dataset file.
+type datasetLine struct {
+	System   string           `json:"system,omitempty"`
+	Messages []datasetMessage `json:"messages"`
+}
+
+// WriteJSONL writes the added conversations that pass the filters to w, one
+// per line, in the order they were added.
+func (b *DatasetBuilder) WriteJSONL(w io.Writer) (DatasetStats, error) {
+	b.mu.Lock()
+	examples := append([]DatasetExample(nil), b.examples...)
+	b.mu.Unlock()
+
+	var stats DatasetStats
+	seen := make(map[[sha256.Size]byte]bool)
+	for _, example := range examples {
+		if example.Score < b.MinScore {
+			stats.LowScore++
+			continue
+		}
+		line, ok := b.line(example)
+		if !ok {
+			stats.Invalid++
+			continue
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			return stats, NewLLMError(ErrorTypeResponse, "failed to encode dataset example", err)
+		}
+		if b.Deduplicate {
+			sum := sha256.Sum256(data)
+			if seen[sum] {
+				stats.Duplicates++
+				continue
+			}
+			seen[sum] = true
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return stats, fmt.Errorf("failed to write dataset: %w", err)
+		}
+		stats.Written++
+	}
+	return stats, nil
+}
+
+// line converts example to the dataset format. It returns false when the
+// example has no user message or does not end with an assistant reply.
+func (b *DatasetBuilder) line(example DatasetExample) (datasetLine, bool) {
+	scrub := b.Scrub
+	if scrub == nil {
+		scrub = func(s string) string { return s }
+	}
+	var systems []string
+	if example.System != "" {
+		systems = append(systems, scrub(example.System))
+	}
+	var messages []datasetMessage
+	hasUser := false
+	for _, m := range example.Messages {
+		content := scrub(m.Content)
+		switch m.Role {
+		case "system":
+			systems = append(systems, content)
+			continue
+		case "user":
+			hasUser = true
+		case "assistant":
+		default:
+			return datasetLine{}, false
+		}
+		if b.Format == DatasetFormatAnthropic && len(messages) > 0 && messages[len(messages)-1].Role == m.Role {
+			// Claude fine-tuning requires alternating roles
+			messages[len(messages)-1].Content += "\n\n" + content
+			continue
+		}
+		messages = append(messages, datasetMessage{Role: m.Role, Content: content})
+	}
+	if !hasUser || messages[len(messages)-1].Role != "assistant" {
+		return datasetLine{}, false
+	}
+
+	system := strings.Join(systems, "\n\n")
+	if b.Format == DatasetFormatAnthropic {
+		if messages[0].Role != "user" {
+			return datasetLine{}, false
+		}
+		return datasetLine{System: system, Messages: messages}, true
+	}
+	if system != "" {
+		messages = append([]datasetMessage{{Role: "system", Content: system}}, messages...)
+	}
+	return datasetLine{Messages: messages}, true
+}
+
+// piiPatterns are the personal data ScrubPII masks, in the order they are
+// applied: card numbers before the phone numbers their digits would match.
+var piiPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[CARD]"},
+	{regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), "[SSN]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "[IP]"},
+	{regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`), "[PHONE]"},
+}
+
+// ScrubPII masks the email addresses, payment card numbers, US social
+// security numbers, IPv4 addresses and phone numbers in text, such as
+// "[EMAIL]" for an email address. It is a best effort that catches common
+// formats, not names or street addresses; review datasets before sharing
+// them.
+func ScrubPII(text string) string {
+	for _, p := range piiPatterns {
+		text = p.pattern.ReplaceAllString(text, p.replacement)
+	}
+	return text
+}
@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasetBuilder(t *testing.T) {
+	assert.Equal(t, "Mail [EMAIL] or call [PHONE], card [CARD] from [IP]",
+		ScrubPII("Mail jane.doe@example.com or call (555) 123-4567, card 4111 1111 1111 1111 from 192.168.1.10"))
+
+	transcript := []MemoryMessage{
+		{Role: "user", Content: "My email is bob@example.com"},
+		{Role: "assistant", Content: "Thanks, noted."},
+	}
+	builder := &DatasetBuilder{MinScore: 0.5, Deduplicate: true, Scrub: ScrubPII}
+	builder.AddMemory(transcript, "You are a support agent.", 0.9)
+	builder.AddMemory(transcript, "You are a support agent.", 0.8)
+	builder.Add(
+		DatasetExample{Messages: transcript, Score: 0.2},
+		DatasetExample{Messages: transcript[:1], Score: 1},
+	)
+	assert.Equal(t, 4, builder.Len())
+
+	var out strings.Builder
+	stats, err := builder.WriteJSONL(&out)
+	require.NoError(t, err)
+	assert.Equal(t, DatasetStats{Written: 1, LowScore: 1, Duplicates: 1, Invalid: 1}, stats)
+	assert.Equal(t, `{"messages":[{"role":"system","content":"You are a support agent."},{"role":"user","content":"My email is [EMAIL]"},{"role":"assistant","content":"Thanks, noted."}]}`+"\n", out.String())
+
+	anthropic := &DatasetBuilder{Format: DatasetFormatAnthropic}
+	anthropic.Add(DatasetExample{Messages: []MemoryMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "user", Content: "Anyone there?"},
+		{Role: "assistant", Content: "Yes."},
+	}})
+	out.Reset()
+	stats, err = anthropic.WriteJSONL(&out)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Written)
+	assert.Equal(t, `{"system":"Be brief.","messages":[{"role":"user","content":"Hi\n\nAnyone there?"},{"role":"assistant","content":"Yes."}]}`+"\n", out.String())
+}
@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// DedupMethod is how FindDuplicates compared texts.
+type DedupMethod string
+
+const (
+	// DedupEmbedding compares texts by the cosine similarity of their
+	// embeddings, which catches paraphrases.
+	DedupEmbedding DedupMethod = "embedding"
+	// DedupMinHash compares texts by the estimated Jaccard similarity of
+	// their word shingles, which only catches near-identical wording.
+	DedupMinHash DedupMethod = "minhash"
+)
+
+// DuplicateGroup is a set of texts that are near-duplicates of the first.
+type DuplicateGroup struct {
+	// Indexes are the positions of the texts in the corpus, in order; the
+	// first is the text the group keeps.
+	Indexes []int `json:"indexes"`
+	// Similarities holds the similarity of each text to the first, which
+	// is 1 for the first.
+	Similarities []float64 `json:"similarities"`
+}
+
+// DedupResult is the near-duplicates found in a corpus.
+type DedupResult struct {
+	// Method is how the texts were compared.
+	Method DedupMethod `json:"method"`
+	// Groups holds the groups of two or more near-duplicates.
+	Groups []DuplicateGroup `json:"groups"`
+	// Unique holds the indexes of the texts to keep: every text that is not
+	// a near-duplicate of an earlier one, in order.
+	Unique []int `json:"unique"`
+	// Duplicates holds the indexes of the texts to drop, in order.
+	Duplicates []int `json:"duplicates"`
+}
+
+// DedupOption configures FindDuplicates.
+type DedupOption func(*dedupConfig)
+
+type dedupConfig struct {
+	threshold        float64
+	minHashThreshold float64
+	minHash          *utils.MinHash
+}
+
+// WithDedupThreshold sets the cosine similarity of embeddings from which
+// texts are near-duplicates, 0.95 by default.
+func WithDedupThreshold(threshold float64) DedupOption {
+	return func(c *dedupConfig) {
+		c.threshold = threshold
+	}
+}
+
+// WithMinHashThreshold sets the estimated Jaccard similarity from which
+// texts are near-duplicates when compared with MinHash, 0.8 by default.
+func WithMinHashThreshold(threshold float64) DedupOption {
+	return func(c *dedupConfig) {
+		c.minHashThreshold = threshold
+	}
+}
+
+// WithMinHash sets the shingle size in words and the number of hash
+// functions of the MinHash comparison, 3 and 128 by default.
+func WithMinHash(shingleSize, numHashes int) DedupOption {
+	return func(c *dedupConfig) {
+		c.minHash = utils.NewMinHash(shingleSize, numHashes)
+	}
+}
+
+// FindDuplicates groups the near-duplicates of texts, such as a few-shot
+// example pool or documents before RAG ingestion. It compares the
+// embeddings of the texts computed with embedder, and falls back to MinHash
+// when embedder is nil or its provider has no embeddings endpoint. Each text
+// joins the group of the first kept text it is similar enough to; the
+// comparison is quadratic in the number of texts.
+//
+// Example:
+//
+//	result, err := llm.FindDuplicates(ctx, embeddingClient, examples, llm.WithDedupThreshold(0.92))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, i := range result.Unique {
+//	    selector.Add(examples[i])
+//	}
+func FindDuplicates(ctx context.Context, embedder LLM, texts []string, opts ...DedupOption) (*DedupResult, error) {
+	cfg := &dedupConfig{threshold: 0.95, minHashThreshold: 0.8}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(texts) == 0 {
+		return &DedupResult{Method: DedupEmbedding}, nil
+	}
+
+	var similarity func(i, j int) float64
+	threshold := cfg.threshold
+	method := DedupEmbedding
+	var vectors [][]float64
+	if embedder != nil {
+		var err error
+		vectors, err = Embed(ctx, embedder, texts)
+		var llmErr *LLMError
+		if err != nil && !(errors.As(err, &llmErr) && llmErr.Type == ErrorTypeUnsupported) {
+			return nil, NewLLMError(ErrorTypeRequest, "failed to embed texts", err)
+		}
+	}
+	if vectors != nil {
+		similarity = func(i, j int) float64 { return cosineSimilarity(vectors[i], vectors[j]) }
+	} else {
+		minHash := cfg.minHash
+		if minHash == nil {
+			minHash = utils.NewMinHash(0, 0)
+		}
+		signatures := make([][]uint64, len(texts))
+		for i, text := range texts {
+			signatures[i] = minHash.Signature(text)
+		}
+		similarity = func(i, j int) float64 { return minHash.Similarity(signatures[i], signatures[j]) }
+		threshold = cfg.minHashThreshold
+		method = DedupMinHash
+	}
+
+	result := &DedupResult{Method: method}
+	duplicate := make([]bool, len(texts))
+	for i := range texts {
+		if duplicate[i] {
+			continue
+		}
+		result.Unique = append(result.Unique, i)
+		current := DuplicateGroup{Indexes: []int{i}, Similarities: []float64{1}}
+		for j := i + 1; j < len(texts); j++ {
+			if duplicate[j] {
+				continue
+			}
+			if s := similarity(i, j); s >= threshold {
+				current.Indexes = append(current.Indexes, j)
+				current.Similarities = append(current.Similarities, s)
+				duplicate[j] = true
+			}
+		}
+		if len(current.Indexes) > 1 {
+			result.Groups = append(result.Groups, current)
+		}
+	}
+	for i := range texts {
+		if duplicate[i] {
+			result.Duplicates = append(result.Duplicates, i)
+		}
+	}
+	return result, nil
+}
+
+// Deduplicate returns texts without their near-duplicates, keeping the
+// first text of each group, as found by FindDuplicates.
+func Deduplicate(ctx context.Context, embedder LLM, texts []string, opts ...DedupOption) ([]string, error) {
+	result, err := FindDuplicates(ctx, embedder, texts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	unique := make([]string, len(result.Unique))
+	for i, index := range result.Unique {
+		unique[i] = texts[index]
+	}
+	return unique, nil
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	texts := []string{
+		"Translate the sentence into French: the weather is nice today.",
+		"Summarize this email from the sales team.",
+		"Translate the sentence into French: the weather is nice today!",
+		"Translate this sentence to French: it is sunny today.",
+	}
+
+	result, err := FindDuplicates(context.Background(), nil, texts)
+	require.NoError(t, err)
+	assert.Equal(t, DedupMinHash, result.Method)
+	assert.Equal(t, []int{0, 1, 3}, result.Unique, "MinHash only catches the same wording")
+	assert.Equal(t, []int{2}, result.Duplicates)
+	require.Len(t, result.Groups, 1)
+	assert.Equal(t, []int{0, 2}, result.Groups[0].Indexes)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		// Embeds texts on two axes: French and email
+		var data []string
+		for i, input := range request["input"].([]interface{}) {
+			vector := []float64{0.1, 0.1}
+			if strings.Contains(input.(string), "French") {
+				vector[0] = 1
+			}
+			if strings.Contains(input.(string), "email") {
+				vector[1] = 1
+			}
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%g,%g]}`, i, vector[0], vector[1]))
+		}
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+	}))
+	defer server.Close()
+	registry := providers.NewProviderRegistry()
+	registry.Register("mistral", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		p := providers.NewMistralProvider(apiKey, model, extraHeaders)
+		return &mistralTestProvider{p.(providers.EmbeddingProvider), p, server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("mistral"), config.SetModel("mistral-small-latest"), config.SetAPIKey("test-key"))
+	embedder, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	unique, err := Deduplicate(context.Background(), embedder, texts)
+	require.NoError(t, err)
+	assert.Equal(t, texts[:2], unique, "embeddings also catch paraphrases")
+
+	// Providers without embeddings fall back to MinHash
+	result, err = FindDuplicates(context.Background(), newTestLLM(t, "", config.SetProvider("anthropic"), config.SetAPIKey("key")), texts)
+	require.NoError(t, err)
+	assert.Equal(t, DedupMinHash, result.Method)
+}
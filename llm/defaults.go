@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Struct fields with a default tag are optional in structured responses:
+// the derived schemas do not require them, and when the model leaves one out
+// it is set to the default after decoding, before validation. The tag holds
+// the value as JSON, except for strings, which are written as is.
+//
+//	type Invoice struct {
+//	    Number   string   `json:"number" validate:"required"`
+//	    Currency string   `json:"currency" default:"USD"`
+//	    Tags     []string `json:"tags" default:"[]"`
+//	    Paid     bool     `json:"paid" default:"false"`
+//	}
+
+// WithDefaultedFields receives, after a structured response is decoded, the
+// paths of the fields that the model left out and that were set to their
+// default, such as "currency" or "lines[2].unit". Extraction pipelines can
+// use it to flag partial results instead of failing validation outright.
+//
+// Example:
+//
+//	var invoice Invoice
+//	var defaulted []string
+//	_, err := client.Generate(ctx, prompt,
+//	    llm.WithStructuredResponseFormat(llm.ResponseFormatJSON, &invoice),
+//	    llm.WithDefaultedFields(&defaulted))
+func WithDefaultedFields(fields *[]string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.defaulted = fields
+	}
+}
+
+// UnmarshalWithDefaults decodes JSON data into v, a pointer to a struct, like
+// json.Unmarshal, then sets the fields with a default tag that data leaves
+// out or sets to null to their default. It returns the paths of the
+// defaulted fields.
+func UnmarshalWithDefaults(data []byte, v interface{}) ([]string, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return applyDefaults(v, document, ResponseFormatJSON)
+}
+
+// applyDefaults sets the missing fields of target, a pointer, to their
+// default. document is the response decoded into generic maps and slices;
+// for XML, which has no such form, fields are missing when they are zero.
+func applyDefaults(target interface{}, document interface{}, format ResponseFormat) ([]string, error) {
+	value := reflect.ValueOf(target)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	d := &defaulter{format: format}
+	if err := d.apply(value, document, ""); err != nil {
+		return nil, err
+	}
+	return d.defaulted, nil
+}
+
+type defaulter struct {
+	format    ResponseFormat
+	defaulted []string
+}
+
+// key returns the name of field in the response document, or "" when it is
+// not encoded.
+func (d *defaulter) key(field reflect.StructField) string {
+	tag := field.Tag.Get(string(d.format))
+	name, _, _ := strings.Cut(tag, ",")
+	switch {
+	case name == "-" || !field.IsExported() || field.Type == xmlNameType:
+		return ""
+	case d.format == ResponseFormatXML:
+		// Use the innermost element of an "a>b" path
+		name = name[strings.LastIndex(name, ">")+1:]
+	case d.format == ResponseFormatYAML && name == "":
+		return strings.ToLower(field.Name)
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// apply sets the missing fields of v, found at path in the response, and
+// descends into its nested structs.
+func (d *defaulter) apply(v reflect.Value, document interface{}, path string) error {
+	if v.Kind() != reflect.Struct || v.Type() == timeType {
+		return nil
+	}
+	object, _ := document.(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get(string(d.format)) == "" {
+			// Embedded struct fields are encoded inline
+			if err := d.apply(value, document, path); err != nil {
+				return err
+			}
+			continue
+		}
+		key := d.key(field)
+		if key == "" {
+			continue
+		}
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+
+		raw, present := object[key]
+		if d.format == ResponseFormatXML {
+			present = !value.IsZero()
+		}
+		if def, ok := field.Tag.Lookup("default"); ok && (!present || raw == nil && d.format != ResponseFormatXML) {
+			if err := setDefault(value, def); err != nil {
+				return fmt.Errorf("invalid default for field %s: %w", fieldPath, err)
+			}
+			d.defaulted = append(d.defaulted, fieldPath)
+			continue
+		}
+
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				break
+			}
+			value = value.Elem()
+		}
+		switch value.Kind() {
+		case reflect.Struct:
+			if err := d.apply(value, raw, fieldPath); err != nil {
+				return err
+			}
+		case reflect.Slice, reflect.Array:
+			items, _ := raw.([]interface{})
+			for j := 0; j < value.Len(); j++ {
+				var item interface{}
+				if j < len(items) {
+					item = items[j]
+				}
+				if err := d.apply(reflect.Indirect(value.Index(j)), item, fmt.Sprintf("%s[%d]", fieldPath, j)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setDefault sets v to the default written in a default tag.
+func setDefault(v reflect.Value, def string) error {
+	target := v
+	if target.Kind() == reflect.Ptr {
+		target = reflect.New(v.Type().Elem()).Elem()
+	}
+	if target.Kind() == reflect.String {
+		target.SetString(def)
+	} else if err := json.Unmarshal([]byte(def), target.Addr().Interface()); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Ptr {
+		v.Set(target.Addr())
+	}
+	return nil
+}
+
+// defaultValue returns the default of a field as it appears in a JSON
+// schema, and false when it has none.
+func defaultValue(field reflect.StructField) (interface{}, bool) {
+	def, ok := field.Tag.Lookup("default")
+	if !ok {
+		return nil, false
+	}
+	t := field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String {
+		return def, true
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(def), &value); err != nil {
+		return def, true
+	}
+	return value, true
+}
@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DifficultyClassifier scores how hard prompt is to answer, from 0 for a
+// trivial request to 1 for the hardest.
+type DifficultyClassifier func(ctx context.Context, prompt string) (float64, error)
+
+// scorePattern matches the rating of a ModelDifficultyClassifier reply.
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// ModelDifficultyClassifier asks l, typically a small and cheap model, to
+// rate the difficulty of the prompt from 0 to 10.
+func ModelDifficultyClassifier(l LLM) DifficultyClassifier {
+	return func(ctx context.Context, prompt string) (float64, error) {
+		rating := NewPrompt(
+			"Rate how difficult it is to answer the following request well, from 0 for a trivial lookup or rewrite to 10 for expert multi-step reasoning.\n\nRequest:\n"+prompt,
+			WithDirectives("Reply with the number only"),
+		)
+		reply, err := l.Generate(ctx, rating, WithOption("temperature", 0.0), WithOption("max_tokens", 5))
+		if err != nil {
+			return 0, err
+		}
+		match := scorePattern.FindString(reply)
+		if match == "" {
+			return 0, fmt.Errorf("no difficulty rating in %q", strings.TrimSpace(reply))
+		}
+		score, err := strconv.ParseFloat(match, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid difficulty rating %q: %w", match, err)
+		}
+		return math.Max(0, math.Min(1, score/10)), nil
+	}
+}
+
+// EmbeddingDifficultyClassifier scores a prompt by its k nearest neighbours
+// among labelled example prompts, by the similarity of their embeddings
+// computed with embedder: the score is the similarity-weighted share of
+// hard examples. The embeddings of the examples are computed once, on first
+// use, and cached.
+//
+// Example:
+//
+//	classify := llm.EmbeddingDifficultyClassifier(embeddingClient, easyPrompts, hardPrompts, 5)
+func EmbeddingDifficultyClassifier(embedder LLM, easy, hard []string, k int) DifficultyClassifier {
+	examples := NewExampleSelector(embedder)
+	examples.Add(easy...)
+	examples.Add(hard...)
+	isHard := make(map[string]bool, len(hard))
+	for _, prompt := range hard {
+		isHard[prompt] = true
+	}
+	return func(ctx context.Context, prompt string) (float64, error) {
+		if err := examples.embedExamples(ctx); err != nil {
+			return 0, err
+		}
+		vectors, err := Embed(ctx, embedder, []string{prompt})
+		if err != nil {
+			return 0, fmt.Errorf("error embedding prompt: %w", err)
+		}
+
+		examples.mu.RLock()
+		type neighbour struct {
+			similarity float64
+			hard       bool
+		}
+		neighbours := make([]neighbour, len(examples.examples))
+		for i, embedding := range examples.embeddings {
+			neighbours[i] = neighbour{cosineSimilarity(vectors[0], embedding), isHard[examples.examples[i]]}
+		}
+		examples.mu.RUnlock()
+
+		sort.SliceStable(neighbours, func(i, j int) bool {
+			return neighbours[i].similarity > neighbours[j].similarity
+		})
+		if k > 0 && k < len(neighbours) {
+			neighbours = neighbours[:k]
+		}
+		var hardWeight, total float64
+		for _, n := range neighbours {
+			weight := math.Max(n.similarity, 0)
+			total += weight
+			if n.hard {
+				hardWeight += weight
+			}
+		}
+		if total == 0 {
+			return 0, nil
+		}
+		return hardWeight / total, nil
+	}
+}
+
+// DifficultyRouter routes each Generate call between a cheap and an
+// expensive model by the difficulty score of its prompt. Pass it to
+// Generate with WithDifficultyRouter, and tune Threshold with Evaluate.
+//
+// Example:
+//
+//	router := &llm.DifficultyRouter{
+//	    Classifier: llm.ModelDifficultyClassifier(miniClient),
+//	    Cheap:      "gpt-4o-mini",
+//	    Expensive:  "gpt-4o",
+//	    Threshold:  0.6,
+//	}
+//	answer, err := client.Generate(ctx, prompt, llm.WithDifficultyRouter(router))
+type DifficultyRouter struct {
+	// Classifier scores the difficulty of prompts.
+	Classifier DifficultyClassifier
+	// Cheap serves prompts scoring below Threshold, and Expensive the
+	// others. Expensive also serves prompts the classifier fails on.
+	Cheap     string
+	Expensive string
+	// Threshold is the difficulty score from which prompts go to
+	// Expensive.
+	Threshold float64
+	// OutputTokens is the expected length of replies, used to estimate
+	// costs. Zero estimates the cost of the prompts alone.
+	OutputTokens int
+}
+
+// Route scores prompt and returns the model that serves it. When the
+// classifier fails, the prompt goes to the expensive model and the error
+// is returned with the decision.
+func (r *DifficultyRouter) Route(ctx context.Context, prompt string) (RoutingDecision, error) {
+	decision := RoutingDecision{PromptTokens: EstimateTokens(prompt)}
+	score, err := r.Classifier(ctx, prompt)
+	if err != nil {
+		decision.Model = r.Expensive
+		decision.Reason = "classifier_error"
+	} else {
+		decision.Difficulty = score
+		decision.Model = r.pick(score, r.Threshold)
+		decision.Reason = "classifier"
+	}
+	decision.EstimatedCost = r.cost(decision.Model, decision.PromptTokens)
+	return decision, err
+}
+
+// pick returns the model of a prompt with difficulty score under threshold.
+func (r *DifficultyRouter) pick(score, threshold float64) string {
+	if score >= threshold {
+		return r.Expensive
+	}
+	return r.Cheap
+}
+
+// cost returns the estimated cost of a prompt of promptTokens on model, or
+// zero when the model has no known pricing.
+func (r *DifficultyRouter) cost(model string, promptTokens int) float64 {
+	cost, _ := EstimateCost(model, Usage{PromptTokens: promptTokens, CompletionTokens: r.OutputTokens})
+	return cost
+}
+
+// LabeledPrompt is a prompt labelled with whether it needs the expensive
+// model, to evaluate a DifficultyRouter.
+type LabeledPrompt struct {
+	Prompt string `json:"prompt"`
+	Hard   bool   `json:"hard"`
+}
+
+// RouterReport is how a DifficultyRouter performs at a threshold on a set
+// of labelled prompts.
+type RouterReport struct {
+	// Threshold is the evaluated threshold.
+	Threshold float64 `json:"threshold"`
+	// Accuracy is the share of prompts sent to the model of their label.
+	Accuracy float64 `json:"accuracy"`
+	// ExpensiveShare is the share of prompts sent to the expensive model.
+	ExpensiveShare float64 `json:"expensive_share"`
+	// MissedHard counts the hard prompts sent to the cheap model, and
+	// WastedEasy the easy prompts sent to the expensive one.
+	MissedHard int `json:"missed_hard"`
+	WastedEasy int `json:"wasted_easy"`
+	// Cost is the estimated cost in US dollars of the prompts, and Savings
+	// the share saved compared to sending them all to the expensive model.
+	Cost    float64 `json:"cost"`
+	Savings float64 `json:"savings"`
+}
+
+// Evaluate scores the labelled prompts once and reports the accuracy and
+// cost of routing them at each threshold, to tune Threshold. Without
+// thresholds, it evaluates the router's own. Prompts the classifier fails
+// on count as sent to the expensive model.
+func (r *DifficultyRouter) Evaluate(ctx context.Context, samples []LabeledPrompt, thresholds ...float64) ([]RouterReport, error) {
+	if len(samples) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no labelled prompts to evaluate", nil)
+	}
+	if len(thresholds) == 0 {
+		thresholds = []float64{r.Threshold}
+	}
+	scores := make([]float64, len(samples))
+	var baseline float64
+	for i, sample := range samples {
+		score, err := r.Classifier(ctx, sample.Prompt)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			score = math.Inf(1)
+		}
+		scores[i] = score
+		baseline += r.cost(r.Expensive, EstimateTokens(sample.Prompt))
+	}
+
+	reports := make([]RouterReport, len(thresholds))
+	for t, threshold := range thresholds {
+		report := RouterReport{Threshold: threshold}
+		var correct, expensive int
+		for i, sample := range samples {
+			model := r.pick(scores[i], threshold)
+			sentExpensive := model == r.Expensive
+			switch {
+			case sentExpensive == sample.Hard:
+				correct++
+			case sample.Hard:
+				report.MissedHard++
+			default:
+				report.WastedEasy++
+			}
+			if sentExpensive {
+				expensive++
+			}
+			report.Cost += r.cost(model, EstimateTokens(sample.Prompt))
+		}
+		report.Accuracy = float64(correct) / float64(len(samples))
+		report.ExpensiveShare = float64(expensive) / float64(len(samples))
+		if baseline > 0 {
+			report.Savings = 1 - report.Cost/baseline
+		}
+		reports[t] = report
+	}
+	return reports, nil
+}
+
+// WithDifficultyRouter sends the Generate call to the cheap or the
+// expensive model of router, by the difficulty score of the prompt's
+// input. The decision is logged and stored under the "routing" key of the
+// Response metadata, like that of WithModelRouter, over which it takes
+// precedence. A model set with WithModel takes precedence over both.
+func WithDifficultyRouter(router *DifficultyRouter) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.difficultyRouter = router
+	}
+}
+
+// routeByDifficulty routes the call with its difficulty router before any
+// attempt, so that the prompt is classified once.
+func (l *LLMImpl) routeByDifficulty(ctx context.Context, prompt *Prompt, cfg *GenerateConfig) {
+	if cfg.difficultyRouter == nil {
+		return
+	}
+	if _, ok := cfg.Options["model"]; ok {
+		return
+	}
+	decision, err := cfg.difficultyRouter.Route(ctx, prompt.Input)
+	if err != nil {
+		l.logger.Warn("Difficulty classification failed, routing to the expensive model", "model", decision.Model, "error", err)
+	}
+	cfg.routing = &decision
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestDifficultyRouter(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		models = append(models, request["model"].(string))
+		content := "ok"
+		if strings.Contains(renderJSON(request["messages"]), "Rate how difficult") {
+			content = "Difficulty: 8"
+			if strings.Contains(renderJSON(request["messages"]), "capital") {
+				content = "1"
+			}
+		}
+		fmt.Fprintf(w, `{"choices": [{"message": {"content": %q}}]}`, content)
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, "", config.SetProvider("openai"), config.SetModel("gpt-4o"), config.SetAPIKey("key"), config.SetBaseURL("openai", server.URL))
+	router := &DifficultyRouter{
+		Classifier: ModelDifficultyClassifier(l),
+		Cheap:      "gpt-4o-mini",
+		Expensive:  "gpt-4o",
+		Threshold:  0.5,
+	}
+
+	var resp Response
+	_, err := l.Generate(context.Background(), NewPrompt("What is the capital of France?"), WithDifficultyRouter(router), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, models)
+	routing := resp.Metadata["routing"].(RoutingDecision)
+	assert.Equal(t, "classifier", routing.Reason)
+	assert.InDelta(t, 0.1, routing.Difficulty, 1e-9)
+
+	models = nil
+	_, err = l.Generate(context.Background(), NewPrompt("Prove the Riemann hypothesis."), WithDifficultyRouter(router))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o"}, models)
+
+	scores := map[string]float64{"easy": 0.2, "medium": 0.55, "hard": 0.9}
+	router.Classifier = func(ctx context.Context, prompt string) (float64, error) {
+		return scores[prompt], nil
+	}
+	reports, err := router.Evaluate(context.Background(), []LabeledPrompt{
+		{Prompt: "easy"}, {Prompt: "medium"}, {Prompt: "hard", Hard: true},
+	}, 0.5, 0.7)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.InDelta(t, 2.0/3, reports[0].Accuracy, 1e-9)
+	assert.Equal(t, 1, reports[0].WastedEasy)
+	assert.InDelta(t, 1.0, reports[1].Accuracy, 1e-9)
+	assert.InDelta(t, 1.0/3, reports[1].ExpensiveShare, 1e-9)
+	assert.Greater(t, reports[1].Savings, reports[0].Savings)
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// redacted replaces the values of credentials in dry run requests.
+const redacted = "REDACTED"
+
+// DryRunRequest is the HTTP request a dry run returns instead of sending it.
+// Generate returns it encoded as JSON.
+type DryRunRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// WithDryRun makes Generate and GenerateWithSchema return the fully prepared
+// provider request, as a JSON-encoded DryRunRequest, instead of calling the
+// API. The request has passed through the before-request hooks, and
+// credentials in its headers and URL are redacted. Use it to check that the
+// directives, context and output format of a prompt reach the provider.
+//
+// Example:
+//
+//	request, err := client.Generate(ctx, prompt, llm.WithDryRun())
+//	var dryRun llm.DryRunRequest
+//	err = json.Unmarshal([]byte(request), &dryRun)
+func WithDryRun() GenerateOption {
+	return func(c *GenerateConfig) {
+		c.DryRun = true
+	}
+}
+
+// isDryRun reports whether opts request a dry run.
+func isDryRun(opts []GenerateOption) bool {
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg.DryRun
+}
+
+// dryRun builds the request for reqBody as it would be sent and returns it
+// as a JSON-encoded DryRunRequest.
+func (l *LLMImpl) dryRun(ctx context.Context, provider providers.Provider, reqBody []byte) (string, error) {
+	req, err := l.newRequest(ctx, provider, reqBody)
+	if err != nil {
+		return "", NewLLMError(ErrorTypeRequest, "failed to create request", err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", NewLLMError(ErrorTypeRequest, "failed to read request body", err)
+	}
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+
+	headers := make(map[string]string, len(req.Header))
+	for name := range req.Header {
+		headers[name] = req.Header.Get(name)
+		if isCredential(name) {
+			headers[name] = redacted
+		}
+	}
+	u := *req.URL
+	u.User = nil
+	if query := u.Query(); len(query) > 0 {
+		for name := range query {
+			if isCredential(name) {
+				query.Set(name, redacted)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	request, err := json.MarshalIndent(DryRunRequest{
+		Method:  req.Method,
+		URL:     u.String(),
+		Headers: headers,
+		Body:    body,
+	}, "", "  ")
+	if err != nil {
+		return "", NewLLMError(ErrorTypeRequest, "failed to encode dry run request", err)
+	}
+	return string(request), nil
+}
+
+// isCredential reports whether a header or query parameter name is likely
+// to carry a credential, such as Authorization or x-api-key.
+func isCredential(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"authorization", "key", "token", "secret", "cookie"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
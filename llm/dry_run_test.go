@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("dry runs must not call the API")
+	}))
+	defer server.Close()
+	l := newTestLLM(t, server.URL+"?api_key=secret")
+
+	prompt := NewPrompt("Summarize", WithDirectives("Be brief"), WithOutput("One sentence"))
+	for _, generate := range []func() (string, error){
+		func() (string, error) { return l.Generate(context.Background(), prompt, WithDryRun()) },
+		func() (string, error) {
+			return l.GenerateWithSchema(context.Background(), prompt, map[string]interface{}{"type": "object"}, WithDryRun())
+		},
+	} {
+		result, err := generate()
+		require.NoError(t, err)
+		var request DryRunRequest
+		require.NoError(t, json.Unmarshal([]byte(result), &request))
+		assert.Equal(t, "POST", request.Method)
+		assert.Equal(t, server.URL+"?api_key=REDACTED", request.URL)
+		assert.Equal(t, "REDACTED", request.Headers["Authorization"])
+		assert.Equal(t, "application/json", request.Headers["Content-Type"])
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(request.Body, &body))
+		assert.Contains(t, body["prompt"], "- Be brief")
+		assert.Contains(t, body["prompt"], "Expected Output Format:\nOne sentence")
+	}
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Unwrapper is implemented by LLMs that wrap another LLM, such as the
+// conversation memory wrapper. Helpers that need the underlying client, like
+// Embed, follow the chain of Unwrap calls.
+type Unwrapper interface {
+	Unwrap() LLM
+}
+
+// baseLLM follows the Unwrap chain of l down to the *LLMImpl, if any.
+func baseLLM(l LLM) (*LLMImpl, bool) {
+	for l != nil {
+		if impl, ok := l.(*LLMImpl); ok {
+			return impl, true
+		}
+		w, ok := l.(Unwrapper)
+		if !ok {
+			return nil, false
+		}
+		l = w.Unwrap()
+	}
+	return nil, false
+}
+
+// endpointProvider sends a provider's requests to one of its secondary
+// endpoints, such as the embeddings endpoint.
+type endpointProvider struct {
+	providers.Provider
+	endpoint string
+}
+
+func (p endpointProvider) Endpoint() string { return p.endpoint }
+
+// Embed returns one embedding vector per input, using the embeddings
+// endpoint of the LLM's provider. Options set with WithOption, such as
+// "embedding_model", select provider-specific settings. It returns
+// ErrorTypeUnsupported when the provider has no embeddings endpoint.
+//
+// Example:
+//
+//	vectors, err := llm.Embed(ctx, client, []string{"first text", "second text"})
+func Embed(ctx context.Context, l LLM, inputs []string, opts ...GenerateOption) ([][]float64, error) {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, "embeddings require a gollm client", nil)
+	}
+	if _, ok := impl.Provider.(providers.EmbeddingProvider); !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("embeddings not supported by provider %s", impl.Provider.Name()), nil)
+	}
+	if len(inputs) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no inputs to embed", nil)
+	}
+
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var vectors [][]float64
+	err := impl.callEndpoint(ctx, "failed to embed", func(provider providers.Provider) (string, []byte, error) {
+		embedder, ok := provider.(providers.EmbeddingProvider)
+		if !ok {
+			return "", nil, NewLLMError(ErrorTypeUnsupported, "embeddings not supported by provider "+provider.Name(), nil)
+		}
+		reqBody, err := embedder.PrepareEmbeddingRequest(inputs, cfg.Options)
+		if err != nil {
+			return "", nil, NewLLMError(ErrorTypeRequest, "failed to prepare embedding request", err)
+		}
+		return embedder.EmbeddingEndpoint(), reqBody, nil
+	}, func(provider providers.Provider, body []byte) error {
+		var err error
+		if vectors, err = provider.(providers.EmbeddingProvider).ParseEmbeddingResponse(body); err != nil {
+			return NewLLMError(ErrorTypeResponse, "failed to parse embedding response", err)
+		}
+		if len(vectors) != len(inputs) {
+			return NewLLMError(ErrorTypeResponse, fmt.Sprintf("got %d embeddings for %d inputs", len(vectors), len(inputs)), nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// callEndpoint sends a request to a secondary endpoint of the provider, such
+// as its embeddings endpoint, with the retries, key pooling and hooks of
+// Generate. prepare returns the endpoint URL and request body for the
+// provider of the current attempt; parse handles the response body.
+func (l *LLMImpl) callEndpoint(ctx context.Context, failureMsg string, prepare func(providers.Provider) (string, []byte, error), parse func(providers.Provider, []byte) error) error {
+	_, err := l.withRetries(ctx, failureMsg, func(ctx context.Context, attempt int) (string, error) {
+		provider, release, err := l.acquireProvider(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		endpoint, reqBody, err := prepare(provider)
+		if err != nil {
+			return "", err
+		}
+		body, err := l.sendRequest(ctx, endpointProvider{provider, endpoint}, reqBody)
+		if err != nil {
+			return "", err
+		}
+		return "", parse(provider, body)
+	})
+	return err
+}
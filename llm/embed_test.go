@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// mistralTestProvider sends Mistral requests to a test server.
+type mistralTestProvider struct {
+	providers.EmbeddingProvider
+	providers.Provider
+	endpoint string
+}
+
+func (p *mistralTestProvider) Endpoint() string          { return p.endpoint + "/chat" }
+func (p *mistralTestProvider) EmbeddingEndpoint() string { return p.endpoint + "/embeddings" }
+
+func (p *mistralTestProvider) ParseToolCalls(body []byte) ([]providers.ToolCall, error) {
+	return p.Provider.(providers.ToolCallParser).ParseToolCalls(body)
+}
+
+func TestMistralToolCallsAndEmbeddings(t *testing.T) {
+	requests := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests[r.URL.Path] = request
+		switch r.URL.Path {
+		case "/chat":
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"","tool_calls":[
+				{"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}},
+				{"function":{"name":"get_weather","arguments":"{\"city\":\"Lyon\"}"}}]}}]}`)
+		case "/embeddings":
+			fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}]}`)
+		}
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("mistral", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		p := providers.NewMistralProvider(apiKey, model, extraHeaders)
+		return &mistralTestProvider{p.(providers.EmbeddingProvider), p, server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("mistral"), config.SetModel("mistral-small-latest"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	tools := []utils.Tool{{Type: "function", Function: utils.Function{Name: "get_weather", Parameters: map[string]interface{}{"type": "object"}}}}
+	resp, err := GenerateResponse(context.Background(), l, NewPrompt("weather?", WithTools(tools)), WithOption("safe_prompt", true))
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 2)
+	assert.Equal(t, "get_weather", resp.ToolCalls[1].Function.Name)
+	assert.JSONEq(t, `{"city":"Lyon"}`, string(resp.ToolCalls[1].Function.Arguments))
+	calls, err := utils.ExtractFunctionCalls(resp.Content)
+	require.NoError(t, err)
+	assert.Len(t, calls, 2, "the text format is kept for compatibility")
+	chat := requests["/chat"]
+	assert.Equal(t, true, chat["safe_prompt"])
+	assert.Len(t, chat["tools"], 1)
+
+	vectors, err := Embed(context.Background(), l, []string{"a", "b"}, WithOption("embedding_model", "mistral-embed"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{0.1, 0.2}, {0.3, 0.4}}, vectors)
+	assert.Equal(t, "mistral-embed", requests["/embeddings"]["model"])
+}
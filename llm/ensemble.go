@@ -0,0 +1,295 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EnsembleStrategy selects how an Ensemble combines the responses of its
+// members.
+type EnsembleStrategy string
+
+const (
+	// EnsembleVote returns the most common response. Responses are compared
+	// ignoring case and whitespace, and JSON responses by their content.
+	EnsembleVote EnsembleStrategy = "vote"
+
+	// EnsembleJudge returns the response a judge LLM ranks best.
+	EnsembleJudge EnsembleStrategy = "judge"
+
+	// EnsembleMerge merges JSON object responses field by field, taking the
+	// most common value of every field. Nested objects are merged the same
+	// way; other values are compared as a whole.
+	EnsembleMerge EnsembleStrategy = "merge"
+)
+
+// Ensemble queries several models concurrently and combines their responses,
+// which makes high-stakes tasks such as data extraction more reliable than
+// relying on a single model.
+//
+// Example:
+//
+//	ensemble, err := llm.NewEnsemble([]llm.LLM{gpt, claude, mistral}, llm.EnsembleMerge,
+//	    llm.WithMinAgreement(0.6))
+//	result, err := ensemble.GenerateWithSchema(ctx, prompt, invoiceSchema)
+//	fmt.Println(result.Content, result.Agreement)
+type Ensemble struct {
+	members      []LLM
+	strategy     EnsembleStrategy
+	judge        LLM
+	criteria     string
+	minAgreement float64
+}
+
+// EnsembleOption configures an Ensemble.
+type EnsembleOption func(*Ensemble)
+
+// WithEnsembleJudge sets the judge of the EnsembleJudge strategy and the
+// criteria it ranks responses on.
+func WithEnsembleJudge(judge LLM, criteria string) EnsembleOption {
+	return func(e *Ensemble) {
+		e.judge = judge
+		e.criteria = criteria
+	}
+}
+
+// WithMinAgreement makes the vote and merge strategies fail when less than
+// the given share of the successful members, from 0 to 1, agree on the
+// result. For merges, every field must reach the share.
+func WithMinAgreement(share float64) EnsembleOption {
+	return func(e *Ensemble) {
+		e.minAgreement = share
+	}
+}
+
+// EnsembleResult is the combined response of an Ensemble.
+type EnsembleResult struct {
+	// Content is the combined response.
+	Content string
+
+	// Agreement is the share of successful members whose response matches
+	// Content. For merges it is the lowest share across fields; it is zero
+	// for judge selection.
+	Agreement float64
+
+	// Candidates holds the response of every member, in order, with the
+	// judge's scores for the EnsembleJudge strategy.
+	Candidates []ComparisonResult
+}
+
+// NewEnsemble creates an ensemble of the given members that combines their
+// responses with the strategy.
+func NewEnsemble(members []LLM, strategy EnsembleStrategy, opts ...EnsembleOption) (*Ensemble, error) {
+	e := &Ensemble{members: members, strategy: strategy}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if len(members) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "an ensemble needs at least one member", nil)
+	}
+	switch strategy {
+	case EnsembleVote, EnsembleMerge:
+	case EnsembleJudge:
+		if e.judge == nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "the judge strategy requires WithEnsembleJudge", nil)
+		}
+	default:
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unknown ensemble strategy %q", strategy), nil)
+	}
+	return e, nil
+}
+
+// Generate sends the prompt to every member concurrently and combines their
+// responses.
+func (e *Ensemble) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (*EnsembleResult, error) {
+	candidates := fanOut(ctx, e.members, 0, func(ctx context.Context, l LLM) (*Response, error) {
+		return GenerateResponse(ctx, l, prompt, opts...)
+	})
+	return e.combine(ctx, prompt, candidates, nil)
+}
+
+// GenerateWithSchema sends the prompt to every member concurrently, asking
+// for JSON conforming to the schema, and combines their responses. Merged
+// results are validated against the schema.
+func (e *Ensemble) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (*EnsembleResult, error) {
+	candidates := fanOut(ctx, e.members, 0, func(ctx context.Context, l LLM) (*Response, error) {
+		content, err := l.GenerateWithSchema(ctx, prompt, schema, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Content: content}, nil
+	})
+	return e.combine(ctx, prompt, candidates, schema)
+}
+
+func (e *Ensemble) combine(ctx context.Context, prompt *Prompt, candidates []ComparisonResult, schema interface{}) (*EnsembleResult, error) {
+	result := &EnsembleResult{Candidates: candidates}
+	var contents []string
+	var firstErr error
+	for _, c := range candidates {
+		if c.Err != nil {
+			if firstErr == nil {
+				firstErr = c.Err
+			}
+			continue
+		}
+		contents = append(contents, c.Response.Content)
+	}
+	if len(contents) == 0 {
+		return result, NewLLMError(ErrorTypeProvider, "every ensemble member failed", firstErr)
+	}
+
+	switch e.strategy {
+	case EnsembleJudge:
+		if err := judgeResults(ctx, e.judge, e.criteria, prompt, candidates); err != nil {
+			return result, err
+		}
+		for _, c := range candidates {
+			if c.Rank == 1 {
+				result.Content = c.Response.Content
+			}
+		}
+		return result, nil
+	case EnsembleMerge:
+		objects := make([]interface{}, 0, len(contents))
+		for _, content := range contents {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(cleanJSON(content)), &object); err != nil {
+				continue
+			}
+			objects = append(objects, object)
+		}
+		if len(objects) == 0 {
+			return result, NewLLMError(ErrorTypeResponse, "no ensemble member returned a JSON object", nil)
+		}
+		merged, agreement := mergeValues(objects)
+		b, err := json.Marshal(merged)
+		if err != nil {
+			return result, NewLLMError(ErrorTypeResponse, "failed to encode merged response", err)
+		}
+		result.Content = string(b)
+		result.Agreement = agreement * float64(len(objects)) / float64(len(contents))
+	default:
+		winner, votes := vote(contents)
+		result.Content = winner
+		result.Agreement = float64(votes) / float64(len(contents))
+	}
+
+	if result.Agreement < e.minAgreement {
+		return result, NewLLMError(ErrorTypeResponse, fmt.Sprintf("ensemble agreement %.2f is below the minimum %.2f", result.Agreement, e.minAgreement), nil)
+	}
+	if schema != nil {
+		if err := ValidateAgainstSchema(result.Content, schema); err != nil {
+			return result, NewLLMError(ErrorTypeResponse, "merged response does not match schema", err)
+		}
+	}
+	return result, nil
+}
+
+// vote returns the most common response and its number of votes. Ties go to
+// the response seen first.
+func vote(contents []string) (string, int) {
+	counts := make(map[string]int)
+	first := make(map[string]string)
+	var order []string
+	for _, content := range contents {
+		key := voteKey(content)
+		if _, seen := first[key]; !seen {
+			first[key] = content
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+	best := order[0]
+	for _, key := range order[1:] {
+		if counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return first[best], counts[best]
+}
+
+// voteKey normalizes a response for voting: JSON by its content, text
+// ignoring case, whitespace and a final period.
+func voteKey(content string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(cleanJSON(content)), &value); err == nil {
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+	text := strings.ToLower(strings.Join(strings.Fields(content), " "))
+	return strings.TrimSuffix(text, ".")
+}
+
+// cleanJSON strips a markdown code fence around a JSON response.
+func cleanJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+	}
+	return strings.TrimSpace(content)
+}
+
+// mergeValues merges values field by field when they are all JSON objects,
+// and votes on them otherwise. It returns the merged value and the lowest
+// share of values agreeing on any field.
+func mergeValues(values []interface{}) (interface{}, float64) {
+	objects := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		objects = append(objects, object)
+	}
+
+	if len(objects) < len(values) {
+		encoded := make([]string, len(values))
+		for i, v := range values {
+			b, _ := json.Marshal(v)
+			encoded[i] = string(b)
+		}
+		winner, votes := vote(encoded)
+		var value interface{}
+		_ = json.Unmarshal([]byte(winner), &value)
+		return value, float64(votes) / float64(len(values))
+	}
+
+	merged := make(map[string]interface{})
+	agreement := 1.0
+	var keys []string
+	seen := make(map[string]bool)
+	for _, object := range objects {
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range keys {
+		var fieldValues []interface{}
+		for _, object := range objects {
+			if v, ok := object[key]; ok {
+				fieldValues = append(fieldValues, v)
+			}
+		}
+		if len(fieldValues)*2 < len(objects) {
+			// Most members left the field out
+			continue
+		}
+		// Members that left the field out count as disagreeing
+		value, share := mergeValues(fieldValues)
+		share *= float64(len(fieldValues)) / float64(len(objects))
+		merged[key] = value
+		if share < agreement {
+			agreement = share
+		}
+	}
+	return merged, agreement
+}
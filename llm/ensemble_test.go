@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsemble(t *testing.T) {
+	reply := func(content string) LLM {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]string{"content": content})
+		}))
+		t.Cleanup(server.Close)
+		return newTestLLM(t, server.URL)
+	}
+	prompt := NewPrompt("What is the capital of France?")
+
+	voting, err := NewEnsemble([]LLM{reply("Lyon"), reply("Paris."), reply("paris")}, EnsembleVote)
+	require.NoError(t, err)
+	result, err := voting.Generate(context.Background(), prompt)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris.", result.Content)
+	assert.InDelta(t, 2.0/3, result.Agreement, 1e-9)
+	assert.Len(t, result.Candidates, 3)
+
+	merging, err := NewEnsemble([]LLM{
+		reply(`{"name": "ACME", "total": 12.5, "address": {"city": "Paris"}}`),
+		reply("```json\n{\"name\": \"ACME\", \"total\": 12.5, \"address\": {\"city\": \"Lyon\"}}\n```"),
+		reply(`{"name": "Acme Inc", "total": 12.5, "address": {"city": "Paris"}}`),
+	}, EnsembleMerge, WithMinAgreement(0.5))
+	require.NoError(t, err)
+	result, err = merging.Generate(context.Background(), prompt)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "ACME", "total": 12.5, "address": {"city": "Paris"}}`, result.Content)
+	assert.InDelta(t, 2.0/3, result.Agreement, 1e-9)
+
+	strict, err := NewEnsemble([]LLM{reply("yes"), reply("no")}, EnsembleVote, WithMinAgreement(0.6))
+	require.NoError(t, err)
+	_, err = strict.Generate(context.Background(), prompt)
+	assert.Error(t, err)
+
+	_, err = NewEnsemble([]LLM{reply("yes")}, EnsembleJudge)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/teilomillet/gollm/utils"
@@ -37,6 +38,27 @@ const (
 
 	// ErrorTypeUnsupported indicates a requested feature is not supported
 	ErrorTypeUnsupported
+
+	// ErrorTypeTimeout indicates a per-attempt or overall timeout was exceeded
+	ErrorTypeTimeout
+
+	// ErrorTypeContentFiltered indicates the provider refused or filtered the
+	// prompt or the response
+	ErrorTypeContentFiltered
+)
+
+var (
+	// ErrAttemptTimeout is wrapped by errors raised when a single attempt
+	// exceeds the configured AttemptTimeout. Such attempts are retried.
+	ErrAttemptTimeout = errors.New("attempt timeout exceeded")
+
+	// ErrOverallTimeout is wrapped by errors raised when the configured Timeout,
+	// which bounds a call including all of its retries, is exceeded.
+	ErrOverallTimeout = errors.New("overall timeout exceeded")
+
+	// ErrUnsupportedOption is wrapped by the error NewLLM returns in strict
+	// options mode when a sampling option is set that the provider ignores.
+	ErrUnsupportedOption = errors.New("unsupported option")
 )
 
 // LLMError represents a structured error in the LLM package.
@@ -94,11 +116,33 @@ func (e *LLMError) TypeString() string {
 		return "InvalidInputError"
 	case ErrorTypeUnsupported:
 		return "UnsupportedError"
+	case ErrorTypeTimeout:
+		return "TimeoutError"
+	case ErrorTypeContentFiltered:
+		return "ContentFilteredError"
 	default:
 		return "UnknownError"
 	}
 }
 
+// SchemaMismatchError is the error of a GenerateWithSchema response that
+// does not match the schema. It holds the rejected response, so that
+// callers can send it back to the model with the validation error.
+type SchemaMismatchError struct {
+	Response string // The rejected response
+	Err      error  // Why the response does not match the schema
+}
+
+// Error implements the error interface.
+func (e *SchemaMismatchError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the validation error.
+func (e *SchemaMismatchError) Unwrap() error {
+	return e.Err
+}
+
 // NewLLMError creates a new LLMError with the specified type, message,
 // and underlying error.
 //
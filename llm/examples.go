@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ExampleSelector picks the few-shot examples most relevant to a prompt from
+// a pool of examples, by the similarity of their embeddings to the prompt's
+// input. Pass it to Generate with WithExampleSelector. The embedding of each
+// example is computed once and cached. It is safe for concurrent use.
+type ExampleSelector struct {
+	embedder LLM
+	lambda   float64 // MMR trade-off between relevance and diversity; 1 disables MMR
+
+	mu         sync.RWMutex
+	examples   []string
+	embeddings [][]float64 // Embeddings of the examples, nil until computed
+}
+
+// ExampleSelectorOption configures an ExampleSelector.
+type ExampleSelectorOption func(*ExampleSelector)
+
+// WithMMR selects examples by maximal marginal relevance, which trades the
+// similarity of an example to the input against its similarity to the
+// examples already selected, so that near-duplicates are not all picked.
+// lambda ranges from 0, favouring diversity, to 1, favouring relevance only.
+func WithMMR(lambda float64) ExampleSelectorOption {
+	return func(s *ExampleSelector) {
+		s.lambda = math.Max(0, math.Min(1, lambda))
+	}
+}
+
+// NewExampleSelector creates a selector over examples that computes
+// embeddings with the embeddings endpoint of embedder's provider.
+//
+// Example:
+//
+//	selector := llm.NewExampleSelector(embeddingClient, llm.WithMMR(0.7))
+//	selector.Add(examples...)
+//	response, err := client.Generate(ctx, prompt, llm.WithExampleSelector(selector, 3))
+func NewExampleSelector(embedder LLM, opts ...ExampleSelectorOption) *ExampleSelector {
+	s := &ExampleSelector{embedder: embedder, lambda: 1}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add registers examples in the pool.
+func (s *ExampleSelector) Add(examples ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.examples = append(s.examples, examples...)
+	s.embeddings = append(s.embeddings, make([][]float64, len(examples))...)
+}
+
+// Select returns the k examples most relevant to query, the most relevant
+// first. It returns every example when the pool has at most k.
+func (s *ExampleSelector) Select(ctx context.Context, query string, k int) ([]string, error) {
+	s.mu.RLock()
+	n := len(s.examples)
+	if n <= k {
+		defer s.mu.RUnlock()
+		return append([]string(nil), s.examples...), nil
+	}
+	s.mu.RUnlock()
+	if k <= 0 {
+		return nil, nil
+	}
+	if err := s.embedExamples(ctx); err != nil {
+		return nil, err
+	}
+	vectors, err := Embed(ctx, s.embedder, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+
+	s.mu.RLock()
+	examples := s.examples[:n]
+	embeddings := s.embeddings[:n]
+	s.mu.RUnlock()
+
+	relevance := make([]float64, n)
+	for i, embedding := range embeddings {
+		relevance[i] = cosineSimilarity(vectors[0], embedding)
+	}
+	selected := make([]int, 0, k)
+	picked := make([]bool, n)
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range examples {
+			if picked[i] {
+				continue
+			}
+			score := relevance[i]
+			if s.lambda < 1 {
+				var redundancy float64
+				for _, j := range selected {
+					redundancy = math.Max(redundancy, cosineSimilarity(embeddings[i], embeddings[j]))
+				}
+				score = s.lambda*relevance[i] - (1-s.lambda)*redundancy
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		selected = append(selected, best)
+	}
+
+	result := make([]string, len(selected))
+	for i, index := range selected {
+		result[i] = examples[index]
+	}
+	return result, nil
+}
+
+// embedExamples computes the missing embeddings of the examples.
+func (s *ExampleSelector) embedExamples(ctx context.Context) error {
+	var indexes []int
+	var texts []string
+	s.mu.RLock()
+	for i, embedding := range s.embeddings {
+		if embedding == nil {
+			indexes = append(indexes, i)
+			texts = append(texts, s.examples[i])
+		}
+	}
+	s.mu.RUnlock()
+	if len(texts) == 0 {
+		return nil
+	}
+
+	vectors, err := Embed(ctx, s.embedder, texts)
+	if err != nil {
+		return fmt.Errorf("error embedding examples: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, index := range indexes {
+		s.embeddings[index] = vectors[i]
+	}
+	return nil
+}
+
+// exampleSelection is the example selection requested for a call.
+type exampleSelection struct {
+	selector *ExampleSelector
+	k        int
+}
+
+// WithExampleSelector adds the k examples of selector most relevant to the
+// prompt's input to the prompt's examples, for a single Generate call.
+func WithExampleSelector(selector *ExampleSelector, k int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.examples = &exampleSelection{selector: selector, k: k}
+	}
+}
+
+// apply returns a copy of prompt with the selected examples added.
+func (e *exampleSelection) apply(ctx context.Context, prompt *Prompt) (*Prompt, error) {
+	if e.selector == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "nil example selector", nil)
+	}
+	examples, err := e.selector.Select(ctx, prompt.Input, e.k)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to select examples", err)
+	}
+	selected := *prompt
+	selected.Examples = append(append([]string(nil), prompt.Examples...), examples...)
+	return &selected, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestExampleSelector(t *testing.T) {
+	var chat map[string]interface{}
+	var embedded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		switch r.URL.Path {
+		case "/chat":
+			chat = request
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"cloudy"}}]}`)
+		case "/embeddings":
+			// Embeds texts on two axes: weather and email
+			var data []string
+			for i, input := range request["input"].([]interface{}) {
+				text := input.(string)
+				embedded = append(embedded, text)
+				vector := []float64{0.1, 0.1}
+				if strings.Contains(text, "weather") {
+					vector[0] = 1
+				}
+				if strings.Contains(text, "email") {
+					vector[1] = 1
+				}
+				data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%g,%g]}`, i, vector[0], vector[1]))
+			}
+			fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+		}
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("mistral", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		p := providers.NewMistralProvider(apiKey, model, extraHeaders)
+		return &mistralTestProvider{p.(providers.EmbeddingProvider), p, server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("mistral"), config.SetModel("mistral-small-latest"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	examples := []string{
+		"Q: weather in Paris? A: sunny",
+		"Q: weather in Lyon? A: rainy",
+		"Q: send an email to Bob A: sent",
+	}
+	selector := NewExampleSelector(l)
+	selector.Add(examples...)
+	_, err = l.Generate(context.Background(), NewPrompt("What is the weather tomorrow?"), WithExampleSelector(selector, 1))
+	require.NoError(t, err)
+	assert.Contains(t, fmt.Sprint(chat["messages"]), examples[0])
+	assert.NotContains(t, fmt.Sprint(chat["messages"]), examples[2])
+
+	selected, err := selector.Select(context.Background(), "weather and email", 2)
+	require.NoError(t, err)
+	assert.Equal(t, examples[:2], selected)
+	assert.Len(t, embedded, 5, "example embeddings are cached")
+
+	diverse := NewExampleSelector(l, WithMMR(0.5))
+	diverse.Add(examples...)
+	selected, err = diverse.Select(context.Background(), "weather and email", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{examples[0], examples[2]}, selected)
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// CompleteFIM returns the text that fills the gap between prefix and suffix,
+// using the fill-in-the-middle endpoint of the LLM's provider: Codestral for
+// Mistral, the beta completions API for DeepSeek, and the model's FIM
+// template for Ollama. Options set with WithOption, such as "max_tokens" or
+// "stop", are passed to the provider. It returns ErrorTypeUnsupported when
+// the provider has no FIM endpoint.
+//
+// Example:
+//
+//	middle, err := llm.CompleteFIM(ctx, client, "def fib(n):\n", "\nprint(fib(10))")
+func CompleteFIM(ctx context.Context, l LLM, prefix, suffix string, opts ...GenerateOption) (string, error) {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return "", NewLLMError(ErrorTypeUnsupported, "fill-in-the-middle completion requires a gollm client", nil)
+	}
+	if _, ok := impl.Provider.(providers.FIMProvider); !ok {
+		return "", NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("fill-in-the-middle completion not supported by provider %s", impl.Provider.Name()), nil)
+	}
+	if prefix == "" {
+		return "", NewLLMError(ErrorTypeInvalidInput, "empty prefix", nil)
+	}
+
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var completion string
+	err := impl.callEndpoint(ctx, "failed to complete", func(provider providers.Provider) (string, []byte, error) {
+		completer, ok := provider.(providers.FIMProvider)
+		if !ok {
+			return "", nil, NewLLMError(ErrorTypeUnsupported, "fill-in-the-middle completion not supported by provider "+provider.Name(), nil)
+		}
+		reqBody, err := completer.PrepareFIMRequest(prefix, suffix, cfg.Options)
+		if err != nil {
+			return "", nil, NewLLMError(ErrorTypeRequest, "failed to prepare fill-in-the-middle request", err)
+		}
+		return completer.FIMEndpoint(), reqBody, nil
+	}, func(provider providers.Provider, body []byte) error {
+		var err error
+		if completion, err = provider.(providers.FIMProvider).ParseFIMResponse(body); err != nil {
+			return NewLLMError(ErrorTypeResponse, "failed to parse fill-in-the-middle response", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return completion, nil
+}
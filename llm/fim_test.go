@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestCompleteFIM(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/fim", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"choices":[{"text":"    return n if n < 2 else fib(n-1) + fib(n-2)"}]}`)
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("deepseek", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &deepSeekTestProvider{providers.NewDeepSeekProvider(apiKey, model, extraHeaders), server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("deepseek"), config.SetModel("deepseek-reasoner"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	middle, err := CompleteFIM(context.Background(), l, "def fib(n):\n", "\nprint(fib(10))", WithOption("max_tokens", 64))
+	require.NoError(t, err)
+	assert.Equal(t, "    return n if n < 2 else fib(n-1) + fib(n-2)", middle)
+	assert.Equal(t, "deepseek-chat", request["model"])
+	assert.Equal(t, "def fib(n):\n", request["prompt"])
+	assert.Equal(t, "\nprint(fib(10))", request["suffix"])
+	assert.Equal(t, float64(64), request["max_tokens"])
+
+	_, err = CompleteFIM(context.Background(), &tokenLLM{}, "prefix", "suffix")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// generationStatsAttempts is the number of times the statistics of a
+// generation are requested, as they become available shortly after it
+// completes.
+const generationStatsAttempts = 3
+
+// WithCostLookup fetches the statistics of the generation after the
+// response, for providers that report them separately, and adds them to the
+// Metadata of the Response passed to WithResponse. With OpenRouter, the
+// actual cost, the upstream provider used and the latency are stored under
+// the "openrouter" key as a providers.OpenRouterGeneration value. The lookup
+// is an extra request; when it fails, the failure is logged and the
+// response is returned without the statistics.
+//
+// Example:
+//
+//	var resp llm.Response
+//	_, err := client.Generate(ctx, prompt, llm.WithResponse(&resp), llm.WithCostLookup())
+//	if gen, ok := resp.Metadata["openrouter"].(providers.OpenRouterGeneration); ok {
+//	    log.Printf("$%.6f via %s", gen.TotalCost, gen.ProviderName)
+//	}
+func WithCostLookup() GenerateOption {
+	return func(c *GenerateConfig) {
+		c.CostLookup = true
+	}
+}
+
+// lookupGenerationStats fetches the statistics of the generation in body
+// and adds them to resp.Metadata, when provider reports them.
+func (l *LLMImpl) lookupGenerationStats(ctx context.Context, provider providers.Provider, body []byte, resp *Response) {
+	stats, ok := provider.(providers.GenerationStatsProvider)
+	if !ok {
+		return
+	}
+	id := stats.GenerationID(body)
+	if id == "" {
+		l.logger.Warn("Response has no generation ID for the cost lookup")
+		return
+	}
+	metadata, err := l.fetchGenerationStats(ctx, provider, stats, id)
+	if err != nil {
+		l.logger.Warn("Failed to look up generation cost", "id", id, "error", err)
+		return
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]interface{}, len(metadata))
+	}
+	for k, v := range metadata {
+		resp.Metadata[k] = v
+	}
+}
+
+func (l *LLMImpl) fetchGenerationStats(ctx context.Context, provider providers.Provider, stats providers.GenerationStatsProvider, id string) (map[string]interface{}, error) {
+	var lastErr error
+	for attempt := 0; attempt < generationStatsAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, stats.GenerationEndpoint(id), nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range provider.Headers() {
+			req.Header.Set(k, v)
+		}
+		resp, err := l.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return stats.ParseGenerationStats(body)
+		case http.StatusNotFound:
+			// Not recorded yet
+			lastErr = fmt.Errorf("generation not found")
+		default:
+			return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, body)
+		}
+	}
+	return nil, lastErr
+}
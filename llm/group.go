@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Group runs related LLM calls concurrently, like errgroup: the calls share
+// a concurrency limit and a rate limit, the first fatal error cancels the
+// calls still running or waiting, and the usage of the Generate calls is
+// collected in one UsageTracker.
+//
+// Example:
+//
+//	g, ctx := llm.NewGroup(ctx, llm.WithGroupLimit(4), llm.WithGroupRateLimit(10, 1))
+//	summaries := make([]string, len(documents))
+//	for i, doc := range documents {
+//	    g.Generate(client, llm.NewPrompt("Summarize: "+doc), &summaries[i])
+//	}
+//	if err := g.Wait(); err != nil {
+//	    return err
+//	}
+//	log.Printf("cost: $%.4f", g.Usage().Cost)
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	sem     chan struct{}    // nil without a concurrency limit
+	limiter *rate.Limiter    // nil without a rate limit
+	fatal   func(error) bool // nil when every error is fatal
+	tracker *UsageTracker
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	err      error   // The first fatal error
+	nonFatal []error // The errors that did not cancel the group
+}
+
+// GroupOption configures a Group.
+type GroupOption func(*Group)
+
+// WithGroupLimit runs at most n calls of the group at once. Calls beyond the
+// limit wait for a running one to return.
+func WithGroupLimit(n int) GroupOption {
+	return func(g *Group) {
+		if n > 0 {
+			g.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithGroupRateLimit starts at most requestsPerSecond calls of the group per
+// second, with bursts of up to burst calls.
+func WithGroupRateLimit(requestsPerSecond float64, burst int) GroupOption {
+	return func(g *Group) {
+		if requestsPerSecond > 0 {
+			g.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), max(burst, 1))
+		}
+	}
+}
+
+// WithGroupFatal sets which errors cancel the group. Other errors are
+// collected and returned by Wait without stopping the other calls. By
+// default, every error is fatal.
+//
+// Example:
+//
+//	llm.WithGroupFatal(func(err error) bool {
+//	    var llmErr *llm.LLMError
+//	    return errors.As(err, &llmErr) && llmErr.Type == llm.ErrorTypeAuthentication
+//	})
+func WithGroupFatal(fatal func(error) bool) GroupOption {
+	return func(g *Group) {
+		g.fatal = fatal
+	}
+}
+
+// NewGroup creates a Group and the context its calls run with, which is
+// cancelled by the first fatal error or when Wait returns. The Generate calls
+// made with the context are recorded in the group's UsageTracker.
+func NewGroup(ctx context.Context, opts ...GroupOption) (*Group, context.Context) {
+	g := &Group{tracker: NewUsageTracker()}
+	for _, opt := range opts {
+		opt(g)
+	}
+	ctx, g.cancel = context.WithCancelCause(ContextWithUsageTracker(ctx, g.tracker))
+	g.ctx = ctx
+	return g, ctx
+}
+
+// Go runs fn in a new goroutine once the concurrency and rate limits allow
+// it. fn is not run when the group is cancelled while it waits.
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			case <-g.ctx.Done():
+			}
+		}
+		if g.ctx.Err() != nil {
+			g.fail(context.Cause(g.ctx))
+			return
+		}
+		if g.limiter != nil {
+			if err := g.limiter.Wait(g.ctx); err != nil {
+				g.fail(context.Cause(g.ctx))
+				return
+			}
+		}
+		if err := fn(g.ctx); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// Generate runs a Generate call of l in the group, storing the generated
+// text in dst.
+func (g *Group) Generate(l LLM, prompt *Prompt, dst *string, opts ...GenerateOption) {
+	g.Go(func(ctx context.Context) error {
+		text, err := l.Generate(ctx, prompt, opts...)
+		if err != nil {
+			return err
+		}
+		*dst = text
+		return nil
+	})
+}
+
+// fail records the error of a call, cancelling the group when it is fatal.
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		// Calls fail with the group's cancellation after the first fatal error
+		return
+	}
+	if g.fatal != nil && !g.fatal(err) {
+		g.nonFatal = append(g.nonFatal, err)
+		return
+	}
+	g.err = err
+	g.cancel(err)
+}
+
+// Wait waits for the calls of the group to return. It returns the first
+// fatal error, or else the non-fatal errors joined, or nil.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(context.Canceled)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return g.err
+	}
+	return errors.Join(g.nonFatal...)
+}
+
+// Usage returns the total usage of the Generate calls of the group so far.
+func (g *Group) Usage() UsageStats {
+	return g.tracker.Total()
+}
+
+// UsageTracker returns the tracker recording the Generate calls of the
+// group, for a breakdown by model, provider or tag.
+func (g *Group) UsageTracker() *UsageTracker {
+	return g.tracker
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL)
+
+	g, ctx := NewGroup(context.Background(), WithGroupLimit(2), WithGroupRateLimit(1000, 5))
+	results := make([]string, 5)
+	var running, peak int32
+	for i := range results {
+		i := i
+		g.Go(func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			text, err := l.Generate(ctx, NewPrompt(fmt.Sprintf("doc %d", i)))
+			results[i] = text
+			return err
+		})
+	}
+	require.NoError(t, g.Wait())
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	assert.Contains(t, results[3], "doc 3")
+	assert.Equal(t, 5, g.Usage().Requests)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled, "Wait cancels the group's context")
+
+	// The first fatal error cancels the calls waiting for their turn
+	fatal := errors.New("invalid API key")
+	g, _ = NewGroup(context.Background(), WithGroupLimit(1))
+	started, proceed := make(chan struct{}), make(chan struct{})
+	var ran int32
+	g.Go(func(ctx context.Context) error {
+		close(started)
+		<-proceed
+		return fatal
+	})
+	<-started
+	for i := 0; i < 3; i++ {
+		g.Go(func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		})
+	}
+	close(proceed)
+	assert.ErrorIs(t, g.Wait(), fatal)
+	assert.Zero(t, atomic.LoadInt32(&ran))
+
+	// Non-fatal errors are collected without cancelling the others
+	g, _ = NewGroup(context.Background(), WithGroupFatal(func(err error) bool { return !errors.Is(err, fatal) }))
+	var summary string
+	g.Go(func(ctx context.Context) error { return fatal })
+	g.Generate(l, NewPrompt("summarize"), &summary)
+	assert.ErrorIs(t, g.Wait(), fatal)
+	assert.Contains(t, summary, "summarize")
+}
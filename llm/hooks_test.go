@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestRequestResponseHooks(t *testing.T) {
+	var gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotHeader = r.Header.Get("Idempotency-Key")
+		gotBody = string(body)
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "raw"})
+	}))
+	defer server.Close()
+
+	var audited []byte
+	l := newTestLLM(t, server.URL,
+		config.SetBeforeRequestHook(func(req *http.Request, body []byte) ([]byte, error) {
+			req.Header.Set("Idempotency-Key", "abc")
+			return []byte(`{"signed":true}`), nil
+		}),
+		config.SetAfterResponseHook(func(resp *http.Response, body []byte) ([]byte, error) {
+			audited = body
+			return []byte(`{"content":"rewritten"}`), nil
+		}),
+	)
+
+	response, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", gotHeader)
+	assert.Equal(t, `{"signed":true}`, gotBody)
+	assert.JSONEq(t, `{"content":"raw"}`, string(audited))
+	assert.Equal(t, "rewritten", response)
+}
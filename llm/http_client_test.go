@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newSSETestLLM returns an LLM whose provider streams server-sent events from
+// endpoint and answers JSON otherwise.
+func newSSETestLLM(t *testing.T, endpoint string, opts ...config.ConfigOption) LLM {
+	t.Helper()
+	registry := providers.NewProviderRegistry()
+	registry.Register("sse", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &sseProvider{echoProvider{endpoint: endpoint, apiKey: apiKey, options: make(map[string]interface{})}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("sse"), config.SetAPIKey("test-key"), config.SetMaxRetries(0))
+	config.ApplyOptions(cfg, opts...)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+	return l
+}
+
+// answer replies with a stream to stream requests and with JSON otherwise.
+func answer(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var request map[string]interface{}
+	_ = json.Unmarshal(body, &request)
+	if stream, _ := request["stream"].(bool); stream {
+		fmt.Fprint(w, "data: {\"text\":\"streamed\"}\n\ndata: [DONE]\n\n")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"content": "generated"})
+}
+
+func collectStream(t *testing.T, l LLM) string {
+	t.Helper()
+	stream, err := l.Stream(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	defer stream.Close()
+	var text string
+	for {
+		token, err := stream.Next(context.Background())
+		if err == io.EOF {
+			return text
+		}
+		require.NoError(t, err)
+		text += token.Text
+	}
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(answer))
+	defer server.Close()
+
+	var requests atomic.Int32
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	l := newSSETestLLM(t, server.URL, config.SetHTTPClient(client))
+
+	response, err := l.Generate(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "generated", response)
+	assert.Equal(t, "streamed", collectStream(t, l))
+	assert.Equal(t, int32(2), requests.Load(), "generate and stream requests go through the custom transport")
+}
+
+func TestSetProxy(t *testing.T) {
+	// The proxy answers itself instead of forwarding, and records the
+	// absolute URLs it was asked for.
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		answer(w, r)
+	}))
+	defer proxy.Close()
+
+	const endpoint = "http://provider.invalid/v1/chat"
+	l := newSSETestLLM(t, endpoint, config.SetProxy(proxy.URL))
+
+	response, err := l.Generate(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "generated", response)
+	assert.Equal(t, "streamed", collectStream(t, l))
+	assert.Equal(t, []string{endpoint, endpoint}, proxied, "generate and stream requests go through the proxy")
+
+	// The proxy is set on a copy of a custom client's transport
+	transport := &http.Transport{}
+	client := &http.Client{Transport: transport}
+	l = newSSETestLLM(t, endpoint, config.SetHTTPClient(client), config.SetProxy(proxy.URL))
+	_, err = l.Generate(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Len(t, proxied, 3)
+	assert.Same(t, transport, client.Transport)
+	assert.Nil(t, transport.Proxy, "the caller's transport is not changed")
+}
+
+func TestNewHTTPClientErrors(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProxy("not a url"))
+	_, err := NewHTTPClient(cfg)
+	assert.Error(t, err)
+
+	config.ApplyOptions(cfg,
+		config.SetProxy("http://proxy.internal:3128"),
+		config.SetHTTPClient(&http.Client{Transport: roundTripFunc(http.DefaultTransport.RoundTrip)}),
+	)
+	_, err = NewHTTPClient(cfg)
+	assert.ErrorContains(t, err, "cannot apply proxy to custom transport")
+}
@@ -0,0 +1,106 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+)
+
+// keyPool distributes requests across several provider instances, each bound
+// to its own API key and rate limiter. It is safe for concurrent use.
+type keyPool struct {
+	entries  []*poolEntry
+	strategy string
+	next     atomic.Uint64 // Round-robin cursor
+}
+
+// poolEntry is a provider bound to one pooled key.
+type poolEntry struct {
+	provider providers.Provider
+	limiter  *rate.Limiter // nil when the key is not rate limited
+	inFlight atomic.Int64
+}
+
+// newKeyPool builds one provider per pooled key using the same registry,
+// model and default options as the primary provider.
+func newKeyPool(cfg *config.Config, registry *providers.ProviderRegistry, extraHeaders map[string]string) (*keyPool, error) {
+	pool := cfg.APIKeyPool
+	if len(pool.Keys) == 0 {
+		return nil, fmt.Errorf("API key pool is empty")
+	}
+	if pool.Strategy != config.KeyPoolRoundRobin && pool.Strategy != config.KeyPoolLeastLoaded {
+		return nil, fmt.Errorf("unknown key pool strategy %q", pool.Strategy)
+	}
+
+	kp := &keyPool{strategy: pool.Strategy}
+	for i, key := range pool.Keys {
+		if key.APIKey == "" {
+			return nil, fmt.Errorf("API key pool entry %d has an empty key", i)
+		}
+
+		headers := make(map[string]string, len(extraHeaders)+len(key.ExtraHeaders))
+		for k, v := range extraHeaders {
+			headers[k] = v
+		}
+		for k, v := range key.ExtraHeaders {
+			headers[k] = v
+		}
+
+		provider, err := registry.Get(cfg.Provider, key.APIKey, cfg.Model, headers)
+		if err != nil {
+			return nil, err
+		}
+		provider.SetDefaultOptions(cfg)
+		applyEndpoint(provider, cfg)
+
+		entry := &poolEntry{provider: provider}
+		if key.RequestsPerSecond > 0 {
+			burst := key.Burst
+			if burst <= 0 {
+				burst = 1
+			}
+			entry.limiter = rate.NewLimiter(rate.Limit(key.RequestsPerSecond), burst)
+		}
+		kp.entries = append(kp.entries, entry)
+	}
+	return kp, nil
+}
+
+// acquire selects an entry according to the pool strategy, waits for its rate
+// limiter and marks it as in flight. The caller must call release when done.
+func (kp *keyPool) acquire(ctx context.Context) (*poolEntry, error) {
+	entry := kp.pick()
+	entry.inFlight.Add(1)
+	if entry.limiter != nil {
+		if err := entry.limiter.Wait(ctx); err != nil {
+			entry.release()
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func (kp *keyPool) pick() *poolEntry {
+	if kp.strategy == config.KeyPoolLeastLoaded {
+		best := kp.entries[0]
+		for _, entry := range kp.entries[1:] {
+			if entry.inFlight.Load() < best.inFlight.Load() {
+				best = entry
+			}
+		}
+		return best
+	}
+	n := kp.next.Add(1) - 1
+	return kp.entries[n%uint64(len(kp.entries))]
+}
+
+// release marks a request on this entry as finished.
+func (e *poolEntry) release() {
+	e.inFlight.Add(-1)
+}
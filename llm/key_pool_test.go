@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+)
+
+func TestAPIKeyPoolRoundRobin(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("Authorization")]++
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "ok"})
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, server.URL, config.SetAPIKeyPool(config.APIKeyPoolConfig{
+		Keys: []config.PooledKey{{APIKey: "key-a"}, {APIKey: "key-b"}, {APIKey: "key-c"}},
+	}))
+
+	for i := 0; i < 6; i++ {
+		_, err := l.Generate(context.Background(), NewPrompt("hello"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[string]int{"Bearer key-a": 2, "Bearer key-b": 2, "Bearer key-c": 2}, seen)
+}
+
+func TestAPIKeyPoolLeastLoadedAndRateLimit(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetAPIKeyPool(config.APIKeyPoolConfig{
+		Strategy: config.KeyPoolLeastLoaded,
+		Keys:     []config.PooledKey{{APIKey: "a"}, {APIKey: "b", RequestsPerSecond: 1}},
+	}))
+	registry := providers.NewProviderRegistry()
+	registry.Register("anthropic", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &echoProvider{apiKey: apiKey, options: make(map[string]interface{})}
+	})
+	pool, err := newKeyPool(cfg, registry, nil)
+	require.NoError(t, err)
+
+	first, err := pool.acquire(context.Background())
+	require.NoError(t, err)
+	second, err := pool.acquire(context.Background())
+	require.NoError(t, err)
+	assert.NotSame(t, first, second, "least-loaded should spread concurrent requests")
+	first.release()
+	second.release()
+
+	// Key "b" allows one request per second, so an immediate second use must wait.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	limited := pool.entries[1]
+	limited.inFlight.Store(-10) // Force selection of the rate-limited key
+	_, err = pool.acquire(ctx)
+	assert.Error(t, err)
+}
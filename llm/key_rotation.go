@@ -0,0 +1,76 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// rotatingProvider rebuilds the provider whenever the API key returned by a
+// config.KeyProvider changes. Keys are cached for the refresh interval and
+// re-fetched early after the provider rejects them. It is safe for concurrent use.
+type rotatingProvider struct {
+	mu        sync.Mutex
+	source    config.KeyProvider
+	name      string
+	interval  time.Duration // Zero keeps a key until it is invalidated
+	build     func(apiKey string) (providers.Provider, error)
+	logger    utils.Logger
+	current   providers.Provider
+	key       string
+	fetchedAt time.Time
+	stale     bool
+}
+
+// get returns a provider bound to the current API key, fetching a new key
+// when the cached one has expired or was invalidated. If the key provider
+// fails but a previous key exists, the previous key keeps being used.
+func (r *rotatingProvider) get(ctx context.Context) (providers.Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := r.interval > 0 && time.Since(r.fetchedAt) >= r.interval
+	if r.current != nil && !r.stale && !expired {
+		return r.current, nil
+	}
+
+	key, err := r.source.GetAPIKey(ctx, r.name)
+	if err == nil && key == "" {
+		err = NewLLMError(ErrorTypeAuthentication, "key provider returned an empty API key", nil)
+	}
+	if err != nil {
+		if r.current == nil {
+			return nil, err
+		}
+		r.logger.Warn("Failed to refresh API key, keeping the previous key", "provider", r.name, "error", err)
+		return r.current, nil
+	}
+
+	r.fetchedAt = time.Now()
+	r.stale = false
+	if key == r.key && r.current != nil {
+		return r.current, nil
+	}
+
+	provider, err := r.build(key)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("API key rotated", "provider", r.name)
+	r.current = provider
+	r.key = key
+	return provider, nil
+}
+
+// invalidate forces the next get to fetch the key again, e.g. after the
+// provider answered 401 Unauthorized.
+func (r *rotatingProvider) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stale = true
+}
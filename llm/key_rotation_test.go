@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestKeyProviderRotatesOnUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer key-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "ok"})
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	fetches := 0
+	source := config.KeyProviderFunc(func(ctx context.Context, provider string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		return fmt.Sprintf("key-%d", fetches), nil
+	})
+
+	l := newTestLLM(t, server.URL, config.SetMaxRetries(1), config.SetKeyProvider(source, 0))
+
+	response, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, 2, fetches)
+}
@@ -7,9 +7,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/teilomillet/gollm/config"
@@ -20,6 +24,11 @@ import (
 // LLM interface defines the methods that our internal language model should implement.
 // It provides a unified way to interact with different LLM providers while abstracting
 // away provider-specific details.
+//
+// Implementations returned by NewLLM are safe for concurrent use: a single instance
+// may serve many goroutines at once. Per-request settings (system prompt, sampling
+// overrides) travel with each call instead of being written to shared state, so
+// concurrent requests never observe each other's options.
 type LLM interface {
 	// Generate produces text based on the given prompt and options.
 	// Returns ErrorTypeRequest for request preparation failures,
@@ -38,8 +47,9 @@ type LLM interface {
 	// SupportsStreaming checks if the provider supports streaming responses.
 	SupportsStreaming() bool
 
-	// SetOption configures a provider-specific option.
-	// Returns ErrorTypeInvalidInput if the option is not supported.
+	// SetOption configures a provider-specific option used as a default for
+	// every subsequent request. Use WithOption to override an option for a
+	// single call without affecting concurrent requests.
 	SetOption(key string, value interface{})
 
 	// SetLogLevel adjusts the logging verbosity.
@@ -57,18 +67,35 @@ type LLM interface {
 
 	// SupportsJSONSchema checks if the provider supports JSON schema validation.
 	SupportsJSONSchema() bool
+
+	// SetModelCapabilities declares what the configured model supports,
+	// overriding the built-in detection, such as for fine-tuned or proxied
+	// models with custom names.
+	SetModelCapabilities(caps providers.ModelCapabilities)
 }
 
 // LLMImpl implements the LLM interface and manages interactions with specific providers.
 // It handles provider communication, error management, and logging.
+//
+// The provider is configured once at construction time and is only read afterwards.
+// Default options are guarded by a mutex and copied into a fresh map for every
+// request, which keeps LLMImpl safe for concurrent use.
 type LLMImpl struct {
 	Provider   providers.Provider     // The underlying LLM provider
-	Options    map[string]interface{} // Provider-specific options
+	Options    map[string]interface{} // Default provider-specific options; modify via SetOption
+	optionsMu  sync.RWMutex           // Guards Options and capabilities
 	client     *http.Client           // HTTP client for API requests
 	logger     utils.Logger           // Logger for debugging and monitoring
 	config     *config.Config         // Configuration settings
 	MaxRetries int                    // Maximum number of retry attempts
 	RetryDelay time.Duration          // Delay between retry attempts
+	breaker    *CircuitBreaker        // Circuit breaker shared per endpoint and config, nil when disabled
+	keyPool    *keyPool               // Pooled providers, one per API key; nil when disabled
+	rotator    *rotatingProvider      // Provider rebuilt on key rotation; nil without a KeyProvider
+	exporter   TraceExporter          // Receives a trace of every call; nil without observability
+
+	capabilities *providers.ModelCapabilities // Set by SetModelCapabilities; nil to detect them
+	fingerprint  fingerprintTracker           // Last system fingerprint, to warn when it changes
 }
 
 // GenerateOption is a function type for configuring generation behavior.
@@ -76,7 +103,108 @@ type GenerateOption func(*GenerateConfig)
 
 // GenerateConfig holds configuration options for text generation.
 type GenerateConfig struct {
-	UseJSONSchema bool // Whether to use JSON schema validation
+	UseJSONSchema  bool                   // Whether to use JSON schema validation
+	Options        map[string]interface{} // Per-request provider options overriding the defaults
+	Response       *Response              // Receives the response details, if set
+	DryRun         bool                   // Whether to return the prepared request instead of sending it
+	CostLookup     bool                   // Whether to fetch the generation's cost into Response.Metadata
+	Trace          *Trace                 // Receives a span for the call, if set
+	PostProcessors []PostProcessor        // Applied in order to the generated text
+	CleanStrategy  string                 // How the generated text is cleaned; defaults to the configured strategy
+	Validators     []ResponseValidator    // Check the generated text, which is regenerated once with feedback on failure
+	UsageTracker   *UsageTracker          // Records the call's usage, if set
+	Tags           []string               // Break the call's usage down in a UsageTracker
+
+	structured    *structuredFormat   // Set by WithStructuredResponseFormat
+	examples      *exampleSelection   // Set by WithExampleSelector
+	compression   *contextCompression // Set by WithContextCompression
+	autoMaxTokens *autoMaxTokens      // Set by WithAutoMaxTokens
+	defaulted     *[]string           // Set by WithDefaultedFields
+	schemaRef     *schemaReference    // Set by WithSchemaRef
+
+	contextFallback string     // Set by WithContextFallback
+	promptVersion   *PromptRef // Set by WithPromptVersion
+
+	lengthEnforcement LengthEnforcement // Set by WithLengthEnforcement
+
+	router           *ModelRouter      // Set by WithModelRouter
+	complexity       TaskComplexity    // Set by WithTaskComplexity
+	difficultyRouter *DifficultyRouter // Set by WithDifficultyRouter
+	routing          *RoutingDecision  // Decision of the difficulty router
+}
+
+// WithOption overrides a provider option (e.g., "temperature", "max_tokens")
+// for a single Generate call. Unlike SetOption, the override is scoped to the
+// request and never leaks into concurrent or subsequent calls.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithOption("temperature", 0.2))
+func WithOption(key string, value interface{}) GenerateOption {
+	return func(c *GenerateConfig) {
+		if c.Options == nil {
+			c.Options = make(map[string]interface{})
+		}
+		c.Options[key] = value
+	}
+}
+
+// WithUser identifies the end user a request is made for, so the provider's
+// abuse monitoring can act on that user rather than the whole account. Use
+// a stable, anonymized ID, such as a hash of the user's account ID. It is
+// sent as OpenAI's and OpenRouter's user field and Anthropic's
+// metadata.user_id; providers without an equivalent do not send it.
+func WithUser(id string) GenerateOption {
+	return WithOption("user", id)
+}
+
+// WithRequestMetadata attaches key-value metadata to a request, such as a
+// customer or feature name for per-customer analytics. It is sent as the
+// metadata field of OpenAI-compatible APIs. Anthropic only accepts a
+// "user_id" entry, which WithUser overrides, and providers without metadata
+// support do not send it.
+func WithRequestMetadata(metadata map[string]string) GenerateOption {
+	return func(c *GenerateConfig) {
+		merged := make(map[string]string, len(metadata))
+		if existing, ok := c.Options["metadata"].(map[string]string); ok {
+			for k, v := range existing {
+				merged[k] = v
+			}
+		}
+		for k, v := range metadata {
+			merged[k] = v
+		}
+		WithOption("metadata", merged)(c)
+	}
+}
+
+// WithProviderPreferences sets how OpenRouter routes the request between
+// the upstream providers serving the model. The preferences are validated
+// before the request is sent. Use it with the openrouter provider only.
+//
+// Example:
+//
+//	text, err := client.Generate(ctx, prompt, llm.WithProviderPreferences(providers.OpenRouterPreferences{
+//	    Order:          []string{"anthropic"},
+//	    DataCollection: providers.DataCollectionDeny,
+//	}))
+func WithProviderPreferences(prefs providers.OpenRouterPreferences) GenerateOption {
+	return WithOption("provider_preferences", prefs)
+}
+
+// WithSafetySettings sets the blocking thresholds of Gemini's safety filters
+// for a request, overriding those of config.SetSafetySettings per category.
+// Use it with the gemini provider only. The safety ratings Gemini returns
+// are reported in Response.Metadata under the "safety_ratings" key.
+//
+// Example:
+//
+//	text, err := client.Generate(ctx, prompt, llm.WithSafetySettings(config.SafetySetting{
+//	    Category:  config.HarmCategoryDangerousContent,
+//	    Threshold: config.BlockOnlyHigh,
+//	}))
+func WithSafetySettings(settings ...config.SafetySetting) GenerateOption {
+	return WithOption("safety_settings", settings)
 }
 
 // NewLLM creates a new LLM instance with the specified configuration.
@@ -87,45 +215,414 @@ type GenerateConfig struct {
 //   - ErrorTypeProvider if provider initialization fails
 //   - ErrorTypeAuthentication if API key validation fails
 func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.ProviderRegistry) (LLM, error) {
+	if cfg.SamplingProfile != "" {
+		profile, ok := config.LookupSamplingProfile(cfg.SamplingProfile)
+		if !ok {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "invalid sampling profile", fmt.Errorf("unknown profile %q", cfg.SamplingProfile))
+		}
+		cfg = withProfileTopK(cfg, profile)
+	}
+	if cfg.Reproducible {
+		cfg = reproducibleConfig(cfg, logger)
+	}
+
 	extraHeaders := make(map[string]string)
 	if cfg.Provider == "anthropic" && cfg.EnableCaching {
 		extraHeaders["anthropic-beta"] = "prompt-caching-2024-07-31"
 	}
+	if org := cfg.Organizations[cfg.Provider]; cfg.Provider == "openai" && org != "" {
+		extraHeaders["OpenAI-Organization"] = org
+	}
+	if project := cfg.Projects[cfg.Provider]; cfg.Provider == "openai" && project != "" {
+		extraHeaders["OpenAI-Project"] = project
+	}
 
-	// Check if API key is empty
-	apiKey := cfg.APIKeys[cfg.Provider]
-	if apiKey == "" {
-		return nil, NewLLMError(ErrorTypeAuthentication, "empty API key", nil)
+	buildProvider := func(apiKey string) (providers.Provider, error) {
+		provider, err := registry.Get(cfg.Provider, apiKey, cfg.Model, extraHeaders)
+		if err != nil {
+			return nil, err
+		}
+		provider.SetDefaultOptions(cfg)
+		applyEndpoint(provider, cfg)
+		return provider, nil
 	}
 
-	provider, err := registry.Get(cfg.Provider, apiKey, cfg.Model, extraHeaders)
+	var rotator *rotatingProvider
+	if cfg.KeyProvider != nil {
+		rotator = &rotatingProvider{
+			source:   cfg.KeyProvider,
+			name:     cfg.Provider,
+			interval: cfg.KeyRefreshInterval,
+			build:    buildProvider,
+			logger:   logger,
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	var provider providers.Provider
+	var err error
+	if rotator != nil {
+		provider, err = rotator.get(context.Background())
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeAuthentication, "failed to fetch API key", err)
+		}
+	} else {
+		// Check if API key is empty
+		apiKey := cfg.APIKeys[cfg.Provider]
+		if apiKey == "" {
+			return nil, NewLLMError(ErrorTypeAuthentication, "empty API key", nil)
+		}
+		if provider, err = buildProvider(apiKey); err != nil {
+			return nil, err
+		}
 	}
 
-	provider.SetDefaultOptions(cfg)
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid HTTP client configuration", err)
+	}
 
 	llmClient := &LLMImpl{
 		Provider:   provider,
-		client:     &http.Client{Timeout: cfg.Timeout},
+		client:     client,
 		logger:     logger,
 		config:     cfg,
 		MaxRetries: cfg.MaxRetries,
 		RetryDelay: cfg.RetryDelay,
 		Options:    make(map[string]interface{}),
+		rotator:    rotator,
+	}
+	if cfg.CircuitBreaker != nil {
+		llmClient.breaker = circuitBreakerFor(provider.Endpoint(), *cfg.CircuitBreaker)
+	}
+	if cfg.APIKeyPool != nil {
+		llmClient.keyPool, err = newKeyPool(cfg, registry, extraHeaders)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "invalid API key pool", err)
+		}
+	}
+	if _, err := CleanText("", cfg.CleanStrategy); err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid clean strategy", err)
+	}
+	switch cfg.SystemPromptPolicy {
+	case "", config.SystemPromptReplace, config.SystemPromptPrepend, config.SystemPromptAppend:
+	default:
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid system prompt policy", fmt.Errorf("unknown policy %q", cfg.SystemPromptPolicy))
+	}
+	if err := checkOptionSupport(cfg, logger); err != nil {
+		return nil, err
+	}
+	if cfg.SystemPrompt != "" {
+		llmClient.Options["system_prompt"] = cfg.SystemPrompt
+	}
+	if cfg.Observability != nil {
+		llmClient.exporter, err = newTraceExporter(*cfg.Observability, client)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "invalid observability configuration", err)
+		}
 	}
 
 	return llmClient, nil
 }
 
+// mergeSystemPrompt combines the system prompt of a Prompt with the default
+// one, set in the configuration or with SetOption("system_prompt", ...),
+// according to the configured policy.
+func (l *LLMImpl) mergeSystemPrompt(defaultPrompt interface{}, systemPrompt string) string {
+	base, _ := defaultPrompt.(string)
+	if base == "" || l.config == nil {
+		return systemPrompt
+	}
+	switch l.config.SystemPromptPolicy {
+	case config.SystemPromptPrepend:
+		return systemPrompt + "\n\n" + base
+	case config.SystemPromptAppend:
+		return base + "\n\n" + systemPrompt
+	default:
+		return systemPrompt
+	}
+}
+
+// applyEndpoint points the provider at a custom endpoint configured for it,
+// when the provider supports changing its endpoint.
+func applyEndpoint(provider providers.Provider, cfg *config.Config) {
+	endpoint := cfg.Endpoints[cfg.Provider]
+	if endpoint == "" {
+		return
+	}
+	if p, ok := provider.(providers.EndpointSetter); ok {
+		p.SetEndpoint(endpoint)
+	}
+}
+
+// NewHTTPClient builds the HTTP client used for provider calls, so that other
+// requests to the provider go through the same transport and proxy.
+// A client supplied via config.SetHTTPClient is used as-is unless a proxy is
+// configured, in which case a shallow copy with a cloned transport is returned
+// so the caller's client is never mutated.
+func NewHTTPClient(cfg *config.Config) (*http.Client, error) {
+	// The overall Timeout is enforced through the request context so that it
+	// spans retries; the client itself carries no timeout by default.
+	if cfg.HTTPClient == nil && cfg.ProxyURL == "" {
+		return &http.Client{}, nil
+	}
+
+	client := &http.Client{}
+	if cfg.HTTPClient != nil {
+		copied := *cfg.HTTPClient
+		client = &copied
+	}
+	if cfg.ProxyURL == "" {
+		return client, nil
+	}
+
+	proxyURL, err := url.Parse(cfg.ProxyURL)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+	}
+
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("cannot apply proxy to custom transport of type %T", client.Transport)
+	}
+	transport.Proxy = http.ProxyURL(proxyURL)
+	client.Transport = transport
+	return client, nil
+}
+
 // SetOption sets a provider-specific option with the given key and value.
 // The option is logged at debug level for troubleshooting.
+// It is safe to call concurrently with Generate, but the new value only applies
+// to requests started after the call returns.
 func (l *LLMImpl) SetOption(key string, value interface{}) {
+	l.optionsMu.Lock()
 	l.Options[key] = value
+	l.optionsMu.Unlock()
 	l.logger.Debug("Option set", key, value)
 }
 
+// requestOptions builds the option map for a single request. It starts from a
+// snapshot of the default options, adds prompt-scoped values such as the system
+// prompt, and finally applies per-request overrides. The returned map is owned
+// by the caller, so providers may modify it freely.
+func (l *LLMImpl) requestOptions(prompt *Prompt, cfg *GenerateConfig) map[string]interface{} {
+	l.optionsMu.RLock()
+	options := make(map[string]interface{}, len(l.Options)+4)
+	for k, v := range l.Options {
+		options[k] = v
+	}
+	l.optionsMu.RUnlock()
+
+	if prompt != nil && prompt.SystemPrompt != "" {
+		options["system_prompt"] = l.mergeSystemPrompt(options["system_prompt"], prompt.SystemPrompt)
+	}
+	if prompt != nil && len(prompt.Attachments) > 0 {
+		options["attachments"] = prompt.Attachments
+	}
+	if prompt != nil && prompt.ResponsePrefill != "" {
+		options["response_prefill"] = prompt.ResponsePrefill
+	}
+	if prompt != nil && prompt.Grammar != "" {
+		options[string(providers.ConstraintGrammar)] = prompt.Grammar
+	}
+	if prompt != nil && prompt.RegexConstraint != "" {
+		options[string(providers.ConstraintRegex)] = prompt.RegexConstraint
+	}
+	if prompt != nil && prompt.ReasoningEffort != "" {
+		options["reasoning_effort"] = prompt.ReasoningEffort
+	}
+	if prompt != nil && len(prompt.Tools) > 0 {
+		options["tools"] = prompt.Tools
+	}
+	if prompt != nil && len(prompt.ToolChoice) > 0 {
+		options["tool_choice"] = prompt.ToolChoice
+	}
+	if cfg != nil {
+		for k, v := range cfg.Options {
+			options[k] = v
+		}
+	}
+	return options
+}
+
+// withProfileTopK returns a copy of cfg with the top-k of its sampling
+// profile, when the provider supports top-k sampling and SetTopK was not used.
+func withProfileTopK(cfg *config.Config, profile config.SamplingProfile) *config.Config {
+	if profile.TopK == 0 || cfg.TopK != nil || !providers.SupportsOption(cfg.Provider, providers.OptionTopK) {
+		return cfg
+	}
+	profiled := *cfg
+	profiled.TopK = &profile.TopK
+	return &profiled
+}
+
+// checkOptionSupport checks the sampling options set in cfg against the
+// option support matrix. Options the provider ignores are reported with a
+// warning, or an ErrUnsupportedOption error when cfg.StrictOptions is set.
+func checkOptionSupport(cfg *config.Config, logger utils.Logger) error {
+	set := map[string]bool{
+		providers.OptionSeed: cfg.Seed != nil,
+		providers.OptionTopP: cfg.TopPSet(),
+		providers.OptionTopK: cfg.TopK != nil,
+	}
+	for _, option := range providers.SamplingOptions {
+		if !set[option] || providers.SupportsOption(cfg.Provider, option) {
+			continue
+		}
+		if cfg.StrictOptions {
+			return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("%s not supported by provider %s", option, cfg.Provider), ErrUnsupportedOption)
+		}
+		logger.Warn("Option not supported by provider, ignoring it", "option", option, "provider", cfg.Provider)
+	}
+	return nil
+}
+
+// checkPromptSupport returns an ErrorTypeUnsupported error when prompt uses
+// a feature that provider, or the model of capabilities caps, cannot send,
+// and an ErrorTypeInvalidInput error when its images exceed the provider's
+// limits. caps is nil when the model's capabilities are unknown.
+func checkPromptSupport(provider providers.Provider, caps *providers.ModelCapabilities, prompt *Prompt) error {
+	if err := checkPrefill(provider, prompt); err != nil {
+		return err
+	}
+	if err := checkCapabilities(provider, caps, prompt); err != nil {
+		return err
+	}
+	if p, ok := provider.(providers.ImageLimitsProvider); ok && prompt != nil {
+		if err := prompt.CheckImageLimits(p.ImageLimits()); err != nil {
+			return err
+		}
+	}
+	return checkConstraints(provider, prompt)
+}
+
+// checkCapabilities returns an ErrorTypeUnsupported error when prompt has
+// tools or images and the model of capabilities caps does not support them.
+func checkCapabilities(provider providers.Provider, caps *providers.ModelCapabilities, prompt *Prompt) error {
+	if caps == nil || prompt == nil {
+		return nil
+	}
+	if len(prompt.Tools) > 0 && !caps.Tools {
+		return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("tools not supported by the model of provider %s", provider.Name()), nil)
+	}
+	if !caps.Vision {
+		for _, a := range append(prompt.messageAttachments(), prompt.Attachments...) {
+			if a.Type == utils.AttachmentImage {
+				return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("images not supported by the model of provider %s", provider.Name()), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConstraints returns an ErrorTypeUnsupported error when prompt has a
+// decoding constraint that provider cannot enforce.
+func checkConstraints(provider providers.Provider, prompt *Prompt) error {
+	if prompt == nil {
+		return nil
+	}
+	for kind, value := range map[providers.ConstraintKind]string{
+		providers.ConstraintGrammar: prompt.Grammar,
+		providers.ConstraintRegex:   prompt.RegexConstraint,
+	} {
+		if value == "" {
+			continue
+		}
+		if p, ok := provider.(providers.ConstraintProvider); !ok || !p.SupportsConstraint(kind) {
+			return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("%s constraints not supported by provider %s", kind, provider.Name()), nil)
+		}
+	}
+	return nil
+}
+
+// checkReasoningEffort returns an ErrorTypeUnsupported error when prompt has
+// a reasoning effort and the model of the request, from its options or the
+// configuration, does not take one.
+func (l *LLMImpl) checkReasoningEffort(provider providers.Provider, prompt *Prompt, options map[string]interface{}) error {
+	if prompt == nil || prompt.ReasoningEffort == "" {
+		return nil
+	}
+	if !prompt.ReasoningEffort.Valid() {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid reasoning effort %q: must be low, medium or high", prompt.ReasoningEffort), nil)
+	}
+	model, _ := options["model"].(string)
+	if model == "" && l.config != nil {
+		model = l.config.Model
+	}
+	if p, ok := provider.(providers.ReasoningEffortProvider); !ok || !p.SupportsReasoningEffort(model) {
+		return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("reasoning effort not supported by model %s of provider %s", model, provider.Name()), nil)
+	}
+	return nil
+}
+
+// checkPrefill returns an ErrorTypeUnsupported error when prompt has a
+// response prefill that provider cannot send.
+func checkPrefill(provider providers.Provider, prompt *Prompt) error {
+	if prompt == nil || prompt.ResponsePrefill == "" {
+		return nil
+	}
+	if p, ok := provider.(providers.PrefillProvider); ok && p.SupportsPrefill() {
+		return nil
+	}
+	return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("response prefill not supported by provider %s", provider.Name()), nil)
+}
+
+// withPrefill returns the full reply for a prefilled response. Models that
+// continue the prefill return only the continuation; others restate it.
+func withPrefill(prefill, result string) string {
+	if prefill == "" || strings.HasPrefix(result, prefill) {
+		return result
+	}
+	return prefill + result
+}
+
+// promptText renders prompt for provider. Providers with native document
+// support receive the prompt's documents as the "documents" option instead
+// of as part of the text, and providers with native message support receive
+// its conversation history as the "messages" option. Providers that place
+// cache breakpoints receive the text before the input as the "cache_prefix"
+// option.
+func promptText(provider providers.Provider, prompt *Prompt, options map[string]interface{}) string {
+	if history := prompt.history(); len(history) > 0 {
+		if mp, ok := provider.(providers.MessageProvider); ok && mp.SupportsMessages() {
+			options["messages"] = history
+			prompt = prompt.withoutHistory()
+		} else if attachments := prompt.messageAttachments(); len(attachments) > 0 {
+			// The flattened messages keep their attachments with the prompt
+			options["attachments"] = append(append([]utils.Attachment(nil), prompt.Attachments...), attachments...)
+		}
+	}
+	if dp, ok := provider.(providers.DocumentProvider); ok && dp.SupportsDocuments() && len(prompt.Documents) > 0 {
+		options["documents"] = prompt.Documents
+		withoutDocs := *prompt
+		withoutDocs.Documents = nil
+		prompt = &withoutDocs
+	}
+	if cp, ok := provider.(providers.CacheBreakpointProvider); ok && cp.SupportsCacheBreakpoints() {
+		if prefix := prompt.stablePrefix(); prefix != "" {
+			options["cache_prefix"] = prefix
+		}
+	}
+	return prompt.String()
+}
+
+// requestTarget returns the provider to send a request with options to: the
+// provider itself, or its message endpoint when the request carries a
+// conversation history. It must be called before the provider consumes the
+// options.
+func requestTarget(provider providers.Provider, options map[string]interface{}) providers.Provider {
+	if _, ok := options["messages"]; ok {
+		if p, ok := provider.(providers.MessageEndpointProvider); ok {
+			return endpointProvider{provider, p.MessageEndpoint()}
+		}
+	}
+	return provider
+}
+
 // SetEndpoint updates the API endpoint for the provider.
 // This is primarily used for local models like Ollama.
 func (l *LLMImpl) SetEndpoint(endpoint string) {
@@ -150,44 +647,190 @@ func (l *LLMImpl) NewPrompt(prompt string) *Prompt {
 }
 
 // SupportsJSONSchema checks if the current provider supports JSON schema validation.
+// Capabilities set with SetModelCapabilities take precedence over the provider's.
 func (l *LLMImpl) SupportsJSONSchema() bool {
+	l.optionsMu.RLock()
+	caps := l.capabilities
+	l.optionsMu.RUnlock()
+	if caps != nil {
+		return caps.JSONSchema
+	}
 	return l.Provider.SupportsJSONSchema()
 }
 
+// SetModelCapabilities declares what the configured model supports. Names
+// the built-in detection does not know, such as those of fine-tuned models
+// ("ft:gpt-4o-mini-2024-07-18:acme::9xyz") or of models behind a proxy, may
+// otherwise be assumed to support native JSON schemas they reject, or not to
+// support them. The declared capabilities decide whether structured output
+// uses the native JSON schema support, and prompts with tools or images fail
+// with ErrorTypeUnsupported before they are sent to a model without them.
+//
+// Example:
+//
+//	client.SetModelCapabilities(providers.ModelCapabilities{Tools: true, JSONMode: true})
+func (l *LLMImpl) SetModelCapabilities(caps providers.ModelCapabilities) {
+	l.optionsMu.Lock()
+	l.capabilities = &caps
+	l.optionsMu.Unlock()
+	l.logger.Debug("Model capabilities set", "capabilities", caps)
+}
+
+// modelCapabilities returns the capabilities of the configured model, from
+// SetModelCapabilities or LookupModelCapabilities, or nil when they are
+// unknown.
+func (l *LLMImpl) modelCapabilities() *providers.ModelCapabilities {
+	l.optionsMu.RLock()
+	caps := l.capabilities
+	l.optionsMu.RUnlock()
+	if caps != nil || l.config == nil {
+		return caps
+	}
+	if found, ok := providers.LookupModelCapabilities(l.Provider.Name(), l.config.Model); ok {
+		return &found
+	}
+	return nil
+}
+
 // Generate produces text based on the given prompt and options.
 // It handles retries, logging, and error management.
 //
+// The configured Timeout bounds the whole call including retries, while
+// AttemptTimeout (if set) gives every attempt its own fresh budget.
+//
 // Returns:
 //   - Generated text response
 //   - ErrorTypeRequest for request preparation failures
 //   - ErrorTypeAPI for provider API errors
 //   - ErrorTypeResponse for response processing issues
 //   - ErrorTypeRateLimit if provider rate limit is exceeded
+//   - ErrorTypeTimeout if the attempt or overall timeout fired
 func (l *LLMImpl) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
 	config := &GenerateConfig{}
 	for _, opt := range opts {
 		opt(config)
 	}
-	// Set the system prompt in the LLM's options
-	if prompt.SystemPrompt != "" {
-		l.SetOption("system_prompt", prompt.SystemPrompt)
+	strategy, err := l.cleanStrategy(config)
+	if err != nil {
+		return "", err
+	}
+	config.CleanStrategy = strategy
+	if config.examples != nil {
+		if prompt, err = config.examples.apply(ctx, prompt); err != nil {
+			return "", err
+		}
+	}
+	if config.compression != nil {
+		if prompt, err = config.compression.apply(ctx, prompt); err != nil {
+			return "", err
+		}
+	}
+	if config.structured != nil {
+		if prompt, err = config.structured.apply(prompt, config); err != nil {
+			return "", err
+		}
+	}
+	if config.schemaRef != nil {
+		if prompt, err = config.schemaRef.apply(prompt, config); err != nil {
+			return "", err
+		}
+	}
+	applyLengthEnforcement(prompt, config)
+	l.routeByDifficulty(ctx, prompt, config)
+
+	span := l.startGenerationSpan(ctx, "generate", prompt, config)
+	tracker := startUsageTracking(ctx, config)
+	start := time.Now()
+	var timing Timing
+	generate := func(prompt *Prompt) (string, error) {
+		return l.withRetries(ctx, "failed to generate", func(ctx context.Context, attempt int) (string, error) {
+			l.logger.Debug("Generating text", "provider", l.Provider.Name(), "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt+1)
+			if attempt > 0 {
+				timing.Retries++
+			}
+			queued := time.Now()
+			provider, release, err := l.acquireProvider(ctx)
+			timing.QueueTime = time.Since(queued)
+			if err != nil {
+				return "", err
+			}
+			defer release()
+			// Pass the entire Prompt struct to attemptGenerate
+			return l.attemptGenerate(ctx, provider, prompt, config)
+		})
+	}
+	result, err := generate(prompt)
+	if err == nil && len(config.Validators) > 0 && !config.DryRun {
+		result, err = l.withValidation(prompt, config, result, generate)
+	}
+	if err == nil && config.Response != nil {
+		timing.Duration = time.Since(start)
+		config.Response.Timing = timing
+	}
+	l.endGenerationSpan(ctx, span, config.Response, result, err)
+	if tracker != nil && err == nil && !config.DryRun {
+		tracker.Record(config.Response.Provider, config.Response.Model, config.Response.Usage, config.Tags...)
+	}
+	return result, err
+}
+
+// withRetries runs fn until it succeeds or the retry budget is exhausted.
+// The overall Timeout is applied once around all attempts, and AttemptTimeout
+// is applied to each attempt individually. Timeout errors are wrapped with
+// ErrOverallTimeout or ErrAttemptTimeout so callers can tell which limit fired.
+func (l *LLMImpl) withRetries(ctx context.Context, failureMsg string, fn func(ctx context.Context, attempt int) (string, error)) (string, error) {
+	if l.config != nil && l.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, l.config.Timeout, ErrOverallTimeout)
+		defer cancel()
 	}
+
+	var lastErr error
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		l.logger.Debug("Generating text", "provider", l.Provider.Name(), "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt+1)
-		// Pass the entire Prompt struct to attemptGenerate
-		result, err := l.attemptGenerate(ctx, prompt)
+		result, err := l.runAttempt(ctx, attempt, fn)
 		if err == nil {
 			return result, nil
 		}
+		if ctx.Err() != nil {
+			return "", l.contextError(ctx, err)
+		}
+		if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrContentFiltered) {
+			return "", err
+		}
+		lastErr = err
 		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt+1)
 		if attempt < l.MaxRetries {
 			l.logger.Debug("Retrying", "delay", l.RetryDelay)
 			if err := l.wait(ctx); err != nil {
-				return "", err
+				return "", l.contextError(ctx, lastErr)
 			}
 		}
 	}
-	return "", fmt.Errorf("failed to generate after %d attempts", l.MaxRetries+1)
+	return "", fmt.Errorf("%s after %d attempts: %w", failureMsg, l.MaxRetries+1, lastErr)
+}
+
+// runAttempt executes a single attempt, bounded by AttemptTimeout when configured.
+func (l *LLMImpl) runAttempt(ctx context.Context, attempt int, fn func(ctx context.Context, attempt int) (string, error)) (string, error) {
+	if l.config == nil || l.config.AttemptTimeout <= 0 {
+		return fn(ctx, attempt)
+	}
+
+	attemptCtx, cancel := context.WithTimeoutCause(ctx, l.config.AttemptTimeout, ErrAttemptTimeout)
+	defer cancel()
+	result, err := fn(attemptCtx, attempt)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(attemptCtx), ErrAttemptTimeout) {
+		return "", NewLLMError(ErrorTypeTimeout, fmt.Sprintf("attempt %d exceeded timeout of %s", attempt+1, l.config.AttemptTimeout), fmt.Errorf("%w: %w", ErrAttemptTimeout, err))
+	}
+	return result, err
+}
+
+// contextError converts a failure caused by the call's context into an error
+// that identifies whether gollm's overall Timeout or the caller's context ended it.
+func (l *LLMImpl) contextError(ctx context.Context, err error) error {
+	if errors.Is(context.Cause(ctx), ErrOverallTimeout) {
+		return NewLLMError(ErrorTypeTimeout, fmt.Sprintf("overall timeout of %s exceeded", l.config.Timeout), fmt.Errorf("%w: %w", ErrOverallTimeout, err))
+	}
+	return fmt.Errorf("%w: %w", ctx.Err(), err)
 }
 
 // wait implements a cancellable delay between retry attempts.
@@ -201,94 +844,270 @@ func (l *LLMImpl) wait(ctx context.Context) error {
 	}
 }
 
-// attemptGenerate makes a single attempt to generate text using the provider.
-// It handles request preparation, API communication, and response processing.
-//
-// Returns:
-//   - Generated text response
-//   - ErrorTypeRequest for request preparation failures
-//   - ErrorTypeAPI for provider API errors
-//   - ErrorTypeResponse for response processing issues
-//   - ErrorTypeRateLimit if provider rate limit is exceeded
-func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string, error) {
-	// Create a new options map that includes both l.Options and prompt-specific options
-	options := make(map[string]interface{})
-	for k, v := range l.Options {
-		options[k] = v
+// acquireProvider returns the provider instance to use for one attempt along
+// with a release function. With an API key pool, a key is selected according
+// to the pool strategy and its rate limit is honoured before returning; with a
+// KeyProvider, the provider bound to the current key is returned. Otherwise
+// this is always l.Provider.
+func (l *LLMImpl) acquireProvider(ctx context.Context) (providers.Provider, func(), error) {
+	if l.keyPool == nil {
+		if l.rotator != nil {
+			provider, err := l.rotator.get(ctx)
+			if err != nil {
+				return nil, nil, NewLLMError(ErrorTypeAuthentication, "failed to fetch API key", err)
+			}
+			return provider, func() {}, nil
+		}
+		return l.Provider, func() {}, nil
+	}
+	entry, err := l.keyPool.acquire(ctx)
+	if err != nil {
+		return nil, nil, NewLLMError(ErrorTypeRateLimit, "failed to acquire API key from pool", err)
 	}
+	return entry.provider, entry.release, nil
+}
 
-	// Add Tools and ToolChoice to options
-	if len(prompt.Tools) > 0 {
-		options["tools"] = prompt.Tools
+// newRequest creates an HTTP request for the provider endpoint carrying the
+// given body and all provider headers, then applies the before-request hooks.
+func (l *LLMImpl) newRequest(ctx context.Context, provider providers.Provider, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", provider.Endpoint(), nil)
+	if err != nil {
+		return nil, err
 	}
-	if len(prompt.ToolChoice) > 0 {
-		options["tool_choice"] = prompt.ToolChoice
+	for k, v := range provider.Headers() {
+		req.Header.Set(k, v)
 	}
+	for _, hook := range l.config.BeforeRequestHooks {
+		if body, err = hook(req, body); err != nil {
+			return nil, fmt.Errorf("before-request hook: %w", err)
+		}
+	}
+	setRequestBody(req, body)
+	return req, nil
+}
 
-	// Prepare the request with both the user prompt and the combined options
-	reqBody, err := l.Provider.PrepareRequest(prompt.String(), options)
-	if err != nil {
-		return "", NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
+// setRequestBody attaches body to req so that it can be replayed on redirects.
+func setRequestBody(req *http.Request, body []byte) {
+	req.ContentLength = int64(len(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
 	}
-	l.logger.Debug("Full request body", "body", string(reqBody))
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
-	if err != nil {
-		return "", NewLLMError(ErrorTypeRequest, "failed to create request", err)
+}
+
+// afterResponse runs the after-response hooks over a received response body.
+func (l *LLMImpl) afterResponse(resp *http.Response, body []byte) ([]byte, error) {
+	var err error
+	for _, hook := range l.config.AfterResponseHooks {
+		if body, err = hook(resp, body); err != nil {
+			return nil, NewLLMError(ErrorTypeResponse, "after-response hook failed", err)
+		}
 	}
+	return body, nil
+}
 
-	l.logger.Debug("Full API request", "method", req.Method, "url", req.URL.String(), "headers", req.Header, "body", string(reqBody))
-	for k, v := range l.Provider.Headers() {
-		req.Header.Set(k, v)
-		l.logger.Debug("Request header", "provider", l.Provider.Name(), "key", k, "value", v)
+// sendRequest posts a prepared request body to the provider and returns the
+// response body. Non-200 responses are reported as ErrorTypeAPI.
+func (l *LLMImpl) sendRequest(ctx context.Context, provider providers.Provider, reqBody []byte) ([]byte, error) {
+	body, _, err := l.sendRequestHeader(ctx, provider, reqBody)
+	return body, err
+}
+
+// sendRequestHeader is sendRequest, also returning the response headers.
+func (l *LLMImpl) sendRequestHeader(ctx context.Context, provider providers.Provider, reqBody []byte) ([]byte, http.Header, error) {
+	req, err := l.newRequest(ctx, provider, reqBody)
+	if err != nil {
+		return nil, nil, NewLLMError(ErrorTypeRequest, "failed to create request", err)
 	}
-	resp, err := l.client.Do(req)
+	l.logger.Debug("Full API request", "method", req.Method, "url", req.URL.String(), "headers", req.Header, "body", string(reqBody))
+
+	resp, err := l.do(ctx, req)
 	if err != nil {
-		return "", NewLLMError(ErrorTypeRequest, "failed to send request", err)
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", NewLLMError(ErrorTypeResponse, "failed to read response body", err)
+		return nil, nil, NewLLMError(ErrorTypeResponse, "failed to read response body", err)
+	}
+	if body, err = l.afterResponse(resp, body); err != nil {
+		return nil, nil, err
 	}
 
 	// Log the full API response
 	l.logger.Debug("Full API response", "body", string(body))
 
+	if err := contentFilterError(l.Provider.Name(), body); err != nil {
+		l.logger.Warn("Content filtered", "provider", l.Provider.Name(), "status", resp.StatusCode, "error", err)
+		return nil, nil, err
+	}
 	if resp.StatusCode != http.StatusOK {
 		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode, "body", string(body))
-		return "", NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
-	}
-
-	// Extract and log caching information
-	var fullResponse map[string]interface{}
-	if err := json.Unmarshal(body, &fullResponse); err != nil {
-		// Try parsing as JSONL if JSON parsing fails
-		lines := bytes.Split(bytes.TrimSpace(body), []byte("\n"))
-		if len(lines) > 0 {
-			// the last line is *usually* the full response
-			if err := json.Unmarshal(lines[len(lines)-1], &fullResponse); err != nil {
-				l.logger.Warn("Failed to parse response as both JSON and JSONL", "error", err)
-			}
+		return nil, nil, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
+	}
+	return body, resp.Header, nil
+}
+
+// do sends req through the HTTP client, consulting the circuit breaker first
+// and reporting the outcome to it afterwards. Transport failures, timeouts,
+// 429 and 5xx responses count as failures; caller cancellations do not.
+func (l *LLMImpl) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if l.breaker != nil {
+		if err := l.breaker.Allow(); err != nil {
+			l.logger.Warn("Circuit open, failing fast", "provider", l.Provider.Name(), "endpoint", req.URL.String())
+			return nil, NewLLMError(ErrorTypeProvider, "circuit open for "+l.Provider.Name(), err)
 		}
 	}
 
-	// Process usage information regardless of format
-	if usage, ok := fullResponse["usage"].(map[string]interface{}); ok {
-		l.logger.Debug("Usage information", "usage", usage)
-		cacheInfo := map[string]interface{}{
-			"cache_creation_input_tokens": usage["cache_creation_input_tokens"],
-			"cache_read_input_tokens":     usage["cache_read_input_tokens"],
+	resp, err := l.client.Do(req)
+	if l.breaker != nil {
+		switch {
+		case err != nil && ctx.Err() != nil && !errors.Is(context.Cause(ctx), ErrAttemptTimeout):
+			l.breaker.release()
+		case err != nil, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+			l.breaker.RecordFailure()
+		default:
+			l.breaker.RecordSuccess()
+		}
+	}
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized && l.rotator != nil {
+		// The key may have been rotated; fetch it again on the next attempt
+		l.rotator.invalidate()
+	}
+	return resp, nil
+}
+
+// attemptGenerate makes a single attempt to generate text using the provider.
+// It handles request preparation, API communication, and response processing.
+//
+// Returns:
+//   - Generated text response
+//   - ErrorTypeRequest for request preparation failures
+//   - ErrorTypeAPI for provider API errors
+//   - ErrorTypeResponse for response processing issues
+//   - ErrorTypeRateLimit if provider rate limit is exceeded
+func (l *LLMImpl) attemptGenerate(ctx context.Context, provider providers.Provider, prompt *Prompt, cfg *GenerateConfig) (string, error) {
+	// Build a request-scoped options map from the defaults and prompt-specific options
+	options := l.requestOptions(prompt, cfg)
+	if err := checkPromptSupport(provider, l.modelCapabilities(), prompt); err != nil {
+		return "", err
+	}
+
+	// Prepare the request with both the user prompt and the combined options
+	text := promptText(provider, prompt, options)
+	var model string
+	if l.config != nil {
+		model = l.config.Model
+	}
+	routing, err := l.routeModel(cfg, text, options)
+	if err != nil {
+		return "", err
+	}
+	if override, ok := options["model"].(string); ok && override != "" {
+		model = override
+	}
+	substitution := l.substituteModel(cfg, model, text, options)
+	if substitution != nil {
+		model = substitution.To
+	}
+	if cfg != nil && cfg.autoMaxTokens != nil && l.config != nil {
+		if err := cfg.autoMaxTokens.apply(model, text, options); err != nil {
+			return "", err
+		}
+	}
+	capMaxTokens(prompt, cfg, options)
+	if err := l.checkReasoningEffort(provider, prompt, options); err != nil {
+		return "", err
+	}
+	target := requestTarget(provider, options)
+	reqBody, err := provider.PrepareRequest(text, options)
+	if err != nil {
+		return "", NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
+	}
+	l.logger.Debug("Full request body", "body", string(reqBody))
+	if cfg != nil && cfg.DryRun {
+		return l.dryRun(ctx, target, reqBody)
+	}
+
+	body, header, err := l.sendRequestHeader(ctx, target, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	// Log usage, including prompt caching information, when the response has it
+	usage, ok := ParseUsage(body)
+	if parser, isParser := provider.(providers.CostHeaderParser); isParser {
+		if cost, reported := parser.ParseCostHeader(header); reported {
+			usage.Cost = cost
+			ok = true
 		}
-		l.logger.Debug("Cache information", "info", cacheInfo)
+	}
+	if ok {
+		l.logger.Debug("Usage information", "usage", usage)
 	} else {
-		l.logger.Debug("Cache information not available in the response")
+		l.logger.Debug("Usage information not available in the response")
 	}
 
-	result, err := l.Provider.ParseResponse(body)
+	result, err := provider.ParseResponse(body)
 	if err != nil {
 		return "", NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	result = withPrefill(prompt.ResponsePrefill, result)
 	l.logger.Debug("Text generated successfully", "result", result)
+	fingerprint := parseSystemFingerprint(body)
+	l.checkFingerprint(fingerprint)
+	raw := result
+	if cfg != nil {
+		if result, err = CleanText(result, cfg.CleanStrategy); err != nil {
+			return "", NewLLMError(ErrorTypeInvalidInput, "invalid clean strategy", err)
+		}
+		if result, err = postProcess(result, cfg.PostProcessors); err != nil {
+			return "", err
+		}
+	}
+
+	if cfg != nil && cfg.Response != nil {
+		*cfg.Response = Response{Content: result, Raw: raw, Provider: provider.Name(), Model: model, Usage: usage, SystemFingerprint: fingerprint}
+		if parser, ok := provider.(providers.MetadataParser); ok {
+			cfg.Response.Metadata = parser.ParseMetadata(body)
+		}
+		if substitution != nil {
+			if cfg.Response.Metadata == nil {
+				cfg.Response.Metadata = make(map[string]interface{})
+			}
+			cfg.Response.Metadata["model_substitution"] = *substitution
+		}
+		if cfg.promptVersion != nil {
+			if cfg.Response.Metadata == nil {
+				cfg.Response.Metadata = make(map[string]interface{})
+			}
+			cfg.Response.Metadata["prompt_version"] = *cfg.promptVersion
+		}
+		if routing != nil {
+			if cfg.Response.Metadata == nil {
+				cfg.Response.Metadata = make(map[string]interface{})
+			}
+			cfg.Response.Metadata["routing"] = *routing
+		}
+		if cfg.CostLookup {
+			l.lookupGenerationStats(ctx, provider, body, cfg.Response)
+		}
+		if parser, ok := provider.(providers.ReasoningParser); ok {
+			cfg.Response.Reasoning = parser.ParseReasoning(body)
+		}
+		if parser, ok := provider.(providers.ToolCallParser); ok {
+			calls, err := parser.ParseToolCalls(body)
+			if err != nil {
+				return "", NewLLMError(ErrorTypeResponse, "failed to parse tool calls", err)
+			}
+			for _, call := range calls {
+				cfg.Response.ToolCalls = append(cfg.Response.ToolCalls, newToolCall(call.ID, call.Name, call.Arguments))
+			}
+		}
+	}
 	return result, nil
 }
 
@@ -305,31 +1124,26 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 		opt(config)
 	}
 
-	var result string
-	var lastErr error
-
-	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
+	span := l.startGenerationSpan(ctx, "generate_with_schema", prompt, config)
+	result, err := l.withRetries(ctx, "failed to generate with schema", func(ctx context.Context, attempt int) (string, error) {
 		l.logger.Debug("Generating text with schema", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt+1)
-
-		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt.String(), schema)
-		if lastErr == nil {
-			return result, nil
+		provider, release, err := l.acquireProvider(ctx)
+		if err != nil {
+			return "", err
 		}
-
-		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt+1)
-
-		if attempt < l.MaxRetries {
-			l.logger.Debug("Retrying", "delay", l.RetryDelay)
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(l.RetryDelay):
-				// Continue to next attempt
-			}
+		defer release()
+		options := l.requestOptions(prompt, config)
+		if err := checkPromptSupport(provider, l.modelCapabilities(), prompt); err != nil {
+			return "", err
 		}
-	}
-
-	return "", fmt.Errorf("failed to generate with schema after %d attempts: %w", l.MaxRetries+1, lastErr)
+		if err := l.checkReasoningEffort(provider, prompt, options); err != nil {
+			return "", err
+		}
+		result, _, err := l.attemptGenerateWithSchema(ctx, provider, promptText(provider, prompt, options), schema, options, config.DryRun)
+		return result, err
+	})
+	l.endGenerationSpan(ctx, span, config.Response, result, err)
+	return result, err
 }
 
 // attemptGenerateWithSchema makes a single attempt to generate text using the provider and a JSON schema.
@@ -340,17 +1154,31 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 //   - Full prompt used for generation
 //   - ErrorTypeInvalidInput for schema validation failures
 //   - Other error types as per attemptGenerate
-func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string, schema interface{}) (string, string, error) {
+func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, provider providers.Provider, prompt string, schema interface{}, options map[string]interface{}, dryRun bool) (string, string, error) {
 	var reqBody []byte
 	var err error
 	var fullPrompt string
+	// Providers consume the prefill and messages options while building the request
+	prefill, _ := options["response_prefill"].(string)
+	target := requestTarget(provider, options)
+	mode, _ := options["json_mode"].(providers.JSONMode)
+	if mode != "" {
+		if p, ok := provider.(providers.JSONModeProvider); !ok || !p.SupportsJSONMode(mode) {
+			return "", prompt, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("JSON mode %s not supported by %s", mode, provider.Name()), nil)
+		}
+	}
 
-	if l.SupportsJSONSchema() {
-		reqBody, err = l.Provider.PrepareRequestWithSchema(prompt, l.Options, schema)
+	switch {
+	case mode == providers.JSONModeObject:
+		// JSON mode does not enforce the schema, so the model must be told it
+		fullPrompt = l.preparePromptWithSchema(prompt, schema)
+		reqBody, err = provider.PrepareRequestWithSchema(fullPrompt, options, schema)
+	case mode == providers.JSONModeSchema || l.SupportsJSONSchema():
+		reqBody, err = provider.PrepareRequestWithSchema(prompt, options, schema)
 		fullPrompt = prompt
-	} else {
+	default:
 		fullPrompt = l.preparePromptWithSchema(prompt, schema)
-		reqBody, err = l.Provider.PrepareRequest(fullPrompt, l.Options)
+		reqBody, err = provider.PrepareRequest(fullPrompt, options)
 	}
 
 	if err != nil {
@@ -358,40 +1186,25 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string,
 	}
 
 	l.logger.Debug("Request body", "provider", l.Provider.Name(), "body", string(reqBody))
-
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(reqBody))
-	if err != nil {
-		return "", fullPrompt, NewLLMError(ErrorTypeRequest, "failed to create request", err)
-	}
-
-	for k, v := range l.Provider.Headers() {
-		req.Header.Set(k, v)
+	if dryRun {
+		result, err := l.dryRun(ctx, target, reqBody)
+		return result, fullPrompt, err
 	}
 
-	resp, err := l.client.Do(req)
+	body, err := l.sendRequest(ctx, target, reqBody)
 	if err != nil {
-		return "", fullPrompt, NewLLMError(ErrorTypeRequest, "failed to send request", err)
+		return "", fullPrompt, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "failed to read response body", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode, "body", string(body))
-		return "", fullPrompt, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
-	}
-
-	result, err := l.Provider.ParseResponse(body)
+	result, err := provider.ParseResponse(body)
 	if err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	result = withPrefill(prefill, result)
 
 	// Validate the result against the schema
 	if err := ValidateAgainstSchema(result, schema); err != nil {
-		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "response does not match schema", err)
+		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "response does not match schema", &SchemaMismatchError{Response: result, Err: err})
 	}
 
 	l.logger.Debug("Text generated successfully", "result", result)
@@ -411,6 +1224,7 @@ func (l *LLMImpl) preparePromptWithSchema(prompt string, schema interface{}) str
 }
 
 // Stream initiates a streaming response from the LLM.
+// The stream is bounded by ctx only; the configured Timeout applies to Generate calls.
 func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOption) (TokenStream, error) {
 	if !l.SupportsStreaming() {
 		return nil, NewLLMError(ErrorTypeUnsupported, "streaming not supported by provider", nil)
@@ -430,41 +1244,67 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	}
 
 	// Prepare request with streaming enabled
-	options := make(map[string]interface{})
-	for k, v := range l.Options {
-		options[k] = v
-	}
+	options := l.requestOptions(prompt, nil)
 	options["stream"] = true
 
-	body, err := l.Provider.PrepareStreamRequest(prompt.String(), options)
+	// The pooled key stays checked out until the stream is closed
+	start := time.Now()
+	provider, release, err := l.acquireProvider(ctx)
 	if err != nil {
-		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare stream request", err)
+		return nil, err
+	}
+	queue := time.Since(start)
+	if err := checkPromptSupport(provider, l.modelCapabilities(), prompt); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.checkReasoningEffort(provider, prompt, options); err != nil {
+		release()
+		return nil, err
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", l.Provider.Endpoint(), bytes.NewReader(body))
+	text := promptText(provider, prompt, options)
+	target := requestTarget(provider, options)
+	body, err := provider.PrepareStreamRequest(text, options)
 	if err != nil {
-		return nil, NewLLMError(ErrorTypeRequest, "failed to create stream request", err)
+		release()
+		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare stream request", err)
 	}
 
-	// Add headers
-	for k, v := range l.Provider.Headers() {
-		req.Header.Set(k, v)
+	// Create request with provider headers
+	req, err := l.newRequest(ctx, target, body)
+	if err != nil {
+		release()
+		return nil, NewLLMError(ErrorTypeRequest, "failed to create stream request", err)
 	}
 
 	// Make request
-	resp, err := l.client.Do(req)
+	resp, err := l.do(ctx, req)
 	if err != nil {
+		release()
 		return nil, NewLLMError(ErrorTypeAPI, "failed to make stream request", err)
 	}
 
+	if _, err := l.afterResponse(resp, nil); err != nil {
+		resp.Body.Close()
+		release()
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
+		release()
 		return nil, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
 	}
 
 	// Create and return stream
-	return newProviderStream(resp.Body, l.Provider, config), nil
+	stream := newProviderStream(resp.Body, provider, config)
+	stream.release = release
+	stream.timing = streamTiming{start: start, queue: queue}
+	if prompt.ResponsePrefill != "" {
+		stream.pending = append(stream.pending, StreamToken{Text: prompt.ResponsePrefill, Type: TokenTypeText})
+	}
+	return stream, nil
 }
 
 // SupportsStreaming checks if the provider supports streaming responses.
@@ -474,40 +1314,82 @@ func (l *LLMImpl) SupportsStreaming() bool {
 
 // providerStream implements TokenStream for a specific provider
 type providerStream struct {
-	decoder       *SSEDecoder
-	provider      providers.Provider
-	config        *StreamConfig
-	buffer        []byte
-	currentIndex  int
-	retryStrategy RetryStrategy
+	decoder      *SSEDecoder
+	body         io.Closer
+	provider     providers.Provider
+	config       *StreamConfig
+	buffer       []byte
+	currentIndex int
+	release      func() // Returns a pooled API key, if any
+
+	pending   []StreamToken      // Tokens decoded but not yet returned
+	toolCalls []*toolCallBuilder // Tool calls being assembled
+	done      bool               // The provider ended the response
+	reported  bool               // The provider reported usage, not yet sent as a token
+
+	mu        sync.Mutex
+	text      strings.Builder
+	reasoning strings.Builder
+	usage     Usage
+	timing    streamTiming
+	finished  bool
+	closed    bool
 }
 
 func newProviderStream(reader io.ReadCloser, provider providers.Provider, config *StreamConfig) *providerStream {
 	return &providerStream{
-		decoder:       NewSSEDecoder(reader),
-		provider:      provider,
-		config:        config,
-		buffer:        make([]byte, 0, 4096),
-		currentIndex:  0,
-		retryStrategy: config.RetryStrategy,
+		decoder:      NewSSEDecoder(reader),
+		body:         reader,
+		provider:     provider,
+		config:       config,
+		buffer:       make([]byte, 0, 4096),
+		currentIndex: 0,
 	}
 }
 
 func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 	for {
+		if s.isClosed() {
+			return nil, ErrStreamClosed
+		}
+		if len(s.pending) > 0 {
+			token := s.pending[0]
+			s.pending = s.pending[1:]
+			if s.config.StopPattern != nil && isTextToken(&token) {
+				s.stopAtPattern(&token)
+			}
+			token.Index = s.currentIndex
+			s.currentIndex++
+			s.record(&token)
+			return &token, nil
+		}
+		if s.done {
+			if len(s.toolCalls) > 0 {
+				s.flushToolCalls()
+				continue
+			}
+			if s.reported {
+				s.flushUsage()
+				continue
+			}
+			s.finish()
+			return nil, io.EOF
+		}
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 			if !s.decoder.Next() {
+				if s.isClosed() {
+					return nil, ErrStreamClosed
+				}
+				// A broken connection cannot be resumed mid-response, so read
+				// errors end the stream and leave the result truncated
 				if err := s.decoder.Err(); err != nil {
-					if s.retryStrategy.ShouldRetry(err) {
-						time.Sleep(s.retryStrategy.NextDelay())
-						continue
-					}
-					return nil, err
+					return nil, NewLLMError(ErrorTypeResponse, "stream interrupted", err)
 				}
-				return nil, io.EOF
+				s.done = true
+				continue
 			}
 
 			event := s.decoder.Event()
@@ -515,28 +1397,219 @@ func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 				continue
 			}
 
-			// Process the event
+			if parser, ok := s.provider.(providers.StreamEventParser); ok {
+				events, err := parser.ParseStreamEvent(event.Type, event.Data)
+				if err != nil {
+					return nil, NewLLMError(ErrorTypeResponse, "stream error", err)
+				}
+				s.queueEvents(events)
+				continue
+			}
+
+			// Providers without typed events only report text
 			token, err := s.provider.ParseStreamResponse(event.Data)
 			if err != nil {
-				if err.Error() == "skip token" {
+				if errors.Is(err, providers.ErrSkipToken) || err.Error() == "skip token" {
 					continue
 				}
 				if err == io.EOF {
-					return nil, io.EOF
+					s.done = true
+					continue
 				}
 				continue // Not enough data or malformed
 			}
+			s.pending = append(s.pending, StreamToken{Text: token, Type: TokenTypeText})
+		}
+	}
+}
+
+// queueEvents translates provider events into pending tokens. Tool call
+// fragments are assembled and delivered as complete calls when the provider
+// marks them complete, or when the response ends.
+func (s *providerStream) queueEvents(events []providers.StreamEvent) {
+	for _, event := range events {
+		switch event.Type {
+		case providers.StreamEventDelta:
+			s.pending = append(s.pending, StreamToken{Text: event.Text, Type: TokenTypeText})
+		case providers.StreamEventReasoningDelta:
+			if !s.config.HideReasoning {
+				s.pending = append(s.pending, StreamToken{Text: event.Text, Type: TokenTypeReasoning})
+			}
+		case providers.StreamEventToolCallDelta:
+			s.addToolCallDelta(event.ToolCall)
+		case providers.StreamEventToolCallDone:
+			if event.ToolCall != nil {
+				s.flushToolCall(event.ToolCall.Index)
+			}
+		case providers.StreamEventUsage:
+			s.mergeUsage(event.Usage)
+		case providers.StreamEventDone:
+			s.done = true
+		}
+	}
+}
 
-			// Create and return token
-			return &StreamToken{
-				Text:  token,
-				Type:  event.Type,
-				Index: s.currentIndex,
-			}, nil
+func (s *providerStream) addToolCallDelta(delta *providers.ToolCallDelta) {
+	if delta == nil {
+		return
+	}
+	for _, call := range s.toolCalls {
+		if call.index == delta.Index {
+			if delta.ID != "" {
+				call.id = delta.ID
+			}
+			call.name += delta.Name
+			call.arguments.WriteString(delta.Arguments)
+			return
 		}
 	}
+	call := &toolCallBuilder{index: delta.Index, id: delta.ID, name: delta.Name}
+	call.arguments.WriteString(delta.Arguments)
+	s.toolCalls = append(s.toolCalls, call)
+}
+
+// mergeUsage adds the counts of a usage event to the usage of the stream.
+// Providers report cumulative counts, some of them in several events, such
+// as Anthropic's input tokens when the message starts and output tokens as
+// it ends, so the stream keeps the highest count of each kind.
+func (s *providerStream) mergeUsage(reported *providers.StreamUsage) {
+	if reported == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage.PromptTokens = max(s.usage.PromptTokens, reported.PromptTokens)
+	s.usage.CompletionTokens = max(s.usage.CompletionTokens, reported.CompletionTokens)
+	s.usage.CachedTokens = max(s.usage.CachedTokens, reported.CachedTokens)
+	s.usage.CacheWriteTokens = max(s.usage.CacheWriteTokens, reported.CacheWriteTokens)
+	s.usage.ReasoningTokens = max(s.usage.ReasoningTokens, reported.ReasoningTokens)
+	s.usage.TotalTokens = s.usage.PromptTokens + s.usage.CompletionTokens
+	s.reported = true
+}
+
+// flushUsage queues the usage token that reports the final usage of the
+// stream.
+func (s *providerStream) flushUsage() {
+	s.mu.Lock()
+	usage := s.usage
+	s.mu.Unlock()
+	s.pending = append(s.pending, StreamToken{
+		Type:     TokenTypeUsage,
+		Metadata: map[string]interface{}{"usage": usage},
+		Usage:    &usage,
+	})
+	s.reported = false
+}
+
+// flushToolCalls queues a token for every assembled tool call.
+func (s *providerStream) flushToolCalls() {
+	for _, builder := range s.toolCalls {
+		s.queueToolCall(builder)
+	}
+	s.toolCalls = nil
 }
 
+// flushToolCall queues a token for the tool call with index, if any, and
+// stops assembling it.
+func (s *providerStream) flushToolCall(index int) {
+	for i, builder := range s.toolCalls {
+		if builder.index == index {
+			s.queueToolCall(builder)
+			s.toolCalls = append(s.toolCalls[:i], s.toolCalls[i+1:]...)
+			return
+		}
+	}
+}
+
+// queueToolCall queues the token of an assembled tool call.
+func (s *providerStream) queueToolCall(builder *toolCallBuilder) {
+	call := newToolCall(builder.id, builder.name, json.RawMessage(builder.arguments.String()))
+	s.pending = append(s.pending, StreamToken{
+		Text:     builder.name,
+		Type:     TokenTypeToolCall,
+		Metadata: map[string]interface{}{"tool_call": call},
+	})
+}
+
+// toolCallBuilder accumulates the fragments of a streamed tool call.
+type toolCallBuilder struct {
+	index     int
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// record adds a token to the partial result.
+func (s *providerStream) record(token *StreamToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.Type != TokenTypeUsage {
+		s.timing.firstTokenAt(time.Now())
+	}
+	switch token.Type {
+	case TokenTypeReasoning:
+		s.reasoning.WriteString(token.Text)
+	case TokenTypeUsage, TokenTypeToolCall:
+	default:
+		s.text.WriteString(token.Text)
+	}
+}
+
+// stopAtPattern cuts token at the end of the first match of the stop
+// pattern in the text so far, if any, and aborts the response there.
+func (s *providerStream) stopAtPattern(token *StreamToken) {
+	s.mu.Lock()
+	received := s.text.String()
+	s.mu.Unlock()
+	text := received + token.Text
+	match := s.config.StopPattern.FindStringIndex(text)
+	if match == nil {
+		return
+	}
+	token.Text = text[len(received):max(match[1], len(received))]
+	s.pending, s.toolCalls = nil, nil
+	s.done = true
+	// Closing the body cancels the request; Close still releases the key
+	_ = s.body.Close()
+}
+
+func (s *providerStream) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = true
+	s.timing.endAt(time.Now())
+}
+
+func (s *providerStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Result returns the text and usage received so far. The result is
+// truncated unless the provider signalled the end of the response.
+func (s *providerStream) Result() StreamResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StreamResult{Text: s.text.String(), Reasoning: s.reasoning.String(), Usage: s.usage, Truncated: !s.finished, Timing: s.timing.timing()}
+}
+
+// Close aborts the response if it is still in flight. It may be called from
+// another goroutine to cancel a stream blocked in Next.
 func (s *providerStream) Close() error {
-	return nil
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.timing.endAt(time.Now())
+	s.mu.Unlock()
+
+	err := s.body.Close()
+	if s.release != nil {
+		s.release()
+		s.release = nil
+	}
+	return err
 }
@@ -0,0 +1,558 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// echoProvider is a minimal OpenAI-style provider used to exercise LLMImpl
+// against an httptest server. It serializes the prompt and options verbatim.
+type echoProvider struct {
+	endpoint string
+	apiKey   string
+	options  map[string]interface{}
+}
+
+func (p *echoProvider) Name() string     { return "echo" }
+func (p *echoProvider) Endpoint() string { return p.endpoint }
+func (p *echoProvider) Headers() map[string]string {
+	return map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + p.apiKey}
+}
+func (p *echoProvider) SetExtraHeaders(map[string]string)               {}
+func (p *echoProvider) SupportsJSONSchema() bool                        { return false }
+func (p *echoProvider) SetOption(key string, value interface{})         { p.options[key] = value }
+func (p *echoProvider) SetLogger(utils.Logger)                          {}
+func (p *echoProvider) SupportsStreaming() bool                         { return false }
+func (p *echoProvider) HandleFunctionCalls(body []byte) ([]byte, error) { return nil, nil }
+func (p *echoProvider) ParseStreamResponse([]byte) (string, error)      { return "", io.EOF }
+
+func (p *echoProvider) SetDefaultOptions(cfg *config.Config) {
+	p.SetOption("temperature", cfg.Temperature)
+}
+
+func (p *echoProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	request := map[string]interface{}{"prompt": prompt}
+	for k, v := range p.options {
+		request[k] = v
+	}
+	for k, v := range options {
+		request[k] = v
+	}
+	return json.Marshal(request)
+}
+
+func (p *echoProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	return p.PrepareRequest(prompt, options)
+}
+
+func (p *echoProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	return p.PrepareRequest(prompt, options)
+}
+
+func (p *echoProvider) ParseResponse(body []byte) (string, error) {
+	var response struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// newEchoServer returns a server that replies with the received request body
+// encoded as the response content, so tests can inspect what was sent.
+func newEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": string(body)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestLLM(t *testing.T, endpoint string, opts ...config.ConfigOption) *LLMImpl {
+	t.Helper()
+	registry := providers.NewProviderRegistry()
+	registry.Register("echo", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &echoProvider{endpoint: endpoint, apiKey: apiKey, options: make(map[string]interface{})}
+	})
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("echo"),
+		config.SetAPIKey("test-key"),
+		config.SetMaxRetries(0),
+		config.SetRetryDelay(time.Millisecond),
+	)
+	config.ApplyOptions(cfg, opts...)
+
+	instance, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+	return instance.(*LLMImpl)
+}
+
+func TestGenerateConcurrentOptionsDoNotBleed(t *testing.T) {
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			temperature := float64(i) / 20
+			system := fmt.Sprintf("system-%d", i)
+			prompt := NewPrompt("hello", WithSystemPrompt(system, ""))
+
+			response, err := l.Generate(context.Background(), prompt, WithOption("temperature", temperature))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var sent map[string]interface{}
+			if !assert.NoError(t, json.Unmarshal([]byte(response), &sent)) {
+				return
+			}
+			assert.Equal(t, temperature, sent["temperature"])
+			assert.Equal(t, system, sent["system_prompt"])
+		}(i)
+	}
+	wg.Wait()
+
+	// Neither per-request overrides nor prompt system prompts leak into defaults.
+	l.optionsMu.RLock()
+	defer l.optionsMu.RUnlock()
+	assert.NotContains(t, l.Options, "system_prompt")
+	assert.NotContains(t, l.Options, "temperature")
+}
+
+func TestSetOptionAppliesAsDefault(t *testing.T) {
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL)
+	l.SetOption("max_tokens", 42)
+
+	response, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.NoError(t, err)
+
+	var sent map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(response), &sent))
+	assert.Equal(t, float64(42), sent["max_tokens"])
+}
+
+func TestResponsePrefill(t *testing.T) {
+	prompt := NewPrompt("List three colors as JSON", WithSystemPrompt("Answer in JSON", ""), WithResponsePrefill("{"))
+	l := &LLMImpl{Options: map[string]interface{}{}}
+
+	anthropic := providers.NewAnthropicProvider("key", "claude-3-5-sonnet-latest", nil)
+	body, err := anthropic.PrepareRequest(prompt.String(), l.requestOptions(prompt, nil))
+	require.NoError(t, err)
+	var request struct {
+		Messages []struct {
+			Role    string      `json:"role"`
+			Content interface{} `json:"content"`
+		} `json:"messages"`
+		Prefill interface{} `json:"response_prefill"`
+	}
+	require.NoError(t, json.Unmarshal(body, &request))
+	require.Len(t, request.Messages, 2)
+	assert.Equal(t, "assistant", request.Messages[1].Role)
+	assert.Equal(t, "{", request.Messages[1].Content)
+	assert.Nil(t, request.Prefill)
+
+	assert.Equal(t, `{"colors": []}`, withPrefill("{", `"colors": []}`), "continuations get the prefill")
+	assert.Equal(t, `{"colors": []}`, withPrefill("{", `{"colors": []}`), "restated prefills are kept once")
+
+	err = checkPrefill(providers.NewOllamaProvider("", "llama3", nil), prompt)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestUserAndRequestMetadata(t *testing.T) {
+	cfg := &GenerateConfig{}
+	for _, opt := range []GenerateOption{WithUser("user-42"), WithRequestMetadata(map[string]string{"customer": "acme"})} {
+		opt(cfg)
+	}
+	l := &LLMImpl{Options: map[string]interface{}{}}
+	prompt := NewPrompt("Hello")
+
+	prepare := func(provider providers.Provider) map[string]interface{} {
+		body, err := provider.PrepareRequest(prompt.String(), l.requestOptions(prompt, cfg))
+		require.NoError(t, err)
+		var request map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &request))
+		return request
+	}
+
+	openai := prepare(providers.NewOpenAIProvider("key", "gpt-4o-mini", nil))
+	assert.Equal(t, "user-42", openai["user"])
+	assert.Equal(t, map[string]interface{}{"customer": "acme"}, openai["metadata"])
+
+	anthropic := prepare(providers.NewAnthropicProvider("key", "claude-3-5-haiku-latest", nil))
+	assert.Equal(t, map[string]interface{}{"user_id": "user-42"}, anthropic["metadata"])
+	assert.NotContains(t, anthropic, "user")
+
+	for _, provider := range []providers.Provider{
+		providers.NewMistralProvider("key", "mistral-small-latest", nil),
+		providers.NewGroqProvider("key", "llama-3.1-8b-instant", nil),
+		providers.NewDeepSeekProvider("key", "deepseek-chat", nil),
+		providers.NewOllamaProvider("", "llama3", nil),
+	} {
+		request := prepare(provider)
+		assert.NotContains(t, request, "user", provider.Name())
+		assert.NotContains(t, request, "metadata", provider.Name())
+	}
+}
+
+func TestOpenRouterPreferences(t *testing.T) {
+	provider := providers.NewOpenRouterProvider("key", "meta-llama/llama-3.1-70b-instruct", nil)
+	l := &LLMImpl{Options: map[string]interface{}{}}
+	prompt := NewPrompt("Hello")
+	prepare := func(prefs providers.OpenRouterPreferences) (map[string]interface{}, error) {
+		cfg := &GenerateConfig{}
+		WithProviderPreferences(prefs)(cfg)
+		body, err := provider.PrepareRequest(prompt.String(), l.requestOptions(prompt, cfg))
+		if err != nil {
+			return nil, err
+		}
+		var request map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &request))
+		return request, nil
+	}
+
+	noFallbacks := false
+	request, err := prepare(providers.OpenRouterPreferences{
+		Order:          []string{"together", "fireworks"},
+		AllowFallbacks: &noFallbacks,
+		DataCollection: providers.DataCollectionDeny,
+		Quantizations:  []string{"fp8"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"order":           []interface{}{"together", "fireworks"},
+		"allow_fallbacks": false,
+		"data_collection": "deny",
+		"quantizations":   []interface{}{"fp8"},
+	}, request["provider"])
+	assert.NotContains(t, request, "provider_preferences")
+
+	_, err = prepare(providers.OpenRouterPreferences{DataCollection: "never"})
+	assert.Error(t, err)
+	_, err = prepare(providers.OpenRouterPreferences{Order: []string{"together"}, Ignore: []string{"Together"}})
+	assert.Error(t, err)
+}
+
+func TestDefaultSystemPrompt(t *testing.T) {
+	server := newEchoServer(t)
+	systemPrompt := func(l *LLMImpl, prompt *Prompt) interface{} {
+		result, err := l.Generate(context.Background(), prompt)
+		require.NoError(t, err)
+		var request map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(result), &request))
+		return request["system_prompt"]
+	}
+
+	l := newTestLLM(t, server.URL, config.SetDefaultSystemPrompt("Be concise."))
+	assert.Equal(t, "Be concise.", systemPrompt(l, NewPrompt("hi")))
+	assert.Equal(t, "Speak French.", systemPrompt(l, NewPrompt("hi", WithSystemPrompt("Speak French.", ""))))
+
+	l = newTestLLM(t, server.URL, config.SetDefaultSystemPrompt("Be concise."), config.SetSystemPromptPolicy(config.SystemPromptAppend))
+	assert.Equal(t, "Be concise.\n\nSpeak French.", systemPrompt(l, NewPrompt("hi", WithSystemPrompt("Speak French.", ""))))
+
+	l = newTestLLM(t, server.URL, config.SetDefaultSystemPrompt("Be concise."), config.SetSystemPromptPolicy(config.SystemPromptPrepend))
+	assert.Equal(t, "Speak French.\n\nBe concise.", systemPrompt(l, NewPrompt("hi", WithSystemPrompt("Speak French.", ""))))
+
+	_, err := NewLLM(&config.Config{Provider: "openai", Model: "m", APIKeys: map[string]string{"openai": "k"}, SystemPromptPolicy: "merge"},
+		utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	assert.Error(t, err)
+}
+
+func TestMessagesPassthrough(t *testing.T) {
+	prompt := NewPrompt("And Germany?",
+		WithSystemPrompt("Be brief.", ""),
+		WithMessages([]PromptMessage{
+			{Role: "user", Content: "Capital of France?"},
+			{Role: "assistant", Content: "Paris."},
+			{Role: "user", Content: "And Germany?"},
+		}),
+	)
+	request := func(provider, model string, prompt *Prompt, opts ...GenerateOption) (string, map[string]interface{}) {
+		l := newTestLLM(t, "", config.SetProvider(provider), config.SetAPIKey("key"), config.SetModel(model))
+		result, err := l.Generate(context.Background(), prompt, append(opts, WithDryRun())...)
+		require.NoError(t, err)
+		var dryRun DryRunRequest
+		require.NoError(t, json.Unmarshal([]byte(result), &dryRun))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(dryRun.Body, &body))
+		return dryRun.URL, body
+	}
+	roles := func(body map[string]interface{}) []string {
+		var roles []string
+		for _, message := range body["messages"].([]interface{}) {
+			roles = append(roles, message.(map[string]interface{})["role"].(string))
+		}
+		return roles
+	}
+
+	t.Run("openai", func(t *testing.T) {
+		_, body := request("openai", "gpt-4o-mini", prompt)
+		assert.Equal(t, []string{"developer", "user", "assistant", "user"}, roles(body))
+		assert.Equal(t, "Paris.", body["messages"].([]interface{})[2].(map[string]interface{})["content"])
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		_, body := request("anthropic", "claude-3-5-haiku-latest", prompt)
+		assert.Equal(t, []string{"user", "assistant", "user"}, roles(body))
+		assert.Equal(t, "Be brief.", body["system"].([]interface{})[0].(map[string]interface{})["text"])
+	})
+
+	t.Run("ollama", func(t *testing.T) {
+		url, body := request("ollama", "llama3", prompt)
+		assert.True(t, strings.HasSuffix(url, "/api/chat"))
+		assert.Equal(t, []string{"system", "user", "assistant", "user"}, roles(body))
+		assert.NotContains(t, body, "prompt")
+
+		// Prompts without history still use the generate endpoint
+		url, body = request("ollama", "llama3", NewPrompt("Hello"))
+		assert.True(t, strings.HasSuffix(url, "/api/generate"))
+		assert.Contains(t, body, "prompt")
+	})
+
+	t.Run("openrouter", func(t *testing.T) {
+		_, body := request("openrouter", "meta-llama/llama-3.1-8b-instruct", prompt)
+		assert.Equal(t, []string{"system", "user", "assistant", "user"}, roles(body))
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		url, body := request("gemini", "gemini-2.5-flash", prompt)
+		assert.True(t, strings.HasSuffix(url, "/openai/chat/completions"))
+		assert.Equal(t, []string{"system", "user", "assistant", "user"}, roles(body))
+		assert.NotContains(t, body, "extra_body")
+
+		// Cached content holds the system prompt, but the history is still sent
+		_, body = request("gemini", "gemini-2.5-flash", prompt,
+			UseCachedContext(providers.CachedContext{Name: "cachedContents/abc", Model: "gemini-2.5-flash"}))
+		assert.Equal(t, []string{"user", "assistant", "user"}, roles(body))
+		assert.Equal(t, "Paris.", body["messages"].([]interface{})[1].(map[string]interface{})["content"])
+		assert.Equal(t, map[string]interface{}{"google": map[string]interface{}{"cached_content": "cachedContents/abc"}}, body["extra_body"])
+	})
+
+	// The rest of the prompt follows the history as a final user message
+	withDirectives := NewPrompt("Capital of Spain?", WithMessages([]PromptMessage{
+		{Role: "user", Content: "Capital of France?"},
+		{Role: "assistant", Content: "Paris."},
+	}), WithDirectives("Answer with the city only"))
+	_, body := request("openai", "gpt-4o-mini", withDirectives)
+	messages := body["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	last := messages[2].(map[string]interface{})["content"].(string)
+	assert.Contains(t, last, "Capital of Spain?")
+	assert.Contains(t, last, "Answer with the city only")
+	assert.NotContains(t, last, "Paris.")
+}
+
+func TestOptionSupport(t *testing.T) {
+	newLLM := func(provider string, opts ...config.ConfigOption) (LLM, error) {
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg, config.SetProvider(provider), config.SetAPIKey("key"), config.SetModel("m"))
+		config.ApplyOptions(cfg, opts...)
+		return NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	}
+
+	_, err := newLLM("anthropic", config.SetSeed(7))
+	assert.NoError(t, err)
+
+	_, err = newLLM("anthropic", config.SetSeed(7), config.SetStrictOptions(true))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+
+	_, err = newLLM("deepseek", config.SetTopK(40), config.SetStrictOptions(true))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+
+	l, err := newLLM("anthropic", config.SetTopP(0.5), config.SetTopK(40), config.SetStrictOptions(true))
+	require.NoError(t, err)
+	result, err := l.Generate(context.Background(), NewPrompt("hi"), WithDryRun())
+	require.NoError(t, err)
+	var dryRun DryRunRequest
+	require.NoError(t, json.Unmarshal([]byte(result), &dryRun))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(dryRun.Body, &body))
+	assert.Equal(t, 0.5, body["top_p"])
+	assert.Equal(t, float64(40), body["top_k"])
+
+	assert.True(t, providers.SupportsOption("custom", providers.OptionTopK))
+}
+
+func TestSamplingProfile(t *testing.T) {
+	body := func(provider string, opts ...config.ConfigOption) map[string]interface{} {
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg, config.SetProvider(provider), config.SetAPIKey("key"), config.SetModel("m"), config.SetStrictOptions(true))
+		config.ApplyOptions(cfg, opts...)
+		l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+		require.NoError(t, err)
+		result, err := l.Generate(context.Background(), NewPrompt("hi"), WithDryRun())
+		require.NoError(t, err)
+		var dryRun DryRunRequest
+		require.NoError(t, json.Unmarshal([]byte(result), &dryRun))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(dryRun.Body, &body))
+		return body
+	}
+
+	request := body("openrouter", config.SetSamplingProfile(config.SamplingDeterministic))
+	assert.Equal(t, float64(0), request["temperature"])
+	assert.Equal(t, float64(1), request["top_k"])
+
+	request = body("openai", config.SetSamplingProfile("Deterministic"))
+	assert.Equal(t, float64(0), request["temperature"])
+	assert.NotContains(t, request, "top_k")
+
+	request = body("openai", config.SetSamplingProfile(config.SamplingCreative), config.SetTemperature(0.8))
+	assert.Equal(t, 0.8, request["temperature"])
+	assert.Equal(t, 0.95, request["top_p"])
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("openai"), config.SetAPIKey("key"), config.SetSamplingProfile("wild"))
+	_, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	assert.Error(t, err)
+}
+
+func TestConstrainedDecoding(t *testing.T) {
+	prompt := NewPrompt("Is the sky blue?", WithGrammar(`root ::= "yes" | "no"`), WithRegexConstraint(`yes|no`))
+	l := &LLMImpl{Options: map[string]interface{}{}}
+
+	vllm := providers.NewVLLMProvider("", "qwen", nil)
+	require.NoError(t, checkPromptSupport(vllm, nil, prompt))
+	body, err := vllm.PrepareRequest(prompt.String(), l.requestOptions(prompt, nil))
+	require.NoError(t, err)
+	var request map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &request))
+	assert.Equal(t, `root ::= "yes" | "no"`, request["guided_grammar"])
+	assert.Equal(t, "yes|no", request["guided_regex"])
+	assert.NotContains(t, request, "grammar")
+	assert.NotContains(t, request, "regex_constraint")
+
+	grammarOnly := NewPrompt("Is the sky blue?", WithGrammar(`root ::= "yes" | "no"`))
+	llamacpp := providers.NewLlamaCppProvider("", "qwen", nil)
+	require.NoError(t, checkPromptSupport(llamacpp, nil, grammarOnly))
+	body, err = llamacpp.PrepareRequest(grammarOnly.String(), l.requestOptions(grammarOnly, nil))
+	require.NoError(t, err)
+	request = nil
+	require.NoError(t, json.Unmarshal(body, &request))
+	assert.Equal(t, `root ::= "yes" | "no"`, request["grammar"])
+
+	for _, provider := range []providers.Provider{llamacpp, providers.NewOllamaProvider("", "llama3", nil)} {
+		var llmErr *LLMError
+		require.ErrorAs(t, checkPromptSupport(provider, nil, prompt), &llmErr, provider.Name())
+		assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+	}
+}
+
+func TestSetModelCapabilities(t *testing.T) {
+	assert.Equal(t, "gpt-4o-mini-2024-07-18", providers.BaseModel("ft:gpt-4o-mini-2024-07-18:acme::9xyz"))
+	assert.Equal(t, "gpt-4o", providers.BaseModel("gpt-4o"))
+	caps, ok := providers.LookupModelCapabilities("openai", "ft:gpt-3.5-turbo-0125:acme:support:9xyz")
+	require.True(t, ok)
+	assert.True(t, caps.Tools)
+	assert.False(t, caps.JSONSchema)
+	assert.False(t, providers.NewOpenAIProvider("key", "ft:gpt-3.5-turbo-0125:acme::9xyz", nil).SupportsJSONSchema())
+	assert.True(t, providers.NewOpenAIProvider("key", "my-proxied-model", nil).SupportsJSONSchema())
+
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL)
+	assert.False(t, l.SupportsJSONSchema())
+	ctx := context.Background()
+
+	// Unknown models are not checked
+	prompt := NewPrompt("What's the weather?", WithTools([]utils.Tool{{Type: "function", Function: utils.Function{Name: "get_weather"}}}))
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+
+	l.SetModelCapabilities(providers.ModelCapabilities{JSONMode: true, JSONSchema: true})
+	assert.True(t, l.SupportsJSONSchema())
+	_, err = l.Generate(ctx, prompt)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+
+	_, err = l.Generate(ctx, NewPrompt("Describe this", WithImageURL("https://example.com/cat.png")))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+
+	l.SetModelCapabilities(providers.ModelCapabilities{Tools: true, Vision: true})
+	assert.False(t, l.SupportsJSONSchema())
+	_, err = l.Generate(ctx, prompt)
+	require.NoError(t, err)
+}
+
+func TestSetBaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/messages":
+			fmt.Fprint(w, `{"content": [{"type": "text", "text": "ok"}]}`)
+		default:
+			fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		provider, model, baseURL, path string
+	}{
+		{"openai", "gpt-4o-mini", server.URL + "/v1/", "/v1/chat/completions"},
+		{"anthropic", "claude-3-5-haiku-latest", server.URL, "/v1/messages"},
+		{"mistral", "mistral-small-latest", server.URL + "/v1", "/v1/chat/completions"},
+		{"deepseek", "deepseek-chat", server.URL + "/gateway", "/gateway/chat/completions"},
+	} {
+		paths = nil
+		l := newTestLLM(t, "", config.SetProvider(tc.provider), config.SetModel(tc.model), config.SetAPIKey("key"), config.SetBaseURL(tc.provider, tc.baseURL))
+		text, err := l.Generate(context.Background(), NewPrompt("hi"))
+		require.NoError(t, err, tc.provider)
+		assert.Equal(t, "ok", text)
+		assert.Equal(t, []string{tc.path}, paths, tc.provider)
+	}
+}
+
+func TestGatewayProvider(t *testing.T) {
+	var auth string
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("x-litellm-response-cost", "0.00042")
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 10, "completion_tokens": 2, "total_tokens": 12}}`)
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, "", config.SetProvider("gateway"), config.SetModel("gpt-4o-mini"), config.SetAPIKey("sk-virtual"), config.SetBaseURL("gateway", server.URL))
+	tracker := NewUsageTracker()
+	var resp Response
+	text, err := l.Generate(context.Background(), NewPrompt("hi"), WithModel("anthropic/claude-3-5-haiku"), WithResponse(&resp), WithUsageTracker(tracker))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+	assert.Equal(t, "Bearer sk-virtual", auth)
+	assert.Equal(t, "anthropic/claude-3-5-haiku", request["model"])
+	assert.Equal(t, "anthropic/claude-3-5-haiku", resp.Model)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12, Cost: 0.00042}, resp.Usage)
+	assert.InDelta(t, 0.00042, tracker.Total().Cost, 1e-12)
+}
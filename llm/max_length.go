@@ -0,0 +1,91 @@
+package llm
+
+import "math"
+
+// LengthEnforcement is how Generate holds a response to the MaxLength of its
+// prompt, set with WithMaxLength.
+type LengthEnforcement string
+
+const (
+	// LengthEnforcementDirective only asks for the length in the prompt,
+	// which models follow loosely. This is the default.
+	LengthEnforcementDirective LengthEnforcement = "directive"
+	// LengthEnforcementMaxTokens also caps max_tokens to the length, so that
+	// a long-winded response is cut off by the provider, possibly
+	// mid-sentence.
+	LengthEnforcementMaxTokens LengthEnforcement = "max_tokens"
+	// LengthEnforcementTruncate caps max_tokens and cuts the response to the
+	// length, at the end of a sentence when possible, as MaxWords.
+	LengthEnforcementTruncate LengthEnforcement = "truncate"
+	// LengthEnforcementShorten caps max_tokens and, when the response is
+	// longer than the length, asks once more for a shorter one, as
+	// RequireMaxWords. Generate fails when the second response is still too
+	// long.
+	LengthEnforcementShorten LengthEnforcement = "shorten"
+)
+
+const (
+	// tokensPerWord is the average number of tokens of an English word.
+	tokensPerWord = 4.0 / 3.0
+	// maxLengthHeadroom lets responses run a little over their length before
+	// max_tokens cuts them off, as word counts are approximate.
+	maxLengthHeadroom = 1.25
+)
+
+// WithLengthEnforcement sets how the Generate call holds the response to the
+// MaxLength of the prompt, in words. Every mode but
+// LengthEnforcementDirective sets max_tokens from the length, unless it is
+// already lower or set with WithOption.
+//
+// Example:
+//
+//	prompt := llm.NewPrompt("Describe the product.", llm.WithMaxLength(50))
+//	description, err := client.Generate(ctx, prompt, llm.WithLengthEnforcement(llm.LengthEnforcementTruncate))
+func WithLengthEnforcement(mode LengthEnforcement) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.lengthEnforcement = mode
+	}
+}
+
+// MaxLengthTokens returns the max_tokens WithLengthEnforcement sets for a
+// response of words words.
+func MaxLengthTokens(words int) int {
+	return int(math.Ceil(float64(words) * tokensPerWord * maxLengthHeadroom))
+}
+
+// enforcesLength reports whether the Generate call of prompt enforces its
+// MaxLength beyond the directive.
+func enforcesLength(prompt *Prompt, cfg *GenerateConfig) bool {
+	return prompt != nil && prompt.MaxLength > 0 && cfg != nil &&
+		cfg.lengthEnforcement != "" && cfg.lengthEnforcement != LengthEnforcementDirective
+}
+
+// applyLengthEnforcement adds the post-processor or validator that holds the
+// response to the MaxLength of prompt.
+func applyLengthEnforcement(prompt *Prompt, cfg *GenerateConfig) {
+	if !enforcesLength(prompt, cfg) {
+		return
+	}
+	switch cfg.lengthEnforcement {
+	case LengthEnforcementTruncate:
+		cfg.PostProcessors = append(cfg.PostProcessors, MaxWords(prompt.MaxLength))
+	case LengthEnforcementShorten:
+		cfg.Validators = append(cfg.Validators, RequireMaxWords(prompt.MaxLength))
+	}
+}
+
+// capMaxTokens lowers the max_tokens option of the request to the MaxLength
+// of prompt, unless the call set max_tokens with WithOption.
+func capMaxTokens(prompt *Prompt, cfg *GenerateConfig, options map[string]interface{}) {
+	if !enforcesLength(prompt, cfg) {
+		return
+	}
+	if _, ok := cfg.Options["max_tokens"]; ok {
+		return
+	}
+	limit := MaxLengthTokens(prompt.MaxLength)
+	if current, ok := options["max_tokens"].(int); ok && current > 0 && current < limit {
+		return
+	}
+	options["max_tokens"] = limit
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLengthEnforcement(t *testing.T) {
+	ctx := context.Background()
+	prompt := NewPrompt("Describe the product.", WithMaxLength(30))
+
+	echo := newEchoServer(t)
+	l := newTestLLM(t, echo.URL)
+	body, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "max_tokens", "the directive alone does not cap max_tokens")
+	body, err = l.Generate(ctx, prompt, WithLengthEnforcement(LengthEnforcementMaxTokens))
+	require.NoError(t, err)
+	assert.Contains(t, body, fmt.Sprintf(`"max_tokens":%d`, MaxLengthTokens(30)))
+	body, err = l.Generate(ctx, prompt, WithLengthEnforcement(LengthEnforcementMaxTokens), WithOption("max_tokens", 500))
+	require.NoError(t, err)
+	assert.Contains(t, body, `"max_tokens":500`, "an explicit max_tokens wins")
+
+	long := strings.Repeat("This sentence has five words. ", 10)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := long
+		if atomic.AddInt32(&requests, 1) > 1 {
+			content = "Short enough."
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": content})
+	}))
+	defer server.Close()
+	l = newTestLLM(t, server.URL)
+
+	text, err := l.Generate(ctx, prompt, WithLengthEnforcement(LengthEnforcementTruncate))
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(strings.Repeat("This sentence has five words. ", 6)), text)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+	text, err = l.Generate(ctx, prompt, WithLengthEnforcement(LengthEnforcementShorten))
+	require.NoError(t, err)
+	assert.Equal(t, "Short enough.", text)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+
+	truncated, err := MaxWords(4)("One two three. Four five six.")
+	require.NoError(t, err)
+	assert.Equal(t, "One two three.", truncated)
+	truncated, err = MaxWords(2)("One two three four")
+	require.NoError(t, err)
+	assert.Equal(t, "One two", truncated)
+}
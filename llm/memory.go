@@ -4,6 +4,8 @@ package llm
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -136,6 +138,8 @@ func (m *Memory) GetMessages() []MemoryMessage {
 type LLMWithMemory struct {
 	LLM              // Underlying LLM instance
 	memory *Memory   // Conversation memory manager
+
+	persistPartial bool // Keep the partial reply of streams closed mid-flight
 }
 
 // NewLLMWithMemory creates a new LLM instance with conversation memory.
@@ -172,12 +176,23 @@ func NewLLMWithMemory(baseLLM LLM, maxTokens int, model string, logger utils.Log
 //   - Generated text response
 //   - Error types as per the base LLM's Generate method
 func (l *LLMWithMemory) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
-	l.memory.Add("user", prompt.Input)
-	fullPrompt := l.memory.GetPrompt()
+	// Dry runs show the request without recording the turn
+	dryRun := isDryRun(opts)
+	fullPrompt := l.memory.GetPrompt() + "user: " + prompt.Input + "\n"
+	if !dryRun {
+		l.memory.Add("user", prompt.Input)
+		fullPrompt = l.memory.GetPrompt()
+	}
 
 	// Create a new Prompt with the full memory context
 	memoryPrompt := &Prompt{
-		Input: fullPrompt,
+		Input:           fullPrompt,
+		Attachments:     prompt.Attachments,
+		Documents:       prompt.Documents,
+		ResponsePrefill: prompt.ResponsePrefill,
+		Grammar:         prompt.Grammar,
+		RegexConstraint: prompt.RegexConstraint,
+		ReasoningEffort: prompt.ReasoningEffort,
 		// Copy other fields from the original prompt if needed
 	}
 
@@ -186,7 +201,9 @@ func (l *LLMWithMemory) Generate(ctx context.Context, prompt *Prompt, opts ...Ge
 		return "", err
 	}
 
-	l.memory.Add("assistant", response)
+	if !dryRun {
+		l.memory.Add("assistant", response)
+	}
 	return response, nil
 }
 
@@ -216,11 +233,22 @@ func (l *LLMWithMemory) GetMemory() []MemoryMessage {
 //   - Generated text response
 //   - Error types as per the base LLM's GenerateWithSchema method
 func (l *LLMWithMemory) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, error) {
-	l.memory.Add("user", prompt.Input)
-	fullPrompt := l.memory.GetPrompt()
+	// Dry runs show the request without recording the turn
+	dryRun := isDryRun(opts)
+	fullPrompt := l.memory.GetPrompt() + "user: " + prompt.Input + "\n"
+	if !dryRun {
+		l.memory.Add("user", prompt.Input)
+		fullPrompt = l.memory.GetPrompt()
+	}
 
 	memoryPrompt := &Prompt{
-		Input: fullPrompt,
+		Input:           fullPrompt,
+		Attachments:     prompt.Attachments,
+		Documents:       prompt.Documents,
+		ResponsePrefill: prompt.ResponsePrefill,
+		Grammar:         prompt.Grammar,
+		RegexConstraint: prompt.RegexConstraint,
+		ReasoningEffort: prompt.ReasoningEffort,
 		// Copy other fields from the original prompt if needed
 	}
 
@@ -229,6 +257,101 @@ func (l *LLMWithMemory) GenerateWithSchema(ctx context.Context, prompt *Prompt,
 		return "", err
 	}
 
-	l.memory.Add("assistant", response)
+	if !dryRun {
+		l.memory.Add("assistant", response)
+	}
 	return response, nil
 }
+
+// Unwrap returns the LLM wrapped with memory.
+func (l *LLMWithMemory) Unwrap() LLM {
+	return l.LLM
+}
+
+// SetPersistPartialStreams controls what happens to the assistant turn when a
+// stream is closed before it completes. When enabled, the partial reply is
+// stored in memory; otherwise only completed replies are remembered.
+func (l *LLMWithMemory) SetPersistPartialStreams(enabled bool) {
+	l.persistPartial = enabled
+}
+
+// Stream streams a response to the prompt with conversation history. The
+// prompt is added to memory immediately and the reply once the stream
+// completes, or when it is closed early if SetPersistPartialStreams is enabled.
+func (l *LLMWithMemory) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOption) (TokenStream, error) {
+	l.memory.Add("user", prompt.Input)
+
+	memoryPrompt := &Prompt{
+		Input:           l.memory.GetPrompt(),
+		Attachments:     prompt.Attachments,
+		Documents:       prompt.Documents,
+		ResponsePrefill: prompt.ResponsePrefill,
+		Grammar:         prompt.Grammar,
+		RegexConstraint: prompt.RegexConstraint,
+		ReasoningEffort: prompt.ReasoningEffort,
+	}
+
+	stream, err := l.LLM.Stream(ctx, memoryPrompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryStream{TokenStream: stream, llm: l}, nil
+}
+
+// memoryStream records the assistant reply of a stream in memory. Close may
+// be called from another goroutine while Next is blocked, so the recorded
+// reply is guarded by mu.
+type memoryStream struct {
+	TokenStream
+	llm  *LLMWithMemory
+	once sync.Once
+
+	mu   sync.Mutex
+	text strings.Builder
+	done bool
+}
+
+func (s *memoryStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := s.TokenStream.Next(ctx)
+	switch {
+	case err == io.EOF:
+		s.mu.Lock()
+		s.done = true
+		s.mu.Unlock()
+		s.remember()
+	case err == nil && isTextToken(token):
+		s.mu.Lock()
+		s.text.WriteString(token.Text)
+		s.mu.Unlock()
+	}
+	return token, err
+}
+
+// Result reports the partial result of the underlying stream.
+func (s *memoryStream) Result() StreamResult {
+	if result, ok := PartialResult(s.TokenStream); ok {
+		return result
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StreamResult{Text: s.text.String(), Truncated: !s.done}
+}
+
+func (s *memoryStream) Close() error {
+	err := s.TokenStream.Close()
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if !done && s.llm.persistPartial {
+		s.remember()
+	}
+	return err
+}
+
+func (s *memoryStream) remember() {
+	s.once.Do(func() {
+		if text := s.Result().Text; text != "" {
+			s.llm.memory.Add("assistant", text)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// chanStream yields the tokens sent on its channel until it is closed.
+type chanStream struct {
+	tokens    chan string
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newChanStream() *chanStream {
+	return &chanStream{tokens: make(chan string), closed: make(chan struct{})}
+}
+
+func (s *chanStream) Next(ctx context.Context) (*StreamToken, error) {
+	select {
+	case text, ok := <-s.tokens:
+		if !ok {
+			return nil, io.EOF
+		}
+		return &StreamToken{Text: text, Type: "text"}, nil
+	case <-s.closed:
+		return nil, io.ErrClosedPipe
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *chanStream) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// byteBpeLoader ranks every single byte, so encodings load without
+// downloading the real ranks.
+type byteBpeLoader struct{}
+
+func (byteBpeLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	ranks := make(map[string]int, 256)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	return ranks, nil
+}
+
+func newTestMemoryLLM(t *testing.T) *LLMWithMemory {
+	t.Helper()
+	tiktoken.SetBpeLoader(byteBpeLoader{})
+	l, err := NewLLMWithMemory(nil, 1000, "gpt-4o", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	return l
+}
+
+func TestMemoryStreamCloseDuringNext(t *testing.T) {
+	l := newTestMemoryLLM(t)
+	l.SetPersistPartialStreams(true)
+
+	source := newChanStream()
+	stream := &memoryStream{TokenStream: source, llm: l}
+
+	// Tokens keep arriving while the stream is closed from another goroutine
+	go func() {
+		for {
+			select {
+			case source.tokens <- "a":
+			case <-source.closed:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if _, err := stream.Next(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+
+	require.Eventually(t, func() bool { return stream.Result().Text != "" }, time.Second, time.Millisecond)
+	require.NoError(t, stream.Close())
+	wg.Wait()
+
+	messages := l.GetMemory()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "assistant", messages[0].Role)
+	assert.NotEmpty(t, messages[0].Content)
+	assert.Equal(t, strings.Repeat("a", len(messages[0].Content)), messages[0].Content)
+	assert.True(t, stream.Result().Truncated)
+}
+
+func TestMemoryStreamRemembersFinishedReply(t *testing.T) {
+	l := newTestMemoryLLM(t)
+
+	source := newChanStream()
+	stream := &memoryStream{TokenStream: source, llm: l}
+	go func() {
+		source.tokens <- "hello "
+		source.tokens <- "world"
+		close(source.tokens)
+	}()
+
+	for {
+		if _, err := stream.Next(context.Background()); err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+	}
+	require.NoError(t, stream.Close())
+
+	messages := l.GetMemory()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "hello world", messages[0].Content)
+	assert.False(t, stream.Result().Truncated)
+}
@@ -0,0 +1,256 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaskComplexity is how demanding a request is, declared with
+// WithTaskComplexity so that a ModelRouter can send it to a capable enough
+// model.
+type TaskComplexity string
+
+const (
+	// TaskSimple suits the smallest models, such as classification,
+	// extraction or short rewrites.
+	TaskSimple TaskComplexity = "simple"
+	// TaskModerate suits mid-sized models, such as summaries or drafting.
+	TaskModerate TaskComplexity = "moderate"
+	// TaskComplex needs the most capable models, such as multi-step
+	// reasoning or code generation.
+	TaskComplex TaskComplexity = "complex"
+)
+
+// rank orders the complexities; an undeclared complexity ranks lowest.
+func (c TaskComplexity) rank() int {
+	switch c {
+	case TaskSimple:
+		return 1
+	case TaskModerate:
+		return 2
+	case TaskComplex:
+		return 3
+	}
+	return 0
+}
+
+// RoutedModel is a model a ModelRouter may send requests to. Its price and
+// context window come from LookupPricing and LookupModelLimits.
+type RoutedModel struct {
+	// Model is the model name, sent in place of the configured model.
+	Model string `json:"model"`
+	// MaxComplexity is the most complex task the model handles. Empty
+	// means any task.
+	MaxComplexity TaskComplexity `json:"max_complexity,omitempty"`
+	// MaxPromptTokens is the largest prompt the model is used for, such as
+	// 2000 to keep a mini model to small prompts. Zero means any prompt
+	// that fits in its context window.
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+	// Latency is the model's typical latency, checked against the latency
+	// SLO of the policy. Zero means unknown.
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// RoutingPolicy decides which model serves each request. It is a plain
+// value: the same policy and request always route to the same model.
+type RoutingPolicy struct {
+	// Models are the candidates, from the preferred to the last resort;
+	// list them from the cheapest to the most capable. A request goes to
+	// the first model that handles its complexity and size, meets the
+	// latency SLO and stays under the cost ceiling.
+	Models []RoutedModel `json:"models"`
+	// LatencySLO rejects models whose Latency is above it, or unknown.
+	// Zero means no SLO.
+	LatencySLO time.Duration `json:"latency_slo,omitempty"`
+	// MaxCost is the ceiling in US dollars on the estimated cost of a
+	// request. It rejects models whose estimate is above it, or unknown.
+	// Zero means no ceiling.
+	MaxCost float64 `json:"max_cost,omitempty"`
+	// OutputTokens is the expected length of replies, used to estimate the
+	// cost. Zero uses the max_tokens of the request.
+	OutputTokens int `json:"output_tokens,omitempty"`
+	// Fallback is the model used when no candidate qualifies. Empty makes
+	// such requests fail with ErrorTypeInvalidInput.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// RoutingRequest describes a request to route.
+type RoutingRequest struct {
+	// Complexity is the declared complexity of the task. Empty routes on
+	// size, latency and cost alone.
+	Complexity TaskComplexity `json:"complexity,omitempty"`
+	// PromptTokens is the estimated size of the request, in tokens.
+	PromptTokens int `json:"prompt_tokens"`
+	// OutputTokens is the max_tokens of the request.
+	OutputTokens int `json:"output_tokens,omitempty"`
+}
+
+// RoutingDecision records the model a ModelRouter picked and why. Generate
+// logs it and stores it in the Response metadata under the "routing" key.
+type RoutingDecision struct {
+	// Model is the model that serves the request.
+	Model string `json:"model"`
+	// Reason is "policy" when a candidate qualified, or "fallback". A
+	// DifficultyRouter reports "classifier", or "classifier_error" when the
+	// prompt could not be classified.
+	Reason string `json:"reason"`
+	// Complexity and PromptTokens describe the routed request.
+	Complexity   TaskComplexity `json:"complexity,omitempty"`
+	PromptTokens int            `json:"prompt_tokens"`
+	// Difficulty is the difficulty score of the prompt, for a
+	// DifficultyRouter.
+	Difficulty float64 `json:"difficulty,omitempty"`
+	// EstimatedCost is the estimated price in US dollars of the request on
+	// Model, or zero when unknown.
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+	// Rejected holds why each candidate tried before Model was passed over.
+	Rejected map[string]string `json:"rejected,omitempty"`
+}
+
+// ModelRouter picks the model of each Generate call from a RoutingPolicy,
+// so that small or simple requests go to cheaper and faster models.
+type ModelRouter struct {
+	policy RoutingPolicy
+}
+
+// NewModelRouter creates a ModelRouter for policy.
+//
+// Example:
+//
+//	router := llm.NewModelRouter(llm.RoutingPolicy{
+//	    Models: []llm.RoutedModel{
+//	        {Model: "gpt-4o-mini", MaxComplexity: llm.TaskModerate, MaxPromptTokens: 4000},
+//	        {Model: "gpt-4o"},
+//	    },
+//	    MaxCost: 0.05,
+//	})
+func NewModelRouter(policy RoutingPolicy) *ModelRouter {
+	return &ModelRouter{policy: policy}
+}
+
+// Policy returns the policy of the router.
+func (r *ModelRouter) Policy() RoutingPolicy {
+	return r.policy
+}
+
+// Route returns the model that serves req under the policy. It fails with
+// ErrorTypeInvalidInput when no candidate qualifies and the policy has no
+// fallback.
+func (r *ModelRouter) Route(req RoutingRequest) (RoutingDecision, error) {
+	decision := RoutingDecision{Complexity: req.Complexity, PromptTokens: req.PromptTokens}
+	outputTokens := r.policy.OutputTokens
+	if outputTokens <= 0 {
+		outputTokens = req.OutputTokens
+	}
+
+	for _, candidate := range r.policy.Models {
+		cost, rejection := r.check(candidate, req, outputTokens)
+		if rejection == "" {
+			decision.Model = candidate.Model
+			decision.Reason = "policy"
+			decision.EstimatedCost = cost
+			return decision, nil
+		}
+		if decision.Rejected == nil {
+			decision.Rejected = make(map[string]string)
+		}
+		decision.Rejected[candidate.Model] = rejection
+	}
+
+	if r.policy.Fallback == "" {
+		reasons := make([]string, 0, len(r.policy.Models))
+		for _, candidate := range r.policy.Models {
+			reasons = append(reasons, candidate.Model+": "+decision.Rejected[candidate.Model])
+		}
+		return decision, NewLLMError(ErrorTypeInvalidInput, "no model satisfies the routing policy ("+strings.Join(reasons, "; ")+")", nil)
+	}
+	decision.Model = r.policy.Fallback
+	decision.Reason = "fallback"
+	decision.EstimatedCost, _ = EstimateCost(r.policy.Fallback, Usage{PromptTokens: req.PromptTokens, CompletionTokens: outputTokens})
+	return decision, nil
+}
+
+// check returns the estimated cost of req on candidate, and why candidate
+// cannot serve req, or an empty string when it can.
+func (r *ModelRouter) check(candidate RoutedModel, req RoutingRequest, outputTokens int) (float64, string) {
+	if candidate.MaxComplexity != "" && req.Complexity.rank() > candidate.MaxComplexity.rank() {
+		return 0, fmt.Sprintf("%s task above its %s limit", req.Complexity, candidate.MaxComplexity)
+	}
+	if candidate.MaxPromptTokens > 0 && req.PromptTokens > candidate.MaxPromptTokens {
+		return 0, fmt.Sprintf("prompt of %d tokens above its %d-token limit", req.PromptTokens, candidate.MaxPromptTokens)
+	}
+	if limits, ok := LookupModelLimits(candidate.Model); ok && limits.ContextWindow > 0 && req.PromptTokens+outputTokens > limits.ContextWindow {
+		return 0, fmt.Sprintf("request of %d tokens overflows its %d-token context window", req.PromptTokens+outputTokens, limits.ContextWindow)
+	}
+	if r.policy.LatencySLO > 0 {
+		if candidate.Latency <= 0 {
+			return 0, "unknown latency"
+		}
+		if candidate.Latency > r.policy.LatencySLO {
+			return 0, fmt.Sprintf("latency of %s above the %s SLO", candidate.Latency, r.policy.LatencySLO)
+		}
+	}
+	cost, priced := EstimateCost(candidate.Model, Usage{PromptTokens: req.PromptTokens, CompletionTokens: outputTokens})
+	if r.policy.MaxCost > 0 {
+		if !priced {
+			return 0, "unknown pricing"
+		}
+		if cost > r.policy.MaxCost {
+			return cost, fmt.Sprintf("estimated cost of $%.6f above the $%.6f ceiling", cost, r.policy.MaxCost)
+		}
+	}
+	return cost, ""
+}
+
+// WithModelRouter picks the model of the Generate call with router, from
+// the declared complexity of the task, the estimated size of the prompt and
+// the latency SLO and cost ceiling of the router's policy. The decision is
+// logged, and stored as a RoutingDecision under the "routing" key of the
+// Response metadata. A model set with WithModel takes precedence.
+//
+// Example:
+//
+//	label, err := client.Generate(ctx, prompt, llm.WithModelRouter(router), llm.WithTaskComplexity(llm.TaskSimple))
+func WithModelRouter(router *ModelRouter) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.router = router
+	}
+}
+
+// WithTaskComplexity declares the complexity of the task for the
+// ModelRouter of the Generate call.
+func WithTaskComplexity(complexity TaskComplexity) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.complexity = complexity
+	}
+}
+
+// routeModel routes the request whose prompt text is text with the router of
+// the call, and sets the model option of the request to the routed model. It
+// returns nil when the call has no router or sets its model with WithModel.
+// The decision of a DifficultyRouter, made once before the attempts, takes
+// precedence.
+func (l *LLMImpl) routeModel(cfg *GenerateConfig, text string, options map[string]interface{}) (*RoutingDecision, error) {
+	if cfg == nil || (cfg.router == nil && cfg.routing == nil) {
+		return nil, nil
+	}
+	if _, ok := cfg.Options["model"]; ok {
+		return nil, nil
+	}
+	decision := RoutingDecision{}
+	if cfg.routing != nil {
+		decision = *cfg.routing
+	} else {
+		req := RoutingRequest{Complexity: cfg.complexity, PromptTokens: estimateRequestTokens(text, options)}
+		req.OutputTokens, _ = options["max_tokens"].(int)
+		var err error
+		if decision, err = cfg.router.Route(req); err != nil {
+			l.logger.Warn("No model satisfies the routing policy", "complexity", req.Complexity, "prompt_tokens", req.PromptTokens, "rejected", decision.Rejected)
+			return nil, err
+		}
+	}
+	l.logger.Info("Routed request", "model", decision.Model, "reason", decision.Reason, "complexity", decision.Complexity, "prompt_tokens", decision.PromptTokens, "difficulty", decision.Difficulty, "estimated_cost", decision.EstimatedCost, "rejected", decision.Rejected)
+	options["model"] = decision.Model
+	return &decision, nil
+}
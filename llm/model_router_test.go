@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestModelRouter(t *testing.T) {
+	router := NewModelRouter(RoutingPolicy{
+		Models: []RoutedModel{
+			{Model: "gpt-4o-mini", MaxComplexity: TaskModerate, MaxPromptTokens: 1000, Latency: time.Second},
+			{Model: "gpt-4o", Latency: 3 * time.Second},
+			{Model: "o1", Latency: 20 * time.Second},
+		},
+		OutputTokens: 500,
+	})
+
+	decision, err := router.Route(RoutingRequest{Complexity: TaskSimple, PromptTokens: 200})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", decision.Model)
+	assert.Equal(t, "policy", decision.Reason)
+	assert.InDelta(t, (200*0.15+500*0.60)/1e6, decision.EstimatedCost, 1e-12)
+
+	decision, err = router.Route(RoutingRequest{Complexity: TaskComplex, PromptTokens: 200})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", decision.Model)
+	assert.Contains(t, decision.Rejected["gpt-4o-mini"], "complex task")
+
+	decision, err = router.Route(RoutingRequest{PromptTokens: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", decision.Model)
+	assert.Contains(t, decision.Rejected["gpt-4o-mini"], "prompt of 5000 tokens")
+
+	strict := NewModelRouter(RoutingPolicy{Models: router.Policy().Models, LatencySLO: 2 * time.Second, MaxCost: 0.001})
+	_, err = strict.Route(RoutingRequest{Complexity: TaskComplex, PromptTokens: 200, OutputTokens: 500})
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, "", config.SetProvider("openai"), config.SetModel("gpt-4o"), config.SetAPIKey("key"), config.SetBaseURL("openai", server.URL))
+	var resp Response
+	_, err = l.Generate(context.Background(), NewPrompt("Is this spam?"), WithModelRouter(router), WithTaskComplexity(TaskSimple), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", request["model"])
+	assert.Equal(t, "gpt-4o-mini", resp.Model)
+	routing, ok := resp.Metadata["routing"].(RoutingDecision)
+	require.True(t, ok)
+	assert.Equal(t, TaskSimple, routing.Complexity)
+
+	_, err = l.Generate(context.Background(), NewPrompt("Is this spam?"), WithModelRouter(router), WithModel("o1"))
+	require.NoError(t, err)
+	assert.Equal(t, "o1", request["model"])
+}
@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// PostProcessor transforms the text generated by Generate. An error fails
+// the attempt, which is retried like other failed attempts, so processors
+// can also reject unusable output. Any func(string) (string, error) can be
+// used as a PostProcessor.
+type PostProcessor func(text string) (string, error)
+
+// WithPostProcessors applies processors, in order, to the text generated by
+// the Generate call. Response.Content holds the processed text.
+//
+// Example:
+//
+//	answer, err := client.Generate(ctx, prompt, llm.WithPostProcessors(
+//	    llm.StripCodeFences(),
+//	    llm.MaxSentences(2),
+//	    llm.EnforceLanguage("fr"),
+//	))
+func WithPostProcessors(processors ...PostProcessor) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.PostProcessors = append(c.PostProcessors, processors...)
+	}
+}
+
+// postProcess applies the processors to text.
+func postProcess(text string, processors []PostProcessor) (string, error) {
+	for _, process := range processors {
+		var err error
+		if text, err = process(text); err != nil {
+			return "", NewLLMError(ErrorTypeResponse, "post-processing failed", err)
+		}
+	}
+	return text, nil
+}
+
+var codeFence = regexp.MustCompile("(?s)```[\\w+-]*[ \\t]*\\n?(.*?)\\n?```")
+
+// StripCodeFences returns the content of the first markdown code block of
+// the text, such as a ```json block, or the text itself when it has none.
+func StripCodeFences() PostProcessor {
+	return func(text string) (string, error) {
+		if match := codeFence.FindStringSubmatch(text); match != nil {
+			return strings.TrimSpace(match[1]), nil
+		}
+		return strings.TrimSpace(text), nil
+	}
+}
+
+// ExtractJSON returns the first JSON object or array of the text, skipping
+// any surrounding prose or markdown. It fails when the text has none.
+func ExtractJSON() PostProcessor {
+	return func(text string) (string, error) {
+		for start := 0; start < len(text); start++ {
+			if text[start] != '{' && text[start] != '[' {
+				continue
+			}
+			decoder := json.NewDecoder(strings.NewReader(text[start:]))
+			var value json.RawMessage
+			if decoder.Decode(&value) == nil {
+				return string(value), nil
+			}
+		}
+		return "", fmt.Errorf("no JSON object or array in the response")
+	}
+}
+
+var sentenceEnd = regexp.MustCompile(`[.!?…。！？]+["')\]]*(\s+|$)`)
+
+// MaxSentences keeps the first n sentences of the text.
+func MaxSentences(n int) PostProcessor {
+	return func(text string) (string, error) {
+		text = strings.TrimSpace(text)
+		ends := sentenceEnd.FindAllStringIndex(text, n)
+		if len(ends) < n {
+			return text, nil
+		}
+		return strings.TrimSpace(text[:ends[n-1][1]]), nil
+	}
+}
+
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// MaxWords keeps the first n words of the text, ending at the last complete
+// sentence within them when there is one.
+func MaxWords(n int) PostProcessor {
+	return func(text string) (string, error) {
+		text = strings.TrimSpace(text)
+		words := wordPattern.FindAllStringIndex(text, n+1)
+		if n < 1 || len(words) <= n {
+			return text, nil
+		}
+		cut := text[:words[n-1][1]]
+		if ends := sentenceEnd.FindAllStringIndex(cut, -1); len(ends) > 0 {
+			return strings.TrimSpace(cut[:ends[len(ends)-1][1]]), nil
+		}
+		return cut, nil
+	}
+}
+
+// EnforceLanguage fails when the text is not written in the language, an
+// ISO 639-1 code such as "en" or "ja", so the attempt is retried. The
+// language is detected from the script for languages with their own script
+// (ar, el, he, hi, ja, ko, ru, th, zh) and from common words for de, en, es,
+// fr, it, nl and pt. Text whose language cannot be detected is accepted.
+func EnforceLanguage(language string) PostProcessor {
+	language = strings.ToLower(language)
+	return func(text string) (string, error) {
+		detected := DetectLanguage(text)
+		if detected != "" && detected != language {
+			return "", fmt.Errorf("response is in %q instead of %q", detected, language)
+		}
+		return text, nil
+	}
+}
+
+// scriptLanguages maps scripts to the language they identify.
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// stopWords are frequent words of the Latin-script languages.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "this", "was", "you", "not"},
+	"fr": {"le", "la", "les", "et", "est", "des", "un", "une", "du", "que", "pour", "dans", "pas", "vous", "qui"},
+	"es": {"el", "la", "los", "las", "y", "es", "de", "que", "en", "un", "una", "por", "con", "para", "del"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "sie", "auf", "ich", "für"},
+	"it": {"il", "lo", "la", "gli", "e", "è", "di", "che", "un", "una", "per", "non", "con", "del", "sono"},
+	"pt": {"o", "a", "os", "as", "e", "é", "de", "que", "em", "um", "uma", "para", "com", "não", "do"},
+	"nl": {"de", "het", "een", "en", "is", "van", "dat", "niet", "op", "te", "zijn", "met", "voor", "ik", "je"},
+}
+
+// DetectLanguage returns the ISO 639-1 code of the language of the text, as
+// detected by EnforceLanguage, or "" when it cannot tell.
+func DetectLanguage(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				counts[script.language]++
+				break
+			}
+		}
+	}
+	// Japanese mixes kana with Han characters
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	best, bestCount := "", 0
+	for language, count := range counts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	if letters > 0 && bestCount*2 > letters {
+		return best
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]int)
+	for _, word := range words {
+		for language, list := range stopWords {
+			for _, stopWord := range list {
+				if word == stopWord {
+					scores[language]++
+					break
+				}
+			}
+		}
+	}
+	best, bestScore, second := "", 0, 0
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, second = language, score, bestScore
+		case score > second:
+			second = score
+		}
+	}
+	// Require a clear lead, as short texts share many words
+	if bestScore < 2 || bestScore == second {
+		return ""
+	}
+	return best
+}
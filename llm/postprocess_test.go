@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestPostProcessors(t *testing.T) {
+	strip, err := StripCodeFences()("Here it is:\n```json\n{\"a\": 1}\n```\nDone.")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1}`, strip)
+
+	extracted, err := ExtractJSON()(`Sure! {not json} then [1, {"b": "}"}] and more`)
+	require.NoError(t, err)
+	assert.Equal(t, `[1, {"b": "}"}]`, extracted)
+	_, err = ExtractJSON()("no json here")
+	assert.Error(t, err)
+
+	short, err := MaxSentences(2)("First one. Second one! Third one? Fourth.")
+	require.NoError(t, err)
+	assert.Equal(t, "First one. Second one!", short)
+
+	assert.Equal(t, "fr", DetectLanguage("Le chat est sur la table et il dort dans le salon."))
+	assert.Equal(t, "en", DetectLanguage("The cat is on the table and it sleeps in the living room."))
+	assert.Equal(t, "ja", DetectLanguage("猫はテーブルの上にいます。"))
+	assert.Equal(t, "", DetectLanguage("OK"))
+
+	// A failing processor fails the attempt, which is retried
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL, config.SetMaxRetries(1))
+	attempts := 0
+	var response Response
+	result, err := l.Generate(context.Background(), NewPrompt("hello"), WithResponse(&response), WithPostProcessors(
+		func(text string) (string, error) {
+			attempts++
+			if attempts == 1 {
+				return "", fmt.Errorf("rejected")
+			}
+			return "processed", nil
+		},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "processed", result)
+	assert.Equal(t, "processed", response.Content)
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelPricing holds the price of a model in US dollars per million tokens.
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+	// CachedInput is the price of input tokens read from the prompt cache.
+	// Zero means cached tokens cost the same as Input.
+	CachedInput float64 `json:"cached_input,omitempty"`
+	// CacheWrite is the price of input tokens written to the prompt cache.
+	// Zero means written tokens cost the same as Input.
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+var (
+	pricingMu sync.RWMutex
+	// pricing is keyed by model name prefix; the longest matching prefix wins,
+	// so dated snapshots such as gpt-4o-2024-08-06 use the gpt-4o entry.
+	pricing = map[string]ModelPricing{
+		"gpt-4o":                  {Input: 2.50, Output: 10.00, CachedInput: 1.25},
+		"gpt-4o-mini":             {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+		"gpt-4.1":                 {Input: 2.00, Output: 8.00, CachedInput: 0.50},
+		"gpt-4.1-mini":            {Input: 0.40, Output: 1.60, CachedInput: 0.10},
+		"gpt-4.1-nano":            {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+		"gpt-4-turbo":             {Input: 10.00, Output: 30.00},
+		"gpt-3.5-turbo":           {Input: 0.50, Output: 1.50},
+		"o1":                      {Input: 15.00, Output: 60.00, CachedInput: 7.50},
+		"o1-mini":                 {Input: 1.10, Output: 4.40, CachedInput: 0.55},
+		"o3-mini":                 {Input: 1.10, Output: 4.40, CachedInput: 0.55},
+		"o4-mini":                 {Input: 1.10, Output: 4.40, CachedInput: 0.275},
+		"claude-3-5-sonnet":       {Input: 3.00, Output: 15.00, CachedInput: 0.30, CacheWrite: 3.75},
+		"claude-3-7-sonnet":       {Input: 3.00, Output: 15.00, CachedInput: 0.30, CacheWrite: 3.75},
+		"claude-sonnet-4":         {Input: 3.00, Output: 15.00, CachedInput: 0.30, CacheWrite: 3.75},
+		"claude-3-5-haiku":        {Input: 0.80, Output: 4.00, CachedInput: 0.08, CacheWrite: 1.00},
+		"claude-3-haiku":          {Input: 0.25, Output: 1.25, CachedInput: 0.03, CacheWrite: 0.30},
+		"claude-3-opus":           {Input: 15.00, Output: 75.00, CachedInput: 1.50, CacheWrite: 18.75},
+		"claude-opus-4":           {Input: 15.00, Output: 75.00, CachedInput: 1.50, CacheWrite: 18.75},
+		"llama-3.1-8b-instant":    {Input: 0.05, Output: 0.08},
+		"llama-3.3-70b-versatile": {Input: 0.59, Output: 0.79},
+		"mistral-large":           {Input: 2.00, Output: 6.00},
+		"mistral-small":           {Input: 0.10, Output: 0.30},
+		"open-mistral-nemo":       {Input: 0.15, Output: 0.15},
+		"command-r-plus":          {Input: 2.50, Output: 10.00},
+		"command-r":               {Input: 0.15, Output: 0.60},
+		"command-a":               {Input: 2.50, Output: 10.00},
+		"gemini-2.5-pro":          {Input: 1.25, Output: 10.00, CachedInput: 0.31},
+		"gemini-2.5-flash":        {Input: 0.30, Output: 2.50, CachedInput: 0.075},
+		"gemini-2.5-flash-lite":   {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+		"gemini-2.0-flash":        {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+		"gemini-1.5-pro":          {Input: 1.25, Output: 5.00, CachedInput: 0.3125},
+		"gemini-1.5-flash":        {Input: 0.075, Output: 0.30, CachedInput: 0.01875},
+		"deepseek-chat":           {Input: 0.27, Output: 1.10, CachedInput: 0.07},
+		"deepseek-reasoner":       {Input: 0.55, Output: 2.19, CachedInput: 0.14},
+	}
+)
+
+// RegisterModelPricing sets the price of a model, or of every model whose
+// name starts with model. It overrides the built-in price, if any.
+func RegisterModelPricing(model string, p ModelPricing) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricing[model] = p
+}
+
+// LookupPricing returns the price of a model, matching the longest
+// registered name prefix.
+func LookupPricing(model string) (ModelPricing, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	var best string
+	for name := range pricing {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return pricing[best], true
+}
+
+// Cost returns the price in US dollars of the given usage.
+func (p ModelPricing) Cost(u Usage) float64 {
+	cachedPrice, writePrice := p.cachePrices()
+	uncached := u.PromptTokens - u.CachedTokens - u.CacheWriteTokens
+	return (float64(uncached)*p.Input + float64(u.CachedTokens)*cachedPrice + float64(u.CacheWriteTokens)*writePrice + float64(u.CompletionTokens)*p.Output) / 1e6
+}
+
+// CacheSavings returns how much less, in US dollars, the given usage cost
+// than it would have without prompt caching: the discount on cached tokens
+// minus the premium on tokens written to the cache. It is negative when the
+// cache was written more than it was read.
+func (p ModelPricing) CacheSavings(u Usage) float64 {
+	cachedPrice, writePrice := p.cachePrices()
+	return (float64(u.CachedTokens)*(p.Input-cachedPrice) - float64(u.CacheWriteTokens)*(writePrice-p.Input)) / 1e6
+}
+
+// cachePrices returns the prices of cached and cache-written input tokens.
+func (p ModelPricing) cachePrices() (cached, write float64) {
+	cached, write = p.CachedInput, p.CacheWrite
+	if cached == 0 {
+		cached = p.Input
+	}
+	if write == 0 {
+		write = p.Input
+	}
+	return cached, write
+}
+
+// EstimateCacheSavings returns the savings of prompt caching on the given
+// usage of a model, as ModelPricing.CacheSavings, and false when the model
+// has no known pricing.
+func EstimateCacheSavings(model string, u Usage) (float64, bool) {
+	p, ok := LookupPricing(model)
+	if !ok {
+		return 0, false
+	}
+	return p.CacheSavings(u), true
+}
+
+// EstimateCost returns the price in US dollars of the given usage of a model,
+// and false when the model has no known pricing.
+func EstimateCost(model string, u Usage) (float64, bool) {
+	p, ok := LookupPricing(model)
+	if !ok {
+		return 0, false
+	}
+	return p.Cost(u), true
+}
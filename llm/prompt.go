@@ -3,9 +3,12 @@ package llm
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/invopop/jsonschema"
+	"github.com/teilomillet/gollm/providers"
 	"github.com/teilomillet/gollm/utils"
 )
 
@@ -22,12 +25,13 @@ const (
 // It can be a system message, user message, or assistant message, and may include
 // tool calls and caching configuration.
 type PromptMessage struct {
-	Role       string     `json:"role"`                   // Role of the message sender (e.g., "system", "user", "assistant")
-	Content    string     `json:"content"`                // The actual message content
-	CacheType  CacheType  `json:"cache_type,omitempty"`   // Optional caching strategy for this message
-	Name       string     `json:"name,omitempty"`         // Optional name identifier for the message
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Optional tool calls requested by the LLM
-	ToolCallID string     `json:"tool_call_id,omitempty"` // ID of the tool call this message responds to
+	Role        string             `json:"role"`                   // Role of the message sender (e.g., "system", "user", "assistant")
+	Content     string             `json:"content"`                // The actual message content
+	CacheType   CacheType          `json:"cache_type,omitempty"`   // Optional caching strategy for this message
+	Name        string             `json:"name,omitempty"`         // Optional name identifier for the message
+	ToolCalls   []ToolCall         `json:"tool_calls,omitempty"`   // Optional tool calls requested by the LLM
+	ToolCallID  string             `json:"tool_call_id,omitempty"` // ID of the tool call this message responds to
+	Attachments []utils.Attachment `json:"attachments,omitempty"`  // Images and documents sent with this message
 }
 
 // ToolCall represents a request from the LLM to use a specific tool.
@@ -41,21 +45,39 @@ type ToolCall struct {
 	} `json:"function"`
 }
 
+// newToolCall creates a function ToolCall. Missing arguments default to an
+// empty JSON object.
+func newToolCall(id, name string, arguments json.RawMessage) ToolCall {
+	call := ToolCall{ID: id, Type: "function"}
+	call.Function.Name = name
+	call.Function.Arguments = arguments
+	if len(call.Function.Arguments) == 0 {
+		call.Function.Arguments = json.RawMessage("{}")
+	}
+	return call
+}
+
 // Prompt represents a complete prompt structure that can be sent to an LLM.
 // It includes various components like system messages, user input, context,
 // and optional elements like tools and examples.
 type Prompt struct {
-	Input           string                 `json:"input" jsonschema:"required,description=The main input text for the LLM" validate:"required"`
-	Output          string                 `json:"output,omitempty" jsonschema:"description=Specification for the expected output format"`
-	Directives      []string               `json:"directives,omitempty" jsonschema:"description=List of directives to guide the LLM"`
-	Context         string                 `json:"context,omitempty" jsonschema:"description=Additional context for the LLM"`
-	MaxLength       int                    `json:"maxLength,omitempty" jsonschema:"minimum=1,description=Maximum length of the response in words" validate:"omitempty,min=1"`
-	Examples        []string               `json:"examples,omitempty" jsonschema:"description=List of examples to guide the LLM"`
-	SystemPrompt    string                 `json:"systemPrompt,omitempty" jsonschema:"description=System prompt for the LLM"`
-	SystemCacheType CacheType              `json:"systemCacheType,omitempty" jsonschema:"description=Cache type for the system prompt"`
-	Messages        []PromptMessage        `json:"messages,omitempty" jsonschema:"description=List of messages for the conversation"`
-	Tools           []utils.Tool           `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
-	ToolChoice      map[string]interface{} `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
+	Input           string                    `json:"input" jsonschema:"required,description=The main input text for the LLM" validate:"required"`
+	Output          string                    `json:"output,omitempty" jsonschema:"description=Specification for the expected output format"`
+	Directives      []string                  `json:"directives,omitempty" jsonschema:"description=List of directives to guide the LLM"`
+	Context         string                    `json:"context,omitempty" jsonschema:"description=Additional context for the LLM"`
+	MaxLength       int                       `json:"maxLength,omitempty" jsonschema:"minimum=1,description=Maximum length of the response in words" validate:"omitempty,min=1"`
+	Examples        []string                  `json:"examples,omitempty" jsonschema:"description=List of examples to guide the LLM"`
+	SystemPrompt    string                    `json:"systemPrompt,omitempty" jsonschema:"description=System prompt for the LLM"`
+	SystemCacheType CacheType                 `json:"systemCacheType,omitempty" jsonschema:"description=Cache type for the system prompt"`
+	Messages        []PromptMessage           `json:"messages,omitempty" jsonschema:"description=List of messages for the conversation"`
+	Tools           []utils.Tool              `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
+	ToolChoice      map[string]interface{}    `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
+	Attachments     []utils.Attachment        `json:"attachments,omitempty" jsonschema:"description=Images and documents sent with the input"`
+	Documents       []utils.Document          `json:"documents,omitempty" jsonschema:"description=Sources the response should be grounded in"`
+	ResponsePrefill string                    `json:"responsePrefill,omitempty" jsonschema:"description=Beginning of the assistant's reply that the model continues"`
+	Grammar         string                    `json:"grammar,omitempty" jsonschema:"description=GBNF grammar the output must follow"`
+	RegexConstraint string                    `json:"regexConstraint,omitempty" jsonschema:"description=Regular expression the output must match"`
+	ReasoningEffort providers.ReasoningEffort `json:"reasoningEffort,omitempty" jsonschema:"enum=low,enum=medium,enum=high,description=How much a reasoning model thinks before answering"`
 }
 
 // PromptOption is a function type that modifies a Prompt.
@@ -124,6 +146,30 @@ func WithMessage(role, content string, cacheType CacheType) PromptOption {
 	}
 }
 
+// WithMessageAttachments attaches images or documents to the last message
+// of the prompt, so that each turn of a multi-turn conversation keeps its
+// own attachments. Unlike WithImageURL, which attaches to the prompt as a
+// whole, it requires a provider that accepts messages natively; other
+// providers receive all attachments with the prompt.
+//
+// Example:
+//
+//	prompt := NewPrompt("Now compare it with this second image.",
+//	    WithMessage("user", "Describe this image.", ""),
+//	    WithMessageAttachments(utils.Attachment{Type: utils.AttachmentImage, URL: firstURL}),
+//	    WithMessage("assistant", description, ""),
+//	    WithMessage("user", "Now compare it with this second image.", ""),
+//	    WithMessageAttachments(utils.Attachment{Type: utils.AttachmentImage, Path: "second.png"}),
+//	)
+func WithMessageAttachments(attachments ...utils.Attachment) PromptOption {
+	return func(p *Prompt) {
+		if len(p.Messages) > 0 {
+			last := &p.Messages[len(p.Messages)-1]
+			last.Attachments = append(last.Attachments, attachments...)
+		}
+	}
+}
+
 // WithTools configures the available tools for the LLM to use.
 //
 // Parameters:
@@ -186,7 +232,9 @@ func WithContext(context string) PromptOption {
 	}
 }
 
-// WithMaxLength sets the maximum length for the LLM's response.
+// WithMaxLength sets the maximum length for the LLM's response. The length
+// is asked for in the prompt; use WithLengthEnforcement to also cap
+// max_tokens and cut or shorten longer responses.
 //
 // Parameters:
 //   - length: Maximum response length in words
@@ -196,12 +244,192 @@ func WithMaxLength(length int) PromptOption {
 	}
 }
 
+// WithImageURL attaches an image by URL for vision models.
+//
+// Parameters:
+//   - url: HTTP(S) or data: URL of the image
+func WithImageURL(url string) PromptOption {
+	return func(p *Prompt) {
+		p.Attachments = append(p.Attachments, utils.Attachment{Type: utils.AttachmentImage, URL: url})
+	}
+}
+
+// WithImageFile attaches a local image for vision models.
+// The file is read and base64-encoded when the request is prepared.
+//
+// Parameters:
+//   - path: Path to the image file
+func WithImageFile(path string) PromptOption {
+	return func(p *Prompt) {
+		p.Attachments = append(p.Attachments, utils.Attachment{Type: utils.AttachmentImage, Path: path})
+	}
+}
+
+// CheckImageLimits returns an error when the images of the prompt, including
+// those of its messages, exceed limits: ErrorTypeInvalidInput for too many
+// or too large images, and ErrorTypeUnsupported for images by URL that must
+// be sent inline. The size of images by URL other than data: URLs is not
+// known, so they are not checked. Generate checks the limits of providers
+// that report them with providers.ImageLimitsProvider.
+//
+// Example:
+//
+//	limits := providers.ImageLimits{MaxImages: 100, MaxImageBytes: 5 << 20 * 3 / 4}
+//	if err := prompt.CheckImageLimits(limits); err != nil {
+//	    log.Fatal(err)
+//	}
+func (p *Prompt) CheckImageLimits(limits providers.ImageLimits) error {
+	count := 0
+	for _, a := range append(p.messageAttachments(), p.Attachments...) {
+		if a.Type != utils.AttachmentImage {
+			continue
+		}
+		count++
+		if limits.InlineOnly && a.URL != "" && !strings.HasPrefix(a.URL, "data:") {
+			return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("image %d (%s) must be sent as data, not by URL", count, a.Name()), nil)
+		}
+		if size, ok := a.Size(); ok && limits.MaxImageBytes > 0 && size > limits.MaxImageBytes {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("image %d (%s) is %s, over the limit of %s per image", count, a.Name(), formatSize(size), formatSize(limits.MaxImageBytes)), nil)
+		}
+	}
+	if limits.MaxImages > 0 && count > limits.MaxImages {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("prompt has %d images, over the limit of %d per request", count, limits.MaxImages), nil)
+	}
+	return nil
+}
+
+// formatSize formats a size in bytes in the largest unit up to megabytes.
+func formatSize(size int64) string {
+	unit, scale := "bytes", int64(1)
+	switch {
+	case size >= 1<<20:
+		unit, scale = "MB", 1<<20
+	case size >= 1<<10:
+		unit, scale = "KB", 1<<10
+	}
+	value := math.Round(float64(size)/float64(scale)*100) / 100
+	return strconv.FormatFloat(value, 'f', -1, 64) + " " + unit
+}
+
+// WithFile attaches a local document, such as a PDF, for models that accept files.
+// The file is read and base64-encoded when the request is prepared.
+//
+// Parameters:
+//   - path: Path to the document
+func WithFile(path string) PromptOption {
+	return func(p *Prompt) {
+		p.Attachments = append(p.Attachments, utils.Attachment{Type: utils.AttachmentFile, Path: path})
+	}
+}
+
+// WithDocuments adds documents the response should be grounded in.
+// Providers with native document support, such as Cohere, cite them in the
+// response (see Response.Metadata); for other providers the documents are
+// included in the prompt text.
+func WithDocuments(docs ...utils.Document) PromptOption {
+	return func(p *Prompt) {
+		p.Documents = append(p.Documents, docs...)
+	}
+}
+
+// WithResponsePrefill sets the beginning of the assistant's reply, which the
+// model continues. Starting the reply with "{" or a heading reliably forces
+// the output format. The prefill is part of the returned text. Providers
+// that cannot prime replies return an ErrorTypeUnsupported error.
+//
+// Example:
+//
+//	prompt := NewPrompt("List three colors as JSON", WithResponsePrefill("{"))
+func WithResponsePrefill(prefill string) PromptOption {
+	return func(p *Prompt) {
+		p.ResponsePrefill = prefill
+	}
+}
+
+// WithGrammar constrains the output to a GBNF grammar, enforced while the
+// model decodes. Self-hosted servers such as vLLM and llama.cpp support it;
+// other providers return an ErrorTypeUnsupported error.
+//
+// Example:
+//
+//	prompt := NewPrompt("Is the sky blue?", WithGrammar(`root ::= "yes" | "no"`))
+func WithGrammar(gbnf string) PromptOption {
+	return func(p *Prompt) {
+		p.Grammar = gbnf
+	}
+}
+
+// WithRegexConstraint constrains the output to match a regular expression,
+// enforced while the model decodes. vLLM supports it; other providers return
+// an ErrorTypeUnsupported error.
+//
+// Example:
+//
+//	prompt := NewPrompt("When was Go released?", WithRegexConstraint(`\d{4}-\d{2}-\d{2}`))
+func WithRegexConstraint(pattern string) PromptOption {
+	return func(p *Prompt) {
+		p.RegexConstraint = pattern
+	}
+}
+
+// WithReasoningEffort sets how much a reasoning model thinks before it
+// answers: providers.ReasoningLow, ReasoningMedium or ReasoningHigh. It maps
+// to OpenAI's reasoning_effort, Anthropic's extended thinking budget,
+// Gemini's thinking budget and OpenRouter's reasoning effort. Other
+// providers, and models that do not reason, return an ErrorTypeUnsupported
+// error.
+//
+// Example:
+//
+//	prompt := NewPrompt("Plan the database migration.", WithReasoningEffort(providers.ReasoningHigh))
+func WithReasoningEffort(effort providers.ReasoningEffort) PromptOption {
+	return func(p *Prompt) {
+		p.ReasoningEffort = effort
+	}
+}
+
 func WithJSONSchemaValidation() GenerateOption {
 	return func(c *GenerateConfig) {
 		c.UseJSONSchema = true
 	}
 }
 
+// WithStrictTools enables or disables strict mode for the tools of a
+// request. Strict mode is on by default for providers that support it, such
+// as OpenAI: tool schemas are normalized so the model's arguments always
+// match them. Disable it for schemas that rely on optional properties being
+// absent rather than null.
+func WithStrictTools(strict bool) GenerateOption {
+	return WithOption("strict_tools", strict)
+}
+
+// WithJSONMode selects how GenerateWithSchema constrains the response on
+// providers that offer both JSON modes, such as OpenAI, OpenRouter, Groq,
+// Mistral, Gemini and Cohere. providers.JSONModeObject asks for any JSON
+// object and describes the schema in the prompt, which works with schemas
+// strict mode rejects; providers.JSONModeSchema enforces the schema in strict
+// mode. Providers that do not offer the mode return an ErrorTypeUnsupported
+// error. Without it, each provider uses its default.
+//
+// Example:
+//
+//	result, err := client.GenerateWithSchema(ctx, prompt, schema, llm.WithJSONMode(providers.JSONModeObject))
+func WithJSONMode(mode providers.JSONMode) GenerateOption {
+	return WithOption("json_mode", mode)
+}
+
+// WithModel overrides the configured model for a single Generate call, as
+// the "model" request field. It suits gateways and routers that serve many
+// models behind one endpoint and key, such as the "gateway" provider. The
+// Response reports the model used.
+//
+// Example:
+//
+//	summary, err := client.Generate(ctx, prompt, llm.WithModel("claude-3-5-haiku"))
+func WithModel(model string) GenerateOption {
+	return WithOption("model", model)
+}
+
 // WithExamples adds example conversations or outputs to guide the LLM.
 // If a single example ends with .txt or .jsonl, it's treated as a file path.
 //
@@ -231,6 +459,73 @@ func (p *Prompt) Apply(opts ...PromptOption) {
 	}
 }
 
+// history returns the messages of the prompt as a conversation history, or
+// nil when its only message is the user message NewPrompt creates from the
+// input.
+func (p *Prompt) history() []providers.Message {
+	if len(p.Messages) == 0 || len(p.Messages) == 1 && p.Messages[0].Role == "user" && p.Messages[0].Content == p.Input && len(p.Messages[0].Attachments) == 0 {
+		return nil
+	}
+	history := make([]providers.Message, len(p.Messages))
+	for i, m := range p.Messages {
+		history[i] = providers.Message{
+			Role:        m.Role,
+			Content:     m.Content,
+			Name:        m.Name,
+			CacheType:   string(m.CacheType),
+			ToolCallID:  m.ToolCallID,
+			Attachments: m.Attachments,
+		}
+		for _, call := range m.ToolCalls {
+			history[i].ToolCalls = append(history[i].ToolCalls, providers.ToolCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+	}
+	return history
+}
+
+// messageAttachments returns the attachments of the messages of the prompt.
+func (p *Prompt) messageAttachments() []utils.Attachment {
+	var attachments []utils.Attachment
+	for _, m := range p.Messages {
+		attachments = append(attachments, m.Attachments...)
+	}
+	return attachments
+}
+
+// withoutHistory returns a copy of the prompt to render as the final user
+// message after its history. The system prompt is sent separately, and the
+// input is left out when it is already one of the user messages.
+func (p *Prompt) withoutHistory() *Prompt {
+	rest := *p
+	rest.Messages = nil
+	rest.SystemPrompt, rest.SystemCacheType = "", ""
+	for _, m := range p.Messages {
+		if m.Role == "user" && m.Content == p.Input {
+			rest.Input = ""
+			break
+		}
+	}
+	return &rest
+}
+
+// stablePrefix returns the text of the sections before the input, such as
+// the context and documents, which stay the same across requests that only
+// change the input.
+func (p *Prompt) stablePrefix() string {
+	var builder strings.Builder
+	for _, section := range p.sections() {
+		if section.Name == SectionInput {
+			break
+		}
+		builder.WriteString(section.Text)
+	}
+	return builder.String()
+}
+
 // String returns a formatted string representation of the prompt.
 // It includes all components (system prompt, context, directives, etc.)
 // in a human-readable format.
@@ -239,63 +534,116 @@ func (p *Prompt) Apply(opts ...PromptOption) {
 //   - Formatted prompt string
 func (p *Prompt) String() string {
 	var builder strings.Builder
+	for _, section := range p.sections() {
+		builder.WriteString(section.Text)
+	}
+	return builder.String()
+}
 
-	if p.SystemPrompt != "" {
-		builder.WriteString("System: ")
-		builder.WriteString(p.SystemPrompt)
-		if p.SystemCacheType != "" {
-			builder.WriteString(fmt.Sprintf(" (Cache: %s)", p.SystemCacheType))
+// sections assembles the text of the prompt part by part, in the order of
+// String. Empty parts are left out.
+func (p *Prompt) sections() []PromptSection {
+	var sections []PromptSection
+	add := func(name string, build func(builder *strings.Builder)) {
+		var builder strings.Builder
+		build(&builder)
+		if builder.Len() > 0 {
+			sections = append(sections, PromptSection{Name: name, Text: builder.String()})
 		}
-		builder.WriteString("\n\n")
 	}
 
-	if p.Context != "" {
-		builder.WriteString("Context: ")
-		builder.WriteString(p.Context)
-		builder.WriteString("\n\n")
-	}
+	add(SectionSystem, func(builder *strings.Builder) {
+		if p.SystemPrompt != "" {
+			builder.WriteString("System: ")
+			builder.WriteString(p.SystemPrompt)
+			if p.SystemCacheType != "" {
+				builder.WriteString(fmt.Sprintf(" (Cache: %s)", p.SystemCacheType))
+			}
+			builder.WriteString("\n\n")
+		}
+	})
 
-	if len(p.Directives) > 0 {
-		builder.WriteString("Directives:\n")
-		for _, d := range p.Directives {
-			builder.WriteString("- ")
-			builder.WriteString(d)
+	add(SectionContext, func(builder *strings.Builder) {
+		if p.Context != "" {
+			builder.WriteString("Context: ")
+			builder.WriteString(p.Context)
+			builder.WriteString("\n\n")
+		}
+	})
+
+	add(SectionDocuments, func(builder *strings.Builder) {
+		if len(p.Documents) > 0 {
+			builder.WriteString("Documents:\n")
+			for i, doc := range p.Documents {
+				id := doc.ID
+				if id == "" {
+					id = strconv.Itoa(i + 1)
+				}
+				builder.WriteString(fmt.Sprintf("[%s]", id))
+				if doc.Title != "" {
+					builder.WriteString(" " + doc.Title)
+				}
+				builder.WriteString("\n")
+				builder.WriteString(doc.Text)
+				builder.WriteString("\n")
+			}
 			builder.WriteString("\n")
 		}
-		builder.WriteString("\n")
-	}
-
-	builder.WriteString(p.Input)
+	})
+
+	add(SectionDirectives, func(builder *strings.Builder) {
+		if len(p.Directives) > 0 {
+			builder.WriteString("Directives:\n")
+			for _, d := range p.Directives {
+				builder.WriteString("- ")
+				builder.WriteString(d)
+				builder.WriteString("\n")
+			}
+			builder.WriteString("\n")
+		}
+	})
 
-	if p.Output != "" {
-		builder.WriteString("\n\nExpected Output Format:\n")
-		builder.WriteString(p.Output)
-	}
+	add(SectionInput, func(builder *strings.Builder) {
+		builder.WriteString(p.Input)
+	})
 
-	if len(p.Examples) > 0 {
-		builder.WriteString("\n\nExamples:\n")
-		for _, example := range p.Examples {
-			builder.WriteString("- ")
-			builder.WriteString(example)
-			builder.WriteString("\n")
+	add(SectionOutput, func(builder *strings.Builder) {
+		if p.Output != "" {
+			builder.WriteString("\n\nExpected Output Format:\n")
+			builder.WriteString(p.Output)
 		}
-	}
+	})
+
+	add(SectionExamples, func(builder *strings.Builder) {
+		if len(p.Examples) > 0 {
+			builder.WriteString("\n\nExamples:\n")
+			for _, example := range p.Examples {
+				builder.WriteString("- ")
+				builder.WriteString(example)
+				builder.WriteString("\n")
+			}
+		}
+	})
 
-	if p.MaxLength > 0 {
-		builder.WriteString(fmt.Sprintf("\n\nPlease limit your response to approximately %d words.", p.MaxLength))
-	}
+	add(SectionMaxLength, func(builder *strings.Builder) {
+		if p.MaxLength > 0 {
+			builder.WriteString(fmt.Sprintf("\n\nPlease limit your response to approximately %d words.", p.MaxLength))
+		}
+	})
 
-	if len(p.Messages) > 0 {
-		builder.WriteString("\nMessages:\n")
-		for _, msg := range p.Messages {
-			builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
-			if msg.CacheType != "" {
-				builder.WriteString(fmt.Sprintf("(Cache: %s)\n", msg.CacheType))
+	add(SectionMessages, func(builder *strings.Builder) {
+		if len(p.Messages) > 0 {
+			builder.WriteString("\nMessages:\n")
+			for _, msg := range p.Messages {
+				builder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+				if msg.CacheType != "" {
+					builder.WriteString(fmt.Sprintf("(Cache: %s)\n", msg.CacheType))
+				}
 			}
 		}
-	}
+	})
 
-	return builder.String()
+	return sections
 }
 
 // Validate checks if the prompt configuration is valid according to
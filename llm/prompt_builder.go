@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PriorityRequired marks a PromptBuilder section that is never truncated
+// nor dropped.
+const PriorityRequired = math.MaxInt
+
+// PromptBuilder assembles a Prompt from sections that declare a priority,
+// and fits them into a token budget: while the prompt is over budget, the
+// lowest-priority section loses its least useful item, truncated when it is
+// text that can be cut to fit, or dropped otherwise. Ties go against the
+// section added last. The same sections and budget always build the same
+// prompt.
+//
+// Example:
+//
+//	prompt, report, err := llm.NewPromptBuilder(4000).
+//	    System("You are a support assistant.", llm.PriorityRequired).
+//	    Question(question, llm.PriorityRequired).
+//	    History(conversation, 3).
+//	    Context(retrievedChunks, 2).
+//	    Examples(examples, 1).
+//	    Build()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, cut := range report.Dropped {
+//	    log.Printf("dropped %s #%d (%d tokens)", cut.Section, cut.Index, cut.Tokens)
+//	}
+type PromptBuilder struct {
+	budget   int
+	sections []*builderSection
+}
+
+// builderSection is a section of a PromptBuilder.
+type builderSection struct {
+	name     string
+	priority int
+	items    []builderItem
+	// truncate allows items to be cut to fit, and oldestFirst drops the
+	// first items first instead of the last, for conversation history.
+	truncate    bool
+	oldestFirst bool
+}
+
+// builderItem is a unit of a section that is truncated or dropped as a
+// whole: a text, a context chunk, an example or a message.
+type builderItem struct {
+	text    string
+	role    string
+	tokens  int
+	dropped bool
+}
+
+// PromptBudgetCut is a section item that was truncated or dropped to fit
+// the budget.
+type PromptBudgetCut struct {
+	// Section is the name of the section, such as SectionContext.
+	Section string `json:"section"`
+	// Index is the position of the item in its section, as added.
+	Index int `json:"index"`
+	// Tokens is the estimated number of tokens removed.
+	Tokens int `json:"tokens"`
+}
+
+// PromptBudgetReport is what a PromptBuilder removed to fit its budget.
+type PromptBudgetReport struct {
+	// Budget is the token budget of the builder.
+	Budget int `json:"budget"`
+	// Tokens is the estimated size of the built prompt's sections.
+	Tokens int `json:"tokens"`
+	// Truncated and Dropped list the items cut short or left out, in the
+	// order they were removed.
+	Truncated []PromptBudgetCut `json:"truncated,omitempty"`
+	Dropped   []PromptBudgetCut `json:"dropped,omitempty"`
+}
+
+// NewPromptBuilder creates a PromptBuilder fitting prompts into budget
+// tokens, as estimated by EstimateTokens. The labels the prompt adds around
+// the sections are not counted; leave a margin of a few tokens per section.
+func NewPromptBuilder(budget int) *PromptBuilder {
+	return &PromptBuilder{budget: budget}
+}
+
+// add appends a section of the texts.
+func (b *PromptBuilder) add(name string, priority int, texts []string, role func(int) string, truncate, oldestFirst bool) *PromptBuilder {
+	section := &builderSection{name: name, priority: priority, truncate: truncate, oldestFirst: oldestFirst}
+	for i, text := range texts {
+		item := builderItem{text: text, tokens: EstimateTokens(text)}
+		if role != nil {
+			item.role = role(i)
+		}
+		section.items = append(section.items, item)
+	}
+	b.sections = append(b.sections, section)
+	return b
+}
+
+// System adds the system prompt, which is truncated to fit.
+func (b *PromptBuilder) System(text string, priority int) *PromptBuilder {
+	return b.add(SectionSystem, priority, []string{text}, nil, true, false)
+}
+
+// Context adds context chunks, from the most to the least relevant. The
+// least relevant chunks are truncated or dropped first.
+func (b *PromptBuilder) Context(chunks []string, priority int) *PromptBuilder {
+	return b.add(SectionContext, priority, chunks, nil, true, false)
+}
+
+// Examples adds few-shot examples, from the most to the least useful. The
+// last examples are dropped first; examples are never truncated.
+func (b *PromptBuilder) Examples(examples []string, priority int) *PromptBuilder {
+	return b.add(SectionExamples, priority, examples, nil, false, false)
+}
+
+// History adds the conversation history before the question. The oldest
+// messages are dropped first; messages are never truncated.
+func (b *PromptBuilder) History(messages []PromptMessage, priority int) *PromptBuilder {
+	texts := make([]string, len(messages))
+	for i, m := range messages {
+		texts[i] = m.Content
+	}
+	return b.add(SectionMessages, priority, texts, func(i int) string { return messages[i].Role }, false, true)
+}
+
+// Question adds the question, the input of the prompt, which is truncated
+// to fit.
+func (b *PromptBuilder) Question(text string, priority int) *PromptBuilder {
+	return b.add(SectionInput, priority, []string{text}, nil, true, false)
+}
+
+// Build fits the sections into the budget and returns the prompt with a
+// report of what was truncated or dropped. It fails with
+// ErrorTypeInvalidInput when the required sections alone exceed the budget.
+func (b *PromptBuilder) Build() (*Prompt, *PromptBudgetReport, error) {
+	// Work on copies so that Build can be called again
+	sections := make([]*builderSection, len(b.sections))
+	total := 0
+	for i, s := range b.sections {
+		copied := *s
+		copied.items = append([]builderItem(nil), s.items...)
+		sections[i] = &copied
+		for _, item := range s.items {
+			total += item.tokens
+		}
+	}
+	report := &PromptBudgetReport{Budget: b.budget}
+
+	for total > b.budget {
+		section, index := nextCut(sections)
+		if section == nil {
+			report.Tokens = total
+			return nil, report, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("required prompt sections of %d tokens exceed the budget of %d tokens", total, b.budget), nil)
+		}
+		item := &section.items[index]
+		excess := total - b.budget
+		if section.truncate && item.tokens > excess {
+			if text := truncateWords(item.text, (item.tokens-excess)*4); text != "" {
+				removed := item.tokens - EstimateTokens(text)
+				item.text, item.tokens = text, item.tokens-removed
+				total -= removed
+				report.Truncated = append(report.Truncated, PromptBudgetCut{Section: section.name, Index: index, Tokens: removed})
+				continue
+			}
+		}
+		item.dropped = true
+		total -= item.tokens
+		report.Dropped = append(report.Dropped, PromptBudgetCut{Section: section.name, Index: index, Tokens: item.tokens})
+	}
+	report.Tokens = total
+	return assemblePrompt(sections), report, nil
+}
+
+// nextCut returns the section and the index of the item to cut next: the
+// least useful remaining item of the lowest-priority section that is not
+// required, the section added last winning ties. It returns nil when only
+// required items remain.
+func nextCut(sections []*builderSection) (*builderSection, int) {
+	var cut *builderSection
+	cutIndex := -1
+	for _, s := range sections {
+		if s.priority == PriorityRequired || (cut != nil && s.priority > cut.priority) {
+			continue
+		}
+		index := -1
+		for i := range s.items {
+			j := len(s.items) - 1 - i
+			if s.oldestFirst {
+				j = i
+			}
+			if !s.items[j].dropped && s.items[j].tokens > 0 {
+				index = j
+				break
+			}
+		}
+		if index >= 0 {
+			cut, cutIndex = s, index
+		}
+	}
+	return cut, cutIndex
+}
+
+// assemblePrompt builds the prompt of the remaining items of sections.
+func assemblePrompt(sections []*builderSection) *Prompt {
+	var system, context, input []string
+	var examples []string
+	var messages []PromptMessage
+	for _, s := range sections {
+		for _, item := range s.items {
+			if item.dropped {
+				continue
+			}
+			switch s.name {
+			case SectionSystem:
+				system = append(system, item.text)
+			case SectionContext:
+				context = append(context, item.text)
+			case SectionExamples:
+				examples = append(examples, item.text)
+			case SectionMessages:
+				messages = append(messages, PromptMessage{Role: item.role, Content: item.text})
+			case SectionInput:
+				input = append(input, item.text)
+			}
+		}
+	}
+
+	prompt := NewPrompt(strings.Join(input, "\n\n"))
+	prompt.SystemPrompt = strings.Join(system, "\n\n")
+	prompt.Context = strings.Join(context, "\n\n")
+	prompt.Examples = examples
+	if len(messages) > 0 {
+		prompt.Messages = append(messages, prompt.Messages...)
+	}
+	return prompt
+}
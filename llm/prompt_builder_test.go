@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptBuilder(t *testing.T) {
+	chunk := func(word string) string { return strings.TrimSpace(strings.Repeat(word+" ", 20)) } // 4-letter words: 25 tokens
+	history := []PromptMessage{
+		{Role: "user", Content: chunk("frst")},
+		{Role: "assistant", Content: chunk("rply")},
+	}
+	build := func(budget int) (*Prompt, *PromptBudgetReport, error) {
+		return NewPromptBuilder(budget).
+			System("You are a support assistant.", PriorityRequired).
+			Question("How do I reset my password?", PriorityRequired).
+			History(history, 3).
+			Context([]string{chunk("alfa"), chunk("beta")}, 2).
+			Examples([]string{chunk("exmp")}, 1).
+			Build()
+	}
+
+	prompt, report, err := build(1000)
+	require.NoError(t, err)
+	assert.Empty(t, report.Dropped)
+	assert.Empty(t, report.Truncated)
+	assert.Equal(t, "How do I reset my password?", prompt.Input)
+	assert.Equal(t, chunk("alfa")+"\n\n"+chunk("beta"), prompt.Context)
+	assert.Len(t, prompt.Examples, 1)
+	require.Len(t, prompt.Messages, 3)
+	assert.Equal(t, PromptMessage{Role: "user", Content: "How do I reset my password?"}, prompt.Messages[2])
+
+	// 7 + 7 + 125 tokens: the example goes, then the second chunk is cut
+	prompt, report, err = build(110)
+	require.NoError(t, err)
+	assert.Equal(t, []PromptBudgetCut{{Section: SectionExamples, Index: 0, Tokens: 25}}, report.Dropped)
+	require.Len(t, report.Truncated, 1)
+	assert.Equal(t, SectionContext, report.Truncated[0].Section)
+	assert.Equal(t, 1, report.Truncated[0].Index)
+	assert.LessOrEqual(t, report.Tokens, 110)
+	assert.Empty(t, prompt.Examples)
+	assert.True(t, strings.HasPrefix(prompt.Context, chunk("alfa")+"\n\nbeta beta"))
+
+	// Without room for the context, the oldest message goes next
+	prompt, report, err = build(40)
+	require.NoError(t, err)
+	assert.Equal(t, []PromptBudgetCut{
+		{Section: SectionExamples, Index: 0, Tokens: 25},
+		{Section: SectionContext, Index: 1, Tokens: 25},
+		{Section: SectionContext, Index: 0, Tokens: 25},
+		{Section: SectionMessages, Index: 0, Tokens: 25},
+	}, report.Dropped)
+	assert.Empty(t, prompt.Context)
+	require.Len(t, prompt.Messages, 2)
+	assert.Equal(t, "assistant", prompt.Messages[0].Role)
+
+	_, _, err = build(10)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PromptSchemaVersion is the version of the JSON encoding of prompts. It is
+// written with every encoded prompt, and prompts written by a newer version
+// of gollm are rejected instead of being silently truncated.
+const PromptSchemaVersion = 1
+
+// promptFields has the fields of Prompt without its methods, so the JSON
+// methods can encode them without recursing.
+type promptFields Prompt
+
+// MarshalJSON encodes the prompt with every field, including attachments,
+// tools and cache directives, and the schema version.
+func (p Prompt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schemaVersion"`
+		promptFields
+	}{PromptSchemaVersion, promptFields(p)})
+}
+
+// UnmarshalJSON decodes a prompt encoded by MarshalJSON. Prompts without a
+// schema version are accepted as version 1.
+func (p *Prompt) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		SchemaVersion int `json:"schemaVersion"`
+		promptFields
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.SchemaVersion > PromptSchemaVersion {
+		return fmt.Errorf("prompt schema version %d is newer than the supported version %d", decoded.SchemaVersion, PromptSchemaVersion)
+	}
+	*p = Prompt(decoded.promptFields)
+	return nil
+}
+
+// SavePrompt writes the prompt to a JSON file that LoadPrompt reads back, so
+// prompts can be stored, audited and replayed exactly. Attachments read from
+// local files are embedded in the file, so it does not depend on them.
+//
+// Example:
+//
+//	if err := llm.SavePrompt("prompts/summary.json", prompt); err != nil {
+//	    log.Fatal(err)
+//	}
+func SavePrompt(path string, prompt *Prompt) error {
+	saved := *prompt
+	if len(prompt.Attachments) > 0 {
+		saved.Attachments = append(saved.Attachments[:0:0], prompt.Attachments...)
+		for i, a := range saved.Attachments {
+			if a.Path == "" {
+				continue
+			}
+			data, mediaType, err := a.Load()
+			if err != nil {
+				return fmt.Errorf("failed to embed attachment: %w", err)
+			}
+			saved.Attachments[i].Data = data
+			saved.Attachments[i].MediaType = mediaType
+			saved.Attachments[i].Filename = a.Name()
+			saved.Attachments[i].Path = ""
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return nil
+}
+
+// LoadPrompt reads a prompt written by SavePrompt.
+func LoadPrompt(path string) (*Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	var prompt Prompt
+	if err := json.Unmarshal(data, &prompt); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt file %s: %w", path, err)
+	}
+	return &prompt, nil
+}
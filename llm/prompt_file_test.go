@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestSaveAndLoadPrompt(t *testing.T) {
+	dir := t.TempDir()
+	image := dir + "/chart.png"
+	require.NoError(t, os.WriteFile(image, []byte("\x89PNG\r\n\x1a\nchart"), 0o644))
+
+	prompt := NewPrompt("Describe the chart",
+		WithSystemPrompt("You are an analyst.", CacheTypeEphemeral),
+		WithDirectives("Be brief"),
+		WithImageFile(image),
+		WithTools([]utils.Tool{{Type: "function", Function: utils.Function{Name: "lookup", Parameters: map[string]interface{}{"type": "object"}}}}),
+		WithToolChoice("auto"),
+	)
+	path := dir + "/prompt.json"
+	require.NoError(t, SavePrompt(path, prompt))
+	require.NoError(t, os.Remove(image))
+
+	loaded, err := LoadPrompt(path)
+	require.NoError(t, err)
+	assert.Equal(t, prompt.String(), loaded.String())
+	assert.Equal(t, CacheTypeEphemeral, loaded.SystemCacheType)
+	assert.Equal(t, prompt.Tools, loaded.Tools)
+	assert.Equal(t, prompt.ToolChoice, loaded.ToolChoice)
+	require.Len(t, loaded.Attachments, 1)
+	assert.Empty(t, loaded.Attachments[0].Path)
+	assert.Equal(t, "chart.png", loaded.Attachments[0].Filename)
+	assert.Equal(t, "image/png", loaded.Attachments[0].MediaType)
+	assert.Equal(t, image, prompt.Attachments[0].Path, "the saved prompt is not modified")
+
+	var newer Prompt
+	assert.Error(t, json.Unmarshal([]byte(`{"schemaVersion": 99, "input": "hi"}`), &newer))
+}
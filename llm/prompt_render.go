@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Names of the sections of a rendered prompt.
+const (
+	SectionSystem      = "system"
+	SectionContext     = "context"
+	SectionDocuments   = "documents"
+	SectionDirectives  = "directives"
+	SectionInput       = "input"
+	SectionOutput      = "output"
+	SectionExamples    = "examples"
+	SectionMaxLength   = "max_length"
+	SectionMessages    = "messages"
+	SectionTools       = "tools"
+	SectionAttachments = "attachments"
+)
+
+// PromptSection is one part of what a provider receives for a prompt.
+type PromptSection struct {
+	// Name identifies the section, such as SectionDirectives.
+	Name string
+
+	// Text is the text of the section as sent.
+	Text string
+
+	// Tokens is the estimated number of tokens of Text.
+	Tokens int
+
+	// Separate is true for sections sent outside the prompt text, such as
+	// tool definitions or documents passed to the provider natively.
+	Separate bool
+}
+
+// RenderedPrompt shows what a provider receives for a prompt, section by
+// section, with estimated token counts.
+type RenderedPrompt struct {
+	// Provider is the name of the provider the prompt was rendered for.
+	Provider string
+
+	// SystemPrompt is sent as the system message, in addition to Text.
+	SystemPrompt string
+
+	// Text is the prompt text sent as the user message.
+	Text string
+
+	// Sections are the parts of Text, followed by the separate sections.
+	Sections []PromptSection
+
+	// Tokens is the estimated total of all sections.
+	Tokens int
+}
+
+// EstimateTokens returns a rough token count of text, about four bytes per
+// token, which is close for English text with most tokenizers. Use the
+// provider's usage report when an exact count matters.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Render returns exactly what provider receives for the prompt, after the
+// directives, context, output format and examples are assembled into the
+// prompt text. A nil provider renders the prompt as providers without native
+// document support receive it.
+//
+// Example:
+//
+//	provider, _ := providers.NewProviderRegistry().Get("anthropic", "", "claude-3-5-sonnet-latest", nil)
+//	fmt.Println(prompt.Render(provider))
+func (p *Prompt) Render(provider providers.Provider) *RenderedPrompt {
+	rendered := &RenderedPrompt{SystemPrompt: p.SystemPrompt}
+	inline := p
+	if provider != nil {
+		rendered.Provider = provider.Name()
+		options := map[string]interface{}{}
+		rendered.Text = promptText(provider, p, options)
+		if _, native := options["documents"]; native {
+			withoutDocs := *p
+			withoutDocs.Documents = nil
+			inline = &withoutDocs
+		}
+	} else {
+		rendered.Text = p.String()
+	}
+
+	rendered.Sections = inline.sections()
+	if inline != p {
+		rendered.Sections = append(rendered.Sections, PromptSection{
+			Name:     SectionDocuments,
+			Text:     renderJSON(p.Documents),
+			Separate: true,
+		})
+	}
+	if len(p.Tools) > 0 {
+		rendered.Sections = append(rendered.Sections, PromptSection{
+			Name:     SectionTools,
+			Text:     renderJSON(p.Tools),
+			Separate: true,
+		})
+	}
+	if len(p.Attachments) > 0 {
+		names := make([]string, len(p.Attachments))
+		for i, a := range p.Attachments {
+			names[i] = fmt.Sprintf("%s (%s)", a.Name(), a.Type)
+		}
+		// Image and file tokens depend on the provider; they are not estimated
+		rendered.Sections = append(rendered.Sections, PromptSection{
+			Name:     SectionAttachments,
+			Text:     strings.Join(names, "\n"),
+			Separate: true,
+		})
+	}
+
+	for i := range rendered.Sections {
+		section := &rendered.Sections[i]
+		if section.Name != SectionAttachments {
+			section.Tokens = EstimateTokens(section.Text)
+		}
+		rendered.Tokens += section.Tokens
+	}
+	return rendered
+}
+
+func renderJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// String returns a report of the token counts per section followed by the
+// prompt text and the separate sections.
+func (r *RenderedPrompt) String() string {
+	var builder strings.Builder
+	if r.Provider != "" {
+		fmt.Fprintf(&builder, "Provider: %s\n", r.Provider)
+	}
+	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, section := range r.Sections {
+		name := section.Name
+		if section.Separate {
+			name += " (separate)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t\n", name, section.Tokens)
+	}
+	fmt.Fprintf(w, "total\t%d\t\n", r.Tokens)
+	_ = w.Flush()
+
+	builder.WriteString("\n--- text ---\n")
+	builder.WriteString(r.Text)
+	builder.WriteString("\n")
+	for _, section := range r.Sections {
+		if section.Separate {
+			fmt.Fprintf(&builder, "--- %s ---\n%s\n", section.Name, section.Text)
+		}
+	}
+	return builder.String()
+}
+
+// Diff compares the rendered text of two prompts section by section. For
+// each changed section, it returns a header with the token counts in both
+// prompts followed by the section's lines, prefixed with "-" when only in p,
+// "+" when only in other, and a space when unchanged. It returns an empty
+// string when the prompts render the same.
+//
+// Example:
+//
+//	revised := *prompt
+//	revised.Apply(llm.WithOutput("A bulleted list"))
+//	fmt.Print(prompt.Diff(&revised))
+func (p *Prompt) Diff(other *Prompt) string {
+	before := p.Render(nil).Sections
+	after := other.Render(nil).Sections
+
+	find := func(sections []PromptSection, name string) PromptSection {
+		for _, section := range sections {
+			if section.Name == name {
+				return section
+			}
+		}
+		return PromptSection{Name: name}
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, sections := range [][]PromptSection{before, after} {
+		for _, section := range sections {
+			if !seen[section.Name] {
+				seen[section.Name] = true
+				names = append(names, section.Name)
+			}
+		}
+	}
+
+	var builder strings.Builder
+	for _, name := range names {
+		from, to := find(before, name), find(after, name)
+		if from.Text == to.Text {
+			continue
+		}
+		fmt.Fprintf(&builder, "@@ %s (%d -> %d tokens)\n", name, from.Tokens, to.Tokens)
+		for _, line := range diffLines(splitLines(from.Text), splitLines(to.Text)) {
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+// splitLines splits a section into lines, without the blank lines that
+// separate it from the next section.
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines returns the lines removed from a, prefixed with "-", and the
+// lines added in b, prefixed with "+", using their longest common
+// subsequence. Unchanged lines are prefixed with a space.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+b[j])
+	}
+	return lines
+}
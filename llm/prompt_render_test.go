@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestRenderAndDiff(t *testing.T) {
+	prompt := NewPrompt("Summarize the report",
+		WithDirectives("Be brief"),
+		WithContext("Quarterly results"),
+		WithDocuments(utils.Document{ID: "q3", Text: "Revenue grew 10%."}),
+	)
+
+	rendered := prompt.Render(nil)
+	assert.Equal(t, prompt.String(), rendered.Text)
+	var names []string
+	var text strings.Builder
+	for _, section := range rendered.Sections {
+		names = append(names, section.Name)
+		text.WriteString(section.Text)
+		assert.Equal(t, EstimateTokens(section.Text), section.Tokens)
+	}
+	assert.Equal(t, []string{SectionContext, SectionDocuments, SectionDirectives, SectionInput, SectionMessages}, names)
+	assert.Equal(t, rendered.Text, text.String(), "sections assemble into the text")
+
+	cohere := prompt.Render(providers.NewCohereProvider("key", "command-r", nil))
+	assert.NotContains(t, cohere.Text, "Revenue grew")
+	last := cohere.Sections[len(cohere.Sections)-1]
+	assert.Equal(t, SectionDocuments, last.Name)
+	assert.True(t, last.Separate)
+
+	assert.Empty(t, prompt.Diff(prompt))
+	revised := *prompt
+	revised.Apply(WithDirectives("Use bullet points"))
+	assert.Equal(t, "@@ directives (6 -> 11 tokens)\n Directives:\n - Be brief\n+- Use bullet points\n", prompt.Diff(&revised))
+}
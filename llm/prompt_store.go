@@ -0,0 +1,403 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// PromptVersion is a version of a named prompt. Versions are immutable once
+// saved, so a version names exactly the prompt that produced a response.
+type PromptVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Template is the input of the prompt, a Go template executed with the
+	// variables given to PromptManager.Get, as for PromptTemplate.
+	Template string `json:"template"`
+	// SystemPrompt is the system prompt of the prompt, if any.
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RolloutVersion is the share of requests served by a version of a prompt.
+type RolloutVersion struct {
+	Version string  `json:"version"`
+	Percent float64 `json:"percent"`
+}
+
+// Rollout splits the requests of an environment between versions of a
+// prompt, such as 90% on "v3" and 10% on "v4". The percentages add up
+// to 100.
+type Rollout []RolloutVersion
+
+// PromptStore stores named, versioned prompts and how each environment rolls
+// them out. NewMemoryPromptStore and NewFilePromptStore are ready to use; a
+// database can back a PromptStore by implementing it.
+type PromptStore interface {
+	// SavePromptVersion adds a version of a prompt. It fails when the
+	// version exists.
+	SavePromptVersion(ctx context.Context, version PromptVersion) error
+
+	// PromptVersions returns the versions of a prompt, oldest first.
+	PromptVersions(ctx context.Context, name string) ([]PromptVersion, error)
+
+	// SetRollout sets the rollout of a prompt in an environment, such as
+	// "staging" or "production".
+	SetRollout(ctx context.Context, name, environment string, rollout Rollout) error
+
+	// Rollout returns the rollout of a prompt in an environment, or nil
+	// when none is set.
+	Rollout(ctx context.Context, name, environment string) (Rollout, error)
+}
+
+// PromptRef identifies the version of a prompt that produced a response.
+// Generate stores it in the Response metadata under the "prompt_version"
+// key when given with WithPromptVersion.
+type PromptRef struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// String returns the reference as "name@version".
+func (r PromptRef) String() string {
+	return r.Name + "@" + r.Version
+}
+
+// WithPromptVersion records the version of the prompt of the Generate call:
+// the Response passed to WithResponse holds ref under the "prompt_version"
+// metadata key, and the call is tagged "prompt:name@version" for a
+// UsageTracker.
+func WithPromptVersion(ref PromptRef) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.promptVersion = &ref
+		c.Tags = append(c.Tags, "prompt:"+ref.String())
+	}
+}
+
+// PromptManager serves the prompts of a PromptStore in one environment,
+// selecting the version of each request according to the rollout.
+//
+// Example:
+//
+//	prompts := llm.NewPromptManager(llm.NewFilePromptStore("prompts"), "production")
+//	prompt, ref, err := prompts.Get(ctx, "summary", userID, map[string]interface{}{"text": article})
+//	summary, err := client.Generate(ctx, prompt, llm.WithPromptVersion(ref), llm.WithResponse(&resp))
+type PromptManager struct {
+	store       PromptStore
+	environment string
+}
+
+// NewPromptManager creates a PromptManager serving the prompts of store in
+// environment.
+func NewPromptManager(store PromptStore, environment string) *PromptManager {
+	return &PromptManager{store: store, environment: environment}
+}
+
+// Get returns the prompt name for a request, executed with vars, and the
+// reference of its version. The version is picked according to the rollout
+// of the environment, or is the latest one without a rollout. Requests with
+// the same key, such as a user ID, get the same version while the rollout
+// is unchanged; an empty key picks at random.
+func (m *PromptManager) Get(ctx context.Context, name, key string, vars map[string]interface{}) (*Prompt, PromptRef, error) {
+	version, err := m.Select(ctx, name, key)
+	if err != nil {
+		return nil, PromptRef{}, err
+	}
+	tmpl, err := template.New(name).Parse(version.Template)
+	if err != nil {
+		return nil, PromptRef{}, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid template of prompt %s@%s", name, version.Version), err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, PromptRef{}, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("failed to execute prompt %s@%s", name, version.Version), err)
+	}
+	prompt := NewPrompt(buf.String())
+	prompt.SystemPrompt = version.SystemPrompt
+	return prompt, PromptRef{Name: name, Version: version.Version, Environment: m.environment}, nil
+}
+
+// Select returns the version of the prompt name that serves the request of
+// key, as Get.
+func (m *PromptManager) Select(ctx context.Context, name, key string) (PromptVersion, error) {
+	versions, err := m.store.PromptVersions(ctx, name)
+	if err != nil {
+		return PromptVersion{}, err
+	}
+	if len(versions) == 0 {
+		return PromptVersion{}, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unknown prompt %q", name), nil)
+	}
+	rollout, err := m.store.Rollout(ctx, name, m.environment)
+	if err != nil {
+		return PromptVersion{}, err
+	}
+	if len(rollout) == 0 {
+		return versions[len(versions)-1], nil
+	}
+
+	var bucket float64
+	if key == "" {
+		bucket = rand.Float64() * 100
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(name + "\x00" + key))
+		bucket = float64(h.Sum64()%10000) / 100
+	}
+	selected := rollout[len(rollout)-1].Version
+	var cumulative float64
+	for _, rv := range rollout {
+		cumulative += rv.Percent
+		if bucket < cumulative {
+			selected = rv.Version
+			break
+		}
+	}
+	for _, v := range versions {
+		if v.Version == selected {
+			return v, nil
+		}
+	}
+	return PromptVersion{}, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("rollout of prompt %s in %s refers to unknown version %q", name, m.environment, selected), nil)
+}
+
+// validateRollout checks that the percentages of rollout add up to 100 and
+// refer to versions.
+func validateRollout(name string, versions []PromptVersion, rollout Rollout) error {
+	var total float64
+	for _, rv := range rollout {
+		if rv.Percent < 0 {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("negative percentage for version %q", rv.Version), nil)
+		}
+		found := false
+		for _, v := range versions {
+			found = found || v.Version == rv.Version
+		}
+		if !found {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unknown version %q of prompt %s", rv.Version, name), nil)
+		}
+		total += rv.Percent
+	}
+	if len(rollout) > 0 && math.Abs(total-100) > 1e-9 {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("rollout percentages add up to %g, not 100", total), nil)
+	}
+	return nil
+}
+
+// promptRecord holds the versions and rollouts of a prompt.
+type promptRecord struct {
+	Versions []PromptVersion    `json:"versions"`
+	Rollouts map[string]Rollout `json:"rollouts,omitempty"`
+}
+
+func (r *promptRecord) addVersion(version PromptVersion) error {
+	if version.Name == "" || version.Version == "" {
+		return NewLLMError(ErrorTypeInvalidInput, "prompt versions need a name and a version", nil)
+	}
+	if _, err := template.New(version.Name).Parse(version.Template); err != nil {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid template of prompt %s@%s", version.Name, version.Version), err)
+	}
+	for _, v := range r.Versions {
+		if v.Version == version.Version {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("prompt %s@%s already exists", version.Name, version.Version), nil)
+		}
+	}
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now().UTC()
+	}
+	r.Versions = append(r.Versions, version)
+	return nil
+}
+
+func (r *promptRecord) setRollout(name, environment string, rollout Rollout) error {
+	if err := validateRollout(name, r.Versions, rollout); err != nil {
+		return err
+	}
+	if r.Rollouts == nil {
+		r.Rollouts = make(map[string]Rollout)
+	}
+	if len(rollout) == 0 {
+		delete(r.Rollouts, environment)
+		return nil
+	}
+	r.Rollouts[environment] = append(Rollout(nil), rollout...)
+	return nil
+}
+
+// memoryPromptStore keeps prompts in memory.
+type memoryPromptStore struct {
+	mu      sync.RWMutex
+	records map[string]*promptRecord
+}
+
+// NewMemoryPromptStore creates a PromptStore that keeps prompts in memory,
+// for tests or prompts defined in code.
+func NewMemoryPromptStore() PromptStore {
+	return &memoryPromptStore{records: make(map[string]*promptRecord)}
+}
+
+func (s *memoryPromptStore) SavePromptVersion(ctx context.Context, version PromptVersion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[version.Name]
+	if record == nil {
+		record = &promptRecord{}
+	}
+	if err := record.addVersion(version); err != nil {
+		return err
+	}
+	s.records[version.Name] = record
+	return nil
+}
+
+func (s *memoryPromptStore) PromptVersions(ctx context.Context, name string) ([]PromptVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if record := s.records[name]; record != nil {
+		return append([]PromptVersion(nil), record.Versions...), nil
+	}
+	return nil, nil
+}
+
+func (s *memoryPromptStore) SetRollout(ctx context.Context, name, environment string, rollout Rollout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[name]
+	if record == nil {
+		record = &promptRecord{}
+	}
+	if err := record.setRollout(name, environment, rollout); err != nil {
+		return err
+	}
+	s.records[name] = record
+	return nil
+}
+
+func (s *memoryPromptStore) Rollout(ctx context.Context, name, environment string) (Rollout, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if record := s.records[name]; record != nil {
+		return append(Rollout(nil), record.Rollouts[environment]...), nil
+	}
+	return nil, nil
+}
+
+// filePromptStore keeps each prompt in a JSON file of a directory.
+type filePromptStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFilePromptStore creates a PromptStore that keeps each prompt in a JSON
+// file of dir, named after the prompt, so prompts can be reviewed and
+// versioned along with the code. The directory is created when a prompt is
+// first saved.
+func NewFilePromptStore(dir string) PromptStore {
+	return &filePromptStore{dir: dir}
+}
+
+func (s *filePromptStore) path(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid prompt name %q", name), nil)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+func (s *filePromptStore) load(name string) (*promptRecord, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &promptRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	var record promptRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt file %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+func (s *filePromptStore) save(name string, record *promptRecord) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+	// Replace the file atomically, so concurrent readers never see it partly written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return nil
+}
+
+func (s *filePromptStore) SavePromptVersion(ctx context.Context, version PromptVersion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.load(version.Name)
+	if err != nil {
+		return err
+	}
+	if err := record.addVersion(version); err != nil {
+		return err
+	}
+	return s.save(version.Name, record)
+}
+
+func (s *filePromptStore) PromptVersions(ctx context.Context, name string) ([]PromptVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.load(name)
+	if err != nil {
+		return nil, err
+	}
+	return record.Versions, nil
+}
+
+func (s *filePromptStore) SetRollout(ctx context.Context, name, environment string, rollout Rollout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.load(name)
+	if err != nil {
+		return err
+	}
+	if err := record.setRollout(name, environment, rollout); err != nil {
+		return err
+	}
+	return s.save(name, record)
+}
+
+func (s *filePromptStore) Rollout(ctx context.Context, name, environment string) (Rollout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, err := s.load(name)
+	if err != nil {
+		return nil, err
+	}
+	return record.Rollouts[environment], nil
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptStore(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]PromptStore{
+		"memory": NewMemoryPromptStore(),
+		"file":   NewFilePromptStore(filepath.Join(t.TempDir(), "prompts")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.SavePromptVersion(ctx, PromptVersion{Name: "summary", Version: "v1", Template: "Summarize: {{.text}}"}))
+			require.NoError(t, store.SavePromptVersion(ctx, PromptVersion{Name: "summary", Version: "v2", Template: "Summarize briefly: {{.text}}", SystemPrompt: "Be concise."}))
+			assert.Error(t, store.SavePromptVersion(ctx, PromptVersion{Name: "summary", Version: "v2", Template: "changed"}), "versions are immutable")
+			assert.Error(t, store.SetRollout(ctx, "summary", "production", Rollout{{Version: "v1", Percent: 50}}), "percentages must add up to 100")
+			assert.Error(t, store.SetRollout(ctx, "summary", "production", Rollout{{Version: "v3", Percent: 100}}))
+
+			// Without a rollout, the latest version is served
+			staging := NewPromptManager(store, "staging")
+			prompt, ref, err := staging.Get(ctx, "summary", "", map[string]interface{}{"text": "doc"})
+			require.NoError(t, err)
+			assert.Equal(t, "Summarize briefly: doc", prompt.Input)
+			assert.Equal(t, "Be concise.", prompt.SystemPrompt)
+			assert.Equal(t, PromptRef{Name: "summary", Version: "v2", Environment: "staging"}, ref)
+
+			require.NoError(t, store.SetRollout(ctx, "summary", "production", Rollout{{Version: "v1", Percent: 70}, {Version: "v2", Percent: 30}}))
+			production := NewPromptManager(store, "production")
+			counts := map[string]int{}
+			for i := 0; i < 1000; i++ {
+				v, err := production.Select(ctx, "summary", fmt.Sprintf("user-%d", i))
+				require.NoError(t, err)
+				counts[v.Version]++
+			}
+			assert.InDelta(t, 700, counts["v1"], 60)
+			assert.InDelta(t, 300, counts["v2"], 60)
+			first, _ := production.Select(ctx, "summary", "user-42")
+			again, _ := production.Select(ctx, "summary", "user-42")
+			assert.Equal(t, first.Version, again.Version, "a key keeps its version")
+
+			_, _, err = production.Get(ctx, "missing", "", nil)
+			assert.Error(t, err)
+		})
+	}
+
+	server := newEchoServer(t)
+	defer server.Close()
+	l := newTestLLM(t, server.URL)
+	ref := PromptRef{Name: "summary", Version: "v2", Environment: "production"}
+	var resp Response
+	_, err := l.Generate(ctx, NewPrompt("hello"), WithPromptVersion(ref), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, ref, resp.Metadata["prompt_version"])
+}
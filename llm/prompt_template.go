@@ -3,6 +3,7 @@ package llm
 
 import (
 	"bytes"
+	"strings"
 	"text/template"
 )
 
@@ -24,10 +25,12 @@ import (
 //	    "text": "Hello, world!",
 //	})
 type PromptTemplate struct {
-	Name        string         // Unique identifier for the template
-	Description string         // Human-readable description of the template's purpose
-	Template    string         // Go template string for generating prompts
-	Options     []PromptOption // Configuration options for generated prompts
+	Name        string            // Unique identifier for the template
+	Description string            // Human-readable description of the template's purpose
+	Template    string            // Go template string for generating prompts
+	Options     []PromptOption    // Configuration options for generated prompts
+	MaxTokens   int               // Size allowed by Validate; see WithTemplateMaxTokens
+	Locales     map[string]string // Per-locale variants of Template; see WithLocaleTemplate
 }
 
 // PromptTemplateOption is a function type that modifies a PromptTemplate.
@@ -105,6 +108,7 @@ func WithPromptOptions(options ...PromptOption) PromptTemplateOption {
 //
 // Parameters:
 //   - data: Map of key-value pairs to substitute in the template
+//   - opts: Optional execution settings, such as WithExecuteLocale
 //
 // Returns:
 //   - Generated and configured Prompt instance
@@ -119,8 +123,14 @@ func WithPromptOptions(options ...PromptOption) PromptTemplateOption {
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func (pt *PromptTemplate) Execute(data map[string]interface{}) (*Prompt, error) {
-	tmpl, err := template.New(pt.Name).Parse(pt.Template)
+func (pt *PromptTemplate) Execute(data map[string]interface{}, opts ...ExecuteOption) (*Prompt, error) {
+	cfg := &executeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	_, text := pt.Variant(cfg.locales...)
+
+	tmpl, err := template.New(pt.Name).Parse(text)
 	if err != nil {
 		return nil, err
 	}
@@ -135,3 +145,75 @@ func (pt *PromptTemplate) Execute(data map[string]interface{}) (*Prompt, error)
 
 	return prompt, nil
 }
+
+// ExecuteOption configures a single PromptTemplate.Execute call.
+type ExecuteOption func(*executeConfig)
+
+// executeConfig holds the settings of an Execute call.
+type executeConfig struct {
+	locales []string
+}
+
+// WithLocaleTemplate adds the variant of the template for a locale, such as
+// "de" or "pt-BR". Template remains the variant used when no locale matches.
+//
+// Example:
+//
+//	template := NewPromptTemplate(
+//	    "ProductDescription",
+//	    "Writes a product description",
+//	    "Write a product description for {{.product}}.",
+//	    WithLocaleTemplate("de", "Schreibe eine Produktbeschreibung für {{.product}}."),
+//	    WithLocaleTemplate("fr", "Rédige une description du produit {{.product}}."),
+//	)
+func WithLocaleTemplate(locale, template string) PromptTemplateOption {
+	return func(pt *PromptTemplate) {
+		if pt.Locales == nil {
+			pt.Locales = make(map[string]string)
+		}
+		pt.Locales[normalizeLocale(locale)] = template
+	}
+}
+
+// WithExecuteLocale selects the variant of the template to execute. The
+// locales are tried in order, each one before its parents, so that
+// WithExecuteLocale("de-AT", "en") tries "de-AT", "de", "en", and then
+// falls back to Template.
+//
+// Example:
+//
+//	prompt, err := template.Execute(data, WithExecuteLocale(user.Locale, "en"))
+func WithExecuteLocale(locales ...string) ExecuteOption {
+	return func(c *executeConfig) {
+		c.locales = append(c.locales, locales...)
+	}
+}
+
+// Variant returns the locale and template text Execute uses for the given
+// fallback chain of locales, as with WithExecuteLocale. The locale is empty
+// when no variant matches and Template is used.
+func (pt *PromptTemplate) Variant(locales ...string) (string, string) {
+	for _, locale := range locales {
+		for locale = normalizeLocale(locale); locale != ""; locale = parentLocale(locale) {
+			if text, ok := pt.Locales[locale]; ok {
+				return locale, text
+			}
+		}
+	}
+	return "", pt.Template
+}
+
+// normalizeLocale returns locale in the form "pt-br", so that "pt_BR" and
+// "pt-BR" name the same variant.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// parentLocale returns the locale without its last subtag, such as "zh-hant"
+// for "zh-hant-tw", or an empty string for a language alone.
+func parentLocale(locale string) string {
+	if i := strings.LastIndex(locale, "-"); i >= 0 {
+		return locale[:i]
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptTemplateValidate(t *testing.T) {
+	tmpl := NewPromptTemplate("translator", "Translates text",
+		`Translate to {{.language}}:
+{{range .lines}}- {{.text}} ({{$.tone}})
+{{end}}{{template "footer" .}}{{define "footer"}}Sign as {{.author}}{{end}}`,
+		WithTemplateMaxTokens(30))
+
+	variables, err := tmpl.Variables()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"author", "language", "lines", "tone"}, variables, "fields inside range refer to the items")
+
+	vars := map[string]interface{}{
+		"language": "French",
+		"lines":    []map[string]string{{"text": "Hello"}},
+		"tone":     "formal",
+		"author":   "Ada",
+	}
+	require.NoError(t, tmpl.Validate(vars))
+
+	var llmErr *LLMError
+	var problems *TemplateValidationError
+	err = tmpl.Validate(map[string]interface{}{"language": "French", "lines": nil, "tone": "formal", "audience": "kids"})
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	require.ErrorAs(t, err, &problems)
+	assert.Equal(t, []string{"author"}, problems.Undefined)
+	assert.Equal(t, []string{"audience"}, problems.Unused)
+
+	vars["lines"] = []map[string]string{{"text": strings.Repeat("a long line of text ", 20)}}
+	require.ErrorAs(t, tmpl.Validate(vars), &problems)
+	assert.Equal(t, 30, problems.MaxTokens)
+	assert.Greater(t, problems.Tokens, 30)
+
+	_, err = NewPromptTemplate("broken", "", "{{.x").Variables()
+	require.ErrorAs(t, err, &llmErr)
+}
+
+func TestPromptTemplateLocales(t *testing.T) {
+	tmpl := NewPromptTemplate("ProductDescription", "Writes a product description",
+		"Describe {{.product}}.",
+		WithLocaleTemplate("de", "Beschreibe {{.product}}."),
+		WithLocaleTemplate("pt_BR", "Descreva {{.product}}."),
+	)
+	data := map[string]interface{}{"product": "X1"}
+
+	for _, tc := range []struct {
+		locales []string
+		want    string
+	}{
+		{nil, "Describe X1."},
+		{[]string{"de"}, "Beschreibe X1."},
+		{[]string{"de-AT"}, "Beschreibe X1."},
+		{[]string{"pt-br"}, "Descreva X1."},
+		{[]string{"pt"}, "Describe X1."},
+		{[]string{"ja", "de"}, "Beschreibe X1."},
+		{[]string{"ja"}, "Describe X1."},
+	} {
+		prompt, err := tmpl.Execute(data, WithExecuteLocale(tc.locales...))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, prompt.Input, "locales %v", tc.locales)
+	}
+
+	locale, _ := tmpl.Variant("de-CH")
+	assert.Equal(t, "de", locale)
+}
@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateValidationError lists the problems Validate found in a
+// PromptTemplate.
+type TemplateValidationError struct {
+	// Undefined holds the variables the template uses but the data lacks.
+	Undefined []string
+	// Unused holds the variables of the data the template does not use.
+	Unused []string
+	// Tokens is the estimated size of the rendered prompt, and MaxTokens the
+	// limit it exceeds, or zero when it fits.
+	Tokens    int
+	MaxTokens int
+}
+
+func (e *TemplateValidationError) Error() string {
+	var problems []string
+	if len(e.Undefined) > 0 {
+		problems = append(problems, "undefined variables "+strings.Join(e.Undefined, ", "))
+	}
+	if len(e.Unused) > 0 {
+		problems = append(problems, "unused variables "+strings.Join(e.Unused, ", "))
+	}
+	if e.MaxTokens > 0 {
+		problems = append(problems, fmt.Sprintf("about %d tokens, more than the limit of %d", e.Tokens, e.MaxTokens))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// WithTemplateMaxTokens sets the size, in tokens as estimated by
+// EstimateTokens, that Validate allows the rendered prompt.
+func WithTemplateMaxTokens(n int) PromptTemplateOption {
+	return func(pt *PromptTemplate) {
+		pt.MaxTokens = n
+	}
+}
+
+// Variables returns the names of the top-level variables the template uses,
+// such as "language" for {{.language}} or {{range .items}}, sorted.
+func (pt *PromptTemplate) Variables() ([]string, error) {
+	tmpl, err := template.New(pt.Name).Parse(pt.Template)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid prompt template %s", pt.Name), err)
+	}
+	w := &templateWalker{tmpl: tmpl, used: make(map[string]bool), visited: make(map[string]bool)}
+	if tmpl.Tree != nil {
+		w.walk(tmpl.Tree.Root, true)
+	}
+	names := make([]string, 0, len(w.used))
+	for name := range w.used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Validate checks the template against the data it will be executed with,
+// so that mistakes are found in tests or at startup instead of when Execute
+// renders "<no value>" in production. It reports the variables the template
+// uses but vars lacks, the variables of vars the template does not use, and,
+// with WithTemplateMaxTokens, a rendered prompt too large. The problems are
+// returned together as a TemplateValidationError wrapped in an
+// ErrorTypeInvalidInput error.
+//
+// Example:
+//
+//	func TestTranslatorTemplate(t *testing.T) {
+//	    if err := translator.Validate(map[string]interface{}{"language": "French", "text": "Hello"}); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+func (pt *PromptTemplate) Validate(vars map[string]interface{}) error {
+	used, err := pt.Variables()
+	if err != nil {
+		return err
+	}
+	problems := &TemplateValidationError{}
+	for _, name := range used {
+		if _, ok := vars[name]; !ok {
+			problems.Undefined = append(problems.Undefined, name)
+		}
+	}
+	for name := range vars {
+		if i := sort.SearchStrings(used, name); i == len(used) || used[i] != name {
+			problems.Unused = append(problems.Unused, name)
+		}
+	}
+	sort.Strings(problems.Unused)
+
+	if pt.MaxTokens > 0 && len(problems.Undefined) == 0 {
+		tmpl, _ := template.New(pt.Name).Parse(pt.Template)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("failed to execute prompt template %s", pt.Name), err)
+		}
+		if tokens := EstimateTokens(buf.String()); tokens > pt.MaxTokens {
+			problems.Tokens, problems.MaxTokens = tokens, pt.MaxTokens
+		}
+	}
+
+	if len(problems.Undefined) > 0 || len(problems.Unused) > 0 || problems.MaxTokens > 0 {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid prompt template %s", pt.Name), problems)
+	}
+	return nil
+}
+
+// templateWalker collects the top-level variables a template uses.
+type templateWalker struct {
+	tmpl    *template.Template
+	used    map[string]bool
+	visited map[string]bool // Associated templates already walked with the data as dot
+}
+
+// walk visits node. root is true when dot is the data the template is
+// executed with, and false inside range and with blocks, where it is not.
+func (w *templateWalker) walk(node parse.Node, root bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, root)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, root)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.walk(arg, root)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, root)
+	case *parse.FieldNode:
+		if root {
+			w.used[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		// $ is the data whatever the dot, as in {{$.language}}
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			w.used[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		w.walk(n.Pipe, root)
+		w.walk(n.List, root)
+		w.walk(n.ElseList, root)
+	case *parse.RangeNode:
+		w.walk(n.Pipe, root)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, root)
+	case *parse.WithNode:
+		w.walk(n.Pipe, root)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, root)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, root)
+		if root && n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); ok && !w.visited[n.Name] {
+				w.visited[n.Name] = true
+				if t := w.tmpl.Lookup(n.Name); t != nil && t.Tree != nil {
+					w.walk(t.Tree.Root, true)
+				}
+			}
+		}
+	}
+}
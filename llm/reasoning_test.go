@@ -0,0 +1,197 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// deepSeekTestProvider sends DeepSeek requests to a test server.
+type deepSeekTestProvider struct {
+	providers.Provider
+	endpoint string
+}
+
+func (p *deepSeekTestProvider) Endpoint() string { return p.endpoint }
+
+func (p *deepSeekTestProvider) ParseReasoning(body []byte) string {
+	return p.Provider.(providers.ReasoningParser).ParseReasoning(body)
+}
+
+func (p *deepSeekTestProvider) ParseStreamEvent(eventType string, data []byte) ([]providers.StreamEvent, error) {
+	return p.Provider.(providers.StreamEventParser).ParseStreamEvent(eventType, data)
+}
+
+func (p *deepSeekTestProvider) FIMEndpoint() string { return p.endpoint + "/fim" }
+
+func (p *deepSeekTestProvider) PrepareFIMRequest(prefix, suffix string, options map[string]interface{}) ([]byte, error) {
+	return p.Provider.(providers.FIMProvider).PrepareFIMRequest(prefix, suffix, options)
+}
+
+func (p *deepSeekTestProvider) ParseFIMResponse(body []byte) (string, error) {
+	return p.Provider.(providers.FIMProvider).ParseFIMResponse(body)
+}
+
+func TestDeepSeekReasoner(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		if request["stream"] == true {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"Compare sizes.\"}}]}\n\n"+
+				"data: {\"choices\":[{\"delta\":{\"content\":\"9.9\"}}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"9.9","reasoning_content":"Compare sizes."}}]}`)
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("deepseek", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &deepSeekTestProvider{providers.NewDeepSeekProvider(apiKey, model, extraHeaders), server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("deepseek"), config.SetModel("deepseek-reasoner"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	resp, err := GenerateResponse(context.Background(), l, NewPrompt("9.11 or 9.9?"), WithOption("top_p", 0.5))
+	require.NoError(t, err)
+	assert.Equal(t, "9.9", resp.Content)
+	assert.Equal(t, "Compare sizes.", resp.Reasoning)
+	assert.NotContains(t, requests[0], "temperature")
+	assert.NotContains(t, requests[0], "top_p")
+
+	var tokenTypes []string
+	text, err := StreamWithCallbacks(context.Background(), l, NewPrompt("9.11 or 9.9?"), StreamCallbacks{
+		OnToken: func(token *StreamToken) { tokenTypes = append(tokenTypes, token.Type) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "9.9", text)
+	assert.Equal(t, []string{TokenTypeText}, tokenTypes, "reasoning is not delivered as text")
+}
+
+func TestReasoningEffort(t *testing.T) {
+	request := func(provider, model string, effort providers.ReasoningEffort) (map[string]interface{}, error) {
+		l := newTestLLM(t, "", config.SetProvider(provider), config.SetAPIKey("key"), config.SetModel(model), config.SetMaxTokens(500))
+		result, err := l.Generate(context.Background(), NewPrompt("Plan the migration.", WithReasoningEffort(effort)), WithDryRun())
+		if err != nil {
+			return nil, err
+		}
+		var dryRun DryRunRequest
+		require.NoError(t, json.Unmarshal([]byte(result), &dryRun))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(dryRun.Body, &body))
+		return body, nil
+	}
+
+	body, err := request("openai", "o3-mini", providers.ReasoningHigh)
+	require.NoError(t, err)
+	assert.Equal(t, "high", body["reasoning_effort"])
+
+	body, err = request("anthropic", "claude-sonnet-4-20250514", providers.ReasoningLow)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "enabled", "budget_tokens": 1024.0}, body["thinking"])
+	assert.Equal(t, 1524.0, body["max_tokens"], "the thinking budget comes on top of the answer")
+	assert.NotContains(t, body, "temperature")
+	assert.NotContains(t, body, "reasoning_effort")
+
+	body, err = request("gemini", "gemini-2.5-flash", providers.ReasoningMedium)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"google": map[string]interface{}{"thinking_config": map[string]interface{}{"thinking_budget": 8192.0}}}, body["extra_body"])
+
+	body, err = request("openrouter", "deepseek/deepseek-r1", providers.ReasoningHigh)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"effort": "high"}, body["reasoning"])
+	assert.NotContains(t, body, "reasoning_effort")
+
+	for _, tc := range []struct{ provider, model string }{
+		{"openai", "gpt-4o"},
+		{"anthropic", "claude-3-5-haiku-latest"},
+		{"mistral", "mistral-small-latest"},
+	} {
+		_, err = request(tc.provider, tc.model, providers.ReasoningHigh)
+		var llmErr *LLMError
+		require.ErrorAs(t, err, &llmErr, tc.provider)
+		assert.Equal(t, ErrorTypeUnsupported, llmErr.Type, tc.provider)
+	}
+
+	_, err = request("openai", "o3-mini", "extreme")
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+}
+
+func TestStreamReasoning(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Two plus two "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"is four."}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"4"}}`,
+		`{"type":"message_delta","usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		for _, event := range events {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("events", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &anthropicStreamProvider{sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("events"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	var reasoning string
+	text, err := StreamWithCallbacks(context.Background(), l, NewPrompt("2+2?"), StreamCallbacks{
+		OnReasoning: func(token *StreamToken) { reasoning += token.Text },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "4", text, "reasoning stays out of the text")
+	assert.Equal(t, "Two plus two is four.", reasoning)
+
+	stream, err := l.Stream(context.Background(), NewPrompt("2+2?"), WithoutReasoning())
+	require.NoError(t, err)
+	for {
+		token, err := stream.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		assert.NotEqual(t, TokenTypeReasoning, token.Type)
+	}
+
+	parser := providers.NewOpenRouterProvider("key", "model", nil).(providers.StreamEventParser)
+	parsed, err := parser.ParseStreamEvent("", []byte(`{"choices":[{"delta":{"reasoning":"Hmm"}}]}`))
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	assert.Equal(t, providers.StreamEvent{Type: providers.StreamEventReasoningDelta, Text: "Hmm"}, parsed[0])
+	parsed, err = parser.ParseStreamEvent("", []byte(`{"choices":[],"usage":{"prompt_tokens":4,"completion_tokens":30,"completion_tokens_details":{"reasoning_tokens":24}}}`))
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	require.NotNil(t, parsed[0].Usage)
+	assert.Equal(t, 24, parsed[0].Usage.ReasoningTokens)
+
+	usage, ok := ParseUsage([]byte(`{"usage":{"prompt_tokens":4,"completion_tokens":30,"total_tokens":34,"completion_tokens_details":{"reasoning_tokens":24}}}`))
+	require.True(t, ok)
+	assert.Equal(t, 24, usage.ReasoningTokens)
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// reproducibleConfig returns a copy of cfg with a temperature of 0 and, for
+// providers that support a seed, config.ReproducibleSeed unless a seed is
+// set. It warns when the provider ignores the seed, since it then cannot
+// guarantee deterministic outputs.
+func reproducibleConfig(cfg *config.Config, logger utils.Logger) *config.Config {
+	reproducible := *cfg
+	reproducible.Temperature = 0
+	if !providers.SupportsOption(cfg.Provider, providers.OptionSeed) {
+		logger.Warn("Provider does not support a seed, outputs may not be reproducible", "provider", cfg.Provider)
+		return &reproducible
+	}
+	if reproducible.Seed == nil {
+		seed := config.ReproducibleSeed
+		reproducible.Seed = &seed
+	}
+	return &reproducible
+}
+
+// parseSystemFingerprint returns the system_fingerprint of an
+// OpenAI-compatible response body, or an empty string when it has none.
+func parseSystemFingerprint(body []byte) string {
+	var response struct {
+		SystemFingerprint string `json:"system_fingerprint"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	return response.SystemFingerprint
+}
+
+// fingerprintTracker holds the last system fingerprint a client received.
+type fingerprintTracker struct {
+	mu   sync.Mutex
+	last string
+}
+
+// checkFingerprint records fingerprint and, in reproducible mode, warns when
+// it differs from the previous one: the backend changed, so the same seed
+// may no longer reproduce earlier outputs.
+func (l *LLMImpl) checkFingerprint(fingerprint string) {
+	if fingerprint == "" {
+		return
+	}
+	l.fingerprint.mu.Lock()
+	previous := l.fingerprint.last
+	l.fingerprint.last = fingerprint
+	l.fingerprint.mu.Unlock()
+	if previous != "" && previous != fingerprint && l.config != nil && l.config.Reproducible {
+		l.logger.Warn("System fingerprint changed, outputs may differ from earlier ones with the same seed", "previous", previous, "current", fingerprint)
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestReproducible(t *testing.T) {
+	var requests []map[string]interface{}
+	fingerprint := "fp_1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		fmt.Fprintf(w, `{"system_fingerprint":%q,"choices":[{"message":{"content":"ok"}}]}`, fingerprint)
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, "", config.SetProvider("openai"), config.SetModel("gpt-4o"), config.SetAPIKey("key"), config.SetBaseURL("openai", server.URL),
+		config.SetTemperature(0.9), config.SetReproducible(true))
+	var resp Response
+	_, err := l.Generate(context.Background(), NewPrompt("hi"), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), requests[0]["temperature"])
+	assert.Equal(t, float64(config.ReproducibleSeed), requests[0]["seed"])
+	assert.Equal(t, "fp_1", resp.SystemFingerprint)
+
+	l = newTestLLM(t, "", config.SetProvider("openai"), config.SetModel("gpt-4o"), config.SetAPIKey("key"), config.SetBaseURL("openai", server.URL),
+		config.SetSeed(7), config.SetReproducible(true))
+	_, err = l.Generate(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, float64(7), requests[1]["seed"], "an explicit seed is kept")
+
+	logger := new(utils.MockLogger)
+	logger.On("Warn", mock.Anything, mock.Anything).Return()
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("anthropic"), config.SetReproducible(true))
+	reproducible := reproducibleConfig(cfg, logger)
+	assert.Equal(t, float64(0), reproducible.Temperature)
+	assert.Nil(t, reproducible.Seed, "the provider ignores seeds")
+	logger.AssertCalled(t, "Warn", "Provider does not support a seed, outputs may not be reproducible", mock.Anything)
+
+	l.logger = logger
+	l.checkFingerprint("fp_2")
+	logger.AssertCalled(t, "Warn", "System fingerprint changed, outputs may differ from earlier ones with the same seed", []interface{}{"previous", "fp_1", "current", "fp_2"})
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Response holds the generated text of a Generate call together with the
+// details the provider reported about it.
+type Response struct {
+	// Content is the generated text, as returned by Generate.
+	Content string
+
+	// Raw is the generated text before cleaning and post-processing.
+	Raw string
+
+	// Provider and Model identify what produced the response.
+	Provider string
+	Model    string
+
+	// Usage is the token usage of the final attempt, when reported.
+	Usage Usage
+
+	// ToolCalls holds every tool call the model requested, in order, for
+	// providers that support tool calling. Content carries the same calls
+	// formatted as <function_call> text for compatibility, which
+	// ParseToolCallText reads back.
+	ToolCalls []ToolCall
+
+	// Reasoning is the reasoning a reasoning model reported separately from
+	// its answer, such as the reasoning_content of deepseek-reasoner.
+	Reasoning string
+
+	// SystemFingerprint identifies the backend configuration that produced
+	// the response, for providers that report it such as OpenAI. Outputs
+	// are only reproducible with a seed while it stays the same.
+	SystemFingerprint string
+
+	// Timing is the latency of the call, including its retries.
+	Timing Timing
+
+	// Metadata holds provider-specific details, such as Groq's latency and
+	// queue metrics under the "groq" key, Cohere's citations under the
+	// "citations" key or Gemini's safety ratings under the "safety_ratings"
+	// key.
+	Metadata map[string]interface{}
+}
+
+// WithResponse makes Generate fill dst with the details of the response.
+// dst is left untouched when the call fails.
+//
+// Example:
+//
+//	var resp llm.Response
+//	text, err := client.Generate(ctx, prompt, llm.WithResponse(&resp))
+//	if metrics, ok := resp.Metadata["groq"].(providers.GroqMetrics); ok {
+//	    log.Printf("queued for %s", metrics.QueueTime)
+//	}
+func WithResponse(dst *Response) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Response = dst
+	}
+}
+
+// ParseToolCallText extracts the tool calls of text in the legacy
+// <function_call> format returned by Generate, for code written before
+// Response.ToolCalls. The calls have no ID; prefer Response.ToolCalls, which
+// keeps the IDs.
+//
+// Example:
+//
+//	text, err := client.Generate(ctx, prompt)
+//	calls, err := llm.ParseToolCallText(text)
+func ParseToolCallText(text string) ([]ToolCall, error) {
+	parsed, err := providers.ParseToolCallText(text)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to parse tool calls", err)
+	}
+	calls := make([]ToolCall, len(parsed))
+	for i, call := range parsed {
+		calls[i] = newToolCall(call.ID, call.Name, call.Arguments)
+	}
+	return calls, nil
+}
+
+// GenerateResponse calls Generate and returns the detailed Response.
+func GenerateResponse(ctx context.Context, l LLM, prompt *Prompt, opts ...GenerateOption) (*Response, error) {
+	var resp Response
+	text, err := l.Generate(ctx, prompt, append(opts, WithResponse(&resp))...)
+	if err != nil {
+		return nil, err
+	}
+	// Wrappers may post-process the text after the provider call
+	resp.Content = text
+	return &resp, nil
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// groqTestProvider sends Groq requests to a test server.
+type groqTestProvider struct {
+	providers.Provider
+	endpoint string
+}
+
+func (p *groqTestProvider) Endpoint() string { return p.endpoint }
+
+func (p *groqTestProvider) ParseMetadata(body []byte) map[string]interface{} {
+	return p.Provider.(providers.MetadataParser).ParseMetadata(body)
+}
+
+func TestGroqResponseMetadata(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"hi"}}],"service_tier":"flex",
+			"usage":{"queue_time":0.25,"prompt_tokens":12,"prompt_time":0.01,"completion_tokens":3,"completion_time":0.02,"total_tokens":15,"total_time":0.03},
+			"x_groq":{"id":"req_123"}}`)
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("groq", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &groqTestProvider{providers.NewGroqProvider(apiKey, model, extraHeaders), server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("groq"), config.SetModel("llama-3.3-70b-versatile"),
+		config.SetAPIKey("test-key"), config.SetServiceTier("flex"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	resp, err := GenerateResponse(context.Background(), l, NewPrompt("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "flex", request["service_tier"])
+	assert.Equal(t, "hi", resp.Content)
+	assert.Equal(t, 15, resp.Usage.TotalTokens)
+	assert.Equal(t, providers.GroqMetrics{
+		RequestID:      "req_123",
+		ServiceTier:    "flex",
+		QueueTime:      250 * time.Millisecond,
+		PromptTime:     10 * time.Millisecond,
+		CompletionTime: 20 * time.Millisecond,
+		TotalTime:      30 * time.Millisecond,
+	}, resp.Metadata["groq"])
+
+	caps, ok := providers.LookupModelCapabilities("groq", "llama-3.3-70b-versatile")
+	require.True(t, ok)
+	assert.True(t, caps.Tools && caps.JSONMode)
+	assert.False(t, l.SupportsJSONSchema())
+}
+
+// cohereTestProvider sends Cohere requests to a test server.
+type cohereTestProvider struct {
+	providers.Provider
+	endpoint string
+}
+
+func (p *cohereTestProvider) Endpoint() string        { return p.endpoint }
+func (p *cohereTestProvider) SupportsDocuments() bool { return true }
+
+func (p *cohereTestProvider) ParseMetadata(body []byte) map[string]interface{} {
+	return p.Provider.(providers.MetadataParser).ParseMetadata(body)
+}
+
+func TestCohereDocumentsAndCitations(t *testing.T) {
+	var request struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Documents []struct {
+			ID   string            `json:"id"`
+			Data map[string]string `json:"data"`
+		} `json:"documents"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":[{"type":"text","text":"Emperor penguins are the tallest."}],
+			"citations":[{"start":0,"end":16,"text":"Emperor penguins","sources":[{"type":"document","id":"penguins"}]}]}}`)
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("cohere", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &cohereTestProvider{providers.NewCohereProvider(apiKey, model, extraHeaders), server.URL}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("cohere"), config.SetModel("command-r"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	doc := utils.Document{ID: "penguins", Title: "Penguins", Text: "Emperor penguins are the tallest penguins."}
+	prompt := NewPrompt("Which penguin is the tallest?", WithSystemPrompt("Be brief.", ""), WithDocuments(doc))
+	resp, err := GenerateResponse(context.Background(), l, prompt)
+	require.NoError(t, err)
+
+	require.Len(t, request.Messages, 2)
+	assert.Equal(t, "system", request.Messages[0].Role)
+	assert.NotContains(t, request.Messages[1].Content, doc.Text, "native documents are not repeated in the prompt")
+	require.Len(t, request.Documents, 1)
+	assert.Equal(t, "penguins", request.Documents[0].ID)
+	assert.Equal(t, doc.Text, request.Documents[0].Data["text"])
+	assert.Equal(t, []providers.Citation{{Start: 0, End: 16, Text: "Emperor penguins", DocumentIDs: []string{"penguins"}}}, resp.Metadata["citations"])
+
+	assert.Contains(t, NewPrompt("q", WithDocuments(doc)).String(), "[penguins] Penguins\n"+doc.Text)
+}
+
+func TestSafetySettings(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"content":"ok"},"finish_reason":"stop","safety_ratings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"},{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"LOW"}]}]}`)
+	}))
+	defer server.Close()
+	l := newTestLLM(t, "", config.SetProvider("gemini"), config.SetModel("gemini-2.5-flash"), config.SetAPIKey("key"), config.SetBaseURL("gemini", server.URL),
+		config.SetSafetySettings(
+			config.SafetySetting{Category: config.HarmCategoryHarassment, Threshold: config.BlockNone},
+			config.SafetySetting{Category: config.HarmCategoryDangerousContent, Threshold: config.BlockLowAndAbove},
+		))
+
+	var resp Response
+	_, err := l.Generate(context.Background(), NewPrompt("hi"), WithResponse(&resp),
+		WithSafetySettings(config.SafetySetting{Category: config.HarmCategoryDangerousContent, Threshold: config.BlockOnlyHigh}))
+	require.NoError(t, err)
+	assert.NotContains(t, body, "safety_settings", "settings are only sent in extra_body")
+	google := body["extra_body"].(map[string]interface{})["google"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"},
+		map[string]interface{}{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "threshold": "BLOCK_ONLY_HIGH"},
+	}, google["safety_settings"])
+	assert.Equal(t, []providers.SafetyRating{
+		{Category: "HARM_CATEGORY_HARASSMENT", Probability: "NEGLIGIBLE"},
+		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "LOW"},
+	}, resp.Metadata["safety_ratings"])
+
+	// The defaults are unchanged by the request override
+	_, err = l.Generate(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	google = body["extra_body"].(map[string]interface{})["google"].(map[string]interface{})
+	assert.Equal(t, "BLOCK_LOW_AND_ABOVE", google["safety_settings"].([]interface{})[1].(map[string]interface{})["threshold"])
+}
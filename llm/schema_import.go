@@ -0,0 +1,596 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIOnlyKeywords are the OpenAPI schema keywords that JSON Schema does
+// not know.
+var openAPIOnlyKeywords = []string{"nullable", "discriminator", "xml", "externalDocs", "example", "deprecated", "readOnly", "writeOnly"}
+
+// SchemaFromOpenAPI derives the JSON schema of a response from the component
+// schema name of an OpenAPI 3 document, in JSON or YAML, so that services
+// whose types are described in OpenAPI need no parallel Go structs. $ref
+// references are inlined, allOf compositions merged into one object, and
+// write-only properties, which never appear in responses, left out. The
+// result can be passed to GenerateWithSchema or RegisterSchema.
+//
+// Example:
+//
+//	spec, _ := os.ReadFile("openapi.yaml")
+//	schema, err := llm.SchemaFromOpenAPI(spec, "Invoice")
+//	response, err := client.GenerateWithSchema(ctx, prompt, schema)
+func SchemaFromOpenAPI(spec []byte, name string) (json.RawMessage, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid OpenAPI document", err)
+	}
+	components, _ := document["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if _, ok := schemas[name]; !ok {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("no component schema %q in the OpenAPI document", name), nil)
+	}
+	converted, err := convertOpenAPISchema(schemas, schemas[name], map[string]bool{name: true})
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("failed to convert component schema %q", name), err)
+	}
+	raw, err := json.Marshal(converted)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "failed to encode JSON schema", err)
+	}
+	return raw, nil
+}
+
+// convertOpenAPISchema returns the JSON schema of an OpenAPI schema object.
+// resolving holds the components being inlined, to stop at recursive
+// references.
+func convertOpenAPISchema(components map[string]interface{}, schema interface{}, resolving map[string]bool) (interface{}, error) {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return schema, nil
+	}
+	if ref, ok := object["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, ok := components[name]
+		if !ok || name == ref {
+			return nil, fmt.Errorf("unresolved reference %q", ref)
+		}
+		if resolving[name] {
+			// A recursive type: describe the nested value loosely
+			return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, nil
+		}
+		resolving[name] = true
+		defer delete(resolving, name)
+		return convertOpenAPISchema(components, target, resolving)
+	}
+
+	converted := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		switch key {
+		case "properties":
+			properties := make(map[string]interface{})
+			declared, _ := value.(map[string]interface{})
+			for name, property := range declared {
+				if p, ok := property.(map[string]interface{}); ok && p["writeOnly"] == true {
+					continue
+				}
+				c, err := convertOpenAPISchema(components, property, resolving)
+				if err != nil {
+					return nil, fmt.Errorf("property %s: %w", name, err)
+				}
+				properties[name] = c
+			}
+			converted[key] = properties
+		case "items", "additionalProperties", "not":
+			c, err := convertOpenAPISchema(components, value, resolving)
+			if err != nil {
+				return nil, err
+			}
+			converted[key] = c
+		case "allOf", "anyOf", "oneOf":
+			items, _ := value.([]interface{})
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				c, err := convertOpenAPISchema(components, item, resolving)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = c
+			}
+			converted[key] = list
+		default:
+			converted[key] = value
+		}
+	}
+	for _, keyword := range openAPIOnlyKeywords {
+		delete(converted, keyword)
+	}
+	if all, ok := converted["allOf"].([]interface{}); ok {
+		mergeAllOf(converted, all)
+	}
+	// Drop required properties that were left out
+	if required, ok := converted["required"].([]interface{}); ok {
+		properties, _ := converted["properties"].(map[string]interface{})
+		kept := required[:0:0]
+		for _, name := range required {
+			if _, ok := properties[fmt.Sprint(name)]; ok || properties == nil {
+				kept = append(kept, name)
+			}
+		}
+		converted["required"] = kept
+	}
+	return converted, nil
+}
+
+// mergeAllOf merges the object schemas of an allOf composition into schema,
+// which validators and models handle more reliably.
+func mergeAllOf(schema map[string]interface{}, all []interface{}) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	required, _ := schema["required"].([]interface{})
+	for _, part := range all {
+		p, ok := part.(map[string]interface{})
+		if !ok || (p["type"] != nil && p["type"] != "object") {
+			return
+		}
+		if props, ok := p["properties"].(map[string]interface{}); ok {
+			for name, property := range props {
+				properties[name] = property
+			}
+		}
+		if req, ok := p["required"].([]interface{}); ok {
+			required = append(required, req...)
+		}
+	}
+	delete(schema, "allOf")
+	schema["type"] = "object"
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+}
+
+// protoScalarTypes maps the scalar types of protobuf to JSON schema types.
+var protoScalarTypes = map[string]string{
+	"double": "number", "float": "number",
+	"int32": "integer", "int64": "integer", "uint32": "integer", "uint64": "integer",
+	"sint32": "integer", "sint64": "integer", "fixed32": "integer", "fixed64": "integer",
+	"sfixed32": "integer", "sfixed64": "integer",
+	"bool": "boolean", "string": "string", "bytes": "string",
+}
+
+// protoWellKnownTypes maps the well-known protobuf types to their JSON
+// schema, following their canonical JSON mapping.
+var protoWellKnownTypes = map[string]map[string]interface{}{
+	"google.protobuf.Timestamp":   {"type": "string", "format": "date-time"},
+	"google.protobuf.Duration":    {"type": "string", "pattern": `^-?\d+(\.\d+)?s$`},
+	"google.protobuf.Struct":      {"type": "object", "properties": map[string]interface{}{}},
+	"google.protobuf.Any":         {"type": "object", "properties": map[string]interface{}{}},
+	"google.protobuf.Empty":       {"type": "object", "properties": map[string]interface{}{}},
+	"google.protobuf.FieldMask":   {"type": "string"},
+	"google.protobuf.StringValue": {"type": "string"},
+	"google.protobuf.BytesValue":  {"type": "string"},
+	"google.protobuf.BoolValue":   {"type": "boolean"},
+	"google.protobuf.DoubleValue": {"type": "number"},
+	"google.protobuf.FloatValue":  {"type": "number"},
+	"google.protobuf.Int32Value":  {"type": "integer"},
+	"google.protobuf.Int64Value":  {"type": "integer"},
+	"google.protobuf.UInt32Value": {"type": "integer"},
+	"google.protobuf.UInt64Value": {"type": "integer"},
+}
+
+// protoField is a field of a protobuf message.
+type protoField struct {
+	name     string
+	jsonName string
+	typ      string
+	mapValue string // Value type of a map field, whose typ is the key type
+	repeated bool
+	required bool
+}
+
+// protoMessage is a protobuf message or enum definition.
+type protoMessage struct {
+	fullName string
+	fields   []protoField
+	enum     []string // Values of an enum
+}
+
+// SchemaFromProto derives the JSON schema of a response from the message
+// named message of a .proto file, in proto2 or proto3 syntax, so that
+// services whose types are defined in protobuf need no parallel Go structs.
+// Field names follow the canonical JSON mapping of protobuf, in lowerCamelCase
+// unless set with json_name, so responses decode with protojson. Nested and
+// imported well-known types are supported; other imported types are not.
+// The result can be passed to GenerateWithSchema or RegisterSchema.
+//
+// Example:
+//
+//	source, _ := os.ReadFile("invoice.proto")
+//	schema, err := llm.SchemaFromProto(string(source), "billing.v1.Invoice")
+func SchemaFromProto(source, message string) (json.RawMessage, error) {
+	parser := &protoParser{tokens: tokenizeProto(source), messages: make(map[string]*protoMessage)}
+	if err := parser.parseFile(); err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid .proto source", err)
+	}
+	msg := parser.resolve(message, "")
+	if msg == nil || msg.enum != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("no message %q in the .proto source", message), nil)
+	}
+	schema := parser.messageSchema(msg, map[string]bool{})
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "failed to encode JSON schema", err)
+	}
+	return raw, nil
+}
+
+// tokenizeProto splits .proto source into identifiers, numbers, strings and
+// symbols, without comments.
+func tokenizeProto(source string) []string {
+	var tokens []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '"' || r == '\'':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			tokens = append(tokens, string(runes[start:min(i, len(runes))]))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '+':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			if i == start {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// protoParser reads the message and enum definitions of a .proto file.
+type protoParser struct {
+	tokens   []string
+	pos      int
+	pkg      string
+	messages map[string]*protoMessage // By full name, without the package
+}
+
+func (p *protoParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *protoParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *protoParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+// skipStatement skips to the end of the current statement or block.
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseFile() error {
+	for p.pos < len(p.tokens) {
+		switch p.peek() {
+		case "package":
+			p.next()
+			p.pkg = p.next()
+			p.skipStatement()
+		case "message":
+			if err := p.parseMessage(""); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(""); err != nil {
+				return err
+			}
+		default:
+			// syntax, import, option, service, extend
+			p.skipStatement()
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) parseMessage(scope string) error {
+	p.next()
+	msg := &protoMessage{fullName: joinProtoName(scope, p.next())}
+	p.messages[msg.fullName] = msg
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch token := p.peek(); token {
+		case "":
+			return fmt.Errorf("unterminated message %s", msg.fullName)
+		case "}":
+			p.next()
+			return nil
+		case ";":
+			p.next()
+		case "message":
+			if err := p.parseMessage(msg.fullName); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(msg.fullName); err != nil {
+				return err
+			}
+		case "oneof":
+			// The fields of a oneof are optional fields of the message
+			p.next()
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for p.peek() != "}" && p.peek() != "" {
+				if p.peek() == "option" {
+					p.skipStatement()
+					continue
+				}
+				if err := p.parseField(msg); err != nil {
+					return err
+				}
+			}
+			p.next()
+		case "option", "reserved", "extensions", "extend":
+			p.skipStatement()
+		default:
+			if err := p.parseField(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseField(msg *protoMessage) error {
+	var field protoField
+	switch p.peek() {
+	case "repeated":
+		field.repeated = true
+		p.next()
+	case "required":
+		field.required = true
+		p.next()
+	case "optional":
+		p.next()
+	}
+	if p.peek() == "map" {
+		p.next()
+		if err := p.expect("<"); err != nil {
+			return err
+		}
+		field.typ = p.next()
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		field.mapValue = p.next()
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+	} else {
+		field.typ = p.next()
+	}
+	field.name = p.next()
+	if err := p.expect("="); err != nil {
+		return fmt.Errorf("field %s of %s: %w", field.name, msg.fullName, err)
+	}
+	p.next()
+	if p.peek() == "[" {
+		for p.peek() != "]" && p.peek() != "" {
+			if p.next() == "json_name" && p.peek() == "=" {
+				p.next()
+				field.jsonName = strings.Trim(p.next(), `"'`)
+			}
+		}
+		p.next()
+	}
+	if err := p.expect(";"); err != nil {
+		return fmt.Errorf("field %s of %s: %w", field.name, msg.fullName, err)
+	}
+	if field.jsonName == "" {
+		field.jsonName = protoJSONName(field.name)
+	}
+	msg.fields = append(msg.fields, field)
+	return nil
+}
+
+func (p *protoParser) parseEnum(scope string) error {
+	p.next()
+	enum := &protoMessage{fullName: joinProtoName(scope, p.next()), enum: []string{}}
+	p.messages[enum.fullName] = enum
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch token := p.peek(); token {
+		case "":
+			return fmt.Errorf("unterminated enum %s", enum.fullName)
+		case "}":
+			p.next()
+			return nil
+		case ";":
+			p.next()
+		case "option", "reserved":
+			p.skipStatement()
+		default:
+			enum.enum = append(enum.enum, p.next())
+			p.skipStatement()
+		}
+	}
+}
+
+// resolve finds the message or enum name refers to from within scope,
+// following the scoping rules of protobuf: the innermost enclosing scope
+// first, then outwards.
+func (p *protoParser) resolve(name, scope string) *protoMessage {
+	name = strings.TrimPrefix(name, ".")
+	if p.pkg != "" {
+		name = strings.TrimPrefix(name, p.pkg+".")
+	}
+	for {
+		if msg, ok := p.messages[joinProtoName(scope, name)]; ok {
+			return msg
+		}
+		if scope == "" {
+			return nil
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// messageSchema returns the JSON schema of a message. visiting holds the
+// messages being described, to stop at recursive ones.
+func (p *protoParser) messageSchema(msg *protoMessage, visiting map[string]bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if visiting[msg.fullName] {
+		return schema
+	}
+	visiting[msg.fullName] = true
+	defer delete(visiting, msg.fullName)
+
+	var required []string
+	for _, field := range msg.fields {
+		var property map[string]interface{}
+		if field.mapValue != "" {
+			property = map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{},
+				"additionalProperties": p.typeSchema(field.mapValue, msg.fullName, visiting),
+			}
+		} else {
+			property = p.typeSchema(field.typ, msg.fullName, visiting)
+			if field.repeated {
+				property = map[string]interface{}{"type": "array", "items": property}
+			}
+		}
+		properties[field.jsonName] = property
+		if field.required {
+			required = append(required, field.jsonName)
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema returns the JSON schema of a field type referred to from scope.
+func (p *protoParser) typeSchema(typ, scope string, visiting map[string]bool) map[string]interface{} {
+	if t, ok := protoScalarTypes[typ]; ok {
+		schema := map[string]interface{}{"type": t}
+		if typ == "bytes" {
+			schema["contentEncoding"] = "base64"
+		}
+		return schema
+	}
+	if schema, ok := protoWellKnownTypes[strings.TrimPrefix(typ, ".")]; ok {
+		return copyJSONSchema(schema)
+	}
+	msg := p.resolve(typ, scope)
+	switch {
+	case msg == nil:
+		// An imported type: describe it loosely
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	case msg.enum != nil:
+		return map[string]interface{}{"type": "string", "enum": msg.enum}
+	default:
+		return p.messageSchema(msg, visiting)
+	}
+}
+
+func copyJSONSchema(schema map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		c[k] = v
+	}
+	return c
+}
+
+func joinProtoName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// protoJSONName converts a field name to lowerCamelCase, as protobuf's JSON
+// mapping does.
+func protoJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
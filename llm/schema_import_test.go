@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaFromOpenAPI(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+components:
+  schemas:
+    Money:
+      type: object
+      required: [amount, currency]
+      properties:
+        amount: {type: number}
+        currency: {type: string, example: USD}
+    Invoice:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          required: [total, secret]
+          properties:
+            total: {$ref: '#/components/schemas/Money'}
+            note: {type: string, nullable: true}
+            secret: {type: string, writeOnly: true}
+            parent: {$ref: '#/components/schemas/Invoice'}
+    Base:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+`
+	raw, err := SchemaFromOpenAPI([]byte(spec), "Invoice")
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	assert.Equal(t, "object", schema["type"])
+	assert.NotContains(t, schema, "allOf")
+	assert.ElementsMatch(t, []interface{}{"id", "total"}, schema["required"])
+	properties := schema["properties"].(map[string]interface{})
+	assert.NotContains(t, properties, "secret")
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["note"])
+	assert.Equal(t, []interface{}{"amount", "currency"}, properties["total"].(map[string]interface{})["required"])
+
+	assert.NoError(t, ValidateAgainstSchema(`{"id": "1", "total": {"amount": 3, "currency": "EUR"}}`, schema))
+	assert.Error(t, ValidateAgainstSchema(`{"id": "1"}`, schema))
+
+	_, err = SchemaFromOpenAPI([]byte(spec), "Missing")
+	assert.Error(t, err)
+}
+
+func TestSchemaFromProto(t *testing.T) {
+	source := `
+syntax = "proto3";
+package billing.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// An invoice sent to a customer.
+message Invoice {
+  string invoice_number = 1;
+  Status status = 2 [json_name = "state"];
+  repeated Line lines = 3;
+  map<string, string> labels = 4;
+  google.protobuf.Timestamp issued_at = 5;
+  oneof payment {
+    string card_token = 6;
+    bytes bank_reference = 7;
+  }
+  Invoice previous = 8; /* a recursive reference */
+
+  message Line {
+    string item = 1;
+    int64 quantity = 2;
+    double unit_price = 3 [deprecated = true];
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PAID = 1;
+}
+`
+	raw, err := SchemaFromProto(source, "billing.v1.Invoice")
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	properties := schema["properties"].(map[string]interface{})
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"invoiceNumber", "state", "lines", "labels", "issuedAt", "cardToken", "bankReference", "previous"}, names)
+	assert.Equal(t, map[string]interface{}{"type": "string", "enum": []interface{}{"STATUS_UNSPECIFIED", "STATUS_PAID"}}, properties["state"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, properties["issuedAt"])
+	lines := properties["lines"].(map[string]interface{})
+	assert.Equal(t, "array", lines["type"])
+	line := lines["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, line["quantity"])
+	assert.Contains(t, line, "unitPrice")
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["labels"].(map[string]interface{})["additionalProperties"])
+	assert.Equal(t, "object", properties["previous"].(map[string]interface{})["type"])
+
+	assert.NoError(t, ValidateAgainstSchema(`{"invoiceNumber": "INV-1", "lines": [{"item": "bolt", "quantity": 3}]}`, schema))
+
+	_, err = SchemaFromProto(source, "Status")
+	assert.Error(t, err, "enums are not messages")
+	_, err = SchemaFromProto("message Broken { string name 1; }", "Broken")
+	assert.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SchemaMigration converts a response document from one version of a schema
+// to the next, such as by renaming or splitting fields.
+type SchemaMigration func(document map[string]interface{}) (map[string]interface{}, error)
+
+// registeredSchema is one version of a named schema.
+type registeredSchema struct {
+	raw    json.RawMessage
+	parsed map[string]interface{}
+}
+
+// SchemaRegistry holds named, versioned JSON schemas for structured
+// responses, so that the teams of an application share one definition of
+// each extraction format. Generate calls refer to a schema by reference,
+// "name@version", or "name" for its latest version, with WithSchemaRef.
+// Migrations convert documents extracted with an older version. It is safe
+// for concurrent use.
+type SchemaRegistry struct {
+	mu         sync.RWMutex
+	schemas    map[string]map[string]*registeredSchema
+	versions   map[string][]string // Versions of each name, in registration order
+	migrations map[string]map[string]map[string]SchemaMigration
+}
+
+// NewSchemaRegistry creates an empty SchemaRegistry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		schemas:    make(map[string]map[string]*registeredSchema),
+		versions:   make(map[string][]string),
+		migrations: make(map[string]map[string]map[string]SchemaMigration),
+	}
+}
+
+// DefaultSchemaRegistry is the registry of RegisterSchema and WithSchemaRef.
+var DefaultSchemaRegistry = NewSchemaRegistry()
+
+// RegisterSchema registers a schema in DefaultSchemaRegistry, as
+// SchemaRegistry.Register.
+//
+// Example:
+//
+//	llm.RegisterSchema("invoice", "v2", Invoice{})
+//	document, err := client.Generate(ctx, prompt, llm.WithSchemaRef("invoice@v2"))
+func RegisterSchema(name, version string, schema interface{}) error {
+	return DefaultSchemaRegistry.Register(name, version, schema)
+}
+
+// WithSchemaRef asks for a JSON response conforming to a schema of
+// DefaultSchemaRegistry, as SchemaRegistry.Ref.
+func WithSchemaRef(ref string) GenerateOption {
+	return DefaultSchemaRegistry.Ref(ref)
+}
+
+// Register adds a version of the schema name. schema is a JSON schema, as
+// JSON text, bytes or a map, or a struct, or a pointer to one, whose schema
+// is derived with GenerateJSONSchema. The latest registered version is the
+// one a reference without version selects. Registering a version twice
+// fails, as documents extracted with it would become ambiguous.
+func (r *SchemaRegistry) Register(name, version string, schema interface{}) error {
+	if name == "" || version == "" || strings.Contains(name, "@") {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("invalid schema name %q or version %q", name, version), nil)
+	}
+	var raw []byte
+	var err error
+	switch s := schema.(type) {
+	case string:
+		raw = []byte(s)
+	case []byte:
+		raw = s
+	case json.RawMessage:
+		raw = s
+	case map[string]interface{}:
+		raw, err = json.Marshal(s)
+	default:
+		raw, err = GenerateJSONSchema(schema)
+	}
+	if err != nil {
+		return NewLLMError(ErrorTypeInvalidInput, "failed to derive JSON schema", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return NewLLMError(ErrorTypeInvalidInput, "invalid JSON schema", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schemas[name] == nil {
+		r.schemas[name] = make(map[string]*registeredSchema)
+	}
+	if _, ok := r.schemas[name][version]; ok {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("schema %s@%s is already registered", name, version), nil)
+	}
+	r.schemas[name][version] = &registeredSchema{raw: raw, parsed: parsed}
+	r.versions[name] = append(r.versions[name], version)
+	return nil
+}
+
+// Versions returns the registered versions of name, in registration order.
+func (r *SchemaRegistry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.versions[name]...)
+}
+
+// Schema returns the JSON schema of a reference, for use with
+// GenerateWithSchema or ValidateAgainstSchema.
+func (r *SchemaRegistry) Schema(ref string) (json.RawMessage, error) {
+	schema, _, err := r.lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	return schema.raw, nil
+}
+
+// lookup returns the schema of ref and its canonical "name@version" form.
+func (r *SchemaRegistry) lookup(ref string) (*registeredSchema, string, error) {
+	name, version, _ := strings.Cut(ref, "@")
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if version == "" {
+		versions := r.versions[name]
+		if len(versions) == 0 {
+			return nil, "", NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unknown schema %q", name), nil)
+		}
+		version = versions[len(versions)-1]
+	}
+	schema, ok := r.schemas[name][version]
+	if !ok {
+		return nil, "", NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unknown schema %s@%s", name, version), nil)
+	}
+	return schema, name + "@" + version, nil
+}
+
+// RegisterMigration registers the conversion of documents of the schema
+// name from one version to another. Migrate chains migrations, so each
+// version only needs one to the version that follows it.
+func (r *SchemaRegistry) RegisterMigration(name, from, to string, migrate SchemaMigration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.migrations[name] == nil {
+		r.migrations[name] = make(map[string]map[string]SchemaMigration)
+	}
+	if r.migrations[name][from] == nil {
+		r.migrations[name][from] = make(map[string]SchemaMigration)
+	}
+	r.migrations[name][from][to] = migrate
+}
+
+// Migrate converts a JSON document conforming to the schema reference from,
+// such as "invoice@v1", to the version of to, such as "invoice@v2", through
+// the shortest chain of registered migrations. The result is validated
+// against the target schema.
+func (r *SchemaRegistry) Migrate(document []byte, from, to string) ([]byte, error) {
+	_, fromRef, err := r.lookup(from)
+	if err != nil {
+		return nil, err
+	}
+	target, toRef, err := r.lookup(to)
+	if err != nil {
+		return nil, err
+	}
+	name, fromVersion, _ := strings.Cut(fromRef, "@")
+	toName, toVersion, _ := strings.Cut(toRef, "@")
+	if name != toName {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("cannot migrate %s to %s", fromRef, toRef), nil)
+	}
+	chain, ok := r.migrationChain(name, fromVersion, toVersion)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("no migration from %s to %s", fromRef, toRef), nil)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(document, &data); err != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "invalid JSON document", err)
+	}
+	for _, migrate := range chain {
+		if data, err = migrate(data); err != nil {
+			return nil, NewLLMError(ErrorTypeResponse, fmt.Sprintf("failed to migrate %s to %s", fromRef, toRef), err)
+		}
+	}
+	migrated, err := json.Marshal(data)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to encode migrated document", err)
+	}
+	if err := ValidateAgainstSchema(string(migrated), target.parsed); err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, fmt.Sprintf("migrated document does not match %s", toRef), err)
+	}
+	return migrated, nil
+}
+
+// migrationChain returns the shortest sequence of migrations of name from
+// one version to another.
+func (r *SchemaRegistry) migrationChain(name, from, to string) ([]SchemaMigration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	type step struct {
+		version string
+		chain   []SchemaMigration
+	}
+	queue := []step{{version: from}}
+	visited := map[string]bool{from: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.version == to {
+			return current.chain, true
+		}
+		for next, migrate := range r.migrations[name][current.version] {
+			if !visited[next] {
+				visited[next] = true
+				chain := append(append([]SchemaMigration(nil), current.chain...), migrate)
+				queue = append(queue, step{version: next, chain: chain})
+			}
+		}
+	}
+	return nil, false
+}
+
+// schemaReference is the registered schema requested for a call.
+type schemaReference struct {
+	registry *SchemaRegistry
+	ref      string
+}
+
+// Ref asks for a JSON response conforming to the schema of ref, such as
+// "invoice@v2". The schema is added to the prompt's output instructions, and
+// a response that does not conform is rejected and the attempt retried.
+// Generate returns the JSON document without the surrounding text.
+func (r *SchemaRegistry) Ref(ref string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.schemaRef = &schemaReference{registry: r, ref: ref}
+	}
+}
+
+// apply returns a copy of prompt asking for the referenced schema, and adds
+// the validation of the response to cfg's post-processors.
+func (s *schemaReference) apply(prompt *Prompt, cfg *GenerateConfig) (*Prompt, error) {
+	schema, _, err := s.registry.lookup(s.ref)
+	if err != nil {
+		return nil, err
+	}
+	instructions := "Respond only with JSON that conforms to this JSON schema:\n" + string(schema.raw)
+	referenced := *prompt
+	if referenced.Output != "" {
+		instructions = referenced.Output + "\n\n" + instructions
+	}
+	referenced.Output = instructions
+	cfg.PostProcessors = append(cfg.PostProcessors, func(text string) (string, error) {
+		document, _ := StripCodeFences()(text)
+		document, err := ExtractJSON()(document)
+		if err != nil {
+			return "", err
+		}
+		if err := ValidateAgainstSchema(document, schema.parsed); err != nil {
+			return "", err
+		}
+		return document, nil
+	})
+	return &referenced, nil
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	type invoiceV2 struct {
+		Number string  `json:"number" validate:"required"`
+		Total  float64 `json:"total" validate:"required"`
+	}
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.Register("invoice", "v1", `{"type": "object", "properties": {"id": {"type": "string"}, "amount": {"type": "number"}}, "required": ["id"]}`))
+	require.NoError(t, registry.Register("invoice", "v2", &invoiceV2{}))
+	assert.Error(t, registry.Register("invoice", "v2", invoiceV2{}), "versions are immutable")
+	assert.Equal(t, []string{"v1", "v2"}, registry.Versions("invoice"))
+
+	latest, err := registry.Schema("invoice")
+	require.NoError(t, err)
+	assert.Contains(t, string(latest), `"total"`)
+	_, err = registry.Schema("invoice@v3")
+	assert.Error(t, err)
+
+	registry.RegisterMigration("invoice", "v1", "v2", func(doc map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"number": doc["id"], "total": doc["amount"]}, nil
+	})
+	migrated, err := registry.Migrate([]byte(`{"id": "INV-1", "amount": 12.5}`), "invoice@v1", "invoice@v2")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"number": "INV-1", "total": 12.5}`, string(migrated))
+	_, err = registry.Migrate(migrated, "invoice@v2", "invoice@v1")
+	assert.Error(t, err, "no migration back")
+
+	var replies, prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		prompts = append(prompts, request["prompt"].(string))
+		reply := replies[0]
+		replies = replies[1:]
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": reply})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL, config.SetMaxRetries(1))
+
+	// The first reply does not conform and is retried
+	replies = []string{`{"id": "INV-2"}`, "Here it is: {\"number\": \"INV-2\", \"total\": 30}"}
+	document, err := l.Generate(context.Background(), NewPrompt("extract"), registry.Ref("invoice@v2"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"number": "INV-2", "total": 30}`, document)
+	assert.Contains(t, prompts[0], `"total"`)
+	assert.Len(t, prompts, 2)
+
+	_, err = l.Generate(context.Background(), NewPrompt("extract"), WithSchemaRef("unknown@v1"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// ScoreResult is the log-likelihood of a continuation given a prompt.
+type ScoreResult struct {
+	// LogProb is the sum of the natural log probabilities of the tokens of
+	// the continuation.
+	LogProb float64
+
+	// Tokens are the tokens of the continuation with their log probabilities.
+	Tokens []providers.TokenLogprob
+}
+
+// Mean returns the average log probability per token of the continuation,
+// which compares continuations of different lengths more fairly than
+// LogProb.
+func (r ScoreResult) Mean() float64 {
+	if len(r.Tokens) == 0 {
+		return 0
+	}
+	return r.LogProb / float64(len(r.Tokens))
+}
+
+// Score returns the log-likelihood of continuation following prompt, using
+// the completions endpoint of the LLM's provider to echo the text with the
+// log probability of each token. No chat template or system prompt is
+// applied. Scoring several continuations of one prompt ranks them, which
+// suits reranking and multiple-choice evaluation. It returns
+// ErrorTypeUnsupported when the provider cannot score text; OpenAI and the
+// OpenAI-compatible servers, such as vLLM, can.
+//
+// Example:
+//
+//	paris, err := llm.Score(ctx, client, "The capital of France is", " Paris")
+//	lyon, err := llm.Score(ctx, client, "The capital of France is", " Lyon")
+func Score(ctx context.Context, l LLM, prompt, continuation string, opts ...GenerateOption) (ScoreResult, error) {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return ScoreResult{}, NewLLMError(ErrorTypeUnsupported, "scoring requires a gollm client", nil)
+	}
+	if _, ok := impl.Provider.(providers.ScoringProvider); !ok {
+		return ScoreResult{}, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("scoring not supported by provider %s", impl.Provider.Name()), nil)
+	}
+	if continuation == "" {
+		return ScoreResult{}, NewLLMError(ErrorTypeInvalidInput, "empty continuation", nil)
+	}
+
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// Offsets are in characters; the tokens of the continuation lie between
+	// the end of the prompt and the end of the text.
+	text := prompt + continuation
+	start, end := utf8.RuneCountInString(prompt), utf8.RuneCountInString(text)
+
+	var result ScoreResult
+	err := impl.callEndpoint(ctx, "failed to score", func(provider providers.Provider) (string, []byte, error) {
+		scorer, ok := provider.(providers.ScoringProvider)
+		if !ok {
+			return "", nil, NewLLMError(ErrorTypeUnsupported, "scoring not supported by provider "+provider.Name(), nil)
+		}
+		reqBody, err := scorer.PrepareScoringRequest(text, cfg.Options)
+		if err != nil {
+			return "", nil, NewLLMError(ErrorTypeRequest, "failed to prepare scoring request", err)
+		}
+		return scorer.ScoringEndpoint(), reqBody, nil
+	}, func(provider providers.Provider, body []byte) error {
+		tokens, err := provider.(providers.ScoringProvider).ParseScoringResponse(body)
+		if err != nil {
+			return NewLLMError(ErrorTypeResponse, "failed to parse scoring response", err)
+		}
+		result = ScoreResult{}
+		for _, token := range tokens {
+			if token.Offset >= start && token.Offset < end {
+				result.LogProb += token.Logprob
+				result.Tokens = append(result.Tokens, token)
+			}
+		}
+		if len(result.Tokens) == 0 {
+			return NewLLMError(ErrorTypeResponse, "no log probabilities for the continuation", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return ScoreResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestScore(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/completions", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fmt.Fprint(w, `{"choices":[{"text":"The capital is Paris.","logprobs":{
+			"tokens":["The"," capital"," is"," Paris","."],
+			"token_logprobs":[null,-4.5,-1.0,-0.5,-2.0],
+			"text_offset":[0,3,11,14,20]}}]}`)
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("vllm"), config.SetModel("base"), config.SetAPIKey("test-key"))
+	cfg.Endpoints = map[string]string{"vllm": server.URL}
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	result, err := Score(context.Background(), l, "The capital is", " Paris")
+	require.NoError(t, err)
+	assert.Equal(t, -0.5, result.LogProb)
+	require.Len(t, result.Tokens, 1)
+	assert.Equal(t, " Paris", result.Tokens[0].Token)
+	assert.Equal(t, "The capital is Paris", request["prompt"])
+	assert.Equal(t, true, request["echo"])
+	assert.Equal(t, float64(1), request["max_tokens"])
+
+	result, err = Score(context.Background(), l, "The", " capital is")
+	require.NoError(t, err)
+	assert.Equal(t, -5.5, result.LogProb)
+	assert.Equal(t, -2.75, result.Mean())
+
+	_, err = Score(context.Background(), newTestLLM(t, server.URL), "prompt", " text")
+	assert.Error(t, err)
+}
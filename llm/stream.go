@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"regexp"
 	"time"
 )
 
@@ -21,6 +23,11 @@ type StreamToken struct {
 
 	// Metadata contains provider-specific metadata
 	Metadata map[string]interface{}
+
+	// Usage is the final token usage of the response, set on the
+	// TokenTypeUsage token that streams returned by Stream send once, after
+	// the response text and tool calls, whatever the provider.
+	Usage *Usage
 }
 
 // TokenStream represents a stream of tokens from the LLM.
@@ -34,6 +41,55 @@ type TokenStream interface {
 	io.Closer
 }
 
+// ErrStreamClosed is returned by Next after the stream has been closed.
+var ErrStreamClosed = errors.New("stream closed")
+
+// StreamResult summarizes a stream that has ended or been closed.
+type StreamResult struct {
+	// Text is the text received so far.
+	Text string
+
+	// Reasoning is the reasoning received so far, for models that stream
+	// it apart from the text.
+	Reasoning string
+
+	// Usage is the token usage reported by the stream, if any.
+	Usage Usage
+
+	// Truncated is true when the stream was closed, cancelled or failed
+	// before the provider finished the response.
+	Truncated bool
+
+	// Timing is the latency of the stream, including its time to first
+	// token.
+	Timing Timing
+}
+
+// StreamResulter is implemented by streams that can report their partial
+// result, including the streams returned by Stream. Closing a stream
+// mid-flight aborts the response; Result then returns what was received
+// before the cancellation.
+type StreamResulter interface {
+	Result() StreamResult
+}
+
+// PartialResult returns the result of stream so far, or false when the
+// stream does not report results.
+//
+// Example:
+//
+//	stream.Close() // e.g. when the user presses stop
+//	if result, ok := llm.PartialResult(stream); ok && result.Truncated {
+//	    save(result.Text)
+//	}
+func PartialResult(stream TokenStream) (StreamResult, bool) {
+	r, ok := stream.(StreamResulter)
+	if !ok {
+		return StreamResult{}, false
+	}
+	return r.Result(), true
+}
+
 // StreamOption is a function type for configuring streaming behavior.
 type StreamOption func(*StreamConfig)
 
@@ -44,6 +100,39 @@ type StreamConfig struct {
 
 	// RetryStrategy defines how to handle stream interruptions
 	RetryStrategy RetryStrategy
+
+	// StopPattern ends the stream where it first matches the text, if set
+	StopPattern *regexp.Regexp
+
+	// HideReasoning drops the reasoning tokens of reasoning models
+	HideReasoning bool
+}
+
+// WithoutReasoning drops the reasoning tokens that reasoning models such as
+// DeepSeek-R1 or Claude with extended thinking stream before their answer,
+// for callers that only render the answer. The reasoning still counts in
+// the ReasoningTokens of the usage, when the provider reports it.
+func WithoutReasoning() StreamOption {
+	return func(c *StreamConfig) {
+		c.HideReasoning = true
+	}
+}
+
+// WithStopPattern ends the stream as soon as pattern matches the text
+// received so far, such as the closing brace of a JSON object or an
+// "END_OF_ANSWER" marker, and aborts the request so that the provider stops
+// generating. The text is delivered up to the end of the match; what follows
+// it is dropped. Unlike a server-side stop sequence, it works with any
+// provider and any regular expression. Providers rarely report the usage of
+// an aborted response.
+//
+// Example:
+//
+//	stream, err := client.Stream(ctx, prompt, llm.WithStopPattern(regexp.MustCompile(`END_OF_ANSWER`)))
+func WithStopPattern(pattern *regexp.Regexp) StreamOption {
+	return func(c *StreamConfig) {
+		c.StopPattern = pattern
+	}
 }
 
 // RetryStrategy defines how to handle stream interruptions.
@@ -83,7 +172,9 @@ func (s *DefaultRetryStrategy) Reset() {
 	s.attempts = 0
 }
 
-// SSEDecoder handles Server-Sent Events (SSE) streaming
+// SSEDecoder handles Server-Sent Events (SSE) streaming. It also reads
+// newline-delimited JSON streams, such as Ollama's, where every line that
+// starts a JSON value is dispatched as an event of its own.
 type SSEDecoder struct {
 	reader  *bufio.Scanner
 	current Event
@@ -96,8 +187,11 @@ type Event struct {
 }
 
 func NewSSEDecoder(reader io.Reader) *SSEDecoder {
+	scanner := bufio.NewScanner(reader)
+	// Tool call chunks and final responses can exceed the default 64KB line limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
 	return &SSEDecoder{
-		reader: bufio.NewScanner(reader),
+		reader: scanner,
 	}
 }
 
@@ -121,6 +215,12 @@ func (d *SSEDecoder) Next() bool {
 			return true
 		}
 
+		// A bare JSON line is a complete newline-delimited JSON event
+		if event == "" && data.Len() == 0 && (line[0] == '{' || line[0] == '[') {
+			d.current = Event{Data: append([]byte(nil), line...)}
+			return true
+		}
+
 		// Split "event: value" into parts
 		name, value, _ := bytes.Cut(line, []byte(":"))
 
@@ -140,6 +240,13 @@ func (d *SSEDecoder) Next() bool {
 		}
 	}
 
+	// Report read failures, such as a dropped connection, instead of a clean end
+	d.err = d.reader.Err()
+	if d.err == nil && data.Len() > 0 {
+		// Dispatch a final event that was not followed by an empty line
+		d.current = Event{Type: event, Data: data.Bytes()}
+		return true
+	}
 	return false
 }
 
@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Token types with special meaning to StreamWithCallbacks. Streams deliver
+// the tool call or usage of such tokens in Metadata under the "tool_call"
+// (ToolCall) or "usage" (Usage) key, and the usage in StreamToken.Usage too. Reasoning tokens carry the model's
+// reasoning in Text, which is not part of the response. Any other type is
+// treated as text.
+const (
+	TokenTypeText      = "text"
+	TokenTypeToolCall  = "tool_call"
+	TokenTypeUsage     = "usage"
+	TokenTypeReasoning = "reasoning"
+)
+
+// isTextToken reports whether token is part of the response text.
+func isTextToken(token *StreamToken) bool {
+	switch token.Type {
+	case TokenTypeToolCall, TokenTypeUsage, TokenTypeReasoning:
+		return false
+	}
+	return true
+}
+
+// StreamCallbacks receives the events of a streamed response. Nil callbacks
+// are skipped. Callbacks run on the goroutine that called StreamWithCallbacks.
+type StreamCallbacks struct {
+	// OnToken is called for every text token.
+	OnToken func(token *StreamToken)
+
+	// OnReasoning is called for every reasoning token, which reasoning
+	// models stream before their answer, such as to render a "thinking"
+	// section apart from the response.
+	OnReasoning func(token *StreamToken)
+
+	// OnToolCall is called for every tool call the model requests.
+	OnToolCall func(call ToolCall)
+
+	// OnUsage is called when the provider reports token usage.
+	OnUsage func(usage Usage)
+
+	// OnFinish is called once with the complete text when the stream ends,
+	// together with the error that ended it, if any.
+	OnFinish func(response string, err error)
+}
+
+// StreamWithCallbacks streams the response to prompt and dispatches each
+// token to the matching callback, as an alternative to pulling tokens from a
+// TokenStream. It returns the complete text once the stream ends. Providers
+// without streaming support fall back to a single Generate call delivered as
+// one token.
+//
+// Example:
+//
+//	_, err := llm.StreamWithCallbacks(ctx, client, prompt, llm.StreamCallbacks{
+//	    OnToken:  func(t *llm.StreamToken) { fmt.Print(t.Text) },
+//	    OnFinish: func(string, error) { fmt.Println() },
+//	})
+func StreamWithCallbacks(ctx context.Context, l LLM, prompt *Prompt, callbacks StreamCallbacks, opts ...StreamOption) (string, error) {
+	response, err := streamWithCallbacks(ctx, l, prompt, callbacks, opts...)
+	if callbacks.OnFinish != nil {
+		callbacks.OnFinish(response, err)
+	}
+	return response, err
+}
+
+func streamWithCallbacks(ctx context.Context, l LLM, prompt *Prompt, callbacks StreamCallbacks, opts ...StreamOption) (string, error) {
+	if !l.SupportsStreaming() {
+		response, err := l.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		if callbacks.OnToken != nil {
+			callbacks.OnToken(&StreamToken{Text: response, Type: TokenTypeText})
+		}
+		return response, nil
+	}
+
+	stream, err := l.Stream(ctx, prompt, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var response strings.Builder
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return response.String(), nil
+		}
+		if err != nil {
+			return response.String(), err
+		}
+
+		switch token.Type {
+		case TokenTypeToolCall:
+			if call, ok := token.Metadata["tool_call"].(ToolCall); ok && callbacks.OnToolCall != nil {
+				callbacks.OnToolCall(call)
+			}
+		case TokenTypeUsage:
+			usage, ok := token.Metadata["usage"].(Usage)
+			if token.Usage != nil {
+				usage, ok = *token.Usage, true
+			}
+			if ok && callbacks.OnUsage != nil {
+				callbacks.OnUsage(usage)
+			}
+		case TokenTypeReasoning:
+			if callbacks.OnReasoning != nil {
+				callbacks.OnReasoning(token)
+			}
+		default:
+			response.WriteString(token.Text)
+			if callbacks.OnToken != nil {
+				callbacks.OnToken(token)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWithCallbacks(t *testing.T) {
+	l := &tokenLLM{tokens: []string{"Hel", "lo"}}
+
+	var tokens []string
+	var finished string
+	text, err := StreamWithCallbacks(context.Background(), l, NewPrompt("hi"), StreamCallbacks{
+		OnToken: func(token *StreamToken) { tokens = append(tokens, token.Text) },
+		OnFinish: func(response string, err error) {
+			assert.NoError(t, err)
+			finished = response
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, []string{"Hel", "lo"}, tokens)
+	assert.Equal(t, "Hello", finished)
+}
@@ -0,0 +1,276 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// sseProvider streams {"text": ...} server-sent events ending with [DONE].
+type sseProvider struct{ echoProvider }
+
+func (p *sseProvider) SupportsStreaming() bool { return true }
+
+func (p *sseProvider) ParseStreamResponse(chunk []byte) (string, error) {
+	if string(bytes.TrimSpace(chunk)) == "[DONE]" {
+		return "", io.EOF
+	}
+	var event struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return "", err
+	}
+	return event.Text, nil
+}
+
+// anthropicStreamProvider decodes streams with the Anthropic event parser.
+type anthropicStreamProvider struct{ sseProvider }
+
+func (p *anthropicStreamProvider) ParseStreamEvent(eventType string, data []byte) ([]providers.StreamEvent, error) {
+	parser := providers.NewAnthropicProvider("key", "model", nil).(providers.StreamEventParser)
+	return parser.ParseStreamEvent(eventType, data)
+}
+
+func TestStreamTypedEvents(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":5,"output_tokens":1}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check"}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"message_delta","usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		for _, event := range events {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("events", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &anthropicStreamProvider{sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("events"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	var calls []ToolCall
+	var usage Usage
+	var usageEvents int
+	text, err := StreamWithCallbacks(context.Background(), l, NewPrompt("hi"), StreamCallbacks{
+		OnToolCall: func(call ToolCall) { calls = append(calls, call) },
+		OnUsage:    func(u Usage) { usage = u; usageEvents++ },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check", text)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "toolu_1", calls[0].ID)
+	assert.Equal(t, "get_weather", calls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(calls[0].Function.Arguments))
+	assert.Equal(t, 1, usageEvents, "usage is reported once, at the end")
+	assert.Equal(t, Usage{PromptTokens: 15, CompletionTokens: 7, CachedTokens: 5, TotalTokens: 22}, usage)
+}
+
+func TestStreamToolCallDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		for _, event := range []string{
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_time"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"get_weather"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("events", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &anthropicStreamProvider{sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("events"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	stream, err := l.Stream(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	defer stream.Close()
+	// Both calls arrive as their blocks stop, before the message ends
+	var calls []ToolCall
+	for len(calls) < 2 {
+		token, err := stream.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, TokenTypeToolCall, token.Type)
+		calls = append(calls, token.Metadata["tool_call"].(ToolCall))
+	}
+	assert.Equal(t, "get_time", calls[0].Function.Name)
+	assert.JSONEq(t, `{}`, string(calls[0].Function.Arguments))
+	assert.Equal(t, "toolu_2", calls[1].ID)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(calls[1].Function.Arguments))
+}
+
+// openRouterStreamProvider decodes streams with the OpenRouter event parser.
+type openRouterStreamProvider struct{ sseProvider }
+
+func (p *openRouterStreamProvider) ParseStreamEvent(eventType string, data []byte) ([]providers.StreamEvent, error) {
+	parser := providers.NewOpenRouterProvider("key", "model", nil).(providers.StreamEventParser)
+	return parser.ParseStreamEvent(eventType, data)
+}
+
+func TestStreamUsageToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":4,\"completion_tokens\":1}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("events", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &openRouterStreamProvider{sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("events"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	stream, err := l.Stream(context.Background(), NewPrompt("hi"))
+	require.NoError(t, err)
+	var tokens []*StreamToken
+	for {
+		token, err := stream.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		tokens = append(tokens, token)
+	}
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "Hi", tokens[0].Text)
+	assert.Nil(t, tokens[0].Usage)
+	assert.Equal(t, TokenTypeUsage, tokens[1].Type)
+	require.NotNil(t, tokens[1].Usage)
+	want := Usage{PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5}
+	assert.Equal(t, want, *tokens[1].Usage)
+	result, ok := PartialResult(stream)
+	require.True(t, ok)
+	assert.Equal(t, want, result.Usage)
+}
+
+func TestStreamCloseReturnsPartialResult(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		fmt.Fprint(w, "data: {\"text\":\"Hello\"}\n\ndata: {\"text\":\", wor\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("sse", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("sse"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	stream, err := l.Stream(ctx, NewPrompt("hi"))
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := stream.Next(ctx)
+		require.NoError(t, err)
+	}
+	require.NoError(t, stream.Close())
+
+	_, err = stream.Next(ctx)
+	assert.ErrorIs(t, err, ErrStreamClosed)
+
+	result, ok := PartialResult(stream)
+	require.True(t, ok)
+	assert.Equal(t, "Hello, wor", result.Text)
+	assert.True(t, result.Truncated)
+}
+
+func TestStreamStopPattern(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		fmt.Fprint(w, "data: {\"text\":\"The answer is 42. END_OF\"}\n\ndata: {\"text\":\"_ANSWER and more\"}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("sse", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &sseProvider{echoProvider{endpoint: server.URL, options: make(map[string]interface{})}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("sse"), config.SetAPIKey("test-key"))
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	stream, err := l.Stream(ctx, NewPrompt("hi"), WithStopPattern(regexp.MustCompile(`END_OF_ANSWER`)))
+	require.NoError(t, err)
+	defer stream.Close()
+	var tokens []string
+	for {
+		token, err := stream.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		tokens = append(tokens, token.Text)
+	}
+	assert.Equal(t, []string{"The answer is 42. END_OF", "_ANSWER"}, tokens, "the match spans tokens and the rest is dropped")
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the request was not cancelled")
+	}
+	result, ok := PartialResult(stream)
+	require.True(t, ok)
+	assert.Equal(t, "The answer is 42. END_OF_ANSWER", result.Text)
+	assert.False(t, result.Truncated)
+}
@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamTo streams the response to prompt into w, flushing after every token
+// when w supports it (for example an http.ResponseWriter), and returns the
+// complete response. Providers without streaming support fall back to a
+// single Generate call whose result is written at once.
+//
+// Example:
+//
+//	text, err := llm.StreamTo(ctx, client, llm.NewPrompt("Tell me a story"), os.Stdout)
+func StreamTo(ctx context.Context, l LLM, prompt *Prompt, w io.Writer, opts ...StreamOption) (string, error) {
+	flush := flusher(w)
+
+	if !l.SupportsStreaming() {
+		response, err := l.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, response); err != nil {
+			return response, err
+		}
+		flush()
+		return response, nil
+	}
+
+	stream, err := l.Stream(ctx, prompt, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var response strings.Builder
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return response.String(), nil
+		}
+		if err != nil {
+			return response.String(), err
+		}
+		if !isTextToken(token) {
+			continue
+		}
+		response.WriteString(token.Text)
+		if _, err := io.WriteString(w, token.Text); err != nil {
+			return response.String(), err
+		}
+		flush()
+	}
+}
+
+// StreamFormat selects the wire format used by NewStreamHandler.
+type StreamFormat string
+
+const (
+	// StreamFormatAuto picks NDJSON when the request accepts
+	// application/x-ndjson and server-sent events otherwise.
+	StreamFormatAuto StreamFormat = ""
+
+	// StreamFormatSSE emits server-sent events: "token" events carrying
+	// {"text", "index"}, then a final "done" or "error" event.
+	StreamFormatSSE StreamFormat = "sse"
+
+	// StreamFormatNDJSON emits one JSON object per line with a "type" of
+	// "token", "done" or "error".
+	StreamFormatNDJSON StreamFormat = "ndjson"
+)
+
+// streamEvent is the payload written for each event by NewStreamHandler.
+type streamEvent struct {
+	Type  string `json:"type"`
+	Text  string `json:"text,omitempty"`
+	Index int    `json:"index"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewStreamHandler returns an http.Handler that builds a prompt from each
+// request with promptFunc and streams the response to the client as
+// server-sent events or NDJSON. Every event is flushed immediately, and the
+// stream stops as soon as the client disconnects. A promptFunc error is
+// reported with 400 Bad Request before streaming starts.
+//
+// Example:
+//
+//	http.Handle("/chat", llm.NewStreamHandler(client, func(r *http.Request) (*llm.Prompt, error) {
+//	    return llm.NewPrompt(r.URL.Query().Get("q")), nil
+//	}, llm.StreamFormatAuto))
+func NewStreamHandler(l LLM, promptFunc func(*http.Request) (*Prompt, error), format StreamFormat) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prompt, err := promptFunc(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f := format
+		if f == StreamFormatAuto {
+			f = StreamFormatSSE
+			if strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+				f = StreamFormatNDJSON
+			}
+		}
+
+		if f == StreamFormatNDJSON {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Connection", "keep-alive")
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		// Ask reverse proxies such as nginx not to buffer the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// ResponseController also reaches writers wrapped by middleware
+		rc := http.NewResponseController(w)
+		ew := &eventWriter{w: w, format: f, flush: func() { _ = rc.Flush() }}
+		// The request context is cancelled when the client disconnects
+		ctx := r.Context()
+		if _, err := StreamTo(ctx, l, prompt, ew); err != nil {
+			if ctx.Err() == nil && ew.err == nil {
+				ew.write(streamEvent{Type: "error", Index: ew.index, Error: err.Error()})
+			}
+			return
+		}
+		ew.write(streamEvent{Type: "done", Index: ew.index})
+	})
+}
+
+// eventWriter turns each Write from StreamTo into one stream event.
+type eventWriter struct {
+	w      io.Writer
+	format StreamFormat
+	flush  func()
+	index  int
+	err    error
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	if err := e.write(streamEvent{Type: "token", Text: string(p), Index: e.index}); err != nil {
+		return 0, err
+	}
+	e.index++
+	return len(p), nil
+}
+
+func (e *eventWriter) write(event streamEvent) error {
+	if e.err != nil {
+		return e.err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if e.format == StreamFormatNDJSON {
+		_, e.err = fmt.Fprintf(e.w, "%s\n", data)
+	} else {
+		_, e.err = fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event.Type, data)
+	}
+	if e.err == nil {
+		e.flush()
+	}
+	return e.err
+}
+
+// flusher returns a function that flushes w if it supports flushing.
+func flusher(w io.Writer) func() {
+	switch f := w.(type) {
+	case http.Flusher:
+		return f.Flush
+	case interface{ Flush() error }:
+		return func() { _ = f.Flush() }
+	}
+	return func() {}
+}
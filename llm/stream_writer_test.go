@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenLLM streams a fixed list of tokens; other LLM methods are not implemented.
+type tokenLLM struct {
+	LLM
+	tokens []string
+}
+
+func (l *tokenLLM) SupportsStreaming() bool { return true }
+
+func (l *tokenLLM) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOption) (TokenStream, error) {
+	return &tokenStream{tokens: l.tokens}, nil
+}
+
+type tokenStream struct {
+	tokens []string
+	index  int
+}
+
+func (s *tokenStream) Next(ctx context.Context) (*StreamToken, error) {
+	if s.index == len(s.tokens) {
+		return nil, io.EOF
+	}
+	s.index++
+	return &StreamToken{Text: s.tokens[s.index-1], Type: "text", Index: s.index - 1}, nil
+}
+
+func (s *tokenStream) Close() error { return nil }
+
+func TestStreamToAndHandler(t *testing.T) {
+	l := &tokenLLM{tokens: []string{"Hel", "lo"}}
+
+	var out strings.Builder
+	text, err := StreamTo(context.Background(), l, NewPrompt("hi"), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, "Hello", out.String())
+
+	handler := NewStreamHandler(l, func(r *http.Request) (*Prompt, error) {
+		return NewPrompt(r.URL.Query().Get("q")), nil
+	}, StreamFormatAuto)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?q=hi", nil))
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "event: token\ndata: {\"type\":\"token\",\"text\":\"Hel\",\"index\":0}\n\n"+
+		"event: token\ndata: {\"type\":\"token\",\"text\":\"lo\",\"index\":1}\n\n"+
+		"event: done\ndata: {\"type\":\"done\",\"index\":2}\n\n", rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/?q=hi", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"type\":\"token\",\"text\":\"Hel\",\"index\":0}\n"+
+		"{\"type\":\"token\",\"text\":\"lo\",\"index\":1}\n"+
+		"{\"type\":\"done\",\"index\":2}\n", rec.Body.String())
+}
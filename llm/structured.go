@@ -0,0 +1,390 @@
+package llm
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResponseFormat selects the format of a structured response.
+type ResponseFormat string
+
+const (
+	// ResponseFormatJSON asks for JSON matching the JSON schema of the
+	// target, derived from its json tags.
+	ResponseFormatJSON ResponseFormat = "json"
+
+	// ResponseFormatXML asks for XML matching an XML schema derived from
+	// the xml tags of the target. Some models follow XML more reliably than
+	// JSON.
+	ResponseFormatXML ResponseFormat = "xml"
+
+	// ResponseFormatYAML asks for YAML matching a skeleton derived from the
+	// yaml tags of the target.
+	ResponseFormatYAML ResponseFormat = "yaml"
+)
+
+// structuredFormat is the structured response requested for a call.
+type structuredFormat struct {
+	format    ResponseFormat
+	target    interface{}
+	defaulted *[]string // Receives the defaulted fields, if set
+}
+
+// WithStructuredResponseFormat asks the model for a response in format
+// matching the structure of target, a pointer to a struct or another value
+// the format's decoder accepts. The response is decoded into target and
+// validated with its validate tags; a response that fails either is
+// rejected and the attempt retried. Generate returns the response document
+// without the surrounding text.
+//
+// Example:
+//
+//	type Recipe struct {
+//	    XMLName     xml.Name `xml:"recipe"`
+//	    Title       string   `xml:"title" validate:"required"`
+//	    Ingredients []string `xml:"ingredients>ingredient" validate:"min=1"`
+//	}
+//
+//	var recipe Recipe
+//	_, err := client.Generate(ctx, llm.NewPrompt("A pancake recipe"),
+//	    llm.WithStructuredResponseFormat(llm.ResponseFormatXML, &recipe))
+func WithStructuredResponseFormat(format ResponseFormat, target interface{}) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.structured = &structuredFormat{format: format, target: target}
+	}
+}
+
+// apply returns a copy of prompt asking for the structured format, and
+// adds the decoding of the response to cfg's post-processors.
+func (s *structuredFormat) apply(prompt *Prompt, cfg *GenerateConfig) (*Prompt, error) {
+	target := reflect.ValueOf(s.target)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "structured response target must be a non-nil pointer", nil)
+	}
+	t := target.Type().Elem()
+
+	var instructions string
+	switch s.format {
+	case ResponseFormatJSON:
+		schema, err := GenerateJSONSchema(s.target)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "failed to derive JSON schema", err)
+		}
+		instructions = "Respond only with JSON that conforms to this JSON schema:\n" + string(schema)
+	case ResponseFormatXML:
+		instructions = "Respond only with an XML document that conforms to this XML schema, without the schema itself:\n" + xmlSchema(t)
+	case ResponseFormatYAML:
+		instructions = "Respond only with a YAML document with this structure, where values show the expected types:\n" + yamlSkeleton(t)
+	default:
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("unsupported response format %q", s.format), nil)
+	}
+
+	structured := *prompt
+	if structured.Output != "" {
+		instructions = structured.Output + "\n\n" + instructions
+	}
+	structured.Output = instructions
+	s.defaulted = cfg.defaulted
+	cfg.PostProcessors = append(cfg.PostProcessors, s.decode)
+	return &structured, nil
+}
+
+// decode parses text into the target and validates it. The target is only
+// changed when text is valid.
+func (s *structuredFormat) decode(text string) (string, error) {
+	t := reflect.TypeOf(s.target).Elem()
+	value := reflect.New(t)
+	document, _ := StripCodeFences()(text)
+
+	var err error
+	var generic interface{} // The response as maps and slices, to find left out fields
+	switch s.format {
+	case ResponseFormatJSON:
+		if document, err = ExtractJSON()(document); err == nil {
+			if err = json.Unmarshal([]byte(document), value.Interface()); err == nil {
+				err = json.Unmarshal([]byte(document), &generic)
+			}
+		}
+	case ResponseFormatXML:
+		root := xmlRootName(t)
+		start := strings.Index(document, "<"+root)
+		end := strings.LastIndex(document, "</"+root+">")
+		if start == -1 {
+			return "", fmt.Errorf("no <%s> element in the response", root)
+		}
+		if end > start {
+			document = document[start : end+len(root)+3]
+		} else {
+			document = document[start:]
+		}
+		err = xml.Unmarshal([]byte(document), value.Interface())
+	case ResponseFormatYAML:
+		if err = yaml.Unmarshal([]byte(document), value.Interface()); err == nil {
+			err = yaml.Unmarshal([]byte(document), &generic)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %s response: %w", strings.ToUpper(string(s.format)), err)
+	}
+	defaulted, err := applyDefaults(value.Interface(), generic, s.format)
+	if err != nil {
+		return "", err
+	}
+	if t.Kind() == reflect.Struct {
+		if err := Validate(value.Interface()); err != nil {
+			return "", fmt.Errorf("response failed validation: %w", err)
+		}
+	}
+	reflect.ValueOf(s.target).Elem().Set(value.Elem())
+	if s.defaulted != nil {
+		*s.defaulted = defaulted
+	}
+	return document, nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	xmlNameType = reflect.TypeOf(xml.Name{})
+)
+
+// xmlRootName returns the name encoding/xml gives the root element of t.
+func xmlRootName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName("XMLName"); ok && field.Type == xmlNameType {
+			if name, _, _ := strings.Cut(field.Tag.Get("xml"), ","); name != "" {
+				// A name may be preceded by its namespace
+				return name[strings.LastIndex(name, " ")+1:]
+			}
+		}
+	}
+	if t.Name() == "" {
+		return "response"
+	}
+	return t.Name()
+}
+
+// xmlSchema describes the XML encoding of t as an XML Schema.
+func xmlSchema(t reflect.Type) string {
+	var b strings.Builder
+	b.WriteString(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + "\n")
+	writeXMLElement(&b, xmlRootName(t), t, "", 1, map[reflect.Type]bool{})
+	b.WriteString("</xs:schema>\n")
+	return b.String()
+}
+
+// writeXMLElement writes the declaration of an element of type t, with its
+// occurrence attributes.
+func writeXMLElement(b *strings.Builder, name string, t reflect.Type, occurs string, depth int, visiting map[reflect.Type]bool) {
+	indent := strings.Repeat("  ", depth)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		writeXMLElement(b, name, t.Elem(), occurs+` maxOccurs="unbounded"`, depth, visiting)
+		return
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		fmt.Fprintf(b, "%s<xs:element name=%q type=%q%s/>\n", indent, name, xsdType(t), occurs)
+		return
+	}
+	if visiting[t] {
+		fmt.Fprintf(b, "%s<xs:element name=%q type=\"xs:anyType\"%s/>\n", indent, name, occurs)
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var elements, attributes []reflect.StructField
+	mixed := ""
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if !field.IsExported() || tag == "-" || field.Type == xmlNameType {
+			continue
+		}
+		_, flags, _ := strings.Cut(tag, ",")
+		switch {
+		case strings.Contains(flags, "attr"):
+			attributes = append(attributes, field)
+		case strings.Contains(flags, "chardata"), strings.Contains(flags, "innerxml"):
+			mixed = ` mixed="true"`
+		case strings.Contains(flags, "comment"):
+		default:
+			elements = append(elements, field)
+		}
+	}
+
+	fmt.Fprintf(b, "%s<xs:element name=%q%s>\n%s  <xs:complexType%s>\n", indent, name, occurs, indent, mixed)
+	if len(elements) > 0 {
+		fmt.Fprintf(b, "%s    <xs:sequence>\n", indent)
+		for _, field := range elements {
+			path, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+			if path == "" {
+				path = field.Name
+			}
+			// "a>b" nests b in a wrapper element a
+			names := strings.Split(path, ">")
+			fieldOccurs := ""
+			if optionalField(field) {
+				fieldOccurs = ` minOccurs="0"`
+			}
+			wrapperDepth := depth + 3
+			for _, wrapper := range names[:len(names)-1] {
+				wrapperIndent := strings.Repeat("  ", wrapperDepth)
+				fmt.Fprintf(b, "%s<xs:element name=%q%s>\n%s  <xs:complexType>\n%s    <xs:sequence>\n", wrapperIndent, wrapper, fieldOccurs, wrapperIndent, wrapperIndent)
+				fieldOccurs = ""
+				wrapperDepth += 3
+			}
+			writeXMLElement(b, names[len(names)-1], field.Type, fieldOccurs, wrapperDepth, visiting)
+			for range names[:len(names)-1] {
+				wrapperDepth -= 3
+				wrapperIndent := strings.Repeat("  ", wrapperDepth)
+				fmt.Fprintf(b, "%s    </xs:sequence>\n%s  </xs:complexType>\n%s</xs:element>\n", wrapperIndent, wrapperIndent, wrapperIndent)
+			}
+		}
+		fmt.Fprintf(b, "%s    </xs:sequence>\n", indent)
+	}
+	for _, field := range attributes {
+		name, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		use := "required"
+		if optionalField(field) {
+			use = "optional"
+		}
+		fmt.Fprintf(b, "%s    <xs:attribute name=%q type=%q use=%q/>\n", indent, name, xsdType(field.Type), use)
+	}
+	fmt.Fprintf(b, "%s  </xs:complexType>\n%s</xs:element>\n", indent, indent)
+}
+
+// optionalField reports whether a field may be left out: it has a default,
+// or it is a pointer or marked omitempty, and not required by its validate
+// tag.
+func optionalField(field reflect.StructField) bool {
+	if _, ok := field.Tag.Lookup("default"); ok {
+		return true
+	}
+	if strings.Contains(field.Tag.Get("validate"), "required") {
+		return false
+	}
+	return field.Type.Kind() == reflect.Ptr || strings.Contains(field.Tag.Get("xml"), "omitempty") ||
+		strings.Contains(field.Tag.Get("yaml"), "omitempty")
+}
+
+// xsdType returns the XML Schema type of a simple type.
+func xsdType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "xs:dateTime"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "xs:boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "xs:integer"
+	case reflect.Float32, reflect.Float64:
+		return "xs:decimal"
+	default:
+		return "xs:string"
+	}
+}
+
+// yamlSkeleton describes the YAML encoding of t as a document whose values
+// are type names.
+func yamlSkeleton(t reflect.Type) string {
+	return strings.Join(yamlLines(t, map[reflect.Type]bool{}), "\n") + "\n"
+}
+
+// yamlLines returns the lines of the skeleton of a value of type t.
+func yamlLines(t reflect.Type, visiting map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return []string{"timestamp"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		lines := indentLines(yamlLines(t.Elem(), visiting), "  ")
+		lines[0] = "- " + strings.TrimPrefix(lines[0], "  ")
+		return lines
+	case t.Kind() == reflect.Map:
+		return yamlEntry("<key>", t.Elem(), "", visiting)
+	case t.Kind() == reflect.Struct:
+		if visiting[t] {
+			return []string{"{}"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		var lines []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			// XMLName fields are only meaningful in XML
+			if !field.IsExported() || name == "-" || field.Type == xmlNameType {
+				continue
+			}
+			if strings.Contains(flags, "inline") {
+				lines = append(lines, yamlLines(field.Type, visiting)...)
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			comment := ""
+			if def, ok := field.Tag.Lookup("default"); ok {
+				comment = "  # optional, default: " + def
+			} else if strings.Contains(field.Tag.Get("validate"), "required") {
+				comment = "  # required"
+			}
+			lines = append(lines, yamlEntry(name, field.Type, comment, visiting)...)
+		}
+		if len(lines) == 0 {
+			return []string{"{}"}
+		}
+		return lines
+	case t.Kind() == reflect.Bool:
+		return []string{"boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return []string{"integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return []string{"number"}
+	default:
+		return []string{"string"}
+	}
+}
+
+// yamlEntry returns the lines of a mapping entry: "key: type" for scalars,
+// or the key followed by the indented lines of its value.
+func yamlEntry(key string, t reflect.Type, comment string, visiting map[reflect.Type]bool) []string {
+	value := yamlLines(t, visiting)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	scalar := t == timeType || (t.Kind() != reflect.Struct && t.Kind() != reflect.Slice &&
+		t.Kind() != reflect.Array && t.Kind() != reflect.Map)
+	if scalar || value[0] == "{}" {
+		return []string{key + ": " + value[0] + comment}
+	}
+	return append([]string{key + ":" + comment}, indentLines(value, "  ")...)
+}
+
+func indentLines(lines []string, indent string) []string {
+	indented := make([]string, len(lines))
+	for i, line := range lines {
+		indented[i] = indent + line
+	}
+	return indented
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+)
+
+func TestStructuredResponseFormat(t *testing.T) {
+	type recipe struct {
+		XMLName     xml.Name `xml:"recipe"`
+		Servings    int      `xml:"servings,attr" yaml:"servings"`
+		Title       string   `xml:"title" yaml:"title" validate:"required"`
+		Ingredients []string `xml:"ingredients>ingredient" yaml:"ingredients" validate:"min=1"`
+	}
+
+	schema := xmlSchema(reflect.TypeOf(recipe{}))
+	assert.Contains(t, schema, `<xs:element name="recipe">`)
+	assert.Contains(t, schema, `<xs:element name="ingredient" type="xs:string" maxOccurs="unbounded"/>`)
+	assert.Contains(t, schema, `<xs:attribute name="servings" type="xs:integer" use="required"/>`)
+	assert.Equal(t, "servings: integer\ntitle: string  # required\ningredients:\n  - string\n", yamlSkeleton(reflect.TypeOf(recipe{})))
+
+	var replies []string
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		prompts = append(prompts, request["prompt"].(string))
+		reply := replies[0]
+		replies = replies[1:]
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": reply})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL, config.SetMaxRetries(1))
+
+	// The first reply fails validation and is retried
+	replies = []string{
+		`<recipe servings="2"><title>Pancakes</title><ingredients></ingredients></recipe>`,
+		"Here you go:\n```xml\n<recipe servings=\"2\"><title>Pancakes</title><ingredients><ingredient>flour</ingredient><ingredient>milk</ingredient></ingredients></recipe>\n```",
+	}
+	var fromXML recipe
+	document, err := l.Generate(context.Background(), NewPrompt("pancakes"), WithStructuredResponseFormat(ResponseFormatXML, &fromXML))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(document, "<recipe"))
+	assert.Equal(t, 2, fromXML.Servings)
+	assert.Equal(t, []string{"flour", "milk"}, fromXML.Ingredients)
+	assert.Contains(t, prompts[0], "xs:schema")
+
+	replies = []string{"```yaml\ntitle: Pancakes\nservings: 4\ningredients:\n  - eggs\n```"}
+	var fromYAML recipe
+	_, err = l.Generate(context.Background(), NewPrompt("pancakes"), WithStructuredResponseFormat(ResponseFormatYAML, &fromYAML))
+	require.NoError(t, err)
+	assert.Equal(t, recipe{Title: "Pancakes", Servings: 4, Ingredients: []string{"eggs"}}, fromYAML)
+
+	_, err = l.Generate(context.Background(), NewPrompt("pancakes"), WithStructuredResponseFormat(ResponseFormatYAML, fromYAML))
+	assert.Error(t, err)
+}
+
+func TestStructuredResponseDefaults(t *testing.T) {
+	type line struct {
+		Item string `json:"item" yaml:"item" validate:"required"`
+		Unit string `json:"unit" yaml:"unit" default:"pcs" validate:"required"`
+	}
+	type invoice struct {
+		Number   string   `json:"number" yaml:"number" validate:"required"`
+		Currency string   `json:"currency" yaml:"currency" default:"USD" validate:"required"`
+		Discount *float64 `json:"discount" yaml:"discount" default:"0"`
+		Lines    []line   `json:"lines" yaml:"lines"`
+	}
+
+	schema, err := GenerateJSONSchema(invoice{})
+	require.NoError(t, err)
+	var parsed struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+		Required   []string                          `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(schema, &parsed))
+	assert.Equal(t, "USD", parsed.Properties["currency"]["default"])
+	assert.Equal(t, 0.0, parsed.Properties["discount"]["default"])
+	assert.Equal(t, []string{"number"}, parsed.Required, "fields with a default are optional")
+	assert.Contains(t, yamlSkeleton(reflect.TypeOf(invoice{})), "currency: string  # optional, default: USD")
+
+	var reply string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": reply})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL)
+
+	reply = `{"number": "INV-1", "currency": null, "lines": [{"item": "bolt", "unit": "box"}, {"item": "nut"}]}`
+	var fromJSON invoice
+	var defaulted []string
+	_, err = l.Generate(context.Background(), NewPrompt("extract"),
+		WithStructuredResponseFormat(ResponseFormatJSON, &fromJSON), WithDefaultedFields(&defaulted))
+	require.NoError(t, err, "defaults are applied before validation")
+	assert.Equal(t, "USD", fromJSON.Currency)
+	require.NotNil(t, fromJSON.Discount)
+	assert.Equal(t, 0.0, *fromJSON.Discount)
+	assert.Equal(t, []line{{Item: "bolt", Unit: "box"}, {Item: "nut", Unit: "pcs"}}, fromJSON.Lines)
+	assert.Equal(t, []string{"currency", "discount", "lines[1].unit"}, defaulted)
+
+	reply = "number: INV-2\ncurrency: EUR\ndiscount: 5\n"
+	var fromYAML invoice
+	_, err = l.Generate(context.Background(), NewPrompt("extract"),
+		WithStructuredResponseFormat(ResponseFormatYAML, &fromYAML), WithDefaultedFields(&defaulted))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", fromYAML.Currency)
+	assert.Empty(t, defaulted)
+
+	var direct invoice
+	defaulted, err = UnmarshalWithDefaults([]byte(`{"number": "INV-3", "discount": 2}`), &direct)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"currency"}, defaulted)
+	assert.Equal(t, 2.0, *direct.Discount)
+}
+
+func TestJSONMode(t *testing.T) {
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}}
+	responseFormat := func(provider, model string, opts ...GenerateOption) (map[string]interface{}, string, error) {
+		l := newTestLLM(t, "", config.SetProvider(provider), config.SetAPIKey("key"), config.SetModel(model))
+		result, err := l.GenerateWithSchema(context.Background(), NewPrompt("Who wrote Dune?"), schema, append(opts, WithDryRun())...)
+		if err != nil {
+			return nil, "", err
+		}
+		var request DryRunRequest
+		require.NoError(t, json.Unmarshal([]byte(result), &request))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(request.Body, &body))
+		assert.NotContains(t, body, "json_mode")
+		format, _ := body["response_format"].(map[string]interface{})
+		return format, string(request.Body), nil
+	}
+
+	format, _, err := responseFormat("openai", "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "json_schema", format["type"])
+	format, body, err := responseFormat("openai", "gpt-4o", WithJSONMode(providers.JSONModeObject))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, format)
+	assert.Contains(t, body, "JSON format", "the schema is described in the prompt")
+
+	format, _, err = responseFormat("openrouter", "openai/gpt-4o")
+	require.NoError(t, err)
+	assert.NotContains(t, format["json_schema"], "strict")
+	format, _, err = responseFormat("openrouter", "openai/gpt-4o", WithJSONMode(providers.JSONModeSchema))
+	require.NoError(t, err)
+	assert.Equal(t, true, format["json_schema"].(map[string]interface{})["strict"])
+
+	var llmErr *LLMError
+	_, _, err = responseFormat("deepseek", "deepseek-chat", WithJSONMode(providers.JSONModeSchema))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestGenerateWithSchemaMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": `{"name": 42}`})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL)
+
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}}
+	_, err := l.GenerateWithSchema(context.Background(), NewPrompt("Who wrote Dune?"), schema)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeResponse, llmErr.Type)
+	var mismatch *SchemaMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, `{"name": 42}`, mismatch.Response)
+	assert.Error(t, mismatch.Err)
+}
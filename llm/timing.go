@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"time"
+)
+
+// Timing holds the latency of a Generate call or a stream, so applications
+// can monitor performance without timing every call themselves.
+type Timing struct {
+	// QueueTime is the time spent waiting before the request of the final
+	// attempt was sent, such as for a pooled API key within its rate limit.
+	QueueTime time.Duration `json:"queue_time"`
+
+	// TimeToFirstToken is the time from the start of a stream to its first
+	// token. It is zero for Generate calls and streams without tokens.
+	TimeToFirstToken time.Duration `json:"time_to_first_token,omitempty"`
+
+	// Duration is the time the call took, including retries and the delays
+	// between them. For a stream, it runs until the stream ended or was
+	// closed, or until now while it is in flight.
+	Duration time.Duration `json:"duration"`
+
+	// Retries is the number of attempts made after the first one. Streams
+	// are not retried.
+	Retries int `json:"retries"`
+}
+
+// streamTiming measures the latency of a stream.
+type streamTiming struct {
+	start      time.Time
+	queue      time.Duration
+	firstToken time.Duration
+	end        time.Time
+}
+
+// firstTokenAt records the time of the first token, if it is the first.
+func (t *streamTiming) firstTokenAt(now time.Time) {
+	if t.firstToken == 0 {
+		t.firstToken = now.Sub(t.start)
+	}
+}
+
+// endAt records the end of the stream, if it has not ended yet.
+func (t *streamTiming) endAt(now time.Time) {
+	if t.end.IsZero() {
+		t.end = now
+	}
+}
+
+// timing returns the Timing of the stream so far, or no Timing when its
+// start was not recorded.
+func (t *streamTiming) timing() Timing {
+	if t.start.IsZero() {
+		return Timing{}
+	}
+	end := t.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return Timing{QueueTime: t.queue, TimeToFirstToken: t.firstToken, Duration: end.Sub(t.start)}
+}
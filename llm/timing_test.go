@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestAttemptTimeoutRetriesWithFreshBudget(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			_, _ = io.ReadAll(r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "ok"})
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, server.URL,
+		config.SetMaxRetries(1),
+		config.SetAttemptTimeout(50*time.Millisecond),
+		config.SetTimeout(5*time.Second),
+	)
+
+	response, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, 2, calls)
+}
+
+func TestOverallTimeoutIsReported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, server.URL,
+		config.SetMaxRetries(3),
+		config.SetAttemptTimeout(time.Second),
+		config.SetTimeout(50*time.Millisecond),
+	)
+
+	_, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrOverallTimeout)
+	assert.NotErrorIs(t, err, ErrAttemptTimeout)
+
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeTimeout, llmErr.Type)
+}
+
+func TestTiming(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/stream" {
+			time.Sleep(20 * time.Millisecond)
+			fmt.Fprint(w, "data: {\"text\":\"Hello\"}\n\n")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": "ok"})
+	}))
+	defer server.Close()
+
+	l := newTestLLM(t, server.URL, config.SetMaxRetries(2))
+	ctx := context.Background()
+	var resp Response
+	_, err := l.Generate(ctx, NewPrompt("hi"), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Timing.Retries)
+	assert.GreaterOrEqual(t, resp.Timing.Duration, time.Millisecond, "the duration includes the retry delay")
+	assert.Zero(t, resp.Timing.TimeToFirstToken)
+
+	registry := providers.NewProviderRegistry()
+	registry.Register("sse", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+		return &sseProvider{echoProvider{endpoint: server.URL + "/stream", options: make(map[string]interface{})}}
+	})
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("sse"), config.SetAPIKey("test-key"))
+	streaming, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+	require.NoError(t, err)
+	stream, err := streaming.Stream(ctx, NewPrompt("hi"))
+	require.NoError(t, err)
+	defer stream.Close()
+	for {
+		if _, err := stream.Next(ctx); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+	result, ok := PartialResult(stream)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, result.Timing.TimeToFirstToken, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, result.Timing.Duration, result.Timing.TimeToFirstToken)
+	time.Sleep(5 * time.Millisecond)
+	later, _ := PartialResult(stream)
+	assert.Equal(t, result.Timing.Duration, later.Timing.Duration, "the duration stops when the stream ends")
+}
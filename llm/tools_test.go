@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestStrictToolSchemas(t *testing.T) {
+	tools := []utils.Tool{
+		{Type: "function", Function: utils.Function{Name: "get_weather", Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"city": map[string]interface{}{"type": "string"},
+				"unit": map[string]interface{}{"type": "string", "enum": []interface{}{"celsius", "fahrenheit"}},
+			},
+			"required": []interface{}{"city"},
+		}}},
+		{Type: "function", Function: utils.Function{Name: "set_labels", Parameters: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		}}},
+	}
+	prompt := NewPrompt("weather?", WithTools(tools))
+	l := &LLMImpl{Options: map[string]interface{}{}}
+	openai := providers.NewOpenAIProvider("sk-test", "gpt-4o", nil)
+
+	requestTools := func(opts ...GenerateOption) []map[string]interface{} {
+		cfg := &GenerateConfig{}
+		for _, opt := range opts {
+			opt(cfg)
+		}
+		body, err := openai.PrepareRequest(prompt.String(), l.requestOptions(prompt, cfg))
+		require.NoError(t, err)
+		var request struct {
+			Tools []struct {
+				Function map[string]interface{} `json:"function"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.Unmarshal(body, &request))
+		functions := make([]map[string]interface{}, len(request.Tools))
+		for i, tool := range request.Tools {
+			functions[i] = tool.Function
+		}
+		return functions
+	}
+
+	functions := requestTools()
+	require.Len(t, functions, 2)
+	assert.Equal(t, true, functions[0]["strict"])
+	parameters, _ := json.Marshal(functions[0]["parameters"])
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string"},
+			"unit": {"type": ["string", "null"], "enum": ["celsius", "fahrenheit", null]}
+		},
+		"required": ["city", "unit"],
+		"additionalProperties": false
+	}`, string(parameters))
+	assert.NotContains(t, functions[1], "strict", "free-form objects cannot be strict")
+	assert.Len(t, tools[0].Function.Parameters["required"], 1, "the caller's schema is not modified")
+
+	functions = requestTools(WithStrictTools(false))
+	assert.NotContains(t, functions[0], "strict")
+	parameters, _ = json.Marshal(functions[0]["parameters"])
+	assert.NotContains(t, string(parameters), "additionalProperties")
+}
+
+func TestToolCallFidelity(t *testing.T) {
+	body := []byte(`{"choices":[{"message":{"content":"","tool_calls":[
+		{"id":"call_1","function":{"name":"charge","arguments":"{\"amount\":9007199254740993,\"note\":\"a<b\"}"}},
+		{"id":"call_2","function":{"name":"notify","arguments":""}}]}}]}`)
+	provider := providers.NewOpenAIProvider("key", "gpt-4o", nil)
+
+	text, err := provider.ParseResponse(body)
+	require.NoError(t, err)
+	assert.Equal(t, `<function_call>{"arguments":{"amount":9007199254740993,"note":"a<b"},"name":"charge"}</function_call>`+"\n"+
+		`<function_call>{"arguments":{},"name":"notify"}</function_call>`, text)
+
+	// The legacy text reads back without losing argument types
+	calls, err := ParseToolCallText(text)
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "charge", calls[0].Function.Name)
+	assert.JSONEq(t, `{"amount":9007199254740993,"note":"a<b"}`, string(calls[0].Function.Arguments))
+	assert.Contains(t, string(calls[0].Function.Arguments), "9007199254740993")
+
+	// HandleFunctionCalls reads response bodies and the legacy text alike
+	handled, err := provider.HandleFunctionCalls(body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"call_1","name":"charge","arguments":{"amount":9007199254740993,"note":"a<b"}},{"id":"call_2","name":"notify","arguments":{}}]`, string(handled))
+	handled, err = provider.HandleFunctionCalls([]byte(text))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"charge","arguments":{"amount":9007199254740993,"note":"a<b"}},{"name":"notify","arguments":{}}]`, string(handled))
+	handled, err = provider.HandleFunctionCalls([]byte(`{"choices":[{"message":{"content":"hi"}}]}`))
+	assert.EqualError(t, err, "no function calls found in response")
+	assert.Nil(t, handled)
+}
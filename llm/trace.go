@@ -0,0 +1,333 @@
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Kinds of trace spans.
+const (
+	SpanGeneration = "generation" // A Generate or GenerateWithSchema call
+	SpanTool       = "tool"       // A tool execution
+	SpanCustom     = "custom"     // A step recorded by the application
+)
+
+// TraceMessage is a message sent to the model.
+type TraceMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TraceSpan is one step of a trace, such as a generation or a tool call.
+// Its fields are set with Update by the code running the step until End is
+// called.
+type TraceSpan struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id,omitempty"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	// Provider and Model identify the model of generation spans.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	// Messages are the input of a generation, and Input that of other spans,
+	// such as the arguments of a tool call.
+	Messages []TraceMessage `json:"messages,omitempty"`
+	Input    string         `json:"input,omitempty"`
+
+	// Output is the generated text or the tool result.
+	Output    string     `json:"output,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Usage     Usage      `json:"usage"`
+
+	// Cost is the cost of a generation in US dollars, when the provider
+	// reports it, such as with WithCostLookup on OpenRouter.
+	Cost float64 `json:"cost,omitempty"`
+
+	// Error is the error message of a failed step.
+	Error string `json:"error,omitempty"`
+
+	// Attributes hold any other details of the step.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	trace *Trace
+	ended bool
+}
+
+// Trace collects the steps of a Generate call or an agent run, with their
+// messages, tool calls, timings and token usage, for debugging multi-step
+// behavior. Export it as JSON with json.Marshal or as OpenTelemetry spans
+// with OTLP. It is safe for concurrent use.
+//
+// Example:
+//
+//	trace := llm.NewTrace("support-agent")
+//	ctx = llm.ContextWithTrace(ctx, trace)
+//	answer, err := client.Generate(ctx, prompt)
+//	// Tool calls are traced with tools.TracingMiddleware
+//	trace.End()
+//	b, _ := json.MarshalIndent(trace, "", "  ")
+type Trace struct {
+	mu     sync.Mutex
+	id     string
+	name   string
+	start  time.Time
+	end    time.Time
+	spans  []*TraceSpan
+	scores []TraceScore
+
+	// auto is set on the traces an LLM creates for a single call, to
+	// export them to the configured observability backend.
+	auto bool
+}
+
+// TraceScore is an evaluation of a trace, such as user feedback or the
+// result of an automated check.
+type TraceScore struct {
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// NewTrace starts a trace.
+func NewTrace(name string) *Trace {
+	return &Trace{id: randomID(16), name: name, start: time.Now()}
+}
+
+// ID returns the trace ID, 32 hexadecimal characters as in OpenTelemetry.
+func (t *Trace) ID() string {
+	return t.id
+}
+
+// End marks the end of the trace. Spans may still be recorded afterwards.
+func (t *Trace) End() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end = time.Now()
+}
+
+// Spans returns the spans recorded so far, in start order.
+func (t *Trace) Spans() []*TraceSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*TraceSpan(nil), t.spans...)
+}
+
+// Usage returns the total token usage of the generation spans.
+func (t *Trace) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var usage Usage
+	for _, span := range t.spans {
+		usage.Add(span.Usage)
+	}
+	return usage
+}
+
+// Score records an evaluation of the trace, exported along with it.
+func (t *Trace) Score(name string, value float64, comment string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scores = append(t.scores, TraceScore{Name: name, Value: value, Comment: comment})
+}
+
+// Scores returns the scores recorded so far.
+func (t *Trace) Scores() []TraceScore {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TraceScore(nil), t.scores...)
+}
+
+// snapshot returns a copy of the trace's spans, for exporters.
+func (t *Trace) snapshot() (spans []TraceSpan, scores []TraceScore, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans = make([]TraceSpan, len(t.spans))
+	for i, span := range t.spans {
+		spans[i] = *span
+	}
+	end = t.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return spans, append([]TraceScore(nil), t.scores...), end
+}
+
+type traceSpanKey struct{}
+
+// StartSpan records a new span, a child of the span in ctx if any, and
+// returns a context carrying it for the steps it contains.
+func (t *Trace) StartSpan(ctx context.Context, kind, name string) (context.Context, *TraceSpan) {
+	span := &TraceSpan{ID: randomID(8), Kind: kind, Name: name, Start: time.Now(), trace: t}
+	if parent, ok := ctx.Value(traceSpanKey{}).(*TraceSpan); ok && parent.trace == t {
+		span.ParentID = parent.ID
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, traceSpanKey{}, span), span
+}
+
+// Update sets fields of the span, such as Input or Attributes, while it
+// runs. Changing them directly races with concurrent exports of the trace.
+func (s *TraceSpan) Update(update func(span *TraceSpan)) {
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	update(s)
+}
+
+// End records the duration of the span and the error, if any. Later calls
+// have no effect.
+func (s *TraceSpan) End(err error) {
+	s.finish(err, nil)
+}
+
+// finish ends the span, first applying update under the trace's lock so the
+// span is not read while it changes.
+func (s *TraceSpan) finish(err error, update func()) {
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	if update != nil {
+		update()
+	}
+	s.Duration = time.Since(s.Start)
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// MarshalJSON encodes the trace with its spans.
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace := struct {
+		ID     string       `json:"id"`
+		Name   string       `json:"name"`
+		Start  time.Time    `json:"start"`
+		End    *time.Time   `json:"end,omitempty"`
+		Spans  []*TraceSpan `json:"spans"`
+		Scores []TraceScore `json:"scores,omitempty"`
+	}{ID: t.id, Name: t.name, Start: t.start, Spans: t.spans, Scores: t.scores}
+	if !t.end.IsZero() {
+		trace.End = &t.end
+	}
+	if trace.Spans == nil {
+		trace.Spans = []*TraceSpan{}
+	}
+	return json.Marshal(trace)
+}
+
+type traceKey struct{}
+
+// ContextWithTrace returns a context that records the Generate calls and
+// traced tool executions made with it into t.
+func ContextWithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFromContext returns the trace of ctx, or nil.
+func TraceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// WithTrace records the Generate call into t, like a context created with
+// ContextWithTrace.
+func WithTrace(t *Trace) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Trace = t
+	}
+}
+
+// startGenerationSpan starts the span of a generation when it is traced or
+// exported to an observability backend, and makes cfg collect the response
+// details for it. It returns nil otherwise.
+func (l *LLMImpl) startGenerationSpan(ctx context.Context, name string, prompt *Prompt, cfg *GenerateConfig) *TraceSpan {
+	t := cfg.Trace
+	if t == nil {
+		t = TraceFromContext(ctx)
+	}
+	if t == nil && l.exporter != nil {
+		t = NewTrace(name)
+		t.auto = true
+	}
+	if t == nil {
+		return nil
+	}
+	_, span := t.StartSpan(ctx, SpanGeneration, name)
+	span.Update(func(span *TraceSpan) {
+		span.Provider = l.Provider.Name()
+		if l.config != nil {
+			span.Model = l.config.Model
+		}
+		if system, _ := l.requestOptions(prompt, cfg)["system_prompt"].(string); system != "" {
+			span.Messages = append(span.Messages, TraceMessage{Role: "system", Content: system})
+		}
+		// The rest of the prompt is sent as one user message
+		user := *prompt
+		user.SystemPrompt, user.SystemCacheType = "", ""
+		span.Messages = append(span.Messages, TraceMessage{Role: "user", Content: user.String()})
+	})
+	if cfg.Response == nil {
+		cfg.Response = &Response{}
+	}
+	return span
+}
+
+// endGeneration records the outcome of a generation. It does nothing on a
+// nil span.
+func (s *TraceSpan) endGeneration(resp *Response, output string, err error) {
+	if s == nil {
+		return
+	}
+	s.finish(err, func() {
+		if err != nil {
+			return
+		}
+		s.Output = output
+		if resp.Provider != "" {
+			s.Provider, s.Model = resp.Provider, resp.Model
+		}
+		s.ToolCalls = resp.ToolCalls
+		s.Usage = resp.Usage
+		if generation, ok := resp.Metadata["openrouter"].(providers.OpenRouterGeneration); ok {
+			s.Cost = generation.TotalCost
+		}
+	})
+}
+
+// endGenerationSpan records the outcome of a generation, and exports the
+// trace when it was created for this call alone. Export failures are
+// logged, not returned.
+func (l *LLMImpl) endGenerationSpan(ctx context.Context, span *TraceSpan, resp *Response, output string, err error) {
+	span.endGeneration(resp, output, err)
+	if span == nil || !span.trace.auto {
+		return
+	}
+	span.trace.End()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), traceExportTimeout)
+	defer cancel()
+	if exportErr := l.exporter.ExportTrace(ctx, span.trace); exportErr != nil {
+		l.logger.Warn("Failed to export trace", "trace", span.trace.ID(), "error", exportErr)
+	}
+}
+
+func randomID(bytes int) string {
+	b := make([]byte, bytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
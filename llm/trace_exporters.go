@@ -0,0 +1,352 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+// traceExportTimeout bounds the export of the trace of a single call.
+const traceExportTimeout = 10 * time.Second
+
+// TraceExporter sends traces to an observability backend. The exporters
+// of config.SetObservability are also available directly, to export traces
+// an application collects itself.
+type TraceExporter interface {
+	ExportTrace(ctx context.Context, t *Trace) error
+}
+
+// newTraceExporter creates the exporter of an observability configuration.
+func newTraceExporter(cfg config.ObservabilityConfig, client *http.Client) (TraceExporter, error) {
+	switch cfg.Backend {
+	case config.ObservabilityLangfuse:
+		if cfg.PublicKey == "" || cfg.SecretKey == "" {
+			return nil, fmt.Errorf("langfuse requires a public and a secret key")
+		}
+		exporter := NewLangfuseExporter(cfg.Host, cfg.PublicKey, cfg.SecretKey)
+		exporter.client = client
+		return exporter, nil
+	case config.ObservabilityLangSmith:
+		if cfg.SecretKey == "" {
+			return nil, fmt.Errorf("langsmith requires an API key")
+		}
+		exporter := NewLangSmithExporter(cfg.Host, cfg.SecretKey, cfg.Project)
+		exporter.client = client
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown observability backend %q", cfg.Backend)
+	}
+}
+
+// LangfuseExporter exports traces, with their generations, tool calls and
+// scores, through the Langfuse ingestion API.
+type LangfuseExporter struct {
+	host      string
+	publicKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewLangfuseExporter creates an exporter for the Langfuse project of the
+// keys. An empty host selects Langfuse Cloud.
+func NewLangfuseExporter(host, publicKey, secretKey string) *LangfuseExporter {
+	if host == "" {
+		host = "https://cloud.langfuse.com"
+	}
+	return &LangfuseExporter{
+		host:      strings.TrimRight(host, "/"),
+		publicKey: publicKey,
+		secretKey: secretKey,
+		client:    http.DefaultClient,
+	}
+}
+
+// ExportTrace implements TraceExporter.
+func (e *LangfuseExporter) ExportTrace(ctx context.Context, t *Trace) error {
+	spans, scores, end := t.snapshot()
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	event := func(kind string, body map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"id": randomID(16), "type": kind, "timestamp": now, "body": body}
+	}
+
+	traceBody := map[string]interface{}{
+		"id":        t.id,
+		"name":      t.name,
+		"timestamp": t.start.UTC().Format(time.RFC3339Nano),
+	}
+	if len(spans) > 0 {
+		traceBody["input"] = spanInput(spans[0])
+		traceBody["output"] = spans[len(spans)-1].Output
+	}
+	batch := []interface{}{event("trace-create", traceBody)}
+
+	for _, span := range spans {
+		body := map[string]interface{}{
+			"id":        span.ID,
+			"traceId":   t.id,
+			"name":      span.Name,
+			"startTime": span.Start.UTC().Format(time.RFC3339Nano),
+			"endTime":   spanEnd(span, end).UTC().Format(time.RFC3339Nano),
+			"input":     spanInput(span),
+			"output":    span.Output,
+		}
+		if span.ParentID != "" {
+			body["parentObservationId"] = span.ParentID
+		}
+		if span.Error != "" {
+			body["level"] = "ERROR"
+			body["statusMessage"] = span.Error
+		}
+		metadata := spanMetadata(span)
+		if len(metadata) > 0 {
+			body["metadata"] = metadata
+		}
+		kind := "span-create"
+		if span.Kind == SpanGeneration {
+			kind = "generation-create"
+			body["model"] = span.Model
+			body["usageDetails"] = map[string]int{
+				"input":  span.Usage.PromptTokens,
+				"output": span.Usage.CompletionTokens,
+				"total":  span.Usage.TotalTokens,
+			}
+			if span.Cost > 0 {
+				body["costDetails"] = map[string]float64{"total": span.Cost}
+			}
+		}
+		batch = append(batch, event(kind, body))
+	}
+
+	for _, score := range scores {
+		body := map[string]interface{}{
+			"id":      randomID(16),
+			"traceId": t.id,
+			"name":    score.Name,
+			"value":   score.Value,
+		}
+		if score.Comment != "" {
+			body["comment"] = score.Comment
+		}
+		batch = append(batch, event("score-create", body))
+	}
+
+	respBody, err := postJSON(ctx, e.client, e.host+"/api/public/ingestion", map[string]interface{}{"batch": batch}, func(req *http.Request) {
+		req.SetBasicAuth(e.publicKey, e.secretKey)
+	})
+	if err != nil {
+		return fmt.Errorf("langfuse: %w", err)
+	}
+	// Events are validated one by one, and failures reported with a 207
+	var result struct {
+		Errors []struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(respBody, &result) == nil && len(result.Errors) > 0 {
+		return fmt.Errorf("langfuse: %d events rejected: %s", len(result.Errors), result.Errors[0].Message)
+	}
+	return nil
+}
+
+// LangSmithExporter exports traces as runs, and their scores as feedback,
+// through the LangSmith API.
+type LangSmithExporter struct {
+	endpoint string
+	apiKey   string
+	project  string
+	client   *http.Client
+}
+
+// NewLangSmithExporter creates an exporter for a LangSmith project. An
+// empty endpoint selects the LangSmith cloud API and an empty project the
+// "default" project.
+func NewLangSmithExporter(endpoint, apiKey, project string) *LangSmithExporter {
+	if endpoint == "" {
+		endpoint = "https://api.smith.langchain.com"
+	}
+	if project == "" {
+		project = "default"
+	}
+	return &LangSmithExporter{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		project:  project,
+		client:   http.DefaultClient,
+	}
+}
+
+// ExportTrace implements TraceExporter.
+func (e *LangSmithExporter) ExportTrace(ctx context.Context, t *Trace) error {
+	spans, scores, end := t.snapshot()
+	traceID := uuidFromHex(t.id)
+	dottedOrder := func(start time.Time, id string) string {
+		start = start.UTC()
+		return start.Format("20060102T150405") + fmt.Sprintf("%06dZ", start.Nanosecond()/1000) + id
+	}
+
+	root := map[string]interface{}{
+		"id":           traceID,
+		"trace_id":     traceID,
+		"dotted_order": dottedOrder(t.start, traceID),
+		"name":         t.name,
+		"run_type":     "chain",
+		"start_time":   t.start.UTC().Format(time.RFC3339Nano),
+		"end_time":     end.UTC().Format(time.RFC3339Nano),
+		"inputs":       map[string]interface{}{},
+		"outputs":      map[string]interface{}{},
+		"session_name": e.project,
+	}
+	if len(spans) > 0 {
+		root["inputs"] = map[string]interface{}{"input": spanInput(spans[0])}
+		root["outputs"] = map[string]interface{}{"output": spans[len(spans)-1].Output}
+	}
+	runs := []interface{}{root}
+
+	// Spans start after their parent, so parents are seen first
+	orders := map[string]string{"": root["dotted_order"].(string)}
+	ids := map[string]string{"": traceID}
+	for _, span := range spans {
+		id := uuidFromHex(span.ID + t.id[16:])
+		ids[span.ID] = id
+		orders[span.ID] = orders[span.ParentID] + "." + dottedOrder(span.Start, id)
+
+		runType := "chain"
+		switch span.Kind {
+		case SpanGeneration:
+			runType = "llm"
+		case SpanTool:
+			runType = "tool"
+		}
+		outputs := map[string]interface{}{"output": span.Output}
+		if len(span.ToolCalls) > 0 {
+			outputs["tool_calls"] = span.ToolCalls
+		}
+		if span.Kind == SpanGeneration {
+			outputs["usage_metadata"] = map[string]int{
+				"input_tokens":  span.Usage.PromptTokens,
+				"output_tokens": span.Usage.CompletionTokens,
+				"total_tokens":  span.Usage.TotalTokens,
+			}
+		}
+		metadata := spanMetadata(span)
+		if span.Kind == SpanGeneration {
+			metadata["ls_provider"] = span.Provider
+			metadata["ls_model_name"] = span.Model
+		}
+		run := map[string]interface{}{
+			"id":            id,
+			"trace_id":      traceID,
+			"parent_run_id": ids[span.ParentID],
+			"dotted_order":  orders[span.ID],
+			"name":          span.Name,
+			"run_type":      runType,
+			"start_time":    span.Start.UTC().Format(time.RFC3339Nano),
+			"end_time":      spanEnd(span, end).UTC().Format(time.RFC3339Nano),
+			"inputs":        map[string]interface{}{"input": spanInput(span)},
+			"outputs":       outputs,
+			"extra":         map[string]interface{}{"metadata": metadata},
+			"session_name":  e.project,
+		}
+		if span.Error != "" {
+			run["error"] = span.Error
+		}
+		runs = append(runs, run)
+	}
+
+	auth := func(req *http.Request) {
+		req.Header.Set("x-api-key", e.apiKey)
+	}
+	if _, err := postJSON(ctx, e.client, e.endpoint+"/runs/batch", map[string]interface{}{"post": runs}, auth); err != nil {
+		return fmt.Errorf("langsmith: %w", err)
+	}
+	for _, score := range scores {
+		feedback := map[string]interface{}{
+			"id":      uuidFromHex(randomID(16)),
+			"run_id":  traceID,
+			"key":     score.Name,
+			"score":   score.Value,
+			"comment": score.Comment,
+		}
+		if _, err := postJSON(ctx, e.client, e.endpoint+"/feedback", feedback, auth); err != nil {
+			return fmt.Errorf("langsmith feedback: %w", err)
+		}
+	}
+	return nil
+}
+
+// spanInput returns the messages of a generation span, or the input of
+// other spans.
+func spanInput(span TraceSpan) interface{} {
+	if len(span.Messages) > 0 {
+		return span.Messages
+	}
+	return span.Input
+}
+
+// spanEnd returns the end of a span, or traceEnd for a span still running.
+func spanEnd(span TraceSpan, traceEnd time.Time) time.Time {
+	if span.Duration == 0 {
+		return traceEnd
+	}
+	return span.Start.Add(span.Duration)
+}
+
+// spanMetadata returns the attributes of a span with its provider, tool
+// calls and cost.
+func spanMetadata(span TraceSpan) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(span.Attributes)+3)
+	for k, v := range span.Attributes {
+		metadata[k] = v
+	}
+	if span.Provider != "" {
+		metadata["provider"] = span.Provider
+	}
+	if len(span.ToolCalls) > 0 {
+		metadata["tool_calls"] = span.ToolCalls
+	}
+	if span.Cost > 0 {
+		metadata["cost"] = span.Cost
+	}
+	return metadata
+}
+
+// uuidFromHex formats 32 hexadecimal characters as a UUID.
+func uuidFromHex(id string) string {
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
+
+// postJSON posts payload to url and returns the response body, failing on
+// non-2xx statuses.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, auth func(*http.Request)) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	auth(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	return respBody, nil
+}
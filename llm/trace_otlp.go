@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLP encodes the trace as an OpenTelemetry ExportTraceServiceRequest in
+// the OTLP/JSON format, with a root span for the trace itself. Generation
+// spans carry the gen_ai semantic convention attributes.
+func (t *Trace) OTLP() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rootID := t.id[:16]
+	end := t.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	spans := []otlpSpan{{
+		TraceID:           t.id,
+		SpanID:            rootID,
+		Name:              t.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(t.start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        []otlpAttribute{},
+	}}
+	for _, span := range t.spans {
+		parent := span.ParentID
+		if parent == "" {
+			parent = rootID
+		}
+		s := otlpSpan{
+			TraceID:           t.id,
+			SpanID:            span.ID,
+			ParentSpanID:      parent,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: unixNano(span.Start),
+			EndTimeUnixNano:   unixNano(span.Start.Add(span.Duration)),
+			Attributes:        span.otlpAttributes(),
+		}
+		if span.Kind == SpanGeneration {
+			s.Kind = otlpSpanKindClient
+		}
+		if span.Error != "" {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		spans = append(spans, s)
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{stringAttribute("service.name", "gollm")},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/teilomillet/gollm"},
+				"spans": spans,
+			}},
+		}},
+	}
+	return json.Marshal(request)
+}
+
+// ExportOTLP sends the trace to an OTLP/HTTP traces endpoint, such as
+// "http://localhost:4318/v1/traces", using the JSON encoding. The headers
+// are added to the request, for authentication.
+func (t *Trace) ExportOTLP(ctx context.Context, endpoint string, headers map[string]string) error {
+	body, err := t.OTLP()
+	if err != nil {
+		return fmt.Errorf("error encoding trace: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error exporting trace: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("error exporting trace: status code %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// OTLP span kinds and status codes.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+	otlpStatusError      = 2
+)
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]interface{}{"stringValue": value}}
+}
+
+// intAttribute encodes an int value, which OTLP/JSON represents as a string.
+func intAttribute(key string, value int) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]interface{}{"intValue": strconv.Itoa(value)}}
+}
+
+// otlpAttributes returns the attributes of the span. The caller holds the
+// trace's lock.
+func (s *TraceSpan) otlpAttributes() []otlpAttribute {
+	attributes := []otlpAttribute{stringAttribute("gollm.span.kind", s.Kind)}
+	if s.Kind == SpanGeneration {
+		attributes = append(attributes,
+			stringAttribute("gen_ai.system", s.Provider),
+			stringAttribute("gen_ai.request.model", s.Model),
+			intAttribute("gen_ai.usage.input_tokens", s.Usage.PromptTokens),
+			intAttribute("gen_ai.usage.output_tokens", s.Usage.CompletionTokens),
+		)
+	}
+	if len(s.Messages) > 0 {
+		messages, _ := json.Marshal(s.Messages)
+		attributes = append(attributes, stringAttribute("input.messages", string(messages)))
+	}
+	if s.Input != "" {
+		attributes = append(attributes, stringAttribute("input.value", s.Input))
+	}
+	if s.Output != "" {
+		attributes = append(attributes, stringAttribute("output.value", s.Output))
+	}
+	if len(s.ToolCalls) > 0 {
+		calls, _ := json.Marshal(s.ToolCalls)
+		attributes = append(attributes, stringAttribute("output.tool_calls", string(calls)))
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for key := range s.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := s.Attributes[key]
+		if text, ok := value.(string); ok {
+			attributes = append(attributes, stringAttribute(key, text))
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		attributes = append(attributes, stringAttribute(key, string(encoded)))
+	}
+	return attributes
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
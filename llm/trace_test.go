@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestTrace(t *testing.T) {
+	server := newEchoServer(t)
+	l := newTestLLM(t, server.URL)
+
+	trace := NewTrace("agent")
+	ctx, step := trace.StartSpan(ContextWithTrace(context.Background(), trace), SpanCustom, "plan")
+	_, err := l.Generate(ctx, NewPrompt("first"))
+	require.NoError(t, err)
+	step.End(nil)
+	_, err = l.Generate(context.Background(), NewPrompt("second"), WithTrace(trace))
+	require.NoError(t, err)
+	trace.End()
+
+	spans := trace.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, SpanGeneration, spans[1].Kind)
+	assert.Equal(t, step.ID, spans[1].ParentID)
+	assert.Equal(t, "echo", spans[1].Provider)
+	require.Len(t, spans[1].Messages, 1)
+	assert.Contains(t, spans[1].Messages[0].Content, "first")
+	assert.Contains(t, spans[1].Output, "first")
+	assert.Empty(t, spans[2].ParentID)
+
+	encoded, err := json.Marshal(trace)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"name":"agent"`)
+
+	var otlp struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Attributes   []struct {
+						Key string `json:"key"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	encoded, err = trace.OTLP()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(encoded, &otlp))
+	otlpSpans := otlp.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, otlpSpans, 4)
+	assert.Equal(t, trace.ID(), otlpSpans[0].TraceID)
+	assert.Equal(t, otlpSpans[0].SpanID, otlpSpans[1].ParentSpanID)
+	assert.Equal(t, step.ID, otlpSpans[2].ParentSpanID)
+	assert.Contains(t, otlpSpans[2].Attributes, struct {
+		Key string `json:"key"`
+	}{Key: "gen_ai.system"})
+}
+
+func TestObservabilityExport(t *testing.T) {
+	server := newEchoServer(t)
+	var mu sync.Mutex
+	received := make(map[string][]map[string]interface{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		body["auth"] = r.Header.Get("Authorization") + r.Header.Get("x-api-key")
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], body)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"successes":[],"errors":[]}`))
+	}))
+	t.Cleanup(backend.Close)
+
+	l := newTestLLM(t, server.URL, config.SetObservability(config.ObservabilityConfig{
+		Backend:   config.ObservabilityLangfuse,
+		Host:      backend.URL,
+		PublicKey: "pk",
+		SecretKey: "sk",
+	}))
+	_, err := l.Generate(context.Background(), NewPrompt("hello"))
+	require.NoError(t, err)
+
+	require.Len(t, received["/api/public/ingestion"], 1)
+	ingestion := received["/api/public/ingestion"][0]
+	assert.Equal(t, "Basic cGs6c2s=", ingestion["auth"])
+	batch := ingestion["batch"].([]interface{})
+	require.Len(t, batch, 2)
+	assert.Equal(t, "trace-create", batch[0].(map[string]interface{})["type"])
+	generation := batch[1].(map[string]interface{})
+	assert.Equal(t, "generation-create", generation["type"])
+	assert.Contains(t, generation["body"].(map[string]interface{})["output"], "hello")
+
+	// Traces of the application are exported explicitly
+	trace := NewTrace("agent")
+	_, err = l.Generate(ContextWithTrace(context.Background(), trace), NewPrompt("again"))
+	require.NoError(t, err)
+	assert.Len(t, received["/api/public/ingestion"], 1)
+	trace.Score("helpful", 1, "")
+	trace.End()
+
+	require.NoError(t, NewLangSmithExporter(backend.URL, "ls-key", "").ExportTrace(context.Background(), trace))
+	require.Len(t, received["/runs/batch"], 1)
+	runs := received["/runs/batch"][0]["post"].([]interface{})
+	require.Len(t, runs, 2)
+	root, run := runs[0].(map[string]interface{}), runs[1].(map[string]interface{})
+	assert.Equal(t, root["id"], run["parent_run_id"])
+	assert.Equal(t, "llm", run["run_type"])
+	assert.True(t, strings.HasPrefix(run["dotted_order"].(string), root["dotted_order"].(string)+"."))
+	require.Len(t, received["/feedback"], 1)
+	assert.Equal(t, "helpful", received["/feedback"][0]["key"])
+	assert.Equal(t, "ls-key", received["/feedback"][0]["auth"])
+
+	_, err = NewLLM(&config.Config{Provider: "openai", Model: "m", APIKeys: map[string]string{"openai": "k"},
+		Observability: &config.ObservabilityConfig{Backend: config.ObservabilityLangfuse}},
+		utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Usage reports the tokens consumed by a single request.
+type Usage struct {
+	// PromptTokens counts all input tokens, including cached ones.
+	PromptTokens int `json:"prompt_tokens"`
+	// CompletionTokens counts the generated tokens.
+	CompletionTokens int `json:"completion_tokens"`
+	// CachedTokens counts the input tokens served from the provider's prompt cache.
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	// CacheWriteTokens counts the input tokens written to the provider's
+	// prompt cache, which Anthropic bills above the regular input price.
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	// ReasoningTokens counts the CompletionTokens a reasoning model spent
+	// thinking, for providers that report them, such as OpenAI's o-series
+	// and DeepSeek-R1.
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	// TotalTokens is PromptTokens plus CompletionTokens.
+	TotalTokens int `json:"total_tokens"`
+	// Cost is the price in US dollars of the request, for providers that
+	// report it, such as AI gateways in their response headers.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CachedTokens += other.CachedTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
+
+// ParseUsage extracts token usage from a provider response body. It
+// understands the OpenAI-compatible, Anthropic, Cohere and Ollama formats and
+// reports false when the body carries no usage information. For JSONL bodies
+// the last line is used.
+func ParseUsage(body []byte) (Usage, bool) {
+	var response struct {
+		Usage *struct {
+			PromptTokens        int `json:"prompt_tokens"`
+			CompletionTokens    int `json:"completion_tokens"`
+			TotalTokens         int `json:"total_tokens"`
+			PromptTokensDetails struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+			CompletionTokensDetails struct {
+				ReasoningTokens int `json:"reasoning_tokens"`
+			} `json:"completion_tokens_details"`
+			// Anthropic
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			// Cohere v2
+			BilledUnits *cohereBilledUnits `json:"billed_units"`
+		} `json:"usage"`
+		// Cohere v1
+		Meta *struct {
+			BilledUnits *cohereBilledUnits `json:"billed_units"`
+		} `json:"meta"`
+		// Ollama
+		PromptEvalCount *int `json:"prompt_eval_count"`
+		EvalCount       *int `json:"eval_count"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		lines := bytes.Split(bytes.TrimSpace(body), []byte("\n"))
+		if err := json.Unmarshal(lines[len(lines)-1], &response); err != nil {
+			return Usage{}, false
+		}
+	}
+
+	var usage Usage
+	switch {
+	case response.Usage != nil && response.Usage.BilledUnits != nil:
+		usage.PromptTokens = response.Usage.BilledUnits.InputTokens
+		usage.CompletionTokens = response.Usage.BilledUnits.OutputTokens
+	case response.Usage != nil && (response.Usage.InputTokens > 0 || response.Usage.OutputTokens > 0):
+		u := response.Usage
+		usage.PromptTokens = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+		usage.CompletionTokens = u.OutputTokens
+		usage.CachedTokens = u.CacheReadInputTokens
+		usage.CacheWriteTokens = u.CacheCreationInputTokens
+	case response.Usage != nil:
+		usage.PromptTokens = response.Usage.PromptTokens
+		usage.CompletionTokens = response.Usage.CompletionTokens
+		usage.CachedTokens = response.Usage.PromptTokensDetails.CachedTokens
+		usage.ReasoningTokens = response.Usage.CompletionTokensDetails.ReasoningTokens
+	case response.Meta != nil && response.Meta.BilledUnits != nil:
+		usage.PromptTokens = response.Meta.BilledUnits.InputTokens
+		usage.CompletionTokens = response.Meta.BilledUnits.OutputTokens
+	case response.PromptEvalCount != nil || response.EvalCount != nil:
+		if response.PromptEvalCount != nil {
+			usage.PromptTokens = *response.PromptEvalCount
+		}
+		if response.EvalCount != nil {
+			usage.CompletionTokens = *response.EvalCount
+		}
+	default:
+		return Usage{}, false
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage, true
+}
+
+type cohereBilledUnits struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+)
+
+func TestParseUsageAndEstimateCost(t *testing.T) {
+	openai, ok := ParseUsage([]byte(`{"usage":{"prompt_tokens":1000,"completion_tokens":200,"total_tokens":1200,"prompt_tokens_details":{"cached_tokens":400}}}`))
+	require.True(t, ok)
+	assert.Equal(t, Usage{PromptTokens: 1000, CompletionTokens: 200, CachedTokens: 400, TotalTokens: 1200}, openai)
+
+	anthropic, ok := ParseUsage([]byte(`{"usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":5}}`))
+	require.True(t, ok)
+	assert.Equal(t, Usage{PromptTokens: 100, CompletionTokens: 5, CachedTokens: 90, TotalTokens: 105}, anthropic)
+
+	ollama, ok := ParseUsage([]byte("{\"response\":\"a\"}\n{\"done\":true,\"prompt_eval_count\":7,\"eval_count\":3}"))
+	require.True(t, ok)
+	assert.Equal(t, 10, ollama.TotalTokens)
+
+	_, ok = ParseUsage([]byte(`{"content":"no usage"}`))
+	assert.False(t, ok)
+
+	// 600 uncached at $0.15/M, 400 cached at $0.075/M and 200 output at $0.60/M
+	cost, ok := EstimateCost("gpt-4o-mini-2024-07-18", openai)
+	require.True(t, ok)
+	assert.InDelta(t, (600*0.15+400*0.075+200*0.60)/1e6, cost, 1e-12)
+
+	_, ok = EstimateCost("unknown-model", openai)
+	assert.False(t, ok)
+
+	written, ok := ParseUsage([]byte(`{"usage":{"input_tokens":10,"cache_creation_input_tokens":1000,"output_tokens":5}}`))
+	require.True(t, ok)
+	assert.Equal(t, 1000, written.CacheWriteTokens)
+	// Reading 90 tokens saves $2.70/M each; writing 1000 costs $0.75/M more each
+	savings, ok := EstimateCacheSavings("claude-3-5-sonnet-latest", Usage{CachedTokens: 90, CacheWriteTokens: 1000})
+	require.True(t, ok)
+	assert.InDelta(t, (90*2.70-1000*0.75)/1e6, savings, 1e-12)
+}
+
+func TestCostLookup(t *testing.T) {
+	var lookups int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			fmt.Fprint(w, `{"id": "gen-123", "choices": [{"message": {"content": "Paris"}}]}`)
+		case "/v1/generation":
+			lookups++
+			assert.Equal(t, "gen-123", r.URL.Query().Get("id"))
+			assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"data": {"id": "gen-123", "provider_name": "Anthropic", "total_cost": 0.0012, "latency": 420, "generation_time": 900}}`)
+		}
+	}))
+	defer server.Close()
+	l := newTestLLM(t, "", config.SetProvider("openrouter"), config.SetAPIKey("test-key"), config.SetModel("anthropic/claude-3.5-sonnet"), func(c *config.Config) {
+		c.Endpoints = map[string]string{"openrouter": server.URL}
+	})
+
+	var resp Response
+	_, err := l.Generate(context.Background(), NewPrompt("Capital of France?"), WithResponse(&resp))
+	require.NoError(t, err)
+	assert.Zero(t, lookups)
+
+	_, err = l.Generate(context.Background(), NewPrompt("Capital of France?"), WithResponse(&resp), WithCostLookup())
+	require.NoError(t, err)
+	gen, ok := resp.Metadata["openrouter"].(providers.OpenRouterGeneration)
+	require.True(t, ok)
+	assert.Equal(t, "Anthropic", gen.ProviderName)
+	assert.Equal(t, 0.0012, gen.TotalCost)
+	assert.Equal(t, 420*time.Millisecond, gen.Latency)
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UsageStats aggregates the usage of a set of requests.
+type UsageStats struct {
+	// Requests counts the successful requests.
+	Requests int `json:"requests"`
+	// Usage is the total token usage of the requests.
+	Usage Usage `json:"usage"`
+	// Cost is the price in US dollars of the requests: the cost the provider
+	// reported in Usage.Cost, or else the estimate from the pricing table.
+	// Requests without either add nothing.
+	Cost float64 `json:"cost"`
+}
+
+func (s *UsageStats) add(usage Usage, cost float64) {
+	s.Requests++
+	s.Usage.Add(usage)
+	s.Cost += cost
+}
+
+// UsageTracker aggregates the token usage and estimated cost of Generate
+// calls, in total and broken down by tag, model and provider. Calls are
+// recorded when made with WithUsageTracker or a context created with
+// ContextWithUsageTracker, and tagged with WithTag. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	tracker := llm.NewUsageTracker()
+//	summary, err := client.Generate(ctx, prompt, llm.WithUsageTracker(tracker), llm.WithTag("feature:summarize"))
+//	log.Printf("summaries cost $%.4f", tracker.ByTag()["feature:summarize"].Cost)
+type UsageTracker struct {
+	mu         sync.Mutex
+	total      UsageStats
+	byTag      map[string]*UsageStats
+	byModel    map[string]*UsageStats
+	byProvider map[string]*UsageStats
+}
+
+// NewUsageTracker creates an empty UsageTracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		byTag:      make(map[string]*UsageStats),
+		byModel:    make(map[string]*UsageStats),
+		byProvider: make(map[string]*UsageStats),
+	}
+}
+
+// Record adds the usage of a request to model of provider, with its tags.
+// Generate calls record themselves; Record accounts for requests made
+// otherwise.
+func (t *UsageTracker) Record(provider, model string, usage Usage, tags ...string) {
+	cost := usage.Cost
+	if cost == 0 {
+		cost, _ = EstimateCost(model, usage)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total.add(usage, cost)
+	addUsage(t.byModel, model, usage, cost)
+	addUsage(t.byProvider, provider, usage, cost)
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		// A tag repeated on a request counts it once
+		if !seen[tag] {
+			seen[tag] = true
+			addUsage(t.byTag, tag, usage, cost)
+		}
+	}
+}
+
+func addUsage(stats map[string]*UsageStats, key string, usage Usage, cost float64) {
+	s, ok := stats[key]
+	if !ok {
+		s = &UsageStats{}
+		stats[key] = s
+	}
+	s.add(usage, cost)
+}
+
+// Total returns the usage of all recorded requests.
+func (t *UsageTracker) Total() UsageStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// ByTag returns the usage of the recorded requests per tag. A request with
+// several tags counts towards each of them, and untagged requests towards
+// none.
+func (t *UsageTracker) ByTag() map[string]UsageStats {
+	return t.breakdown(t.byTag)
+}
+
+// ByModel returns the usage of the recorded requests per model.
+func (t *UsageTracker) ByModel() map[string]UsageStats {
+	return t.breakdown(t.byModel)
+}
+
+// ByProvider returns the usage of the recorded requests per provider.
+func (t *UsageTracker) ByProvider() map[string]UsageStats {
+	return t.breakdown(t.byProvider)
+}
+
+func (t *UsageTracker) breakdown(stats map[string]*UsageStats) map[string]UsageStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make(map[string]UsageStats, len(stats))
+	for key, s := range stats {
+		result[key] = *s
+	}
+	return result
+}
+
+// Reset discards the recorded usage, such as after exporting it for a
+// billing period.
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = UsageStats{}
+	t.byTag = make(map[string]*UsageStats)
+	t.byModel = make(map[string]*UsageStats)
+	t.byProvider = make(map[string]*UsageStats)
+}
+
+// WritePrometheus writes the recorded usage in the Prometheus text
+// exposition format, for a metrics endpoint or a push gateway. Requests,
+// tokens by type and cost are counters labelled by tag, model or provider,
+// under the gollm_ prefix.
+func (t *UsageTracker) WritePrometheus(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	metrics := []struct {
+		name, help string
+		value      func(s *UsageStats) float64
+	}{
+		{"gollm_requests_total", "Successful requests.", func(s *UsageStats) float64 { return float64(s.Requests) }},
+		{"gollm_prompt_tokens_total", "Input tokens, including cached ones.", func(s *UsageStats) float64 { return float64(s.Usage.PromptTokens) }},
+		{"gollm_completion_tokens_total", "Generated tokens.", func(s *UsageStats) float64 { return float64(s.Usage.CompletionTokens) }},
+		{"gollm_cached_tokens_total", "Input tokens served from the prompt cache.", func(s *UsageStats) float64 { return float64(s.Usage.CachedTokens) }},
+		{"gollm_cache_write_tokens_total", "Input tokens written to the prompt cache.", func(s *UsageStats) float64 { return float64(s.Usage.CacheWriteTokens) }},
+		{"gollm_cost_dollars_total", "Estimated cost in US dollars.", func(s *UsageStats) float64 { return s.Cost }},
+	}
+	breakdowns := []struct {
+		label string
+		stats map[string]*UsageStats
+	}{
+		{"tag", t.byTag},
+		{"model", t.byModel},
+		{"provider", t.byProvider},
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name)
+		for _, breakdown := range breakdowns {
+			keys := make([]string, 0, len(breakdown.stats))
+			for key := range breakdown.stats {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(&b, "%s{%s=%q} %g\n", m.name, breakdown.label, key, m.value(breakdown.stats[key]))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type usageTrackerKey struct{}
+
+// ContextWithUsageTracker returns a context that records the Generate calls
+// made with it into t.
+func ContextWithUsageTracker(ctx context.Context, t *UsageTracker) context.Context {
+	return context.WithValue(ctx, usageTrackerKey{}, t)
+}
+
+// UsageTrackerFromContext returns the usage tracker of ctx, or nil.
+func UsageTrackerFromContext(ctx context.Context) *UsageTracker {
+	t, _ := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	return t
+}
+
+// WithUsageTracker records the usage of the Generate call into t, like a
+// context created with ContextWithUsageTracker.
+func WithUsageTracker(t *UsageTracker) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.UsageTracker = t
+	}
+}
+
+// WithTag tags the Generate call, such as "feature:summarize" or
+// "customer:acme", to break its usage down in a UsageTracker. It can be
+// given several times.
+func WithTag(tags ...string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Tags = append(c.Tags, tags...)
+	}
+}
+
+// startUsageTracking returns the tracker recording the call, and makes cfg
+// collect the response details for it. It returns nil when the call is not
+// tracked.
+func startUsageTracking(ctx context.Context, cfg *GenerateConfig) *UsageTracker {
+	t := cfg.UsageTracker
+	if t == nil {
+		t = UsageTrackerFromContext(ctx)
+	}
+	if t != nil && cfg.Response == nil {
+		cfg.Response = &Response{}
+	}
+	return t
+}
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestUsageTracker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"content": "ok", "usage": {"prompt_tokens": 1000, "completion_tokens": 100, "total_tokens": 1100}}`)
+	}))
+	defer server.Close()
+	l := newTestLLM(t, server.URL, config.SetModel("gpt-4o-mini"))
+
+	tracker := NewUsageTracker()
+	ctx := context.Background()
+	_, err := l.Generate(ctx, NewPrompt("Summarize"), WithUsageTracker(tracker), WithTag("feature:summarize", "customer:acme"))
+	require.NoError(t, err)
+	_, err = l.Generate(ContextWithUsageTracker(ctx, tracker), NewPrompt("Translate"), WithTag("feature:translate"))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("Untracked"))
+	require.NoError(t, err)
+
+	total := tracker.Total()
+	assert.Equal(t, 2, total.Requests)
+	assert.Equal(t, Usage{PromptTokens: 2000, CompletionTokens: 200, TotalTokens: 2200}, total.Usage)
+	assert.InDelta(t, 2*(1000*0.15+100*0.6)/1e6, total.Cost, 1e-12)
+
+	byTag := tracker.ByTag()
+	assert.Len(t, byTag, 3)
+	assert.Equal(t, 1, byTag["feature:summarize"].Requests)
+	assert.Equal(t, 1100, byTag["customer:acme"].Usage.TotalTokens)
+	assert.Equal(t, 2, tracker.ByModel()["gpt-4o-mini"].Requests)
+	assert.Equal(t, 2, tracker.ByProvider()["echo"].Requests)
+
+	var metrics strings.Builder
+	require.NoError(t, tracker.WritePrometheus(&metrics))
+	assert.Contains(t, metrics.String(), "# TYPE gollm_requests_total counter\n")
+	assert.Contains(t, metrics.String(), `gollm_completion_tokens_total{tag="feature:translate"} 100`)
+	assert.Contains(t, metrics.String(), `gollm_prompt_tokens_total{provider="echo"} 2000`)
+
+	tracker.Reset()
+	assert.Equal(t, UsageStats{}, tracker.Total())
+	assert.Empty(t, tracker.ByTag())
+}
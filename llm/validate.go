@@ -38,6 +38,11 @@ func validateAPIKey(fl validator.FieldLevel) bool {
 	parent := fl.Parent()
 	provider := parent.FieldByName("Provider").String()
 
+	// Keys supplied by a KeyProvider are fetched when the LLM is created
+	if keyProvider := parent.FieldByName("KeyProvider"); keyProvider.IsValid() && !keyProvider.IsNil() {
+		return true
+	}
+
 	// Check if there's a key for the provider
 	apiKey, exists := apiKeys[provider]
 	if !exists || apiKey == "" {
@@ -135,7 +140,11 @@ func RegisterCustomValidation(tag string, fn validator.Func) error {
 func GenerateJSONSchema(v interface{}) ([]byte, error) {
 	schema := make(map[string]interface{})
 	schema["type"] = "object"
-	properties, required, err := getStructProperties(reflect.TypeOf(v))
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	properties, required, err := getStructProperties(t)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +186,10 @@ func getStructProperties(t reflect.Type) (map[string]interface{}, []string, erro
 		}
 		properties[jsonName] = fieldSchema
 
-		if validateTag := field.Tag.Get("validate"); strings.Contains(validateTag, "required") {
+		// Fields with a default may be left out
+		if def, ok := defaultValue(field); ok {
+			fieldSchema["default"] = def
+		} else if validateTag := field.Tag.Get("validate"); strings.Contains(validateTag, "required") {
 			required = append(required, jsonName)
 		}
 	}
@@ -198,6 +210,10 @@ func getFieldSchema(field reflect.StructField) (map[string]interface{}, error) {
 	schema := make(map[string]interface{})
 
 	switch field.Type.Kind() {
+	case reflect.Ptr:
+		// Optional values share the schema of the value
+		field.Type = field.Type.Elem()
+		return getFieldSchema(field)
 	case reflect.String:
 		schema["type"] = "string"
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ResponseValidator checks the text generated by Generate, returning an
+// error that describes what is wrong with it. The error is sent back to the
+// model as feedback, so it should read as an instruction, such as "the
+// response must cite its sources with markers like [1]". Any
+// func(string) error can be used as a ResponseValidator.
+type ResponseValidator func(text string) error
+
+// WithResponseValidators checks the text generated by the Generate call,
+// after the post-processors, with every validator. When some fail, the
+// request is sent once more with the rejected response and the validators'
+// feedback; if the new response still fails, Generate returns an
+// ErrorTypeResponse error.
+//
+// Example:
+//
+//	answer, err := client.Generate(ctx, prompt, llm.WithResponseValidators(
+//	    llm.RequireCitations(),
+//	    llm.RequireMaxSentences(3),
+//	    llm.ForbidURLs(),
+//	))
+func WithResponseValidators(validators ...ResponseValidator) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Validators = append(c.Validators, validators...)
+	}
+}
+
+// validateResponse runs every validator on text, joining their errors.
+func validateResponse(text string, validators []ResponseValidator) error {
+	var errs []error
+	for _, validate := range validators {
+		if err := validate(text); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withValidation checks result with the validators of cfg and, when it
+// fails, calls generate once more with the prompt amended with feedback.
+func (l *LLMImpl) withValidation(prompt *Prompt, cfg *GenerateConfig, result string, generate func(*Prompt) (string, error)) (string, error) {
+	invalid := validateResponse(result, cfg.Validators)
+	if invalid == nil {
+		return result, nil
+	}
+	l.logger.Warn("Response failed validation, retrying with feedback", "error", invalid)
+
+	retry := *prompt
+	retry.Input = fmt.Sprintf("%s\n\nA previous response to this request was rejected.\n<rejected_response>\n%s\n</rejected_response>\nIt had these problems:\n- %s\nRespond again, fixing all of them.",
+		prompt.Input, result, strings.ReplaceAll(invalid.Error(), "\n", "\n- "))
+	result, err := generate(&retry)
+	if err != nil {
+		return "", err
+	}
+	if invalid := validateResponse(result, cfg.Validators); invalid != nil {
+		return "", NewLLMError(ErrorTypeResponse, "response failed validation", invalid)
+	}
+	return result, nil
+}
+
+// RequirePattern rejects text that does not match re. The description
+// tells the model what is required, such as "a final line starting with
+// 'Answer:'".
+func RequirePattern(re *regexp.Regexp, description string) ResponseValidator {
+	return func(text string) error {
+		if !re.MatchString(text) {
+			return fmt.Errorf("the response must contain %s", description)
+		}
+		return nil
+	}
+}
+
+// ForbidPattern rejects text that matches re. The description tells the
+// model what is forbidden, such as "email addresses".
+func ForbidPattern(re *regexp.Regexp, description string) ResponseValidator {
+	return func(text string) error {
+		if match := re.FindString(text); match != "" {
+			return fmt.Errorf("the response must not contain %s, but contains %q", description, match)
+		}
+		return nil
+	}
+}
+
+var citationMarker = regexp.MustCompile(`\[\^?\d+\]`)
+
+// RequireCitations rejects text without citation markers such as [1] or
+// [^1].
+func RequireCitations() ResponseValidator {
+	return RequirePattern(citationMarker, "citation markers such as [1] referring to its sources")
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"')\]]+`)
+
+// ForbidURLs rejects text containing URLs.
+func ForbidURLs() ResponseValidator {
+	return ForbidPattern(urlPattern, "URLs")
+}
+
+// RequireMaxSentences rejects text of more than n sentences.
+func RequireMaxSentences(n int) ResponseValidator {
+	return func(text string) error {
+		if count := countSentences(text); count > n {
+			return fmt.Errorf("the response must be at most %d sentences long, but has %d", n, count)
+		}
+		return nil
+	}
+}
+
+// RequireMaxWords rejects text of more than n words.
+func RequireMaxWords(n int) ResponseValidator {
+	return func(text string) error {
+		if count := len(strings.Fields(text)); count > n {
+			return fmt.Errorf("the response must be at most %d words long, but has %d", n, count)
+		}
+		return nil
+	}
+}
+
+// countSentences counts the sentences of text, including a final sentence
+// without ending punctuation.
+func countSentences(text string) int {
+	text = strings.TrimSpace(text)
+	ends := sentenceEnd.FindAllStringIndex(text, -1)
+	count := len(ends)
+	if len(ends) == 0 && text != "" || len(ends) > 0 && ends[len(ends)-1][1] < len(text) {
+		count++
+	}
+	return count
+}
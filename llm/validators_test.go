@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseValidators(t *testing.T) {
+	assert.NoError(t, RequireCitations()("Paris is the capital [1]."))
+	assert.Error(t, RequireCitations()("Paris is the capital."))
+	assert.Error(t, ForbidURLs()("See https://example.com for details."))
+	assert.NoError(t, RequireMaxSentences(2)("One. Two!"))
+	assert.Error(t, RequireMaxSentences(2)("One. Two! And three"))
+
+	var replies []string
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		prompts = append(prompts, request["prompt"].(string))
+		reply := replies[0]
+		replies = replies[1:]
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": reply})
+	}))
+	t.Cleanup(server.Close)
+	l := newTestLLM(t, server.URL)
+	validators := WithResponseValidators(RequireCitations(), ForbidURLs())
+
+	// The invalid response is regenerated once with feedback
+	replies = []string{"Paris, see https://example.com", "Paris [1]."}
+	result, err := l.Generate(context.Background(), NewPrompt("capital of France?"), validators)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris [1].", result)
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[1], "Paris, see https://example.com")
+	assert.Contains(t, prompts[1], "must not contain URLs")
+	assert.Contains(t, prompts[1], "citation markers")
+
+	replies = []string{"Paris.", "Still Paris."}
+	_, err = l.Generate(context.Background(), NewPrompt("capital of France?"), validators)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeResponse, llmErr.Type)
+}
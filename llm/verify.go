@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Verify checks that the LLM's provider is reachable, accepts its API key
+// and serves its model, so that a misconfiguration is found at startup
+// instead of on the first real request. Providers with a model endpoint are
+// asked for the model, which generates no tokens; others are sent a
+// one-token generation. The failure is reported with the error taxonomy:
+// ErrorTypeRequest when the provider cannot be reached,
+// ErrorTypeAuthentication when the key is rejected, ErrorTypeInvalidInput
+// when the model does not exist, ErrorTypeRateLimit when the key is
+// throttled, and ErrorTypeAPI for other errors. Verify is not retried.
+//
+// Example:
+//
+//	if err := llm.Verify(ctx, client); err != nil {
+//	    log.Fatalf("LLM misconfigured: %v", err)
+//	}
+func Verify(ctx context.Context, l LLM) error {
+	impl, ok := baseLLM(l)
+	if !ok {
+		return NewLLMError(ErrorTypeUnsupported, "verification requires a gollm client", nil)
+	}
+	provider, release, err := impl.acquireProvider(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var req *http.Request
+	if p, ok := provider.(providers.ModelEndpointProvider); ok {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.ModelEndpoint(), nil)
+		if err == nil {
+			for k, v := range provider.Headers() {
+				req.Header.Set(k, v)
+			}
+		}
+	} else {
+		options := impl.requestOptions(nil, nil)
+		options["max_tokens"] = 1
+		var body []byte
+		if body, err = provider.PrepareRequest("Hi", options); err != nil {
+			return NewLLMError(ErrorTypeRequest, "failed to prepare verification request", err)
+		}
+		req, err = impl.newRequest(ctx, provider, body)
+	}
+	if err != nil {
+		return NewLLMError(ErrorTypeRequest, "failed to create verification request", err)
+	}
+
+	resp, err := impl.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return NewLLMError(ErrorTypeResponse, "failed to read verification response", err)
+	}
+	return impl.verifyStatus(provider, resp.StatusCode, body)
+}
+
+// verifyStatus maps the status code of a verification response to the
+// error taxonomy.
+func (l *LLMImpl) verifyStatus(provider providers.Provider, status int, body []byte) error {
+	model := ""
+	if l.config != nil {
+		model = l.config.Model
+	}
+	switch {
+	case status == http.StatusOK:
+		l.logger.Debug("Provider verified", "provider", provider.Name(), "model", model)
+		return nil
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return NewLLMError(ErrorTypeAuthentication, fmt.Sprintf("API key rejected by %s: status code %d", provider.Name(), status), fmt.Errorf("%s", body))
+	case status == http.StatusNotFound:
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("model %q not found at %s", model, provider.Name()), fmt.Errorf("%s", body))
+	case status == http.StatusTooManyRequests:
+		return NewLLMError(ErrorTypeRateLimit, fmt.Sprintf("rate limit exceeded at %s", provider.Name()), fmt.Errorf("%s", body))
+	default:
+		return NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", status), fmt.Errorf("%s", body))
+	}
+}
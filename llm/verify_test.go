@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/providers"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// modelsProvider is an echoProvider with a model endpoint.
+type modelsProvider struct {
+	echoProvider
+	model string
+}
+
+func (p *modelsProvider) ModelEndpoint() string { return p.endpoint + "/models/" + p.model }
+
+func TestVerify(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer test-key":
+			http.Error(w, `{"error":"invalid key"}`, http.StatusUnauthorized)
+		case r.URL.Path == "/models/unknown":
+			http.Error(w, `{"error":"no such model"}`, http.StatusNotFound)
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]string{"content": "ok"})
+		}
+	}))
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	// Providers without a model endpoint are sent a one-token generation
+	require.NoError(t, Verify(ctx, newTestLLM(t, server.URL)))
+	assert.Equal(t, []string{"POST /"}, paths)
+
+	var llmErr *LLMError
+	err := Verify(ctx, newTestLLM(t, server.URL, config.SetAPIKey("wrong-key")))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeAuthentication, llmErr.Type)
+
+	newModelsLLM := func(model string) LLM {
+		registry := providers.NewProviderRegistry()
+		registry.Register("models", func(apiKey, model string, extraHeaders map[string]string) providers.Provider {
+			return &modelsProvider{echoProvider{endpoint: server.URL, apiKey: apiKey, options: make(map[string]interface{})}, model}
+		})
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg, config.SetProvider("models"), config.SetModel(model), config.SetAPIKey("test-key"))
+		instance, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), registry)
+		require.NoError(t, err)
+		return instance
+	}
+	paths = nil
+	require.NoError(t, Verify(ctx, newModelsLLM("known")))
+	assert.Equal(t, []string{"GET /models/known"}, paths)
+
+	err = Verify(ctx, newModelsLLM("unknown"))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	assert.Contains(t, llmErr.Message, `"unknown"`)
+}
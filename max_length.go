@@ -0,0 +1,32 @@
+// Package gollm provides response length enforcement for Language Learning Models.
+// This file contains re-exports for holding responses to the maximum length of their prompt.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// LengthEnforcement is how Generate holds a response to the MaxLength of its prompt.
+type LengthEnforcement = llm.LengthEnforcement
+
+const (
+	// LengthEnforcementDirective only asks for the length in the prompt.
+	LengthEnforcementDirective = llm.LengthEnforcementDirective
+
+	// LengthEnforcementMaxTokens also caps max_tokens to the length.
+	LengthEnforcementMaxTokens = llm.LengthEnforcementMaxTokens
+
+	// LengthEnforcementTruncate caps max_tokens and cuts the response to the length.
+	LengthEnforcementTruncate = llm.LengthEnforcementTruncate
+
+	// LengthEnforcementShorten caps max_tokens and asks again for a shorter response when it is too long.
+	LengthEnforcementShorten = llm.LengthEnforcementShorten
+)
+
+var (
+	// WithLengthEnforcement sets how the Generate call holds the response to the MaxLength of the prompt.
+	WithLengthEnforcement = llm.WithLengthEnforcement
+
+	// MaxLengthTokens returns the max_tokens set for a response of a number of words.
+	MaxLengthTokens = llm.MaxLengthTokens
+)
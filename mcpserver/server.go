@@ -0,0 +1,375 @@
+// Package mcpserver exposes the tools and prompt templates of a gollm
+// application to Model Context Protocol (MCP) hosts, such as Claude Desktop
+// or IDEs, over stdio or HTTP with server-sent events.
+//
+// Example:
+//
+//	registry := tools.NewToolRegistry()
+//	registry.Register(tools.MustFromFunc(getWeather, "get_weather", "Get the weather in a city"))
+//
+//	server := mcpserver.New("weather", mcpserver.WithTools(registry))
+//	server.AddPrompt(llm.NewPromptTemplate("forecast", "Summarize the forecast", "Summarize the weather in {{.city}}"))
+//	if err := server.ServeStdio(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/tools"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// supportedVersions lists the MCP protocol revisions the server speaks, the
+// latest last.
+var supportedVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP requests with the tools of a ToolRegistry and a set of
+// prompt templates. It is safe for concurrent use.
+type Server struct {
+	name    string
+	version string
+	tools   *tools.ToolRegistry
+	logger  utils.Logger
+
+	mu      sync.RWMutex
+	prompts map[string]*llm.PromptTemplate
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithVersion sets the server version reported to hosts.
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// WithTools exposes the tools of a registry. Calls run through the
+// registry, with its validation, timeouts and middleware.
+func WithTools(registry *tools.ToolRegistry) Option {
+	return func(s *Server) {
+		s.tools = registry
+	}
+}
+
+// WithLogger sets the logger for protocol errors. Logs must not go to
+// stdout when serving over stdio.
+func WithLogger(logger utils.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// New creates an MCP server with the given name.
+func New(name string, opts ...Option) *Server {
+	s := &Server{
+		name:    name,
+		version: "0.1.0",
+		logger:  utils.NewLogger(utils.LogLevelOff),
+		prompts: make(map[string]*llm.PromptTemplate),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// AddPrompt exposes a prompt template. Its template variables become the
+// arguments of the MCP prompt.
+func (s *Server) AddPrompt(templates ...*llm.PromptTemplate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range templates {
+		s.prompts[t.Name] = t
+	}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Handle processes one JSON-RPC message and returns the encoded response,
+// or nil for notifications.
+func (s *Server) Handle(ctx context.Context, message []byte) []byte {
+	var req request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return s.encode(response{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}})
+	}
+	if req.Method == "" {
+		// Responses to server requests are not used
+		return nil
+	}
+
+	result, err := s.dispatch(ctx, req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := response{ID: req.ID, Result: result}
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = &rpcError{codeInvalidRequest, err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+		s.logger.Debug("MCP request failed", "method", req.Method, "error", err)
+	}
+	return s.encode(resp)
+}
+
+func (s *Server) encode(resp response) []byte {
+	resp.JSONRPC = "2.0"
+	b, err := json.Marshal(resp)
+	if err != nil {
+		s.logger.Error("Failed to encode MCP response", "error", err)
+		b, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{codeInvalidRequest, "failed to encode result"}})
+	}
+	return b
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	case "prompts/list":
+		return s.listPrompts(), nil
+	case "prompts/get":
+		return s.getPrompt(req.Params)
+	default:
+		if len(req.ID) == 0 {
+			// Notifications such as notifications/initialized need no handling
+			return nil, nil
+		}
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid initialize params"}
+		}
+	}
+	version := supportedVersions[len(supportedVersions)-1]
+	for _, v := range supportedVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+
+	capabilities := map[string]interface{}{}
+	if s.tools != nil {
+		capabilities["tools"] = map[string]interface{}{}
+	}
+	capabilities["prompts"] = map[string]interface{}{}
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    capabilities,
+		"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+	}, nil
+}
+
+func (s *Server) listTools() interface{} {
+	list := []map[string]interface{}{}
+	if s.tools != nil {
+		for _, def := range s.tools.Definitions() {
+			list = append(list, map[string]interface{}{
+				"name":        def.Function.Name,
+				"description": def.Function.Description,
+				"inputSchema": def.Function.Parameters,
+			})
+		}
+	}
+	return map[string]interface{}{"tools": list}
+}
+
+// callTool runs a tool. Tool failures are reported in the result, so the
+// model can see them, rather than as protocol errors.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.Name == "" {
+		return nil, &rpcError{codeInvalidParams, "invalid tools/call params"}
+	}
+	if s.tools == nil {
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + p.Name}
+	}
+	if _, ok := s.tools.Get(p.Name); !ok {
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + p.Name}
+	}
+
+	var call llm.ToolCall
+	call.Type = "function"
+	call.Function.Name = p.Name
+	call.Function.Arguments = p.Arguments
+	output, err := s.tools.Execute(ctx, call)
+	isError := err != nil
+	if isError {
+		output = err.Error()
+	}
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": output}},
+		"isError": isError,
+	}, nil
+}
+
+func (s *Server) listPrompts() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.prompts))
+	for name := range s.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []map[string]interface{}{}
+	for _, name := range names {
+		t := s.prompts[name]
+		arguments := []map[string]interface{}{}
+		for _, arg := range templateArguments(t.Template) {
+			arguments = append(arguments, map[string]interface{}{"name": arg, "required": true})
+		}
+		list = append(list, map[string]interface{}{
+			"name":        t.Name,
+			"description": t.Description,
+			"arguments":   arguments,
+		})
+	}
+	return map[string]interface{}{"prompts": list}
+}
+
+func (s *Server) getPrompt(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.Name == "" {
+		return nil, &rpcError{codeInvalidParams, "invalid prompts/get params"}
+	}
+	s.mu.RLock()
+	t, ok := s.prompts[p.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, &rpcError{codeInvalidParams, "unknown prompt: " + p.Name}
+	}
+
+	data := make(map[string]interface{}, len(p.Arguments))
+	for _, arg := range templateArguments(t.Template) {
+		value, ok := p.Arguments[arg]
+		if !ok {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("missing argument %q for prompt %s", arg, p.Name)}
+		}
+		data[arg] = value
+	}
+	prompt, err := t.Execute(data)
+	if err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+
+	// MCP prompts have no system role; the rendered prompt includes the
+	// system prompt as text
+	return map[string]interface{}{
+		"description": t.Description,
+		"messages": []map[string]interface{}{{
+			"role":    "user",
+			"content": map[string]string{"type": "text", "text": prompt.String()},
+		}},
+	}, nil
+}
+
+// templateArguments returns the top-level fields a template references,
+// such as "city" for {{.city}}, in order of first use.
+func templateArguments(text string) []string {
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil || tmpl.Tree == nil {
+		return nil
+	}
+	var arguments []string
+	seen := make(map[string]bool)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				arguments = append(arguments, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		}
+	}
+	walk(tmpl.Tree.Root)
+	return arguments
+}
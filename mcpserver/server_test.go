@@ -0,0 +1,74 @@
+package mcpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/tools"
+)
+
+type weatherParams struct {
+	City string `json:"city"`
+}
+
+func TestServer(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	require.NoError(t, registry.Register(tools.MustFromFunc(func(p weatherParams) (string, error) {
+		return "Sunny in " + p.City, nil
+	}, "get_weather", "Get the weather in a city")))
+	server := New("weather", WithTools(registry))
+	server.AddPrompt(llm.NewPromptTemplate("forecast", "Summarize the forecast", "Summarize the weather in {{.city}} for {{.day}}"))
+
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Serve(ctx, in, out)
+
+	responses := bufio.NewScanner(outReader)
+	call := func(message string) map[string]interface{} {
+		_, err := io.WriteString(inWriter, message+"\n")
+		require.NoError(t, err)
+		require.True(t, responses.Scan())
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(responses.Bytes(), &resp))
+		return resp
+	}
+
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	assert.Equal(t, "2024-11-05", resp["result"].(map[string]interface{})["protocolVersion"])
+	_, err := io.WriteString(inWriter, `{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n")
+	require.NoError(t, err)
+
+	resp = call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	listed := resp["result"].(map[string]interface{})["tools"].([]interface{})
+	require.Len(t, listed, 1)
+	assert.Equal(t, "get_weather", listed[0].(map[string]interface{})["name"])
+
+	resp = call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_weather","arguments":{"city":"Paris"}}}`)
+	result, _ := json.Marshal(resp["result"])
+	assert.JSONEq(t, `{"content":[{"type":"text","text":"Sunny in Paris"}],"isError":false}`, string(result))
+
+	resp = call(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_weather","arguments":{}}}`)
+	assert.Equal(t, true, resp["result"].(map[string]interface{})["isError"], "tool failures are reported to the model")
+
+	resp = call(`{"jsonrpc":"2.0","id":5,"method":"prompts/list"}`)
+	result, _ = json.Marshal(resp["result"])
+	assert.JSONEq(t, `{"prompts":[{"name":"forecast","description":"Summarize the forecast",
+		"arguments":[{"name":"city","required":true},{"name":"day","required":true}]}]}`, string(result))
+
+	resp = call(`{"jsonrpc":"2.0","id":6,"method":"prompts/get","params":{"name":"forecast","arguments":{"city":"Paris","day":"Monday"}}}`)
+	messages := resp["result"].(map[string]interface{})["messages"].([]interface{})
+	text := messages[0].(map[string]interface{})["content"].(map[string]interface{})["text"].(string)
+	assert.True(t, strings.Contains(text, "Summarize the weather in Paris for Monday"), text)
+
+	resp = call(`{"jsonrpc":"2.0","id":7,"method":"resources/list"}`)
+	assert.Equal(t, float64(codeMethodNotFound), resp["error"].(map[string]interface{})["code"])
+}
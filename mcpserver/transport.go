@@ -0,0 +1,176 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// ServeStdio serves MCP over the process's standard input and output, as
+// hosts do when they launch the server as a subprocess. It returns when
+// stdin is closed or ctx is done.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes the
+// responses to w. Requests are handled concurrently; responses are written
+// as they complete.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case lines <- append([]byte(nil), line...):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := s.Handle(ctx, line)
+				if resp == nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if _, err := w.Write(append(resp, '\n')); err != nil {
+					s.logger.Error("Failed to write MCP response", "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// SSEHandler returns an http.Handler serving MCP over HTTP with server-sent
+// events. A GET request opens the event stream of a session, whose first
+// "endpoint" event tells the host where to POST its messages; responses are
+// sent on the stream as "message" events.
+//
+// Example:
+//
+//	http.Handle("/mcp", server.SSEHandler())
+func (s *Server) SSEHandler() http.Handler {
+	return &sseHandler{server: s, sessions: make(map[string]chan []byte)}
+}
+
+type sseHandler struct {
+	server   *Server
+	mu       sync.Mutex
+	sessions map[string]chan []byte
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.stream(w, r)
+	case http.MethodPost:
+		h.message(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// stream holds a session's event stream open until the host disconnects.
+func (h *sseHandler) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	events := make(chan []byte, 16)
+	h.mu.Lock()
+	h.sessions[id] = events
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: endpoint\ndata: %s?sessionId=%s\n\n", r.URL.Path, id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+			flusher.Flush()
+		}
+	}
+}
+
+// message handles a JSON-RPC message posted to a session.
+func (h *sseHandler) message(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	events, ok := h.sessions[r.URL.Query().Get("sessionId")]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 16*1024*1024))
+	if err != nil {
+		http.Error(w, "failed to read message", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if resp := h.server.Handle(r.Context(), body); resp != nil {
+		select {
+		case events <- resp:
+		case <-r.Context().Done():
+		}
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
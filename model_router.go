@@ -0,0 +1,66 @@
+// Package gollm provides model routing for Language Learning Models.
+// This file contains re-exports for picking the model of each request by task, cost policy or difficulty.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// ModelRouter picks the model of each request from a RoutingPolicy.
+	ModelRouter = llm.ModelRouter
+
+	// RoutingPolicy lists the candidate models with the latency SLO and cost ceiling of requests.
+	RoutingPolicy = llm.RoutingPolicy
+
+	// RoutedModel is a candidate model of a RoutingPolicy.
+	RoutedModel = llm.RoutedModel
+
+	// RoutingRequest describes a request to route.
+	RoutingRequest = llm.RoutingRequest
+
+	// RoutingDecision records the model a ModelRouter picked and why.
+	RoutingDecision = llm.RoutingDecision
+
+	// TaskComplexity is how demanding a request is.
+	TaskComplexity = llm.TaskComplexity
+
+	// DifficultyRouter routes requests between a cheap and an expensive model by the difficulty of their prompt.
+	DifficultyRouter = llm.DifficultyRouter
+
+	// DifficultyClassifier scores how hard a prompt is, from 0 to 1.
+	DifficultyClassifier = llm.DifficultyClassifier
+
+	// LabeledPrompt is a prompt labelled with whether it needs the expensive model.
+	LabeledPrompt = llm.LabeledPrompt
+
+	// RouterReport is the accuracy and cost of a DifficultyRouter at a threshold.
+	RouterReport = llm.RouterReport
+)
+
+// Task complexities for WithTaskComplexity
+const (
+	TaskSimple   = llm.TaskSimple   // Classification, extraction or short rewrites
+	TaskModerate = llm.TaskModerate // Summaries or drafting
+	TaskComplex  = llm.TaskComplex  // Multi-step reasoning or code generation
+)
+
+var (
+	// NewModelRouter creates a ModelRouter for a policy.
+	NewModelRouter = llm.NewModelRouter
+
+	// WithModelRouter picks the model of the Generate call with a ModelRouter.
+	WithModelRouter = llm.WithModelRouter
+
+	// WithTaskComplexity declares the complexity of the task for the ModelRouter of the Generate call.
+	WithTaskComplexity = llm.WithTaskComplexity
+
+	// WithDifficultyRouter sends the Generate call to the cheap or expensive model of a DifficultyRouter.
+	WithDifficultyRouter = llm.WithDifficultyRouter
+
+	// ModelDifficultyClassifier asks a small model to rate the difficulty of prompts.
+	ModelDifficultyClassifier = llm.ModelDifficultyClassifier
+
+	// EmbeddingDifficultyClassifier scores prompts by their nearest labelled examples.
+	EmbeddingDifficultyClassifier = llm.EmbeddingDifficultyClassifier
+)
@@ -0,0 +1,184 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and question-answering capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/teilomillet/gollm"
+)
+
+// ContextChunk is a piece of context an answer may cite, such as a
+// retrieved document passage.
+type ContextChunk struct {
+	ID   string `json:"id"`   // Identifier the answer cites, such as "1" or "doc-3"
+	Text string `json:"text"` // Content of the chunk
+}
+
+// CitedSentence is a sentence of an answer and the chunks it cites.
+type CitedSentence struct {
+	Text     string   `json:"text"`      // Sentence, without its citation markers
+	ChunkIDs []string `json:"chunk_ids"` // IDs of the provided chunks it cites
+}
+
+// CitedAnswer is an answer parsed for its citations of context chunks.
+type CitedAnswer struct {
+	// Raw is the answer as generated, with its citation markers.
+	Raw string `json:"raw"`
+	// Answer is the answer without its citation markers.
+	Answer string `json:"answer"`
+	// Sentences holds every sentence of the answer with the chunks it cites.
+	Sentences []CitedSentence `json:"sentences"`
+	// Cited lists the IDs of the cited chunks, in order of first citation.
+	Cited []string `json:"cited"`
+	// Unsupported lists the sentences that cite no provided chunk, which
+	// are claims the context may not support.
+	Unsupported []string `json:"unsupported,omitempty"`
+	// InvalidCitations lists the cited IDs that match no provided chunk.
+	InvalidCitations []string `json:"invalid_citations,omitempty"`
+}
+
+// Supported reports whether every sentence of the answer cites a chunk.
+func (a *CitedAnswer) Supported() bool {
+	return len(a.Unsupported) == 0
+}
+
+// WithCitations provides chunks as the context of the question, each
+// labelled with its ID, and requires the answer to cite the IDs of the
+// chunks supporting each sentence in square brackets, such as "[2]" or
+// "[2, 5]". Parse the answer with ParseCitations, or use
+// QuestionAnswerWithCitations to do both.
+func WithCitations(chunks ...ContextChunk) gollm.PromptOption {
+	return func(p *gollm.Prompt) {
+		var b strings.Builder
+		for _, chunk := range chunks {
+			fmt.Fprintf(&b, "[%s] %s\n\n", chunk.ID, strings.TrimSpace(chunk.Text))
+		}
+		gollm.WithContext(strings.TrimSpace(b.String()))(p)
+		gollm.WithDirectives(
+			"Answer only from the context, whose passages are labelled with an ID in square brackets",
+			"End every sentence with the IDs of the passages supporting it in square brackets, such as [1] or [1, 3]",
+			"If the context does not contain the answer, say so instead of answering from other knowledge",
+		)(p)
+	}
+}
+
+// QuestionAnswerWithCitations answers question from chunks, requiring
+// citations with WithCitations, and parses them out of the answer with
+// ParseCitations. Sentences without a citation are flagged in the
+// Unsupported field of the result rather than failing the call.
+//
+// Example:
+//
+//	answer, err := presets.QuestionAnswerWithCitations(ctx, llm,
+//	    "When was the Eiffel Tower built?",
+//	    []presets.ContextChunk{
+//	        {ID: "1", Text: "The Eiffel Tower was built from 1887 to 1889."},
+//	        {ID: "2", Text: "It is 330 metres tall."},
+//	    },
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if !answer.Supported() {
+//	    log.Printf("unsupported claims: %q", answer.Unsupported)
+//	}
+func QuestionAnswerWithCitations(ctx context.Context, l gollm.LLM, question string, chunks []ContextChunk, opts ...gollm.PromptOption) (*CitedAnswer, error) {
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no context chunks to cite")
+	}
+	response, err := QuestionAnswer(ctx, l, question, append(opts, WithCitations(chunks...))...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCitations(response, chunks), nil
+}
+
+var (
+	// citationPattern matches a citation marker, such as [1] or [doc-2, doc-5].
+	citationPattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+	// spacesPattern matches the runs of spaces left by removed markers.
+	spacesPattern = regexp.MustCompile(`[ \t]{2,}`)
+	// lineEndSpacesPattern matches the spaces left at the end of a line by
+	// removed markers.
+	lineEndSpacesPattern = regexp.MustCompile(`[ \t]+\n`)
+	// sentenceEndPattern matches the end of a sentence with the citation
+	// markers that follow its final punctuation.
+	sentenceEndPattern = regexp.MustCompile(`[.!?]+(\s*\[[^\[\]]+\])*(\s+|$)`)
+)
+
+// ParseCitations splits answer into sentences and parses the chunk IDs each
+// sentence cites, flagging the sentences that cite no chunk of chunks.
+func ParseCitations(answer string, chunks []ContextChunk) *CitedAnswer {
+	known := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		known[chunk.ID] = true
+	}
+	result := &CitedAnswer{Raw: answer, Answer: stripCitations(answer)}
+	cited := make(map[string]bool)
+	invalid := make(map[string]bool)
+
+	for _, sentence := range splitSentences(answer) {
+		cs := CitedSentence{Text: stripCitations(sentence)}
+		if !strings.ContainsFunc(cs.Text, unicode.IsLetter) {
+			continue
+		}
+		for _, match := range citationPattern.FindAllStringSubmatch(sentence, -1) {
+			for _, id := range strings.Split(match[1], ",") {
+				id = strings.TrimSpace(id)
+				switch {
+				case id == "":
+				case !known[id]:
+					if !invalid[id] {
+						invalid[id] = true
+						result.InvalidCitations = append(result.InvalidCitations, id)
+					}
+				default:
+					cs.ChunkIDs = append(cs.ChunkIDs, id)
+					if !cited[id] {
+						cited[id] = true
+						result.Cited = append(result.Cited, id)
+					}
+				}
+			}
+		}
+		if len(cs.ChunkIDs) == 0 {
+			result.Unsupported = append(result.Unsupported, cs.Text)
+		}
+		result.Sentences = append(result.Sentences, cs)
+	}
+	return result
+}
+
+// splitSentences splits text into sentences, keeping each sentence's
+// citation markers with it whether they come before or after its final
+// punctuation. Lines, such as list items, are sentences of their own.
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		start := 0
+		for _, loc := range sentenceEndPattern.FindAllStringIndex(line, -1) {
+			sentences = append(sentences, strings.TrimSpace(line[start:loc[1]]))
+			start = loc[1]
+		}
+		if rest := strings.TrimSpace(line[start:]); rest != "" {
+			sentences = append(sentences, rest)
+		}
+	}
+	return sentences
+}
+
+// stripCitations removes the citation markers of text and the spaces left
+// before punctuation and line ends.
+func stripCitations(text string) string {
+	text = spacesPattern.ReplaceAllString(citationPattern.ReplaceAllString(text, ""), " ")
+	text = lineEndSpacesPattern.ReplaceAllString(text, "\n")
+	for _, p := range []string{".", ",", "!", "?", ";", ":"} {
+		text = strings.ReplaceAll(text, " "+p, p)
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,140 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCitations(t *testing.T) {
+	chunks := []ContextChunk{
+		{ID: "1", Text: "The Eiffel Tower was built from 1887 to 1889."},
+		{ID: "2", Text: "It is 330 metres tall."},
+		{ID: "doc-3", Text: "It is in Paris."},
+	}
+	tests := []struct {
+		name   string
+		answer string
+		want   *CitedAnswer
+	}{
+		{
+			name:   "citation after the final punctuation",
+			answer: "The tower was built from 1887 to 1889. [1] It is 330 metres tall. [2]",
+			want: &CitedAnswer{
+				Answer: "The tower was built from 1887 to 1889. It is 330 metres tall.",
+				Sentences: []CitedSentence{
+					{Text: "The tower was built from 1887 to 1889.", ChunkIDs: []string{"1"}},
+					{Text: "It is 330 metres tall.", ChunkIDs: []string{"2"}},
+				},
+				Cited: []string{"1", "2"},
+			},
+		},
+		{
+			name:   "citation before the final punctuation",
+			answer: "The tower was built from 1887 to 1889 [1]. It stands in Paris [doc-3]!",
+			want: &CitedAnswer{
+				Answer: "The tower was built from 1887 to 1889. It stands in Paris!",
+				Sentences: []CitedSentence{
+					{Text: "The tower was built from 1887 to 1889.", ChunkIDs: []string{"1"}},
+					{Text: "It stands in Paris!", ChunkIDs: []string{"doc-3"}},
+				},
+				Cited: []string{"1", "doc-3"},
+			},
+		},
+		{
+			name:   "several IDs in one marker and repeated citations",
+			answer: "It is a 330 metre tower in Paris [2, doc-3]. It is tall [2].",
+			want: &CitedAnswer{
+				Answer: "It is a 330 metre tower in Paris. It is tall.",
+				Sentences: []CitedSentence{
+					{Text: "It is a 330 metre tower in Paris.", ChunkIDs: []string{"2", "doc-3"}},
+					{Text: "It is tall.", ChunkIDs: []string{"2"}},
+				},
+				Cited: []string{"2", "doc-3"},
+			},
+		},
+		{
+			name:   "unsupported sentence",
+			answer: "It was built for the 1889 World's Fair. It is 330 metres tall [2].",
+			want: &CitedAnswer{
+				Answer: "It was built for the 1889 World's Fair. It is 330 metres tall.",
+				Sentences: []CitedSentence{
+					{Text: "It was built for the 1889 World's Fair."},
+					{Text: "It is 330 metres tall.", ChunkIDs: []string{"2"}},
+				},
+				Cited:       []string{"2"},
+				Unsupported: []string{"It was built for the 1889 World's Fair."},
+			},
+		},
+		{
+			name:   "invalid citation",
+			answer: "It is 330 metres tall [2, 7]. It is made of iron [9].",
+			want: &CitedAnswer{
+				Answer: "It is 330 metres tall. It is made of iron.",
+				Sentences: []CitedSentence{
+					{Text: "It is 330 metres tall.", ChunkIDs: []string{"2"}},
+					{Text: "It is made of iron."},
+				},
+				Cited:            []string{"2"},
+				Unsupported:      []string{"It is made of iron."},
+				InvalidCitations: []string{"7", "9"},
+			},
+		},
+		{
+			name:   "list items",
+			answer: "Facts:\n- Built in 1889 [1]\n- 330 metres tall [2]",
+			want: &CitedAnswer{
+				Answer: "Facts:\n- Built in 1889\n- 330 metres tall",
+				Sentences: []CitedSentence{
+					{Text: "Facts:"},
+					{Text: "- Built in 1889", ChunkIDs: []string{"1"}},
+					{Text: "- 330 metres tall", ChunkIDs: []string{"2"}},
+				},
+				Cited:       []string{"1", "2"},
+				Unsupported: []string{"Facts:"},
+			},
+		},
+		{
+			name:   "markers without text are ignored",
+			answer: "It is in Paris. [doc-3]\n[1]",
+			want: &CitedAnswer{
+				Answer:    "It is in Paris.",
+				Sentences: []CitedSentence{{Text: "It is in Paris.", ChunkIDs: []string{"doc-3"}}},
+				Cited:     []string{"doc-3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Raw = tt.answer
+			got := ParseCitations(tt.answer, chunks)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(tt.want.Unsupported) == 0, got.Supported())
+		})
+	}
+}
+
+func TestQuestionAnswerWithCitations(t *testing.T) {
+	chunks := []ContextChunk{
+		{ID: "1", Text: "The Eiffel Tower was built from 1887 to 1889."},
+		{ID: "2", Text: "  It is 330 metres tall.  "},
+	}
+	stub := reply("It was built from 1887 to 1889 [1].")
+
+	answer, err := QuestionAnswerWithCitations(context.Background(), stub, "When was the Eiffel Tower built?", chunks)
+	require.NoError(t, err)
+	assert.True(t, answer.Supported())
+	assert.Equal(t, []string{"1"}, answer.Cited)
+
+	require.Len(t, stub.calls, 1)
+	prompt := stub.calls[0].Prompt
+	assert.Contains(t, prompt.Input, "When was the Eiffel Tower built?")
+	assert.Equal(t, "[1] The Eiffel Tower was built from 1887 to 1889.\n\n[2] It is 330 metres tall.", prompt.Context)
+	assert.Contains(t, prompt.Directives, "End every sentence with the IDs of the passages supporting it in square brackets, such as [1] or [1, 3]")
+
+	_, err = QuestionAnswerWithCitations(context.Background(), stub, "Question?", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,264 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and fact-checking capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/teilomillet/gollm"
+)
+
+// Claim verdicts of CheckGroundedness.
+const (
+	ClaimSupported    = "supported"    // The sources state or directly imply the claim
+	ClaimContradicted = "contradicted" // The sources state the opposite of the claim
+	ClaimUnsupported  = "unsupported"  // The sources say nothing about the claim
+)
+
+// ClaimVerdict is the verdict on one claim of an answer.
+type ClaimVerdict struct {
+	Claim   string // Self-contained factual claim made by the answer
+	Verdict string // ClaimSupported, ClaimContradicted or ClaimUnsupported
+
+	// Sources holds the indices in the sources of those that support or
+	// contradict the claim, with a quote of the deciding passage.
+	Sources   []int
+	Evidence  string
+	Rationale string
+
+	// Err is the error of the verification, if any; the claim then has no
+	// verdict.
+	Err error
+}
+
+// GroundednessReport is the verdict on every claim of an answer.
+type GroundednessReport struct {
+	// Claims holds the claims of the answer, in order.
+	Claims []ClaimVerdict
+
+	// Score is the share of verified claims the sources support, from 0 to
+	// 1. An answer without claims scores 1.
+	Score float64
+}
+
+// Grounded reports whether the sources support every claim of the answer.
+func (r *GroundednessReport) Grounded() bool {
+	for _, claim := range r.Claims {
+		if claim.Verdict != ClaimSupported {
+			return false
+		}
+	}
+	return true
+}
+
+// Ungrounded returns the claims the sources do not support, including those
+// that could not be verified.
+func (r *GroundednessReport) Ungrounded() []ClaimVerdict {
+	var claims []ClaimVerdict
+	for _, claim := range r.Claims {
+		if claim.Verdict != ClaimSupported {
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
+
+// GroundednessOption configures CheckGroundedness.
+type GroundednessOption func(*groundednessConfig)
+
+type groundednessConfig struct {
+	verifier    gollm.LLM
+	concurrency int
+}
+
+// WithGroundednessVerifier sets the LLM verifying the claims. It defaults to
+// the LLM decomposing the answer.
+func WithGroundednessVerifier(verifier gollm.LLM) GroundednessOption {
+	return func(c *groundednessConfig) {
+		c.verifier = verifier
+	}
+}
+
+// WithGroundednessConcurrency sets the number of claims verified at the same
+// time, 4 by default.
+func WithGroundednessConcurrency(n int) GroundednessOption {
+	return func(c *groundednessConfig) {
+		c.concurrency = n
+	}
+}
+
+var groundednessClaimsSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"claims": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+	},
+	"required": []string{"claims"},
+}
+
+var groundednessVerdictSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"verdict": map[string]interface{}{
+			"type": "string",
+			"enum": []string{ClaimSupported, ClaimContradicted, ClaimUnsupported},
+		},
+		"sources": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "integer"},
+		},
+		"evidence":  map[string]interface{}{"type": "string"},
+		"rationale": map[string]interface{}{"type": "string"},
+	},
+	"required": []string{"verdict", "sources", "evidence", "rationale"},
+}
+
+// CheckGroundedness fact-checks answer against sources: it decomposes the
+// answer into self-contained factual claims, then verifies each claim
+// against the sources, such as the documents retrieved for a RAG answer.
+// Claims that fail to verify are reported with their error; CheckGroundedness
+// only fails when the answer cannot be decomposed.
+//
+// Example:
+//
+//	report, err := presets.CheckGroundedness(ctx, llm, answer, retrievedPassages)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if report.Score < 0.9 {
+//	    for _, claim := range report.Ungrounded() {
+//	        log.Printf("%s: %s", claim.Verdict, claim.Claim)
+//	    }
+//	}
+func CheckGroundedness(ctx context.Context, l gollm.LLM, answer string, sources []string, opts ...GroundednessOption) (*GroundednessReport, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no sources to check the answer against")
+	}
+	cfg := &groundednessConfig{verifier: l, concurrency: 4}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+
+	claims, err := decomposeClaims(ctx, l, answer)
+	if err != nil {
+		return nil, err
+	}
+
+	var labelled strings.Builder
+	for i, source := range sources {
+		fmt.Fprintf(&labelled, "<source index=\"%d\">\n%s\n</source>\n", i, strings.TrimSpace(source))
+	}
+
+	report := &GroundednessReport{Claims: make([]ClaimVerdict, len(claims)), Score: 1}
+	slots := make(chan struct{}, cfg.concurrency)
+	var wg sync.WaitGroup
+	for i, claim := range claims {
+		report.Claims[i].Claim = claim
+		wg.Add(1)
+		go func(verdict *ClaimVerdict) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			verifyClaim(ctx, cfg.verifier, labelled.String(), len(sources), verdict)
+		}(&report.Claims[i])
+	}
+	wg.Wait()
+
+	var verified, supported int
+	for _, claim := range report.Claims {
+		if claim.Err != nil {
+			continue
+		}
+		verified++
+		if claim.Verdict == ClaimSupported {
+			supported++
+		}
+	}
+	if verified > 0 {
+		report.Score = float64(supported) / float64(verified)
+	}
+	return report, nil
+}
+
+// decomposeClaims asks l for the factual claims of answer.
+func decomposeClaims(ctx context.Context, l gollm.LLM, answer string) ([]string, error) {
+	prompt := gollm.NewPrompt(
+		"Answer:\n<answer>\n"+answer+"\n</answer>",
+		gollm.WithSystemPrompt("You decompose texts into the factual claims they make, to fact-check them.", ""),
+		gollm.WithDirectives(
+			"List every factual claim of the answer as a short, self-contained sentence",
+			"Resolve pronouns and references so that each claim can be checked on its own",
+			"Leave out opinions, hedges, greetings and questions",
+		),
+	)
+	response, err := l.GenerateWithSchema(ctx, prompt, groundednessClaimsSchema)
+	if err != nil {
+		return nil, fmt.Errorf("decomposing answer into claims: %w", err)
+	}
+	var parsed struct {
+		Claims []string `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(cleanResponse(response)), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing claims: %w", err)
+	}
+	claims := make([]string, 0, len(parsed.Claims))
+	for _, claim := range parsed.Claims {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// verifyClaim has the verifier judge the claim of verdict against the
+// labelled sources.
+func verifyClaim(ctx context.Context, verifier gollm.LLM, sources string, count int, verdict *ClaimVerdict) {
+	prompt := gollm.NewPrompt(
+		fmt.Sprintf("Sources:\n%s\nClaim:\n<claim>\n%s\n</claim>", sources, verdict.Claim),
+		gollm.WithSystemPrompt("You are a strict fact-checker verifying claims against sources only, never against your own knowledge.", ""),
+		gollm.WithDirectives(
+			"The claim is supported if the sources state it or directly imply it, contradicted if they state otherwise, and unsupported if they say nothing about it",
+			"List the indices of the deciding sources and quote the deciding passage as evidence",
+			"Give a one-sentence rationale",
+		),
+	)
+	response, err := verifier.GenerateWithSchema(ctx, prompt, groundednessVerdictSchema)
+	if err != nil {
+		verdict.Err = fmt.Errorf("verifying claim: %w", err)
+		return
+	}
+	var parsed struct {
+		Verdict   string `json:"verdict"`
+		Sources   []int  `json:"sources"`
+		Evidence  string `json:"evidence"`
+		Rationale string `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(cleanResponse(response)), &parsed); err != nil {
+		verdict.Err = fmt.Errorf("parsing verdict: %w", err)
+		return
+	}
+	switch parsed.Verdict {
+	case ClaimSupported, ClaimContradicted, ClaimUnsupported:
+	default:
+		verdict.Err = fmt.Errorf("unknown verdict %q", parsed.Verdict)
+		return
+	}
+	verdict.Verdict, verdict.Evidence, verdict.Rationale = parsed.Verdict, parsed.Evidence, parsed.Rationale
+	for _, index := range parsed.Sources {
+		if index >= 0 && index < count {
+			verdict.Sources = append(verdict.Sources, index)
+		}
+	}
+}
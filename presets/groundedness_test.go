@@ -0,0 +1,147 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// factChecker returns a stubLLM that decomposes answers into claims and
+// answers each claim's verification with verdicts[claim].
+func factChecker(claims string, verdicts map[string]string) *stubLLM {
+	return &stubLLM{respond: func(call stubCall) (string, error) {
+		if strings.HasPrefix(call.Prompt.Input, "Answer:") {
+			return claims, nil
+		}
+		for claim, verdict := range verdicts {
+			if strings.Contains(call.Prompt.Input, "<claim>\n"+claim+"\n</claim>") {
+				return verdict, nil
+			}
+		}
+		return "", fmt.Errorf("unexpected prompt: %s", call.Prompt.Input)
+	}}
+}
+
+func TestCheckGroundedness(t *testing.T) {
+	sources := []string{"The Eiffel Tower is in Paris.", "It was completed in 1889."}
+	tests := []struct {
+		name       string
+		claims     string
+		responses  map[string]string
+		score      float64
+		grounded   bool
+		verdicts   []string
+		sources    [][]int
+		errs       []bool
+		ungrounded int
+	}{
+		{
+			name:   "all supported",
+			claims: `{"claims":["The Eiffel Tower is in Paris.","It was completed in 1889."]}`,
+			responses: map[string]string{
+				"The Eiffel Tower is in Paris.": `{"verdict":"supported","sources":[0],"evidence":"is in Paris","rationale":"Stated."}`,
+				"It was completed in 1889.":     "```json\n" + `{"verdict":"supported","sources":[1],"evidence":"completed in 1889","rationale":"Stated."}` + "\n```",
+			},
+			score:    1,
+			grounded: true,
+			verdicts: []string{ClaimSupported, ClaimSupported},
+			sources:  [][]int{{0}, {1}},
+			errs:     []bool{false, false},
+		},
+		{
+			name:   "contradicted and unsupported claims",
+			claims: `{"claims":["The Eiffel Tower is in Paris.","It was completed in 1890.","It is painted red.","  "]}`,
+			responses: map[string]string{
+				"The Eiffel Tower is in Paris.": `{"verdict":"supported","sources":[0],"evidence":"","rationale":""}`,
+				"It was completed in 1890.":     `{"verdict":"contradicted","sources":[1,5,-1],"evidence":"completed in 1889","rationale":"Wrong year."}`,
+				"It is painted red.":            `{"verdict":"unsupported","sources":[],"evidence":"","rationale":"Not mentioned."}`,
+			},
+			score:      1.0 / 3,
+			verdicts:   []string{ClaimSupported, ClaimContradicted, ClaimUnsupported},
+			sources:    [][]int{{0}, {1}, nil},
+			errs:       []bool{false, false, false},
+			ungrounded: 2,
+		},
+		{
+			name:   "failed verifications are not scored",
+			claims: `{"claims":["The Eiffel Tower is in Paris.","It was completed in 1889.","It is 330 metres tall."]}`,
+			responses: map[string]string{
+				"The Eiffel Tower is in Paris.": `{"verdict":"supported","sources":[0],"evidence":"","rationale":""}`,
+				"It was completed in 1889.":     `{"verdict":"maybe","sources":[],"evidence":"","rationale":""}`,
+				"It is 330 metres tall.":        `not json`,
+			},
+			score:      1,
+			verdicts:   []string{ClaimSupported, "", ""},
+			sources:    [][]int{{0}, nil, nil},
+			errs:       []bool{false, true, true},
+			ungrounded: 2,
+		},
+		{
+			name:     "no claims",
+			claims:   `{"claims":[]}`,
+			score:    1,
+			grounded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := factChecker(tt.claims, tt.responses)
+			report, err := CheckGroundedness(context.Background(), stub, "Some answer.", sources, WithGroundednessConcurrency(2))
+			require.NoError(t, err)
+			assert.InDelta(t, tt.score, report.Score, 1e-9)
+			assert.Equal(t, tt.grounded, report.Grounded())
+			assert.Len(t, report.Ungrounded(), tt.ungrounded)
+			require.Len(t, report.Claims, len(tt.verdicts))
+			for i, claim := range report.Claims {
+				assert.Equal(t, tt.verdicts[i], claim.Verdict, claim.Claim)
+				assert.Equal(t, tt.sources[i], claim.Sources, claim.Claim)
+				assert.Equal(t, tt.errs[i], claim.Err != nil, claim.Claim)
+			}
+		})
+	}
+}
+
+func TestCheckGroundednessVerifier(t *testing.T) {
+	decomposer := factChecker(`{"claims":["The Eiffel Tower is in Paris."]}`, nil)
+	verifier := factChecker("", map[string]string{
+		"The Eiffel Tower is in Paris.": `{"verdict":"supported","sources":[0],"evidence":"is in Paris","rationale":"Stated."}`,
+	})
+
+	report, err := CheckGroundedness(context.Background(), decomposer, "It is in Paris.", []string{"The Eiffel Tower is in Paris."},
+		WithGroundednessVerifier(verifier))
+	require.NoError(t, err)
+	assert.True(t, report.Grounded())
+
+	require.Len(t, decomposer.calls, 1)
+	assert.Contains(t, decomposer.calls[0].Prompt.Input, "<answer>\nIt is in Paris.\n</answer>")
+	assert.Equal(t, groundednessClaimsSchema, decomposer.calls[0].Schema)
+	require.Len(t, verifier.calls, 1)
+	assert.Contains(t, verifier.calls[0].Prompt.Input, "<source index=\"0\">\nThe Eiffel Tower is in Paris.\n</source>")
+	assert.Equal(t, groundednessVerdictSchema, verifier.calls[0].Schema)
+}
+
+func TestCheckGroundednessErrors(t *testing.T) {
+	ctx := context.Background()
+	sources := []string{"source"}
+
+	_, err := CheckGroundedness(ctx, nil, "answer", sources)
+	assert.Error(t, err)
+
+	_, err = CheckGroundedness(ctx, reply(`{"claims":[]}`), "answer", nil)
+	assert.Error(t, err)
+
+	failing := &stubLLM{respond: func(stubCall) (string, error) { return "", errors.New("unavailable") }}
+	_, err = CheckGroundedness(ctx, failing, "answer", sources)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "decomposing answer into claims")
+
+	_, err = CheckGroundedness(ctx, reply("I cannot do that."), "answer", sources)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parsing claims")
+}
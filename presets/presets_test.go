@@ -0,0 +1,47 @@
+package presets
+
+import (
+	"context"
+	"sync"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+)
+
+// stubCall is a request received by a stubLLM.
+type stubCall struct {
+	Prompt  *gollm.Prompt
+	Schema  interface{}
+	Options map[string]interface{}
+}
+
+// stubLLM answers Generate and GenerateWithSchema with respond and records
+// the calls. The other methods of gollm.LLM are not implemented.
+type stubLLM struct {
+	gollm.LLM
+	respond func(call stubCall) (string, error)
+
+	mu    sync.Mutex
+	calls []stubCall
+}
+
+func (s *stubLLM) Generate(ctx context.Context, prompt *gollm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	return s.GenerateWithSchema(ctx, prompt, nil, opts...)
+}
+
+func (s *stubLLM) GenerateWithSchema(ctx context.Context, prompt *gollm.Prompt, schema interface{}, opts ...llm.GenerateOption) (string, error) {
+	cfg := &llm.GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	call := stubCall{Prompt: prompt, Schema: schema, Options: cfg.Options}
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	return s.respond(call)
+}
+
+// reply returns a stubLLM answering every request with response.
+func reply(response string) *stubLLM {
+	return &stubLLM{respond: func(stubCall) (string, error) { return response, nil }}
+}
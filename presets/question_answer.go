@@ -49,6 +49,7 @@ var QuestionAnswerTemplate = gollm.NewPromptTemplate(
 //   - WithDirectives: Add specific answering instructions
 //   - WithTemperature: Adjust answer creativity
 //   - WithTopP: Control response diversity
+//   - WithCitations: Answer from context chunks, citing their IDs
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeouts
@@ -0,0 +1,290 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and table extraction capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/teilomillet/gollm"
+)
+
+// Table is tabular data extracted from a response.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Column returns the index of the column named name, ignoring case and
+// surrounding spaces, or -1.
+func (t *Table) Column(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, header := range t.Header {
+		if strings.ToLower(strings.TrimSpace(header)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ExtractTable extracts tabular data from text with the LLM, for users who
+// want rows rather than JSON. The model is asked for a markdown table with
+// the given columns, in that order; it may also answer with a CSV block.
+// Every column must be present in the parsed header.
+//
+// Example:
+//
+//	table, err := presets.ExtractTable(ctx, llm, report, []string{"Country", "Population", "Capital"})
+//	for _, row := range table.Rows {
+//	    fmt.Println(row[0], row[1], row[2])
+//	}
+func ExtractTable(ctx context.Context, l gollm.LLM, text string, columns []string, opts ...gollm.PromptOption) (*Table, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	prompt := gollm.NewPrompt(fmt.Sprintf("Extract a table with the columns %s from the following text:\n\n%s",
+		strings.Join(columns, ", "), text))
+	prompt.Apply(append(opts,
+		gollm.WithDirectives(
+			"Include one row per item found in the text",
+			"Keep the columns in the given order, with these exact names as the header",
+			"Leave a cell empty when the text does not give its value",
+		),
+		gollm.WithOutput("A markdown table only, without any other text"),
+	)...)
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate table: %w", err)
+	}
+
+	table, err := ParseTable(response)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		if table.Column(column) == -1 {
+			return nil, fmt.Errorf("table is missing column %q", column)
+		}
+	}
+	return table, nil
+}
+
+// ExtractRows extracts tabular data into typed rows. The columns are the
+// fields of T, named by their "table" tag, or else their json tag or field
+// name. Cells are converted to the field types (strings, numbers and
+// booleans) and each row is validated with the fields' validate tags.
+//
+// Example:
+//
+//	type Country struct {
+//	    Name       string  `table:"Country" validate:"required"`
+//	    Population int     `table:"Population"`
+//	    Area       float64 `table:"Area (km²)"`
+//	}
+//
+//	countries, err := presets.ExtractRows[Country](ctx, llm, report)
+func ExtractRows[T any](ctx context.Context, l gollm.LLM, text string, opts ...gollm.PromptOption) ([]T, error) {
+	fields, err := tableFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.column
+	}
+	table, err := ExtractTable(ctx, l, text, columns, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]T, 0, len(table.Rows))
+	for i, cells := range table.Rows {
+		var row T
+		value := reflect.ValueOf(&row).Elem()
+		for _, field := range fields {
+			cell := ""
+			if column := table.Column(field.column); column >= 0 && column < len(cells) {
+				cell = strings.TrimSpace(cells[column])
+			}
+			if err := setCell(value.Field(field.index), cell); err != nil {
+				return nil, fmt.Errorf("row %d, column %q: %w", i+1, field.column, err)
+			}
+		}
+		if err := gollm.Validate(&row); err != nil {
+			return nil, fmt.Errorf("row %d failed validation: %w", i+1, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// tableField maps a struct field to a column.
+type tableField struct {
+	index  int
+	column string
+}
+
+// tableFields returns the columns of the exported fields of struct type t.
+func tableFields(t reflect.Type) ([]tableField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rows must be structs, not %s", t)
+	}
+	var fields []tableField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		column := field.Tag.Get("table")
+		if column == "" {
+			column, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if column == "-" {
+			continue
+		}
+		if column == "" {
+			column = field.Name
+		}
+		fields = append(fields, tableField{index: i, column: column})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s has no columns", t)
+	}
+	return fields, nil
+}
+
+// setCell converts cell to the type of field and stores it. Empty cells
+// leave the zero value.
+func setCell(field reflect.Value, cell string) error {
+	if cell == "" {
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(cell)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(numeric(cell), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(numeric(cell), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(numeric(cell), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		switch strings.ToLower(cell) {
+		case "true", "yes", "y", "1":
+			field.SetBool(true)
+		case "false", "no", "n", "0":
+		default:
+			return fmt.Errorf("invalid boolean %q", cell)
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// numeric removes the thousands separators and units models add to
+// numbers, such as "1,234" or "45 %".
+func numeric(cell string) string {
+	return strings.NewReplacer(",", "", "_", "", " ", "", "%", "", "$", "", "€", "", "£", "").Replace(cell)
+}
+
+// ParseTable parses the first markdown table or CSV block of text. A CSV
+// block is either fenced, as in ```csv, or the whole text. The first row is
+// the header; rows are padded or truncated to its width.
+func ParseTable(text string) (*Table, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var rows [][]string
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), "|") {
+			continue
+		}
+		for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+			cells := markdownCells(lines[i])
+			if !isSeparatorRow(cells) {
+				rows = append(rows, cells)
+			}
+		}
+		break
+	}
+
+	if rows == nil {
+		block := strings.TrimSpace(text)
+		if start := strings.Index(block, "```"); start != -1 {
+			block = block[start+3:]
+			if newline := strings.IndexByte(block, '\n'); newline != -1 {
+				block = block[newline+1:]
+			}
+			if end := strings.Index(block, "```"); end != -1 {
+				block = block[:end]
+			}
+		}
+		reader := csv.NewReader(strings.NewReader(strings.TrimSpace(block)))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil || len(records) == 0 || len(records[0]) < 2 {
+			return nil, fmt.Errorf("no markdown table or CSV block in the response")
+		}
+		rows = records
+	}
+
+	table := &Table{Header: rows[0]}
+	for _, row := range rows[1:] {
+		cells := make([]string, len(table.Header))
+		copy(cells, row)
+		table.Rows = append(table.Rows, cells)
+	}
+	return table, nil
+}
+
+// markdownCells splits a markdown table row into its trimmed cells.
+func markdownCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	// Escaped pipes are part of the cell
+	parts := strings.Split(strings.ReplaceAll(line, `\|`, "\x00"), "|")
+	cells := make([]string, len(parts))
+	for i, part := range parts {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(part, "\x00", "|"))
+	}
+	return cells
+}
+
+// isSeparatorRow reports whether cells are the |---|:---:| row under a
+// markdown header.
+func isSeparatorRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(cell, "-: ") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		table *Table
+	}{
+		{
+			name: "markdown",
+			text: "| Country | Capital |\n|---|---|\n| France | Paris |\n| Japan | Tokyo |",
+			table: &Table{
+				Header: []string{"Country", "Capital"},
+				Rows:   [][]string{{"France", "Paris"}, {"Japan", "Tokyo"}},
+			},
+		},
+		{
+			name: "markdown surrounded by text",
+			text: "Here is the table:\n\n  | A | B |\n  | :--- | ---: |\n  | 1 | 2 |\n\nLet me know if you need more.",
+			table: &Table{
+				Header: []string{"A", "B"},
+				Rows:   [][]string{{"1", "2"}},
+			},
+		},
+		{
+			name: "markdown without outer pipes on the last cell",
+			text: "| A | B\n|---|---\n| 1 | 2",
+			table: &Table{
+				Header: []string{"A", "B"},
+				Rows:   [][]string{{"1", "2"}},
+			},
+		},
+		{
+			name: "markdown escaped pipe",
+			text: `| Expr | Meaning |` + "\n" + `|---|---|` + "\n" + `| a \| b | a or b |`,
+			table: &Table{
+				Header: []string{"Expr", "Meaning"},
+				Rows:   [][]string{{"a | b", "a or b"}},
+			},
+		},
+		{
+			name: "markdown short and long rows",
+			text: "| A | B | C |\n|---|---|---|\n| 1 |\n| 1 | 2 | 3 | 4 |",
+			table: &Table{
+				Header: []string{"A", "B", "C"},
+				Rows:   [][]string{{"1", "", ""}, {"1", "2", "3"}},
+			},
+		},
+		{
+			name: "markdown header only",
+			text: "| A | B |\n|---|---|",
+			table: &Table{
+				Header: []string{"A", "B"},
+			},
+		},
+		{
+			name: "only the first markdown table",
+			text: "| A | B |\n|---|---|\n| 1 | 2 |\n\n| C | D |\n|---|---|\n| 3 | 4 |",
+			table: &Table{
+				Header: []string{"A", "B"},
+				Rows:   [][]string{{"1", "2"}},
+			},
+		},
+		{
+			name: "fenced CSV",
+			text: "```csv\nCountry, Capital\nFrance, Paris\n\"Korea, South\", Seoul\n```",
+			table: &Table{
+				Header: []string{"Country", "Capital"},
+				Rows:   [][]string{{"France", "Paris"}, {"Korea, South", "Seoul"}},
+			},
+		},
+		{
+			name: "bare CSV",
+			text: "Country,Capital\nFrance,Paris\nJapan",
+			table: &Table{
+				Header: []string{"Country", "Capital"},
+				Rows:   [][]string{{"France", "Paris"}, {"Japan", ""}},
+			},
+		},
+		{
+			name: "CRLF line endings",
+			text: "| A | B |\r\n|---|---|\r\n| 1 | 2 |\r\n",
+			table: &Table{
+				Header: []string{"A", "B"},
+				Rows:   [][]string{{"1", "2"}},
+			},
+		},
+		{name: "prose", text: "I could not find any table in the text."},
+		{name: "single CSV column", text: "Country\nFrance"},
+		{name: "empty", text: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseTable(tt.text)
+			if tt.table == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.table, table)
+		})
+	}
+}
+
+func TestTableColumn(t *testing.T) {
+	table := &Table{Header: []string{"Country", " Population "}}
+	assert.Equal(t, 0, table.Column("country"))
+	assert.Equal(t, 1, table.Column("Population"))
+	assert.Equal(t, -1, table.Column("Capital"))
+}
+
+func TestExtractTable(t *testing.T) {
+	columns := []string{"Country", "Capital"}
+	tests := []struct {
+		name     string
+		columns  []string
+		text     string
+		response string
+		err      error
+		table    *Table
+		wantErr  string
+	}{
+		{
+			name:     "markdown",
+			columns:  columns,
+			text:     "France's capital is Paris.",
+			response: "| Country | Capital |\n|---|---|\n| France | Paris |",
+			table:    &Table{Header: columns, Rows: [][]string{{"France", "Paris"}}},
+		},
+		{
+			name:     "columns in another order and case",
+			columns:  columns,
+			text:     "France's capital is Paris.",
+			response: "| capital | country |\n|---|---|\n| Paris | France |",
+			table:    &Table{Header: []string{"capital", "country"}, Rows: [][]string{{"Paris", "France"}}},
+		},
+		{
+			name:     "missing column",
+			columns:  columns,
+			text:     "France's capital is Paris.",
+			response: "| Country | Population |\n|---|---|\n| France | 68M |",
+			wantErr:  `table is missing column "Capital"`,
+		},
+		{
+			name:     "no table",
+			columns:  columns,
+			text:     "Nothing here.",
+			response: "There is no data.",
+			wantErr:  "no markdown table or CSV block",
+		},
+		{
+			name:    "generation error",
+			columns: columns,
+			text:    "France's capital is Paris.",
+			err:     errors.New("rate limited"),
+			wantErr: "rate limited",
+		},
+		{name: "no columns", text: "text", wantErr: "at least one column"},
+		{name: "empty text", columns: columns, text: "  ", wantErr: "text cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubLLM{respond: func(stubCall) (string, error) { return tt.response, tt.err }}
+			table, err := ExtractTable(context.Background(), stub, tt.text, tt.columns)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.table, table)
+			require.Len(t, stub.calls, 1)
+			assert.Contains(t, stub.calls[0].Prompt.Input, "Country, Capital")
+			assert.Contains(t, stub.calls[0].Prompt.Input, tt.text)
+		})
+	}
+}
+
+func TestExtractRows(t *testing.T) {
+	type country struct {
+		Name       string  `table:"Country" validate:"required"`
+		Population int     `json:"population"`
+		Area       float64 `table:"Area (km²)"`
+		Coastal    bool
+		Internal   string `table:"-"`
+	}
+
+	t.Run("typed cells", func(t *testing.T) {
+		stub := reply("| Country | population | Area (km²) | Coastal |\n|---|---|---|---|\n" +
+			"| France | 68,170,000 | 551,695.5 | yes |\n" +
+			"| Switzerland | 8 700 000 |  | no |")
+		rows, err := ExtractRows[country](context.Background(), stub, "Some report")
+		require.NoError(t, err)
+		assert.Equal(t, []country{
+			{Name: "France", Population: 68170000, Area: 551695.5, Coastal: true},
+			{Name: "Switzerland", Population: 8700000},
+		}, rows)
+	})
+
+	t.Run("invalid cell", func(t *testing.T) {
+		stub := reply("| Country | population | Area (km²) | Coastal |\n|---|---|---|---|\n| France | many | 1 | yes |")
+		_, err := ExtractRows[country](context.Background(), stub, "Some report")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `row 1, column "population"`)
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		stub := reply("| Country | population | Area (km²) | Coastal |\n|---|---|---|---|\n|  | 1 | 1 | no |")
+		_, err := ExtractRows[country](context.Background(), stub, "Some report")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "row 1 failed validation")
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := ExtractRows[string](context.Background(), reply(""), "Some report")
+		assert.Error(t, err)
+	})
+}
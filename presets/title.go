@@ -0,0 +1,212 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and conversation titling capabilities.
+package presets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/teilomillet/gollm"
+	"github.com/teilomillet/gollm/llm"
+)
+
+// ConversationTitle is the title and tags of a conversation, as listed by
+// chat UIs.
+type ConversationTitle struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+// TitleCache holds the titles of conversations by conversation hash, so
+// that a conversation is titled once. It is safe for concurrent use.
+type TitleCache struct {
+	mu     sync.RWMutex
+	titles map[string]ConversationTitle
+}
+
+// NewTitleCache creates an empty TitleCache.
+func NewTitleCache() *TitleCache {
+	return &TitleCache{titles: make(map[string]ConversationTitle)}
+}
+
+// Len returns the number of cached titles.
+func (c *TitleCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.titles)
+}
+
+// Clear removes every cached title.
+func (c *TitleCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.titles = make(map[string]ConversationTitle)
+}
+
+func (c *TitleCache) get(key string) (ConversationTitle, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	title, ok := c.titles[key]
+	return title, ok
+}
+
+func (c *TitleCache) put(key string, title ConversationTitle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.titles[key] = title
+}
+
+// defaultTitleCache is the cache of TitleConversation unless WithTitleCache
+// replaces it.
+var defaultTitleCache = NewTitleCache()
+
+// TitleOption configures TitleConversation.
+type TitleOption func(*titleConfig)
+
+type titleConfig struct {
+	model    string
+	cache    *TitleCache
+	maxTags  int
+	maxChars int
+}
+
+// WithTitleModel titles conversations with model, typically a small and
+// cheap one such as "gpt-4o-mini", instead of the LLM's configured model.
+func WithTitleModel(model string) TitleOption {
+	return func(c *titleConfig) {
+		c.model = model
+	}
+}
+
+// WithTitleCache caches titles in cache instead of the package's shared
+// cache. A nil cache disables caching.
+func WithTitleCache(cache *TitleCache) TitleOption {
+	return func(c *titleConfig) {
+		c.cache = cache
+	}
+}
+
+// WithTitleMaxTags sets the maximum number of tags, 3 by default.
+func WithTitleMaxTags(n int) TitleOption {
+	return func(c *titleConfig) {
+		c.maxTags = n
+	}
+}
+
+// WithTitleMaxMessageChars truncates each message to n characters in the
+// titling prompt, 2000 by default, to keep long conversations cheap to
+// title.
+func WithTitleMaxMessageChars(n int) TitleOption {
+	return func(c *titleConfig) {
+		c.maxChars = n
+	}
+}
+
+var conversationTitleSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"title": map[string]interface{}{"type": "string"},
+		"tags": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+	},
+	"required": []string{"title", "tags"},
+}
+
+// ConversationHash returns the hash of messages under which
+// TitleConversation caches their title: the SHA-256 of their roles and
+// contents.
+func ConversationHash(messages []gollm.PromptMessage) string {
+	h := sha256.New()
+	for _, m := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", m.Role, m.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TitleConversation generates a short title and a few lowercase tags for a
+// conversation, for the conversation list of a chat UI. Results are cached
+// by conversation hash and model, so titling the same conversation again
+// makes no request. System messages are left out of the titling prompt.
+//
+// Example:
+//
+//	title, err := presets.TitleConversation(ctx, llm, messages,
+//	    presets.WithTitleModel("gpt-4o-mini"),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(title.Title, title.Tags)
+func TitleConversation(ctx context.Context, l gollm.LLM, messages []gollm.PromptMessage, opts ...TitleOption) (*ConversationTitle, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	cfg := &titleConfig{cache: defaultTitleCache, maxTags: 3, maxChars: 2000}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var transcript strings.Builder
+	for _, m := range messages {
+		if m.Role == "system" || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		content := m.Content
+		if runes := []rune(content); cfg.maxChars > 0 && len(runes) > cfg.maxChars {
+			content = string(runes[:cfg.maxChars]) + "..."
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", m.Role, content)
+	}
+	if transcript.Len() == 0 {
+		return nil, fmt.Errorf("conversation has no messages to title")
+	}
+
+	key := ConversationHash(messages) + "/" + cfg.model
+	if cfg.cache != nil {
+		if title, ok := cfg.cache.get(key); ok {
+			return &title, nil
+		}
+	}
+
+	prompt := gollm.NewPrompt(
+		"Conversation:\n<conversation>\n"+transcript.String()+"</conversation>",
+		gollm.WithSystemPrompt("You title conversations for the conversation list of a chat application.", ""),
+		gollm.WithDirectives(
+			"Write a title of 3 to 6 words that tells the conversation apart from others, without quotes or final punctuation",
+			"Write the title in the language of the conversation",
+			fmt.Sprintf("Give at most %d short lowercase tags for the topics of the conversation", cfg.maxTags),
+		),
+	)
+	var genOpts []llm.GenerateOption
+	if cfg.model != "" {
+		genOpts = append(genOpts, gollm.WithModel(cfg.model))
+	}
+	response, err := l.GenerateWithSchema(ctx, prompt, conversationTitleSchema, genOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("generating conversation title: %w", err)
+	}
+	var title ConversationTitle
+	if err := json.Unmarshal([]byte(cleanResponse(response)), &title); err != nil {
+		return nil, fmt.Errorf("parsing conversation title: %w", err)
+	}
+	title.Title = strings.Trim(strings.TrimSpace(title.Title), `"'.`)
+	tags := make([]string, 0, len(title.Tags))
+	for _, tag := range title.Tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" && (cfg.maxTags <= 0 || len(tags) < cfg.maxTags) {
+			tags = append(tags, tag)
+		}
+	}
+	title.Tags = tags
+
+	if cfg.cache != nil {
+		cfg.cache.put(key, title)
+	}
+	return &title, nil
+}
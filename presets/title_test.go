@@ -0,0 +1,133 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm"
+)
+
+func TestTitleConversation(t *testing.T) {
+	conversation := []gollm.PromptMessage{
+		{Role: "system", Content: "You are a travel assistant."},
+		{Role: "user", Content: "I want to visit Paris in May."},
+		{Role: "assistant", Content: "Great choice! Here are some ideas."},
+	}
+	tests := []struct {
+		name       string
+		messages   []gollm.PromptMessage
+		opts       []TitleOption
+		response   string
+		title      *ConversationTitle
+		transcript []string
+		omitted    []string
+		wantErr    string
+	}{
+		{
+			name:     "title and tags are cleaned",
+			messages: conversation,
+			response: "```json\n" + `{"title":" \"Paris trip in May.\" ","tags":[" Travel ","France","","Food","Spring"]}` + "\n```",
+			title:    &ConversationTitle{Title: "Paris trip in May", Tags: []string{"travel", "france", "food"}},
+			transcript: []string{
+				"user: I want to visit Paris in May.",
+				"assistant: Great choice! Here are some ideas.",
+			},
+			omitted: []string{"You are a travel assistant."},
+		},
+		{
+			name:     "max tags",
+			messages: conversation,
+			opts:     []TitleOption{WithTitleMaxTags(1)},
+			response: `{"title":"Paris trip","tags":["travel","france"]}`,
+			title:    &ConversationTitle{Title: "Paris trip", Tags: []string{"travel"}},
+		},
+		{
+			name:       "long messages are truncated",
+			messages:   []gollm.PromptMessage{{Role: "user", Content: "Héllo world, how are you?"}},
+			opts:       []TitleOption{WithTitleMaxMessageChars(5)},
+			response:   `{"title":"Greeting","tags":[]}`,
+			title:      &ConversationTitle{Title: "Greeting", Tags: []string{}},
+			transcript: []string{"user: Héllo..."},
+			omitted:    []string{"world"},
+		},
+		{
+			name:     "only system messages",
+			messages: []gollm.PromptMessage{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "  "}},
+			wantErr:  "no messages to title",
+		},
+		{
+			name:     "invalid response",
+			messages: conversation,
+			response: "Paris trip",
+			wantErr:  "parsing conversation title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := reply(tt.response)
+			opts := append([]TitleOption{WithTitleCache(nil)}, tt.opts...)
+			title, err := TitleConversation(context.Background(), stub, tt.messages, opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.title, title)
+			require.Len(t, stub.calls, 1)
+			assert.Equal(t, conversationTitleSchema, stub.calls[0].Schema)
+			for _, s := range tt.transcript {
+				assert.Contains(t, stub.calls[0].Prompt.Input, s)
+			}
+			for _, s := range tt.omitted {
+				assert.NotContains(t, stub.calls[0].Prompt.Input, s)
+			}
+		})
+	}
+}
+
+func TestTitleConversationCache(t *testing.T) {
+	ctx := context.Background()
+	messages := []gollm.PromptMessage{{Role: "user", Content: "How do I bake bread?"}}
+	cache := NewTitleCache()
+	stub := reply(`{"title":"Baking bread","tags":["cooking"]}`)
+
+	first, err := TitleConversation(ctx, stub, messages, WithTitleCache(cache))
+	require.NoError(t, err)
+	second, err := TitleConversation(ctx, stub, messages, WithTitleCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Len(t, stub.calls, 1, "the second title comes from the cache")
+	assert.Equal(t, 1, cache.Len())
+
+	_, err = TitleConversation(ctx, stub, messages, WithTitleCache(cache), WithTitleModel("gpt-4o-mini"))
+	require.NoError(t, err)
+	require.Len(t, stub.calls, 2, "titles are cached per model")
+	assert.Equal(t, "gpt-4o-mini", stub.calls[1].Options["model"])
+	assert.Equal(t, 2, cache.Len())
+
+	changed := append(messages, gollm.PromptMessage{Role: "assistant", Content: "Mix flour and water."})
+	_, err = TitleConversation(ctx, stub, changed, WithTitleCache(cache))
+	require.NoError(t, err)
+	assert.Len(t, stub.calls, 3, "a changed conversation is titled again")
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+
+	failing := &stubLLM{respond: func(stubCall) (string, error) { return "", errors.New("unavailable") }}
+	_, err = TitleConversation(ctx, failing, messages, WithTitleCache(cache))
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Len(), "failures are not cached")
+}
+
+func TestConversationHash(t *testing.T) {
+	a := []gollm.PromptMessage{{Role: "user", Content: "ab"}, {Role: "assistant", Content: "c"}}
+	b := []gollm.PromptMessage{{Role: "user", Content: "a"}, {Role: "assistant", Content: "bc"}}
+	assert.Equal(t, ConversationHash(a), ConversationHash(a))
+	assert.NotEqual(t, ConversationHash(a), ConversationHash(b))
+	assert.Len(t, ConversationHash(nil), 64)
+}
@@ -4,10 +4,9 @@
 package gollm
 
 import (
-	"strings"
-
 	"github.com/teilomillet/gollm/config"
 	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/providers"
 	"github.com/teilomillet/gollm/utils"
 )
 
@@ -35,6 +34,14 @@ type (
 	// Tools are higher-level abstractions over functions that include usage policies.
 	Tool = utils.Tool
 
+	// Attachment is an image or document sent alongside a prompt.
+	// Use WithImageURL, WithImageFile or WithFile to add attachments.
+	Attachment = utils.Attachment
+
+	// Document is a source the response should be grounded in.
+	// Use WithDocuments to add documents to a prompt.
+	Document = utils.Document
+
 	// PromptOption defines a function that can modify a prompt's configuration.
 	// These are used to customize prompt behavior in a flexible, chainable way.
 	PromptOption = llm.PromptOption
@@ -54,6 +61,30 @@ type (
 	// PromptTemplate defines a reusable template for generating prompts.
 	// Templates can include variables that are filled in at runtime.
 	PromptTemplate = llm.PromptTemplate
+
+	// TemplateValidationError lists the undefined and unused variables, and the size, of an invalid template.
+	TemplateValidationError = llm.TemplateValidationError
+
+	// ExecuteOption configures a single PromptTemplate.Execute call, such as its locale.
+	ExecuteOption = llm.ExecuteOption
+
+	// RenderedPrompt shows what a provider receives for a prompt, with token counts per section.
+	RenderedPrompt = llm.RenderedPrompt
+
+	// PromptSection is one part of a rendered prompt.
+	PromptSection = llm.PromptSection
+
+	// ReasoningEffort is how much a reasoning model thinks before it answers.
+	ReasoningEffort = providers.ReasoningEffort
+
+	// PromptBuilder assembles a prompt from prioritized sections fitted into a token budget.
+	PromptBuilder = llm.PromptBuilder
+
+	// PromptBudgetReport is what a PromptBuilder truncated or dropped to fit its budget.
+	PromptBudgetReport = llm.PromptBudgetReport
+
+	// PromptBudgetCut is a section item truncated or dropped by a PromptBuilder.
+	PromptBudgetCut = llm.PromptBudgetCut
 )
 
 // Cache type constants define the available caching strategies.
@@ -61,6 +92,19 @@ const (
 	// CacheTypeEphemeral indicates that cached responses should only persist
 	// for the duration of the program's execution.
 	CacheTypeEphemeral = llm.CacheTypeEphemeral
+
+	// PromptSchemaVersion is the version of the JSON encoding of prompts.
+	PromptSchemaVersion = llm.PromptSchemaVersion
+
+	// PriorityRequired marks a PromptBuilder section that is never truncated nor dropped.
+	PriorityRequired = llm.PriorityRequired
+)
+
+// Reasoning efforts for WithReasoningEffort
+const (
+	ReasoningLow    = providers.ReasoningLow    // Answers quickly with little thinking
+	ReasoningMedium = providers.ReasoningMedium // Balances thinking and latency
+	ReasoningHigh   = providers.ReasoningHigh   // Thinks longest before answering
 )
 
 // The following variables are re-exported functions from the llm package.
@@ -69,6 +113,18 @@ var (
 	// NewPrompt creates a new prompt instance with the given options.
 	NewPrompt = llm.NewPrompt
 
+	// SavePrompt writes a prompt to a versioned JSON file.
+	SavePrompt = llm.SavePrompt
+
+	// LoadPrompt reads a prompt written by SavePrompt.
+	LoadPrompt = llm.LoadPrompt
+
+	// EstimateTokens returns a rough token count of a text.
+	EstimateTokens = llm.EstimateTokens
+
+	// NewPromptBuilder creates a PromptBuilder fitting prompts into a token budget.
+	NewPromptBuilder = llm.NewPromptBuilder
+
 	// CacheOption configures caching behavior for a prompt.
 	CacheOption = llm.CacheOption
 
@@ -87,6 +143,9 @@ var (
 	// WithMessages adds multiple messages to the prompt.
 	WithMessages = llm.WithMessages
 
+	// WithMessageAttachments attaches images or documents to the last message of the prompt.
+	WithMessageAttachments = llm.WithMessageAttachments
+
 	// WithDirectives adds special instructions or constraints.
 	WithDirectives = llm.WithDirectives
 
@@ -99,6 +158,18 @@ var (
 	// WithMaxLength sets the maximum length for generated responses.
 	WithMaxLength = llm.WithMaxLength
 
+	// WithImageURL attaches an image by URL for vision models.
+	WithImageURL = llm.WithImageURL
+
+	// WithImageFile attaches a local image for vision models.
+	WithImageFile = llm.WithImageFile
+
+	// WithFile attaches a local document, such as a PDF.
+	WithFile = llm.WithFile
+
+	// WithDocuments adds documents the response should be grounded in.
+	WithDocuments = llm.WithDocuments
+
 	// WithExamples adds example conversations or outputs.
 	WithExamples = llm.WithExamples
 
@@ -108,12 +179,57 @@ var (
 	// NewPromptTemplate creates a new template for generating prompts.
 	NewPromptTemplate = llm.NewPromptTemplate
 
+	// WithTemplateMaxTokens sets the size PromptTemplate.Validate allows the rendered prompt.
+	WithTemplateMaxTokens = llm.WithTemplateMaxTokens
+
+	// WithLocaleTemplate adds the variant of a template for a locale, such as "de" or "pt-BR".
+	WithLocaleTemplate = llm.WithLocaleTemplate
+
+	// WithExecuteLocale selects the locale variant of a template to execute, with a fallback chain.
+	WithExecuteLocale = llm.WithExecuteLocale
+
 	// WithPromptOptions adds multiple prompt options at once.
 	WithPromptOptions = llm.WithPromptOptions
 
+	// WithResponsePrefill sets the beginning of the assistant's reply, which the model continues.
+	WithResponsePrefill = llm.WithResponsePrefill
+
+	// WithGrammar constrains the output to a GBNF grammar on providers that support it.
+	WithGrammar = llm.WithGrammar
+
+	// WithRegexConstraint constrains the output to a regular expression on providers that support it.
+	WithRegexConstraint = llm.WithRegexConstraint
+
+	// WithReasoningEffort sets how much a reasoning model thinks, mapped to each provider's setting.
+	WithReasoningEffort = llm.WithReasoningEffort
+
 	// WithJSONSchemaValidation enables JSON schema validation.
 	WithJSONSchemaValidation = llm.WithJSONSchemaValidation
 
+	// WithStrictTools enables or disables strict mode for the tools of a request.
+	WithStrictTools = llm.WithStrictTools
+
+	// WithJSONMode selects between loose JSON mode and strict schema mode for GenerateWithSchema.
+	WithJSONMode = llm.WithJSONMode
+
+	// WithModel overrides the configured model for a single Generate call.
+	WithModel = llm.WithModel
+
+	// WithOption overrides a provider option for a single Generate call.
+	WithOption = llm.WithOption
+
+	// WithUser identifies the end user of a request for the provider's abuse monitoring.
+	WithUser = llm.WithUser
+
+	// WithRequestMetadata attaches key-value metadata to a request.
+	WithRequestMetadata = llm.WithRequestMetadata
+
+	// WithProviderPreferences sets OpenRouter's routing preferences for a request.
+	WithProviderPreferences = llm.WithProviderPreferences
+
+	// WithSafetySettings sets Gemini's safety filter thresholds for a request.
+	WithSafetySettings = llm.WithSafetySettings
+
 	// WithStream enables or disables streaming responses.
 	WithStream = config.WithStream
 )
@@ -125,7 +241,8 @@ var (
 //  3. Trims any remaining whitespace
 //
 // This is particularly useful when working with LLMs that return formatted markdown
-// or when you need to extract clean JSON from a response.
+// or when you need to extract clean JSON from a response. It is the CleanAggressive
+// strategy, which Generate can apply itself with SetCleanStrategy or WithCleanStrategy.
 //
 // Parameters:
 //   - response: The raw response string from the LLM
@@ -133,12 +250,6 @@ var (
 // Returns:
 //   - A cleaned string containing only the relevant content
 func CleanResponse(response string) string {
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimSuffix(response, "```")
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-	if start != -1 && end != -1 && end > start {
-		response = response[start : end+1]
-	}
-	return strings.TrimSpace(response)
+	cleaned, _ := llm.CleanText(response, config.CleanAggressive)
+	return cleaned
 }
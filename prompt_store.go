@@ -0,0 +1,41 @@
+// Package gollm provides versioned prompt management for Language Learning Models.
+// This file contains re-exports for storing prompt versions and rolling them out by environment.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// PromptStore stores named, versioned prompts and their rollout in each environment.
+	PromptStore = llm.PromptStore
+
+	// PromptVersion is an immutable version of a named prompt.
+	PromptVersion = llm.PromptVersion
+
+	// Rollout splits the requests of an environment between versions of a prompt.
+	Rollout = llm.Rollout
+
+	// RolloutVersion is the share of requests served by a version of a prompt.
+	RolloutVersion = llm.RolloutVersion
+
+	// PromptRef identifies the version of a prompt that produced a response.
+	PromptRef = llm.PromptRef
+
+	// PromptManager serves the prompts of a store in one environment according to their rollout.
+	PromptManager = llm.PromptManager
+)
+
+var (
+	// NewMemoryPromptStore creates a PromptStore that keeps prompts in memory.
+	NewMemoryPromptStore = llm.NewMemoryPromptStore
+
+	// NewFilePromptStore creates a PromptStore that keeps each prompt in a JSON file of a directory.
+	NewFilePromptStore = llm.NewFilePromptStore
+
+	// NewPromptManager creates a PromptManager serving the prompts of a store in an environment.
+	NewPromptManager = llm.NewPromptManager
+
+	// WithPromptVersion records the prompt version of a Generate call in the Response metadata.
+	WithPromptVersion = llm.WithPromptVersion
+)
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"strings"
 
 	"github.com/teilomillet/gollm/config"
@@ -17,10 +16,12 @@ import (
 // including structured output and system prompts.
 type AnthropicProvider struct {
 	apiKey       string                 // API key for authentication
+	baseURL      string                 // API base URL, changed with SetEndpoint
 	model        string                 // Model identifier (e.g., "claude-3-opus", "claude-3-sonnet")
 	extraHeaders map[string]string      // Additional HTTP headers
 	options      map[string]interface{} // Model-specific options
 	logger       utils.Logger           // Logger instance
+	caching      bool                   // Whether cache breakpoints are placed automatically
 }
 
 // NewAnthropicProvider creates a new Anthropic provider instance.
@@ -36,6 +37,7 @@ type AnthropicProvider struct {
 func NewAnthropicProvider(apiKey, model string, extraHeaders map[string]string) Provider {
 	provider := &AnthropicProvider{
 		apiKey:       apiKey,
+		baseURL:      "https://api.anthropic.com",
 		model:        model,
 		extraHeaders: make(map[string]string),
 		options:      make(map[string]interface{}),
@@ -77,9 +79,30 @@ func (p *AnthropicProvider) SetOption(key string, value interface{}) {
 func (p *AnthropicProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
 	}
+	if config.TopK != nil {
+		p.SetOption("top_k", *config.TopK)
+	}
+	p.caching = config.EnableCaching
+}
+
+// SupportsCacheBreakpoints reports whether caching is enabled, in which case
+// cache breakpoints are placed after the system prompt, after the
+// conversation history and after the stable beginning of the prompt text.
+func (p *AnthropicProvider) SupportsCacheBreakpoints() bool {
+	return p.caching
+}
+
+// automaticCaching reports whether cache breakpoints are placed for the
+// request: when caching is enabled in the configuration or with the
+// "enable_caching" option.
+func (p *AnthropicProvider) automaticCaching(options map[string]interface{}) bool {
+	if caching, ok := options["enable_caching"].(bool); ok {
+		return caching
+	}
+	return p.caching
 }
 
 // Name returns "anthropic" as the provider identifier.
@@ -88,9 +111,22 @@ func (p *AnthropicProvider) Name() string {
 }
 
 // Endpoint returns the Anthropic API endpoint URL.
-// For API version 2024-02-15, this is "https://api.anthropic.com/v1/messages".
+// For API version 2024-02-15, this is "https://api.anthropic.com/v1/messages",
+// unless changed with SetEndpoint.
 func (p *AnthropicProvider) Endpoint() string {
-	return "https://api.anthropic.com/v1/messages"
+	return p.baseURL + "/v1/messages"
+}
+
+// SetEndpoint points the provider at baseURL instead of the Anthropic API, such
+// as a Anthropic-compatible gateway, a proxy or a self-hosted endpoint. A trailing /v1 is accepted.
+func (p *AnthropicProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1")
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when Anthropic does not serve it.
+func (p *AnthropicProvider) ModelEndpoint() string {
+	return p.baseURL + "/v1/models/" + p.model
 }
 
 // SupportsJSONSchema indicates that Anthropic supports structured output
@@ -130,18 +166,34 @@ func (p *AnthropicProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *AnthropicProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	attachments := takeAttachments(options)
+	content, err := anthropicUserContent(prompt, attachments)
+	if err != nil {
+		return nil, err
+	}
+	system, history, err := anthropicMessages(takeMessages(options))
+	if err != nil {
+		return nil, err
+	}
+	caching := p.automaticCaching(options)
+	cachePrefix := takeCachePrefix(options)
+	effort := takeReasoningEffort(options)
+
 	requestBody := map[string]interface{}{
 		"model":      p.model,
-		"max_tokens": p.options["max_tokens"],
+		"max_tokens": p.maxTokens(options),
 		"system":     []map[string]interface{}{},
 		"messages":   []map[string]interface{}{},
 	}
 
-	// Handle system prompt
+	// Handle system prompt, followed by the system messages of the history
 	systemPrompt := ""
 	if sp, ok := options["system_prompt"].(string); ok && sp != "" {
 		systemPrompt = sp
 	}
+	if len(system) > 0 {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + strings.Join(system, "\n\n"))
+	}
 
 	// If we have tools, add tool usage instructions to the system prompt
 	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
@@ -186,41 +238,138 @@ func (p *AnthropicProvider) PrepareRequest(prompt string, options map[string]int
 				"type": "text",
 				"text": part,
 			}
-			if i > 0 {
-				systemMessage["cache_control"] = map[string]string{"type": "ephemeral"}
+			// With automatic caching, one breakpoint covers the whole system
+			// prompt and leaves the others for the conversation
+			if caching && i == len(parts)-1 || !caching && i > 0 {
+				ephemeralCache(systemMessage)
 			}
 			requestBody["system"] = append(requestBody["system"].([]map[string]interface{}), systemMessage)
 		}
 	}
 
-	// Handle user message with potential caching
+	// The stable beginning of the prompt text is cached apart from the input
+	if caching {
+		content = splitCachePrefix(content, cachePrefix)
+	}
 	userMessage := map[string]interface{}{
-		"role": "user",
-		"content": []map[string]interface{}{
-			{
-				"type": "text",
-				"text": prompt,
-			},
-		},
+		"role":    "user",
+		"content": content,
+	}
+
+	requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), history...)
+	stable := history
+	if len(history) == 0 || prompt != "" || len(attachments) > 0 {
+		requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), userMessage)
+	} else if len(history) > 0 {
+		// The last message of the history is the new user turn
+		stable = history[:len(history)-1]
+	}
+	if caching {
+		if len(stable) > 0 {
+			blocks := stable[len(stable)-1]["content"].([]map[string]interface{})
+			ephemeralCache(blocks[len(blocks)-1])
+		}
+		limitCacheBreakpoints(requestBody["system"].([]map[string]interface{}), requestBody["messages"].([]map[string]interface{}), maxAnthropicCacheBreakpoints)
 	}
 
-	// Add cache_control only if caching is enabled
-	if caching, ok := options["enable_caching"].(bool); ok && caching {
-		userMessage["content"].([]map[string]interface{})[0]["cache_control"] = map[string]string{"type": "ephemeral"}
+	// Claude continues a trailing assistant message
+	if prefill := takePrefill(options); prefill != "" {
+		requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), prefillMessage(prefill))
+	}
+	if metadata := anthropicMetadata(options); metadata != nil {
+		requestBody["metadata"] = metadata
 	}
 
-	requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), userMessage)
-
-	// Add other options
+	// Add the sampling defaults, then other options
+	for k, v := range samplingDefaults(p.options, options) {
+		requestBody[k] = v
+	}
 	for k, v := range options {
-		if k != "system_prompt" && k != "max_tokens" && k != "tools" && k != "tool_choice" && k != "enable_caching" {
+		if k != "system_prompt" && k != "max_tokens" && k != "tools" && k != "tool_choice" && k != "enable_caching" && k != "strict_tools" {
 			requestBody[k] = v
 		}
 	}
+	if effort != "" {
+		anthropicThinking(requestBody, effort)
+	}
 
 	return json.Marshal(requestBody)
 }
 
+// anthropicThinking enables extended thinking with the budget of effort.
+// The budget counts toward max_tokens, which is raised by it so the answer
+// keeps its room, and thinking rejects a custom temperature or top-k.
+func anthropicThinking(requestBody map[string]interface{}, effort ReasoningEffort) {
+	budget := effort.BudgetTokens()
+	requestBody["thinking"] = map[string]interface{}{
+		"type":          "enabled",
+		"budget_tokens": budget,
+	}
+	maxTokens, ok := requestBody["max_tokens"].(int)
+	if !ok || maxTokens <= 0 {
+		maxTokens = 1024
+	}
+	requestBody["max_tokens"] = budget + maxTokens
+	delete(requestBody, "temperature")
+	delete(requestBody, "top_k")
+}
+
+// SupportsReasoningEffort reports whether model supports extended thinking,
+// as Claude 3.7 Sonnet and the Claude 4 models do.
+func (p *AnthropicProvider) SupportsReasoningEffort(model string) bool {
+	return hasModelPrefix(model, "claude-3-7-sonnet", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4")
+}
+
+// ParseReasoning returns the text of the thinking blocks of a response with
+// extended thinking.
+func (p *AnthropicProvider) ParseReasoning(body []byte) string {
+	var response struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Thinking string `json:"thinking"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	var thinking []string
+	for _, block := range response.Content {
+		if block.Type == "thinking" && block.Thinking != "" {
+			thinking = append(thinking, block.Thinking)
+		}
+	}
+	return strings.Join(thinking, "\n\n")
+}
+
+// ImageLimits returns the limits of the Messages API: 100 images of 5 MB
+// each once base64-encoded.
+func (p *AnthropicProvider) ImageLimits() ImageLimits {
+	return ImageLimits{MaxImages: 100, MaxImageBytes: 5 << 20 * 3 / 4}
+}
+
+// SupportsPrefill reports that Claude's replies can be prefilled.
+func (p *AnthropicProvider) SupportsPrefill() bool {
+	return true
+}
+
+// SupportsMessages reports that conversation histories are sent as
+// messages. System messages are added to the system prompt.
+func (p *AnthropicProvider) SupportsMessages() bool {
+	return true
+}
+
+// maxTokens returns the max_tokens of the request, which the Messages API
+// requires: the request option, the provider default, or 1024.
+func (p *AnthropicProvider) maxTokens(options map[string]interface{}) interface{} {
+	if maxTokens, ok := options["max_tokens"]; ok && maxTokens != nil {
+		return maxTokens
+	}
+	if maxTokens, ok := p.options["max_tokens"]; ok && maxTokens != nil {
+		return maxTokens
+	}
+	return 1024
+}
+
 // Helper function to split the system prompt into a maximum of n parts
 func splitSystemPrompt(prompt string, n int) []string {
 	if n <= 1 {
@@ -272,17 +421,41 @@ func (p *AnthropicProvider) PrepareRequestWithSchema(prompt string, options map[
 	// Create a system message that enforces the JSON schema
 	systemMsg := fmt.Sprintf("You must respond with a JSON object that strictly adheres to this schema:\n%s\nDo not include any explanatory text, only output valid JSON.", string(schemaJSON))
 
+	attachments := takeAttachments(options)
+	content, err := anthropicUserContent(prompt, attachments)
+	if err != nil {
+		return nil, err
+	}
+	system, messages, err := anthropicMessages(takeMessages(options))
+	if err != nil {
+		return nil, err
+	}
+	takeCachePrefix(options)
+	if len(system) > 0 {
+		systemMsg = strings.Join(system, "\n\n") + "\n\n" + systemMsg
+	}
+	if len(messages) == 0 || prompt != "" || len(attachments) > 0 {
+		messages = append(messages, map[string]interface{}{"role": "user", "content": content})
+	}
+
 	requestBody := map[string]interface{}{
-		"model":  p.model,
-		"system": systemMsg,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"system":   systemMsg,
+		"messages": messages,
+	}
+	if prefill := takePrefill(options); prefill != "" {
+		requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), prefillMessage(prefill))
+	}
+	if metadata := anthropicMetadata(options); metadata != nil {
+		requestBody["metadata"] = metadata
 	}
 
-	// Add any additional options
+	// Add the sampling defaults and any additional options
+	for k, v := range samplingDefaults(p.options, options) {
+		requestBody[k] = v
+	}
 	for k, v := range options {
-		if k != "system_prompt" { // Skip system_prompt as we're using it for schema
+		if k != "system_prompt" && k != "enable_caching" { // Skip system_prompt as we're using it for schema
 			requestBody[k] = v
 		}
 	}
@@ -361,14 +534,13 @@ func (p *AnthropicProvider) ParseResponse(body []byte) (string, error) {
 				pendingText.Reset()
 			}
 
-			// Parse input as raw JSON to preserve the exact format
-			var args interface{}
-			if err := json.Unmarshal(content.Input, &args); err != nil {
+			args, err := toolArguments(content.Input)
+			if err != nil {
 				p.logger.Debug("Error parsing tool input: %v, raw input: %s", err, string(content.Input))
 				return "", fmt.Errorf("error parsing tool input: %w", err)
 			}
 
-			functionCall, err := utils.FormatFunctionCall(content.Name, args)
+			functionCall, err := formatToolCalls([]ToolCall{{ID: content.ID, Name: content.Name, Arguments: args}})
 			if err != nil {
 				p.logger.Debug("Error formatting function call: %v", err)
 				return "", fmt.Errorf("error formatting function call: %w", err)
@@ -405,24 +577,40 @@ func (p *AnthropicProvider) ParseResponse(body []byte) (string, error) {
 	return result, nil
 }
 
-// HandleFunctionCalls processes structured output in the response.
-// This supports Anthropic's response formatting capabilities.
-func (p *AnthropicProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	p.logger.Debug("Handling function calls from response")
-	response := string(body)
-
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
+// ParseToolCalls returns the tool_use blocks of an Anthropic response as
+// tool calls, in order.
+func (p *AnthropicProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	var response struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
 	}
 
-	if len(functionCalls) == 0 {
-		p.logger.Debug("No function calls found in the response")
-		return nil, nil
+	var toolCalls []ToolCall
+	for _, content := range response.Content {
+		if content.Type != "tool_use" {
+			continue
+		}
+		args, err := toolArguments(content.Input)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing input of %s: %w", content.Name, err)
+		}
+		toolCalls = append(toolCalls, ToolCall{ID: content.ID, Name: content.Name, Arguments: args})
 	}
+	return toolCalls, nil
+}
 
-	p.logger.Debug("Function calls to handle: %v", functionCalls)
-	return json.Marshal(functionCalls)
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *AnthropicProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -436,87 +624,115 @@ func (p *AnthropicProvider) SupportsStreaming() bool {
 	return true
 }
 
-// PrepareStreamRequest creates a request body for streaming API calls
+// PrepareStreamRequest creates a request body for streaming API calls. It
+// shares the messages, tools and options handling of PrepareRequest.
 func (p *AnthropicProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
-	requestBody := map[string]interface{}{
-		"model":  p.model,
-		"stream": true,
-		"messages": []map[string]interface{}{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"max_tokens": 1024, // Default max tokens
-	}
-
-	// Add system prompt if present
-	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
-		requestBody["system"] = systemPrompt
-		delete(options, "system_prompt")
-	}
-
-	// Add max tokens if present
-	if maxTokens, ok := options["max_tokens"].(int); ok {
-		requestBody["max_tokens"] = maxTokens
-		delete(options, "max_tokens")
-	}
-
-	// Add temperature if present
-	if temperature, ok := options["temperature"].(float64); ok {
-		requestBody["temperature"] = temperature
-		delete(options, "temperature")
-	}
-
-	// Add other options
-	for k, v := range options {
-		if k != "stream" { // Don't override stream setting
-			requestBody[k] = v
-		}
-	}
-
-	return json.Marshal(requestBody)
+	options["stream"] = true
+	return p.PrepareRequest(prompt, options)
 }
 
-// ParseStreamResponse processes a single chunk from a streaming response
+// ParseStreamResponse processes a single chunk from a streaming response. It
+// only reports text; tool calls are streamed by ParseStreamEvent.
 func (p *AnthropicProvider) ParseStreamResponse(chunk []byte) (string, error) {
-	// Skip empty lines
-	if len(bytes.TrimSpace(chunk)) == 0 {
-		return "", fmt.Errorf("empty chunk")
-	}
+	return streamText(p.ParseStreamEvent("", chunk))
+}
 
-	// Check for [DONE] marker
-	if bytes.Equal(bytes.TrimSpace(chunk), []byte("[DONE]")) {
-		return "", io.EOF
+// ParseStreamEvent translates a Messages API stream event into typed events.
+// Usage is reported twice: input tokens with message_start and output tokens
+// with message_delta. The input of a tool_use block is streamed as
+// input_json_delta fragments, and the call is complete when its block stops.
+func (p *AnthropicProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if isDoneMarker(data) {
+		return []StreamEvent{{Type: StreamEventDone}}, nil
 	}
 
-	// Parse the event
+	type anthropicUsage struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	}
 	var event struct {
-		Type  string `json:"type"`
-		Index int    `json:"index"`
-		Delta struct {
+		Type    string `json:"type"`
+		Index   int    `json:"index"`
+		Message struct {
+			Usage *anthropicUsage `json:"usage"`
+		} `json:"message"`
+		ContentBlock struct {
 			Type string `json:"type"`
-			Text string `json:"text"`
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"content_block"`
+		Delta struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			Thinking    string `json:"thinking"`
+			PartialJSON string `json:"partial_json"`
 		} `json:"delta"`
+		Usage *anthropicUsage `json:"usage"`
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("malformed event: %w", err)
 	}
 
-	if err := json.Unmarshal(chunk, &event); err != nil {
-		return "", fmt.Errorf("malformed event: %w", err)
+	usageEvent := func(u *anthropicUsage) []StreamEvent {
+		if u == nil {
+			return nil
+		}
+		return []StreamEvent{{Type: StreamEventUsage, Usage: &StreamUsage{
+			PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+			CompletionTokens: u.OutputTokens,
+			CachedTokens:     u.CacheReadInputTokens,
+			CacheWriteTokens: u.CacheCreationInputTokens,
+		}}}
 	}
 
-	// Handle different event types
 	switch event.Type {
+	case "message_start":
+		return usageEvent(event.Message.Usage), nil
+	case "content_block_start":
+		if event.ContentBlock.Type == "tool_use" {
+			return []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index: event.Index,
+				ID:    event.ContentBlock.ID,
+				Name:  event.ContentBlock.Name,
+			}}}, nil
+		}
 	case "content_block_delta":
-		if event.Delta.Type == "text_delta" {
-			if event.Delta.Text == "" {
-				return "", fmt.Errorf("skip token")
+		switch event.Delta.Type {
+		case "text_delta":
+			if event.Delta.Text != "" {
+				return []StreamEvent{{Type: StreamEventDelta, Text: event.Delta.Text}}, nil
+			}
+		case "thinking_delta":
+			if event.Delta.Thinking != "" {
+				return []StreamEvent{{Type: StreamEventReasoningDelta, Text: event.Delta.Thinking}}, nil
 			}
-			return event.Delta.Text, nil
+		case "input_json_delta":
+			return []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index:     event.Index,
+				Arguments: event.Delta.PartialJSON,
+			}}}, nil
 		}
-		return "", fmt.Errorf("skip token")
+	case "content_block_stop":
+		// The event does not name the block type; stops of other blocks
+		// match no tool call and are ignored
+		return []StreamEvent{{Type: StreamEventToolCallDone, ToolCall: &ToolCallDelta{Index: event.Index}}}, nil
+	case "message_delta":
+		return usageEvent(event.Usage), nil
 	case "message_stop":
-		return "", io.EOF
-	default:
-		return "", fmt.Errorf("skip token")
+		return []StreamEvent{{Type: StreamEventDone}}, nil
+	case "error":
+		if event.Error != nil {
+			return nil, fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+		}
 	}
+	return nil, nil
 }
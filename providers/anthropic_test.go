@@ -0,0 +1,353 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestAnthropicMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []Message
+		system   []string
+		messages string
+	}{
+		{
+			name: "system messages are returned apart",
+			history: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hi"},
+			},
+			system:   []string{"Be brief."},
+			messages: `[{"role":"user","content":[{"type":"text","text":"Hi"}]}]`,
+		},
+		{
+			name: "tool call and result",
+			history: []Message{
+				{Role: "assistant", Content: "Checking.", ToolCalls: []ToolCall{{ID: "toolu_1", Name: "get_weather", Arguments: json.RawMessage(`"{\"city\":\"Paris\"}"`)}}},
+				{Role: "tool", Content: "18C", ToolCallID: "toolu_1"},
+			},
+			messages: `[
+				{"role":"assistant","content":[
+					{"type":"text","text":"Checking."},
+					{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}
+				]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"18C"}]}
+			]`,
+		},
+		{
+			name: "cache breakpoint on the last block",
+			history: []Message{
+				{Role: "user", Content: "Long document", CacheType: "ephemeral"},
+			},
+			messages: `[{"role":"user","content":[{"type":"text","text":"Long document","cache_control":{"type":"ephemeral"}}]}]`,
+		},
+		{
+			name: "attachment without text",
+			history: []Message{
+				{Role: "user", Attachments: []utils.Attachment{{Type: utils.AttachmentImage, URL: "https://example.com/cat.png"}}},
+			},
+			messages: `[{"role":"user","content":[{"type":"image","source":{"type":"url","url":"https://example.com/cat.png"}}]}]`,
+		},
+		{
+			name:    "empty messages are skipped",
+			history: []Message{{Role: "assistant"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			system, messages, err := anthropicMessages(tt.history)
+			require.NoError(t, err)
+			assert.Equal(t, tt.system, system)
+			if tt.messages == "" {
+				assert.Empty(t, messages)
+				return
+			}
+			encoded, err := json.Marshal(messages)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.messages, string(encoded))
+		})
+	}
+
+	_, _, err := anthropicMessages([]Message{{Role: "assistant", ToolCalls: []ToolCall{{Name: "f", Arguments: json.RawMessage(`{"a":`)}}}})
+	assert.Error(t, err, "invalid tool call arguments")
+}
+
+func TestAnthropicPrepareRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  string
+		options map[string]interface{}
+		fields  map[string]string
+		absent  []string
+	}{
+		{
+			name:    "default max_tokens",
+			prompt:  "Hi",
+			options: map[string]interface{}{},
+			fields: map[string]string{
+				"max_tokens": `1024`,
+				"system":     `[]`,
+				"messages":   `[{"role":"user","content":[{"type":"text","text":"Hi"}]}]`,
+			},
+		},
+		{
+			name:   "system prompt and history system messages",
+			prompt: "Hi",
+			options: map[string]interface{}{
+				"system_prompt":  "Be brief.",
+				"enable_caching": false,
+				"messages":       []Message{{Role: "system", Content: "Answer in French."}},
+			},
+			fields: map[string]string{
+				"system": `[
+					{"type":"text","text":"Be brief."},
+					{"type":"text","text":"Answer in French.","cache_control":{"type":"ephemeral"}}
+				]`,
+			},
+			absent: []string{"system_prompt", "enable_caching"},
+		},
+		{
+			name:   "one tool",
+			prompt: "Weather?",
+			options: map[string]interface{}{"tools": []utils.Tool{{Type: "function", Function: utils.Function{
+				Name: "get_weather", Description: "Get the weather", Parameters: map[string]interface{}{"type": "object"},
+			}}}},
+			fields: map[string]string{
+				"tools":       `[{"name":"get_weather","description":"Get the weather","input_schema":{"type":"object"}}]`,
+				"tool_choice": `{"type":"auto"}`,
+				"system":      `[]`,
+			},
+		},
+		{
+			name:    "prefill",
+			prompt:  "List colors",
+			options: map[string]interface{}{"response_prefill": "1."},
+			fields: map[string]string{
+				"messages": `[
+					{"role":"user","content":[{"type":"text","text":"List colors"}]},
+					{"role":"assistant","content":"1."}
+				]`,
+			},
+			absent: []string{"response_prefill"},
+		},
+		{
+			name:    "user as metadata user_id",
+			prompt:  "Hi",
+			options: map[string]interface{}{"user": "user-1", "metadata": map[string]string{"team": "search"}},
+			fields:  map[string]string{"metadata": `{"user_id":"user-1"}`},
+			absent:  []string{"user"},
+		},
+		{
+			name:   "reasoning effort",
+			prompt: "Prove it",
+			options: map[string]interface{}{
+				"reasoning_effort": ReasoningLow,
+				"max_tokens":       1000,
+				"temperature":      0.5,
+			},
+			fields: map[string]string{
+				"thinking":   `{"type":"enabled","budget_tokens":1024}`,
+				"max_tokens": `2024`,
+			},
+			absent: []string{"temperature", "reasoning_effort"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil)
+			body, err := p.PrepareRequest(tt.prompt, tt.options)
+			require.NoError(t, err)
+			for key, want := range tt.fields {
+				assert.JSONEq(t, want, requestField(t, body, key), key)
+			}
+			request := decodeRequest(t, body)
+			for _, key := range tt.absent {
+				assert.NotContains(t, request, key)
+			}
+		})
+	}
+}
+
+func TestAnthropicPrepareRequestToolUsagePrompt(t *testing.T) {
+	tool := func(name string) utils.Tool {
+		return utils.Tool{Type: "function", Function: utils.Function{Name: name}}
+	}
+	p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil)
+	body, err := p.PrepareRequest("Hi", map[string]interface{}{
+		"tools":       []utils.Tool{tool("a"), tool("b")},
+		"tool_choice": "any",
+	})
+	require.NoError(t, err)
+
+	var request struct {
+		System []struct {
+			Text string `json:"text"`
+		} `json:"system"`
+	}
+	require.NoError(t, json.Unmarshal(body, &request))
+	require.Len(t, request.System, 1)
+	assert.Contains(t, request.System[0].Text, "identify all required tools upfront")
+	assert.JSONEq(t, `{"type":"any"}`, requestField(t, body, "tool_choice"))
+}
+
+func TestAnthropicPrepareRequestCacheBreakpoints(t *testing.T) {
+	t.Run("system prompt, history and prompt prefix", func(t *testing.T) {
+		p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil)
+		body, err := p.PrepareRequest("Context: docs\n\nQuestion?", map[string]interface{}{
+			"enable_caching": true,
+			"system_prompt":  "Rules.\n\nMore rules.",
+			"cache_prefix":   "Context: docs\n\n",
+			"messages": []Message{
+				{Role: "user", Content: "Q1"},
+				{Role: "assistant", Content: "A1"},
+			},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"type":"text","text":"Rules."},
+			{"type":"text","text":"More rules.","cache_control":{"type":"ephemeral"}}
+		]`, requestField(t, body, "system"))
+		assert.JSONEq(t, `[
+			{"role":"user","content":[{"type":"text","text":"Q1"}]},
+			{"role":"assistant","content":[{"type":"text","text":"A1","cache_control":{"type":"ephemeral"}}]},
+			{"role":"user","content":[
+				{"type":"text","text":"Context: docs\n\n","cache_control":{"type":"ephemeral"}},
+				{"type":"text","text":"Question?"}
+			]}
+		]`, requestField(t, body, "messages"))
+		assert.NotContains(t, decodeRequest(t, body), "cache_prefix")
+	})
+
+	t.Run("earliest breakpoints over the limit are removed", func(t *testing.T) {
+		p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil)
+		body, err := p.PrepareRequest("Q", map[string]interface{}{
+			"enable_caching": true,
+			"system_prompt":  "Rules.",
+			"messages": []Message{
+				{Role: "user", Content: "m1", CacheType: "ephemeral"},
+				{Role: "assistant", Content: "m2", CacheType: "ephemeral"},
+				{Role: "user", Content: "m3", CacheType: "ephemeral"},
+				{Role: "assistant", Content: "m4"},
+			},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"role":"user","content":[{"type":"text","text":"m1"}]},
+			{"role":"assistant","content":[{"type":"text","text":"m2","cache_control":{"type":"ephemeral"}}]},
+			{"role":"user","content":[{"type":"text","text":"m3","cache_control":{"type":"ephemeral"}}]},
+			{"role":"assistant","content":[{"type":"text","text":"m4","cache_control":{"type":"ephemeral"}}]},
+			{"role":"user","content":[{"type":"text","text":"Q"}]}
+		]`, requestField(t, body, "messages"))
+	})
+}
+
+func TestAnthropicParseStreamEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		events []StreamEvent
+		err    bool
+	}{
+		{name: "empty", data: ""},
+		{name: "ping", data: `{"type":"ping"}`},
+		{
+			name: "message_start usage",
+			data: `{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_creation_input_tokens":3,"cache_read_input_tokens":5,"output_tokens":1}}}`,
+			events: []StreamEvent{{Type: StreamEventUsage, Usage: &StreamUsage{
+				PromptTokens: 18, CompletionTokens: 1, CachedTokens: 5, CacheWriteTokens: 3,
+			}}},
+		},
+		{
+			name:   "text delta",
+			data:   `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			events: []StreamEvent{{Type: StreamEventDelta, Text: "Hello"}},
+		},
+		{
+			name:   "thinking delta",
+			data:   `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Hmm"}}`,
+			events: []StreamEvent{{Type: StreamEventReasoningDelta, Text: "Hmm"}},
+		},
+		{
+			name:   "text block start",
+			data:   `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			events: nil,
+		},
+		{
+			name: "tool_use block start",
+			data: `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+			events: []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index: 1, ID: "toolu_1", Name: "get_weather",
+			}}},
+		},
+		{
+			name: "input_json_delta",
+			data: `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			events: []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index: 1, Arguments: `{"city":`,
+			}}},
+		},
+		{
+			name:   "content_block_stop",
+			data:   `{"type":"content_block_stop","index":1}`,
+			events: []StreamEvent{{Type: StreamEventToolCallDone, ToolCall: &ToolCallDelta{Index: 1}}},
+		},
+		{
+			name:   "message_delta usage",
+			data:   `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+			events: []StreamEvent{{Type: StreamEventUsage, Usage: &StreamUsage{CompletionTokens: 7}}},
+		},
+		{name: "message_stop", data: `{"type":"message_stop"}`, events: []StreamEvent{{Type: StreamEventDone}}},
+		{name: "done marker", data: "[DONE]", events: []StreamEvent{{Type: StreamEventDone}}},
+		{name: "error", data: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, err: true},
+		{name: "malformed", data: `{"type":`, err: true},
+	}
+
+	p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil).(StreamEventParser)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := p.ParseStreamEvent("", []byte(tt.data))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
+
+func TestAnthropicParseToolCalls(t *testing.T) {
+	body := []byte(`{"content":[
+		{"type":"text","text":"Let me check."},
+		{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris","days":2}},
+		{"type":"tool_use","id":"toolu_2","name":"get_time","input":{}}
+	],"stop_reason":"tool_use"}`)
+	p := NewAnthropicProvider("key", "claude-sonnet-4-0", nil)
+
+	calls, err := p.(ToolCallParser).ParseToolCalls(body)
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCall{
+		{ID: "toolu_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris","days":2}`)},
+		{ID: "toolu_2", Name: "get_time", Arguments: json.RawMessage(`{}`)},
+	}, calls)
+
+	text, err := p.ParseResponse(body)
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check.\n"+
+		`<function_call>{"arguments":{"city":"Paris","days":2},"name":"get_weather"}</function_call>`+"\n"+
+		`<function_call>{"arguments":{},"name":"get_time"}</function_call>`, text)
+
+	handled, err := p.HandleFunctionCalls(body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id":"toolu_1","name":"get_weather","arguments":{"city":"Paris","days":2}},
+		{"id":"toolu_2","name":"get_time","arguments":{}}
+	]`, string(handled))
+}
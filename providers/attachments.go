@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// ImageLimits are the limits an API sets on the images of a request. Zero
+// fields are unlimited.
+type ImageLimits struct {
+	// MaxImages is the number of images a request may carry, counting the
+	// images of its conversation history.
+	MaxImages int
+
+	// MaxImageBytes is the size of an image before base64 encoding.
+	MaxImageBytes int64
+
+	// InlineOnly is true when images must be sent as data rather than by
+	// URL.
+	InlineOnly bool
+}
+
+// ImageLimitsProvider is implemented by providers whose API limits the
+// images of a request, so that prompts over the limits are rejected before
+// they are sent rather than with an opaque error of the API.
+type ImageLimitsProvider interface {
+	ImageLimits() ImageLimits
+}
+
+// takeAttachments removes the prompt attachments from options so they are
+// not sent as a top-level request field, and returns them.
+func takeAttachments(options map[string]interface{}) []utils.Attachment {
+	attachments, _ := options["attachments"].([]utils.Attachment)
+	delete(options, "attachments")
+	return attachments
+}
+
+// openAIUserContent builds the content of an OpenAI-style user message: the
+// plain prompt when there are no attachments, or text, image_url and file
+// parts otherwise. Providers that do not accept file parts pass allowFiles false.
+func openAIUserContent(provider, prompt string, attachments []utils.Attachment, allowFiles bool) (interface{}, error) {
+	if len(attachments) == 0 {
+		return prompt, nil
+	}
+
+	parts := []map[string]interface{}{{"type": "text", "text": prompt}}
+	for _, a := range attachments {
+		switch {
+		case a.Type == utils.AttachmentImage:
+			url, err := a.DataURL()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": url},
+			})
+		case allowFiles && a.URL == "":
+			url, err := a.DataURL()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, map[string]interface{}{
+				"type": "file",
+				"file": map[string]interface{}{"filename": a.Name(), "file_data": url},
+			})
+		default:
+			return nil, unsupportedAttachment(provider, a)
+		}
+	}
+	return parts, nil
+}
+
+// anthropicUserContent builds the content blocks of an Anthropic user
+// message, with images and PDF documents before the prompt text.
+func anthropicUserContent(prompt string, attachments []utils.Attachment) ([]map[string]interface{}, error) {
+	content := make([]map[string]interface{}, 0, len(attachments)+1)
+	for _, a := range attachments {
+		blockType := "image"
+		if a.Type == utils.AttachmentFile {
+			blockType = "document"
+		}
+
+		source := map[string]interface{}{"type": "url", "url": a.URL}
+		if a.URL == "" {
+			data, mediaType, err := a.Base64()
+			if err != nil {
+				return nil, err
+			}
+			source = map[string]interface{}{"type": "base64", "media_type": mediaType, "data": data}
+		}
+		content = append(content, map[string]interface{}{"type": blockType, "source": source})
+	}
+	return append(content, map[string]interface{}{"type": "text", "text": prompt}), nil
+}
+
+func unsupportedAttachment(provider string, a utils.Attachment) error {
+	if a.URL != "" {
+		return fmt.Errorf("%s does not support %s attachments by URL: %s", provider, a.Type, a.URL)
+	}
+	return fmt.Errorf("%s does not support %s attachments", provider, a.Type)
+}
@@ -0,0 +1,66 @@
+package providers
+
+// CacheBreakpointProvider is implemented by providers with prompt caching
+// that place cache breakpoints themselves. When SupportsCacheBreakpoints
+// reports true, requests carry the stable beginning of the prompt text, such
+// as its context and documents, under the "cache_prefix" option so that it is
+// cached separately from the input that follows.
+type CacheBreakpointProvider interface {
+	SupportsCacheBreakpoints() bool
+}
+
+// maxAnthropicCacheBreakpoints is the number of blocks with cache_control
+// the Messages API accepts in a request.
+const maxAnthropicCacheBreakpoints = 4
+
+// takeCachePrefix removes the stable prompt prefix from options so it is not
+// sent as a top-level request field, and returns it.
+func takeCachePrefix(options map[string]interface{}) string {
+	prefix, _ := options["cache_prefix"].(string)
+	delete(options, "cache_prefix")
+	return prefix
+}
+
+// ephemeralCache marks a content block as a cache breakpoint.
+func ephemeralCache(block map[string]interface{}) {
+	block["cache_control"] = map[string]string{"type": "ephemeral"}
+}
+
+// splitCachePrefix splits the text block ending content at prefix and marks
+// the prefix as a cache breakpoint, so the stable beginning of the prompt is
+// cached apart from the input. content is returned unchanged when its text
+// does not start with prefix or has nothing after it.
+func splitCachePrefix(content []map[string]interface{}, prefix string) []map[string]interface{} {
+	last := content[len(content)-1]
+	text, _ := last["text"].(string)
+	if prefix == "" || len(text) <= len(prefix) || text[:len(prefix)] != prefix {
+		return content
+	}
+	stable := map[string]interface{}{"type": "text", "text": prefix}
+	ephemeralCache(stable)
+	volatile := map[string]interface{}{"type": "text", "text": text[len(prefix):]}
+	return append(content[:len(content)-1:len(content)-1], stable, volatile)
+}
+
+// limitCacheBreakpoints removes the earliest cache breakpoints of messages
+// until system and messages hold at most limit. Later breakpoints cache the
+// whole prefix before them, so the earliest ones matter least.
+func limitCacheBreakpoints(system, messages []map[string]interface{}, limit int) {
+	var marked []map[string]interface{}
+	for _, block := range system {
+		if _, ok := block["cache_control"]; ok {
+			limit--
+		}
+	}
+	for _, message := range messages {
+		blocks, _ := message["content"].([]map[string]interface{})
+		for _, block := range blocks {
+			if _, ok := block["cache_control"]; ok {
+				marked = append(marked, block)
+			}
+		}
+	}
+	for i := 0; i < len(marked)-max(limit, 0); i++ {
+		delete(marked[i], "cache_control")
+	}
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelCapabilities describes the features a model supports beyond plain
+// text generation.
+type ModelCapabilities struct {
+	// Tools is true when the model supports function/tool calling.
+	Tools bool `json:"tools"`
+
+	// JSONMode is true when the model can be forced to emit a JSON object.
+	JSONMode bool `json:"json_mode"`
+
+	// JSONSchema is true when the model natively enforces a JSON schema.
+	JSONSchema bool `json:"json_schema"`
+
+	// Vision is true when the model accepts image inputs.
+	Vision bool `json:"vision"`
+}
+
+var (
+	capabilitiesMu sync.RWMutex
+	// capabilities is keyed by provider, then by model name prefix; the
+	// longest matching prefix wins.
+	capabilities = map[string]map[string]ModelCapabilities{
+		"openai": {
+			"gpt-4o":        {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"gpt-4.1":       {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"gpt-4-turbo":   {Tools: true, JSONMode: true, Vision: true},
+			"gpt-3.5-turbo": {Tools: true, JSONMode: true},
+			"o1":            {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"o1-mini":       {},
+			"o1-preview":    {},
+			"o3":            {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"o3-mini":       {Tools: true, JSONMode: true, JSONSchema: true},
+			"o4-mini":       {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+		},
+		"groq": {
+			"llama-3.1-8b-instant":                      {Tools: true, JSONMode: true},
+			"llama-3.3-70b-versatile":                   {Tools: true, JSONMode: true},
+			"gemma2-9b-it":                              {Tools: true, JSONMode: true},
+			"deepseek-r1-distill-llama-70b":             {Tools: true, JSONMode: true},
+			"qwen/qwen3-32b":                            {Tools: true, JSONMode: true},
+			"meta-llama/llama-4-scout-17b-16e-instruct": {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"meta-llama/llama-4-maverick":               {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"moonshotai/kimi-k2-instruct":               {Tools: true, JSONMode: true, JSONSchema: true},
+			"openai/gpt-oss":                            {Tools: true, JSONMode: true, JSONSchema: true},
+		},
+		"mistral": {
+			"mistral-large":     {Tools: true, JSONMode: true, JSONSchema: true},
+			"mistral-medium":    {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"mistral-small":     {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"ministral":         {Tools: true, JSONMode: true, JSONSchema: true},
+			"open-mistral-nemo": {Tools: true, JSONMode: true, JSONSchema: true},
+			"pixtral":           {Tools: true, JSONMode: true, JSONSchema: true, Vision: true},
+			"codestral":         {Tools: true, JSONMode: true, JSONSchema: true},
+		},
+		"deepseek": {
+			"deepseek-chat":     {Tools: true, JSONMode: true},
+			"deepseek-reasoner": {Tools: true, JSONMode: true},
+		},
+	}
+)
+
+// RegisterModelCapabilities declares the capabilities of a provider's model,
+// or of every model whose name starts with model. It overrides the built-in
+// entry, if any.
+func RegisterModelCapabilities(provider, model string, caps ModelCapabilities) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if capabilities[provider] == nil {
+		capabilities[provider] = make(map[string]ModelCapabilities)
+	}
+	capabilities[provider][model] = caps
+}
+
+// LookupModelCapabilities returns the capabilities of a provider's model,
+// matching the longest registered name prefix. A fine-tuned model without
+// an entry of its own, such as "ft:gpt-4o-mini-2024-07-18:acme::9xyz",
+// inherits the capabilities of its base model.
+func LookupModelCapabilities(provider, model string) (ModelCapabilities, bool) {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+
+	models := capabilities[provider]
+	for _, name := range []string{model, BaseModel(model)} {
+		var best string
+		for prefix := range models {
+			if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+		if best != "" {
+			return models[best], true
+		}
+	}
+	return ModelCapabilities{}, false
+}
+
+// BaseModel returns the model an OpenAI fine-tuned model name, of the form
+// "ft:<base>:<organization>:<suffix>:<id>", was trained from. Other names
+// are returned unchanged.
+func BaseModel(model string) string {
+	rest, ok := strings.CutPrefix(model, "ft:")
+	if !ok {
+		return model
+	}
+	base, _, _ := strings.Cut(rest, ":")
+	return base
+}
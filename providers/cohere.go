@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -14,6 +15,7 @@ import (
 // including chat completion and structured output
 type CohereProvider struct {
 	apiKey       string            // API key for authentication
+	baseURL      string            // API base URL, changed with SetEndpoint
 	model        string            // Model identifier (e.g., "command-r-plus-08-2024", "command-r-plus-04-2024")
 	extraHeaders map[string]string // Additional HTTP headers
 	options      map[string]any    // Model-specific options
@@ -37,6 +39,7 @@ func NewCohereProvider(apiKey, model string, extraHeaders map[string]string) Pro
 
 	return &CohereProvider{
 		apiKey:       apiKey,
+		baseURL:      "https://api.cohere.com",
 		model:        model,
 		extraHeaders: extraHeaders,
 		options:      make(map[string]any),
@@ -73,6 +76,12 @@ func (p *CohereProvider) SetDefaultOptions(config *config.Config) {
 	if config.Seed != nil {
 		p.SetOption("seed", *config.Seed)
 	}
+	if config.TopPSet() {
+		p.SetOption("p", config.TopP)
+	}
+	if config.TopK != nil {
+		p.SetOption("k", *config.TopK)
+	}
 }
 
 // Name returns "cohere" as the provider identifier.
@@ -80,10 +89,22 @@ func (p *CohereProvider) Name() string {
 	return "cohere"
 }
 
-// Endpoint returns the base URL for the Cohere API.
-// This is "https://api.cohere.com/v2/chat".
+// Endpoint returns the URL of Cohere's v2 chat API.
+// This is "https://api.cohere.com/v2/chat" unless changed with SetEndpoint.
 func (p *CohereProvider) Endpoint() string {
-	return "https://api.cohere.com/v2/chat"
+	return p.baseURL + "/v2/chat"
+}
+
+// SetEndpoint points the provider at baseURL instead of the Cohere API, such
+// as a Cohere-compatible gateway, a proxy or a self-hosted endpoint. A trailing /v1 or /v2 is accepted.
+func (p *CohereProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1", "/v2")
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when Cohere does not serve it.
+func (p *CohereProvider) ModelEndpoint() string {
+	return p.baseURL + "/v1/models/" + p.model
 }
 
 // SupportsJSONSchema indicates that Cohere supports structured output
@@ -124,28 +145,11 @@ func (p *CohereProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *CohereProvider) PrepareRequest(prompt string, options map[string]any) ([]byte, error) {
-	requestBody := map[string]any{
-		"model": p.model,
-		"messages": []map[string]any{
-			{"role": "user", "content": prompt},
-		},
-	}
-
-	// First, add default options
-	for k, v := range p.options {
-		requestBody[k] = v
-	}
-
-	// Then, add any additional options (which may override defaults)
-	for k, v := range options {
-		requestBody[k] = v
-	}
-
-	return json.Marshal(requestBody)
+	return p.prepareRequest(prompt, options, nil)
 }
 
 // PrepareRequestWithSchema creates a request that includes structured output formatting.
-// This uses Cohere's system prompts to enforce response structure.
+// This uses Cohere's json_object response format with the schema attached.
 //
 // Parameters:
 //   - prompt: The input text or conversation
@@ -156,30 +160,109 @@ func (p *CohereProvider) PrepareRequest(prompt string, options map[string]any) (
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *CohereProvider) PrepareRequestWithSchema(prompt string, options map[string]any, schema any) ([]byte, error) {
+	responseFormat := map[string]any{"type": "json_object"}
+	if takeJSONMode(options) != JSONModeObject {
+		responseFormat["json_schema"] = schema
+	}
+	return p.prepareRequest(prompt, options, responseFormat)
+}
+
+// SupportsJSONMode reports that Cohere offers both JSON modes: a
+// json_object response format without a schema, or with one it enforces.
+func (p *CohereProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema
+}
+
+// prepareRequest builds a v2 chat request with the system prompt, tools and
+// grounding documents taken from options.
+func (p *CohereProvider) prepareRequest(prompt string, options map[string]any, responseFormat map[string]any) ([]byte, error) {
+	if attachments := takeAttachments(options); len(attachments) > 0 {
+		return nil, unsupportedAttachment(p.Name(), attachments[0])
+	}
+
+	var messages []map[string]any
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, map[string]any{"role": "user", "content": prompt})
+
 	requestBody := map[string]any{
-		"model": p.model,
-		"messages": []map[string]any{
-			{"role": "user", "content": prompt},
-		},
-		"response_format": map[string]any{
-			"type":        "json_object",
-			"json_schema": schema,
-		},
+		"model":    p.model,
+		"messages": messages,
+	}
+	if responseFormat != nil {
+		requestBody["response_format"] = responseFormat
 	}
 
-	// First, add the default options
-	for k, v := range p.options {
-		requestBody[k] = v
+	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		cohereTools := make([]map[string]any, len(tools))
+		for i, tool := range tools {
+			cohereTools[i] = map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name":        tool.Function.Name,
+					"description": tool.Function.Description,
+					"parameters":  tool.Function.Parameters,
+				},
+			}
+		}
+		requestBody["tools"] = cohereTools
+	}
+	// v2 only accepts REQUIRED and NONE; "auto" is the default behaviour
+	if toolChoice, ok := options["tool_choice"].(string); ok {
+		switch strings.ToLower(toolChoice) {
+		case "required", "any":
+			requestBody["tool_choice"] = "REQUIRED"
+		case "none":
+			requestBody["tool_choice"] = "NONE"
+		}
 	}
 
-	// Then, add any additional options (which may override defaults)
-	for k, v := range options {
-		requestBody[k] = v
+	if docs, ok := options["documents"].([]utils.Document); ok && len(docs) > 0 {
+		requestBody["documents"] = cohereDocuments(docs)
+	}
+
+	// First, add default options, then any additional options (which may override defaults)
+	for _, opts := range []map[string]any{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "system_prompt", "tools", "tool_choice", "documents", "strict_tools", "user", "metadata":
+			default:
+				requestBody[k] = v
+			}
+		}
 	}
 
 	return json.Marshal(requestBody)
 }
 
+// cohereDocuments converts documents to the v2 format, where the text and
+// any other fields go in the document's data.
+func cohereDocuments(docs []utils.Document) []map[string]any {
+	result := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		data := map[string]any{"text": doc.Text}
+		if doc.Title != "" {
+			data["title"] = doc.Title
+		}
+		for k, v := range doc.Fields {
+			data[k] = v
+		}
+		entry := map[string]any{"data": data}
+		if doc.ID != "" {
+			entry["id"] = doc.ID
+		}
+		result[i] = entry
+	}
+	return result
+}
+
+// SupportsDocuments reports that Cohere grounds responses in documents
+// natively and returns citations for them.
+func (p *CohereProvider) SupportsDocuments() bool {
+	return true
+}
+
 // ParseResponse extracts the generated text from the Cohere API response.
 // It handles various response formats and error cases
 //
@@ -212,7 +295,7 @@ func (p *CohereProvider) ParseResponse(body []byte) (string, error) {
 		return "", fmt.Errorf("error parsing response: %w", err)
 	}
 
-	if len(response.Message.Content) == 0 {
+	if len(response.Message.Content) == 0 && len(response.Message.ToolCalls) == 0 {
 		return "", fmt.Errorf("empty response from API")
 	}
 
@@ -226,41 +309,85 @@ func (p *CohereProvider) ParseResponse(body []byte) (string, error) {
 		}
 	}
 
-	for _, toolCall := range response.Message.ToolCalls {
-		// Parse arguments as raw JSON to preserve the exact format
-		var args interface{}
-		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-			return "", fmt.Errorf("error parsing function arguments: %w", err)
+	if len(response.Message.ToolCalls) > 0 {
+		calls, err := p.ParseToolCalls(body)
+		if err != nil {
+			return "", err
 		}
-
-		functionCall, err := utils.FormatFunctionCall(toolCall.Function.Name, args)
+		functionCalls, err := formatToolCalls(calls)
 		if err != nil {
-			return "", fmt.Errorf("error formatting function call: %w", err)
+			return "", err
 		}
 		if finalResponse.Len() > 0 {
 			finalResponse.WriteString("\n")
 		}
-		finalResponse.WriteString(functionCall)
+		finalResponse.WriteString(functionCalls)
 	}
 
 	p.logger.Debug("Final response: %s", finalResponse.String())
 	return finalResponse.String(), nil
 }
 
-// HandleFunctionCalls processes structured output in the response.
-// This supports Cohere's response formatting capabilities.
-func (p *CohereProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	response := string(body)
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
+// Citation links a span of the response text to the documents supporting it.
+type Citation struct {
+	// Start and End are the character offsets of the cited span.
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// Text is the cited span.
+	Text string `json:"text"`
+
+	// DocumentIDs identifies the supporting documents.
+	DocumentIDs []string `json:"document_ids"`
+}
+
+// ParseMetadata returns the citations of a grounded response under the
+// "citations" key as a []Citation.
+func (p *CohereProvider) ParseMetadata(body []byte) map[string]interface{} {
+	var response struct {
+		Message struct {
+			Citations []struct {
+				Start   int    `json:"start"`
+				End     int    `json:"end"`
+				Text    string `json:"text"`
+				Sources []struct {
+					ID string `json:"id"`
+				} `json:"sources"`
+			} `json:"citations"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Message.Citations) == 0 {
+		return nil
+	}
+
+	citations := make([]Citation, len(response.Message.Citations))
+	for i, c := range response.Message.Citations {
+		citations[i] = Citation{Start: c.Start, End: c.End, Text: c.Text}
+		for _, source := range c.Sources {
+			citations[i].DocumentIDs = append(citations[i].DocumentIDs, source.ID)
+		}
 	}
+	return map[string]interface{}{"citations": citations}
+}
 
-	if len(functionCalls) == 0 {
-		return nil, nil // No function calls found
+// ParseToolCalls returns every tool call of a Cohere v2 chat response.
+func (p *CohereProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	var response struct {
+		Message struct {
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
 	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	return convertOpenAIToolCalls(response.Message.ToolCalls)
+}
 
-	return json.Marshal(functionCalls)
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *CohereProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -283,11 +410,64 @@ func (p *CohereProvider) PrepareStreamRequest(prompt string, options map[string]
 
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *CohereProvider) ParseStreamResponse(chunk []byte) (string, error) {
-	var response struct {
-		Text string `json:"text"`
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a v2 chat stream event into typed events.
+func (p *CohereProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var event struct {
+		Type  string `json:"type"`
+		Index int    `json:"index"`
+		Delta struct {
+			Message struct {
+				Content struct {
+					Text string `json:"text"`
+				} `json:"content"`
+				ToolCalls struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			Usage struct {
+				BilledUnits *struct {
+					InputTokens  int `json:"input_tokens"`
+					OutputTokens int `json:"output_tokens"`
+				} `json:"billed_units"`
+			} `json:"usage"`
+		} `json:"delta"`
 	}
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", err
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case "content-delta":
+		if text := event.Delta.Message.Content.Text; text != "" {
+			return []StreamEvent{{Type: StreamEventDelta, Text: text}}, nil
+		}
+	case "tool-call-start", "tool-call-delta":
+		call := event.Delta.Message.ToolCalls
+		return []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+			Index:     event.Index,
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		}}}, nil
+	case "message-end":
+		var events []StreamEvent
+		if units := event.Delta.Usage.BilledUnits; units != nil {
+			events = append(events, StreamEvent{Type: StreamEventUsage, Usage: &StreamUsage{
+				PromptTokens:     units.InputTokens,
+				CompletionTokens: units.OutputTokens,
+			}})
+		}
+		return append(events, StreamEvent{Type: StreamEventDone}), nil
 	}
-	return response.Text, nil
+	return nil, nil
 }
@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// completionServer is an OpenAI-compatible server that also serves the
+// legacy completions endpoint, used for raw completion and scoring.
+type completionServer struct {
+	localServer
+}
+
+// CompletionEndpoint returns the raw text completions endpoint of the server.
+func (p *completionServer) CompletionEndpoint() string {
+	return p.baseURL + "/v1/completions"
+}
+
+// PrepareCompletionRequest creates a completions request continuing text.
+func (p *completionServer) PrepareCompletionRequest(text string, options map[string]interface{}) ([]byte, error) {
+	return openAICompletionRequest(p.model, text, p.options, options)
+}
+
+// ParseCompletionResponse extracts the continuation from a completions response.
+func (p *completionServer) ParseCompletionResponse(body []byte) (string, error) {
+	return parseOpenAICompletion(body)
+}
+
+// ScoringEndpoint returns the completions endpoint, which echoes the
+// scored text with log probabilities.
+func (p *completionServer) ScoringEndpoint() string {
+	return p.CompletionEndpoint()
+}
+
+// PrepareScoringRequest creates a completions request echoing text with the
+// log probability of each token.
+func (p *completionServer) PrepareScoringRequest(text string, options map[string]interface{}) ([]byte, error) {
+	return openAIScoringRequest(p.model, text, p.options, options)
+}
+
+// ParseScoringResponse extracts the echoed tokens with their log probabilities.
+func (p *completionServer) ParseScoringResponse(body []byte) ([]TokenLogprob, error) {
+	return parseOpenAIScoring(body)
+}
+
+// openAICompletionRequest creates a request for the legacy completions API
+// of OpenAI-compatible servers, sending the provider defaults and the request
+// options except those that only apply to chat completions.
+func openAICompletionRequest(model, text string, defaults, options map[string]interface{}) ([]byte, error) {
+	return json.Marshal(openAICompletionBody(model, text, defaults, options))
+}
+
+// openAICompletionBody returns the fields of a legacy completions request.
+func openAICompletionBody(model, text string, defaults, options map[string]interface{}) map[string]interface{} {
+	requestBody := map[string]interface{}{
+		"model":  model,
+		"prompt": text,
+	}
+	for _, opts := range []map[string]interface{}{defaults, options} {
+		for k, v := range opts {
+			switch k {
+			case "system_prompt", "tools", "tool_choice", "strict_tools", "response_format", "stream", "provider_preferences", "safety_settings":
+			default:
+				requestBody[k] = v
+			}
+		}
+	}
+	return requestBody
+}
+
+// parseOpenAICompletion extracts the text of the first choice of a legacy
+// completions response.
+func parseOpenAICompletion(body []byte) (string, error) {
+	var response struct {
+		Choices []struct {
+			Text string `json:"text"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	return response.Choices[0].Text, nil
+}
+
+// openAIScoringRequest creates a legacy completions request that echoes text
+// with the log probability of each of its tokens. One token is generated,
+// as some servers reject requests that generate none.
+func openAIScoringRequest(model, text string, defaults, options map[string]interface{}) ([]byte, error) {
+	requestBody := openAICompletionBody(model, text, defaults, options)
+	requestBody["echo"] = true
+	requestBody["logprobs"] = 1
+	requestBody["max_tokens"] = 1
+	delete(requestBody, "stop")
+	return json.Marshal(requestBody)
+}
+
+// parseOpenAIScoring extracts the tokens of the first choice of an echoed
+// completions response with their log probabilities.
+func parseOpenAIScoring(body []byte) ([]TokenLogprob, error) {
+	var response struct {
+		Choices []struct {
+			Logprobs *struct {
+				Tokens        []string   `json:"tokens"`
+				TokenLogprobs []*float64 `json:"token_logprobs"`
+				TextOffset    []int      `json:"text_offset"`
+			} `json:"logprobs"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from API")
+	}
+	logprobs := response.Choices[0].Logprobs
+	if logprobs == nil || len(logprobs.Tokens) == 0 {
+		return nil, fmt.Errorf("response has no log probabilities")
+	}
+	if len(logprobs.TokenLogprobs) != len(logprobs.Tokens) || len(logprobs.TextOffset) != len(logprobs.Tokens) {
+		return nil, fmt.Errorf("mismatched log probabilities: %d tokens, %d log probabilities, %d offsets",
+			len(logprobs.Tokens), len(logprobs.TokenLogprobs), len(logprobs.TextOffset))
+	}
+	tokens := make([]TokenLogprob, len(logprobs.Tokens))
+	for i, token := range logprobs.Tokens {
+		tokens[i] = TokenLogprob{Token: token, Offset: logprobs.TextOffset[i]}
+		// The first token has no log probability, having no context.
+		if lp := logprobs.TokenLogprobs[i]; lp != nil {
+			tokens[i].Logprob = *lp
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,28 @@
+package providers
+
+// ConstraintKind identifies a kind of constrained decoding.
+type ConstraintKind string
+
+const (
+	// ConstraintGrammar restricts the output to a GBNF grammar, passed as
+	// the "grammar" option.
+	ConstraintGrammar ConstraintKind = "grammar"
+
+	// ConstraintRegex restricts the output to a regular expression, passed
+	// as the "regex_constraint" option.
+	ConstraintRegex ConstraintKind = "regex_constraint"
+)
+
+// ConstraintProvider is implemented by providers that can constrain the
+// tokens a model generates, typically self-hosted inference servers.
+type ConstraintProvider interface {
+	SupportsConstraint(kind ConstraintKind) bool
+}
+
+// takeConstraint removes a constraint from options so it is not sent under
+// its option name, and returns it.
+func takeConstraint(options map[string]interface{}, kind ConstraintKind) string {
+	value, _ := options[string(kind)].(string)
+	delete(options, string(kind))
+	return value
+}
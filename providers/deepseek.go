@@ -0,0 +1,338 @@
+// Package providers implements LLM provider interfaces and implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// deepSeekReasonerUnsupported lists the sampling parameters the reasoner
+// model does not accept. They are dropped from its requests.
+var deepSeekReasonerUnsupported = []string{
+	"temperature", "top_p", "presence_penalty", "frequency_penalty", "logprobs", "top_logprobs",
+}
+
+// DeepSeekProvider implements the Provider interface for DeepSeek's API.
+// It supports the deepseek-chat and deepseek-reasoner models; the reasoning
+// of deepseek-reasoner is available separately from its answer.
+type DeepSeekProvider struct {
+	apiKey       string                 // API key for authentication
+	baseURL      string                 // API base URL, changed with SetEndpoint
+	model        string                 // Model identifier (e.g., "deepseek-chat", "deepseek-reasoner")
+	extraHeaders map[string]string      // Additional HTTP headers
+	options      map[string]interface{} // Model-specific options
+	logger       utils.Logger           // Logger instance
+}
+
+// NewDeepSeekProvider creates a new DeepSeek provider instance.
+// It initializes the provider with the given API key, model, and optional headers.
+//
+// Parameters:
+//   - apiKey: DeepSeek API key for authentication
+//   - model: The model to use (e.g., "deepseek-chat", "deepseek-reasoner")
+//   - extraHeaders: Additional HTTP headers for requests
+//
+// Returns:
+//   - A configured DeepSeek Provider instance
+func NewDeepSeekProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	if extraHeaders == nil {
+		extraHeaders = make(map[string]string)
+	}
+	return &DeepSeekProvider{
+		apiKey:       apiKey,
+		baseURL:      "https://api.deepseek.com",
+		model:        model,
+		extraHeaders: extraHeaders,
+		options:      make(map[string]interface{}),
+		logger:       utils.NewLogger(utils.LogLevelInfo),
+	}
+}
+
+// SetLogger configures the logger for the DeepSeek provider.
+func (p *DeepSeekProvider) SetLogger(logger utils.Logger) {
+	p.logger = logger
+}
+
+// Name returns "deepseek" as the provider identifier.
+func (p *DeepSeekProvider) Name() string {
+	return "deepseek"
+}
+
+// Endpoint returns the DeepSeek API endpoint URL.
+// This is "https://api.deepseek.com/chat/completions",
+// unless changed with SetEndpoint.
+func (p *DeepSeekProvider) Endpoint() string {
+	return p.baseURL + "/chat/completions"
+}
+
+// SetEndpoint points the provider at baseURL instead of the DeepSeek API, such
+// as an OpenAI-compatible gateway or a proxy.
+func (p *DeepSeekProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL)
+}
+
+// SetOption sets a model-specific option for the DeepSeek provider.
+// Supported options include:
+//   - temperature: Controls randomness (0.0 to 2.0; ignored by deepseek-reasoner)
+//   - max_tokens: Maximum tokens in the response
+//   - top_p: Nucleus sampling parameter (ignored by deepseek-reasoner)
+//   - response_format: {"type": "json_object"} enables JSON mode
+func (p *DeepSeekProvider) SetOption(key string, value interface{}) {
+	p.options[key] = value
+}
+
+// SetDefaultOptions configures standard options from the global configuration.
+func (p *DeepSeekProvider) SetDefaultOptions(config *config.Config) {
+	p.SetOption("temperature", config.Temperature)
+	p.SetOption("max_tokens", config.MaxTokens)
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
+	}
+}
+
+// SupportsJSONSchema indicates whether this provider supports JSON schema validation.
+// DeepSeek only offers JSON mode, so schemas are described in the prompt.
+func (p *DeepSeekProvider) SupportsJSONSchema() bool {
+	return false
+}
+
+// Headers returns the HTTP headers required for DeepSeek API requests.
+func (p *DeepSeekProvider) Headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + p.apiKey,
+	}
+
+	for key, value := range p.extraHeaders {
+		headers[key] = value
+	}
+
+	return headers
+}
+
+// isReasoner reports whether the configured model is a reasoning model.
+func (p *DeepSeekProvider) isReasoner() bool {
+	return strings.HasPrefix(p.model, "deepseek-reasoner")
+}
+
+// PrepareRequest creates the request body for a DeepSeek API call, including
+// the system prompt and tool definitions.
+func (p *DeepSeekProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	if attachments := takeAttachments(options); len(attachments) > 0 {
+		return nil, unsupportedAttachment(p.Name(), attachments[0])
+	}
+
+	var messages []map[string]interface{}
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, map[string]interface{}{"role": "user", "content": prompt})
+
+	requestBody := map[string]interface{}{
+		"model":    p.model,
+		"messages": messages,
+	}
+
+	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		deepSeekTools := make([]map[string]interface{}, len(tools))
+		for i, tool := range tools {
+			deepSeekTools[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Function.Name,
+					"description": tool.Function.Description,
+					"parameters":  tool.Function.Parameters,
+				},
+			}
+		}
+		requestBody["tools"] = deepSeekTools
+	}
+	if toolChoice, ok := options["tool_choice"].(string); ok {
+		requestBody["tool_choice"] = toolChoice
+	}
+
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "tools", "tool_choice", "system_prompt", "strict_tools", "user", "metadata":
+			default:
+				requestBody[k] = v
+			}
+		}
+	}
+
+	if p.isReasoner() {
+		for _, key := range deepSeekReasonerUnsupported {
+			if _, ok := requestBody[key]; ok {
+				p.logger.Debug("Dropping option unsupported by deepseek-reasoner", "option", key)
+				delete(requestBody, key)
+			}
+		}
+	}
+
+	return json.Marshal(requestBody)
+}
+
+// PrepareRequestWithSchema creates a request in JSON mode. The schema itself
+// must be described in the prompt, as DeepSeek does not enforce schemas.
+func (p *DeepSeekProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	takeJSONMode(options)
+	options["response_format"] = map[string]interface{}{"type": "json_object"}
+	return p.PrepareRequest(prompt, options)
+}
+
+// SupportsJSONMode reports that DeepSeek offers JSON mode only.
+func (p *DeepSeekProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject
+}
+
+// deepSeekResponse is the subset of a chat completion response used by the provider.
+type deepSeekResponse struct {
+	Choices []struct {
+		Message struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// ParseResponse extracts the answer from a DeepSeek API response. The
+// reasoning of deepseek-reasoner is not part of the answer; see ParseReasoning.
+func (p *DeepSeekProvider) ParseResponse(body []byte) (string, error) {
+	var response deepSeekResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+
+	message := response.Choices[0].Message
+	if message.Content != "" {
+		return message.Content, nil
+	}
+
+	if len(message.ToolCalls) > 0 {
+		calls, err := p.ParseToolCalls(body)
+		if err != nil {
+			return "", err
+		}
+		return formatToolCalls(calls)
+	}
+
+	return "", fmt.Errorf("empty response from API")
+}
+
+// ParseReasoning returns the reasoning_content of a deepseek-reasoner response.
+func (p *DeepSeekProvider) ParseReasoning(body []byte) string {
+	var response deepSeekResponse
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Choices) == 0 {
+		return ""
+	}
+	return response.Choices[0].Message.ReasoningContent
+}
+
+// FIMEndpoint returns the URL of DeepSeek's fill-in-the-middle endpoint,
+// which is part of its beta API.
+func (p *DeepSeekProvider) FIMEndpoint() string {
+	return p.baseURL + "/beta/completions"
+}
+
+// PrepareFIMRequest creates a request completing the text between prefix and
+// suffix. FIM is only offered by deepseek-chat, which is used when the
+// provider is configured with deepseek-reasoner.
+func (p *DeepSeekProvider) PrepareFIMRequest(prefix, suffix string, options map[string]interface{}) ([]byte, error) {
+	model := p.model
+	if p.isReasoner() {
+		model = "deepseek-chat"
+	}
+
+	requestBody := map[string]interface{}{
+		"model":  model,
+		"prompt": prefix,
+	}
+	if suffix != "" {
+		requestBody["suffix"] = suffix
+	}
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "temperature", "max_tokens", "top_p", "stop", "presence_penalty", "frequency_penalty":
+				requestBody[k] = v
+			}
+		}
+	}
+	return json.Marshal(requestBody)
+}
+
+// ParseFIMResponse extracts the completion from a FIM response, which uses
+// the legacy completions format.
+func (p *DeepSeekProvider) ParseFIMResponse(body []byte) (string, error) {
+	return parseOpenAICompletion(body)
+}
+
+// CompletionEndpoint returns the URL of DeepSeek's completions endpoint,
+// which is part of its beta API and shared with fill-in-the-middle.
+func (p *DeepSeekProvider) CompletionEndpoint() string {
+	return p.FIMEndpoint()
+}
+
+// PrepareCompletionRequest creates a completions request continuing text.
+func (p *DeepSeekProvider) PrepareCompletionRequest(text string, options map[string]interface{}) ([]byte, error) {
+	return p.PrepareFIMRequest(text, "", options)
+}
+
+// ParseCompletionResponse extracts the continuation from a completions response.
+func (p *DeepSeekProvider) ParseCompletionResponse(body []byte) (string, error) {
+	return parseOpenAICompletion(body)
+}
+
+// ParseToolCalls returns every tool call of a DeepSeek response.
+func (p *DeepSeekProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	return parseOpenAIToolCalls(body)
+}
+
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *DeepSeekProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
+}
+
+// SetExtraHeaders configures additional HTTP headers for API requests.
+func (p *DeepSeekProvider) SetExtraHeaders(extraHeaders map[string]string) {
+	p.extraHeaders = extraHeaders
+}
+
+// SupportsStreaming returns whether the provider supports streaming responses
+func (p *DeepSeekProvider) SupportsStreaming() bool {
+	return true
+}
+
+// PrepareStreamRequest prepares a request body for streaming
+func (p *DeepSeekProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	options["stream"] = true
+	options["stream_options"] = map[string]interface{}{"include_usage": true}
+	return p.PrepareRequest(prompt, options)
+}
+
+// ParseStreamResponse parses a single chunk from a streaming response
+func (p *DeepSeekProvider) ParseStreamResponse(chunk []byte) (string, error) {
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a streaming chunk into typed events. The
+// reasoning of deepseek-reasoner arrives as StreamEventReasoningDelta events.
+func (p *DeepSeekProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	return parseOpenAIStreamEvent(data)
+}
@@ -0,0 +1,23 @@
+package providers
+
+import "strings"
+
+// EndpointSetter is implemented by providers whose API base URL can be
+// changed, to reach an OpenAI-compatible gateway such as LiteLLM or
+// Portkey, a corporate proxy or a self-hosted server instead of the
+// provider's API.
+type EndpointSetter interface {
+	SetEndpoint(baseURL string)
+}
+
+// trimBaseURL removes the trailing slashes of baseURL and then any of the
+// API version suffixes, such as "/v1", that the provider adds to its paths,
+// so that "https://gateway/v1/" and "https://gateway" reach the same
+// endpoints.
+func trimBaseURL(baseURL string, versions ...string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	for _, version := range versions {
+		baseURL = strings.TrimSuffix(baseURL, version)
+	}
+	return baseURL
+}
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gatewayCostHeader is the response header in which LiteLLM reports the
+// price of a request.
+const gatewayCostHeader = "x-litellm-response-cost"
+
+// GatewayProvider implements the Provider interface for LiteLLM-style AI
+// gateways, which proxy an OpenAI-compatible API to many upstream providers
+// behind one endpoint. The API key is a virtual key issued by the gateway,
+// and the model is any model name the gateway routes, such as
+// "anthropic/claude-3-5-sonnet" or an alias from its configuration.
+type GatewayProvider struct {
+	localServer
+}
+
+// NewGatewayProvider creates a provider for an AI gateway, by default a
+// LiteLLM proxy at http://localhost:4000. Set the GATEWAY_BASE_URL
+// environment variable or use SetBaseURL to reach another gateway.
+func NewGatewayProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	return &GatewayProvider{newLocalServer("gateway", "http://localhost:4000", apiKey, model, extraHeaders)}
+}
+
+// SupportsConstraint reports that the gateway accepts no decoding
+// constraints, which depend on the upstream provider.
+func (p *GatewayProvider) SupportsConstraint(kind ConstraintKind) bool {
+	return false
+}
+
+// SupportsJSONMode reports that the gateway forwards both JSON modes, which
+// the upstream model must support.
+func (p *GatewayProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema
+}
+
+// PrepareRequestWithSchema creates a request whose output is constrained to
+// the JSON schema with an OpenAI response_format, which the gateway
+// translates for the upstream provider.
+func (p *GatewayProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	mode := takeJSONMode(options)
+	strict, _ := options["strict"].(bool)
+	options["response_format"] = schemaResponseFormat(mode, schema, strict)
+	return p.PrepareRequest(prompt, options)
+}
+
+// SupportsReasoningEffort reports that the gateway accepts the
+// reasoning_effort field, which it translates for the upstream provider.
+func (p *GatewayProvider) SupportsReasoningEffort(model string) bool {
+	return true
+}
+
+// ParseCostHeader returns the price the gateway reported for the request in
+// its x-litellm-response-cost header.
+func (p *GatewayProvider) ParseCostHeader(header http.Header) (float64, bool) {
+	value := strings.TrimSpace(header.Get(gatewayCostHeader))
+	if value == "" {
+		return 0, false
+	}
+	cost, err := strconv.ParseFloat(value, 64)
+	if err != nil || cost < 0 {
+		return 0, false
+	}
+	return cost, true
+}
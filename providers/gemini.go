@@ -0,0 +1,314 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/config"
+)
+
+// GeminiProvider implements the Provider interface for Google's Gemini models
+// through the OpenAI-compatible endpoint of the Gemini API. Large static
+// content can be cached server-side with its context caching API and
+// referenced in later requests.
+type GeminiProvider struct {
+	localServer
+}
+
+// NewGeminiProvider creates a provider for the Gemini API. Set the
+// GEMINI_BASE_URL environment variable to reach it through a proxy.
+func NewGeminiProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	return &GeminiProvider{newLocalServer("gemini", "https://generativelanguage.googleapis.com/v1beta", apiKey, model, extraHeaders)}
+}
+
+// Endpoint returns the OpenAI-compatible chat completions endpoint.
+func (p *GeminiProvider) Endpoint() string {
+	return p.baseURL + "/openai/chat/completions"
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when Gemini does not serve it.
+func (p *GeminiProvider) ModelEndpoint() string {
+	return p.baseURL + "/openai/models/" + strings.TrimPrefix(p.model, "models/")
+}
+
+// SetEndpoint points the provider at baseURL, the versioned root of the
+// Gemini API, such as "https://generativelanguage.googleapis.com/v1beta".
+func (p *GeminiProvider) SetEndpoint(baseURL string) {
+	p.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SupportsPrefill reports that replies cannot be prefilled.
+func (p *GeminiProvider) SupportsPrefill() bool {
+	return false
+}
+
+// SetDefaultOptions applies the configured sampling options and safety
+// settings. The OpenAI-compatible endpoint has no top_k, so it is not set.
+func (p *GeminiProvider) SetDefaultOptions(cfg *config.Config) {
+	p.localServer.SetDefaultOptions(cfg)
+	delete(p.options, "top_k")
+	if len(cfg.SafetySettings) > 0 {
+		p.SetOption("safety_settings", cfg.SafetySettings)
+	}
+}
+
+// PrepareRequest creates the request body for a chat completion. A cached
+// context, set with the "cached_content" option, the safety settings and the
+// thinking budget of the reasoning effort are sent in the Gemini-specific
+// extra_body field.
+// Gemini rejects a system instruction alongside cached content, which holds
+// its own, so the system prompt is left out. top_k is not supported and
+// dropped.
+func (p *GeminiProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	delete(options, "top_k")
+	google := make(map[string]interface{})
+	if name, ok := options["cached_content"].(string); ok && name != "" {
+		google["cached_content"] = name
+		delete(options, "system_prompt")
+	}
+	delete(options, "cached_content")
+	settings, err := p.takeSafetySettings(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings) > 0 {
+		google["safety_settings"] = settings
+	}
+	if effort := takeReasoningEffort(options); effort != "" {
+		google["thinking_config"] = map[string]interface{}{"thinking_budget": effort.BudgetTokens()}
+	}
+	if len(google) > 0 {
+		options["extra_body"] = map[string]interface{}{"google": google}
+	}
+	return p.localServer.PrepareRequest(prompt, options)
+}
+
+// takeSafetySettings removes the "safety_settings" option and returns the
+// default safety settings overridden, per category, by those of the request.
+func (p *GeminiProvider) takeSafetySettings(options map[string]interface{}) ([]config.SafetySetting, error) {
+	defaults, err := safetySettings(p.options["safety_settings"])
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := safetySettings(options["safety_settings"])
+	if err != nil {
+		return nil, err
+	}
+	delete(options, "safety_settings")
+	merged := append([]config.SafetySetting(nil), defaults...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Category == override.Category {
+				merged[i], replaced = override, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged, nil
+}
+
+// safetySettings converts a "safety_settings" option value.
+func safetySettings(value interface{}) ([]config.SafetySetting, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []config.SafetySetting:
+		return v, nil
+	case config.SafetySetting:
+		return []config.SafetySetting{v}, nil
+	default:
+		return nil, fmt.Errorf("safety_settings must be a []config.SafetySetting, got %T", value)
+	}
+}
+
+// SafetyRating is Gemini's rating of a response candidate for a harm
+// category.
+type SafetyRating struct {
+	// Candidate is the index of the rated candidate.
+	Candidate int `json:"candidate"`
+	// Category is the harm category, such as "HARM_CATEGORY_HARASSMENT".
+	Category string `json:"category"`
+	// Probability is the probability of harm: "NEGLIGIBLE", "LOW",
+	// "MEDIUM" or "HIGH".
+	Probability string `json:"probability"`
+	// Blocked is true when the candidate was blocked for the category.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+// ParseMetadata returns the safety ratings of the response candidates under
+// the "safety_ratings" key as a []SafetyRating, when Gemini reports them.
+func (p *GeminiProvider) ParseMetadata(body []byte) map[string]interface{} {
+	type rating struct {
+		Category    string `json:"category"`
+		Probability string `json:"probability"`
+		Blocked     bool   `json:"blocked"`
+	}
+	var response struct {
+		// OpenAI-compatible endpoint
+		Choices []struct {
+			Index         int      `json:"index"`
+			SafetyRatings []rating `json:"safety_ratings"`
+		} `json:"choices"`
+		// Native API
+		Candidates []struct {
+			Index         int      `json:"index"`
+			SafetyRatings []rating `json:"safetyRatings"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+
+	var ratings []SafetyRating
+	for _, choice := range response.Choices {
+		for _, r := range choice.SafetyRatings {
+			ratings = append(ratings, SafetyRating{Candidate: choice.Index, Category: r.Category, Probability: r.Probability, Blocked: r.Blocked})
+		}
+	}
+	for _, candidate := range response.Candidates {
+		for _, r := range candidate.SafetyRatings {
+			ratings = append(ratings, SafetyRating{Candidate: candidate.Index, Category: r.Category, Probability: r.Probability, Blocked: r.Blocked})
+		}
+	}
+	if len(ratings) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"safety_ratings": ratings}
+}
+
+// SupportsReasoningEffort reports whether model is a thinking model, such as
+// the Gemini 2.5 models.
+func (p *GeminiProvider) SupportsReasoningEffort(model string) bool {
+	return hasModelPrefix(model, "gemini-2.5", "gemini-3")
+}
+
+// PrepareStreamRequest prepares a request body for streaming.
+func (p *GeminiProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	options["stream"] = true
+	options["stream_options"] = map[string]interface{}{"include_usage": true}
+	return p.PrepareRequest(prompt, options)
+}
+
+// PrepareRequestWithSchema creates a request with a json_schema response
+// format.
+func (p *GeminiProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	options["response_format"] = schemaResponseFormat(takeJSONMode(options), schema, false)
+	return p.PrepareRequest(prompt, options)
+}
+
+// SupportsJSONMode reports that Gemini offers both JSON modes through its
+// OpenAI-compatible endpoint.
+func (p *GeminiProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema
+}
+
+// CachedContext is content cached server-side by a provider, referenced by
+// name in later requests until it expires.
+type CachedContext struct {
+	// Name identifies the cached content, such as "cachedContents/abc123".
+	Name string
+
+	// Model is the model the content was cached for. Requests that use the
+	// cache must use the same model.
+	Model string
+
+	// ExpireTime is when the provider deletes the content.
+	ExpireTime time.Time
+
+	// Tokens is the number of tokens cached.
+	Tokens int
+}
+
+// ContextCacheProvider is implemented by providers that cache large static
+// content, such as manuals or codebases, server-side for a limited time.
+// Requests reference the cache with the "cached_content" option.
+type ContextCacheProvider interface {
+	// ContextCacheEndpoint returns the URL to create cached content at.
+	ContextCacheEndpoint() string
+
+	// ContextCacheHeaders returns the HTTP headers of requests to the
+	// endpoint.
+	ContextCacheHeaders() map[string]string
+
+	// PrepareContextCacheRequest creates the request body caching content,
+	// with an optional system prompt, for ttl. A zero ttl uses the
+	// provider's default.
+	PrepareContextCacheRequest(content, systemPrompt string, ttl time.Duration) ([]byte, error)
+
+	// ParseContextCacheResponse extracts the cached content's details.
+	ParseContextCacheResponse(body []byte) (CachedContext, error)
+}
+
+// ContextCacheEndpoint returns the cachedContents endpoint of the native
+// Gemini API.
+func (p *GeminiProvider) ContextCacheEndpoint() string {
+	return p.baseURL + "/cachedContents"
+}
+
+// ContextCacheHeaders returns the headers of the native Gemini API, which
+// takes the API key in the x-goog-api-key header.
+func (p *GeminiProvider) ContextCacheHeaders() map[string]string {
+	headers := map[string]string{
+		"Content-Type":   "application/json",
+		"x-goog-api-key": p.apiKey,
+	}
+	for key, value := range p.extraHeaders {
+		headers[key] = value
+	}
+	return headers
+}
+
+// PrepareContextCacheRequest creates a cachedContents request for the
+// provider's model.
+func (p *GeminiProvider) PrepareContextCacheRequest(content, systemPrompt string, ttl time.Duration) ([]byte, error) {
+	model := p.model
+	if !strings.HasPrefix(model, "models/") {
+		model = "models/" + model
+	}
+	requestBody := map[string]interface{}{
+		"model": model,
+		"contents": []map[string]interface{}{
+			{"role": "user", "parts": []map[string]string{{"text": content}}},
+		},
+	}
+	if systemPrompt != "" {
+		requestBody["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]string{{"text": systemPrompt}},
+		}
+	}
+	if ttl > 0 {
+		requestBody["ttl"] = fmt.Sprintf("%ds", int64(ttl.Round(time.Second)/time.Second))
+	}
+	return json.Marshal(requestBody)
+}
+
+// ParseContextCacheResponse extracts the name, expiry and size of the cached
+// content.
+func (p *GeminiProvider) ParseContextCacheResponse(body []byte) (CachedContext, error) {
+	var response struct {
+		Name          string    `json:"name"`
+		Model         string    `json:"model"`
+		ExpireTime    time.Time `json:"expireTime"`
+		UsageMetadata struct {
+			TotalTokenCount int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return CachedContext{}, fmt.Errorf("error parsing response: %w", err)
+	}
+	if response.Name == "" {
+		return CachedContext{}, fmt.Errorf("response has no cached content name")
+	}
+	return CachedContext{
+		Name:       response.Name,
+		Model:      strings.TrimPrefix(response.Model, "models/"),
+		ExpireTime: response.ExpireTime,
+		Tokens:     response.UsageMetadata.TotalTokenCount,
+	}, nil
+}
@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/config"
+)
+
+func TestGeminiPrepareRequest(t *testing.T) {
+	harassment := config.SafetySetting{Category: config.HarmCategoryHarassment, Threshold: config.BlockOnlyHigh}
+	tests := []struct {
+		name     string
+		defaults map[string]interface{}
+		options  map[string]interface{}
+		fields   map[string]string
+		absent   []string
+		err      string
+	}{
+		{
+			name:    "system prompt and prompt",
+			options: map[string]interface{}{"system_prompt": "Be brief."},
+			fields: map[string]string{
+				"model":    `"gemini-2.5-flash"`,
+				"messages": `[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"}]`,
+			},
+			absent: []string{"system_prompt", "extra_body"},
+		},
+		{
+			name:    "cached content replaces the system prompt",
+			options: map[string]interface{}{"system_prompt": "Be brief.", "cached_content": "cachedContents/abc"},
+			fields: map[string]string{
+				"messages":   `[{"role":"user","content":"Hi"}]`,
+				"extra_body": `{"google":{"cached_content":"cachedContents/abc"}}`,
+			},
+			absent: []string{"cached_content"},
+		},
+		{
+			name:     "safety settings of the request override the defaults per category",
+			defaults: map[string]interface{}{"safety_settings": []config.SafetySetting{{Category: config.HarmCategoryHarassment, Threshold: config.BlockNone}}},
+			options: map[string]interface{}{"safety_settings": []config.SafetySetting{
+				harassment,
+				{Category: config.HarmCategoryHateSpeech, Threshold: config.BlockLowAndAbove},
+			}},
+			fields: map[string]string{
+				"extra_body": `{"google":{"safety_settings":[
+					{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_ONLY_HIGH"},
+					{"category":"HARM_CATEGORY_HATE_SPEECH","threshold":"BLOCK_LOW_AND_ABOVE"}
+				]}}`,
+			},
+			absent: []string{"safety_settings"},
+		},
+		{
+			name:    "safety settings of another type",
+			options: map[string]interface{}{"safety_settings": "none"},
+			err:     "safety_settings must be a []config.SafetySetting",
+		},
+		{
+			name:    "reasoning effort",
+			options: map[string]interface{}{"reasoning_effort": ReasoningLow},
+			fields:  map[string]string{"extra_body": `{"google":{"thinking_config":{"thinking_budget":1024}}}`},
+			absent:  []string{"reasoning_effort"},
+		},
+		{
+			name:    "top_k is dropped",
+			options: map[string]interface{}{"top_k": 40, "top_p": 0.9},
+			fields:  map[string]string{"top_p": `0.9`},
+			absent:  []string{"top_k"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewGeminiProvider("key", "gemini-2.5-flash", nil)
+			for k, v := range tt.defaults {
+				p.SetOption(k, v)
+			}
+			body, err := p.PrepareRequest("Hi", tt.options)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			for key, want := range tt.fields {
+				assert.JSONEq(t, want, requestField(t, body, key), key)
+			}
+			request := decodeRequest(t, body)
+			for _, key := range tt.absent {
+				assert.NotContains(t, request, key)
+			}
+		})
+	}
+}
+
+func TestGeminiSetDefaultOptions(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetSeed(7), config.SetTopP(0.5), config.SetTopK(40),
+		config.SetSafetySettings(config.SafetySetting{Category: config.HarmCategoryHarassment, Threshold: config.BlockNone}))
+	p := NewGeminiProvider("key", "gemini-2.5-flash", nil)
+	p.SetDefaultOptions(cfg)
+
+	body, err := p.PrepareRequest("Hi", map[string]interface{}{})
+	require.NoError(t, err)
+	request := decodeRequest(t, body)
+	assert.Equal(t, 7.0, request["seed"])
+	assert.Equal(t, 0.5, request["top_p"])
+	assert.NotContains(t, request, "top_k", "Gemini does not support top_k")
+	assert.JSONEq(t, `{"google":{"safety_settings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_NONE"}]}}`, requestField(t, body, "extra_body"))
+	for _, option := range SamplingOptions {
+		_, sent := request[option]
+		assert.Equal(t, SupportsOption("gemini", option), sent, option)
+	}
+}
+
+func TestGeminiEndpoints(t *testing.T) {
+	p := NewGeminiProvider("key", "models/gemini-2.5-flash", nil)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/openai/chat/completions", p.Endpoint())
+	cache := p.(ContextCacheProvider)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/cachedContents", cache.ContextCacheEndpoint())
+	assert.Equal(t, "key", cache.ContextCacheHeaders()["x-goog-api-key"])
+
+	p.(EndpointSetter).SetEndpoint("http://proxy.internal/v1beta/")
+	assert.Equal(t, "http://proxy.internal/v1beta/openai/chat/completions", p.Endpoint())
+	assert.Equal(t, "http://proxy.internal/v1beta/openai/models/gemini-2.5-flash", p.(*GeminiProvider).ModelEndpoint())
+}
+
+func TestGeminiContextCache(t *testing.T) {
+	p := NewGeminiProvider("key", "gemini-2.5-flash", nil).(ContextCacheProvider)
+
+	body, err := p.PrepareContextCacheRequest("The manual.", "Answer from the manual.", 90*time.Minute)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "models/gemini-2.5-flash",
+		"contents": [{"role":"user","parts":[{"text":"The manual."}]}],
+		"systemInstruction": {"parts":[{"text":"Answer from the manual."}]},
+		"ttl": "5400s"
+	}`, string(body))
+
+	body, err = p.PrepareContextCacheRequest("The manual.", "", 0)
+	require.NoError(t, err)
+	request := decodeRequest(t, body)
+	assert.NotContains(t, request, "systemInstruction")
+	assert.NotContains(t, request, "ttl")
+
+	cached, err := p.ParseContextCacheResponse([]byte(`{"name":"cachedContents/abc","model":"models/gemini-2.5-flash",
+		"expireTime":"2025-01-01T01:00:00Z","usageMetadata":{"totalTokenCount":4096}}`))
+	require.NoError(t, err)
+	assert.Equal(t, CachedContext{
+		Name:       "cachedContents/abc",
+		Model:      "gemini-2.5-flash",
+		ExpireTime: time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+		Tokens:     4096,
+	}, cached)
+
+	_, err = p.ParseContextCacheResponse([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestGeminiParseMetadata(t *testing.T) {
+	p := NewGeminiProvider("key", "gemini-2.5-flash", nil).(*GeminiProvider)
+	tests := []struct {
+		name    string
+		body    string
+		ratings []SafetyRating
+	}{
+		{
+			name: "OpenAI-compatible response",
+			body: `{"choices":[{"index":0,"safety_ratings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"LOW"}]}]}`,
+			ratings: []SafetyRating{
+				{Candidate: 0, Category: "HARM_CATEGORY_HARASSMENT", Probability: "LOW"},
+			},
+		},
+		{
+			name: "native response",
+			body: `{"candidates":[{"index":1,"safetyRatings":[{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"HIGH","blocked":true}]}]}`,
+			ratings: []SafetyRating{
+				{Candidate: 1, Category: "HARM_CATEGORY_HATE_SPEECH", Probability: "HIGH", Blocked: true},
+			},
+		},
+		{name: "no ratings", body: `{"choices":[{"index":0}]}`},
+		{name: "invalid", body: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := p.ParseMetadata([]byte(tt.body))
+			if tt.ratings == nil {
+				assert.Nil(t, metadata)
+				return
+			}
+			assert.Equal(t, map[string]interface{}{"safety_ratings": tt.ratings}, metadata)
+		})
+	}
+}
@@ -4,6 +4,7 @@ package providers
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/teilomillet/gollm/config"
 	"github.com/teilomillet/gollm/utils"
@@ -14,6 +15,7 @@ import (
 // high-performance inference capabilities.
 type GroqProvider struct {
 	apiKey       string                 // API key for authentication
+	baseURL      string                 // API base URL, changed with SetEndpoint
 	model        string                 // Model identifier (e.g., "llama2-70b", "mixtral-8x7b")
 	extraHeaders map[string]string      // Additional HTTP headers
 	options      map[string]interface{} // Model-specific options
@@ -36,6 +38,7 @@ func NewGroqProvider(apiKey, model string, extraHeaders map[string]string) Provi
 	}
 	return &GroqProvider{
 		apiKey:       apiKey,
+		baseURL:      "https://api.groq.com/openai",
 		model:        model,
 		extraHeaders: extraHeaders,
 		options:      make(map[string]interface{}),
@@ -55,9 +58,22 @@ func (p *GroqProvider) Name() string {
 }
 
 // Endpoint returns the Groq API endpoint URL.
-// This is "https://api.groq.com/openai/v1/chat/completions".
+// This is "https://api.groq.com/openai/v1/chat/completions",
+// unless changed with SetEndpoint.
 func (p *GroqProvider) Endpoint() string {
-	return "https://api.groq.com/openai/v1/chat/completions"
+	return p.baseURL + "/v1/chat/completions"
+}
+
+// SetEndpoint points the provider at baseURL instead of the Groq API, such
+// as an OpenAI-compatible gateway or a proxy. A trailing /v1 is accepted.
+func (p *GroqProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1")
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when Groq does not serve it.
+func (p *GroqProvider) ModelEndpoint() string {
+	return p.baseURL + "/v1/models/" + p.model
 }
 
 // SetOption sets a model-specific option for the Groq provider.
@@ -66,6 +82,7 @@ func (p *GroqProvider) Endpoint() string {
 //   - max_tokens: Maximum tokens in the response
 //   - top_p: Nucleus sampling parameter
 //   - top_k: Top-k sampling parameter
+//   - service_tier: Processing tier ("on_demand", "flex" or "auto")
 func (p *GroqProvider) SetOption(key string, value interface{}) {
 	p.options[key] = value
 }
@@ -78,12 +95,25 @@ func (p *GroqProvider) SetDefaultOptions(config *config.Config) {
 	if config.Seed != nil {
 		p.SetOption("seed", *config.Seed)
 	}
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
+	}
+	if config.ServiceTier != "" {
+		p.SetOption("service_tier", config.ServiceTier)
+	}
 }
 
 // SupportsJSONSchema indicates whether this provider supports JSON schema validation.
-// Currently, Groq does not natively support JSON schema validation.
+// Only some Groq models enforce schemas natively; see LookupModelCapabilities.
 func (p *GroqProvider) SupportsJSONSchema() bool {
-	return false
+	caps, _ := LookupModelCapabilities(p.Name(), p.model)
+	return caps.JSONSchema
+}
+
+// SupportsJSONMode reports that Groq offers JSON mode on every model, and
+// strict schemas on the models with native schema support.
+func (p *GroqProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema && p.SupportsJSONSchema()
 }
 
 // Headers returns the HTTP headers required for Groq API requests.
@@ -112,11 +142,32 @@ func (p *GroqProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *GroqProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	content, err := openAIUserContent(p.Name(), prompt, takeAttachments(options), false)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": groqMessages(options, nil, content),
+	}
+
+	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		groqTools := make([]map[string]interface{}, len(tools))
+		for i, tool := range tools {
+			groqTools[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Function.Name,
+					"description": tool.Function.Description,
+					"parameters":  tool.Function.Parameters,
+				},
+			}
+		}
+		requestBody["tools"] = groqTools
+	}
+	if toolChoice, ok := options["tool_choice"].(string); ok {
+		requestBody["tool_choice"] = toolChoice
 	}
 
 	// First, add the default options
@@ -126,35 +177,68 @@ func (p *GroqProvider) PrepareRequest(prompt string, options map[string]interfac
 
 	// Then, add any additional options (which may override defaults)
 	for k, v := range options {
-		requestBody[k] = v
+		if k != "tools" && k != "tool_choice" && k != "system_prompt" && k != "strict_tools" && k != "user" && k != "metadata" {
+			requestBody[k] = v
+		}
 	}
 
 	return json.Marshal(requestBody)
 }
 
+// groqMessages builds the message list, with the system prompt first, then
+// the conversation history, the user message unless content is nil, and the
+// response prefill, if any, last.
+func groqMessages(options map[string]interface{}, history []map[string]interface{}, content interface{}) []map[string]interface{} {
+	var messages []map[string]interface{}
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, history...)
+	if content != nil {
+		messages = append(messages, map[string]interface{}{"role": "user", "content": content})
+	}
+	if prefill := takePrefill(options); prefill != "" {
+		message := prefillMessage(prefill)
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// SupportsPrefill reports that replies can be primed with a prefill.
+func (p *GroqProvider) SupportsPrefill() bool {
+	return true
+}
+
 // PrepareRequestWithSchema creates a request with JSON schema validation.
-// Since Groq doesn't support schema validation natively, this falls back to
-// standard request preparation.
+// Models with native schema support receive the schema as a json_schema
+// response format; other models are put in JSON mode.
 func (p *GroqProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	content, err := openAIUserContent(p.Name(), prompt, takeAttachments(options), false)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := takeJSONMode(options)
+	if !p.SupportsJSONSchema() {
+		mode = JSONModeObject
+	}
+	strict, _ := options["strict"].(bool)
+	responseFormat := schemaResponseFormat(mode, schema, strict)
+
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"response_format": map[string]interface{}{
-			"type":   "json_schema",
-			"schema": schema,
-		},
+		"model":           p.model,
+		"messages":        groqMessages(options, nil, content),
+		"response_format": responseFormat,
 	}
 
 	// Add any additional options
-	for k, v := range options {
+	for k, v := range p.options {
 		requestBody[k] = v
 	}
-
-	// Add strict option if provided
-	if strict, ok := options["strict"].(bool); ok && strict {
-		requestBody["response_format"].(map[string]interface{})["strict"] = true
+	for k, v := range options {
+		if k != "system_prompt" && k != "strict" && k != "user" && k != "metadata" {
+			requestBody[k] = v
+		}
 	}
 
 	return json.Marshal(requestBody)
@@ -173,7 +257,13 @@ func (p *GroqProvider) ParseResponse(body []byte) (string, error) {
 	var response struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
 			} `json:"message"`
 		} `json:"choices"`
 	}
@@ -183,27 +273,89 @@ func (p *GroqProvider) ParseResponse(body []byte) (string, error) {
 		return "", fmt.Errorf("error parsing response: %w", err)
 	}
 
-	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+	if len(response.Choices) == 0 {
 		return "", fmt.Errorf("empty response from API")
 	}
 
-	return response.Choices[0].Message.Content, nil
+	message := response.Choices[0].Message
+	if message.Content != "" {
+		return message.Content, nil
+	}
+
+	if len(message.ToolCalls) > 0 {
+		calls, err := p.ParseToolCalls(body)
+		if err != nil {
+			return "", err
+		}
+		return formatToolCalls(calls)
+	}
+
+	return "", fmt.Errorf("empty response from API")
 }
 
-// HandleFunctionCalls processes function calling capabilities.
-// Since Groq doesn't support function calling natively, this returns nil.
-func (p *GroqProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	response := string(body)
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
+// GroqMetrics holds the timing information Groq reports for a request.
+type GroqMetrics struct {
+	// RequestID is Groq's identifier for the request (x_groq.id).
+	RequestID string
+
+	// ServiceTier is the tier that processed the request.
+	ServiceTier string
+
+	// QueueTime is the time the request waited before processing started.
+	QueueTime time.Duration
+
+	// PromptTime is the time spent processing the prompt.
+	PromptTime time.Duration
+
+	// CompletionTime is the time spent generating the completion.
+	CompletionTime time.Duration
+
+	// TotalTime is PromptTime plus CompletionTime.
+	TotalTime time.Duration
+}
+
+// ParseMetadata returns Groq's latency and queue metrics under the "groq"
+// key as a GroqMetrics value.
+func (p *GroqProvider) ParseMetadata(body []byte) map[string]interface{} {
+	var response struct {
+		ServiceTier string `json:"service_tier"`
+		Usage       *struct {
+			QueueTime      float64 `json:"queue_time"`
+			PromptTime     float64 `json:"prompt_time"`
+			CompletionTime float64 `json:"completion_time"`
+			TotalTime      float64 `json:"total_time"`
+		} `json:"usage"`
+		XGroq struct {
+			ID string `json:"id"`
+		} `json:"x_groq"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Usage == nil {
+		return nil
 	}
 
-	if len(functionCalls) == 0 {
-		return nil, nil // No function calls found
+	seconds := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	return map[string]interface{}{
+		"groq": GroqMetrics{
+			RequestID:      response.XGroq.ID,
+			ServiceTier:    response.ServiceTier,
+			QueueTime:      seconds(response.Usage.QueueTime),
+			PromptTime:     seconds(response.Usage.PromptTime),
+			CompletionTime: seconds(response.Usage.CompletionTime),
+			TotalTime:      seconds(response.Usage.TotalTime),
+		},
 	}
+}
 
-	return json.Marshal(functionCalls)
+// ParseToolCalls returns every tool call of a Groq response.
+func (p *GroqProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	return parseOpenAIToolCalls(body)
+}
+
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *GroqProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -225,18 +377,10 @@ func (p *GroqProvider) PrepareStreamRequest(prompt string, options map[string]in
 
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *GroqProvider) ParseStreamResponse(chunk []byte) (string, error) {
-	var response struct {
-		Choices []struct {
-			Delta struct {
-				Content string `json:"content"`
-			} `json:"delta"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", err
-	}
-	if len(response.Choices) == 0 {
-		return "", nil
-	}
-	return response.Choices[0].Delta.Content, nil
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a streaming chunk into typed events.
+func (p *GroqProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	return parseOpenAIStreamEvent(data)
 }
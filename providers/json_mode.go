@@ -0,0 +1,53 @@
+package providers
+
+// JSONMode selects how a provider constrains a structured response, for
+// providers that offer more than one way. It is passed as the "json_mode"
+// option of PrepareRequestWithSchema; without it, each provider uses its
+// default.
+type JSONMode string
+
+const (
+	// JSONModeObject asks for any valid JSON object, the "json_object"
+	// response format. The schema is described in the prompt but not
+	// enforced, which works on more models and accepts any schema.
+	JSONModeObject JSONMode = "json_object"
+
+	// JSONModeSchema enforces the schema in strict mode, the "json_schema"
+	// response format with strict set. Schemas must follow the provider's
+	// strict mode rules, such as listing every property as required.
+	JSONModeSchema JSONMode = "json_schema"
+)
+
+// JSONModeProvider is implemented by providers that let callers choose the
+// JSON mode of structured responses.
+type JSONModeProvider interface {
+	SupportsJSONMode(mode JSONMode) bool
+}
+
+// takeJSONMode removes the JSON mode from options so it is not sent as a
+// request parameter, and returns it.
+func takeJSONMode(options map[string]interface{}) JSONMode {
+	mode, _ := options["json_mode"].(JSONMode)
+	delete(options, "json_mode")
+	return mode
+}
+
+// schemaResponseFormat returns the response_format of an OpenAI-compatible
+// request for schema: a json_object format in JSONModeObject, or else a
+// json_schema format, strict in JSONModeSchema or when strict is set.
+func schemaResponseFormat(mode JSONMode, schema interface{}, strict bool) map[string]interface{} {
+	if mode == JSONModeObject {
+		return map[string]interface{}{"type": "json_object"}
+	}
+	jsonSchema := map[string]interface{}{
+		"name":   "structured_response",
+		"schema": schema,
+	}
+	if strict || mode == JSONModeSchema {
+		jsonSchema["strict"] = true
+	}
+	return map[string]interface{}{
+		"type":        "json_schema",
+		"json_schema": jsonSchema,
+	}
+}
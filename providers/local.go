@@ -0,0 +1,319 @@
+// Package providers implements LLM provider interfaces and implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/teilomillet/gollm/config"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// localServer implements the OpenAI-compatible chat completions API of
+// self-hosted inference servers. The servers differ only in the request
+// fields that carry decoding constraints and schemas.
+type localServer struct {
+	name         string                 // Provider name
+	baseURL      string                 // Server URL, without the /v1 path
+	apiKey       string                 // Optional API key of the server
+	model        string                 // Model served by the server
+	extraHeaders map[string]string      // Additional HTTP headers
+	options      map[string]interface{} // Model-specific options
+	logger       utils.Logger           // Logger instance
+
+	// constraintFields maps the supported constraint kinds to the request
+	// field that carries them
+	constraintFields map[ConstraintKind]string
+
+	// schemaField is the request field that carries a JSON schema
+	schemaField string
+
+	// prefillFields are added to requests that prime the reply
+	prefillFields map[string]interface{}
+}
+
+func newLocalServer(name, baseURL, apiKey, model string, extraHeaders map[string]string) localServer {
+	if extraHeaders == nil {
+		extraHeaders = make(map[string]string)
+	}
+	return localServer{
+		name:         name,
+		baseURL:      baseURL,
+		apiKey:       apiKey,
+		model:        model,
+		extraHeaders: extraHeaders,
+		options:      make(map[string]interface{}),
+		logger:       utils.NewLogger(utils.LogLevelInfo),
+	}
+}
+
+// SetLogger configures the logger of the provider.
+func (p *localServer) SetLogger(logger utils.Logger) {
+	p.logger = logger
+}
+
+// Name returns the identifier of the provider.
+func (p *localServer) Name() string {
+	return p.name
+}
+
+// Endpoint returns the chat completions endpoint of the server.
+func (p *localServer) Endpoint() string {
+	return p.baseURL + "/v1/chat/completions"
+}
+
+// SetEndpoint points the provider at the server at baseURL, such as
+// "http://gpu-box:8000". A trailing /v1 is accepted.
+func (p *localServer) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1")
+}
+
+// SetOption sets a model-specific option, sent as a top-level request field.
+func (p *localServer) SetOption(key string, value interface{}) {
+	p.options[key] = value
+}
+
+// SetDefaultOptions configures standard options from the global configuration.
+func (p *localServer) SetDefaultOptions(config *config.Config) {
+	p.SetOption("temperature", config.Temperature)
+	p.SetOption("max_tokens", config.MaxTokens)
+	if config.Seed != nil {
+		p.SetOption("seed", *config.Seed)
+	}
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
+	}
+	if config.TopK != nil {
+		p.SetOption("top_k", *config.TopK)
+	}
+}
+
+// SupportsJSONSchema reports that the server enforces JSON schemas while
+// decoding.
+func (p *localServer) SupportsJSONSchema() bool {
+	return true
+}
+
+// SupportsConstraint reports whether the server accepts the constraint kind.
+func (p *localServer) SupportsConstraint(kind ConstraintKind) bool {
+	_, ok := p.constraintFields[kind]
+	return ok
+}
+
+// SupportsPrefill reports that replies can be primed with a prefill.
+func (p *localServer) SupportsPrefill() bool {
+	return true
+}
+
+// SupportsMessages reports that conversation histories are sent as chat
+// messages.
+func (p *localServer) SupportsMessages() bool {
+	return true
+}
+
+// Headers returns the HTTP headers of requests to the server. The API key is
+// only sent when one is configured.
+func (p *localServer) Headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	for key, value := range p.extraHeaders {
+		headers[key] = value
+	}
+	return headers
+}
+
+// SetExtraHeaders configures additional HTTP headers for API requests.
+func (p *localServer) SetExtraHeaders(extraHeaders map[string]string) {
+	p.extraHeaders = extraHeaders
+}
+
+// PrepareRequest creates the request body for a chat completion. Grammar and
+// regex constraints are sent in the server's own request fields.
+func (p *localServer) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	history, err := openAIMessages(p.Name(), takeMessages(options), false)
+	if err != nil {
+		return nil, err
+	}
+	attachments := takeAttachments(options)
+	content, err := openAIUserContent(p.Name(), prompt, attachments, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 && prompt == "" && len(attachments) == 0 {
+		content = nil
+	}
+
+	prefilled := options["response_prefill"] != nil
+	requestBody := map[string]interface{}{
+		"model":    p.model,
+		"messages": groqMessages(options, history, content),
+	}
+	if prefilled {
+		for k, v := range p.prefillFields {
+			requestBody[k] = v
+		}
+	}
+
+	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		serverTools := make([]map[string]interface{}, len(tools))
+		for i, tool := range tools {
+			serverTools[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Function.Name,
+					"description": tool.Function.Description,
+					"parameters":  tool.Function.Parameters,
+				},
+			}
+		}
+		requestBody["tools"] = serverTools
+	}
+	if toolChoice, ok := options["tool_choice"].(string); ok {
+		requestBody["tool_choice"] = toolChoice
+	}
+
+	for _, kind := range []ConstraintKind{ConstraintGrammar, ConstraintRegex} {
+		value := takeConstraint(options, kind)
+		if value == "" {
+			continue
+		}
+		field, ok := p.constraintFields[kind]
+		if !ok {
+			return nil, fmt.Errorf("%s does not support %s constraints", p.Name(), kind)
+		}
+		requestBody[field] = value
+	}
+
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "tools", "tool_choice", "system_prompt", "strict", "strict_tools", "provider_preferences", "safety_settings":
+			default:
+				requestBody[k] = v
+			}
+		}
+	}
+
+	return json.Marshal(requestBody)
+}
+
+// PrepareRequestWithSchema creates a request whose output the server
+// constrains to the JSON schema.
+func (p *localServer) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	options[p.schemaField] = schema
+	return p.PrepareRequest(prompt, options)
+}
+
+// ParseResponse extracts the generated text from a chat completion.
+func (p *localServer) ParseResponse(body []byte) (string, error) {
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	if content := response.Choices[0].Message.Content; content != "" {
+		return content, nil
+	}
+
+	calls, err := parseOpenAIToolCalls(body)
+	if err != nil {
+		return "", err
+	}
+	if len(calls) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	return formatToolCalls(calls)
+}
+
+// ParseToolCalls returns every tool call of a chat completion.
+func (p *localServer) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	return parseOpenAIToolCalls(body)
+}
+
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *localServer) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
+}
+
+// SupportsStreaming returns whether the provider supports streaming responses
+func (p *localServer) SupportsStreaming() bool {
+	return true
+}
+
+// PrepareStreamRequest prepares a request body for streaming
+func (p *localServer) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	options["stream"] = true
+	options["stream_options"] = map[string]interface{}{"include_usage": true}
+	return p.PrepareRequest(prompt, options)
+}
+
+// ParseStreamResponse parses a single chunk from a streaming response
+func (p *localServer) ParseStreamResponse(chunk []byte) (string, error) {
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a streaming chunk into typed events.
+func (p *localServer) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	return parseOpenAIStreamEvent(data)
+}
+
+// VLLMProvider implements the Provider interface for vLLM's OpenAI-compatible
+// server. Besides JSON schemas, it constrains outputs to GBNF grammars and
+// regular expressions with guided decoding.
+type VLLMProvider struct {
+	completionServer
+}
+
+// NewVLLMProvider creates a provider for a vLLM server, by default at
+// http://localhost:8000. Set the VLLM_ENDPOINT environment variable to reach
+// another server. The API key is the one the
+// server was started with, if any.
+func NewVLLMProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	p := &VLLMProvider{completionServer{newLocalServer("vllm", "http://localhost:8000", apiKey, model, extraHeaders)}}
+	p.constraintFields = map[ConstraintKind]string{
+		ConstraintGrammar: "guided_grammar",
+		ConstraintRegex:   "guided_regex",
+	}
+	p.schemaField = "guided_json"
+	// The chat template must continue the prefill instead of starting a new
+	// assistant turn
+	p.prefillFields = map[string]interface{}{
+		"continue_final_message": true,
+		"add_generation_prompt":  false,
+	}
+	return p
+}
+
+// LlamaCppProvider implements the Provider interface for the llama.cpp
+// server (llama-server). Besides JSON schemas, it constrains outputs to GBNF
+// grammars; regular expressions are not supported.
+type LlamaCppProvider struct {
+	completionServer
+}
+
+// NewLlamaCppProvider creates a provider for a llama.cpp server, by default
+// at http://localhost:8080. Set the LLAMACPP_ENDPOINT environment variable to
+// reach another server. The model name is only
+// reported back, as the server serves the model it was started with.
+func NewLlamaCppProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	p := &LlamaCppProvider{completionServer{newLocalServer("llamacpp", "http://localhost:8080", apiKey, model, extraHeaders)}}
+	p.constraintFields = map[ConstraintKind]string{
+		ConstraintGrammar: "grammar",
+	}
+	p.schemaField = "json_schema"
+	return p
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/teilomillet/gollm/utils"
+)
+
+// Message is a message of a conversation history sent to a MessageProvider.
+type Message struct {
+	// Role is "system", "user", "assistant" or "tool".
+	Role string
+
+	// Content is the text of the message.
+	Content string
+
+	// Name optionally identifies the sender.
+	Name string
+
+	// CacheType marks the message as a cache breakpoint, such as
+	// "ephemeral", for providers with prompt caching.
+	CacheType string
+
+	// ToolCalls are the tool calls requested by an assistant message.
+	ToolCalls []ToolCall
+
+	// ToolCallID is the ID of the tool call a tool message answers.
+	ToolCallID string
+
+	// Attachments are the images and documents sent with the message.
+	Attachments []utils.Attachment
+}
+
+// MessageProvider is implemented by providers that accept a conversation
+// history natively. Such providers receive the prompt's messages as a
+// []Message under the "messages" option, followed by the prompt text as a
+// final user message when the text is not empty.
+type MessageProvider interface {
+	SupportsMessages() bool
+}
+
+// MessageEndpointProvider is implemented by message providers that send
+// requests with a conversation history to another endpoint than Endpoint.
+type MessageEndpointProvider interface {
+	MessageEndpoint() string
+}
+
+// takeMessages removes the conversation history from options so it is not
+// sent as a top-level request field, and returns it.
+func takeMessages(options map[string]interface{}) []Message {
+	messages, _ := options["messages"].([]Message)
+	delete(options, "messages")
+	return messages
+}
+
+// openAIMessages converts a conversation history to the format of the
+// OpenAI chat completion API, where tool call arguments are JSON strings.
+// Attachments are sent as content parts, like those of the prompt.
+func openAIMessages(provider string, history []Message, allowFiles bool) ([]map[string]interface{}, error) {
+	messages := make([]map[string]interface{}, 0, len(history))
+	for _, m := range history {
+		content, err := openAIUserContent(provider, m.Content, m.Attachments, allowFiles)
+		if err != nil {
+			return nil, err
+		}
+		message := map[string]interface{}{"role": m.Role, "content": content}
+		if m.Name != "" {
+			message["name"] = m.Name
+		}
+		if m.ToolCallID != "" {
+			message["tool_call_id"] = m.ToolCallID
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, len(m.ToolCalls))
+			for i, call := range m.ToolCalls {
+				arguments, err := messageToolArguments(call)
+				if err != nil {
+					return nil, err
+				}
+				calls[i] = map[string]interface{}{
+					"id":   call.ID,
+					"type": "function",
+					"function": map[string]interface{}{
+						"name":      call.Name,
+						"arguments": string(arguments),
+					},
+				}
+			}
+			message["tool_calls"] = calls
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// anthropicMessages converts a conversation history to the Messages API
+// format. System messages are returned separately, as Anthropic takes them
+// in the system field; tool calls become tool_use blocks and tool messages
+// user messages with a tool_result block.
+func anthropicMessages(history []Message) (system []string, messages []map[string]interface{}, err error) {
+	for _, m := range history {
+		var blocks []map[string]interface{}
+		switch {
+		case m.Role == "system":
+			system = append(system, m.Content)
+			continue
+		case m.Role == "tool":
+			blocks = append(blocks, map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     m.Content,
+			})
+		case len(m.Attachments) > 0:
+			var err error
+			if blocks, err = anthropicUserContent(m.Content, m.Attachments); err != nil {
+				return nil, nil, err
+			}
+			if m.Content == "" {
+				blocks = blocks[:len(blocks)-1]
+			}
+		default:
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				arguments, err := messageToolArguments(call)
+				if err != nil {
+					return nil, nil, err
+				}
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Name,
+					"input": arguments,
+				})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if m.CacheType != "" {
+			blocks[len(blocks)-1]["cache_control"] = map[string]string{"type": m.CacheType}
+		}
+		role := m.Role
+		if role == "tool" {
+			role = "user"
+		}
+		messages = append(messages, map[string]interface{}{"role": role, "content": blocks})
+	}
+	return system, messages, nil
+}
+
+// ollamaMessages converts a conversation history to the format of Ollama's
+// chat API, where tool call arguments are JSON objects and images are
+// base64 strings.
+func ollamaMessages(history []Message) ([]map[string]interface{}, error) {
+	messages := make([]map[string]interface{}, 0, len(history))
+	for _, m := range history {
+		message := map[string]interface{}{"role": m.Role, "content": m.Content}
+		if len(m.Attachments) > 0 {
+			images, err := ollamaImages(m.Attachments)
+			if err != nil {
+				return nil, err
+			}
+			message["images"] = images
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, len(m.ToolCalls))
+			for i, call := range m.ToolCalls {
+				arguments, err := messageToolArguments(call)
+				if err != nil {
+					return nil, err
+				}
+				calls[i] = map[string]interface{}{
+					"function": map[string]interface{}{
+						"name":      call.Name,
+						"arguments": arguments,
+					},
+				}
+			}
+			message["tool_calls"] = calls
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// messageToolArguments returns the arguments of a tool call of the history
+// as a JSON object.
+func messageToolArguments(call ToolCall) (json.RawMessage, error) {
+	arguments, err := toolArguments(call.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("tool call %s in messages: %w", call.Name, err)
+	}
+	return arguments, nil
+}
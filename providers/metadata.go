@@ -0,0 +1,34 @@
+package providers
+
+// takeUserMetadata removes the end-user identifier and the request metadata,
+// passed as the "user" and "metadata" options, and returns them, for
+// providers that send them in their own format.
+func takeUserMetadata(options map[string]interface{}) (string, map[string]interface{}) {
+	user, _ := options["user"].(string)
+	var metadata map[string]interface{}
+	switch m := options["metadata"].(type) {
+	case map[string]interface{}:
+		metadata = m
+	case map[string]string:
+		metadata = make(map[string]interface{}, len(m))
+		for k, v := range m {
+			metadata[k] = v
+		}
+	}
+	delete(options, "user")
+	delete(options, "metadata")
+	return user, metadata
+}
+
+// anthropicMetadata returns the metadata field of a Messages API request.
+// The API only accepts a user_id, so other metadata is not sent.
+func anthropicMetadata(options map[string]interface{}) map[string]interface{} {
+	user, metadata := takeUserMetadata(options)
+	if user == "" {
+		user, _ = metadata["user_id"].(string)
+	}
+	if user == "" {
+		return nil
+	}
+	return map[string]interface{}{"user_id": user}
+}
@@ -15,6 +15,7 @@ import (
 // including chat completion and structured output.
 type MistralProvider struct {
 	apiKey       string                 // API key for authentication
+	baseURL      string                 // API base URL, changed with SetEndpoint
 	model        string                 // Model identifier (e.g., "mistral-large", "mistral-medium")
 	extraHeaders map[string]string      // Additional HTTP headers
 	options      map[string]interface{} // Model-specific options
@@ -37,6 +38,7 @@ func NewMistralProvider(apiKey, model string, extraHeaders map[string]string) Pr
 	}
 	return &MistralProvider{
 		apiKey:       apiKey,
+		baseURL:      "https://api.mistral.ai",
 		model:        model,
 		extraHeaders: extraHeaders,
 		options:      make(map[string]interface{}),
@@ -56,6 +58,11 @@ func (p *MistralProvider) SetLogger(logger utils.Logger) {
 //   - max_tokens: Maximum tokens in the response
 //   - top_p: Nucleus sampling parameter
 //   - random_seed: Random seed for deterministic sampling
+//   - safe_prompt: Prepends Mistral's safety system prompt when true
+//   - parallel_tool_calls: Allows several tool calls in one response (default true)
+//   - response_format: {"type": "json_object"} enables JSON mode
+//   - embedding_model: Model used by the embeddings endpoint (default "mistral-embed")
+//   - fim_model: Model used by the FIM endpoint (default "codestral-latest")
 func (p *MistralProvider) SetOption(key string, value interface{}) {
 	p.options[key] = value
 }
@@ -66,7 +73,10 @@ func (p *MistralProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
 	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
+		p.SetOption("random_seed", *config.Seed)
+	}
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
 	}
 }
 
@@ -76,9 +86,22 @@ func (p *MistralProvider) Name() string {
 }
 
 // Endpoint returns the Mistral API endpoint URL.
-// This is "https://api.mistral.ai/v1/chat/completions".
+// This is "https://api.mistral.ai/v1/chat/completions",
+// unless changed with SetEndpoint.
 func (p *MistralProvider) Endpoint() string {
-	return "https://api.mistral.ai/v1/chat/completions"
+	return p.baseURL + "/v1/chat/completions"
+}
+
+// SetEndpoint points the provider at baseURL instead of the Mistral API, such
+// as an OpenAI-compatible gateway or a proxy. A trailing /v1 is accepted.
+func (p *MistralProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1")
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when Mistral does not serve it.
+func (p *MistralProvider) ModelEndpoint() string {
+	return p.baseURL + "/v1/models/" + p.model
 }
 
 // SupportsJSONSchema indicates that Mistral supports structured output
@@ -87,6 +110,11 @@ func (p *MistralProvider) SupportsJSONSchema() bool {
 	return true
 }
 
+// SupportsJSONMode reports that Mistral offers both JSON modes.
+func (p *MistralProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema
+}
+
 // Headers returns the required HTTP headers for Mistral API requests.
 // This includes:
 //   - Authorization: Bearer token using the API key
@@ -120,28 +148,77 @@ func (p *MistralProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *MistralProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
-	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]interface{}{
-			{"role": "user", "content": prompt},
-		},
+	content, err := openAIUserContent(p.Name(), prompt, takeAttachments(options), false)
+	if err != nil {
+		return nil, err
 	}
 
-	// First, add the default options
-	for k, v := range p.options {
-		requestBody[k] = v
+	requestBody := map[string]interface{}{
+		"model":    p.model,
+		"messages": mistralMessages(options, content),
 	}
 
-	// Then, add any additional options (which may override defaults)
-	for k, v := range options {
-		requestBody[k] = v
+	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		mistralTools := make([]map[string]interface{}, len(tools))
+		for i, tool := range tools {
+			mistralTools[i] = map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Function.Name,
+					"description": tool.Function.Description,
+					"parameters":  tool.Function.Parameters,
+				},
+			}
+		}
+		requestBody["tools"] = mistralTools
 	}
+	if toolChoice, ok := options["tool_choice"].(string); ok {
+		requestBody["tool_choice"] = toolChoice
+	}
+
+	// First, add the default options
+	p.addOptions(requestBody, options)
 
 	return json.Marshal(requestBody)
 }
 
+// mistralMessages builds the message list, with the system prompt first and the
+// response prefill, if any, last as a prefix the model continues.
+func mistralMessages(options map[string]interface{}, content interface{}) []map[string]interface{} {
+	var messages []map[string]interface{}
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, map[string]interface{}{"role": "user", "content": content})
+	if prefill := takePrefill(options); prefill != "" {
+		message := prefillMessage(prefill)
+		message["prefix"] = true
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// SupportsPrefill reports that replies can be primed with a prefill.
+func (p *MistralProvider) SupportsPrefill() bool {
+	return true
+}
+
+// addOptions copies the default options, then the request options, into
+// requestBody. Keys consumed while building the request are skipped.
+func (p *MistralProvider) addOptions(requestBody map[string]interface{}, options map[string]interface{}) {
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "tools", "tool_choice", "system_prompt", "strict", "embedding_model", "fim_model", "strict_tools", "user", "metadata":
+			default:
+				requestBody[k] = v
+			}
+		}
+	}
+}
+
 // PrepareRequestWithSchema creates a request that includes structured output formatting.
-// This uses Mistral's system prompts to enforce response structure.
+// This uses Mistral's json_schema response format to enforce response structure.
 //
 // Parameters:
 //   - prompt: The input text or conversation
@@ -152,26 +229,20 @@ func (p *MistralProvider) PrepareRequest(prompt string, options map[string]inter
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *MistralProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
-	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"response_format": map[string]interface{}{
-			"type":   "json_schema",
-			"schema": schema,
-		},
+	content, err := openAIUserContent(p.Name(), prompt, takeAttachments(options), false)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add any additional options
-	for k, v := range options {
-		requestBody[k] = v
+	strict, _ := options["strict"].(bool)
+	requestBody := map[string]interface{}{
+		"model":           p.model,
+		"messages":        mistralMessages(options, content),
+		"response_format": schemaResponseFormat(takeJSONMode(options), schema, strict),
 	}
 
-	// Add strict option if provided
-	if strict, ok := options["strict"].(bool); ok && strict {
-		requestBody["response_format"].(map[string]interface{})["strict"] = true
-	}
+	// Add any additional options
+	p.addOptions(requestBody, options)
 
 	return json.Marshal(requestBody)
 }
@@ -204,49 +275,148 @@ func (p *MistralProvider) ParseResponse(body []byte) (string, error) {
 		return "", fmt.Errorf("error parsing response: %w", err)
 	}
 
-	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	message := response.Choices[0].Message
+	if message.Content == "" && len(message.ToolCalls) == 0 {
 		return "", fmt.Errorf("empty response from API")
 	}
 
-	// Combine content and tool calls
+	// Combine content and tool calls; parallel calls are listed one per line
 	var finalResponse strings.Builder
-	finalResponse.WriteString(response.Choices[0].Message.Content)
+	finalResponse.WriteString(message.Content)
 
 	// Process tool calls if present
-	for _, toolCall := range response.Choices[0].Message.ToolCalls {
-		// Parse arguments as raw JSON to preserve the exact format
-		var args interface{}
-		if err := json.Unmarshal(toolCall.Function.Arguments, &args); err != nil {
-			return "", fmt.Errorf("error parsing function arguments: %w", err)
+	if len(message.ToolCalls) > 0 {
+		calls, err := p.ParseToolCalls(body)
+		if err != nil {
+			return "", err
 		}
-
-		functionCall, err := utils.FormatFunctionCall(toolCall.Function.Name, args)
+		functionCalls, err := formatToolCalls(calls)
 		if err != nil {
-			return "", fmt.Errorf("error formatting function call: %w", err)
+			return "", err
 		}
 		if finalResponse.Len() > 0 {
 			finalResponse.WriteString("\n")
 		}
-		finalResponse.WriteString(functionCall)
+		finalResponse.WriteString(functionCalls)
 	}
 
 	return finalResponse.String(), nil
 }
 
-// HandleFunctionCalls processes structured output in the response.
-// This supports Mistral's response formatting capabilities.
-func (p *MistralProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	response := string(body)
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
+// EmbeddingEndpoint returns the Mistral embeddings endpoint URL.
+func (p *MistralProvider) EmbeddingEndpoint() string {
+	return p.baseURL + "/v1/embeddings"
+}
+
+// PrepareEmbeddingRequest creates the request body for embedding inputs
+// with the embedding_model option, "mistral-embed" by default.
+func (p *MistralProvider) PrepareEmbeddingRequest(inputs []string, options map[string]interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"model": p.stringOption(options, "embedding_model", "mistral-embed"),
+		"input": inputs,
+	})
+}
+
+// ParseEmbeddingResponse returns the embedding vectors in input order.
+func (p *MistralProvider) ParseEmbeddingResponse(body []byte) ([][]float64, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing embedding response: %w", err)
 	}
 
-	if len(functionCalls) == 0 {
-		return nil, nil // No function calls found
+	vectors := make([][]float64, len(response.Data))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// FIMEndpoint returns the Codestral fill-in-the-middle endpoint URL.
+func (p *MistralProvider) FIMEndpoint() string {
+	return p.baseURL + "/v1/fim/completions"
+}
+
+// PrepareFIMRequest creates a Codestral request completing the code between
+// prefix and suffix. The model is taken from the fim_model option, falling
+// back to the provider's model when it is a Codestral model and to
+// "codestral-latest" otherwise.
+func (p *MistralProvider) PrepareFIMRequest(prefix, suffix string, options map[string]interface{}) ([]byte, error) {
+	model := "codestral-latest"
+	if strings.HasPrefix(p.model, "codestral") {
+		model = p.model
+	}
+
+	requestBody := map[string]interface{}{
+		"model":  p.stringOption(options, "fim_model", model),
+		"prompt": prefix,
+	}
+	if suffix != "" {
+		requestBody["suffix"] = suffix
+	}
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "temperature", "max_tokens", "min_tokens", "top_p", "stop", "random_seed":
+				requestBody[k] = v
+			}
+		}
+	}
+	return json.Marshal(requestBody)
+}
+
+// ParseFIMResponse extracts the completion from a FIM response, which uses
+// the chat completion format.
+func (p *MistralProvider) ParseFIMResponse(body []byte) (string, error) {
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// stringOption returns the string option key from the request options or
+// the provider defaults, or fallback when neither sets it.
+func (p *MistralProvider) stringOption(options map[string]interface{}, key, fallback string) string {
+	if v, ok := options[key].(string); ok && v != "" {
+		return v
+	}
+	if v, ok := p.options[key].(string); ok && v != "" {
+		return v
 	}
+	return fallback
+}
 
-	return json.Marshal(functionCalls)
+// ParseToolCalls returns every tool call of a Mistral response, including
+// parallel calls.
+func (p *MistralProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	return parseOpenAIToolCalls(body)
+}
+
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or nil when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *MistralProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(p.ParseToolCalls, body)
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -268,18 +438,10 @@ func (p *MistralProvider) PrepareStreamRequest(prompt string, options map[string
 
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *MistralProvider) ParseStreamResponse(chunk []byte) (string, error) {
-	var response struct {
-		Choices []struct {
-			Delta struct {
-				Content string `json:"content"`
-			} `json:"delta"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", err
-	}
-	if len(response.Choices) == 0 {
-		return "", nil
-	}
-	return response.Choices[0].Delta.Content, nil
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a streaming chunk into typed events.
+func (p *MistralProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	return parseOpenAIStreamEvent(data)
 }
@@ -95,7 +95,12 @@ func (p *OllamaProvider) SetDefaultOptions(config *config.Config) {
 	if config.OllamaEndpoint != "" {
 		p.SetEndpoint(config.OllamaEndpoint)
 	}
-	p.SetOption("top_p", config.TopP)
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
+	}
+	if config.TopK != nil {
+		p.SetOption("top_k", *config.TopK)
+	}
 	p.SetOption("min_p", config.MinP)
 	p.SetOption("repeat_penalty", config.RepeatPenalty)
 	p.SetOption("repeat_last_n", config.RepeatLastN)
@@ -106,9 +111,9 @@ func (p *OllamaProvider) SetDefaultOptions(config *config.Config) {
 }
 
 // SupportsJSONSchema indicates whether this provider supports JSON schema validation.
-// Currently, Ollama does not natively support JSON schema validation.
+// Ollama constrains the output to the schema passed as its format.
 func (p *OllamaProvider) SupportsJSONSchema() bool {
-	return false
+	return true
 }
 
 // Headers returns the HTTP headers required for Ollama API requests.
@@ -130,24 +135,116 @@ func (p *OllamaProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *OllamaProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	if _, ok := options["messages"]; ok {
+		return p.prepareChatRequest(prompt, options)
+	}
 	requestBody := map[string]interface{}{
 		"model":  p.model,
 		"prompt": prompt,
 	}
 
+	// Ollama takes images as base64 strings alongside the prompt
+	images, err := ollamaImages(takeAttachments(options))
+	if err != nil {
+		return nil, err
+	}
+	if len(images) > 0 {
+		requestBody["images"] = images
+	}
+	// Ollama takes the sampling defaults in the options object of the request
+	if sampling := samplingDefaults(p.options, options); len(sampling) > 0 {
+		requestBody["options"] = sampling
+	}
+
 	for k, v := range options {
-		requestBody[k] = v
+		if k != "user" && k != "metadata" {
+			requestBody[k] = v
+		}
 	}
 
 	return json.Marshal(requestBody)
 }
 
+// prepareChatRequest creates the request body for the chat endpoint, which
+// takes the conversation history as messages, followed by the prompt.
+func (p *OllamaProvider) prepareChatRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	history, err := ollamaMessages(takeMessages(options))
+	if err != nil {
+		return nil, err
+	}
+	images, err := ollamaImages(takeAttachments(options))
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []map[string]interface{}
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, history...)
+	if len(history) == 0 || prompt != "" || len(images) > 0 {
+		message := map[string]interface{}{"role": "user", "content": prompt}
+		if len(images) > 0 {
+			message["images"] = images
+		}
+		messages = append(messages, message)
+	}
+
+	requestBody := map[string]interface{}{
+		"model":    p.model,
+		"messages": messages,
+	}
+	// Ollama takes the sampling defaults in the options object of the request
+	if sampling := samplingDefaults(p.options, options); len(sampling) > 0 {
+		requestBody["options"] = sampling
+	}
+	for k, v := range options {
+		if k != "system_prompt" && k != "user" && k != "metadata" {
+			requestBody[k] = v
+		}
+	}
+	return json.Marshal(requestBody)
+}
+
+// ImageLimits reports that Ollama only takes images as base64 data.
+func (p *OllamaProvider) ImageLimits() ImageLimits {
+	return ImageLimits{InlineOnly: true}
+}
+
+// ollamaImages returns the base64 data of image attachments, the only kind
+// Ollama accepts.
+func ollamaImages(attachments []utils.Attachment) ([]string, error) {
+	images := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		if a.Type != utils.AttachmentImage || a.URL != "" {
+			return nil, unsupportedAttachment("ollama", a)
+		}
+		data, _, err := a.Base64()
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// SupportsMessages reports that conversation histories are sent as messages
+// to the chat endpoint.
+func (p *OllamaProvider) SupportsMessages() bool {
+	return true
+}
+
+// MessageEndpoint returns the chat endpoint, which requests with a
+// conversation history are sent to.
+func (p *OllamaProvider) MessageEndpoint() string {
+	return p.endpoint + "/api/chat"
+}
+
 // PrepareRequestWithSchema creates a request with JSON schema validation.
-// Since Ollama doesn't support schema validation natively, this falls back to
-// standard request preparation.
+// The schema is sent as the request format, which Ollama enforces while
+// decoding.
 func (p *OllamaProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
-	// Ollama doesn't support JSON schema validation natively
-	// We'll just use the regular PrepareRequest method
+	options["format"] = schema
 	return p.PrepareRequest(prompt, options)
 }
 
@@ -168,12 +265,17 @@ func (p *OllamaProvider) ParseResponse(body []byte) (string, error) {
 		var response struct {
 			Model    string `json:"model"`
 			Response string `json:"response"`
-			Done     bool   `json:"done"`
+			Message  struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Done bool `json:"done"`
 		}
 		if err := decoder.Decode(&response); err != nil {
 			return "", fmt.Errorf("error parsing Ollama response: %w", err)
 		}
+		// Chat responses carry the text in the message
 		fullResponse.WriteString(response.Response)
+		fullResponse.WriteString(response.Message.Content)
 		if response.Done {
 			break
 		}
@@ -182,20 +284,75 @@ func (p *OllamaProvider) ParseResponse(body []byte) (string, error) {
 	return fullResponse.String(), nil
 }
 
-// HandleFunctionCalls processes function calling capabilities.
-// Since Ollama doesn't support function calling natively, this returns nil.
-func (p *OllamaProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	response := string(body)
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
+// FIMEndpoint returns the generate endpoint, which completes the text
+// between a prompt and a suffix for models with a fill-in-the-middle
+// template, such as StarCoder, CodeLlama or Qwen Coder.
+func (p *OllamaProvider) FIMEndpoint() string {
+	return p.Endpoint()
+}
+
+// PrepareFIMRequest creates a generate request completing the text between
+// prefix and suffix. Ollama formats them with the model's FIM template.
+func (p *OllamaProvider) PrepareFIMRequest(prefix, suffix string, options map[string]interface{}) ([]byte, error) {
+	requestBody := map[string]interface{}{
+		"model":  p.model,
+		"prompt": prefix,
+		"suffix": suffix,
+		"stream": false,
 	}
+	for k, v := range options {
+		switch k {
+		case "system_prompt", "model", "prompt", "suffix", "stream", "user", "metadata":
+		default:
+			requestBody[k] = v
+		}
+	}
+	return json.Marshal(requestBody)
+}
+
+// ParseFIMResponse extracts the completion from a generate response.
+func (p *OllamaProvider) ParseFIMResponse(body []byte) (string, error) {
+	return p.ParseResponse(body)
+}
+
+// CompletionEndpoint returns the generate endpoint, which continues raw
+// text when the model's prompt template is bypassed.
+func (p *OllamaProvider) CompletionEndpoint() string {
+	return p.Endpoint()
+}
 
-	if len(functionCalls) == 0 {
-		return nil, nil // No function calls found
+// PrepareCompletionRequest creates a raw generate request continuing text
+// without applying the model's prompt template.
+func (p *OllamaProvider) PrepareCompletionRequest(text string, options map[string]interface{}) ([]byte, error) {
+	requestBody := map[string]interface{}{
+		"model":  p.model,
+		"prompt": text,
+		"raw":    true,
+		"stream": false,
+	}
+	if sampling := samplingDefaults(p.options, options); len(sampling) > 0 {
+		requestBody["options"] = sampling
 	}
+	for k, v := range options {
+		switch k {
+		case "system_prompt", "model", "prompt", "raw", "stream", "user", "metadata":
+		default:
+			requestBody[k] = v
+		}
+	}
+	return json.Marshal(requestBody)
+}
+
+// ParseCompletionResponse extracts the continuation from a generate response.
+func (p *OllamaProvider) ParseCompletionResponse(body []byte) (string, error) {
+	return p.ParseResponse(body)
+}
 
-	return json.Marshal(functionCalls)
+// HandleFunctionCalls returns the function calls of text in the legacy
+// <function_call> format as a JSON array, or nil when there are none.
+// Ollama's chat responses carry no structured tool calls.
+func (p *OllamaProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	return handleFunctionCalls(nil, body)
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -277,12 +434,44 @@ func (p *OllamaProvider) PrepareStreamRequest(prompt string, options map[string]
 
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *OllamaProvider) ParseStreamResponse(chunk []byte) (string, error) {
+	return streamText(p.ParseStreamEvent("", chunk))
+}
+
+// ParseStreamEvent translates a line of Ollama's newline-delimited JSON
+// stream into typed events. The final line carries the token counts.
+func (p *OllamaProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
 	var response struct {
 		Response string `json:"response"`
-		Done     bool   `json:"done"`
+		Message  struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Done            bool   `json:"done"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		Error           string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("ollama: %s", response.Error)
+	}
+
+	var events []StreamEvent
+	if text := response.Response + response.Message.Content; text != "" {
+		events = append(events, StreamEvent{Type: StreamEventDelta, Text: text})
 	}
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", err
+	if response.Done {
+		events = append(events,
+			StreamEvent{Type: StreamEventUsage, Usage: &StreamUsage{
+				PromptTokens:     response.PromptEvalCount,
+				CompletionTokens: response.EvalCount,
+			}},
+			StreamEvent{Type: StreamEventDone},
+		)
 	}
-	return response.Response, nil
+	return events, nil
 }
@@ -0,0 +1,194 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestOllamaPrepareRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  string
+		options map[string]interface{}
+		fields  map[string]string
+		absent  []string
+	}{
+		{
+			name:    "generate request",
+			prompt:  "Hi",
+			options: map[string]interface{}{"format": "json"},
+			fields: map[string]string{
+				"model":   `"llama3"`,
+				"prompt":  `"Hi"`,
+				"format":  `"json"`,
+				"options": `{"seed":42}`,
+			},
+			absent: []string{"messages"},
+		},
+		{
+			name:   "generate request with images",
+			prompt: "Describe",
+			options: map[string]interface{}{"attachments": []utils.Attachment{
+				{Type: utils.AttachmentImage, Data: []byte("png"), MediaType: "image/png"},
+			}},
+			fields: map[string]string{"images": `["cG5n"]`},
+			absent: []string{"attachments"},
+		},
+		{
+			name:   "sampling options of the request override the defaults",
+			prompt: "Hi",
+			options: map[string]interface{}{
+				"seed": 7,
+			},
+			fields: map[string]string{"seed": `7`},
+			absent: []string{"options"},
+		},
+		{
+			name:    "generate request without user and metadata",
+			prompt:  "Hi",
+			options: map[string]interface{}{"user": "user-1", "metadata": map[string]string{"team": "search"}},
+			fields:  map[string]string{"prompt": `"Hi"`},
+			absent:  []string{"user", "metadata"},
+		},
+		{
+			name:   "chat request without user and metadata",
+			prompt: "Hi",
+			options: map[string]interface{}{
+				"messages": []Message{{Role: "user", Content: "Hello"}},
+				"user":     "user-1",
+				"metadata": map[string]string{"team": "search"},
+			},
+			fields: map[string]string{"messages": `[{"role":"user","content":"Hello"},{"role":"user","content":"Hi"}]`},
+			absent: []string{"user", "metadata"},
+		},
+		{
+			name:   "chat request with history",
+			prompt: "And tomorrow?",
+			options: map[string]interface{}{
+				"system_prompt": "Be brief.",
+				"messages": []Message{
+					{Role: "user", Content: "Weather in Paris?"},
+					{Role: "assistant", ToolCalls: []ToolCall{{Name: "get_weather", Arguments: json.RawMessage(`"{\"city\":\"Paris\"}"`)}}},
+					{Role: "tool", Content: "18C"},
+				},
+			},
+			fields: map[string]string{
+				"messages": `[
+					{"role":"system","content":"Be brief."},
+					{"role":"user","content":"Weather in Paris?"},
+					{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},
+					{"role":"tool","content":"18C"},
+					{"role":"user","content":"And tomorrow?"}
+				]`,
+				"options": `{"seed":42}`,
+			},
+			absent: []string{"prompt", "system_prompt"},
+		},
+		{
+			name:   "chat request with images",
+			prompt: "",
+			options: map[string]interface{}{"messages": []Message{
+				{Role: "user", Content: "Describe", Attachments: []utils.Attachment{
+					{Type: utils.AttachmentImage, Data: []byte("png"), MediaType: "image/png"},
+				}},
+			}},
+			fields: map[string]string{
+				"messages": `[{"role":"user","content":"Describe","images":["cG5n"]}]`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOllamaProvider("http://localhost:11434", "llama3", nil)
+			p.SetOption("seed", 42)
+			body, err := p.PrepareRequest(tt.prompt, tt.options)
+			require.NoError(t, err)
+			for key, want := range tt.fields {
+				assert.JSONEq(t, want, requestField(t, body, key), key)
+			}
+			request := decodeRequest(t, body)
+			for _, key := range tt.absent {
+				assert.NotContains(t, request, key)
+			}
+		})
+	}
+}
+
+func TestOllamaImagesByURL(t *testing.T) {
+	p := NewOllamaProvider("http://localhost:11434", "llava", nil)
+	_, err := p.PrepareRequest("Describe", map[string]interface{}{"attachments": []utils.Attachment{
+		{Type: utils.AttachmentImage, URL: "https://example.com/cat.png"},
+	}})
+	assert.Error(t, err)
+}
+
+func TestOllamaEndpoints(t *testing.T) {
+	p := NewOllamaProvider("http://localhost:11434", "llama3", nil)
+	assert.Equal(t, "http://localhost:11434/api/generate", p.Endpoint())
+	assert.Equal(t, "http://localhost:11434/api/chat", p.(MessageEndpointProvider).MessageEndpoint())
+}
+
+func TestOllamaPrepareStreamRequest(t *testing.T) {
+	p := NewOllamaProvider("http://localhost:11434", "llama3", nil)
+	body, err := p.PrepareStreamRequest("Hi", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, true, decodeRequest(t, body)["stream"])
+}
+
+func TestOllamaParseStreamEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		events []StreamEvent
+		err    bool
+	}{
+		{name: "empty", data: "\n"},
+		{
+			name:   "generate chunk",
+			data:   `{"model":"llama3","response":"Hel","done":false}`,
+			events: []StreamEvent{{Type: StreamEventDelta, Text: "Hel"}},
+		},
+		{
+			name:   "chat chunk",
+			data:   `{"model":"llama3","message":{"role":"assistant","content":"lo"},"done":false}`,
+			events: []StreamEvent{{Type: StreamEventDelta, Text: "lo"}},
+		},
+		{
+			name: "final chunk",
+			data: `{"model":"llama3","response":"","done":true,"prompt_eval_count":12,"eval_count":34}`,
+			events: []StreamEvent{
+				{Type: StreamEventUsage, Usage: &StreamUsage{PromptTokens: 12, CompletionTokens: 34}},
+				{Type: StreamEventDone},
+			},
+		},
+		{name: "error", data: `{"error":"model not found"}`, err: true},
+		{name: "malformed", data: `{"response":`, err: true},
+	}
+
+	p := NewOllamaProvider("http://localhost:11434", "llama3", nil).(StreamEventParser)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := p.ParseStreamEvent("", []byte(tt.data))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
+
+func TestOllamaParseResponse(t *testing.T) {
+	p := NewOllamaProvider("http://localhost:11434", "llama3", nil)
+	text, err := p.ParseResponse([]byte(`{"response":"Hel","done":false}
+{"response":"lo","done":true}
+{"response":"ignored","done":false}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+}
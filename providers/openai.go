@@ -2,11 +2,8 @@
 package providers
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"strings"
 
 	"github.com/teilomillet/gollm/config"
 	"github.com/teilomillet/gollm/utils"
@@ -17,6 +14,7 @@ import (
 // including function calling, JSON mode, and structured output validation.
 type OpenAIProvider struct {
 	apiKey       string                 // API key for authentication
+	baseURL      string                 // API base URL, changed with SetEndpoint
 	model        string                 // Model identifier (e.g., "gpt-4", "gpt-4o-mini")
 	extraHeaders map[string]string      // Additional HTTP headers
 	options      map[string]interface{} // Model-specific options
@@ -39,6 +37,7 @@ func NewOpenAIProvider(apiKey, model string, extraHeaders map[string]string) Pro
 	}
 	return &OpenAIProvider{
 		apiKey:       apiKey,
+		baseURL:      "https://api.openai.com",
 		model:        model,
 		extraHeaders: extraHeaders,
 		options:      make(map[string]interface{}),
@@ -60,6 +59,7 @@ func (p *OpenAIProvider) SetLogger(logger utils.Logger) {
 //   - frequency_penalty: Repetition reduction
 //   - presence_penalty: Topic steering
 //   - seed: Deterministic sampling seed
+//   - service_tier: Processing tier ("auto", "default", "flex")
 func (p *OpenAIProvider) SetOption(key string, value interface{}) {
 	p.options[key] = value
 	p.logger.Debug("Option set", "key", key, "value", value)
@@ -73,6 +73,12 @@ func (p *OpenAIProvider) SetDefaultOptions(config *config.Config) {
 	if config.Seed != nil {
 		p.SetOption("seed", *config.Seed)
 	}
+	if config.TopPSet() {
+		p.SetOption("top_p", config.TopP)
+	}
+	if config.ServiceTier != "" {
+		p.SetOption("service_tier", config.ServiceTier)
+	}
 	p.logger.Debug("Default options set", "temperature", config.Temperature, "max_tokens", config.MaxTokens, "seed", config.Seed)
 }
 
@@ -82,15 +88,69 @@ func (p *OpenAIProvider) Name() string {
 }
 
 // Endpoint returns the OpenAI API endpoint URL.
-// For API version 1, this is "https://api.openai.com/v1/chat/completions".
+// For API version 1, this is "https://api.openai.com/v1/chat/completions",
+// unless changed with SetEndpoint.
 func (p *OpenAIProvider) Endpoint() string {
-	return "https://api.openai.com/v1/chat/completions"
+	return p.baseURL + "/v1/chat/completions"
+}
+
+// SetEndpoint points the provider at baseURL instead of the OpenAI API, such
+// as an OpenAI-compatible gateway or a proxy. A trailing /v1 is accepted.
+func (p *OpenAIProvider) SetEndpoint(baseURL string) {
+	p.baseURL = trimBaseURL(baseURL, "/v1")
+}
+
+// CompletionEndpoint returns the URL of the legacy completions endpoint,
+// served by models such as gpt-3.5-turbo-instruct and davinci-002.
+func (p *OpenAIProvider) CompletionEndpoint() string {
+	return p.baseURL + "/v1/completions"
+}
+
+// ModelEndpoint returns the URL describing the configured model, which
+// answers 404 when OpenAI does not serve it.
+func (p *OpenAIProvider) ModelEndpoint() string {
+	return p.baseURL + "/v1/models/" + p.model
+}
+
+// PrepareCompletionRequest creates a legacy completions request continuing text.
+func (p *OpenAIProvider) PrepareCompletionRequest(text string, options map[string]interface{}) ([]byte, error) {
+	return openAICompletionRequest(p.model, text, p.options, options)
+}
+
+// ParseCompletionResponse extracts the continuation from a completions response.
+func (p *OpenAIProvider) ParseCompletionResponse(body []byte) (string, error) {
+	return parseOpenAICompletion(body)
+}
+
+// ScoringEndpoint returns the legacy completions endpoint, which echoes the
+// scored text with log probabilities.
+func (p *OpenAIProvider) ScoringEndpoint() string {
+	return p.CompletionEndpoint()
 }
 
-// SupportsJSONSchema indicates that OpenAI supports native JSON schema validation
-// through its function calling and JSON mode capabilities.
+// PrepareScoringRequest creates a legacy completions request echoing text
+// with the log probability of each token.
+func (p *OpenAIProvider) PrepareScoringRequest(text string, options map[string]interface{}) ([]byte, error) {
+	return openAIScoringRequest(p.model, text, p.options, options)
+}
+
+// ParseScoringResponse extracts the echoed tokens with their log probabilities.
+func (p *OpenAIProvider) ParseScoringResponse(body []byte) ([]TokenLogprob, error) {
+	return parseOpenAIScoring(body)
+}
+
+// SupportsJSONSchema indicates whether the model enforces JSON schemas natively
+// through structured outputs. Models without a known entry in
+// LookupModelCapabilities, such as proxied custom names, are assumed to.
 func (p *OpenAIProvider) SupportsJSONSchema() bool {
-	return true
+	caps, ok := LookupModelCapabilities(p.Name(), p.model)
+	return !ok || caps.JSONSchema
+}
+
+// SupportsJSONMode reports that OpenAI offers JSON mode on every model, and
+// strict schemas on the models that support them.
+func (p *OpenAIProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema && p.SupportsJSONSchema()
 }
 
 // Headers returns the required HTTP headers for OpenAI API requests.
@@ -127,6 +187,13 @@ func (p *OpenAIProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	history := takeMessages(options)
+	attachments := takeAttachments(options)
+	content, err := openAIUserContent(p.Name(), prompt, attachments, true)
+	if err != nil {
+		return nil, err
+	}
+
 	request := map[string]interface{}{
 		"model":    p.model,
 		"messages": []map[string]interface{}{},
@@ -140,11 +207,23 @@ func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interf
 		})
 	}
 
-	// Add user message
-	request["messages"] = append(request["messages"].([]map[string]interface{}), map[string]interface{}{
-		"role":    "user",
-		"content": prompt,
-	})
+	// Add the conversation history, then the user message
+	messages, err := openAIMessages(p.Name(), history, true)
+	if err != nil {
+		return nil, err
+	}
+	request["messages"] = append(request["messages"].([]map[string]interface{}), messages...)
+	if len(history) == 0 || prompt != "" || len(attachments) > 0 {
+		request["messages"] = append(request["messages"].([]map[string]interface{}), map[string]interface{}{
+			"role":    "user",
+			"content": content,
+		})
+	}
+
+	// A trailing assistant message primes the reply
+	if prefill := takePrefill(options); prefill != "" {
+		request["messages"] = append(request["messages"].([]map[string]interface{}), prefillMessage(prefill))
+	}
 
 	// Handle tool_choice
 	if toolChoice, ok := options["tool_choice"].(string); ok {
@@ -153,34 +232,88 @@ func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interf
 
 	// Handle tools
 	if tools, ok := options["tools"].([]utils.Tool); ok && len(tools) > 0 {
-		openAITools := make([]map[string]interface{}, len(tools))
-		for i, tool := range tools {
-			openAITools[i] = map[string]interface{}{
-				"type": "function",
-				"function": map[string]interface{}{
-					"name":        tool.Function.Name,
-					"description": tool.Function.Description,
-					"parameters":  tool.Function.Parameters,
-				},
-				"strict": true, // Add this if you want strict mode
-			}
-		}
-		request["tools"] = openAITools
+		request["tools"] = p.openAITools(tools, p.strictTools(options))
 	}
 
 	// Add other options
-	for k, v := range p.options {
-		if k != "tools" && k != "tool_choice" && k != "system_prompt" {
-			request[k] = v
+	for _, opts := range []map[string]interface{}{p.options, options} {
+		for k, v := range opts {
+			switch k {
+			case "tools", "tool_choice", "system_prompt", "strict_tools":
+			default:
+				request[k] = v
+			}
 		}
 	}
-	for k, v := range options {
-		if k != "tools" && k != "tool_choice" && k != "system_prompt" {
-			request[k] = v
+
+	return json.Marshal(request)
+}
+
+// ImageLimits returns the limits of the chat completions API: 500 images of
+// 20 MB each.
+func (p *OpenAIProvider) ImageLimits() ImageLimits {
+	return ImageLimits{MaxImages: 500, MaxImageBytes: 20 << 20}
+}
+
+// SupportsPrefill reports that replies can be primed with a prefill, sent
+// as a trailing assistant message.
+func (p *OpenAIProvider) SupportsPrefill() bool {
+	return true
+}
+
+// SupportsMessages reports that conversation histories are sent as chat
+// messages.
+func (p *OpenAIProvider) SupportsMessages() bool {
+	return true
+}
+
+// SupportsReasoningEffort reports whether model is an o-series or GPT-5
+// model, which take the reasoning_effort field. o1-mini and o1-preview do
+// not.
+func (p *OpenAIProvider) SupportsReasoningEffort(model string) bool {
+	model = BaseModel(model)
+	if hasModelPrefix(model, "o1-mini", "o1-preview") {
+		return false
+	}
+	return hasModelPrefix(model, "o1", "o3", "o4", "gpt-5")
+}
+
+// strictTools reports whether tools are sent in strict mode, which is the
+// default. The strict_tools option set to false opts out.
+func (p *OpenAIProvider) strictTools(options map[string]interface{}) bool {
+	for _, opts := range []map[string]interface{}{options, p.options} {
+		if strict, ok := opts["strict_tools"].(bool); ok {
+			return strict
 		}
 	}
+	return true
+}
 
-	return json.Marshal(request)
+// openAITools converts tools to OpenAI's format. In strict mode, parameter
+// schemas are normalized to the rules of strict function calling; a tool
+// whose schema cannot be made strict is sent without strict mode.
+func (p *OpenAIProvider) openAITools(tools []utils.Tool, strict bool) []map[string]interface{} {
+	openAITools := make([]map[string]interface{}, len(tools))
+	for i, tool := range tools {
+		function := map[string]interface{}{
+			"name":        tool.Function.Name,
+			"description": tool.Function.Description,
+			"parameters":  tool.Function.Parameters,
+		}
+		if strict {
+			if parameters, ok := NormalizeStrictSchema(tool.Function.Parameters); ok {
+				function["parameters"] = parameters
+				function["strict"] = true
+			} else {
+				p.logger.Debug("Sending tool without strict mode", "tool", tool.Function.Name)
+			}
+		}
+		openAITools[i] = map[string]interface{}{
+			"type":     "function",
+			"function": function,
+		}
+	}
+	return openAITools
 }
 
 // PrepareRequestWithSchema creates a request that includes JSON schema validation.
@@ -196,6 +329,7 @@ func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interf
 //   - Any error encountered during preparation
 func (p *OpenAIProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
 	p.logger.Debug("Preparing request with schema", "prompt", prompt, "schema", schema)
+	mode := takeJSONMode(options)
 
 	// First, ensure we have a proper object for the schema
 	var schemaObj interface{}
@@ -228,19 +362,24 @@ func (p *OpenAIProvider) PrepareRequestWithSchema(prompt string, options map[str
 	cleanSchemaJSON, _ := json.MarshalIndent(cleanSchema, "", "  ")
 	p.logger.Debug("Cleaned schema for OpenAI", "schema", string(cleanSchemaJSON))
 
+	history := takeMessages(options)
+	attachments := takeAttachments(options)
+	content, err := openAIUserContent(p.Name(), prompt, attachments, true)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := openAIMessages(p.Name(), history, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 || prompt != "" || len(attachments) > 0 {
+		messages = append(messages, map[string]interface{}{"role": "user", "content": content})
+	}
+
 	request := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]interface{}{
-			{"role": "user", "content": prompt},
-		},
-		"response_format": map[string]interface{}{
-			"type": "json_schema",
-			"json_schema": map[string]interface{}{
-				"name":   "structured_response",
-				"schema": cleanSchema,
-				"strict": true,
-			},
-		},
+		"model":           p.model,
+		"messages":        messages,
+		"response_format": schemaResponseFormat(mode, cleanSchema, true),
 	}
 
 	// Handle system prompt as system message
@@ -249,10 +388,13 @@ func (p *OpenAIProvider) PrepareRequestWithSchema(prompt string, options map[str
 			{"role": "system", "content": systemPrompt},
 		}, request["messages"].([]map[string]interface{})...)
 	}
+	if prefill := takePrefill(options); prefill != "" {
+		request["messages"] = append(request["messages"].([]map[string]interface{}), prefillMessage(prefill))
+	}
 
 	// Add other options
 	for k, v := range options {
-		if k != "system_prompt" {
+		if k != "system_prompt" && k != "strict_tools" {
 			request[k] = v
 		}
 	}
@@ -338,41 +480,30 @@ func (p *OpenAIProvider) ParseResponse(body []byte) (string, error) {
 	}
 
 	if len(message.ToolCalls) > 0 {
-		var functionCalls []string
-		for _, call := range message.ToolCalls {
-			// Parse arguments as raw JSON to preserve the exact format
-			var args interface{}
-			if err := json.Unmarshal(call.Function.Arguments, &args); err != nil {
-				return "", fmt.Errorf("error parsing function arguments: %w", err)
-			}
-
-			functionCall, err := utils.FormatFunctionCall(call.Function.Name, args)
-			if err != nil {
-				return "", fmt.Errorf("error formatting function call: %w", err)
-			}
-			functionCalls = append(functionCalls, functionCall)
+		calls, err := p.ParseToolCalls(body)
+		if err != nil {
+			return "", err
 		}
-		return strings.Join(functionCalls, "\n"), nil
+		return formatToolCalls(calls)
 	}
 
 	return "", fmt.Errorf("no content or tool calls in response")
 }
 
-// HandleFunctionCalls processes function calling in the response.
-// This supports OpenAI's function calling and JSON mode features.
-func (p *OpenAIProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
-	response := string(body)
-	functionCalls, err := utils.ExtractFunctionCalls(response)
-	if err != nil {
-		return nil, fmt.Errorf("error extracting function calls: %w", err)
-	}
+// ParseToolCalls returns every tool call of an OpenAI response.
+func (p *OpenAIProvider) ParseToolCalls(body []byte) ([]ToolCall, error) {
+	return parseOpenAIToolCalls(body)
+}
 
-	if len(functionCalls) == 0 {
+// HandleFunctionCalls returns the tool calls of a response body as a JSON
+// array of ToolCall, or an error when there are none. Text in the legacy
+// <function_call> format is accepted too.
+func (p *OpenAIProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	calls, err := handleFunctionCalls(p.ParseToolCalls, body)
+	if err == nil && calls == nil {
 		return nil, fmt.Errorf("no function calls found in response")
 	}
-
-	p.logger.Debug("Function calls to handle", "calls", functionCalls)
-	return json.Marshal(functionCalls)
+	return calls, err
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -387,67 +518,21 @@ func (p *OpenAIProvider) SupportsStreaming() bool {
 	return true
 }
 
-// PrepareStreamRequest creates a request body for streaming API calls
+// PrepareStreamRequest creates a request body for streaming API calls. It
+// shares the messages, tools and options handling of PrepareRequest.
 func (p *OpenAIProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
-	// Start with regular request preparation
-	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"stream": true,
-	}
-
-	// Add other options
-	for k, v := range options {
-		if k != "stream" { // Don't override stream setting
-			requestBody[k] = v
-		}
-	}
-
-	return json.Marshal(requestBody)
+	options["stream"] = true
+	// Without this the final usage chunk is never sent
+	options["stream_options"] = map[string]interface{}{"include_usage": true}
+	return p.PrepareRequest(prompt, options)
 }
 
 // ParseStreamResponse processes a single chunk from a streaming response
 func (p *OpenAIProvider) ParseStreamResponse(chunk []byte) (string, error) {
-	// Skip empty lines
-	if len(bytes.TrimSpace(chunk)) == 0 {
-		return "", fmt.Errorf("empty chunk")
-	}
-
-	// Check for [DONE] marker
-	if bytes.Equal(bytes.TrimSpace(chunk), []byte("[DONE]")) {
-		return "", io.EOF
-	}
-
-	// Parse the chunk
-	var response struct {
-		Choices []struct {
-			Delta struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
-			} `json:"delta"`
-			FinishReason string `json:"finish_reason"`
-		} `json:"choices"`
-	}
-
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", fmt.Errorf("malformed response: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
-
-	// Handle finish reason
-	if response.Choices[0].FinishReason != "" {
-		return "", io.EOF
-	}
-
-	// Skip role-only messages
-	if response.Choices[0].Delta.Role != "" && response.Choices[0].Delta.Content == "" {
-		return "", fmt.Errorf("skip token")
-	}
+	return streamText(p.ParseStreamEvent("", chunk))
+}
 
-	return response.Choices[0].Delta.Content, nil
+// ParseStreamEvent translates a streaming chunk into typed events.
+func (p *OpenAIProvider) ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error) {
+	return parseOpenAIStreamEvent(data)
 }
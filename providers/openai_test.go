@@ -0,0 +1,231 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/utils"
+)
+
+func TestOpenAIPrepareRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		prompt   string
+		options  map[string]interface{}
+		messages string
+	}{
+		{
+			name:     "prompt",
+			prompt:   "Hello",
+			options:  map[string]interface{}{},
+			messages: `[{"role":"user","content":"Hello"}]`,
+		},
+		{
+			name:     "system prompt as developer message",
+			prompt:   "Hello",
+			options:  map[string]interface{}{"system_prompt": "Be brief."},
+			messages: `[{"role":"developer","content":"Be brief."},{"role":"user","content":"Hello"}]`,
+		},
+		{
+			name:   "history with tool call and result",
+			prompt: "",
+			options: map[string]interface{}{"messages": []Message{
+				{Role: "user", Content: "Weather in Paris?"},
+				{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}}},
+				{Role: "tool", Content: "18C", ToolCallID: "call_1"},
+			}},
+			messages: `[
+				{"role":"user","content":"Weather in Paris?"},
+				{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},
+				{"role":"tool","content":"18C","tool_call_id":"call_1"}
+			]`,
+		},
+		{
+			name:   "history followed by prompt",
+			prompt: "And tomorrow?",
+			options: map[string]interface{}{"messages": []Message{
+				{Role: "user", Content: "Weather today?"},
+				{Role: "assistant", Content: "Sunny."},
+			}},
+			messages: `[
+				{"role":"user","content":"Weather today?"},
+				{"role":"assistant","content":"Sunny."},
+				{"role":"user","content":"And tomorrow?"}
+			]`,
+		},
+		{
+			name:     "prefill as trailing assistant message",
+			prompt:   "List three colors",
+			options:  map[string]interface{}{"response_prefill": "1. \n"},
+			messages: `[{"role":"user","content":"List three colors"},{"role":"assistant","content":"1."}]`,
+		},
+		{
+			name:   "image attachment as content part",
+			prompt: "Describe",
+			options: map[string]interface{}{"attachments": []utils.Attachment{
+				{Type: utils.AttachmentImage, Data: []byte("png"), MediaType: "image/png"},
+			}},
+			messages: `[{"role":"user","content":[
+				{"type":"text","text":"Describe"},
+				{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}
+			]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOpenAIProvider("key", "gpt-4o", nil)
+			body, err := p.PrepareRequest(tt.prompt, tt.options)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.messages, requestField(t, body, "messages"))
+			request := decodeRequest(t, body)
+			assert.Equal(t, "gpt-4o", request["model"])
+			assert.NotContains(t, request, "system_prompt")
+			assert.NotContains(t, request, "response_prefill")
+			assert.NotContains(t, request, "attachments")
+		})
+	}
+}
+
+func TestOpenAIPrepareRequestOptions(t *testing.T) {
+	p := NewOpenAIProvider("key", "o3-mini", nil)
+	p.SetOption("temperature", 0.2)
+	p.SetOption("max_tokens", 100)
+
+	body, err := p.PrepareRequest("Hi", map[string]interface{}{
+		"max_tokens":       50,
+		"reasoning_effort": ReasoningHigh,
+	})
+	require.NoError(t, err)
+	request := decodeRequest(t, body)
+	assert.Equal(t, 0.2, request["temperature"])
+	assert.Equal(t, float64(50), request["max_tokens"], "request options override the defaults")
+	assert.Equal(t, "high", request["reasoning_effort"])
+}
+
+func TestOpenAIPrepareRequestTools(t *testing.T) {
+	tool := utils.Tool{Type: "function", Function: utils.Function{
+		Name:        "get_weather",
+		Description: "Get the weather",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		},
+	}}
+
+	t.Run("strict by default", func(t *testing.T) {
+		p := NewOpenAIProvider("key", "gpt-4o", nil)
+		body, err := p.PrepareRequest("Weather?", map[string]interface{}{
+			"tools":       []utils.Tool{tool},
+			"tool_choice": "auto",
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"type":"function","function":{
+			"name":"get_weather","description":"Get the weather","strict":true,
+			"parameters":{"type":"object","properties":{"city":{"type":["string","null"]}},"required":["city"],"additionalProperties":false}
+		}}]`, requestField(t, body, "tools"))
+		assert.JSONEq(t, `"auto"`, requestField(t, body, "tool_choice"))
+	})
+
+	t.Run("strict_tools false", func(t *testing.T) {
+		p := NewOpenAIProvider("key", "gpt-4o", nil)
+		body, err := p.PrepareRequest("Weather?", map[string]interface{}{
+			"tools":        []utils.Tool{tool},
+			"strict_tools": false,
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"type":"function","function":{
+			"name":"get_weather","description":"Get the weather",
+			"parameters":{"type":"object","properties":{"city":{"type":"string"}}}
+		}}]`, requestField(t, body, "tools"))
+		assert.NotContains(t, decodeRequest(t, body), "strict_tools")
+	})
+}
+
+func TestOpenAIPrepareStreamRequest(t *testing.T) {
+	p := NewOpenAIProvider("key", "gpt-4o", nil)
+	body, err := p.PrepareStreamRequest("Hi", map[string]interface{}{})
+	require.NoError(t, err)
+	request := decodeRequest(t, body)
+	assert.Equal(t, true, request["stream"])
+	assert.Equal(t, map[string]interface{}{"include_usage": true}, request["stream_options"])
+}
+
+func TestOpenAIParseStreamEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		events []StreamEvent
+		err    bool
+	}{
+		{name: "empty", data: " "},
+		{name: "done", data: "[DONE]", events: []StreamEvent{{Type: StreamEventDone}}},
+		{
+			name:   "content",
+			data:   `{"choices":[{"delta":{"content":"Hel"}}]}`,
+			events: []StreamEvent{{Type: StreamEventDelta, Text: "Hel"}},
+		},
+		{
+			name:   "reasoning",
+			data:   `{"choices":[{"delta":{"reasoning_content":"Thinking"}}]}`,
+			events: []StreamEvent{{Type: StreamEventReasoningDelta, Text: "Thinking"}},
+		},
+		{
+			name: "tool call",
+			data: `{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_1","function":{"name":"get_weather","arguments":"{\"ci"}}]}}]}`,
+			events: []StreamEvent{{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index: 1, ID: "call_1", Name: "get_weather", Arguments: `{"ci`,
+			}}},
+		},
+		{
+			name: "usage",
+			data: `{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":4},"completion_tokens_details":{"reasoning_tokens":2}}}`,
+			events: []StreamEvent{{Type: StreamEventUsage, Usage: &StreamUsage{
+				PromptTokens: 10, CompletionTokens: 5, CachedTokens: 4, ReasoningTokens: 2,
+			}}},
+		},
+		{name: "malformed", data: `{"choices":`, err: true},
+	}
+
+	p := NewOpenAIProvider("key", "gpt-4o", nil).(StreamEventParser)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := p.ParseStreamEvent("", []byte(tt.data))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
+
+func TestOpenAIParseToolCalls(t *testing.T) {
+	body := []byte(`{"choices":[{"message":{"content":null,"tool_calls":[
+		{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\",\"days\":2}"}},
+		{"id":"call_2","type":"function","function":{"name":"get_time","arguments":""}}
+	]}}]}`)
+	p := NewOpenAIProvider("key", "gpt-4o", nil)
+
+	calls, err := p.(ToolCallParser).ParseToolCalls(body)
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCall{
+		{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris","days":2}`)},
+		{ID: "call_2", Name: "get_time", Arguments: json.RawMessage(`{}`)},
+	}, calls)
+
+	text, err := p.ParseResponse(body)
+	require.NoError(t, err)
+	assert.Equal(t, `<function_call>{"arguments":{"city":"Paris","days":2},"name":"get_weather"}</function_call>`+"\n"+
+		`<function_call>{"arguments":{},"name":"get_time"}</function_call>`, text)
+
+	handled, err := p.HandleFunctionCalls(body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id":"call_1","name":"get_weather","arguments":{"city":"Paris","days":2}},
+		{"id":"call_2","name":"get_time","arguments":{}}
+	]`, string(handled))
+}
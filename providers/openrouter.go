@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenRouterProvider implements the Provider interface for OpenRouter, which
+// routes OpenAI-compatible chat completions to many upstream providers.
+// Models are named with their vendor prefix, such as
+// "anthropic/claude-3.5-sonnet".
+type OpenRouterProvider struct {
+	completionServer
+}
+
+// NewOpenRouterProvider creates a provider for OpenRouter. Set the
+// OPENROUTER_BASE_URL environment variable to reach OpenRouter through a
+// proxy.
+func NewOpenRouterProvider(apiKey, model string, extraHeaders map[string]string) Provider {
+	return &OpenRouterProvider{completionServer{newLocalServer("openrouter", "https://openrouter.ai/api", apiKey, model, extraHeaders)}}
+}
+
+// OpenRouterPreferences configures how OpenRouter routes a request between
+// the upstream providers serving the model. Pass it with
+// llm.WithProviderPreferences, or as the "provider_preferences" option.
+// Provider names are OpenRouter's provider slugs, such as "anthropic" or
+// "together".
+//
+// Example:
+//
+//	noFallbacks := false
+//	prefs := providers.OpenRouterPreferences{
+//	    Order:          []string{"anthropic", "amazon-bedrock"},
+//	    AllowFallbacks: &noFallbacks,
+//	    DataCollection: providers.DataCollectionDeny,
+//	}
+type OpenRouterPreferences struct {
+	// Order lists the providers to try first, in order.
+	Order []string `json:"order,omitempty"`
+
+	// AllowFallbacks controls whether providers outside Order may serve the
+	// request when those in Order are unavailable. OpenRouter allows them
+	// when nil.
+	AllowFallbacks *bool `json:"allow_fallbacks,omitempty"`
+
+	// RequireParameters restricts routing to providers that support every
+	// parameter of the request, such as tools or response formats.
+	RequireParameters bool `json:"require_parameters,omitempty"`
+
+	// DataCollection is DataCollectionDeny to exclude providers that may
+	// store or train on prompts. Empty means DataCollectionAllow.
+	DataCollection string `json:"data_collection,omitempty"`
+
+	// Quantizations restricts routing to providers serving the model at one
+	// of the given quantization levels, such as "fp8" or "bf16".
+	Quantizations []string `json:"quantizations,omitempty"`
+
+	// Ignore lists providers that must not serve the request.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Data collection policies of OpenRouterPreferences.
+const (
+	DataCollectionAllow = "allow"
+	DataCollectionDeny  = "deny"
+)
+
+// openRouterQuantizations are the quantization levels OpenRouter accepts.
+var openRouterQuantizations = map[string]bool{
+	"int4": true, "int8": true, "fp4": true, "fp6": true, "fp8": true,
+	"fp16": true, "bf16": true, "fp32": true, "unknown": true,
+}
+
+// Validate reports preferences that OpenRouter would reject or that
+// contradict each other.
+func (p OpenRouterPreferences) Validate() error {
+	switch p.DataCollection {
+	case "", DataCollectionAllow, DataCollectionDeny:
+	default:
+		return fmt.Errorf("invalid data collection policy %q: must be %q or %q", p.DataCollection, DataCollectionAllow, DataCollectionDeny)
+	}
+	for _, q := range p.Quantizations {
+		if !openRouterQuantizations[q] {
+			return fmt.Errorf("invalid quantization %q", q)
+		}
+	}
+	ignored := make(map[string]bool, len(p.Ignore))
+	for _, name := range p.Ignore {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("empty provider name in Ignore")
+		}
+		ignored[strings.ToLower(name)] = true
+	}
+	for _, name := range p.Order {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("empty provider name in Order")
+		}
+		if ignored[strings.ToLower(name)] {
+			return fmt.Errorf("provider %q is both ordered and ignored", name)
+		}
+	}
+	if p.AllowFallbacks != nil && !*p.AllowFallbacks && len(p.Order) == 0 {
+		return fmt.Errorf("disabling fallbacks requires an Order")
+	}
+	return nil
+}
+
+// PrepareRequest creates the request body for a chat completion, sending
+// the provider preferences as the provider field once validated, and the
+// reasoning effort as the reasoning field.
+func (p *OpenRouterProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	if err := p.takePreferences(options); err != nil {
+		return nil, err
+	}
+	takeOpenRouterReasoning(options)
+	return p.localServer.PrepareRequest(prompt, options)
+}
+
+// PrepareStreamRequest prepares a request body for streaming.
+func (p *OpenRouterProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	if err := p.takePreferences(options); err != nil {
+		return nil, err
+	}
+	takeOpenRouterReasoning(options)
+	return p.localServer.PrepareStreamRequest(prompt, options)
+}
+
+// takeOpenRouterReasoning replaces the "reasoning_effort" option with the
+// reasoning field of OpenRouter, which it translates for the upstream
+// provider.
+func takeOpenRouterReasoning(options map[string]interface{}) {
+	if effort := takeReasoningEffort(options); effort != "" {
+		options["reasoning"] = map[string]interface{}{"effort": effort}
+	}
+}
+
+// SupportsReasoningEffort reports that OpenRouter accepts a reasoning effort
+// for any model, ignoring it for models that do not reason.
+func (p *OpenRouterProvider) SupportsReasoningEffort(model string) bool {
+	return true
+}
+
+// takePreferences replaces the "provider_preferences" option, from the
+// request or the defaults, with the provider field of the request.
+func (p *OpenRouterProvider) takePreferences(options map[string]interface{}) error {
+	value, ok := options["provider_preferences"]
+	if !ok {
+		value, ok = p.options["provider_preferences"]
+	}
+	delete(options, "provider_preferences")
+	if !ok {
+		return nil
+	}
+	var prefs OpenRouterPreferences
+	switch v := value.(type) {
+	case OpenRouterPreferences:
+		prefs = v
+	case *OpenRouterPreferences:
+		if v == nil {
+			return nil
+		}
+		prefs = *v
+	default:
+		return fmt.Errorf("provider_preferences must be an OpenRouterPreferences, got %T", value)
+	}
+	if err := prefs.Validate(); err != nil {
+		return fmt.Errorf("invalid provider preferences: %w", err)
+	}
+	options["provider"] = prefs
+	return nil
+}
+
+// SupportsJSONSchema reports that OpenRouter accepts json_schema response
+// formats, which it forwards to upstream providers that support them.
+func (p *OpenRouterProvider) SupportsJSONSchema() bool {
+	return true
+}
+
+// PrepareRequestWithSchema creates a request with a json_schema response
+// format.
+func (p *OpenRouterProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	strict, _ := options["strict"].(bool)
+	options["response_format"] = schemaResponseFormat(takeJSONMode(options), schema, strict)
+	return p.PrepareRequest(prompt, options)
+}
+
+// SupportsJSONMode reports that OpenRouter forwards both JSON modes, which
+// the upstream model must support.
+func (p *OpenRouterProvider) SupportsJSONMode(mode JSONMode) bool {
+	return mode == JSONModeObject || mode == JSONModeSchema
+}
+
+// OpenRouterGeneration holds the statistics OpenRouter reports for a
+// completed generation.
+type OpenRouterGeneration struct {
+	// ID is OpenRouter's identifier for the generation.
+	ID string
+
+	// Model is the model that served the request.
+	Model string
+
+	// ProviderName is the upstream provider OpenRouter routed the request
+	// to, such as "Anthropic" or "Together".
+	ProviderName string
+
+	// TotalCost is the actual cost of the generation, in US dollars.
+	TotalCost float64
+
+	// Latency is the time until the first token, and GenerationTime the
+	// time spent generating.
+	Latency        time.Duration
+	GenerationTime time.Duration
+
+	// NativePromptTokens and NativeCompletionTokens are counted with the
+	// upstream model's tokenizer, as billed.
+	NativePromptTokens     int
+	NativeCompletionTokens int
+}
+
+// GenerationID returns the ID of the generation of a chat completion.
+func (p *OpenRouterProvider) GenerationID(body []byte) string {
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	return response.ID
+}
+
+// GenerationEndpoint returns the URL of the statistics of the generation.
+func (p *OpenRouterProvider) GenerationEndpoint(id string) string {
+	return p.baseURL + "/v1/generation?id=" + url.QueryEscape(id)
+}
+
+// ParseGenerationStats returns the statistics of a generation under the
+// "openrouter" key as an OpenRouterGeneration value.
+func (p *OpenRouterProvider) ParseGenerationStats(body []byte) (map[string]interface{}, error) {
+	var response struct {
+		Data struct {
+			ID                     string  `json:"id"`
+			Model                  string  `json:"model"`
+			ProviderName           string  `json:"provider_name"`
+			TotalCost              float64 `json:"total_cost"`
+			Latency                float64 `json:"latency"`
+			GenerationTime         float64 `json:"generation_time"`
+			NativeTokensPrompt     int     `json:"native_tokens_prompt"`
+			NativeTokensCompletion int     `json:"native_tokens_completion"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing generation stats: %w", err)
+	}
+
+	milliseconds := func(ms float64) time.Duration { return time.Duration(ms * float64(time.Millisecond)) }
+	return map[string]interface{}{
+		"openrouter": OpenRouterGeneration{
+			ID:                     response.Data.ID,
+			Model:                  response.Data.Model,
+			ProviderName:           response.Data.ProviderName,
+			TotalCost:              response.Data.TotalCost,
+			Latency:                milliseconds(response.Data.Latency),
+			GenerationTime:         milliseconds(response.Data.GenerationTime),
+			NativePromptTokens:     response.Data.NativeTokensPrompt,
+			NativeCompletionTokens: response.Data.NativeTokensCompletion,
+		},
+	}, nil
+}
@@ -0,0 +1,182 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRouterPrepareRequest(t *testing.T) {
+	noFallbacks := false
+	tests := []struct {
+		name     string
+		defaults map[string]interface{}
+		options  map[string]interface{}
+		fields   map[string]string
+		absent   []string
+		err      string
+	}{
+		{
+			name:    "system prompt and prompt",
+			options: map[string]interface{}{"system_prompt": "Be brief."},
+			fields: map[string]string{
+				"model":    `"anthropic/claude-3.5-sonnet"`,
+				"messages": `[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"}]`,
+			},
+			absent: []string{"system_prompt", "provider"},
+		},
+		{
+			name: "history",
+			options: map[string]interface{}{"messages": []Message{
+				{Role: "user", Content: "Hello"},
+				{Role: "assistant", Content: "Hi!"},
+			}},
+			fields: map[string]string{
+				"messages": `[
+					{"role":"user","content":"Hello"},
+					{"role":"assistant","content":"Hi!"},
+					{"role":"user","content":"Hi"}
+				]`,
+			},
+		},
+		{
+			name: "provider preferences",
+			options: map[string]interface{}{"provider_preferences": OpenRouterPreferences{
+				Order:          []string{"anthropic", "amazon-bedrock"},
+				AllowFallbacks: &noFallbacks,
+				DataCollection: DataCollectionDeny,
+			}},
+			fields: map[string]string{
+				"provider": `{"order":["anthropic","amazon-bedrock"],"allow_fallbacks":false,"data_collection":"deny"}`,
+			},
+			absent: []string{"provider_preferences"},
+		},
+		{
+			name:     "default provider preferences",
+			defaults: map[string]interface{}{"provider_preferences": &OpenRouterPreferences{Quantizations: []string{"fp8"}}},
+			options:  map[string]interface{}{},
+			fields:   map[string]string{"provider": `{"quantizations":["fp8"]}`},
+			absent:   []string{"provider_preferences"},
+		},
+		{
+			name:    "invalid provider preferences",
+			options: map[string]interface{}{"provider_preferences": OpenRouterPreferences{Quantizations: []string{"q3"}}},
+			err:     `invalid quantization "q3"`,
+		},
+		{
+			name:    "provider preferences of another type",
+			options: map[string]interface{}{"provider_preferences": map[string]interface{}{"order": []string{"anthropic"}}},
+			err:     "provider_preferences must be an OpenRouterPreferences",
+		},
+		{
+			name:    "reasoning effort",
+			options: map[string]interface{}{"reasoning_effort": ReasoningMedium},
+			fields:  map[string]string{"reasoning": `{"effort":"medium"}`},
+			absent:  []string{"reasoning_effort"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOpenRouterProvider("key", "anthropic/claude-3.5-sonnet", nil)
+			for k, v := range tt.defaults {
+				p.SetOption(k, v)
+			}
+			body, err := p.PrepareRequest("Hi", tt.options)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			for key, want := range tt.fields {
+				assert.JSONEq(t, want, requestField(t, body, key), key)
+			}
+			request := decodeRequest(t, body)
+			for _, key := range tt.absent {
+				assert.NotContains(t, request, key)
+			}
+		})
+	}
+}
+
+func TestOpenRouterPreferencesValidate(t *testing.T) {
+	noFallbacks := false
+	tests := []struct {
+		name  string
+		prefs OpenRouterPreferences
+		valid bool
+	}{
+		{name: "empty", valid: true},
+		{name: "order and ignore", prefs: OpenRouterPreferences{Order: []string{"anthropic"}, Ignore: []string{"together"}}, valid: true},
+		{name: "no fallbacks with order", prefs: OpenRouterPreferences{Order: []string{"anthropic"}, AllowFallbacks: &noFallbacks}, valid: true},
+		{name: "no fallbacks without order", prefs: OpenRouterPreferences{AllowFallbacks: &noFallbacks}},
+		{name: "ordered and ignored", prefs: OpenRouterPreferences{Order: []string{"Anthropic"}, Ignore: []string{"anthropic"}}},
+		{name: "empty provider name", prefs: OpenRouterPreferences{Order: []string{" "}}},
+		{name: "unknown data collection", prefs: OpenRouterPreferences{DataCollection: "maybe"}},
+		{name: "unknown quantization", prefs: OpenRouterPreferences{Quantizations: []string{"int3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prefs.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestOpenRouterPrepareStreamRequest(t *testing.T) {
+	p := NewOpenRouterProvider("key", "openai/gpt-4o", nil)
+	body, err := p.PrepareStreamRequest("Hi", map[string]interface{}{
+		"provider_preferences": OpenRouterPreferences{Order: []string{"openai"}},
+	})
+	require.NoError(t, err)
+	request := decodeRequest(t, body)
+	assert.Equal(t, true, request["stream"])
+	assert.Equal(t, map[string]interface{}{"include_usage": true}, request["stream_options"])
+	assert.Equal(t, map[string]interface{}{"order": []interface{}{"openai"}}, request["provider"])
+}
+
+func TestOpenRouterParseStreamEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		events []StreamEvent
+	}{
+		{
+			name:   "reasoning",
+			data:   `{"choices":[{"delta":{"reasoning":"First,"}}]}`,
+			events: []StreamEvent{{Type: StreamEventReasoningDelta, Text: "First,"}},
+		},
+		{
+			name:   "content",
+			data:   `{"choices":[{"delta":{"content":"Hi"}}]}`,
+			events: []StreamEvent{{Type: StreamEventDelta, Text: "Hi"}},
+		},
+		{
+			name:   "usage",
+			data:   `{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2}}`,
+			events: []StreamEvent{{Type: StreamEventUsage, Usage: &StreamUsage{PromptTokens: 3, CompletionTokens: 2}}},
+		},
+		{name: "done", data: "[DONE]", events: []StreamEvent{{Type: StreamEventDone}}},
+	}
+
+	p := NewOpenRouterProvider("key", "openai/gpt-4o", nil).(StreamEventParser)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := p.ParseStreamEvent("", []byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
+
+func TestOpenRouterGenerationStats(t *testing.T) {
+	p := NewOpenRouterProvider("key", "openai/gpt-4o", nil).(*OpenRouterProvider)
+	assert.Equal(t, "gen-1", p.GenerationID([]byte(`{"id":"gen-1","choices":[]}`)))
+	assert.Equal(t, "https://openrouter.ai/api/v1/generation?id=gen+1", p.GenerationEndpoint("gen 1"))
+}
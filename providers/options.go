@@ -0,0 +1,71 @@
+package providers
+
+// Sampling options whose support differs between providers. Setting one that
+// the provider does not honour silently changes how reproducible or diverse
+// the output is, so they are checked against the option support matrix.
+const (
+	OptionSeed = "seed"
+	OptionTopP = "top_p"
+	OptionTopK = "top_k"
+)
+
+// SamplingOptions lists the options covered by the option support matrix.
+var SamplingOptions = []string{OptionSeed, OptionTopP, OptionTopK}
+
+// optionSupport is the option support matrix: the sampling options each
+// provider maps to its API. Cohere names them p and k, and Mistral names the
+// seed random_seed.
+var optionSupport = map[string][]string{
+	"openai":     {OptionSeed, OptionTopP},
+	"anthropic":  {OptionTopP, OptionTopK},
+	"cohere":     {OptionSeed, OptionTopP, OptionTopK},
+	"deepseek":   {OptionTopP},
+	"groq":       {OptionSeed, OptionTopP},
+	"mistral":    {OptionSeed, OptionTopP},
+	"ollama":     {OptionSeed, OptionTopP, OptionTopK},
+	"vllm":       {OptionSeed, OptionTopP, OptionTopK},
+	"llamacpp":   {OptionSeed, OptionTopP, OptionTopK},
+	"openrouter": {OptionSeed, OptionTopP, OptionTopK},
+	"gateway":    {OptionSeed, OptionTopP, OptionTopK},
+	"gemini":     {OptionSeed, OptionTopP},
+}
+
+// SupportsOption reports whether the named provider honours a sampling
+// option. Options outside SamplingOptions, and providers missing from the
+// matrix such as custom ones, are assumed to be supported.
+func SupportsOption(provider, option string) bool {
+	supported, ok := optionSupport[provider]
+	if !ok || !isSamplingOption(option) {
+		return true
+	}
+	for _, o := range supported {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isSamplingOption(option string) bool {
+	for _, o := range SamplingOptions {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// samplingDefaults returns the sampling options among a provider's defaults
+// that the request options do not override, for providers that otherwise
+// only send the request options.
+func samplingDefaults(defaults, options map[string]interface{}) map[string]interface{} {
+	sampling := make(map[string]interface{})
+	for _, key := range SamplingOptions {
+		if v, ok := defaults[key]; ok {
+			if _, overridden := options[key]; !overridden {
+				sampling[key] = v
+			}
+		}
+	}
+	return sampling
+}
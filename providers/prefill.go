@@ -0,0 +1,24 @@
+package providers
+
+import "strings"
+
+// PrefillProvider is implemented by providers that can continue an
+// assistant reply from a given beginning, passed as the "response_prefill"
+// option.
+type PrefillProvider interface {
+	SupportsPrefill() bool
+}
+
+// takePrefill removes the response prefill from options so it is not sent
+// as a top-level request field, and returns it. Trailing whitespace is
+// removed, as the APIs reject assistant messages that end with it.
+func takePrefill(options map[string]interface{}) string {
+	prefill, _ := options["response_prefill"].(string)
+	delete(options, "response_prefill")
+	return strings.TrimRight(prefill, " \t\r\n")
+}
+
+// prefillMessage returns the assistant message that primes the reply.
+func prefillMessage(prefill string) map[string]interface{} {
+	return map[string]interface{}{"role": "assistant", "content": prefill}
+}
@@ -6,6 +6,8 @@ package providers
 
 import (
 	"fmt"
+	"net/http"
+	"sort"
 	"sync"
 
 	"github.com/teilomillet/gollm/config"
@@ -44,6 +46,8 @@ type Provider interface {
 
 	// HandleFunctionCalls processes function calling capabilities.
 	// This is particularly relevant for providers that support function/tool calling.
+	// A response without function calls gives nil, except for OpenAI, which
+	// returns an error.
 	HandleFunctionCalls(body []byte) ([]byte, error)
 
 	// SupportsJSONSchema indicates whether the provider supports native JSON schema validation.
@@ -70,6 +74,127 @@ type Provider interface {
 	ParseStreamResponse(chunk []byte) (string, error)
 }
 
+// MetadataParser is implemented by providers that report provider-specific
+// details, such as timing metrics, alongside the generated text.
+type MetadataParser interface {
+	// ParseMetadata extracts the details from a response body. It returns
+	// nil when the body carries none.
+	ParseMetadata(body []byte) map[string]interface{}
+}
+
+// CostHeaderParser is implemented by providers that report the price of a
+// request in its response headers, as AI gateways such as LiteLLM do.
+type CostHeaderParser interface {
+	// ParseCostHeader returns the price in US dollars of the request, and
+	// false when the headers carry none.
+	ParseCostHeader(header http.Header) (float64, bool)
+}
+
+// GenerationStatsProvider is implemented by providers that report the
+// actual cost of a generation through a separate endpoint once it completes.
+type GenerationStatsProvider interface {
+	// GenerationID returns the ID of the generation in a response body, or
+	// an empty string when it has none.
+	GenerationID(body []byte) string
+
+	// GenerationEndpoint returns the URL to fetch the statistics from.
+	GenerationEndpoint(id string) string
+
+	// ParseGenerationStats extracts the statistics, keyed like the details
+	// returned by MetadataParser.
+	ParseGenerationStats(body []byte) (map[string]interface{}, error)
+}
+
+// ReasoningParser is implemented by providers whose reasoning models return
+// their reasoning separately from the answer.
+type ReasoningParser interface {
+	// ParseReasoning extracts the reasoning from a response body. It returns
+	// an empty string when the body carries none.
+	ParseReasoning(body []byte) string
+}
+
+// DocumentProvider is implemented by providers that accept grounding
+// documents natively. Such providers receive the prompt's documents as a
+// []utils.Document under the "documents" option.
+type DocumentProvider interface {
+	SupportsDocuments() bool
+}
+
+// EmbeddingProvider is implemented by providers with an embeddings endpoint.
+type EmbeddingProvider interface {
+	// EmbeddingEndpoint returns the URL of the embeddings endpoint.
+	EmbeddingEndpoint() string
+
+	// PrepareEmbeddingRequest creates the request body embedding inputs.
+	PrepareEmbeddingRequest(inputs []string, options map[string]interface{}) ([]byte, error)
+
+	// ParseEmbeddingResponse returns one vector per input, in input order.
+	ParseEmbeddingResponse(body []byte) ([][]float64, error)
+}
+
+// FIMProvider is implemented by providers with a fill-in-the-middle
+// completion endpoint, used for code completion.
+type FIMProvider interface {
+	// FIMEndpoint returns the URL of the fill-in-the-middle endpoint.
+	FIMEndpoint() string
+
+	// PrepareFIMRequest creates the request body for completing the code
+	// between prefix and suffix.
+	PrepareFIMRequest(prefix, suffix string, options map[string]interface{}) ([]byte, error)
+
+	// ParseFIMResponse extracts the completion from the response body.
+	ParseFIMResponse(body []byte) (string, error)
+}
+
+// CompletionProvider is implemented by providers with a raw text completion
+// endpoint, which continues the text as is instead of formatting it as a
+// chat conversation.
+type CompletionProvider interface {
+	// CompletionEndpoint returns the URL of the completion endpoint.
+	CompletionEndpoint() string
+
+	// PrepareCompletionRequest creates the request body for continuing text.
+	PrepareCompletionRequest(text string, options map[string]interface{}) ([]byte, error)
+
+	// ParseCompletionResponse extracts the continuation from the response body.
+	ParseCompletionResponse(body []byte) (string, error)
+}
+
+// TokenLogprob is a token of a scored text with its log probability.
+type TokenLogprob struct {
+	// Token is the text of the token.
+	Token string
+
+	// Offset is the position of the token in the text, in characters.
+	Offset int
+
+	// Logprob is the natural log probability of the token given the text
+	// before it. It is zero for the first token of the text.
+	Logprob float64
+}
+
+// ScoringProvider is implemented by providers that can echo a text with the
+// log probability of each of its tokens, without generating a reply.
+type ScoringProvider interface {
+	// ScoringEndpoint returns the URL of the scoring endpoint.
+	ScoringEndpoint() string
+
+	// PrepareScoringRequest creates the request body for scoring text.
+	PrepareScoringRequest(text string, options map[string]interface{}) ([]byte, error)
+
+	// ParseScoringResponse extracts the tokens of the text, in order, with
+	// their log probabilities.
+	ParseScoringResponse(body []byte) ([]TokenLogprob, error)
+}
+
+// ModelEndpointProvider is implemented by providers with an endpoint that
+// describes a model, used to check the API key and the model name without
+// generating any tokens.
+type ModelEndpointProvider interface {
+	// ModelEndpoint returns the URL to GET the configured model from.
+	ModelEndpoint() string
+}
+
 // ProviderConstructor defines a function type for creating new provider instances.
 // Each provider implementation must provide a constructor function of this type.
 type ProviderConstructor func(apiKey, model string, extraHeaders map[string]string) Provider
@@ -91,6 +216,13 @@ type ProviderRegistry struct {
 //   - "groq": Groq's LLM services
 //   - "ollama": Local LLM deployment
 //   - "mistral": Mistral AI's models
+//   - "cohere": Cohere's Command models
+//   - "deepseek": DeepSeek's chat and reasoner models
+//   - "vllm": Self-hosted vLLM servers
+//   - "llamacpp": Self-hosted llama.cpp servers
+//   - "openrouter": OpenRouter's routed models
+//   - "gemini": Google's Gemini models
+//   - "gateway": LiteLLM-style AI gateways
 //
 // Example usage:
 //
@@ -106,12 +238,18 @@ func NewProviderRegistry(providerNames ...string) *ProviderRegistry {
 
 	// Register all known providers
 	knownProviders := map[string]ProviderConstructor{
-		"openai":    NewOpenAIProvider,
-		"anthropic": NewAnthropicProvider,
-		"groq":      NewGroqProvider,
-		"ollama":    NewOllamaProvider,
-		"mistral":   NewMistralProvider,
-		"cohere":    NewCohereProvider,
+		"openai":     NewOpenAIProvider,
+		"anthropic":  NewAnthropicProvider,
+		"groq":       NewGroqProvider,
+		"ollama":     NewOllamaProvider,
+		"mistral":    NewMistralProvider,
+		"cohere":     NewCohereProvider,
+		"deepseek":   NewDeepSeekProvider,
+		"vllm":       NewVLLMProvider,
+		"llamacpp":   NewLlamaCppProvider,
+		"openrouter": NewOpenRouterProvider,
+		"gemini":     NewGeminiProvider,
+		"gateway":    NewGatewayProvider,
 		// Add other providers here as they are implemented
 	}
 
@@ -147,6 +285,18 @@ func (pr *ProviderRegistry) Register(name string, constructor ProviderConstructo
 	pr.providers[name] = constructor
 }
 
+// Names returns the names of all registered providers, sorted alphabetically.
+func (pr *ProviderRegistry) Names() []string {
+	pr.mutex.RLock()
+	defer pr.mutex.RUnlock()
+	names := make([]string, 0, len(pr.providers))
+	for name := range pr.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Get retrieves a provider instance by name.
 // It creates a new provider instance using the registered constructor.
 //
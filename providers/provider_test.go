@@ -0,0 +1,29 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requestField decodes a request body and returns its field key as JSON,
+// for comparison with assert.JSONEq.
+func requestField(t *testing.T, body []byte, key string) string {
+	t.Helper()
+	var request map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &request))
+	field, ok := request[key]
+	if !ok {
+		return ""
+	}
+	return string(field)
+}
+
+// decodeRequest decodes a request body into a map.
+func decodeRequest(t *testing.T, body []byte) map[string]interface{} {
+	t.Helper()
+	var request map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &request))
+	return request
+}
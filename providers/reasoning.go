@@ -0,0 +1,60 @@
+package providers
+
+import "strings"
+
+// ReasoningEffort is how much a reasoning model thinks before it answers,
+// passed as the "reasoning_effort" option. Providers map it to their own
+// setting: OpenAI's reasoning_effort, Anthropic's extended thinking budget,
+// Gemini's thinking budget and OpenRouter's reasoning effort.
+type ReasoningEffort string
+
+const (
+	ReasoningLow    ReasoningEffort = "low"
+	ReasoningMedium ReasoningEffort = "medium"
+	ReasoningHigh   ReasoningEffort = "high"
+)
+
+// BudgetTokens returns the thinking budget, in tokens, of providers that
+// take one instead of an effort level.
+func (e ReasoningEffort) BudgetTokens() int {
+	switch e {
+	case ReasoningLow:
+		return 1024
+	case ReasoningHigh:
+		return 24576
+	}
+	return 8192
+}
+
+// Valid reports whether e is a known effort level.
+func (e ReasoningEffort) Valid() bool {
+	return e == ReasoningLow || e == ReasoningMedium || e == ReasoningHigh
+}
+
+// ReasoningEffortProvider is implemented by providers that let callers set
+// the reasoning effort of reasoning models.
+type ReasoningEffortProvider interface {
+	// SupportsReasoningEffort reports whether model is a reasoning model
+	// whose effort can be set.
+	SupportsReasoningEffort(model string) bool
+}
+
+// takeReasoningEffort removes the reasoning effort from options, for
+// providers that send it in their own format, and returns it.
+func takeReasoningEffort(options map[string]interface{}) ReasoningEffort {
+	effort, _ := options["reasoning_effort"].(ReasoningEffort)
+	delete(options, "reasoning_effort")
+	return effort
+}
+
+// hasModelPrefix reports whether model starts with one of prefixes, once
+// stripped of the "models/" prefix of Gemini model names.
+func hasModelPrefix(model string, prefixes ...string) bool {
+	model = strings.TrimPrefix(model, "models/")
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrSkipToken is returned by ParseStreamResponse for chunks that carry no
+// text, such as role announcements or keep-alive events.
+var ErrSkipToken = errors.New("skip token")
+
+// StreamEventType identifies the kind of a StreamEvent.
+type StreamEventType int
+
+const (
+	// StreamEventDelta carries a piece of response text.
+	StreamEventDelta StreamEventType = iota
+	// StreamEventToolCallDelta carries a fragment of a tool call.
+	StreamEventToolCallDelta
+	// StreamEventUsage carries token usage reported mid-stream or at its end.
+	StreamEventUsage
+	// StreamEventDone marks the end of the response.
+	StreamEventDone
+	// StreamEventReasoningDelta carries a piece of the model's reasoning,
+	// which is not part of the response text.
+	StreamEventReasoningDelta
+	// StreamEventToolCallDone marks the tool call with the Index of
+	// ToolCall as complete, before the response ends. Calls that are not
+	// marked complete are delivered when the response ends.
+	StreamEventToolCallDone
+)
+
+// StreamEvent is a provider-neutral event decoded from a streaming response.
+type StreamEvent struct {
+	Type StreamEventType
+
+	// Text is set for StreamEventDelta and StreamEventReasoningDelta.
+	Text string
+
+	// ToolCall is set for StreamEventToolCallDelta and
+	// StreamEventToolCallDone.
+	ToolCall *ToolCallDelta
+
+	// Usage is set for StreamEventUsage.
+	Usage *StreamUsage
+}
+
+// ToolCallDelta is a fragment of a streamed tool call. Fragments with the
+// same Index belong to the same call; ID and Name usually arrive with the
+// first fragment and Arguments is split across the following ones.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// StreamUsage is the token usage reported by a stream. Counts are
+// cumulative: an event reports the count of each kind so far, or zero for
+// the kinds it leaves to another event, such as Anthropic reporting input
+// tokens when the message starts and output tokens when it ends.
+type StreamUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+	CacheWriteTokens int
+	ReasoningTokens  int
+}
+
+// StreamEventParser is implemented by providers that translate streaming
+// chunks into typed events. eventType is the SSE event name, empty for
+// data-only and newline-delimited JSON streams. Chunks without meaningful
+// content yield no events.
+type StreamEventParser interface {
+	ParseStreamEvent(eventType string, data []byte) ([]StreamEvent, error)
+}
+
+// streamText reduces the events of a chunk to the result expected from
+// ParseStreamResponse: the chunk's text, io.EOF when the response is done,
+// or ErrSkipToken when the chunk carries no text.
+func streamText(events []StreamEvent, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, event := range events {
+		switch event.Type {
+		case StreamEventDelta:
+			text.WriteString(event.Text)
+		case StreamEventDone:
+			if text.Len() == 0 {
+				return "", io.EOF
+			}
+		}
+	}
+	if text.Len() == 0 {
+		return "", ErrSkipToken
+	}
+	return text.String(), nil
+}
+
+// isDoneMarker reports whether data is the "[DONE]" sentinel that ends
+// OpenAI-compatible streams.
+func isDoneMarker(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("[DONE]"))
+}
+
+// parseOpenAIStreamEvent decodes a chat completion chunk of the OpenAI API,
+// which Groq, Mistral and DeepSeek share.
+func parseOpenAIStreamEvent(data []byte) ([]StreamEvent, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	if isDoneMarker(data) {
+		return []StreamEvent{{Type: StreamEventDone}}, nil
+	}
+
+	type openAIUsage struct {
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		PromptTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		CompletionTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"completion_tokens_details"`
+	}
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				// OpenRouter and Groq name the reasoning field reasoning
+				Reasoning string `json:"reasoning"`
+				ToolCalls []struct {
+					Index    int    `json:"index"`
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
+		// Groq reports usage in its own extension field
+		XGroq *struct {
+			Usage *openAIUsage `json:"usage"`
+		} `json:"x_groq"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+
+	var events []StreamEvent
+	for _, choice := range chunk.Choices {
+		if reasoning := choice.Delta.ReasoningContent + choice.Delta.Reasoning; reasoning != "" {
+			events = append(events, StreamEvent{Type: StreamEventReasoningDelta, Text: reasoning})
+		}
+		if choice.Delta.Content != "" {
+			events = append(events, StreamEvent{Type: StreamEventDelta, Text: choice.Delta.Content})
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			events = append(events, StreamEvent{Type: StreamEventToolCallDelta, ToolCall: &ToolCallDelta{
+				Index:     call.Index,
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			}})
+		}
+	}
+
+	usage := chunk.Usage
+	if usage == nil && chunk.XGroq != nil {
+		usage = chunk.XGroq.Usage
+	}
+	if usage != nil {
+		events = append(events, StreamEvent{Type: StreamEventUsage, Usage: &StreamUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			CachedTokens:     usage.PromptTokensDetails.CachedTokens,
+			ReasoningTokens:  usage.CompletionTokensDetails.ReasoningTokens,
+		}})
+	}
+	return events, nil
+}
@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"sort"
+)
+
+// NormalizeStrictSchema rewrites a JSON schema to satisfy the rules of
+// strict function calling and structured outputs: every object sets
+// additionalProperties to false and lists all its properties as required.
+// Properties that were optional become nullable, so the model can still
+// leave them out by sending null. The input schema is not modified.
+//
+// It returns false when the schema cannot be made strict, such as when an
+// object allows arbitrary additional properties; the tool should then be
+// sent without strict mode.
+func NormalizeStrictSchema(schema map[string]interface{}) (map[string]interface{}, bool) {
+	normalized, ok := normalizeStrictNode(schema)
+	if !ok {
+		return nil, false
+	}
+	result, ok := normalized.(map[string]interface{})
+	return result, ok
+}
+
+func normalizeStrictNode(node interface{}) (interface{}, bool) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node, true
+	}
+
+	result := make(map[string]interface{}, len(schema)+2)
+	for k, v := range schema {
+		result[k] = v
+	}
+
+	properties, hasProperties := schema["properties"].(map[string]interface{})
+	if schema["type"] == "object" || hasProperties {
+		if additional, set := schema["additionalProperties"]; set && additional != false {
+			return nil, false
+		}
+
+		required := make(map[string]bool)
+		switch r := schema["required"].(type) {
+		case []interface{}:
+			for _, name := range r {
+				if s, ok := name.(string); ok {
+					required[s] = true
+				}
+			}
+		case []string:
+			for _, name := range r {
+				required[name] = true
+			}
+		}
+
+		normalizedProps := make(map[string]interface{}, len(properties))
+		names := make([]string, 0, len(properties))
+		for name, prop := range properties {
+			normalized, ok := normalizeStrictNode(prop)
+			if !ok {
+				return nil, false
+			}
+			if !required[name] {
+				normalized = nullable(normalized)
+			}
+			normalizedProps[name] = normalized
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		result["properties"] = normalizedProps
+		result["required"] = names
+		result["additionalProperties"] = false
+	}
+
+	if items, ok := schema["items"]; ok {
+		normalized, ok := normalizeStrictNode(items)
+		if !ok {
+			return nil, false
+		}
+		result["items"] = normalized
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if variants, ok := schema[key].([]interface{}); ok {
+			normalizedVariants := make([]interface{}, len(variants))
+			for i, variant := range variants {
+				normalized, ok := normalizeStrictNode(variant)
+				if !ok {
+					return nil, false
+				}
+				normalizedVariants[i] = normalized
+			}
+			result[key] = normalizedVariants
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := schema[key].(map[string]interface{}); ok {
+			normalizedDefs := make(map[string]interface{}, len(defs))
+			for name, def := range defs {
+				normalized, ok := normalizeStrictNode(def)
+				if !ok {
+					return nil, false
+				}
+				normalizedDefs[name] = normalized
+			}
+			result[key] = normalizedDefs
+		}
+	}
+	return result, true
+}
+
+// nullable makes a normalized schema also accept null.
+func nullable(node interface{}) interface{} {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+	if _, ok := schema["$ref"]; ok {
+		return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		if t == "null" {
+			return schema
+		}
+		schema["type"] = []interface{}{t, "null"}
+	case []interface{}:
+		for _, v := range t {
+			if v == "null" {
+				return schema
+			}
+		}
+		schema["type"] = append(append([]interface{}{}, t...), "null")
+	default:
+		if variants, ok := schema["anyOf"].([]interface{}); ok {
+			schema["anyOf"] = append(append([]interface{}{}, variants...), map[string]interface{}{"type": "null"})
+		}
+		return schema
+	}
+	switch enum := schema["enum"].(type) {
+	case []interface{}:
+		schema["enum"] = append(append([]interface{}{}, enum...), nil)
+	case []string:
+		values := make([]interface{}, 0, len(enum)+1)
+		for _, v := range enum {
+			values = append(values, v)
+		}
+		schema["enum"] = append(values, nil)
+	}
+	return schema
+}
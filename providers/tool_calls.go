@@ -0,0 +1,165 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ToolCall is a complete tool call requested by a model.
+type ToolCall struct {
+	// ID identifies the call, for matching its result in a follow-up message.
+	ID string `json:"id,omitempty"`
+
+	// Name is the name of the function to call.
+	Name string `json:"name"`
+
+	// Arguments is the JSON object of arguments, as generated by the model.
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolCallParser is implemented by providers that support tool calling. It
+// returns every tool call of a response, in order, where ParseResponse
+// flattens them into formatted text.
+type ToolCallParser interface {
+	// ParseToolCalls extracts the tool calls from a response body. It
+	// returns no calls when the model answered with text only.
+	ParseToolCalls(body []byte) ([]ToolCall, error)
+}
+
+// openAIToolCall is a tool call in the format of the OpenAI chat completion
+// API, shared by Groq, Mistral, DeepSeek and Cohere's v2 API.
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// parseOpenAIToolCalls extracts the tool calls of the first choice of a chat
+// completion response.
+func parseOpenAIToolCalls(body []byte) ([]ToolCall, error) {
+	var response struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []openAIToolCall `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, nil
+	}
+	return convertOpenAIToolCalls(response.Choices[0].Message.ToolCalls)
+}
+
+// convertOpenAIToolCalls converts OpenAI-format tool calls to ToolCalls.
+func convertOpenAIToolCalls(calls []openAIToolCall) ([]ToolCall, error) {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		args, err := toolArguments(call.Function.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing arguments of %s: %w", call.Function.Name, err)
+		}
+		toolCalls = append(toolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: args})
+	}
+	return toolCalls, nil
+}
+
+// toolArguments normalizes tool call arguments to a JSON object. Most APIs
+// encode the object as a JSON string, some send it as is, and calls without
+// arguments may omit it.
+func toolArguments(raw json.RawMessage) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return json.RawMessage("{}"), nil
+	}
+	if raw[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return nil, err
+		}
+		if encoded == "" {
+			return json.RawMessage("{}"), nil
+		}
+		raw = json.RawMessage(encoded)
+	}
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid JSON arguments: %s", raw)
+	}
+	return raw, nil
+}
+
+// functionCallPattern matches the calls of the legacy text format.
+var functionCallPattern = regexp.MustCompile(`(?s)<function_call>(.*?)</function_call>`)
+
+// formatToolCalls formats calls in the legacy text format of ParseResponse,
+// one <function_call> element per line. The arguments are copied as
+// generated, so their types are preserved.
+func formatToolCalls(calls []ToolCall) (string, error) {
+	formatted := make([]string, len(calls))
+	for i, call := range calls {
+		name, err := json.Marshal(call.Name)
+		if err != nil {
+			return "", fmt.Errorf("error formatting function call: %w", err)
+		}
+		arguments := call.Arguments
+		if len(arguments) == 0 {
+			arguments = json.RawMessage("{}")
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, arguments); err != nil {
+			return "", fmt.Errorf("error formatting arguments of %s: %w", call.Name, err)
+		}
+		formatted[i] = fmt.Sprintf(`<function_call>{"arguments":%s,"name":%s}</function_call>`, compact.String(), name)
+	}
+	return strings.Join(formatted, "\n"), nil
+}
+
+// ParseToolCallText extracts the tool calls of text in the legacy
+// <function_call> format, such as a Generate result saved before tool calls
+// were structured. Calls of that format have no ID.
+func ParseToolCallText(text string) ([]ToolCall, error) {
+	var calls []ToolCall
+	for _, match := range functionCallPattern.FindAllStringSubmatch(text, -1) {
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal([]byte(match[1]), &call); err != nil {
+			return nil, fmt.Errorf("error parsing function call: %w", err)
+		}
+		args, err := toolArguments(call.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing arguments of %s: %w", call.Name, err)
+		}
+		calls = append(calls, ToolCall{Name: call.Name, Arguments: args})
+	}
+	return calls, nil
+}
+
+// handleFunctionCalls implements HandleFunctionCalls: it returns the JSON
+// array of the tool calls of body, a response body that parse reads, or of
+// text in the legacy <function_call> format when parse is nil or body is
+// not a response. It returns nil when there are no calls.
+func handleFunctionCalls(parse func([]byte) ([]ToolCall, error), body []byte) ([]byte, error) {
+	var calls []ToolCall
+	var err error
+	if parse != nil && json.Valid(body) {
+		calls, err = parse(body)
+	} else {
+		calls, err = ParseToolCallText(string(body))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error extracting function calls: %w", err)
+	}
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(calls)
+}
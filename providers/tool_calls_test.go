@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		err  bool
+	}{
+		{name: "object", raw: `{"city":"Paris"}`, want: `{"city":"Paris"}`},
+		{name: "encoded object", raw: `"{\"city\":\"Paris\"}"`, want: `{"city":"Paris"}`},
+		{name: "missing", raw: ``, want: `{}`},
+		{name: "null", raw: `null`, want: `{}`},
+		{name: "empty string", raw: `""`, want: `{}`},
+		{name: "invalid encoded object", raw: `"{\"city\":"`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := toolArguments(json.RawMessage(tt.raw))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(args))
+		})
+	}
+}
+
+func TestFormatToolCallsRoundTrip(t *testing.T) {
+	calls := []ToolCall{
+		{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city": "Paris", "days": 2, "units": ["C"]}`)},
+		{Name: "get_time"},
+	}
+	text, err := formatToolCalls(calls)
+	require.NoError(t, err)
+	assert.Equal(t, `<function_call>{"arguments":{"city":"Paris","days":2,"units":["C"]},"name":"get_weather"}</function_call>`+"\n"+
+		`<function_call>{"arguments":{},"name":"get_time"}</function_call>`, text)
+
+	parsed, err := ParseToolCallText("Sure.\n" + text)
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCall{
+		{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris","days":2,"units":["C"]}`)},
+		{Name: "get_time", Arguments: json.RawMessage(`{}`)},
+	}, parsed, "the legacy format has no IDs")
+
+	_, err = formatToolCalls([]ToolCall{{Name: "f", Arguments: json.RawMessage(`{"a":`)}})
+	assert.Error(t, err)
+}
+
+func TestHandleFunctionCalls(t *testing.T) {
+	body := []byte(`{"choices":[{"message":{"tool_calls":[{"id":"call_1","function":{"name":"f","arguments":"{\"a\":1}"}}]}}]}`)
+
+	handled, err := handleFunctionCalls(parseOpenAIToolCalls, body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"call_1","name":"f","arguments":{"a":1}}]`, string(handled))
+
+	handled, err = handleFunctionCalls(nil, []byte(`<function_call>{"name":"f","arguments":{"a":1}}</function_call>`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"f","arguments":{"a":1}}]`, string(handled))
+
+	_, err = handleFunctionCalls(nil, []byte(`<function_call>{"name":</function_call>`))
+	assert.Error(t, err)
+
+	handled, err = handleFunctionCalls(parseOpenAIToolCalls, []byte(`{"choices":[{"message":{"content":"hi"}}]}`))
+	require.NoError(t, err)
+	assert.Nil(t, handled)
+}
+
+func TestHandleFunctionCallsWithoutCalls(t *testing.T) {
+	const chat = `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`
+	tests := []struct {
+		provider string
+		body     string
+		wantErr  bool
+	}{
+		{provider: "openai", body: chat, wantErr: true},
+		{provider: "anthropic", body: `{"content":[{"type":"text","text":"hi"}]}`},
+		{provider: "cohere", body: `{"message":{"role":"assistant","content":[{"type":"text","text":"hi"}]}}`},
+		{provider: "deepseek", body: chat},
+		{provider: "groq", body: chat},
+		{provider: "mistral", body: chat},
+		{provider: "ollama", body: "hi"},
+		{provider: "vllm", body: chat},
+		{provider: "llamacpp", body: chat},
+		{provider: "openrouter", body: chat},
+		{provider: "gemini", body: chat},
+		{provider: "gateway", body: chat},
+	}
+
+	registry := NewProviderRegistry()
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			provider, err := registry.Get(tt.provider, "key", "model", nil)
+			require.NoError(t, err)
+			handled, err := provider.HandleFunctionCalls([]byte(tt.body))
+			if tt.wantErr {
+				assert.EqualError(t, err, "no function calls found in response")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Nil(t, handled)
+		})
+	}
+}
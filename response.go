@@ -0,0 +1,161 @@
+// Package gollm provides detailed response information for Language Learning Models.
+// This file contains re-exports for response metadata and model capabilities.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/providers"
+)
+
+// Re-export response and capability types
+type (
+	// Response holds the generated text together with usage and provider metadata.
+	Response = llm.Response
+
+	// Timing holds the queue time, time to first token, duration and retries of a call or stream.
+	Timing = llm.Timing
+
+	// GroqMetrics holds the latency and queue metrics Groq reports for a request.
+	GroqMetrics = providers.GroqMetrics
+
+	// OpenRouterGeneration holds the cost and routing statistics of an OpenRouter generation.
+	OpenRouterGeneration = providers.OpenRouterGeneration
+
+	// SafetyRating is Gemini's rating of a response candidate for a harm category.
+	SafetyRating = providers.SafetyRating
+
+	// OpenRouterPreferences configures how OpenRouter routes a request between providers.
+	OpenRouterPreferences = providers.OpenRouterPreferences
+
+	// Citation links a span of a grounded response to its source documents.
+	Citation = providers.Citation
+
+	// ContentFilterError describes a prompt or response a provider refused or filtered.
+	ContentFilterError = llm.ContentFilterError
+
+	// SchemaMismatchError holds a GenerateWithSchema response that does not match the schema.
+	SchemaMismatchError = llm.SchemaMismatchError
+
+	// ContentFilterCategory is the verdict of a provider's content filter for one category.
+	ContentFilterCategory = llm.ContentFilterCategory
+
+	// ModelCapabilities describes the features a model supports.
+	ModelCapabilities = providers.ModelCapabilities
+
+	// ImageLimits are the limits a provider's API sets on the images of a request.
+	ImageLimits = providers.ImageLimits
+
+	// DryRunRequest is the request a dry run returns instead of sending it.
+	DryRunRequest = llm.DryRunRequest
+
+	// PostProcessor transforms the generated text of a Generate call.
+	PostProcessor = llm.PostProcessor
+
+	// ResponseFormat selects the format of a structured response.
+	ResponseFormat = llm.ResponseFormat
+
+	// ResponseValidator checks the generated text of a Generate call.
+	ResponseValidator = llm.ResponseValidator
+
+	// JSONMode selects how a provider constrains a structured response.
+	JSONMode = providers.JSONMode
+)
+
+// Structured response formats
+const (
+	ResponseFormatJSON = llm.ResponseFormatJSON // JSON matching the target's JSON schema
+	ResponseFormatXML  = llm.ResponseFormatXML  // XML matching a schema derived from the target's xml tags
+	ResponseFormatYAML = llm.ResponseFormatYAML // YAML matching the target's yaml tags
+)
+
+// JSON modes of structured responses
+const (
+	JSONModeObject = providers.JSONModeObject // Any JSON object, the schema described in the prompt
+	JSONModeSchema = providers.JSONModeSchema // The schema enforced in strict mode
+)
+
+var (
+	// WithResponse makes Generate fill a Response with the details of the call.
+	WithResponse = llm.WithResponse
+
+	// GenerateResponse calls Generate and returns the detailed Response.
+	GenerateResponse = llm.GenerateResponse
+
+	// ParseToolCallText extracts the tool calls of text in the legacy <function_call> format.
+	ParseToolCallText = llm.ParseToolCallText
+
+	// WithDryRun makes Generate return the prepared request instead of calling the API.
+	WithDryRun = llm.WithDryRun
+
+	// WithCostLookup fetches the actual cost of the generation into the Response metadata.
+	WithCostLookup = llm.WithCostLookup
+
+	// RegisterModelCapabilities declares what a provider's model supports.
+	RegisterModelCapabilities = providers.RegisterModelCapabilities
+
+	// LookupModelCapabilities returns what a provider's model supports.
+	LookupModelCapabilities = providers.LookupModelCapabilities
+
+	// BaseModel returns the model a fine-tuned OpenAI model was trained from.
+	BaseModel = providers.BaseModel
+
+	// WithPostProcessors applies post-processors, in order, to the generated text.
+	WithPostProcessors = llm.WithPostProcessors
+
+	// StripCodeFences returns the content of the first markdown code block of the text.
+	StripCodeFences = llm.StripCodeFences
+
+	// ExtractJSON returns the first JSON object or array of the text.
+	ExtractJSON = llm.ExtractJSON
+
+	// MaxSentences keeps the first sentences of the text.
+	MaxSentences = llm.MaxSentences
+
+	// MaxWords keeps the first words of the text, ending at a complete sentence when possible.
+	MaxWords = llm.MaxWords
+
+	// EnforceLanguage rejects text that is not in the given language, retrying the attempt.
+	EnforceLanguage = llm.EnforceLanguage
+
+	// WithStructuredResponseFormat asks for a JSON, XML or YAML response decoded and validated into a Go value.
+	WithStructuredResponseFormat = llm.WithStructuredResponseFormat
+
+	// WithDefaultedFields receives the fields of a structured response that were left out and set to their default tag.
+	WithDefaultedFields = llm.WithDefaultedFields
+
+	// UnmarshalWithDefaults decodes JSON into a struct and sets the left out fields to their default tag.
+	UnmarshalWithDefaults = llm.UnmarshalWithDefaults
+
+	// WithCleanStrategy sets how the generated text of a call is cleaned.
+	WithCleanStrategy = llm.WithCleanStrategy
+
+	// CleanText cleans a text with a clean strategy.
+	CleanText = llm.CleanText
+
+	// WithResponseValidators checks the generated text, regenerating it once with feedback when invalid.
+	WithResponseValidators = llm.WithResponseValidators
+
+	// RequirePattern rejects text that does not match a regular expression.
+	RequirePattern = llm.RequirePattern
+
+	// ForbidPattern rejects text that matches a regular expression.
+	ForbidPattern = llm.ForbidPattern
+
+	// RequireCitations rejects text without citation markers such as [1].
+	RequireCitations = llm.RequireCitations
+
+	// ForbidURLs rejects text containing URLs.
+	ForbidURLs = llm.ForbidURLs
+
+	// RequireMaxSentences rejects text longer than a number of sentences.
+	RequireMaxSentences = llm.RequireMaxSentences
+
+	// RequireMaxWords rejects text longer than a number of words.
+	RequireMaxWords = llm.RequireMaxWords
+
+	// DetectLanguage returns the ISO 639-1 code of the language of a text, or "".
+	DetectLanguage = llm.DetectLanguage
+)
+
+// ErrContentFiltered is wrapped by the errors of requests a provider's safety system refused or filtered.
+var ErrContentFiltered = llm.ErrContentFiltered
@@ -0,0 +1,15 @@
+// Package gollm provides schema import for structured responses of Language Learning Models.
+// This file contains re-exports for deriving JSON schemas from OpenAPI and protobuf definitions.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+var (
+	// SchemaFromOpenAPI derives a JSON schema from a component schema of an OpenAPI 3 document.
+	SchemaFromOpenAPI = llm.SchemaFromOpenAPI
+
+	// SchemaFromProto derives a JSON schema from a message of a .proto file.
+	SchemaFromProto = llm.SchemaFromProto
+)
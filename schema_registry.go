@@ -0,0 +1,29 @@
+// Package gollm provides versioned schemas for structured responses of Language Learning Models.
+// This file contains re-exports for registering, referencing and migrating named schemas.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// SchemaRegistry holds named, versioned JSON schemas referenced as "name@version".
+	SchemaRegistry = llm.SchemaRegistry
+
+	// SchemaMigration converts a document from one version of a schema to another.
+	SchemaMigration = llm.SchemaMigration
+)
+
+var (
+	// NewSchemaRegistry creates an empty SchemaRegistry.
+	NewSchemaRegistry = llm.NewSchemaRegistry
+
+	// DefaultSchemaRegistry is the registry of RegisterSchema and WithSchemaRef.
+	DefaultSchemaRegistry = llm.DefaultSchemaRegistry
+
+	// RegisterSchema registers a named, versioned schema in the default registry.
+	RegisterSchema = llm.RegisterSchema
+
+	// WithSchemaRef asks for a JSON response conforming to a schema of the default registry, such as "invoice@v2".
+	WithSchemaRef = llm.WithSchemaRef
+)
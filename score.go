@@ -0,0 +1,13 @@
+// Package gollm provides log-likelihood scoring for Language Learning Models.
+// This file contains re-exports for scoring continuations of a prompt.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// ScoreResult is the log-likelihood of a continuation given a prompt.
+type ScoreResult = llm.ScoreResult
+
+// Score returns the log-likelihood of a continuation following a prompt using the provider's completions endpoint.
+var Score = llm.Score
@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSConfig configures an AWS Secrets Manager key provider.
+type AWSConfig struct {
+	// Region of the secrets. Defaults to AWS_REGION, then AWS_DEFAULT_REGION.
+	Region string
+
+	// Credentials. Default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Names maps provider names to secret names or ARNs.
+	Names map[string]string
+
+	// Field, if set, extracts a field from a secret holding a JSON object.
+	Field string
+
+	// Endpoint overrides the regional Secrets Manager endpoint.
+	Endpoint string
+
+	// HTTPClient is used for all requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// AWSSecretsManager reads API keys from AWS Secrets Manager. Requests are
+// signed with Signature Version 4 using static or environment credentials.
+type AWSSecretsManager struct {
+	cfg AWSConfig
+	now func() time.Time
+}
+
+// NewAWSSecretsManager creates an AWS Secrets Manager key provider, filling
+// unset region and credentials from the standard AWS_* environment variables.
+func NewAWSSecretsManager(cfg AWSConfig) *AWSSecretsManager {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", cfg.Region)
+	}
+	return &AWSSecretsManager{cfg: cfg, now: time.Now}
+}
+
+// GetAPIKey fetches the current version of the provider's secret.
+func (a *AWSSecretsManager) GetAPIKey(ctx context.Context, provider string) (string, error) {
+	name, err := secretName(a.cfg.Names, provider)
+	if err != nil {
+		return "", err
+	}
+	if a.cfg.Region == "" || a.cfg.AccessKeyID == "" || a.cfg.SecretAccessKey == "" {
+		return "", fmt.Errorf("aws secrets manager: region and credentials are required")
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(a.cfg.Endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, body)
+
+	var response struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := doJSON(a.cfg.HTTPClient, req, &response); err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+	return extractField(response.SecretString, a.cfg.Field)
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (a *AWSSecretsManager) sign(req *http.Request, body []byte) {
+	const service = "secretsmanager"
+	now := a.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, sha256Hex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, a.cfg.Region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, a.cfg.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPConfig configures a Google Cloud Secret Manager key provider.
+type GCPConfig struct {
+	// Project is the GCP project ID used for short secret names.
+	Project string
+
+	// Names maps provider names to secret IDs. A short ID reads the latest
+	// version; a full "projects/.../secrets/.../versions/..." name is used as-is.
+	Names map[string]string
+
+	// Field, if set, extracts a field from a secret holding a JSON object.
+	Field string
+
+	// TokenSource returns an OAuth2 access token. By default the token of the
+	// default service account is read from the GCE metadata server.
+	TokenSource func(ctx context.Context) (string, error)
+
+	// Endpoint overrides the Secret Manager API base URL.
+	Endpoint string
+
+	// HTTPClient is used for all requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// GCPSecretManager reads API keys from Google Cloud Secret Manager.
+type GCPSecretManager struct {
+	cfg GCPConfig
+}
+
+// NewGCPSecretManager creates a GCP Secret Manager key provider.
+func NewGCPSecretManager(cfg GCPConfig) *GCPSecretManager {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://secretmanager.googleapis.com"
+	}
+	g := &GCPSecretManager{cfg: cfg}
+	if g.cfg.TokenSource == nil {
+		g.cfg.TokenSource = g.metadataToken
+	}
+	return g
+}
+
+// GetAPIKey accesses the configured secret version for the provider.
+func (g *GCPSecretManager) GetAPIKey(ctx context.Context, provider string) (string, error) {
+	name, err := secretName(g.cfg.Names, provider)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(name, "projects/") {
+		if g.cfg.Project == "" {
+			return "", fmt.Errorf("gcp secret manager: project is required for secret %q", name)
+		}
+		name = fmt.Sprintf("projects/%s/secrets/%s/versions/latest", g.cfg.Project, name)
+	}
+
+	token, err := g.cfg.TokenSource(ctx)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: failed to get access token: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s:access", strings.TrimRight(g.cfg.Endpoint, "/"), name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var response struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := doJSON(g.cfg.HTTPClient, req, &response); err != nil {
+		return "", fmt.Errorf("gcp secret manager: %w", err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: invalid payload: %w", err)
+	}
+	return extractField(string(value), g.cfg.Field)
+}
+
+// metadataToken fetches an access token for the default service account.
+func (g *GCPSecretManager) metadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(g.cfg.HTTPClient, req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,72 @@
+// Package secrets provides config.KeyProvider implementations that read API keys
+// from AWS Secrets Manager, GCP Secret Manager and HashiCorp Vault.
+//
+// The providers talk to the services' HTTP APIs directly, so no cloud SDK is
+// required. Each one maps gollm provider names (e.g., "openai") to secret
+// identifiers and can extract a single field when the secret holds a JSON object.
+//
+// Example usage:
+//
+//	keys := secrets.NewAWSSecretsManager(secrets.AWSConfig{
+//		Region: "eu-west-1",
+//		Names:  map[string]string{"openai": "prod/gollm/openai"},
+//	})
+//	llm, err := gollm.NewLLM(
+//		gollm.SetProvider("openai"),
+//		gollm.SetKeyProvider(keys, 15*time.Minute),
+//	)
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// secretName returns the secret identifier configured for a provider.
+func secretName(names map[string]string, provider string) (string, error) {
+	name, ok := names[provider]
+	if !ok || name == "" {
+		return "", fmt.Errorf("no secret configured for provider %q", provider)
+	}
+	return name, nil
+}
+
+// extractField returns value unchanged when field is empty, otherwise it
+// decodes value as a JSON object and returns the named string field.
+func extractField(value, field string) (string, error) {
+	if field == "" {
+		return value, nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	s, ok := object[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string field %q", field)
+	}
+	return s, nil
+}
+
+// doJSON sends req and decodes a successful JSON response into out.
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status code %d: %s", req.Method, req.URL.Redacted(), resp.StatusCode, body)
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultGetAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/secret/data/gollm/openai", r.URL.Path)
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"sk-vault"}}}`))
+	}))
+	defer server.Close()
+
+	vault := NewVault(VaultConfig{
+		Address: server.URL,
+		Token:   "vault-token",
+		Names:   map[string]string{"openai": "gollm/openai"},
+	})
+	key, err := vault.GetAPIKey(context.Background(), "openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-vault", key)
+
+	_, err = vault.GetAPIKey(context.Background(), "anthropic")
+	assert.ErrorContains(t, err, "anthropic")
+}
+
+func TestGCPSecretManagerGetAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/my-project/secrets/openai-key/versions/latest:access", r.URL.Path)
+		assert.Equal(t, "Bearer gcp-token", r.Header.Get("Authorization"))
+		data := base64.StdEncoding.EncodeToString([]byte(`{"key":"sk-gcp"}`))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"payload": map[string]string{"data": data}})
+	}))
+	defer server.Close()
+
+	gcp := NewGCPSecretManager(GCPConfig{
+		Project:     "my-project",
+		Names:       map[string]string{"openai": "openai-key"},
+		Field:       "key",
+		Endpoint:    server.URL,
+		TokenSource: func(context.Context) (string, error) { return "gcp-token", nil },
+	})
+	key, err := gcp.GetAPIKey(context.Background(), "openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-gcp", key)
+}
+
+func TestAWSSecretsManagerGetAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"), auth)
+		assert.Contains(t, auth, "/us-east-1/secretsmanager/aws4_request")
+
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "prod/openai", body["SecretId"])
+		_, _ = w.Write([]byte(`{"SecretString":"sk-aws"}`))
+	}))
+	defer server.Close()
+
+	aws := NewAWSSecretsManager(AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Names:           map[string]string{"openai": "prod/openai"},
+		Endpoint:        server.URL,
+	})
+	key, err := aws.GetAPIKey(context.Background(), "openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-aws", key)
+}
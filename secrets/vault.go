@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultConfig configures a HashiCorp Vault KV version 2 key provider.
+type VaultConfig struct {
+	// Address of the Vault server. Defaults to VAULT_ADDR.
+	Address string
+
+	// Token used to authenticate. Defaults to VAULT_TOKEN.
+	Token string
+
+	// Namespace sent as X-Vault-Namespace (Vault Enterprise). Defaults to VAULT_NAMESPACE.
+	Namespace string
+
+	// Mount is the KV v2 mount path (default: "secret").
+	Mount string
+
+	// Names maps provider names to secret paths below the mount (e.g., "gollm/openai").
+	Names map[string]string
+
+	// Field is the key inside the secret holding the API key (default: "api_key").
+	Field string
+
+	// HTTPClient is used for Vault requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// Vault reads API keys from a Vault KV v2 secrets engine.
+type Vault struct {
+	cfg VaultConfig
+}
+
+// NewVault creates a Vault key provider, filling unset fields from the
+// standard VAULT_* environment variables and defaults.
+func NewVault(cfg VaultConfig) *Vault {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Field == "" {
+		cfg.Field = "api_key"
+	}
+	return &Vault{cfg: cfg}
+}
+
+// GetAPIKey reads the latest version of the provider's secret.
+func (v *Vault) GetAPIKey(ctx context.Context, provider string) (string, error) {
+	path, err := secretName(v.cfg.Names, provider)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(v.cfg.Address, "/"), strings.Trim(v.cfg.Mount, "/"), strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.Token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	var response struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := doJSON(v.cfg.HTTPClient, req, &response); err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+
+	key, ok := response.Data.Data[v.cfg.Field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: secret %s has no string field %q", path, v.cfg.Field)
+	}
+	return key, nil
+}
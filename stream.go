@@ -24,3 +24,53 @@ type (
 
 // StreamOption is a function type that modifies StreamConfig
 type StreamOption = llm.StreamOption
+
+// StreamFormat selects the wire format used by NewStreamHandler.
+type StreamFormat = llm.StreamFormat
+
+// Stream formats for NewStreamHandler
+const (
+	StreamFormatAuto   = llm.StreamFormatAuto
+	StreamFormatSSE    = llm.StreamFormatSSE
+	StreamFormatNDJSON = llm.StreamFormatNDJSON
+)
+
+var (
+	// StreamTo streams a response into an io.Writer, flushing after each token.
+	StreamTo = llm.StreamTo
+
+	// NewStreamHandler returns an http.Handler that streams responses as
+	// server-sent events or NDJSON.
+	NewStreamHandler = llm.NewStreamHandler
+)
+
+// StreamCallbacks receives the token, tool call, usage and completion events
+// of a response streamed with StreamWithCallbacks.
+type StreamCallbacks = llm.StreamCallbacks
+
+// Token types with special meaning to StreamWithCallbacks
+const (
+	TokenTypeText      = llm.TokenTypeText
+	TokenTypeToolCall  = llm.TokenTypeToolCall
+	TokenTypeUsage     = llm.TokenTypeUsage
+	TokenTypeReasoning = llm.TokenTypeReasoning
+)
+
+// StreamWithCallbacks streams a response and dispatches each token to callbacks.
+var StreamWithCallbacks = llm.StreamWithCallbacks
+
+// StreamResult summarizes a stream that has ended or been closed, including
+// the partial text of a stream cancelled mid-flight.
+type StreamResult = llm.StreamResult
+
+// ErrStreamClosed is returned by Next after the stream has been closed.
+var ErrStreamClosed = llm.ErrStreamClosed
+
+// PartialResult returns the text, usage and truncation state of a stream so far.
+var PartialResult = llm.PartialResult
+
+// WithStopPattern ends a stream as soon as a regular expression matches the text received so far.
+var WithStopPattern = llm.WithStopPattern
+
+// WithoutReasoning drops the reasoning tokens that reasoning models stream before their answer.
+var WithoutReasoning = llm.WithoutReasoning
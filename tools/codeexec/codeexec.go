@@ -0,0 +1,119 @@
+// Package codeexec provides an opt-in tool that runs model-generated code and
+// returns its output, for data-analysis agents.
+//
+// Running model-generated code is dangerous. The SubprocessExecutor limits
+// CPU time, memory, output and wall-clock time, and runs the code in an
+// empty temporary directory with a minimal environment, but it is not a
+// security boundary: the code runs as the current user with network and
+// file system access. Run it inside a container or VM, or implement
+// Executor to delegate to a remote sandbox service.
+//
+// Example:
+//
+//	executor := codeexec.NewSubprocessExecutor(codeexec.WithTimeout(10 * time.Second))
+//	runCode, err := codeexec.NewTool(executor)
+//	err = registry.Register(runCode)
+package codeexec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/tools"
+)
+
+// Request is code to execute.
+type Request struct {
+	// Language names the interpreter, such as "python".
+	Language string
+
+	// Code is the program source.
+	Code string
+}
+
+// Result is the outcome of an execution.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+
+	// Duration is the wall-clock time of the execution.
+	Duration time.Duration
+
+	// TimedOut is true when the execution was stopped at its timeout.
+	TimedOut bool
+
+	// Truncated is true when the output exceeded the executor's limit.
+	Truncated bool
+}
+
+// Executor runs code. Implement it to run code in a remote sandbox, such as
+// a container service; the tool only depends on this interface.
+type Executor interface {
+	// Languages returns the languages the executor can run.
+	Languages() []string
+
+	// Execute runs the code. A program that fails or times out is reported
+	// in the Result; the error is for failures of the executor itself.
+	Execute(ctx context.Context, req Request) (*Result, error)
+}
+
+// codeParams are the parameters the model passes to the tool.
+type codeParams struct {
+	Language string `json:"language" jsonschema:"description=Programming language of the code"`
+	Code     string `json:"code" jsonschema:"description=Complete program to run. Print the results you need to stdout."`
+}
+
+// NewTool creates a "run_code" tool that executes code with executor and
+// returns its exit code, stdout and stderr. The language parameter is
+// restricted to the executor's languages.
+func NewTool(executor Executor) (*tools.Tool, error) {
+	if executor == nil {
+		return nil, fmt.Errorf("code execution tool requires an executor")
+	}
+	languages := executor.Languages()
+	if len(languages) == 0 {
+		return nil, fmt.Errorf("executor supports no languages")
+	}
+	sort.Strings(languages)
+
+	tool, err := tools.FromFunc(func(ctx context.Context, p codeParams) (string, error) {
+		result, err := executor.Execute(ctx, Request{Language: p.Language, Code: p.Code})
+		if err != nil {
+			return "", err
+		}
+		return FormatResult(result), nil
+	}, "run_code", fmt.Sprintf("Run a program in a sandbox and return its exit code, stdout and stderr. Supported languages: %s. State is not kept between calls.", strings.Join(languages, ", ")))
+	if err != nil {
+		return nil, err
+	}
+
+	enum := make([]interface{}, len(languages))
+	for i, language := range languages {
+		enum[i] = language
+	}
+	if properties, ok := tool.Definition.Function.Parameters["properties"].(map[string]interface{}); ok {
+		if language, ok := properties["language"].(map[string]interface{}); ok {
+			language["enum"] = enum
+		}
+	}
+	return tool, nil
+}
+
+// FormatResult formats a result for the tool message.
+func FormatResult(result *Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "exit code: %d\n", result.ExitCode)
+	if result.TimedOut {
+		fmt.Fprintf(&b, "timed out after %s\n", result.Duration.Round(time.Millisecond))
+	}
+	if result.Truncated {
+		b.WriteString("output truncated\n")
+	}
+	fmt.Fprintf(&b, "stdout:\n%s\n", strings.TrimRight(result.Stdout, "\n"))
+	fmt.Fprintf(&b, "stderr:\n%s\n", strings.TrimRight(result.Stderr, "\n"))
+	return b.String()
+}
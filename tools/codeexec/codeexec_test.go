@@ -0,0 +1,51 @@
+package codeexec
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/llm"
+)
+
+func TestSubprocessExecutor(t *testing.T) {
+	executor := NewSubprocessExecutor(
+		WithInterpreter("sh", Interpreter{Extension: ".sh", Command: []string{"sh"}}),
+		WithTimeout(500*time.Millisecond),
+		WithMaxOutput(8),
+	)
+
+	result, err := executor.Execute(context.Background(), Request{Language: "sh", Code: "echo hi; echo oops >&2; exit 3"})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Stdout: "hi\n", Stderr: "oops\n", ExitCode: 3}, Result{Stdout: result.Stdout, Stderr: result.Stderr, ExitCode: result.ExitCode})
+
+	result, err = executor.Execute(context.Background(), Request{Language: "sh", Code: "echo 0123456789"})
+	require.NoError(t, err)
+	assert.Equal(t, "01234567", result.Stdout)
+	assert.True(t, result.Truncated)
+
+	result, err = executor.Execute(context.Background(), Request{Language: "sh", Code: "sleep 5"})
+	require.NoError(t, err)
+	assert.True(t, result.TimedOut)
+
+	_, err = executor.Execute(context.Background(), Request{Language: "cobol", Code: ""})
+	assert.Error(t, err)
+}
+
+func TestTool(t *testing.T) {
+	executor := NewSubprocessExecutor(WithInterpreter("sh", Interpreter{Extension: ".sh", Command: []string{"sh"}}))
+	tool, err := NewTool(executor)
+	require.NoError(t, err)
+	language := tool.Definition.Function.Parameters["properties"].(map[string]interface{})["language"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"bash", "javascript", "python", "sh"}, language["enum"])
+
+	var call llm.ToolCall
+	call.Function.Name = "run_code"
+	call.Function.Arguments = json.RawMessage(`{"language": "sh", "code": "echo $((6 * 7))"}`)
+	output, err := tool.Invoke(context.Background(), call)
+	require.NoError(t, err)
+	assert.Equal(t, "exit code: 0\nstdout:\n42\nstderr:\n\n", output)
+}
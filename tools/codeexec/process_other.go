@@ -0,0 +1,9 @@
+//go:build !unix
+
+package codeexec
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups are not supported; only
+// the program itself is killed on cancellation.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,214 @@
+package codeexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Interpreter runs the programs of a language: the program is written to a
+// file with Extension, whose path is appended to Command.
+type Interpreter struct {
+	Extension string
+	Command   []string
+}
+
+// DefaultInterpreters are the languages a SubprocessExecutor runs unless
+// configured otherwise.
+var DefaultInterpreters = map[string]Interpreter{
+	"python":     {Extension: ".py", Command: []string{"python3"}},
+	"javascript": {Extension: ".js", Command: []string{"node"}},
+	"bash":       {Extension: ".sh", Command: []string{"bash"}},
+}
+
+// SubprocessExecutor runs code in a local subprocess, under resource limits
+// set with ulimit, in a temporary directory removed afterwards. It requires
+// a POSIX shell at /bin/sh. See the package documentation for its
+// security limitations.
+type SubprocessExecutor struct {
+	interpreters map[string]Interpreter
+	timeout      time.Duration
+	cpuTime      time.Duration
+	memoryBytes  int64
+	maxOutput    int
+	env          []string
+}
+
+// SubprocessOption configures a SubprocessExecutor.
+type SubprocessOption func(*SubprocessExecutor)
+
+// WithTimeout sets the wall-clock limit of an execution, 30 seconds by
+// default.
+func WithTimeout(timeout time.Duration) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.timeout = timeout
+	}
+}
+
+// WithCPULimit sets the CPU time limit of an execution, 10 seconds by
+// default.
+func WithCPULimit(cpuTime time.Duration) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.cpuTime = cpuTime
+	}
+}
+
+// WithMemoryLimit sets the virtual memory limit of an execution, 512 MiB by
+// default. Zero removes the limit, which some runtimes, such as Node.js,
+// need as they reserve large address spaces.
+func WithMemoryLimit(bytes int64) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.memoryBytes = bytes
+	}
+}
+
+// WithMaxOutput sets the number of bytes of stdout and of stderr kept, 64
+// KiB each by default.
+func WithMaxOutput(bytes int) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.maxOutput = bytes
+	}
+}
+
+// WithInterpreter adds or replaces the interpreter of a language.
+func WithInterpreter(language string, interpreter Interpreter) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.interpreters[language] = interpreter
+	}
+}
+
+// WithEnv sets environment variables of the programs, as "KEY=value". By
+// default programs only get PATH and a HOME in their working directory.
+func WithEnv(env ...string) SubprocessOption {
+	return func(e *SubprocessExecutor) {
+		e.env = append(e.env, env...)
+	}
+}
+
+// NewSubprocessExecutor creates an executor running the DefaultInterpreters
+// in subprocesses.
+func NewSubprocessExecutor(opts ...SubprocessOption) *SubprocessExecutor {
+	e := &SubprocessExecutor{
+		interpreters: make(map[string]Interpreter, len(DefaultInterpreters)),
+		timeout:      30 * time.Second,
+		cpuTime:      10 * time.Second,
+		memoryBytes:  512 << 20,
+		maxOutput:    64 << 10,
+	}
+	for language, interpreter := range DefaultInterpreters {
+		e.interpreters[language] = interpreter
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Languages implements Executor.
+func (e *SubprocessExecutor) Languages() []string {
+	languages := make([]string, 0, len(e.interpreters))
+	for language := range e.interpreters {
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// Execute implements Executor.
+func (e *SubprocessExecutor) Execute(ctx context.Context, req Request) (*Result, error) {
+	interpreter, ok := e.interpreters[req.Language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q", req.Language)
+	}
+
+	dir, err := os.MkdirTemp("", "gollm-codeexec-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating working directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	program := filepath.Join(dir, "main"+interpreter.Extension)
+	if err := os.WriteFile(program, []byte(req.Code), 0o600); err != nil {
+		return nil, fmt.Errorf("error writing program: %w", err)
+	}
+
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	// The limits apply to the shell, which execs the interpreter
+	script := ""
+	if e.cpuTime > 0 {
+		script += fmt.Sprintf("ulimit -t %d; ", int((e.cpuTime+time.Second-1)/time.Second))
+	}
+	if e.memoryBytes > 0 {
+		script += fmt.Sprintf("ulimit -v %d; ", e.memoryBytes>>10)
+	}
+	script += `exec "$@"`
+	args := append([]string{"-c", script, "sh"}, interpreter.Command...)
+	cmd := exec.CommandContext(ctx, "/bin/sh", append(args, program)...)
+	cmd.Dir = dir
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir}, e.env...)
+	stdout := &limitedBuffer{limit: e.maxOutput}
+	stderr := &limitedBuffer{limit: e.maxOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Stop the processes the program started along with it
+	killProcessGroup(cmd)
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	result := &Result{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Duration:  time.Since(start),
+		TimedOut:  ctx.Err() == context.DeadlineExceeded,
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return nil, fmt.Errorf("error running %s program: %w", req.Language, err)
+	}
+	return result, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest. A limit of zero keeps everything.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if b.limit > 0 {
+		if room := b.limit - b.buf.Len(); len(p) > room {
+			p = p[:max(room, 0)]
+			b.truncated = true
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
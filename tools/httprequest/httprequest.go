@@ -0,0 +1,234 @@
+// Package httprequest provides an "http_request" tool that lets models call
+// HTTP APIs on an allow-list of domains.
+//
+// Example:
+//
+//	fetch, err := httprequest.NewTool(
+//	    httprequest.WithAllowedDomains("api.github.com", "*.wikipedia.org"),
+//	    httprequest.WithLogger(logger),
+//	)
+//	err = registry.Register(fetch)
+package httprequest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/teilomillet/gollm/tools"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// Option configures the tool created by NewTool.
+type Option func(*httpTool)
+
+// maxRedirects is the number of redirects the tool follows.
+const maxRedirects = 5
+
+type httpTool struct {
+	domains []string
+	maxSize int64
+	timeout time.Duration
+	client  *http.Client
+	logger  utils.Logger
+	headers map[string]string
+}
+
+// WithAllowedDomains sets the domains the tool may request. A domain
+// matches its host exactly; "*.example.com" matches the subdomains of
+// example.com. At least one domain is required.
+func WithAllowedDomains(domains ...string) Option {
+	return func(t *httpTool) {
+		for _, domain := range domains {
+			t.domains = append(t.domains, strings.ToLower(strings.TrimSpace(domain)))
+		}
+	}
+}
+
+// WithMaxResponseSize sets the number of response body bytes returned to
+// the model, 100 KB by default. Longer bodies are truncated. NewTool rejects
+// sizes that are not positive.
+func WithMaxResponseSize(bytes int64) Option {
+	return func(t *httpTool) {
+		t.maxSize = bytes
+	}
+}
+
+// WithTimeout sets the timeout of a request, 15 seconds by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *httpTool) {
+		t.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the tool. Its redirect policy is
+// replaced by one that enforces the allow-list.
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *httpTool) {
+		t.client = client
+	}
+}
+
+// WithLogger logs every request, at the info level, and the response
+// bodies, at the debug level.
+func WithLogger(logger utils.Logger) Option {
+	return func(t *httpTool) {
+		t.logger = logger
+	}
+}
+
+// WithHeaders adds headers to every request, such as an API token the
+// model must not see. They are not sent on to another host when a request
+// is redirected.
+func WithHeaders(headers map[string]string) Option {
+	return func(t *httpTool) {
+		for k, v := range headers {
+			t.headers[k] = v
+		}
+	}
+}
+
+// requestParams are the parameters the model passes to the tool.
+type requestParams struct {
+	Method  string            `json:"method,omitempty" jsonschema:"enum=GET,enum=POST,description=HTTP method (default GET)"`
+	URL     string            `json:"url" jsonschema:"description=Absolute http or https URL"`
+	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=Request headers"`
+	Body    string            `json:"body,omitempty" jsonschema:"description=Request body for POST requests"`
+}
+
+// NewTool creates the "http_request" tool, which sends GET and POST
+// requests to the allowed domains and returns the status, content type and
+// body of the response.
+func NewTool(opts ...Option) (*tools.Tool, error) {
+	t := &httpTool{
+		maxSize: 100 << 10,
+		timeout: 15 * time.Second,
+		logger:  utils.NewLogger(utils.LogLevelOff),
+		headers: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if len(t.domains) == 0 {
+		return nil, fmt.Errorf("http_request tool requires allowed domains")
+	}
+	if t.maxSize <= 0 {
+		return nil, fmt.Errorf("http_request tool requires a positive max response size, got %d", t.maxSize)
+	}
+
+	client := &http.Client{}
+	if t.client != nil {
+		copied := *t.client
+		client = &copied
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if err := t.check(req.URL); err != nil {
+			return err
+		}
+		// The configured headers hold credentials for the requested host only
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for k := range t.headers {
+				req.Header.Del(k)
+			}
+		}
+		return nil
+	}
+	t.client = client
+
+	sort.Strings(t.domains)
+	return tools.FromFunc(t.do, "http_request",
+		"Send an HTTP GET or POST request and return the response. Allowed domains: "+strings.Join(t.domains, ", "))
+}
+
+// check returns an error unless u is an http or https URL on an allowed
+// domain.
+func (t *httpTool) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range t.domains {
+		if host == domain {
+			return nil
+		}
+		if suffix, ok := strings.CutPrefix(domain, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %q is not allowed", host)
+}
+
+func (t *httpTool) do(ctx context.Context, p requestParams) (string, error) {
+	method := strings.ToUpper(p.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		return "", fmt.Errorf("unsupported method %q", p.Method)
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := t.check(u); err != nil {
+		return "", err
+	}
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if p.Body != "" {
+		body = strings.NewReader(p.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.logger.Warn("HTTP request failed", "method", method, "url", u.Redacted(), "error", err)
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %w", err)
+	}
+	truncated := int64(len(data)) > t.maxSize
+	if truncated {
+		data = data[:t.maxSize]
+	}
+	t.logger.Info("HTTP request", "method", method, "url", u.Redacted(), "status", resp.StatusCode, "bytes", len(data), "duration", time.Since(start))
+	t.logger.Debug("HTTP response", "url", u.Redacted(), "body", string(data))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "status: %s\n", resp.Status)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		fmt.Fprintf(&b, "content-type: %s\n", contentType)
+	}
+	if truncated {
+		fmt.Fprintf(&b, "body truncated to %d bytes\n", t.maxSize)
+	}
+	b.WriteString("\n")
+	b.Write(data)
+	return b.String(), nil
+}
@@ -0,0 +1,93 @@
+package httprequest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/llm"
+)
+
+func TestTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			assert.Equal(t, "secret", r.Header.Get("X-Token"))
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(r.Method + " " + string(body)))
+		case "/redirect":
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	_, err := NewTool()
+	assert.Error(t, err, "an allow-list is required")
+	for _, size := range []int64{0, -1} {
+		_, err = NewTool(WithAllowedDomains(u.Hostname()), WithMaxResponseSize(size))
+		assert.ErrorContains(t, err, "positive max response size")
+	}
+
+	tool, err := NewTool(WithAllowedDomains(u.Hostname()), WithHeaders(map[string]string{"X-Token": "secret"}), WithMaxResponseSize(8))
+	require.NoError(t, err)
+	invoke := func(args map[string]string) (string, error) {
+		arguments, _ := json.Marshal(args)
+		var call llm.ToolCall
+		call.Function.Name = "http_request"
+		call.Function.Arguments = arguments
+		return tool.Invoke(context.Background(), call)
+	}
+
+	output, err := invoke(map[string]string{"method": "POST", "url": server.URL + "/echo", "body": "hello world"})
+	require.NoError(t, err)
+	assert.Equal(t, "status: 200 OK\ncontent-type: text/plain\nbody truncated to 8 bytes\n\nPOST hel", output)
+
+	_, err = invoke(map[string]string{"url": "http://example.com/"})
+	assert.ErrorContains(t, err, "not allowed")
+	_, err = invoke(map[string]string{"url": server.URL + "/redirect"})
+	assert.ErrorContains(t, err, "not allowed")
+	_, err = invoke(map[string]string{"url": "file:///etc/passwd"})
+	assert.ErrorContains(t, err, "scheme")
+}
+
+func TestToolRedirectHeaders(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("token=" + r.Header.Get("X-Token") + " trace=" + r.Header.Get("X-Trace")))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		case "/echo":
+			_, _ = w.Write([]byte("token=" + r.Header.Get("X-Token") + " trace=" + r.Header.Get("X-Trace")))
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	tool, err := NewTool(WithAllowedDomains(u.Hostname()), WithHeaders(map[string]string{"X-Token": "secret"}))
+	require.NoError(t, err)
+	invoke := func(path string) string {
+		arguments, _ := json.Marshal(map[string]interface{}{"url": server.URL + path, "headers": map[string]string{"X-Trace": "1"}})
+		var call llm.ToolCall
+		call.Function.Name = "http_request"
+		call.Function.Arguments = arguments
+		output, err := tool.Invoke(context.Background(), call)
+		require.NoError(t, err)
+		return output
+	}
+
+	assert.Contains(t, invoke("/same"), "token=secret trace=1")
+	assert.Contains(t, invoke("/other"), "token= trace=1", "configured headers are dropped when the host changes")
+}
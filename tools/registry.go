@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// NamespaceSeparator joins a namespace and a tool name. Tool names accepted
+// by the providers may only contain letters, digits, underscores and dashes.
+const NamespaceSeparator = "__"
+
+var (
+	// ErrToolNotFound is returned when a call names an unregistered tool.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolNotAllowed is returned when a call names a tool excluded by the
+	// allow and deny lists of the request.
+	ErrToolNotAllowed = errors.New("tool not allowed")
+)
+
+// Handler executes a tool call and returns the result for the tool message.
+type Handler func(ctx context.Context, call llm.ToolCall) (string, error)
+
+// Middleware wraps the execution of tool calls, for logging, authorization
+// or metrics. Middleware runs after the call's arguments are validated.
+//
+// Example:
+//
+//	authz := func(next tools.Handler) tools.Handler {
+//	    return func(ctx context.Context, call llm.ToolCall) (string, error) {
+//	        if !allowed(ctx, call.Function.Name) {
+//	            return "", fmt.Errorf("%s: permission denied", call.Function.Name)
+//	        }
+//	        return next(ctx, call)
+//	    }
+//	}
+type Middleware func(next Handler) Handler
+
+// ToolRegistry holds the tools an application exposes to models. It is safe
+// for concurrent use.
+type ToolRegistry struct {
+	mu         sync.RWMutex
+	tools      map[string]*registeredTool
+	timeout    time.Duration
+	middleware []Middleware
+
+	// definitions caches the tool definitions sorted by name; nil when a
+	// tool was added or removed since it was built
+	definitions []utils.Tool
+
+	embed      EmbedFunc
+	embeddings map[string][]float64 // Embedding of each tool's description
+}
+
+type registeredTool struct {
+	tool    *Tool
+	timeout time.Duration
+}
+
+// RegistryOption configures a ToolRegistry.
+type RegistryOption func(*ToolRegistry)
+
+// WithDefaultTimeout limits the execution time of every tool that has no
+// timeout of its own.
+func WithDefaultTimeout(timeout time.Duration) RegistryOption {
+	return func(r *ToolRegistry) {
+		r.timeout = timeout
+	}
+}
+
+// WithMiddleware adds execution middleware. The first middleware is the
+// outermost one.
+func WithMiddleware(middleware ...Middleware) RegistryOption {
+	return func(r *ToolRegistry) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
+// NewToolRegistry creates an empty tool registry.
+func NewToolRegistry(opts ...RegistryOption) *ToolRegistry {
+	r := &ToolRegistry{tools: make(map[string]*registeredTool)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ToolOption configures a registered tool.
+type ToolOption func(*registeredTool)
+
+// WithTimeout limits the execution time of the tool.
+func WithTimeout(timeout time.Duration) ToolOption {
+	return func(t *registeredTool) {
+		t.timeout = timeout
+	}
+}
+
+// Register adds a tool to the registry. It fails if a tool with the same
+// name is already registered.
+func (r *ToolRegistry) Register(tool *Tool, opts ...ToolOption) error {
+	return r.register(tool, opts)
+}
+
+// RegisterNamespace adds a tool under a namespace: its name becomes
+// namespace + NamespaceSeparator + name, so tools of different sources do
+// not collide.
+func (r *ToolRegistry) RegisterNamespace(namespace string, tool *Tool, opts ...ToolOption) error {
+	namespaced := *tool
+	namespaced.Definition.Function.Name = namespace + NamespaceSeparator + tool.Name()
+	return r.register(&namespaced, opts)
+}
+
+func (r *ToolRegistry) register(tool *Tool, opts []ToolOption) error {
+	registered := &registeredTool{tool: tool}
+	for _, opt := range opts {
+		opt(registered)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[tool.Name()]; exists {
+		return fmt.Errorf("tool %s is already registered", tool.Name())
+	}
+	r.tools[tool.Name()] = registered
+	r.definitions = nil
+	return nil
+}
+
+// Unregister removes a tool from the registry.
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+	delete(r.embeddings, name)
+	r.definitions = nil
+}
+
+// Get returns the tool registered under name.
+func (r *ToolRegistry) Get(name string) (*Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	registered, ok := r.tools[name]
+	if !ok {
+		return nil, false
+	}
+	return registered.tool, true
+}
+
+// CallOption restricts the tools available to a request.
+type CallOption func(*callConfig)
+
+type callConfig struct {
+	allow []string
+	deny  []string
+}
+
+// Allow restricts a request to the tools matching one of the patterns.
+// Patterns use path.Match syntax, such as "github__*".
+func Allow(patterns ...string) CallOption {
+	return func(c *callConfig) {
+		c.allow = append(c.allow, patterns...)
+	}
+}
+
+// Deny excludes the tools matching one of the patterns from a request.
+// Deny takes precedence over Allow.
+func Deny(patterns ...string) CallOption {
+	return func(c *callConfig) {
+		c.deny = append(c.deny, patterns...)
+	}
+}
+
+// permits reports whether the tool name passes the allow and deny lists.
+func (c *callConfig) permits(name string) bool {
+	if matchAny(c.deny, name) {
+		return false
+	}
+	return len(c.allow) == 0 || matchAny(c.allow, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newCallConfig(opts []CallOption) *callConfig {
+	cfg := &callConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Definitions returns the definitions of the permitted tools, sorted by
+// name, for use with llm.WithTools.
+//
+// Example:
+//
+//	prompt := llm.NewPrompt(input, llm.WithTools(registry.Definitions(tools.Deny("shell__*"))))
+func (r *ToolRegistry) Definitions(opts ...CallOption) []utils.Tool {
+	cfg := newCallConfig(opts)
+	all := r.sortedDefinitions()
+	definitions := make([]utils.Tool, 0, len(all))
+	for _, definition := range all {
+		if cfg.permits(definition.Function.Name) {
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions
+}
+
+// sortedDefinitions returns the definitions of all tools sorted by name,
+// building them only after the registered tools changed. The result must
+// not be modified.
+func (r *ToolRegistry) sortedDefinitions() []utils.Tool {
+	r.mu.RLock()
+	definitions := r.definitions
+	r.mu.RUnlock()
+	if definitions != nil {
+		return definitions
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.definitions == nil {
+		r.definitions = make([]utils.Tool, 0, len(r.tools))
+		for _, registered := range r.tools {
+			r.definitions = append(r.definitions, registered.tool.Definition)
+		}
+		sort.Slice(r.definitions, func(i, j int) bool {
+			return r.definitions[i].Function.Name < r.definitions[j].Function.Name
+		})
+	}
+	return r.definitions
+}
+
+// Execute runs a tool call requested by the model. The call is rejected if
+// the tool is unknown or not permitted, or if its arguments do not match the
+// tool's schema. The tool runs through the registry's middleware, within its
+// timeout, and a panic in the tool is returned as an error.
+func (r *ToolRegistry) Execute(ctx context.Context, call llm.ToolCall, opts ...CallOption) (string, error) {
+	name := call.Function.Name
+	if !newCallConfig(opts).permits(name) {
+		return "", fmt.Errorf("%w: %s", ErrToolNotAllowed, name)
+	}
+
+	r.mu.RLock()
+	registered, ok := r.tools[name]
+	middleware := r.middleware
+	timeout := r.timeout
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	if registered.timeout > 0 {
+		timeout = registered.timeout
+	}
+
+	args := strings.TrimSpace(string(call.Function.Arguments))
+	if args == "" {
+		args = "{}"
+	}
+	if err := llm.ValidateAgainstSchema(args, registered.tool.Definition.Function.Parameters); err != nil {
+		return "", fmt.Errorf("invalid arguments for tool %s: %w", name, err)
+	}
+
+	handler := func(ctx context.Context, call llm.ToolCall) (string, error) {
+		return invoke(ctx, registered.tool, call, timeout)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler(ctx, call)
+}
+
+// Result is the outcome of one tool call run by ExecuteAll.
+type Result struct {
+	Call   llm.ToolCall
+	Output string
+	Err    error
+}
+
+// ExecuteAll runs the tool calls of a response concurrently and returns
+// their results in the order of calls.
+func (r *ToolRegistry) ExecuteAll(ctx context.Context, calls []llm.ToolCall, opts ...CallOption) []Result {
+	results := make([]Result, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call llm.ToolCall) {
+			defer wg.Done()
+			output, err := r.Execute(ctx, call, opts...)
+			results[i] = Result{Call: call, Output: output, Err: err}
+		}(i, call)
+	}
+	wg.Wait()
+	return results
+}
+
+// invoke runs the tool within the timeout, recovering from panics. A tool
+// that ignores its context is abandoned when the timeout expires.
+func invoke(ctx context.Context, tool *Tool, call llm.ToolCall, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		output string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("tool %s panicked: %v", tool.Name(), p)}
+			}
+		}()
+		output, err := tool.Invoke(ctx, call)
+		done <- outcome{output, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.output, o.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("tool %s: %w", tool.Name(), ctx.Err())
+	}
+}
+
+// LoggingMiddleware logs every tool call with its duration and outcome.
+func LoggingMiddleware(logger utils.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call llm.ToolCall) (string, error) {
+			start := time.Now()
+			output, err := next(ctx, call)
+			if err != nil {
+				logger.Warn("Tool call failed", "tool", call.Function.Name, "duration", time.Since(start), "error", err)
+			} else {
+				logger.Debug("Tool call succeeded", "tool", call.Function.Name, "duration", time.Since(start))
+			}
+			return output, err
+		}
+	}
+}
+
+// TracingMiddleware records every tool call as a span of the llm.Trace in
+// the call's context, with its arguments and result. Calls without a trace
+// run untraced.
+func TracingMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call llm.ToolCall) (string, error) {
+			trace := llm.TraceFromContext(ctx)
+			if trace == nil {
+				return next(ctx, call)
+			}
+			ctx, span := trace.StartSpan(ctx, llm.SpanTool, call.Function.Name)
+			span.Update(func(span *llm.TraceSpan) {
+				span.Input = string(call.Function.Arguments)
+				span.Attributes = map[string]interface{}{"tool.call_id": call.ID}
+			})
+			output, err := next(ctx, call)
+			span.Update(func(span *llm.TraceSpan) {
+				span.Output = output
+			})
+			span.End(err)
+			return output, err
+		}
+	}
+}
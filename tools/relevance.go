@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/utils"
+)
+
+// EmbedFunc returns one embedding vector per input.
+type EmbedFunc func(ctx context.Context, inputs []string) ([][]float64, error)
+
+// EmbedWith returns an EmbedFunc that uses the embeddings endpoint of the
+// LLM's provider.
+func EmbedWith(l llm.LLM, opts ...llm.GenerateOption) EmbedFunc {
+	return func(ctx context.Context, inputs []string) ([][]float64, error) {
+		return llm.Embed(ctx, l, inputs, opts...)
+	}
+}
+
+// WithEmbedder enables RelevantDefinitions, which selects the tools whose
+// descriptions are most similar to the query using embed. The embedding of
+// each tool is computed once and cached.
+func WithEmbedder(embed EmbedFunc) RegistryOption {
+	return func(r *ToolRegistry) {
+		r.embed = embed
+	}
+}
+
+// RelevantDefinitions returns the definitions of the k permitted tools whose
+// name and description are most similar to the query, sorted by name. With
+// many registered tools, it saves the tokens of sending every schema in
+// every request. It requires WithEmbedder, and returns every permitted tool
+// when there are at most k.
+//
+// Example:
+//
+//	registry := tools.NewToolRegistry(tools.WithEmbedder(tools.EmbedWith(embeddingClient)))
+//	defs, err := registry.RelevantDefinitions(ctx, input, 5)
+//	prompt := llm.NewPrompt(input, llm.WithTools(defs))
+func (r *ToolRegistry) RelevantDefinitions(ctx context.Context, query string, k int, opts ...CallOption) ([]utils.Tool, error) {
+	if r.embed == nil {
+		return nil, fmt.Errorf("relevance filtering requires WithEmbedder")
+	}
+	definitions := r.Definitions(opts...)
+	if len(definitions) <= k {
+		return definitions, nil
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+
+	if err := r.embedTools(ctx, definitions); err != nil {
+		return nil, err
+	}
+	vectors, err := r.embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("got %d embeddings for the query", len(vectors))
+	}
+
+	scores := make(map[string]float64, len(definitions))
+	r.mu.RLock()
+	for _, definition := range definitions {
+		scores[definition.Function.Name] = cosineSimilarity(vectors[0], r.embeddings[definition.Function.Name])
+	}
+	r.mu.RUnlock()
+
+	ranked := append([]utils.Tool(nil), definitions...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].Function.Name] > scores[ranked[j].Function.Name]
+	})
+	ranked = ranked[:k]
+	// Name order keeps the tools of similar requests in the same order,
+	// which helps provider-side prompt caching
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].Function.Name < ranked[j].Function.Name
+	})
+	return ranked, nil
+}
+
+// embedTools computes the missing embeddings of the definitions.
+func (r *ToolRegistry) embedTools(ctx context.Context, definitions []utils.Tool) error {
+	var names, texts []string
+	r.mu.RLock()
+	for _, definition := range definitions {
+		if _, ok := r.embeddings[definition.Function.Name]; !ok {
+			names = append(names, definition.Function.Name)
+			texts = append(texts, definition.Function.Name+": "+definition.Function.Description)
+		}
+	}
+	r.mu.RUnlock()
+	if len(texts) == 0 {
+		return nil
+	}
+
+	vectors, err := r.embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("error embedding tool descriptions: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("got %d embeddings for %d tools", len(vectors), len(texts))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.embeddings == nil {
+		r.embeddings = make(map[string][]float64, len(vectors))
+	}
+	for i, name := range names {
+		// The tool may have been unregistered meanwhile
+		if _, ok := r.tools[name]; ok {
+			r.embeddings[name] = vectors[i]
+		}
+	}
+	return nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,227 @@
+// Package tools builds LLM tool definitions from Go functions and executes
+// the tool calls a model requests.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/utils"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Tool is a tool definition together with the Go function that executes it.
+type Tool struct {
+	// Definition is the tool as sent to the model with llm.WithTools.
+	Definition utils.Tool
+
+	fn        reflect.Value
+	params    reflect.Type // nil for functions without parameters
+	withCtx   bool
+	hasResult bool
+	hasErr    bool
+}
+
+// FromFunc creates a Tool from a Go function. The function takes an optional
+// context.Context followed by an optional struct, or pointer to struct, that
+// holds its parameters, and returns a result, an error, or both:
+//
+//	func(ctx context.Context, params P) (R, error)
+//	func(params P) (R, error)
+//	func(params P) error
+//	func(ctx context.Context) (R, error)
+//
+// The JSON schema of the tool's parameters is reflected from the struct.
+// Field names follow the json tags, fields without omitempty are required,
+// and jsonschema tags add descriptions, enums and bounds.
+//
+// Example:
+//
+//	type WeatherParams struct {
+//	    City string `json:"city" jsonschema:"description=City name"`
+//	    Unit string `json:"unit,omitempty" jsonschema:"enum=celsius,enum=fahrenheit"`
+//	}
+//
+//	weather, err := tools.FromFunc(func(ctx context.Context, p WeatherParams) (string, error) {
+//	    return lookupWeather(ctx, p.City, p.Unit)
+//	}, "get_weather", "Get the current weather in a city")
+func FromFunc(fn any, name, description string) (*Tool, error) {
+	if name == "" {
+		return nil, fmt.Errorf("tool name is required")
+	}
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("tool %s: expected a function, got %T", name, fn)
+	}
+	t := v.Type()
+	tool := &Tool{fn: v}
+
+	in := 0
+	if t.NumIn() > in && t.In(in) == contextType {
+		tool.withCtx = true
+		in++
+	}
+	if t.NumIn() > in {
+		params := t.In(in)
+		if !isStruct(params) {
+			return nil, fmt.Errorf("tool %s: parameters must be a struct, got %s", name, params)
+		}
+		tool.params = params
+		in++
+	}
+	if t.NumIn() > in || t.IsVariadic() {
+		return nil, fmt.Errorf("tool %s: expected an optional context and one parameter struct, got %s", name, t)
+	}
+
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		if t.Out(0) == errorType {
+			tool.hasErr = true
+		} else {
+			tool.hasResult = true
+		}
+	case 2:
+		if t.Out(1) != errorType {
+			return nil, fmt.Errorf("tool %s: second result must be an error, got %s", name, t.Out(1))
+		}
+		tool.hasResult, tool.hasErr = true, true
+	default:
+		return nil, fmt.Errorf("tool %s: expected at most a result and an error, got %s", name, t)
+	}
+
+	schema, err := parameterSchema(tool.params)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	tool.Definition = utils.Tool{
+		Type: "function",
+		Function: utils.Function{
+			Name:        name,
+			Description: description,
+			Parameters:  schema,
+		},
+	}
+	return tool, nil
+}
+
+// MustFromFunc is like FromFunc but panics if the function cannot be used
+// as a tool. It simplifies declaring tools in package variables.
+func MustFromFunc(fn any, name, description string) *Tool {
+	tool, err := FromFunc(fn, name, description)
+	if err != nil {
+		panic(err)
+	}
+	return tool
+}
+
+// Name returns the name of the tool.
+func (t *Tool) Name() string {
+	return t.Definition.Function.Name
+}
+
+// Call decodes the JSON arguments into the function's parameter struct and
+// calls the function. It returns the function's result, or nil when the
+// function has none.
+func (t *Tool) Call(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var args []reflect.Value
+	if t.withCtx {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	if t.params != nil {
+		params, err := t.decode(arguments)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, params)
+	}
+
+	out := t.fn.Call(args)
+	if t.hasErr {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+	}
+	if t.hasResult {
+		return out[0].Interface(), nil
+	}
+	return nil, nil
+}
+
+// Invoke executes a tool call requested by the model and returns the result
+// as text for the tool message: strings as is, other results as JSON.
+func (t *Tool) Invoke(ctx context.Context, call llm.ToolCall) (string, error) {
+	if call.Function.Name != t.Name() {
+		return "", fmt.Errorf("tool %s cannot handle a call to %s", t.Name(), call.Function.Name)
+	}
+	result, err := t.Call(ctx, call.Function.Arguments)
+	if err != nil {
+		return "", err
+	}
+	switch r := result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return r, nil
+	default:
+		b, err := json.Marshal(r)
+		if err != nil {
+			return "", fmt.Errorf("tool %s: error encoding result: %w", t.Name(), err)
+		}
+		return string(b), nil
+	}
+}
+
+// decode unmarshals the arguments into a new value of the parameter type.
+func (t *Tool) decode(arguments json.RawMessage) (reflect.Value, error) {
+	elem := t.params
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	params := reflect.New(elem)
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, params.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("tool %s: invalid arguments: %w", t.Name(), err)
+		}
+	}
+	if t.params.Kind() == reflect.Ptr {
+		return params, nil
+	}
+	return params.Elem(), nil
+}
+
+// isStruct reports whether t is a struct or a pointer to a struct.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// parameterSchema reflects the JSON schema of a parameter struct, inlining
+// nested types so the schema is self-contained.
+func parameterSchema(params reflect.Type) (map[string]interface{}, error) {
+	if params == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, nil
+	}
+	reflector := &jsonschema.Reflector{DoNotReference: true, ExpandedStruct: true}
+	b, err := json.Marshal(reflector.ReflectFromType(params))
+	if err != nil {
+		return nil, fmt.Errorf("error generating parameter schema: %w", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("error generating parameter schema: %w", err)
+	}
+	delete(schema, "$schema")
+	delete(schema, "$id")
+	return schema, nil
+}
@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/llm"
+)
+
+type weatherParams struct {
+	City string `json:"city" jsonschema:"description=City name"`
+	Unit string `json:"unit,omitempty" jsonschema:"enum=celsius,enum=fahrenheit"`
+}
+
+type weather struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestFromFunc(t *testing.T) {
+	tool, err := FromFunc(func(ctx context.Context, p weatherParams) (weather, error) {
+		if p.City == "" {
+			return weather{}, errors.New("unknown city")
+		}
+		return weather{City: p.City, Temperature: 21.5}, nil
+	}, "get_weather", "Get the current weather in a city")
+	require.NoError(t, err)
+
+	schema, err := json.Marshal(tool.Definition.Function.Parameters)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string", "description": "City name"},
+			"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}
+		},
+		"required": ["city"],
+		"additionalProperties": false
+	}`, string(schema))
+
+	var call llm.ToolCall
+	call.Function.Name = "get_weather"
+	call.Function.Arguments = json.RawMessage(`{"city":"Paris"}`)
+	result, err := tool.Invoke(context.Background(), call)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"city":"Paris","temperature":21.5}`, result)
+
+	_, err = tool.Call(context.Background(), json.RawMessage(`{}`))
+	assert.EqualError(t, err, "unknown city")
+	_, err = tool.Call(context.Background(), json.RawMessage(`{"city":1}`))
+	assert.Error(t, err)
+
+	_, err = FromFunc(func(city string) error { return nil }, "bad", "")
+	assert.Error(t, err, "parameters must be a struct")
+}
+
+func toolCall(name, arguments string) llm.ToolCall {
+	var call llm.ToolCall
+	call.Function.Name = name
+	call.Function.Arguments = json.RawMessage(arguments)
+	return call
+}
+
+func TestToolRegistry(t *testing.T) {
+	var mu sync.Mutex
+	var logged []string
+	registry := NewToolRegistry(
+		WithDefaultTimeout(20*time.Millisecond),
+		WithMiddleware(func(next Handler) Handler {
+			return func(ctx context.Context, call llm.ToolCall) (string, error) {
+				mu.Lock()
+				logged = append(logged, call.Function.Name)
+				mu.Unlock()
+				return next(ctx, call)
+			}
+		}),
+	)
+	echo := MustFromFunc(func(p weatherParams) (string, error) { return p.City, nil }, "echo", "")
+	require.NoError(t, registry.Register(echo))
+	require.NoError(t, registry.RegisterNamespace("ops", echo))
+	assert.Error(t, registry.Register(echo), "duplicate names are rejected")
+	require.NoError(t, registry.Register(MustFromFunc(func() error { panic("boom") }, "crash", "")))
+	require.NoError(t, registry.Register(MustFromFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, "slow", "")))
+
+	var names []string
+	for _, def := range registry.Definitions(Deny("ops__*", "slow")) {
+		names = append(names, def.Function.Name)
+	}
+	assert.Equal(t, []string{"crash", "echo"}, names)
+
+	out, err := registry.Execute(context.Background(), toolCall("ops__echo", `{"city":"Paris"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", out)
+	assert.Equal(t, []string{"ops__echo"}, logged)
+
+	_, err = registry.Execute(context.Background(), toolCall("echo", `{"city":"Paris"}`), Allow("ops__*"))
+	assert.ErrorIs(t, err, ErrToolNotAllowed)
+	_, err = registry.Execute(context.Background(), toolCall("missing", `{}`))
+	assert.ErrorIs(t, err, ErrToolNotFound)
+	_, err = registry.Execute(context.Background(), toolCall("echo", `{"unit":"celsius"}`))
+	assert.ErrorContains(t, err, "missing required field: city")
+	_, err = registry.Execute(context.Background(), toolCall("crash", ``))
+	assert.ErrorContains(t, err, "panicked: boom")
+
+	results := registry.ExecuteAll(context.Background(), []llm.ToolCall{toolCall("slow", `{}`), toolCall("echo", `{"city":"Lyon"}`)})
+	assert.ErrorIs(t, results[0].Err, context.DeadlineExceeded)
+	assert.Equal(t, "Lyon", results[1].Output)
+}
+
+func TestRelevantDefinitions(t *testing.T) {
+	// Embeds texts on two axes: weather and email
+	var embedded []string
+	embed := func(ctx context.Context, inputs []string) ([][]float64, error) {
+		embedded = append(embedded, inputs...)
+		vectors := make([][]float64, len(inputs))
+		for i, input := range inputs {
+			vectors[i] = []float64{0.1, 0.1}
+			if strings.Contains(input, "weather") {
+				vectors[i][0] = 1
+			}
+			if strings.Contains(input, "email") {
+				vectors[i][1] = 1
+			}
+		}
+		return vectors, nil
+	}
+	registry := NewToolRegistry(WithEmbedder(embed))
+	noop := func() error { return nil }
+	require.NoError(t, registry.Register(MustFromFunc(noop, "get_weather", "Get the weather forecast")))
+	require.NoError(t, registry.Register(MustFromFunc(noop, "send_email", "Send an email")))
+	require.NoError(t, registry.Register(MustFromFunc(noop, "read_email", "Read the email inbox")))
+
+	defs, err := registry.RelevantDefinitions(context.Background(), "any new email from Bob?", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "read_email", defs[0].Function.Name)
+	assert.Equal(t, "send_email", defs[1].Function.Name)
+
+	defs, err = registry.RelevantDefinitions(context.Background(), "will it rain? check the weather", 1)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	assert.Equal(t, "get_weather", defs[0].Function.Name)
+	assert.Len(t, embedded, 5, "tool embeddings are cached")
+
+	defs, err = registry.RelevantDefinitions(context.Background(), "email", 5)
+	require.NoError(t, err)
+	assert.Len(t, defs, 3)
+}
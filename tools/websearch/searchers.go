@@ -0,0 +1,203 @@
+package websearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SearcherOption configures a Searcher.
+type SearcherOption func(*client)
+
+// client holds the HTTP settings shared by the searchers.
+type client struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// WithHTTPClient sets the HTTP client of the searcher.
+func WithHTTPClient(httpClient *http.Client) SearcherOption {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBaseURL points the searcher at another URL, such as a proxy.
+func WithBaseURL(baseURL string) SearcherOption {
+	return func(c *client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func newClient(apiKey, baseURL string, opts []SearcherOption) client {
+	c := client{apiKey: apiKey, baseURL: baseURL, httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// do sends req and decodes the JSON response into out.
+func (c *client) do(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The query may carry the API key
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL, _, _ = strings.Cut(urlErr.URL, "?")
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText removes the highlighting markup some APIs put in snippets.
+func plainText(s string) string {
+	return strings.TrimSpace(htmlTag.ReplaceAllString(s, ""))
+}
+
+// Tavily searches with the Tavily Search API, designed for LLM agents.
+type Tavily struct {
+	client
+}
+
+// NewTavily creates a Tavily searcher.
+func NewTavily(apiKey string, opts ...SearcherOption) *Tavily {
+	return &Tavily{newClient(apiKey, "https://api.tavily.com", opts)}
+}
+
+// Search implements Searcher.
+func (s *Tavily) Search(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":       query,
+		"max_results": maxResults,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	var response struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"published_date"`
+		} `json:"results"`
+	}
+	if err := s.do(req, &response); err != nil {
+		return nil, fmt.Errorf("tavily: %w", err)
+	}
+	results := make([]Result, len(response.Results))
+	for i, r := range response.Results {
+		results[i] = Result{Title: r.Title, URL: r.URL, Snippet: plainText(r.Content), Published: r.PublishedDate}
+	}
+	return results, nil
+}
+
+// Brave searches with the Brave Search API.
+type Brave struct {
+	client
+}
+
+// NewBrave creates a Brave Search searcher.
+func NewBrave(apiKey string, opts ...SearcherOption) *Brave {
+	return &Brave{newClient(apiKey, "https://api.search.brave.com", opts)}
+}
+
+// Search implements Searcher.
+func (s *Brave) Search(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(maxResults)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/res/v1/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", s.apiKey)
+
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				Age         string `json:"age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := s.do(req, &response); err != nil {
+		return nil, fmt.Errorf("brave: %w", err)
+	}
+	results := make([]Result, len(response.Web.Results))
+	for i, r := range response.Web.Results {
+		results[i] = Result{Title: plainText(r.Title), URL: r.URL, Snippet: plainText(r.Description), Published: r.Age}
+	}
+	return results, nil
+}
+
+// SerpAPI searches Google through SerpAPI.
+type SerpAPI struct {
+	client
+	engine string
+}
+
+// NewSerpAPI creates a SerpAPI searcher using the Google engine.
+func NewSerpAPI(apiKey string, opts ...SearcherOption) *SerpAPI {
+	return &SerpAPI{client: newClient(apiKey, "https://serpapi.com", opts), engine: "google"}
+}
+
+// Search implements Searcher.
+func (s *SerpAPI) Search(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	params := url.Values{
+		"engine":  {s.engine},
+		"q":       {query},
+		"num":     {strconv.Itoa(maxResults)},
+		"api_key": {s.apiKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/search.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+			Date    string `json:"date"`
+		} `json:"organic_results"`
+	}
+	if err := s.do(req, &response); err != nil {
+		return nil, fmt.Errorf("serpapi: %w", err)
+	}
+	results := make([]Result, len(response.OrganicResults))
+	for i, r := range response.OrganicResults {
+		results[i] = Result{Title: r.Title, URL: r.Link, Snippet: plainText(r.Snippet), Published: r.Date}
+	}
+	return results, nil
+}
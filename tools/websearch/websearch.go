@@ -0,0 +1,177 @@
+// Package websearch provides web search tools backed by search APIs such as
+// Tavily, Brave Search and SerpAPI, ready to register in a tools.ToolRegistry.
+//
+// Example:
+//
+//	search, err := websearch.NewTool(websearch.NewTavily(os.Getenv("TAVILY_API_KEY")))
+//	registry := tools.NewToolRegistry()
+//	err = registry.Register(search)
+//	prompt := llm.NewPrompt("What changed in Go 1.23?", llm.WithTools(registry.Definitions()))
+package websearch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/teilomillet/gollm/llm"
+	"github.com/teilomillet/gollm/tools"
+)
+
+// DefaultMaxResults is the number of results a search returns unless the
+// model or WithMaxResults asks for another number.
+const DefaultMaxResults = 5
+
+// Result is one result of a web search.
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+
+	// Published is the publication date as reported by the search API, if
+	// any, such as "2024-06-01" or "3 days ago".
+	Published string `json:"published,omitempty"`
+}
+
+// Searcher queries a web search API.
+type Searcher interface {
+	// Search returns at most maxResults results for the query.
+	Search(ctx context.Context, query string, maxResults int) ([]Result, error)
+}
+
+// Option configures the tool created by NewTool.
+type Option func(*searchTool)
+
+type searchTool struct {
+	searcher    Searcher
+	name        string
+	description string
+	maxResults  int
+	summarizer  llm.LLM
+}
+
+// WithName sets the name of the tool, "web_search" by default.
+func WithName(name string) Option {
+	return func(t *searchTool) {
+		t.name = name
+	}
+}
+
+// WithDescription replaces the description of the tool shown to the model.
+func WithDescription(description string) Option {
+	return func(t *searchTool) {
+		t.description = description
+	}
+}
+
+// WithMaxResults sets the number of results returned when the model does
+// not ask for a number, and the most it may ask for.
+func WithMaxResults(n int) Option {
+	return func(t *searchTool) {
+		t.maxResults = n
+	}
+}
+
+// WithSummarizer condenses the results into a short answer to the query
+// with an LLM, keeping the [n] citations, before returning them to the
+// model. It trades an extra call for fewer tokens in the agent's context.
+func WithSummarizer(summarizer llm.LLM) Option {
+	return func(t *searchTool) {
+		t.summarizer = summarizer
+	}
+}
+
+// searchParams are the parameters the model passes to the tool.
+type searchParams struct {
+	Query      string `json:"query" jsonschema:"description=The search query"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema:"description=Number of results to return,minimum=1"`
+}
+
+// NewTool creates a web search tool that queries searcher. The tool returns
+// the results as a numbered list of sources the model can cite as [n].
+func NewTool(searcher Searcher, opts ...Option) (*tools.Tool, error) {
+	t := &searchTool{
+		searcher:    searcher,
+		name:        "web_search",
+		description: "Search the web for current information. Cite the sources you use as [n].",
+		maxResults:  DefaultMaxResults,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if searcher == nil {
+		return nil, fmt.Errorf("web search tool requires a searcher")
+	}
+	return tools.FromFunc(t.search, t.name, t.description)
+}
+
+func (t *searchTool) search(ctx context.Context, p searchParams) (string, error) {
+	if strings.TrimSpace(p.Query) == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	n := p.MaxResults
+	if n <= 0 || n > t.maxResults {
+		n = t.maxResults
+	}
+	results, err := t.searcher.Search(ctx, p.Query, n)
+	if err != nil {
+		return "", fmt.Errorf("web search failed: %w", err)
+	}
+	if len(results) > n {
+		results = results[:n]
+	}
+	if len(results) == 0 {
+		return "No results found.", nil
+	}
+	if t.summarizer == nil {
+		return FormatResults(results), nil
+	}
+	return Summarize(ctx, t.summarizer, p.Query, results)
+}
+
+// FormatResults formats results as a numbered list of sources, so that
+// answers can cite them as [1], [2] and so on.
+func FormatResults(results []Result) string {
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s\n", i+1, r.Title, r.URL)
+		if r.Published != "" {
+			fmt.Fprintf(&b, "Published: %s\n", r.Published)
+		}
+		if r.Snippet != "" {
+			b.WriteString(r.Snippet)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// FormatCitations formats the sources of results as a reference list, such
+// as "[1] Title - https://example.com", to append to an answer.
+func FormatCitations(results []Result) string {
+	lines := make([]string, len(results))
+	for i, r := range results {
+		lines[i] = fmt.Sprintf("[%d] %s - %s", i+1, r.Title, r.URL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Summarize answers the query from the results with summarizer, citing them
+// as [n], and appends the reference list.
+func Summarize(ctx context.Context, summarizer llm.LLM, query string, results []Result) (string, error) {
+	prompt := llm.NewPrompt(fmt.Sprintf("Question: %s\n\nSearch results:\n%s", query, FormatResults(results)),
+		llm.WithDirectives(
+			"Answer the question in a few sentences using only the search results",
+			"Cite the results you use as [n], with the numbers of the search results",
+			"Say so if the results do not answer the question",
+		),
+	)
+	summary, err := summarizer.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize search results: %w", err)
+	}
+	return strings.TrimSpace(summary) + "\n\nSources:\n" + FormatCitations(results), nil
+}
@@ -0,0 +1,72 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teilomillet/gollm/llm"
+)
+
+func TestSearchers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			assert.Equal(t, "Bearer tvly-key", r.Header.Get("Authorization"))
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, 2.0, body["max_results"])
+			fmt.Fprint(w, `{"results": [{"title": "Go 1.23", "url": "https://go.dev/doc/go1.23", "content": "Range over functions."}]}`)
+		case "/res/v1/web/search":
+			assert.Equal(t, "brave-key", r.Header.Get("X-Subscription-Token"))
+			assert.Equal(t, "2", r.URL.Query().Get("count"))
+			fmt.Fprint(w, `{"web": {"results": [{"title": "Go <strong>1.23</strong>", "url": "https://go.dev/doc/go1.23", "description": "Range over <strong>functions</strong>.", "age": "2 days ago"}]}}`)
+		case "/search.json":
+			assert.Equal(t, "serp-key", r.URL.Query().Get("api_key"))
+			fmt.Fprint(w, `{"organic_results": [{"title": "Go 1.23", "link": "https://go.dev/doc/go1.23", "snippet": "Range over functions."}]}`)
+		}
+	}))
+	defer server.Close()
+
+	for _, searcher := range []Searcher{
+		NewTavily("tvly-key", WithBaseURL(server.URL)),
+		NewBrave("brave-key", WithBaseURL(server.URL)),
+		NewSerpAPI("serp-key", WithBaseURL(server.URL)),
+	} {
+		results, err := searcher.Search(context.Background(), "go 1.23", 2)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Go 1.23", results[0].Title)
+		assert.Equal(t, "https://go.dev/doc/go1.23", results[0].URL)
+		assert.Equal(t, "Range over functions.", results[0].Snippet)
+	}
+}
+
+type staticSearcher []Result
+
+func (s staticSearcher) Search(ctx context.Context, query string, maxResults int) ([]Result, error) {
+	return s, nil
+}
+
+func TestTool(t *testing.T) {
+	tool, err := NewTool(staticSearcher{
+		{Title: "Go 1.23", URL: "https://go.dev/doc/go1.23", Snippet: "Range over functions."},
+		{Title: "Go blog", URL: "https://go.dev/blog", Snippet: "News."},
+		{Title: "Extra", URL: "https://example.com"},
+	}, WithMaxResults(2))
+	require.NoError(t, err)
+	assert.Equal(t, "web_search", tool.Name())
+
+	var call llm.ToolCall
+	call.Function.Name = "web_search"
+	call.Function.Arguments = json.RawMessage(`{"query": "go 1.23"}`)
+	output, err := tool.Invoke(context.Background(), call)
+	require.NoError(t, err)
+	assert.Equal(t, "[1] Go 1.23\nhttps://go.dev/doc/go1.23\nRange over functions.\n\n[2] Go blog\nhttps://go.dev/blog\nNews.\n", output)
+	assert.NotContains(t, output, "Extra")
+}
@@ -0,0 +1,49 @@
+// Package gollm provides tracing for Language Learning Models.
+// This file contains re-exports for collecting and exporting traces.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Trace collects the generations and tool calls of a run, exportable as JSON or OpenTelemetry spans.
+type Trace = llm.Trace
+
+// TraceSpan is one step of a trace.
+type TraceSpan = llm.TraceSpan
+
+// TraceMessage is a message sent to the model in a traced generation.
+type TraceMessage = llm.TraceMessage
+
+// TraceScore is an evaluation of a trace, such as user feedback.
+type TraceScore = llm.TraceScore
+
+// TraceExporter sends traces to an observability backend.
+type TraceExporter = llm.TraceExporter
+
+// Kinds of trace spans.
+const (
+	SpanGeneration = llm.SpanGeneration
+	SpanTool       = llm.SpanTool
+	SpanCustom     = llm.SpanCustom
+)
+
+var (
+	// NewTrace starts a trace.
+	NewTrace = llm.NewTrace
+
+	// ContextWithTrace returns a context whose Generate calls and traced tool calls are recorded into a trace.
+	ContextWithTrace = llm.ContextWithTrace
+
+	// TraceFromContext returns the trace of a context, or nil.
+	TraceFromContext = llm.TraceFromContext
+
+	// WithTrace records a Generate call into a trace.
+	WithTrace = llm.WithTrace
+
+	// NewLangfuseExporter creates an exporter for Langfuse.
+	NewLangfuseExporter = llm.NewLangfuseExporter
+
+	// NewLangSmithExporter creates an exporter for LangSmith.
+	NewLangSmithExporter = llm.NewLangSmithExporter
+)
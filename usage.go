@@ -0,0 +1,33 @@
+// Package gollm provides token usage and cost estimation for Language Learning Models.
+// This file contains re-exports for inspecting usage and pricing models.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Re-export usage types from the llm package
+type (
+	// Usage reports the tokens consumed by a single request.
+	Usage = llm.Usage
+
+	// ModelPricing holds the price of a model in US dollars per million tokens.
+	ModelPricing = llm.ModelPricing
+)
+
+var (
+	// ParseUsage extracts token usage from a provider response body.
+	ParseUsage = llm.ParseUsage
+
+	// RegisterModelPricing sets the price used to estimate the cost of a model.
+	RegisterModelPricing = llm.RegisterModelPricing
+
+	// LookupPricing returns the price of a model from the pricing table.
+	LookupPricing = llm.LookupPricing
+
+	// EstimateCost returns the price in US dollars of a model's usage.
+	EstimateCost = llm.EstimateCost
+
+	// EstimateCacheSavings returns how much prompt caching saved on a model's usage.
+	EstimateCacheSavings = llm.EstimateCacheSavings
+)
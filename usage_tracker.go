@@ -0,0 +1,32 @@
+// Package gollm provides usage tracking for Language Learning Models.
+// This file contains re-exports for aggregating usage across calls by tag, model and provider.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+type (
+	// UsageTracker aggregates the usage and estimated cost of Generate calls by tag, model and provider.
+	UsageTracker = llm.UsageTracker
+
+	// UsageStats aggregates the usage of a set of requests.
+	UsageStats = llm.UsageStats
+)
+
+var (
+	// NewUsageTracker creates an empty UsageTracker.
+	NewUsageTracker = llm.NewUsageTracker
+
+	// ContextWithUsageTracker returns a context whose Generate calls are recorded into a usage tracker.
+	ContextWithUsageTracker = llm.ContextWithUsageTracker
+
+	// UsageTrackerFromContext returns the usage tracker of a context, or nil.
+	UsageTrackerFromContext = llm.UsageTrackerFromContext
+
+	// WithUsageTracker records the usage of a Generate call into a usage tracker.
+	WithUsageTracker = llm.WithUsageTracker
+
+	// WithTag tags a Generate call to break its usage down in a usage tracker.
+	WithTag = llm.WithTag
+)
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// AttachmentImage marks an attachment as an image for vision models.
+	AttachmentImage = "image"
+	// AttachmentFile marks an attachment as a document, such as a PDF.
+	AttachmentFile = "file"
+)
+
+// Attachment is an image or document sent alongside a prompt. Exactly one of
+// URL, Path or Data identifies its content; files referenced by Path are read
+// when the request is prepared.
+type Attachment struct {
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+}
+
+// Load returns the attachment content and its media type, reading Path if
+// needed. The media type is guessed from the file extension or the content
+// when MediaType is empty. Load fails for URL-only attachments.
+func (a Attachment) Load() ([]byte, string, error) {
+	data := a.Data
+	if data == nil {
+		if a.Path == "" {
+			return nil, "", fmt.Errorf("attachment %s has no inline content", a.URL)
+		}
+		var err error
+		if data, err = os.ReadFile(a.Path); err != nil {
+			return nil, "", fmt.Errorf("failed to read attachment: %w", err)
+		}
+	}
+
+	mediaType := a.MediaType
+	if mediaType == "" && a.Path != "" {
+		mediaType, _, _ = strings.Cut(mime.TypeByExtension(filepath.Ext(a.Path)), ";")
+	}
+	if mediaType == "" {
+		mediaType, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	}
+	return data, mediaType, nil
+}
+
+// Base64 returns the attachment content base64-encoded with its media type.
+func (a Attachment) Base64() (string, string, error) {
+	data, mediaType, err := a.Load()
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), mediaType, nil
+}
+
+// DataURL returns the attachment's URL, or its content as a data: URL.
+func (a Attachment) DataURL() (string, error) {
+	if a.URL != "" {
+		return a.URL, nil
+	}
+	encoded, mediaType, err := a.Base64()
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mediaType + ";base64," + encoded, nil
+}
+
+// Size returns the size of the attachment content without reading it, and
+// false when it is unknown, as for attachments by URL other than data: URLs
+// or files that cannot be found.
+func (a Attachment) Size() (int64, bool) {
+	switch {
+	case a.Data != nil:
+		return int64(len(a.Data)), true
+	case a.Path != "":
+		info, err := os.Stat(a.Path)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size(), true
+	case strings.HasPrefix(a.URL, "data:"):
+		_, encoded, ok := strings.Cut(a.URL, ";base64,")
+		if !ok {
+			return 0, false
+		}
+		return int64(base64.RawStdEncoding.DecodedLen(len(strings.TrimRight(encoded, "=")))), true
+	}
+	return 0, false
+}
+
+// Name returns the attachment's file name, falling back to the base of its path or URL.
+func (a Attachment) Name() string {
+	switch {
+	case a.Filename != "":
+		return a.Filename
+	case a.Path != "":
+		return filepath.Base(a.Path)
+	case a.URL != "" && !strings.HasPrefix(a.URL, "data:"):
+		return filepath.Base(a.URL)
+	}
+	return "attachment"
+}
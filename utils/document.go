@@ -0,0 +1,19 @@
+package utils
+
+// Document is a source the model should ground its answer in. Providers with
+// native support, such as Cohere, receive documents separately from the
+// prompt and cite them in the response; other providers see them as part of
+// the prompt text.
+type Document struct {
+	// ID identifies the document in citations. Providers assign one when empty.
+	ID string `json:"id,omitempty"`
+
+	// Title is an optional document title.
+	Title string `json:"title,omitempty"`
+
+	// Text is the document content.
+	Text string `json:"text"`
+
+	// Fields holds additional named fields, such as a URL or an author.
+	Fields map[string]string `json:"fields,omitempty"`
+}
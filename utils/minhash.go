@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// MinHash estimates the Jaccard similarity of texts from their word
+// shingles, without embeddings. Signatures of the same MinHash compare with
+// Similarity.
+type MinHash struct {
+	shingleSize int
+	seeds       []uint64
+}
+
+// NewMinHash creates a MinHash over shingles of shingleSize words with
+// numHashes hash functions; more hashes give a closer estimate. Sizes below
+// 1 default to 3-word shingles and 128 hashes.
+func NewMinHash(shingleSize, numHashes int) *MinHash {
+	if shingleSize < 1 {
+		shingleSize = 3
+	}
+	if numHashes < 1 {
+		numHashes = 128
+	}
+	seeds := make([]uint64, numHashes)
+	seed := uint64(0x9E3779B97F4A7C15)
+	for i := range seeds {
+		seed = splitMix64(seed)
+		seeds[i] = seed
+	}
+	return &MinHash{shingleSize: shingleSize, seeds: seeds}
+}
+
+// Signature returns the MinHash signature of text, case and punctuation
+// aside. Texts shorter than a shingle are a single shingle.
+func (m *MinHash) Signature(text string) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	signature := make([]uint64, len(m.seeds))
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	if len(words) == 0 {
+		return signature
+	}
+	n := len(words) - m.shingleSize + 1
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+m.shingleSize, len(words))], " ")))
+		shingle := h.Sum64()
+		for j, seed := range m.seeds {
+			if v := splitMix64(shingle ^ seed); v < signature[j] {
+				signature[j] = v
+			}
+		}
+	}
+	return signature
+}
+
+// Similarity returns the estimated Jaccard similarity, from 0 to 1, of the
+// texts of signatures a and b.
+func (m *MinHash) Similarity(a, b []uint64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// splitMix64 is the SplitMix64 mixing function, used to derive the hash
+// functions from a single hash.
+func splitMix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
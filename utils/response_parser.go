@@ -9,6 +9,9 @@ import (
 
 // ExtractFunctionCalls extracts JSON function calls encapsulated within <function_call> tags.
 // It returns a slice of function call objects, each containing a name and arguments.
+//
+// Deprecated: the text format loses the call IDs and may lose argument types.
+// Use the ToolCalls of the llm.Response filled by llm.WithResponse instead.
 func ExtractFunctionCalls(response string) ([]map[string]interface{}, error) {
 	functionCallRegex := regexp.MustCompile(`<function_call>(.*?)</function_call>`)
 	matches := functionCallRegex.FindAllStringSubmatch(response, -1)
@@ -66,6 +69,9 @@ func CleanResponse(rawResponse string) (string, []string, error) {
 
 // FormatFunctionCall creates a properly formatted function call string
 // that can be embedded in the response.
+//
+// Deprecated: arguments decoded to interface{} may lose their types, such as
+// large integers. Providers format their structured tool calls themselves.
 func FormatFunctionCall(name string, arguments interface{}) (string, error) {
 	// If arguments is a string, try to parse it as JSON first
 	if argsStr, ok := arguments.(string); ok {
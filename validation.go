@@ -88,3 +88,16 @@ func Validate(s interface{}) error {
 func GenerateJSONSchema(v interface{}) ([]byte, error) {
 	return llm.GenerateJSONSchema(v)
 }
+
+// ValidateAgainstSchema checks that a JSON response conforms to a JSON schema,
+// given as a JSON string, byte slice or map.
+//
+// Example usage:
+//
+//	schema := `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`
+//	if err := ValidateAgainstSchema(response, schema); err != nil {
+//	    log.Printf("invalid response: %v", err)
+//	}
+func ValidateAgainstSchema(response string, schema interface{}) error {
+	return llm.ValidateAgainstSchema(response, schema)
+}
@@ -0,0 +1,10 @@
+// Package gollm provides provider health checks for Language Learning Models.
+// This file contains re-exports for verifying the configuration at startup.
+package gollm
+
+import (
+	"github.com/teilomillet/gollm/llm"
+)
+
+// Verify checks that the provider is reachable, accepts the API key and serves the model.
+var Verify = llm.Verify